@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/clipboard"
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// splitCommands splits the commands given to -batch at the semicolons and
+// line breaks which are not quoted or escaped
+func splitCommands(s string) []string {
+	var cmds []string
+	var cur strings.Builder
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';' || r == '\n':
+			cmds = append(cmds, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	cmds = append(cmds, cur.String())
+
+	var res []string
+	for _, c := range cmds {
+		if c = strings.TrimSpace(c); c != "" {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// batchEdit runs the commands and then the Lua script, if any, in the pane,
+// stopping at the first error. A command waiting for an answer in a prompt
+// is an error, as nobody can give it.
+func batchEdit(h *action.BufPane, commands []string, script string) error {
+	check := func(what string) error {
+		info := action.InfoBar
+		if info.HasPrompt {
+			info.DonePrompt(true)
+			return fmt.Errorf("%s: prompts can't be answered in batch mode", what)
+		}
+		if info.HasError {
+			msg := info.Msg
+			info.Reset()
+			return fmt.Errorf("%s: %s", what, msg)
+		}
+		info.Reset()
+		return nil
+	}
+
+	for _, c := range commands {
+		h.HandleCommand(c)
+		if err := check(c); err != nil {
+			return err
+		}
+	}
+	if script != "" {
+		if err := ulua.L.DoFile(script); err != nil {
+			return err
+		}
+		if err := check(script); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunBatch applies the commands of -batch and the Lua script of -lua to the
+// files, or to the standard input which is then written to the standard
+// output, without showing anything on the screen, and returns the exit
+// status: 1 if any of them failed, in which case it isn't saved
+func RunBatch(args []string) int {
+	fail := func(msg ...interface{}) {
+		fmt.Fprintln(os.Stderr, msg...)
+	}
+
+	if _, err := screen.InitSimScreen(); err != nil {
+		fail(err)
+		return 1
+	}
+	clipboard.Initialize(clipboard.Internal)
+
+	if err := config.LoadAllPlugins(); err != nil {
+		fail(err)
+	}
+	action.InitBindings()
+	action.InitCommands()
+	if err := config.InitColorscheme(); err != nil {
+		fail(err)
+	}
+	if err := config.RunPluginFn("preinit"); err != nil {
+		fail(err)
+	}
+	action.InitGlobals()
+	buffer.SetMessager(action.InfoBar)
+
+	var bufs []*buffer.Buffer
+	stdin := len(args) == 0
+	if stdin {
+		input, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fail("Error reading from stdin:", err)
+			return 1
+		}
+		bufs = append(bufs, buffer.NewBufferFromString(string(input), "", buffer.BTDefault))
+	}
+	for _, a := range args {
+		b, err := buffer.NewBufferFromFile(a, buffer.BTDefault)
+		if err != nil {
+			fail(err)
+			return 1
+		}
+		bufs = append(bufs, b)
+	}
+	action.InitTabs(bufs)
+
+	for _, fn := range []string{"init", "postinit"} {
+		if err := config.RunPluginFn(fn); err != nil {
+			fail(err)
+		}
+	}
+
+	commands := splitCommands(*flagBatch)
+	status := 0
+	for i, tab := range action.Tabs.List {
+		action.Tabs.SetActive(i)
+		h := tab.CurPane()
+		b := h.Buf
+		name := b.GetName()
+		if stdin {
+			name = "stdin"
+		}
+
+		if err := batchEdit(h, commands, *flagLua); err != nil {
+			fail(name+":", err)
+			status = 1
+			continue
+		}
+		if stdin {
+			os.Stdout.Write(b.Bytes())
+		} else if b.Modified() {
+			if err := b.Save(); err != nil {
+				fail(name+":", err)
+				status = 1
+			}
+		}
+	}
+
+	for _, b := range bufs {
+		b.Fini()
+	}
+	screen.Fini()
+	return status
+}
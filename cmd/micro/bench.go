@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// benchWorkloads are the workloads replayed by -bench, in the order in which
+// 'all' replays them
+var benchWorkloads = []string{"typing", "scroll", "cursor"}
+
+// benchText is the text typed by the typing workload
+const benchText = "The quick brown fox jumps over the lazy dog."
+
+// A benchResult is the latency of each event of a workload, from the event
+// to the screen drawn after it, and the memory allocated by all of them
+type benchResult struct {
+	Name      string
+	Latencies []time.Duration
+	Mallocs   uint64
+	Bytes     uint64
+}
+
+// percentile returns the latency under which the fraction p of the sorted
+// latencies are
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[util.Clamp(i, 0, len(sorted)-1)]
+}
+
+// String formats the result as a line of the output of go test -bench, for
+// benchstat to compare the results of two versions
+func (r benchResult) String() string {
+	n := len(r.Latencies)
+	if n == 0 {
+		return fmt.Sprintf("Benchmark%s\t0\n", strings.Title(r.Name))
+	}
+	sorted := append([]time.Duration(nil), r.Latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return fmt.Sprintf("Benchmark%s\t%d\t%d ns/op\t%d p50-ns\t%d p90-ns\t%d p99-ns\t%d max-ns\t%d B/op\t%d allocs/op\n",
+		strings.Title(r.Name), n, int64(total)/int64(n),
+		int64(percentile(sorted, 0.5)), int64(percentile(sorted, 0.9)), int64(percentile(sorted, 0.99)),
+		int64(sorted[n-1]), r.Bytes/uint64(n), r.Mallocs/uint64(n))
+}
+
+// benchEvents returns the events of the workload name for the pane
+func benchEvents(name string, h *action.BufPane) []tcell.Event {
+	key := func(k tcell.Key, r rune) tcell.Event {
+		return tcell.NewEventKey(k, r, tcell.ModNone, string(r))
+	}
+	var events []tcell.Event
+	switch name {
+	case "typing":
+		// sentences typed in the middle of the file, the last word of each
+		// being erased and typed again
+		for len(events) < 2000 {
+			for _, r := range benchText {
+				events = append(events, key(tcell.KeyRune, r))
+			}
+			word := benchText[strings.LastIndexByte(benchText, ' ')+1:]
+			for range word {
+				events = append(events, key(tcell.KeyBackspace2, rune(tcell.KeyBackspace2)))
+			}
+			for _, r := range word {
+				events = append(events, key(tcell.KeyRune, r))
+			}
+			events = append(events, key(tcell.KeyEnter, '\r'))
+		}
+	case "scroll":
+		// the mouse wheel down and then up again
+		v := h.GetView()
+		x, y := v.X+v.Width/2, v.Y+v.Height/2
+		for i := 0; i < 1000; i++ {
+			button := tcell.WheelDown
+			if i >= 500 {
+				button = tcell.WheelUp
+			}
+			events = append(events, tcell.NewEventMouse(x, y, button, tcell.ModNone, ""))
+		}
+	case "cursor":
+		// the cursor down, a page at a time and then back up
+		for i := 0; i < 1000; i++ {
+			k := tcell.KeyDown
+			switch {
+			case i >= 400 && i < 500:
+				k = tcell.KeyPgDn
+			case i >= 500 && i < 600:
+				k = tcell.KeyPgUp
+			case i >= 600:
+				k = tcell.KeyUp
+			}
+			events = append(events, key(k, 0))
+		}
+	}
+	return events
+}
+
+// runBench replays the workload name in the pane, drawing the screen after
+// each event as the event loop does
+func runBench(name string, h *action.BufPane) benchResult {
+	if name == "typing" {
+		h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: h.Buf.LinesNum() / 2})
+		h.Relocate()
+	}
+	events := benchEvents(name, h)
+	drawScreen()
+
+	r := benchResult{Name: name, Latencies: make([]time.Duration, 0, len(events))}
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for _, e := range events {
+		start := time.Now()
+		action.Tabs.HandleEvent(e)
+		drawScreen()
+		r.Latencies = append(r.Latencies, time.Since(start))
+	}
+	runtime.ReadMemStats(&after)
+	r.Mallocs = after.Mallocs - before.Mallocs
+	r.Bytes = after.TotalAlloc - before.TotalAlloc
+	return r
+}
+
+// RunBench replays the workload given to -bench, or all of them, on the
+// file in a simulated screen and prints the results in the format of go
+// test -bench. The file is never written. Returns the exit status.
+func RunBench(workload string, args []string) int {
+	fail := func(msg ...interface{}) int {
+		fmt.Fprintln(os.Stderr, msg...)
+		return 1
+	}
+
+	workloads := []string{workload}
+	if workload == "all" {
+		workloads = benchWorkloads
+	} else if !contains(benchWorkloads, workload) {
+		return fail("Unknown workload " + workload + ", use typing, scroll, cursor or all")
+	}
+	if len(args) != 1 {
+		return fail("-bench needs one file")
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	// nothing of the buffers is written anywhere
+	for _, o := range []string{"backup", "drafts", "filelock", "savecursor", "saveundo"} {
+		config.GlobalSettings[o] = false
+	}
+	config.GlobalSettings["autosave"] = float64(0)
+
+	s, err := screen.InitSimScreen()
+	if err != nil {
+		return fail(err)
+	}
+	defer s.Fini()
+	s.SetSize(120, 40)
+	screen.Events = make(chan tcell.Event, 8)
+
+	if err := config.LoadAllPlugins(); err != nil {
+		fail(err)
+	}
+	action.InitBindings()
+	action.InitCommands()
+	if err := config.InitColorscheme(); err != nil {
+		fail(err)
+	}
+	if err := config.RunPluginFn("preinit"); err != nil {
+		fail(err)
+	}
+	action.InitGlobals()
+	buffer.SetMessager(action.InfoBar)
+
+	fmt.Printf("micro: %s\nfile: %s\n", util.Version, args[0])
+	for i, w := range workloads {
+		// each workload edits a fresh copy of the file
+		b := buffer.NewBufferFromString(string(data), args[0], buffer.BTDefault)
+		action.InitTabs([]*buffer.Buffer{b})
+		if i == 0 {
+			if err := config.RunPluginFn("init"); err != nil {
+				fail(err)
+			}
+			if err := config.RunPluginFn("postinit"); err != nil {
+				fail(err)
+			}
+		}
+		fmt.Print(runBench(w, action.MainTab().CurPane()))
+	}
+	return 0
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,30 +2,26 @@ package main
 
 import (
 	"log"
-	"os"
 
+	"github.com/zyedidia/micro/v2/internal/config"
 	"github.com/zyedidia/micro/v2/internal/util"
 )
 
-// NullWriter simply sends writes into the void
-type NullWriter struct{}
-
-// Write is empty
-func (NullWriter) Write(data []byte) (n int, err error) {
-	return 0, nil
-}
-
-// InitLog sets up the debug log system for micro if it has been enabled by compile-time variables
+// InitLog sets up the log from the loglevel and logfilter options, at the
+// debug level with -debug or in the debug builds, and writes it to log.txt
+// in the state directory. The messages of the log package are logged as
+// debug messages.
 func InitLog() {
 	if util.Debug == "ON" {
-		f, err := os.OpenFile("log.txt", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-		if err != nil {
-			log.Fatalf("error opening file: %v", err)
-		}
+		config.GlobalSettings["loglevel"] = "debug"
+	}
+	level, _ := util.ParseLogLevel(config.GetGlobalOption("loglevel").(string))
+	util.SetLogLevel(level)
+	util.SetLogFilter(config.GetGlobalOption("logfilter").(string))
 
-		log.SetOutput(f)
-		log.Println("Micro started")
-	} else {
-		log.SetOutput(NullWriter{})
+	log.SetFlags(0)
+	log.SetOutput(util.LogWriter(util.LogDebug, "editor"))
+	if err := util.OpenLogFile(config.LogFile()); err != nil {
+		util.Log(util.LogError, "editor", "Error opening the log file:", err)
 	}
 }
@@ -1,8 +1,6 @@
 package main
 
 import (
-	"log"
-
 	lua "github.com/yuin/gopher-lua"
 	luar "layeh.com/gopher-luar"
 
@@ -19,10 +17,21 @@ import (
 func init() {
 	ulua.L = lua.NewState()
 	ulua.L.SetGlobal("import", luar.New(ulua.L, LuaImport))
+	ulua.PluginImport = func(plugin string) lua.LValue {
+		return luar.New(ulua.L, func(pkg string) *lua.LTable {
+			return pluginImport(plugin, pkg)
+		})
+	}
 }
 
 // LuaImport is meant to be called from lua by a plugin and will import the given micro package
 func LuaImport(pkg string) *lua.LTable {
+	return pluginImport("", pkg)
+}
+
+// pluginImport imports the package for the plugin, whose name is bound to
+// the functions of the package needing it, or for no plugin if it is empty
+func pluginImport(plugin, pkg string) *lua.LTable {
 	switch pkg {
 	case "micro":
 		return luaImportMicro()
@@ -31,7 +40,7 @@ func LuaImport(pkg string) *lua.LTable {
 	case "micro/buffer":
 		return luaImportMicroBuffer()
 	case "micro/config":
-		return luaImportMicroConfig()
+		return luaImportMicroConfig(plugin)
 	case "micro/util":
 		return luaImportMicroUtil()
 	default:
@@ -45,7 +54,17 @@ func luaImportMicro() *lua.LTable {
 	ulua.L.SetField(pkg, "TermMessage", luar.New(ulua.L, screen.TermMessage))
 	ulua.L.SetField(pkg, "TermError", luar.New(ulua.L, screen.TermError))
 	ulua.L.SetField(pkg, "InfoBar", luar.New(ulua.L, action.GetInfoBar))
-	ulua.L.SetField(pkg, "Log", luar.New(ulua.L, log.Println))
+	ulua.L.SetField(pkg, "Log", luar.New(ulua.L, func(args ...interface{}) {
+		util.Log(util.LogDebug, "lua", args...)
+	}))
+	ulua.L.SetField(pkg, "LogAt", luar.New(ulua.L, func(level string, args ...interface{}) error {
+		l, err := util.ParseLogLevel(level)
+		if err == nil {
+			util.Log(l, "lua", args...)
+		}
+		return err
+	}))
+	ulua.L.SetField(pkg, "Speak", luar.New(ulua.L, action.Speak))
 	ulua.L.SetField(pkg, "SetStatusInfoFn", luar.New(ulua.L, display.SetStatusInfoFnLua))
 	ulua.L.SetField(pkg, "CurPane", luar.New(ulua.L, func() action.Pane {
 		return action.MainTab().CurPane()
@@ -55,11 +74,15 @@ func luaImportMicro() *lua.LTable {
 		return action.Tabs
 	}))
 	ulua.L.SetField(pkg, "Lock", luar.New(ulua.L, ulua.Lock))
+	ulua.L.SetField(pkg, "NewPopup", luar.New(ulua.L, display.NewPopup))
+	ulua.L.SetField(pkg, "PopupAt", luar.New(ulua.L, display.PopupAt))
+	ulua.L.SetField(pkg, "SetFiletypeIcon", luar.New(ulua.L, display.SetFiletypeIcon))
+	ulua.L.SetField(pkg, "SetTargetResolver", luar.New(ulua.L, action.SetTargetResolver))
 
 	return pkg
 }
 
-func luaImportMicroConfig() *lua.LTable {
+func luaImportMicroConfig(plugin string) *lua.LTable {
 	pkg := ulua.L.NewTable()
 
 	ulua.L.SetField(pkg, "MakeCommand", luar.New(ulua.L, action.MakeCommand))
@@ -67,9 +90,20 @@ func luaImportMicroConfig() *lua.LTable {
 	ulua.L.SetField(pkg, "HelpComplete", luar.New(ulua.L, action.HelpComplete))
 	ulua.L.SetField(pkg, "OptionComplete", luar.New(ulua.L, action.OptionComplete))
 	ulua.L.SetField(pkg, "OptionValueComplete", luar.New(ulua.L, action.OptionValueComplete))
+	ulua.L.SetField(pkg, "BufferNameComplete", luar.New(ulua.L, action.BufferNameComplete))
+	ulua.L.SetField(pkg, "ColorschemeComplete", luar.New(ulua.L, action.ColorschemeComplete))
+	ulua.L.SetField(pkg, "PluginNameComplete", luar.New(ulua.L, action.PluginNameComplete))
+	ulua.L.SetField(pkg, "FiletypeComplete", luar.New(ulua.L, action.FiletypeComplete))
+	ulua.L.SetField(pkg, "ActionComplete", luar.New(ulua.L, action.ActionComplete))
+	ulua.L.SetField(pkg, "ArgComplete", luar.New(ulua.L, action.ArgComplete))
 	ulua.L.SetField(pkg, "NoComplete", luar.New(ulua.L, nil))
-	ulua.L.SetField(pkg, "TryBindKey", luar.New(ulua.L, action.TryBindKey))
+	ulua.L.SetField(pkg, "TryBindKey", luar.New(ulua.L, action.PluginTryBindKey))
 	ulua.L.SetField(pkg, "Reload", luar.New(ulua.L, action.ReloadConfig))
+	if plugin != "" {
+		ulua.L.SetField(pkg, "GetSecret", luar.New(ulua.L, func(name string) (string, error) {
+			return config.PluginGetSecret(plugin, name)
+		}))
+	}
 	ulua.L.SetField(pkg, "AddRuntimeFileFromMemory", luar.New(ulua.L, config.PluginAddRuntimeFileFromMemory))
 	ulua.L.SetField(pkg, "AddRuntimeFilesFromDirectory", luar.New(ulua.L, config.PluginAddRuntimeFilesFromDirectory))
 	ulua.L.SetField(pkg, "AddRuntimeFile", luar.New(ulua.L, config.PluginAddRuntimeFile))
@@ -80,12 +114,17 @@ func luaImportMicroConfig() *lua.LTable {
 	ulua.L.SetField(pkg, "RTSyntax", luar.New(ulua.L, config.RTSyntax))
 	ulua.L.SetField(pkg, "RTHelp", luar.New(ulua.L, config.RTHelp))
 	ulua.L.SetField(pkg, "RTPlugin", luar.New(ulua.L, config.RTPlugin))
+	ulua.L.SetField(pkg, "RTSnippet", luar.New(ulua.L, config.RTSnippet))
+	ulua.L.SetField(pkg, "RTAbbrev", luar.New(ulua.L, config.RTAbbrev))
+	ulua.L.SetField(pkg, "RTLayout", luar.New(ulua.L, config.RTLayout))
 	ulua.L.SetField(pkg, "RegisterCommonOption", luar.New(ulua.L, config.RegisterCommonOptionPlug))
 	ulua.L.SetField(pkg, "RegisterGlobalOption", luar.New(ulua.L, config.RegisterGlobalOptionPlug))
 	ulua.L.SetField(pkg, "GetGlobalOption", luar.New(ulua.L, config.GetGlobalOption))
 	ulua.L.SetField(pkg, "SetGlobalOption", luar.New(ulua.L, action.SetGlobalOption))
 	ulua.L.SetField(pkg, "SetGlobalOptionNative", luar.New(ulua.L, action.SetGlobalOptionNative))
 	ulua.L.SetField(pkg, "ConfigDir", luar.New(ulua.L, config.ConfigDir))
+	ulua.L.SetField(pkg, "StateDir", luar.New(ulua.L, config.StateDir))
+	ulua.L.SetField(pkg, "DataDir", luar.New(ulua.L, config.DataDir))
 
 	return pkg
 }
@@ -136,6 +175,8 @@ func luaImportMicroBuffer() *lua.LTable {
 	ulua.L.SetField(pkg, "ByteOffset", luar.New(ulua.L, buffer.ByteOffset))
 	ulua.L.SetField(pkg, "Log", luar.New(ulua.L, buffer.WriteLog))
 	ulua.L.SetField(pkg, "LogBuf", luar.New(ulua.L, buffer.GetLogBuf))
+	ulua.L.SetField(pkg, "AddCompletionSource", luar.New(ulua.L, buffer.AddCompletionSource))
+	ulua.L.SetField(pkg, "AddCompletionTrigger", luar.New(ulua.L, buffer.AddCompletionTrigger))
 
 	return pkg
 }
@@ -151,9 +192,39 @@ func luaImportMicroUtil() *lua.LTable {
 	ulua.L.SetField(pkg, "Version", luar.New(ulua.L, util.Version))
 	ulua.L.SetField(pkg, "SemVersion", luar.New(ulua.L, util.SemVersion))
 	ulua.L.SetField(pkg, "CharacterCountInString", luar.New(ulua.L, util.CharacterCountInString))
+	ulua.L.SetField(pkg, "FuzzyMatch", luar.New(ulua.L, util.BuiltinFuzzyMatch))
+	ulua.L.SetField(pkg, "SetFuzzyScorer", luar.New(ulua.L, luaSetFuzzyScorer))
 	ulua.L.SetField(pkg, "RuneStr", luar.New(ulua.L, func(r rune) string {
 		return string(r)
 	}))
 
 	return pkg
 }
+
+// luaSetFuzzyScorer makes the Lua function fn score the fuzzy matches: it
+// is called with the pattern and the string and returns the score of the
+// match, or nil or false if the string doesn't match. A nil fn goes back
+// to the fuzzymatcher option. The built-in matcher is used when fn fails.
+func luaSetFuzzyScorer(fn lua.LValue) {
+	if fn == lua.LNil {
+		util.FuzzyScorer = nil
+		return
+	}
+	util.FuzzyScorer = func(pattern, str string) (int, bool) {
+		err := ulua.L.CallByParam(lua.P{
+			Fn:      fn,
+			NRet:    1,
+			Protect: true,
+		}, lua.LString(pattern), lua.LString(str))
+		if err != nil {
+			util.Log(util.LogError, "lua", "fuzzy scorer:", err)
+			return util.BuiltinFuzzyMatch(pattern, str)
+		}
+		ret := ulua.L.Get(-1)
+		ulua.L.Pop(1)
+		if n, ok := ret.(lua.LNumber); ok {
+			return int(n), true
+		}
+		return 0, false
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/signal"
 	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,6 +21,7 @@ import (
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/clipboard"
 	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/display"
 	ulua "github.com/zyedidia/micro/v2/internal/lua"
 	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/micro/v2/internal/shell"
@@ -36,13 +37,27 @@ var (
 	flagVersion   = flag.Bool("version", false, "Show the version number and information")
 	flagConfigDir = flag.String("config-dir", "", "Specify a custom location for the configuration directory")
 	flagOptions   = flag.Bool("options", false, "Show all option help")
-	flagDebug     = flag.Bool("debug", false, "Enable debug mode (prints debug info to ./log.txt)")
+	flagDebug     = flag.Bool("debug", false, "Log the debug messages to log.txt in the state directory")
 	flagPlugin    = flag.String("plugin", "", "Plugin command")
 	flagClean     = flag.Bool("clean", false, "Clean configuration directory")
+	flagDiff      = flag.Bool("d", false, "Compare two files side by side")
+	flagFilter    = flag.Bool("filter", false, "Edit the standard input and write it to the standard output")
+	flagRemote    = flag.Bool("remote", false, "Send a command to a running instance")
+	flagPrint     = flag.String("print", "", "Print files with their highlighting as html or ansi")
+	flagBatch     = flag.String("batch", "", "Apply commands to files without the interface")
+	flagLua       = flag.String("lua", "", "Run a Lua script on files without the interface")
+	flagReadonly  = flag.Bool("ro", false, "Open the files readonly")
+	flagProfile   = flag.Bool("profile", false, "Print the startup timings on exit")
+	flagBench     = flag.String("bench", "", "Replay a workload on a file and print the latency of its events")
 	optionFlags   map[string]*string
 
 	sigterm chan os.Signal
 	sighup  chan os.Signal
+
+	// inputTime is when the input event handled last was received, until
+	// the screen is drawn after it, for the latency measured by the health
+	// command
+	inputTime time.Time
 )
 
 func InitFlags() {
@@ -52,13 +67,43 @@ func InitFlags() {
 		fmt.Println("    \tCleans the configuration directory")
 		fmt.Println("-config-dir dir")
 		fmt.Println("    \tSpecify a custom location for the configuration directory")
-		fmt.Println("[FILE]:LINE:COL (if the `parsecursor` option is enabled)")
-		fmt.Println("+LINE:COL")
+		fmt.Println("[FILE]:LINE:COL")
+		fmt.Println("+LINE:COL [FILE]")
 		fmt.Println("    \tSpecify a line and column to start the cursor at when opening a buffer")
+		fmt.Println("    \t(FILE:LINE:COL if there is no file with this name or `parsecursor` is on)")
+		fmt.Println("+/REGEX [FILE]")
+		fmt.Println("    \tStart the cursor at the first match of the regex, searched by FindNext")
 		fmt.Println("-options")
 		fmt.Println("    \tShow all option help")
+		fmt.Println("-ro [FILE]...")
+		fmt.Println("    \tOpen the files readonly, refusing edits until the unlock command")
+		fmt.Println("-d FILE1 FILE2")
+		fmt.Println("    \tCompare two files side by side")
+		fmt.Println("-filter [FILE]...")
+		fmt.Println("    \tEdit the standard input, saved to the standard output on exit,")
+		fmt.Println("    \tas in: cmd | micro -filter | cmd")
+		fmt.Println("-print html|ansi [FILE]...")
+		fmt.Println("    \tPrint the files, or the standard input, with their highlighting to")
+		fmt.Println("    \tthe standard output as an HTML page or with ANSI colors")
+		fmt.Println("-batch 'COMMAND; COMMAND' [FILE]...")
+		fmt.Println("    \tApply commands to the files and save them without the interface")
+		fmt.Println("    \t(without files, the standard input is written to the standard output)")
+		fmt.Println("-lua SCRIPT [FILE]...")
+		fmt.Println("    \tRun a Lua script on each file, after the -batch commands, and save it")
+		fmt.Println("-remote open [-wait] [+LINE:COL] FILE[:LINE:COL]...")
+		fmt.Println("    \tOpen files in the running instance, or else in a new one, waiting")
+		fmt.Println("    \tuntil they are closed with -wait (as in GIT_EDITOR)")
+		fmt.Println("-remote goto LINE[:COL]")
+		fmt.Println("-remote eval 'COMMAND; COMMAND'")
+		fmt.Println("    \tMove the cursor or run commands in the running instance")
+		fmt.Println("-profile")
+		fmt.Println("    \tPrint the time taken by each step of the startup, such as loading")
+		fmt.Println("    \tthe plugins, and the input latency when micro exits")
+		fmt.Println("-bench typing|scroll|cursor|all FILE")
+		fmt.Println("    \tReplay synthetic key and mouse events on the file, without saving it,")
+		fmt.Println("    \tand print the latency percentiles and the allocations of each event")
 		fmt.Println("-debug")
-		fmt.Println("    \tEnable debug mode (enables logging to ./log.txt)")
+		fmt.Println("    \tLog the debug messages to log.txt in the state directory")
 		fmt.Println("-version")
 		fmt.Println("    \tShow the version number and information")
 
@@ -137,6 +182,74 @@ func DoPluginFlags() {
 	}
 }
 
+// A fileArg is a file given on the command line along with the position to
+// open it at, {-1, -1} for none, or the regex to search for
+type fileArg struct {
+	path   string
+	loc    buffer.Loc
+	search string
+}
+
+// parseFileArgs returns the files of the command line with their positions,
+// given by +LINE:COL or +/regex before a file, or by FILE:LINE:COL (see
+// buffer.SplitPathLocation). A position after the last file applies to it.
+func parseFileArgs(args []string) []fileArg {
+	var files []fileArg
+	pos := fileArg{loc: buffer.Loc{X: -1, Y: -1}}
+	pending := false
+	flagr := regexp.MustCompile(`^\+(\d+)(?::(\d+))?$`)
+	for _, a := range args {
+		if strings.HasPrefix(a, "+/") && len(a) > 2 {
+			pos = fileArg{loc: buffer.Loc{X: -1, Y: -1}, search: a[2:]}
+			pending = true
+			continue
+		}
+		if match := flagr.FindStringSubmatch(a); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			col := 1
+			if match[2] != "" {
+				col, _ = strconv.Atoi(match[2])
+			}
+			pos = fileArg{loc: buffer.Loc{X: util.Max(col-1, 0), Y: line - 1}}
+			pending = true
+			continue
+		}
+
+		f := pos
+		f.path = a
+		if !pending {
+			if path, loc, ok := buffer.SplitPathLocation(a); ok {
+				f.path, f.loc = path, loc
+			}
+		}
+		files = append(files, f)
+		pos = fileArg{loc: buffer.Loc{X: -1, Y: -1}}
+		pending = false
+	}
+	if n := len(files); pending && n > 0 && files[n-1].loc.Y < 0 && files[n-1].search == "" {
+		pos.path = files[n-1].path
+		files[n-1] = pos
+	}
+	return files
+}
+
+// searchStart moves the cursor of the buffer to the first match of the
+// regex, which becomes the search of its pane for FindNext
+func searchStart(b *buffer.Buffer, search string) {
+	match, found, err := b.FindNext(search, b.Start(), b.End(), b.Start(), true, true)
+	if err != nil {
+		screen.TermMessage(err)
+		return
+	}
+	b.LastSearch = search
+	b.LastSearchRegex = true
+	b.HighlightSearch = true
+	if found {
+		b.StartCursor = match[0]
+		b.GetActiveCursor().GotoLoc(match[0])
+	}
+}
+
 // LoadInput determines which files should be loaded into buffers
 // based on the input stored in flag.Args()
 func LoadInput(args []string) []*buffer.Buffer {
@@ -151,57 +264,51 @@ func LoadInput(args []string) []*buffer.Buffer {
 	// 3. If there is no input file and the input is a terminal, an empty buffer
 	// should be opened
 
+	// 4. In filter mode (-filter), the stdin is opened before the files, in a
+	// buffer saved to the stdout, which is empty if the input is a terminal
+
 	var filename string
 	var input []byte
 	var err error
 	buffers := make([]*buffer.Buffer, 0, len(args))
 
 	btype := buffer.BTDefault
-	if !isatty.IsTerminal(os.Stdout.Fd()) {
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !*flagFilter {
 		btype = buffer.BTStdout
 	}
 
-	files := make([]string, 0, len(args))
-	flagStartPos := buffer.Loc{-1, -1}
-	flagr := regexp.MustCompile(`^\+(\d+)(?::(\d+))?$`)
-	for _, a := range args {
-		match := flagr.FindStringSubmatch(a)
-		if len(match) == 3 && match[2] != "" {
-			line, err := strconv.Atoi(match[1])
+	if *flagFilter {
+		// Option 4
+		if !isatty.IsTerminal(os.Stdin.Fd()) {
+			input, err = ioutil.ReadAll(os.Stdin)
 			if err != nil {
-				screen.TermMessage(err)
-				continue
-			}
-			col, err := strconv.Atoi(match[2])
-			if err != nil {
-				screen.TermMessage(err)
-				continue
-			}
-			flagStartPos = buffer.Loc{col - 1, line - 1}
-		} else if len(match) == 3 && match[2] == "" {
-			line, err := strconv.Atoi(match[1])
-			if err != nil {
-				screen.TermMessage(err)
-				continue
+				screen.TermMessage("Error reading from stdin: ", err)
+				input = []byte{}
 			}
-			flagStartPos = buffer.Loc{0, line - 1}
-		} else {
-			files = append(files, a)
 		}
+		buf := buffer.NewBufferFromString(string(input), filename, buffer.BTFilter)
+		buf.SetName("stdin")
+		buffers = append(buffers, buf)
 	}
 
+	files := parseFileArgs(args)
 	if len(files) > 0 {
 		// Option 1
 		// We go through each file and load it
-		for i := 0; i < len(files); i++ {
-			buf, err := buffer.NewBufferFromFileAtLoc(files[i], btype, flagStartPos)
+		for _, f := range files {
+			buf, err := buffer.NewBufferFromFileAtLoc(f.path, btype, f.loc)
 			if err != nil {
 				screen.TermMessage(err)
 				continue
 			}
+			if f.search != "" {
+				searchStart(buf, f.search)
+			}
 			// If the file didn't exist, input will be empty, and we'll open an empty buffer
 			buffers = append(buffers, buf)
 		}
+	} else if *flagFilter {
+		// Option 4, the stdin is already opened
 	} else if !isatty.IsTerminal(os.Stdin.Fd()) {
 		// Option 2
 		// The input is not a terminal, so something is being piped in
@@ -211,10 +318,16 @@ func LoadInput(args []string) []*buffer.Buffer {
 			screen.TermMessage("Error reading from stdin: ", err)
 			input = []byte{}
 		}
-		buffers = append(buffers, buffer.NewBufferFromStringAtLoc(string(input), filename, btype, flagStartPos))
+		buffers = append(buffers, buffer.NewBufferFromString(string(input), filename, btype))
 	} else {
 		// Option 3, just open an empty buffer
-		buffers = append(buffers, buffer.NewBufferFromStringAtLoc(string(input), filename, btype, flagStartPos))
+		buffers = append(buffers, buffer.NewBufferFromString(string(input), filename, btype))
+	}
+
+	if *flagReadonly {
+		for _, b := range buffers {
+			b.SetOptionNative("readonly", true)
+		}
 	}
 
 	return buffers
@@ -237,8 +350,7 @@ func main() {
 
 	InitFlags()
 
-	InitLog()
-
+	start := time.Now()
 	err = config.InitConfigDir(*flagConfigDir)
 	if err != nil {
 		screen.TermMessage(err)
@@ -265,15 +377,43 @@ func main() {
 			config.GlobalSettings[k] = nativeValue
 		}
 	}
+	InitLog()
+	// the runtime files given by URL which are not cached are fetched in
+	// the background, so that micro starts with the cache alone
+	fetching := config.FetchRuntime(false)
+	util.RecordTiming("config", start)
+
+	args := flag.Args()
+	if *flagRemote {
+		args = RunRemote(args)
+	}
 
 	DoPluginFlags()
 
+	if *flagPrint != "" {
+		os.Exit(RunPrint(*flagPrint, args))
+	}
+	if *flagBatch != "" || *flagLua != "" {
+		os.Exit(RunBatch(args))
+	}
+	if *flagBench != "" {
+		os.Exit(RunBench(*flagBench, args))
+	}
+
+	start = time.Now()
 	err = screen.Init()
 	if err != nil {
 		fmt.Println(err)
 		fmt.Println("Fatal: Micro could not initialize a Screen.")
 		os.Exit(1)
 	}
+	util.RecordTiming("screen", start)
+	if *flagProfile {
+		util.MeasureLatency = true
+		defer func() {
+			fmt.Fprint(util.Stdout, action.HealthReport())
+		}()
+	}
 
 	sigterm = make(chan os.Signal, 1)
 	sighup = make(chan os.Signal, 1)
@@ -282,17 +422,25 @@ func main() {
 
 	m := clipboard.SetMethod(config.GetGlobalOption("clipboard").(string))
 	clipErr := clipboard.Initialize(m)
+	clipboard.SetSyncedRegister(config.GetGlobalOption("clipboardsync").(string))
+	clipboard.SetMaxTerminalSize(int(config.GetGlobalOption("osc52maxsize").(float64)))
+	util.SetFuzzyMatcher(config.GetGlobalOption("fuzzymatcher").(string), config.GetGlobalOption("fuzzycase").(string))
 
 	defer func() {
 		if err := recover(); err != nil {
 			if screen.Screen != nil {
-				screen.Screen.Fini()
+				screen.Fini()
 			}
 			if e, ok := err.(*lua.ApiError); ok {
 				fmt.Println("Lua API error:", e)
 			} else {
 				fmt.Println("Micro encountered an error:", errors.Wrap(err, 2).ErrorStack(), "\nIf you can reproduce this error, please report it at https://github.com/zyedidia/micro/issues")
 			}
+			if dir, err := action.EmergencySave("crash"); err != nil {
+				fmt.Println("Error saving the unsaved buffers:", err)
+			} else if dir != "" {
+				fmt.Println("The unsaved buffers were written to", dir, "and micro will offer to restore them when it starts again")
+			}
 			// backup all open buffers
 			for _, b := range buffer.OpenBuffers {
 				b.Backup()
@@ -309,10 +457,12 @@ func main() {
 	action.InitBindings()
 	action.InitCommands()
 
+	start = time.Now()
 	err = config.InitColorscheme()
-	if err != nil {
+	if err != nil && !(fetching && config.IsRemote(config.GlobalSettings["colorscheme"].(string))) {
 		screen.TermMessage(err)
 	}
+	util.RecordTiming("colorscheme", start)
 
 	err = config.RunPluginFn("preinit")
 	if err != nil {
@@ -321,16 +471,34 @@ func main() {
 
 	action.InitGlobals()
 	buffer.SetMessager(action.InfoBar)
-	args := flag.Args()
+	start = time.Now()
 	b := LoadInput(args)
 
 	if len(b) == 0 {
 		// No buffers to open
-		screen.Screen.Fini()
+		screen.Fini()
 		runtime.Goexit()
 	}
 
-	action.InitTabs(b)
+	if *flagDiff && len(b) == 2 {
+		action.InitTabs(b[:1])
+		action.MainTab().CurPane().DiffSplit(b[1])
+	} else {
+		if *flagDiff {
+			screen.TermMessage("Diff mode needs two files to compare")
+		}
+		action.InitTabs(b)
+	}
+	util.RecordTiming("buffers", start)
+
+	if len(args) == 0 && isatty.IsTerminal(os.Stdin.Fd()) && !*flagFilter {
+		// the autosession is loaded once the recovery is declined
+		if !action.OfferRecovery(true) {
+			action.LoadAutosession()
+		}
+	} else if !*flagFilter {
+		action.OfferRecovery(false)
+	}
 
 	err = config.RunPluginFn("init")
 	if err != nil {
@@ -343,13 +511,17 @@ func main() {
 	}
 
 	if clipErr != nil {
-		log.Println(clipErr, " or change 'clipboard' option")
+		util.Log(util.LogWarn, "editor", clipErr, "or change the clipboard option")
 	}
 
+	closeRemote := StartRemote()
+	defer closeRemote()
+
 	if a := config.GetGlobalOption("autosave").(float64); a > 0 {
 		config.SetAutoTime(int(a))
 		config.StartAutoSave()
 	}
+	config.StartLocalHistory(config.GetGlobalOption("localhistory").(float64))
 
 	screen.Events = make(chan tcell.Event)
 
@@ -384,20 +556,46 @@ func main() {
 	}
 }
 
-// DoEvent runs the main action loop of the editor
-func DoEvent() {
-	var event tcell.Event
+// screenLayout describes the panes shown on the screen, for the display to
+// redraw everything when it changes
+func screenLayout() string {
+	s := fmt.Sprint(action.Tabs.Active(), len(action.Tabs.List))
+	for _, p := range action.MainTab().Visible() {
+		v := p.GetView()
+		s += fmt.Sprintf("|%p %d %d %d %d", p, v.X, v.Y, v.Width, v.Height)
+	}
+	return s
+}
 
-	// Display everything
-	screen.Screen.Fill(' ', config.DefStyle)
+// drawScreen displays everything. Only what changed is redrawn, unless the
+// layout of the screen changed.
+func drawScreen() {
+	if display.StartFrame(screenLayout()) {
+		screen.Screen.Fill(' ', config.DefStyle)
+	}
 	screen.Screen.HideCursor()
 	action.Tabs.Display()
-	for _, ep := range action.MainTab().Panes {
+	for _, ep := range action.MainTab().Visible() {
 		ep.Display()
 	}
 	action.MainTab().Display()
 	action.InfoBar.Display()
+	display.DisplayPopups()
 	screen.Screen.Show()
+	display.FlushImages()
+	action.Tabs.UpdateTerminal()
+}
+
+// DoEvent runs the main action loop of the editor
+func DoEvent() {
+	var event tcell.Event
+
+	drawScreen()
+	util.FinishStartup()
+	if !inputTime.IsZero() {
+		util.RecordLatency(time.Since(inputTime))
+		inputTime = time.Time{}
+	}
 
 	// Check for new events
 	select {
@@ -406,12 +604,42 @@ func DoEvent() {
 		ulua.Lock.Lock()
 		f.Function(f.Output, f.Args)
 		ulua.Lock.Unlock()
+	case f := <-buffer.MessageUpdates:
+		ulua.Lock.Lock()
+		f()
+		action.Announce()
+		ulua.Lock.Unlock()
+	case b := <-buffer.FollowUpdates:
+		ulua.Lock.Lock()
+		action.FollowFile(b)
+		action.Announce()
+		ulua.Lock.Unlock()
+	case e := <-util.LogUpdates:
+		ulua.Lock.Lock()
+		action.WriteLog(e.String() + "\n")
+		ulua.Lock.Unlock()
 	case <-config.Autosave:
 		ulua.Lock.Lock()
 		for _, b := range buffer.OpenBuffers {
 			b.Save()
 		}
 		ulua.Lock.Unlock()
+	case <-config.LocalHistory:
+		ulua.Lock.Lock()
+		for _, b := range buffer.OpenBuffers {
+			if err := b.SnapshotHistory(); err != nil {
+				util.Log(util.LogWarn, "buffer", "Error adding", b.Path, "to the local history:", err)
+			}
+		}
+		ulua.Lock.Unlock()
+	case r := <-config.RemoteResults:
+		ulua.Lock.Lock()
+		action.RuntimeFetched(r)
+		ulua.Lock.Unlock()
+	case f := <-remoteRequests:
+		ulua.Lock.Lock()
+		f()
+		ulua.Lock.Unlock()
 	case <-shell.CloseTerms:
 	case event = <-screen.Events:
 	case <-screen.DrawChan():
@@ -419,6 +647,7 @@ func DoEvent() {
 			<-screen.DrawChan()
 		}
 	case <-sighup:
+		action.EmergencySave("hangup")
 		for _, b := range buffer.OpenBuffers {
 			if !b.Modified() {
 				b.Fini()
@@ -426,6 +655,7 @@ func DoEvent() {
 		}
 		os.Exit(0)
 	case <-sigterm:
+		dir, err := action.EmergencySave("terminated")
 		for _, b := range buffer.OpenBuffers {
 			if !b.Modified() {
 				b.Fini()
@@ -433,18 +663,36 @@ func DoEvent() {
 		}
 
 		if screen.Screen != nil {
-			screen.Screen.Fini()
+			screen.Fini()
+		}
+		if err != nil {
+			fmt.Println("Error saving the unsaved buffers:", err)
+		} else if dir != "" {
+			fmt.Println("The unsaved buffers were written to", dir, "and micro will offer to restore them when it starts again")
 		}
 		os.Exit(0)
 	}
 
+	events := []tcell.Event{event}
+	if event != nil {
+		events = collectPaste(event)
+	}
+	switch event.(type) {
+	case *tcell.EventKey, *tcell.EventMouse, *tcell.EventPaste:
+		if util.MeasureLatency {
+			inputTime = event.When()
+		}
+	}
+
 	ulua.Lock.Lock()
-	// if event != nil {
-	if action.InfoBar.HasPrompt {
-		action.InfoBar.HandleEvent(event)
-	} else {
-		action.Tabs.HandleEvent(event)
+	for _, event := range events {
+		event = action.KeyboardEvent(event)
+		if action.InfoBar.HasPrompt {
+			action.InfoBar.HandleEvent(event)
+		} else {
+			action.Tabs.HandleEvent(event)
+		}
 	}
-	// }
+	action.Announce()
 	ulua.Lock.Unlock()
 }
@@ -5,13 +5,18 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
 	"github.com/zyedidia/micro/v2/internal/action"
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
 	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/tcell/v2"
 )
@@ -330,10 +335,200 @@ func TestSearchAndReplace(t *testing.T) {
 	assert.Equal(t, srTest3, string(data))
 }
 
+func TestParseFileArgs(t *testing.T) {
+	file, err := createTestFile("micro_args_test", "a\n")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(file)
+
+	none := buffer.Loc{X: -1, Y: -1}
+	assert.Equal(t, []fileArg{
+		{path: "a", loc: buffer.Loc{X: 4, Y: 9}},
+		{path: "b", loc: none},
+		{path: "c", loc: none, search: "func main"},
+		{path: file, loc: buffer.Loc{X: 1, Y: 2}},
+		{path: "d", loc: buffer.Loc{X: 0, Y: 6}},
+	}, parseFileArgs([]string{"+10:5", "a", "b", "+/func main", "c", file + ":3:2", "d", "+7"}))
+}
+
+func TestSplitCommands(t *testing.T) {
+	assert.Equal(t, []string{"replace a b -a", "replace ';' ',' -a", "filter sort"},
+		splitCommands("replace a b -a; replace ';' ',' -a\n\nfilter sort;"))
+	assert.Equal(t, []string{`replace "a\";" b`}, splitCommands(`replace "a\";" b`))
+}
+
+func TestBatchEdit(t *testing.T) {
+	file, err := createTestFile("micro_batch_test", "foo bar\n")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(file)
+
+	openFile(file)
+	h := action.MainTab().CurPane()
+
+	err = batchEdit(h, []string{"replace foo baz -a", "filter tr a-z A-Z"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "BAZ BAR\n", string(h.Buf.Bytes()))
+
+	// a command waiting for an answer fails, as unknown ones do
+	err = batchEdit(h, []string{"replace BAR qux", "filter cat"}, "")
+	assert.Error(t, err)
+	assert.False(t, action.InfoBar.HasPrompt)
+	assert.Error(t, batchEdit(h, []string{"nosuchcommand"}, ""))
+	assert.Equal(t, "BAZ BAR\n", string(h.Buf.Bytes()))
+}
+
+func TestRemote(t *testing.T) {
+	file, err := createTestFile("micro_remote_test", "a\nbc\nd\n")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(file)
+
+	dir, name := filepath.Split(file)
+	opened, err := handleRemote(remoteRequest{Command: "open", Args: []string{name + ":2:2"}, Dir: dir})
+	assert.NoError(t, err)
+	assert.Len(t, opened, 1)
+	h := action.MainTab().CurPane()
+	assert.Equal(t, opened[0], h.Buf)
+	assert.Equal(t, buffer.Loc{X: 1, Y: 1}, h.Cursor.Loc)
+
+	// the pane already showing the file is reused
+	tabs := len(action.Tabs.List)
+	_, err = handleRemote(remoteRequest{Command: "open", Args: []string{"+3", file}})
+	assert.NoError(t, err)
+	assert.Equal(t, tabs, len(action.Tabs.List))
+	assert.Equal(t, buffer.Loc{X: 0, Y: 2}, h.Cursor.Loc)
+
+	_, err = handleRemote(remoteRequest{Command: "goto", Args: []string{"1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, h.Cursor.Y)
+
+	_, err = handleRemote(remoteRequest{Command: "eval", Args: []string{"replaceall a x"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "x\nbc\nd\n", string(h.Buf.Bytes()))
+	assert.False(t, action.InfoBar.HasError)
+
+	_, err = handleRemote(remoteRequest{Command: "nosuch"})
+	assert.Error(t, err)
+}
+
+func TestSecureRemoteDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-remote")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	oldState := config.StateDir
+	defer func() { config.StateDir = oldState }()
+	config.StateDir = dir
+
+	// the mode of a directory which is there already is tightened
+	assert.NoError(t, os.Mkdir(remoteDir(), 0755))
+	assert.NoError(t, secureRemoteDir())
+	info, err := os.Stat(remoteDir())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	os.Remove(remoteDir())
+	assert.NoError(t, ioutil.WriteFile(remoteDir(), nil, 0600))
+	assert.Error(t, secureRemoteDir())
+}
+
+func TestPasteBurst(t *testing.T) {
+	file, err := createTestFile("micro_paste_test", "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(file)
+
+	h, err := action.OpenFileAt(file, buffer.Loc{X: -1, Y: -1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// characters sent faster than they are typed are inserted as a paste,
+	// without autoindent and undone at once
+	text := "if x {\n\ty()\n}\nthe end of the paste"
+	go func() {
+		for _, r := range text {
+			switch r {
+			case '\n':
+				screen.Events <- tcell.NewEventKey(tcell.KeyEnter, '\r', tcell.ModNone, "\r")
+			case '\t':
+				screen.Events <- tcell.NewEventKey(tcell.KeyTab, '\t', tcell.ModNone, "\t")
+			default:
+				screen.Events <- tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone, string(r))
+			}
+		}
+	}()
+	DoEvent()
+	assert.Equal(t, text, string(h.Buf.Bytes()))
+	h.Undo()
+	assert.Equal(t, "", string(h.Buf.Bytes()))
+}
+
+func TestBench(t *testing.T) {
+	ms := func(ns ...int) []time.Duration {
+		var ds []time.Duration
+		for _, n := range ns {
+			ds = append(ds, time.Duration(n))
+		}
+		return ds
+	}
+	sorted := ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	assert.Equal(t, time.Duration(5), percentile(sorted, 0.5))
+	assert.Equal(t, time.Duration(9), percentile(sorted, 0.9))
+	assert.Equal(t, time.Duration(10), percentile(sorted, 0.99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.5))
+	assert.Equal(t, "BenchmarkCursor\t2\t2 ns/op\t1 p50-ns\t3 p90-ns\t3 p99-ns\t3 max-ns\t5 B/op\t2 allocs/op\n",
+		benchResult{Name: "cursor", Latencies: ms(3, 1), Mallocs: 4, Bytes: 10}.String())
+
+	b := buffer.NewBufferFromString("a\nb\nc\n", "", buffer.BTDefault)
+	action.InitTabs([]*buffer.Buffer{b})
+	h := action.MainTab().CurPane()
+	r := runBench("typing", h)
+	assert.Len(t, r.Latencies, len(benchEvents("typing", h)))
+	assert.True(t, strings.HasPrefix(string(b.Bytes()), "a\nb\n"+benchText+"\n"+benchText+"\n"))
+}
+
 func TestMultiCursor(t *testing.T) {
 	// TODO
 }
 
+func TestPluginSecrets(t *testing.T) {
+	defer config.LockSecrets()
+	defer func(v interface{}) { config.GlobalSettings["secretcmd"] = v }(config.GlobalSettings["secretcmd"])
+	config.GlobalSettings["secretcmd"] = ""
+	assert.NoError(t, config.UnlockSecrets("master"))
+	defer os.Remove(config.SecretsPath())
+	assert.NoError(t, config.SetSecret("gist.token", "s3cret"))
+
+	// a plugin reads its own secrets by their name, and no others
+	get := func(plugin, name string) string {
+		src := `local config = import("micro/config")
+			value, err = config.GetSecret("` + name + `")`
+		assert.NoError(t, ulua.LoadFile(plugin, plugin+".lua", []byte(src)))
+		return ulua.L.GetField(ulua.L.GetGlobal(plugin), "value").String()
+	}
+	assert.Equal(t, "s3cret", get("gist", "token"))
+	assert.Equal(t, "", get("other", "token"))
+	assert.Equal(t, "", get("other", "gist.token"))
+
+	// nor through the global import, which the module of a plugin falls
+	// back to
+	assert.NoError(t, ulua.LoadFile("other", "other.lua", []byte(`
+		value = _G.import("micro/config").GetSecret == nil and gist.import("micro/config").GetSecret == nil`)))
+	assert.Equal(t, lua.LTrue, ulua.L.GetField(ulua.L.GetGlobal("other"), "value"))
+}
+
 func TestSettingsPersistence(t *testing.T) {
 	// TODO
 }
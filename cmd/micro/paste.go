@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// pasteBurst is the number of characters received back to back from which
+// they are handled as a paste: typing doesn't send them that fast, but a
+// terminal pasting text without bracketed paste does
+const pasteBurst = 16
+
+// pasteWait is how long a character waits for the next one of a burst
+const pasteWait = time.Millisecond
+
+// pasteText returns the text a key event types, if it is a character, a tab
+// or a line break
+func pasteText(event tcell.Event) (string, bool) {
+	e, ok := event.(*tcell.EventKey)
+	if !ok || e.Modifiers() != tcell.ModNone {
+		return "", false
+	}
+	switch e.Key() {
+	case tcell.KeyRune:
+		return string(e.Rune()), true
+	case tcell.KeyEnter:
+		return "\n", true
+	case tcell.KeyTab:
+		return "\t", true
+	}
+	return "", false
+}
+
+// collectPaste returns the events to handle for event. The characters
+// received right after a character are joined into a single paste event
+// when there are many of them, so that a paste which isn't bracketed by the
+// terminal is still inserted as one edit, undone at once and drawn once,
+// without autoindent, rather than typed a character at a time.
+func collectPaste(event tcell.Event) []tcell.Event {
+	events := []tcell.Event{event}
+	text, ok := pasteText(event)
+	if !ok || action.InfoBar.HasPrompt {
+		return events
+	}
+
+	var sb, esc strings.Builder
+	sb.WriteString(text)
+	esc.WriteString(event.EscSeq())
+	timer := time.NewTimer(pasteWait)
+	defer timer.Stop()
+	for {
+		select {
+		case e := <-screen.Events:
+			events = append(events, e)
+			t, ok := pasteText(e)
+			if !ok {
+				return joinPaste(events, len(events)-1, sb.String(), esc.String())
+			}
+			sb.WriteString(t)
+			esc.WriteString(e.EscSeq())
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(pasteWait)
+		case <-timer.C:
+			return joinPaste(events, len(events), sb.String(), esc.String())
+		}
+	}
+}
+
+// joinPaste replaces the first n events, characters typing text, with a
+// paste event if there are enough of them
+func joinPaste(events []tcell.Event, n int, text, esc string) []tcell.Event {
+	if n < pasteBurst {
+		return events
+	}
+	return append([]tcell.Event{tcell.NewEventPaste(text, esc)}, events[n:]...)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// RunPrint writes the files, or the standard input, to the standard output
+// with their highlighting in the format given to -print, html or ansi (see
+// the export command), and returns the exit status
+func RunPrint(format string, args []string) int {
+	fail := func(msg ...interface{}) {
+		fmt.Fprintln(os.Stderr, msg...)
+	}
+
+	if err := config.InitColorscheme(); err != nil {
+		fail(err)
+	}
+	// the buffers are highlighted here rather than in the background
+	syntax := config.GlobalSettings["syntax"].(bool)
+	config.GlobalSettings["syntax"] = false
+
+	var bufs []*buffer.Buffer
+	if len(args) == 0 {
+		input, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fail("Error reading from stdin:", err)
+			return 1
+		}
+		bufs = append(bufs, buffer.NewBufferFromString(string(input), "", buffer.BTDefault))
+	}
+	for _, a := range args {
+		b, err := buffer.NewBufferFromFile(a, buffer.BTDefault)
+		if err != nil {
+			fail(err)
+			return 1
+		}
+		bufs = append(bufs, b)
+	}
+
+	for _, b := range bufs {
+		if syntax && b.Highlighter != nil {
+			b.Settings["syntax"] = true
+			b.Highlighter.HighlightStates(b)
+			b.Highlighter.HighlightMatches(b, 0, b.End().Y)
+		}
+		out, err := action.Export(b, format, b.Start(), b.End())
+		if err != nil {
+			fail(err)
+			return 1
+		}
+		fmt.Print(out)
+	}
+	return 0
+}
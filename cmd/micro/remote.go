@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/action"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A remoteRequest is sent by `micro -remote` to a running instance through
+// its control socket, as a line of JSON answered by a remoteResponse
+type remoteRequest struct {
+	// Command is open, goto or eval
+	Command string
+	Args    []string
+	// Dir is the working directory of the client, which relative paths are
+	// relative to
+	Dir string
+	// Wait keeps the response of open until the files are closed
+	Wait bool
+}
+
+type remoteResponse struct {
+	Output string
+	Error  string
+}
+
+// remoteRequests receives the requests of the control socket, which are
+// handled in the event loop
+var remoteRequests = make(chan func(), 8)
+
+// errNoInstance is returned when no running micro can be reached
+var errNoInstance = errors.New("No running micro instance found")
+
+// remoteDir is the directory of the control sockets of the instances
+// using this configuration directory
+func remoteDir() string {
+	return filepath.Join(config.StateDir, "remote")
+}
+
+// StartRemote opens the control socket of this instance if the remotecontrol
+// option is on, and sets MICRO_REMOTE to its path for the programs started by
+// micro, such as the shells of terminal panes. It returns the function
+// closing the socket.
+func StartRemote() func() {
+	if !config.GetGlobalOption("remotecontrol").(bool) {
+		return func() {}
+	}
+	if err := secureRemoteDir(); err != nil {
+		util.Log(util.LogError, "rpc", "Error creating the remote socket directory:", err)
+		return func() {}
+	}
+	path := filepath.Join(remoteDir(), strconv.Itoa(os.Getpid())+".sock")
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err == nil {
+		// the socket runs any command, so only the user may connect to it
+		if err = os.Chmod(path, 0600); err != nil {
+			l.Close()
+		}
+	}
+	if err != nil {
+		util.Log(util.LogError, "rpc", "Error opening the remote socket:", err)
+		return func() {}
+	}
+	os.Setenv("MICRO_REMOTE", path)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveRemote(conn)
+		}
+	}()
+	return func() {
+		l.Close()
+		os.Remove(path)
+	}
+}
+
+// secureRemoteDir creates the directory of the control sockets, which only
+// the user may open, and checks that another user does not own it
+func secureRemoteDir() error {
+	dir := remoteDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() || ownedByOther(info) {
+		return errors.New(dir + " is not a directory owned by the user")
+	}
+	// MkdirAll leaves the mode of a directory which is there already
+	return os.Chmod(dir, 0700)
+}
+
+// onMainLoop runs f in the event loop and returns its result
+func onMainLoop(f func() remoteResponse) remoteResponse {
+	done := make(chan remoteResponse, 1)
+	remoteRequests <- func() {
+		done <- f()
+	}
+	return <-done
+}
+
+func serveRemote(conn net.Conn) {
+	defer conn.Close()
+
+	var req remoteRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		util.Log(util.LogWarn, "rpc", "Invalid remote request:", err)
+		return
+	}
+	util.Log(util.LogDebug, "rpc", "Remote request:", req.Command, req.Args)
+	var opened []*buffer.Buffer
+	resp := onMainLoop(func() remoteResponse {
+		var err error
+		opened, err = handleRemote(req)
+		if err != nil {
+			util.Log(util.LogInfo, "rpc", "Remote request", req.Command, "failed:", err)
+			return remoteResponse{Error: err.Error()}
+		}
+		if action.InfoBar.HasError {
+			return remoteResponse{Error: action.InfoBar.Msg}
+		}
+		if action.InfoBar.HasMessage {
+			return remoteResponse{Output: action.InfoBar.Msg}
+		}
+		return remoteResponse{}
+	})
+
+	// the files are polled until they are all closed, the connection being
+	// closed in the meantime if micro exits
+	for req.Wait && resp.Error == "" && len(opened) > 0 {
+		time.Sleep(200 * time.Millisecond)
+		onMainLoop(func() remoteResponse {
+			open := opened[:0]
+			for _, b := range opened {
+				for _, o := range buffer.OpenBuffers {
+					if o == b {
+						open = append(open, b)
+						break
+					}
+				}
+			}
+			opened = open
+			return remoteResponse{}
+		})
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// handleRemote executes a request and returns the buffers it opened
+func handleRemote(req remoteRequest) ([]*buffer.Buffer, error) {
+	relative := func(path string) string {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(req.Dir, path)
+		}
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				return rel
+			}
+		}
+		return path
+	}
+
+	action.InfoBar.Reset()
+	switch req.Command {
+	case "open":
+		var opened []*buffer.Buffer
+		args := make([]string, len(req.Args))
+		for i, a := range req.Args {
+			args[i] = a
+			if !strings.HasPrefix(a, "+") {
+				args[i] = relative(a)
+			}
+		}
+		for _, f := range parseFileArgs(args) {
+			h, err := action.OpenFileAt(f.path, f.loc)
+			if err != nil {
+				return opened, err
+			}
+			if f.search != "" {
+				searchStart(h.Buf, f.search)
+				h.Relocate()
+			}
+			opened = append(opened, h.Buf)
+		}
+		if len(opened) == 0 {
+			return nil, errors.New("usage: micro -remote open [-wait] [+LINE:COL|+/REGEX] FILE...")
+		}
+		return opened, nil
+	case "goto", "eval":
+		h := action.MainTab().CurPane()
+		if h == nil {
+			return nil, errors.New("The current pane isn't a buffer")
+		}
+		if req.Command == "goto" {
+			h.GotoCmd(req.Args)
+		} else {
+			for _, c := range splitCommands(strings.Join(req.Args, " ")) {
+				h.HandleCommand(c)
+				if action.InfoBar.HasError {
+					break
+				}
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Unknown remote command %s (expected open, goto or eval)", req.Command)
+}
+
+// remoteSocket returns the control socket of the instance to send requests
+// to, given by MICRO_REMOTE or else the last started instance
+func remoteSocket() (net.Conn, error) {
+	if path := os.Getenv("MICRO_REMOTE"); path != "" {
+		if conn, err := net.Dial("unix", path); err == nil {
+			return conn, nil
+		}
+	}
+	files, _ := ioutil.ReadDir(remoteDir())
+	var last net.Conn
+	var lastTime time.Time
+	for _, f := range files {
+		path := filepath.Join(remoteDir(), f.Name())
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			// the instance is gone
+			os.Remove(path)
+			continue
+		}
+		if last == nil || f.ModTime().After(lastTime) {
+			if last != nil {
+				last.Close()
+			}
+			last, lastTime = conn, f.ModTime()
+		} else {
+			conn.Close()
+		}
+	}
+	if last == nil {
+		return nil, errNoInstance
+	}
+	return last, nil
+}
+
+// RunRemote sends the command given to -remote to a running instance and
+// exits with its result. If there is none, the files of open are returned so
+// that they are opened by this one instead.
+func RunRemote(args []string) []string {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: micro -remote open|goto|eval ARGS...")
+		os.Exit(1)
+	}
+	req := remoteRequest{Command: args[0]}
+	for _, a := range args[1:] {
+		if req.Command == "open" && (a == "-wait" || a == "--wait") {
+			req.Wait = true
+		} else {
+			req.Args = append(req.Args, a)
+		}
+	}
+	req.Dir, _ = os.Getwd()
+
+	conn, err := remoteSocket()
+	if err == errNoInstance && req.Command == "open" {
+		return req.Args
+	}
+	var resp remoteResponse
+	if err == nil {
+		defer conn.Close()
+		if err = json.NewEncoder(conn).Encode(req); err == nil {
+			err = json.NewDecoder(conn).Decode(&resp)
+		}
+		// the files waited for were closed along with micro
+		if err == io.EOF && req.Wait {
+			os.Exit(0)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if resp.Error != "" {
+		fmt.Fprintln(os.Stderr, resp.Error)
+		os.Exit(1)
+	}
+	if resp.Output != "" {
+		fmt.Println(resp.Output)
+	}
+	os.Exit(0)
+	return nil
+}
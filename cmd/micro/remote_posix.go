@@ -0,0 +1,15 @@
+// +build linux darwin dragonfly solaris openbsd netbsd freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByOther returns whether the file is owned by another user than the
+// one running micro
+func ownedByOther(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) != os.Getuid()
+}
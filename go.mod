@@ -2,6 +2,7 @@ module github.com/zyedidia/micro/v2
 
 require (
 	github.com/blang/semver v3.5.1+incompatible
+	github.com/dlclark/regexp2 v1.4.0
 	github.com/dustin/go-humanize v1.0.0
 	github.com/go-errors/errors v1.0.1
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
@@ -22,6 +23,7 @@ require (
 	golang.org/x/text v0.3.2
 	gopkg.in/sourcemap.v1 v1.0.5 // indirect
 	gopkg.in/yaml.v2 v2.2.7
+	gopkg.in/yaml.v3 v3.0.1
 	layeh.com/gopher-luar v1.0.7
 )
 
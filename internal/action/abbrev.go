@@ -0,0 +1,27 @@
+package action
+
+import "github.com/zyedidia/micro/v2/internal/buffer"
+
+// expandAbbreviation replaces the abbreviation ending at end, after which
+// a character which isn't part of a word was typed, by its expansion. The
+// expansion is undone on its own, which gives the abbreviation back.
+func (h *BufPane) expandAbbreviation(end buffer.Loc) {
+	if h.noAbbrev {
+		h.noAbbrev = false
+		return
+	}
+	exp, start, ok := h.Buf.AbbreviationBefore(end)
+	if !ok {
+		return
+	}
+	h.Buf.UndoGroup(func() {
+		h.Buf.Replace(start, end, exp)
+	})
+}
+
+// SuppressAbbreviation keeps the next abbreviation typed as it is
+func (h *BufPane) SuppressAbbreviation() bool {
+	h.noAbbrev = true
+	InfoBar.Message("The next abbreviation is not expanded")
+	return true
+}
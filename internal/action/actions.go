@@ -3,6 +3,7 @@ package action
 import (
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -42,78 +43,169 @@ func (h *BufPane) ScrollAdjust() {
 	h.SetView(v)
 }
 
+// dragScrollBar scrolls the view when the scrollbar is clicked or dragged
+// with the mouse, and returns whether it was
+func (h *BufPane) dragScrollBar(mx, my int) bool {
+	w, ok := h.BWindow.(*display.BufWindow)
+	if !ok {
+		return false
+	}
+	if _, on := w.ScrollBarLine(mx, my); !on && !h.scrollBarDrag {
+		return false
+	}
+	h.scrollBarDrag = true
+	v := h.GetView()
+	v.StartLine = display.SLoc{Line: w.ScrollBarDrag(my)}
+	h.SetView(v)
+	h.ScrollAdjust()
+	return true
+}
+
+// wheelButtons are the buttons of the mouse wheel, which don't click
+const wheelButtons = tcell.WheelUp | tcell.WheelDown | tcell.WheelLeft | tcell.WheelRight
+
+// countClicks counts the quick clicks of a mouse button at the same place,
+// and returns 2 or 3 for the double and triple clicks and the drags which
+// follow them, or 0 for a single click. A click following a triple click
+// is a double click again.
+func (h *BufPane) countClicks(e *tcell.EventMouse) int {
+	btn := e.Buttons()
+	if btn == tcell.ButtonNone {
+		h.mouseButton = tcell.ButtonNone
+		return 0
+	}
+	if btn&wheelButtons != 0 {
+		return 0
+	}
+	if btn != h.mouseButton {
+		mx, my := e.Position()
+		mouseLoc := h.LocFromVisual(buffer.Loc{X: mx, Y: my})
+		if btn == h.clickButton && mouseLoc == h.lastLoc && time.Since(h.lastClickTime)/time.Millisecond < config.DoubleClickThreshold {
+			if h.clicks == 3 {
+				h.clicks = 2
+			} else {
+				h.clicks++
+			}
+		} else {
+			h.clicks = 1
+		}
+		h.mouseButton, h.clickButton = btn, btn
+		h.lastClickTime = time.Now()
+		h.lastLoc = mouseLoc
+	}
+	if h.clicks > 1 {
+		return h.clicks
+	}
+	return 0
+}
+
+// pressMouse moves the cursor to the location clicked, removing the other
+// cursors, when the mouse button is pressed
+func (h *BufPane) pressMouse(mouseLoc buffer.Loc) {
+	if h.Buf.NumCursors() > 1 {
+		h.Buf.ClearCursors()
+		h.Relocate()
+		h.Cursor = h.Buf.GetActiveCursor()
+	}
+	h.Cursor.Loc = mouseLoc
+	h.mouseReleased = false
+}
+
 // MousePress is the event that should happen when a normal click happens
 // This is almost always bound to left click
 func (h *BufPane) MousePress(e *tcell.EventMouse) bool {
-	b := h.Buf
 	mx, my := e.Position()
 	mouseLoc := h.LocFromVisual(buffer.Loc{mx, my})
 	h.Cursor.Loc = mouseLoc
 	if h.mouseReleased {
-		if b.NumCursors() > 1 {
-			b.ClearCursors()
-			h.Relocate()
-			h.Cursor = h.Buf.GetActiveCursor()
-			h.Cursor.Loc = mouseLoc
-		}
-		if time.Since(h.lastClickTime)/time.Millisecond < config.DoubleClickThreshold && (mouseLoc.X == h.lastLoc.X && mouseLoc.Y == h.lastLoc.Y) {
-			if h.doubleClick {
-				// Triple click
-				h.lastClickTime = time.Now()
-
-				h.tripleClick = true
-				h.doubleClick = false
-
-				h.Cursor.SelectLine()
-				h.Cursor.CopySelection(clipboard.PrimaryReg)
-			} else {
-				// Double click
-				h.lastClickTime = time.Now()
-
-				h.doubleClick = true
-				h.tripleClick = false
+		h.pressMouse(mouseLoc)
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+		h.Cursor.CurSelection[0] = h.Cursor.Loc
+		h.Cursor.CurSelection[1] = h.Cursor.Loc
+	} else {
+		h.Cursor.SetSelectionEnd(h.Cursor.Loc)
+	}
 
-				h.Cursor.SelectWord()
-				h.Cursor.CopySelection(clipboard.PrimaryReg)
-			}
-		} else {
-			h.doubleClick = false
-			h.tripleClick = false
-			h.lastClickTime = time.Now()
+	h.Cursor.StoreVisualX()
+	h.Relocate()
+	return true
+}
 
-			h.Cursor.OrigSelection[0] = h.Cursor.Loc
-			h.Cursor.CurSelection[0] = h.Cursor.Loc
-			h.Cursor.CurSelection[1] = h.Cursor.Loc
-		}
-		h.mouseReleased = false
-	} else if !h.mouseReleased {
-		if h.tripleClick {
-			h.Cursor.AddLineToSelection()
-		} else if h.doubleClick {
-			h.Cursor.AddWordToSelection()
-		} else {
-			h.Cursor.SetSelectionEnd(h.Cursor.Loc)
-		}
+// mouseSelect selects the text clicked with sel, and extends the selection
+// with add while the mouse is dragged
+func (h *BufPane) mouseSelect(e *tcell.EventMouse, sel, add func(*buffer.Cursor)) bool {
+	mx, my := e.Position()
+	mouseLoc := h.LocFromVisual(buffer.Loc{X: mx, Y: my})
+	if h.mouseReleased {
+		h.pressMouse(mouseLoc)
+		sel(h.Cursor)
+		h.Cursor.CopySelection(clipboard.PrimaryReg)
+	} else {
+		h.Cursor.Loc = mouseLoc
+		add(h.Cursor)
 	}
 
 	h.Cursor.StoreVisualX()
-	h.lastLoc = mouseLoc
 	h.Relocate()
 	return true
 }
 
+// MouseSelectWord selects the word clicked, and then by words while the
+// mouse is dragged. It is bound to the double click by default.
+func (h *BufPane) MouseSelectWord(e *tcell.EventMouse) bool {
+	return h.mouseSelect(e, (*buffer.Cursor).SelectWord, (*buffer.Cursor).AddWordToSelection)
+}
+
+// MouseSelectLine selects the line clicked, and then by lines while the
+// mouse is dragged. It is bound to the triple click by default.
+func (h *BufPane) MouseSelectLine(e *tcell.EventMouse) bool {
+	return h.mouseSelect(e, (*buffer.Cursor).SelectLine, (*buffer.Cursor).AddLineToSelection)
+}
+
 // ScrollUpAction scrolls the view up
 func (h *BufPane) ScrollUpAction() bool {
-	h.ScrollUp(util.IntOpt(h.Buf.Settings["scrollspeed"]))
+	h.smoothScroll(func() {
+		h.ScrollUp(util.IntOpt(h.Buf.Settings["scrollspeed"]))
+	})
 	return true
 }
 
 // ScrollDownAction scrolls the view up
 func (h *BufPane) ScrollDownAction() bool {
-	h.ScrollDown(util.IntOpt(h.Buf.Settings["scrollspeed"]))
+	h.smoothScroll(func() {
+		h.ScrollDown(util.IntOpt(h.Buf.Settings["scrollspeed"]))
+	})
+	return true
+}
+
+// scrollHorizontal scrolls the view n columns to the right, or to the
+// left if n is negative, without going past the longest line in view
+func (h *BufPane) scrollHorizontal(n int) bool {
+	if h.setting("softwrap").(bool) {
+		return false
+	}
+	v := h.GetView()
+	tabsize := util.IntOpt(h.Buf.Settings["tabsize"])
+	width := 0
+	for y := v.StartLine.Line; y < util.Min(v.StartLine.Line+v.Height, h.Buf.LinesNum()); y++ {
+		l := h.Buf.LineBytes(y)
+		width = util.Max(width, util.StringWidth(l, util.CharacterCount(l), tabsize))
+	}
+	v.StartCol = util.Clamp(v.StartCol+n, 0, util.Max(width-1, 0))
+	h.SetView(v)
 	return true
 }
 
+// ScrollLeftAction scrolls the view left, when softwrap is off
+func (h *BufPane) ScrollLeftAction() bool {
+	return h.scrollHorizontal(-util.IntOpt(h.Buf.Settings["scrollspeed"]))
+}
+
+// ScrollRightAction scrolls the view right, when softwrap is off
+func (h *BufPane) ScrollRightAction() bool {
+	return h.scrollHorizontal(util.IntOpt(h.Buf.Settings["scrollspeed"]))
+}
+
 // Center centers the view on the cursor
 func (h *BufPane) Center() bool {
 	v := h.GetView()
@@ -125,7 +217,7 @@ func (h *BufPane) Center() bool {
 
 // MoveCursorUp is not an action
 func (h *BufPane) MoveCursorUp(n int) {
-	if !h.Buf.Settings["softwrap"].(bool) {
+	if !h.setting("softwrap").(bool) {
 		h.Cursor.UpN(n)
 	} else {
 		vloc := h.VLocFromLoc(h.Cursor.Loc)
@@ -144,7 +236,7 @@ func (h *BufPane) MoveCursorUp(n int) {
 
 // MoveCursorDown is not an action
 func (h *BufPane) MoveCursorDown(n int) {
-	if !h.Buf.Settings["softwrap"].(bool) {
+	if !h.setting("softwrap").(bool) {
 		h.Cursor.DownN(n)
 	} else {
 		vloc := h.VLocFromLoc(h.Cursor.Loc)
@@ -343,6 +435,68 @@ func (h *BufPane) StartOfTextToggle() bool {
 	return true
 }
 
+// smartHome moves the cursor to the start of the text of the line, or to
+// the start of the line if it is there already, or always to the start of
+// the line when the smarthome option is off
+func (h *BufPane) smartHome() {
+	if h.Buf.Settings["smarthome"].(bool) && !h.Cursor.IsStartOfText() {
+		h.Cursor.StartOfText()
+	} else {
+		h.Cursor.Start()
+	}
+}
+
+// smartEnd moves the cursor to the end of the text of the line, before the
+// trailing whitespace, or to the end of the line if it is there already,
+// or always to the end of the line when the smartend option is off
+func (h *BufPane) smartEnd() {
+	if h.Buf.Settings["smartend"].(bool) && !h.Cursor.IsEndOfText() {
+		h.Cursor.EndOfText()
+	} else {
+		h.Cursor.End()
+	}
+}
+
+// SmartHome moves the cursor to the start of the text of the line or to the
+// start of the line, see the smarthome option
+func (h *BufPane) SmartHome() bool {
+	h.Cursor.Deselect(true)
+	h.smartHome()
+	h.Relocate()
+	return true
+}
+
+// SmartEnd moves the cursor to the end of the text of the line or to the
+// end of the line, see the smartend option
+func (h *BufPane) SmartEnd() bool {
+	h.Cursor.Deselect(true)
+	h.smartEnd()
+	h.Relocate()
+	return true
+}
+
+// SelectToSmartHome selects to where SmartHome moves the cursor
+func (h *BufPane) SelectToSmartHome() bool {
+	if !h.Cursor.HasSelection() {
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+	}
+	h.smartHome()
+	h.Cursor.SelectTo(h.Cursor.Loc)
+	h.Relocate()
+	return true
+}
+
+// SelectToSmartEnd selects to where SmartEnd moves the cursor
+func (h *BufPane) SelectToSmartEnd() bool {
+	if !h.Cursor.HasSelection() {
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+	}
+	h.smartEnd()
+	h.Cursor.SelectTo(h.Cursor.Loc)
+	h.Relocate()
+	return true
+}
+
 // StartOfLine moves the cursor to the start of the line
 func (h *BufPane) StartOfLine() bool {
 	h.Cursor.Deselect(true)
@@ -451,6 +605,70 @@ func (h *BufPane) ParagraphNext() bool {
 	return true
 }
 
+// SelectParagraphPrevious selects to the previous empty line, or the
+// beginning of the buffer if there's none
+func (h *BufPane) SelectParagraphPrevious() bool {
+	if !h.Cursor.HasSelection() {
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+	}
+	h.ParagraphPrevious()
+	h.Cursor.SelectTo(h.Cursor.Loc)
+	h.Relocate()
+	return true
+}
+
+// SelectParagraphNext selects to the next empty line, or the end of the
+// buffer if there's none
+func (h *BufPane) SelectParagraphNext() bool {
+	if !h.Cursor.HasSelection() {
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+	}
+	h.ParagraphNext()
+	h.Cursor.SelectTo(h.Cursor.Loc)
+	h.Relocate()
+	return true
+}
+
+// SentencePrevious moves the cursor to the start of the sentence, or of the
+// previous one if it is already there
+func (h *BufPane) SentencePrevious() bool {
+	h.Cursor.Deselect(true)
+	h.Cursor.GotoLoc(h.Buf.SentencePrevious(h.Cursor.Loc))
+	h.Relocate()
+	return true
+}
+
+// SentenceNext moves the cursor to the start of the next sentence
+func (h *BufPane) SentenceNext() bool {
+	h.Cursor.Deselect(false)
+	h.Cursor.GotoLoc(h.Buf.SentenceNext(h.Cursor.Loc))
+	h.Relocate()
+	return true
+}
+
+// SelectSentencePrevious selects to the start of the sentence, or of the
+// previous one
+func (h *BufPane) SelectSentencePrevious() bool {
+	if !h.Cursor.HasSelection() {
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+	}
+	h.Cursor.GotoLoc(h.Buf.SentencePrevious(h.Cursor.Loc))
+	h.Cursor.SelectTo(h.Cursor.Loc)
+	h.Relocate()
+	return true
+}
+
+// SelectSentenceNext selects to the start of the next sentence
+func (h *BufPane) SelectSentenceNext() bool {
+	if !h.Cursor.HasSelection() {
+		h.Cursor.OrigSelection[0] = h.Cursor.Loc
+	}
+	h.Cursor.GotoLoc(h.Buf.SentenceNext(h.Cursor.Loc))
+	h.Cursor.SelectTo(h.Cursor.Loc)
+	h.Relocate()
+	return true
+}
+
 // Retab changes all tabs to spaces or all spaces to tabs depending
 // on the user's settings
 func (h *BufPane) Retab() bool {
@@ -461,6 +679,9 @@ func (h *BufPane) Retab() bool {
 
 // CursorStart moves the cursor to the start of the buffer
 func (h *BufPane) CursorStart() bool {
+	if h.Buf.Huge != nil {
+		h.Buf.HugeLine(0)
+	}
 	h.Cursor.Deselect(true)
 	h.Cursor.X = 0
 	h.Cursor.Y = 0
@@ -471,6 +692,9 @@ func (h *BufPane) CursorStart() bool {
 
 // CursorEnd moves the cursor to the end of the buffer
 func (h *BufPane) CursorEnd() bool {
+	if h.Buf.Huge != nil {
+		h.Buf.HugeLine(h.Buf.FileLinesNum() - 1)
+	}
 	h.Cursor.Deselect(true)
 	h.Cursor.Loc = h.Buf.End()
 	h.Cursor.StoreVisualX()
@@ -502,21 +726,47 @@ func (h *BufPane) SelectToEnd() bool {
 
 // InsertNewline inserts a newline plus possible some whitespace if autoindent is on
 func (h *BufPane) InsertNewline() bool {
+	// the virtual space past the end of the line is dropped
+	h.Cursor.Virtual = 0
 	// Insert a newline
 	if h.Cursor.HasSelection() {
 		h.Cursor.DeleteSelection()
 		h.Cursor.ResetSelection()
 	}
+	h.expandAbbreviation(h.Cursor.Loc)
+
+	// with indent rules, the indentation of the new line depends on the
+	// line before it, which is dedented first if it is a closing line
+	autoindent := h.Buf.Settings["autoindent"].(bool)
+	rules := autoindent && h.Buf.HasIndentRules()
+	if rules && h.Buf.DecreasesIndent(h.Cursor.Y) {
+		h.Buf.Reindent(h.Cursor.Y, h.Cursor.Y)
+	}
+
+	// without indent rules, splitting an automatically closed bracket pair
+	// leaves the closing bracket on its own line
+	pair := !rules && h.Buf.Settings["autoclose"].(bool) && h.Buf.BetweenPair(h.Cursor.Loc) &&
+		h.Buf.RuneAt(h.Cursor.Loc) != h.Buf.RuneAt(h.Cursor.Loc.Move(-1, h.Buf))
 
 	ws := util.GetLeadingWhitespace(h.Buf.LineBytes(h.Cursor.Y))
 	cx := h.Cursor.X
 	h.Buf.Insert(h.Cursor.Loc, "\n")
 	// h.Cursor.Right()
 
-	if h.Buf.Settings["autoindent"].(bool) {
-		if cx < len(ws) {
-			ws = ws[0:cx]
+	if rules {
+		// splitting a line between an opening and a closing text, such as
+		// {}, leaves an indented line between them
+		if y := h.Cursor.Y; h.Buf.IncreasesIndent(y-1) && h.Buf.DecreasesIndent(y) {
+			h.Buf.Insert(h.Cursor.Loc, "\n")
+			h.Buf.Reindent(y+1, y+1)
+			h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: y})
 		}
+		ws = []byte(h.Buf.LineIndent(h.Cursor.Y))
+	} else if cx < len(ws) {
+		ws = ws[0:cx]
+	}
+
+	if autoindent {
 		h.Buf.Insert(h.Cursor.Loc, string(ws))
 		// for i := 0; i < len(ws); i++ {
 		// 	h.Cursor.Right()
@@ -527,6 +777,16 @@ func (h *BufPane) InsertNewline() bool {
 			line := h.Buf.LineBytes(h.Cursor.Y - 1)
 			h.Buf.Remove(buffer.Loc{X: 0, Y: h.Cursor.Y - 1}, buffer.Loc{X: util.CharacterCount(line), Y: h.Cursor.Y - 1})
 		}
+	} else {
+		ws = nil
+	}
+	if pair {
+		if autoindent {
+			h.Buf.Insert(h.Cursor.Loc, h.Buf.IndentString(util.IntOpt(h.Buf.Settings["tabsize"])))
+		}
+		loc := h.Cursor.Loc
+		h.Buf.Insert(loc, "\n"+string(ws))
+		h.Cursor.GotoLoc(loc)
 	}
 	h.Cursor.LastVisualX = h.Cursor.GetVisualX()
 	h.Relocate()
@@ -535,9 +795,19 @@ func (h *BufPane) InsertNewline() bool {
 
 // Backspace deletes the previous character
 func (h *BufPane) Backspace() bool {
+	if h.Cursor.Virtual > 0 {
+		// past the end of the line, there is nothing to delete
+		h.Cursor.Left()
+		h.Relocate()
+		return true
+	}
 	if h.Cursor.HasSelection() {
 		h.Cursor.DeleteSelection()
 		h.Cursor.ResetSelection()
+	} else if h.Buf.Settings["autoclose"].(bool) && h.Buf.BetweenPair(h.Cursor.Loc) {
+		// the closing character of the pair is deleted along with its
+		// opening character
+		h.Buf.DeletePair(h.Cursor)
 	} else if h.Cursor.Loc.GreaterThan(h.Buf.Start()) {
 		// We have to do something a bit hacky here because we want to
 		// delete the line by first moving left and then deleting backwards
@@ -591,6 +861,8 @@ func (h *BufPane) Delete() bool {
 		h.Cursor.DeleteSelection()
 		h.Cursor.ResetSelection()
 	} else {
+		// past the end of the line, the next line is joined at the cursor
+		h.Cursor.FillVirtual()
 		loc := h.Cursor.Loc
 		if loc.LessThan(h.Buf.End()) {
 			h.Buf.Remove(loc, loc.Move(1, h.Buf))
@@ -695,6 +967,34 @@ func (h *BufPane) OutdentSelection() bool {
 	return false
 }
 
+// ToggleComment comments the selected lines, or the line of the cursor, or
+// uncomments them if they are all commented
+func (h *BufPane) ToggleComment() bool {
+	start, end := h.Cursor.Y, h.Cursor.Y
+	var sel [2]buffer.Loc
+	if h.Cursor.HasSelection() {
+		sel = h.Cursor.CurSelection
+		if sel[1].LessThan(sel[0]) {
+			sel[0], sel[1] = sel[1], sel[0]
+		}
+		start, end = sel[0].Y, sel[1].Move(-1, h.Buf).Y
+	}
+
+	h.Buf.ToggleComment(start, end)
+
+	// a selection from the start of a line keeps the marker inserted there
+	if h.Cursor.HasSelection() && sel[0].X == 0 {
+		if h.Cursor.CurSelection[0].LessThan(h.Cursor.CurSelection[1]) {
+			h.Cursor.SetSelectionStart(buffer.Loc{X: 0, Y: start})
+		} else {
+			h.Cursor.SetSelectionEnd(buffer.Loc{X: 0, Y: start})
+		}
+	}
+	h.Cursor.StoreVisualX()
+	h.Relocate()
+	return true
+}
+
 // Autocomplete cycles the suggestions and performs autocompletion if there are suggestions
 func (h *BufPane) Autocomplete() bool {
 	b := h.Buf
@@ -736,6 +1036,7 @@ func (h *BufPane) CycleAutocompleteBack() bool {
 // InsertTab inserts a tab or spaces
 func (h *BufPane) InsertTab() bool {
 	b := h.Buf
+	h.Cursor.FillVirtual()
 	indent := b.IndentString(util.IntOpt(b.Settings["tabsize"]))
 	tabBytes := len(indent)
 	bytesUntilIndent := tabBytes - (h.Cursor.GetVisualX() % tabBytes)
@@ -754,6 +1055,18 @@ func (h *BufPane) SaveAll() bool {
 
 // SaveCB performs a save and does a callback at the very end (after all prompts have been resolved)
 func (h *BufPane) SaveCB(action string, callback func()) bool {
+	// The standard input of filter mode is saved to the standard output
+	if h.Buf.Type == buffer.BTFilter && h.Buf.Path == "" {
+		if err := h.Buf.Save(); err != nil {
+			InfoBar.Error(err)
+			return true
+		}
+		InfoBar.Message("Saved, the text will be written to the standard output on exit")
+		if callback != nil {
+			callback()
+		}
+		return true
+	}
 	// If this is an empty buffer, ask for a filename
 	if h.Buf.Path == "" {
 		h.SaveAsCB(action, callback)
@@ -805,28 +1118,65 @@ func (h *BufPane) SaveAs() bool {
 // to `filename` if the save is successful
 // The callback is only called if the save was successful
 func (h *BufPane) saveBufToFile(filename string, action string, callback func()) bool {
-	err := h.Buf.SaveAs(filename)
+	if filename == h.Buf.Path && h.Buf.ExternallyModified() && confirms("overwrite") {
+		confirm("overwrite", "The file changed on disk since it was opened or saved. Overwrite it?", func() {
+			if h.saveBufToFileChecked(filename, action, callback, true) {
+				h.completeAction(action)
+			}
+		})
+		return false
+	}
+	return h.saveBufToFileChecked(filename, action, callback, true)
+}
+
+// saveBufToFileChecked is saveBufToFile, which runs the savecheck command
+// before writing the file when check is true. When the check fails, the
+// file is only written if the user confirms it.
+func (h *BufPane) saveBufToFileChecked(filename string, action string, callback func(), check bool) bool {
+	saved := func() {
+		h.Buf.Path = filename
+		h.Buf.SetName(filename)
+		InfoBar.Message("Saved " + filename)
+		h.regenerateTags()
+		if callback != nil {
+			callback()
+		}
+	}
+
+	var err error
+	if check {
+		err = h.Buf.SaveAs(filename)
+	} else {
+		err = h.Buf.ForceSaveAs(filename)
+	}
 	if err != nil {
-		if strings.HasSuffix(err.Error(), "permission denied") {
-			saveWithSudo := func() {
-				err = h.Buf.SaveAsWithSudo(filename)
+		if buffer.IsCheckError(err) {
+			InfoBar.YNPrompt(err.Error()+". Save anyway? (y,n)", func(yes, canceled bool) {
+				if yes && !canceled && h.saveBufToFileChecked(filename, action, callback, false) {
+					h.completeAction(action)
+				}
+			})
+			return false
+		} else if buffer.IsPermissionError(err) {
+			saveWithSudo := func() bool {
+				if check {
+					err = h.Buf.SaveAsWithSudo(filename)
+				} else {
+					err = h.Buf.ForceSaveAsWithSudo(filename)
+				}
 				if err != nil {
 					InfoBar.Error(err)
-				} else {
-					h.Buf.Path = filename
-					h.Buf.SetName(filename)
-					InfoBar.Message("Saved " + filename)
-					if callback != nil {
-						callback()
-					}
+					return false
 				}
+				saved()
+				return true
 			}
 			if h.Buf.Settings["autosu"].(bool) {
 				saveWithSudo()
 			} else {
-				InfoBar.YNPrompt("Permission denied. Do you want to save this file using sudo? (y,n)", func(yes, canceled bool) {
-					if yes && !canceled {
-						saveWithSudo()
+				sucmd := config.GlobalSettings["sucmd"].(string)
+				InfoBar.YNPrompt("Permission denied. Do you want to save this file using "+sucmd+"? (y,n)", func(yes, canceled bool) {
+					if yes && !canceled && saveWithSudo() {
 						h.completeAction(action)
 					}
 				})
@@ -836,12 +1186,7 @@ func (h *BufPane) saveBufToFile(filename string, action string, callback func())
 			InfoBar.Error(err)
 		}
 	} else {
-		h.Buf.Path = filename
-		h.Buf.SetName(filename)
-		InfoBar.Message("Saved " + filename)
-		if callback != nil {
-			callback()
-		}
+		saved()
 	}
 	return true
 }
@@ -866,13 +1211,13 @@ func (h *BufPane) Search(str string, useRegex bool, searchDown bool) error {
 		return err
 	}
 	if found {
+		h.pushJump(h.Cursor.Loc)
 		h.Cursor.SetSelectionStart(match[0])
 		h.Cursor.SetSelectionEnd(match[1])
 		h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
 		h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
 		h.Cursor.GotoLoc(h.Cursor.CurSelection[1])
-		h.lastSearch = str
-		h.lastSearchRegex = useRegex
+		h.setLastSearch(str, useRegex)
 		h.Relocate()
 	} else {
 		h.Cursor.ResetSelection()
@@ -880,50 +1225,94 @@ func (h *BufPane) Search(str string, useRegex bool, searchDown bool) error {
 	return nil
 }
 
+// setLastSearch saves the search used by FindNext and FindPrevious and
+// highlights its matches in the buffer
+func (h *BufPane) setLastSearch(str string, useRegex bool) {
+	h.lastSearch = str
+	h.lastSearchRegex = useRegex
+	h.Buf.LastSearch = str
+	h.Buf.LastSearchRegex = useRegex
+	h.Buf.HighlightSearch = true
+}
+
 func (h *BufPane) find(useRegex bool) bool {
 	h.searchOrig = h.Cursor.Loc
 	prompt := "Find: "
 	if useRegex {
 		prompt = "Find (regex): "
 	}
+	// the matches are highlighted while typing, so remember what was
+	// highlighted and shown in case the search is canceled
+	prevSearch, prevRegex, prevHighlight := h.Buf.LastSearch, h.Buf.LastSearchRegex, h.Buf.HighlightSearch
+	v := h.GetView()
+	prevStart, prevCol := v.StartLine, v.StartCol
+	// from is where the match shown while typing was searched from, which
+	// cycling through the matches moves
+	from := h.searchOrig
+	selectMatch := func(match [2]buffer.Loc) {
+		h.Cursor.SetSelectionStart(match[0])
+		h.Cursor.SetSelectionEnd(match[1])
+		h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
+		h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
+		h.Cursor.GotoLoc(match[1])
+	}
 	var eventCallback func(resp string)
-	if h.Buf.Settings["incsearch"].(bool) {
+	var cycleMatch func(resp string, down bool)
+	// the viewer of a huge file searches it once the prompt is done, as
+	// the matches may be far
+	if h.Buf.Settings["incsearch"].(bool) && h.Buf.Huge == nil {
 		eventCallback = func(resp string) {
-			match, found, _ := h.Buf.FindNext(resp, h.Buf.Start(), h.Buf.End(), h.searchOrig, true, useRegex)
+			h.Buf.LastSearch, h.Buf.LastSearchRegex, h.Buf.HighlightSearch = resp, useRegex, true
+			match, found, _ := h.Buf.FindNext(resp, h.Buf.Start(), h.Buf.End(), from, true, useRegex)
 			if found {
-				h.Cursor.SetSelectionStart(match[0])
-				h.Cursor.SetSelectionEnd(match[1])
-				h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
-				h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
-				h.Cursor.GotoLoc(match[1])
+				selectMatch(match)
 			} else {
 				h.Cursor.GotoLoc(h.searchOrig)
 				h.Cursor.ResetSelection()
 			}
 			h.Relocate()
 		}
+		cycleMatch = func(resp string, down bool) {
+			loc := h.Cursor.Loc
+			if h.Cursor.HasSelection() {
+				loc = h.Cursor.CurSelection[0]
+				if down {
+					loc = h.Cursor.CurSelection[1]
+				}
+			}
+			match, found, err := h.Buf.FindNext(resp, h.Buf.Start(), h.Buf.End(), loc, down, useRegex)
+			if err != nil {
+				InfoBar.Error(err)
+			} else if found {
+				from = match[0]
+				selectMatch(match)
+				h.Relocate()
+			}
+		}
 	}
 	findCallback := func(resp string, canceled bool) {
+		InfoBar.cycleMatch = nil
 		// Finished callback
 		if !canceled {
-			match, found, err := h.Buf.FindNext(resp, h.Buf.Start(), h.Buf.End(), h.searchOrig, true, useRegex)
+			match, found, err := h.Buf.FindNext(resp, h.Buf.Start(), h.Buf.End(), from, true, useRegex)
 			if err != nil {
 				InfoBar.Error(err)
 			}
 			if found {
-				h.Cursor.SetSelectionStart(match[0])
-				h.Cursor.SetSelectionEnd(match[1])
-				h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
-				h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
-				h.Cursor.GotoLoc(h.Cursor.CurSelection[1])
-				h.lastSearch = resp
-				h.lastSearchRegex = useRegex
+				h.pushJump(h.searchOrig)
+				selectMatch(match)
+				h.setLastSearch(resp, useRegex)
 			} else {
 				h.Cursor.ResetSelection()
 				InfoBar.Message("No matches found")
+				h.Buf.LastSearch, h.Buf.LastSearchRegex, h.Buf.HighlightSearch = prevSearch, prevRegex, prevHighlight
 			}
 		} else {
+			// back to where the search started, as it was shown
 			h.Cursor.ResetSelection()
+			h.Cursor.GotoLoc(h.searchOrig)
+			h.Buf.LastSearch, h.Buf.LastSearchRegex, h.Buf.HighlightSearch = prevSearch, prevRegex, prevHighlight
+			v.StartLine, v.StartCol = prevStart, prevCol
 		}
 		h.Relocate()
 	}
@@ -932,6 +1321,7 @@ func (h *BufPane) find(useRegex bool) bool {
 		eventCallback(pattern)
 	}
 	InfoBar.Prompt(prompt, pattern, "Find", eventCallback, findCallback)
+	InfoBar.cycleMatch = cycleMatch
 	if pattern != "" {
 		InfoBar.SelectAll()
 	}
@@ -953,11 +1343,13 @@ func (h *BufPane) FindNext() bool {
 		InfoBar.Error(err)
 	}
 	if found {
+		h.pushJump(h.Cursor.Loc)
 		h.Cursor.SetSelectionStart(match[0])
 		h.Cursor.SetSelectionEnd(match[1])
 		h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
 		h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
 		h.Cursor.Loc = h.Cursor.CurSelection[1]
+		h.setLastSearch(h.lastSearch, h.lastSearchRegex)
 	} else {
 		h.Cursor.ResetSelection()
 	}
@@ -980,11 +1372,13 @@ func (h *BufPane) FindPrevious() bool {
 		InfoBar.Error(err)
 	}
 	if found {
+		h.pushJump(h.Cursor.Loc)
 		h.Cursor.SetSelectionStart(match[0])
 		h.Cursor.SetSelectionEnd(match[1])
 		h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
 		h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
 		h.Cursor.Loc = h.Cursor.CurSelection[1]
+		h.setLastSearch(h.lastSearch, h.lastSearchRegex)
 	} else {
 		h.Cursor.ResetSelection()
 	}
@@ -992,6 +1386,51 @@ func (h *BufPane) FindPrevious() bool {
 	return true
 }
 
+// NextDiagnostic moves the cursor to the next message of the buffer, such as
+// an error reported by a linter
+func (h *BufPane) NextDiagnostic() bool {
+	return h.gotoDiagnostic(true)
+}
+
+// PreviousDiagnostic moves the cursor to the previous message of the buffer
+func (h *BufPane) PreviousDiagnostic() bool {
+	return h.gotoDiagnostic(false)
+}
+
+func (h *BufPane) gotoDiagnostic(down bool) bool {
+	m := h.Buf.NextMessage(h.Cursor.Loc, down)
+	if m == nil {
+		InfoBar.Message("No diagnostics")
+		return false
+	}
+	loc := m.Pos()
+	loc.Y = util.Clamp(loc.Y, 0, h.Buf.LinesNum()-1)
+	loc.X = util.Clamp(loc.X, 0, util.CharacterCount(h.Buf.LineBytes(loc.Y)))
+	h.Cursor.Deselect(true)
+	h.Cursor.GotoLoc(loc)
+	h.Relocate()
+	return true
+}
+
+// UnhighlightSearch stops highlighting the matches of the last search until
+// the next search
+func (h *BufPane) UnhighlightSearch() bool {
+	h.Buf.HighlightSearch = false
+	return true
+}
+
+// ToggleHighlightSearch toggles highlighting the matches of the last search
+func (h *BufPane) ToggleHighlightSearch() bool {
+	h.Buf.HighlightSearch = true
+	h.Buf.SetOptionNative("hlsearch", !h.Buf.Settings["hlsearch"].(bool))
+	if h.Buf.Settings["hlsearch"].(bool) {
+		InfoBar.Message("Enabled search highlighting")
+	} else {
+		InfoBar.Message("Disabled search highlighting")
+	}
+	return true
+}
+
 // Undo undoes the last action
 func (h *BufPane) Undo() bool {
 	h.Buf.Undo()
@@ -1010,10 +1449,24 @@ func (h *BufPane) Redo() bool {
 
 // Copy the selection to the system clipboard
 func (h *BufPane) Copy() bool {
+	if h.inBlock() {
+		// the whole rectangular selection is copied with the first cursor
+		if h.Cursor.Num == 0 {
+			if err := h.copyBlock(); err != nil {
+				InfoBar.Error(err)
+			} else {
+				InfoBar.Message("Copied block")
+			}
+		}
+		return true
+	}
 	if h.Cursor.HasSelection() {
-		h.Cursor.CopySelection(clipboard.ClipboardReg)
+		if err := h.Cursor.CopySelection(h.clipRegister()); err != nil {
+			InfoBar.Error(err)
+		} else {
+			InfoBar.Message("Copied selection")
+		}
 		h.freshClip = true
-		InfoBar.Message("Copied selection")
 	}
 	h.Relocate()
 	return true
@@ -1025,9 +1478,12 @@ func (h *BufPane) CopyLine() bool {
 		return false
 	} else {
 		h.Cursor.SelectLine()
-		h.Cursor.CopySelection(clipboard.ClipboardReg)
+		if err := h.Cursor.CopySelection(h.clipRegister()); err != nil {
+			InfoBar.Error(err)
+		} else {
+			InfoBar.Message("Copied line")
+		}
 		h.freshClip = true
-		InfoBar.Message("Copied line")
 	}
 	h.Cursor.Deselect(true)
 	h.Relocate()
@@ -1042,10 +1498,10 @@ func (h *BufPane) CutLine() bool {
 	}
 	if h.freshClip {
 		if h.Cursor.HasSelection() {
-			if clip, err := clipboard.Read(clipboard.ClipboardReg); err != nil {
+			if clip, err := clipboard.Read(h.clipRegister()); err != nil {
 				InfoBar.Error(err)
 			} else {
-				clipboard.WriteMulti(clip+string(h.Cursor.GetSelection()), clipboard.ClipboardReg, h.Cursor.Num, h.Buf.NumCursors())
+				clipboard.WriteMulti(clip+string(h.Cursor.GetSelection()), h.clipRegister(), h.Cursor.Order(), h.Buf.NumCursors())
 			}
 		}
 	} else if time.Since(h.lastCutTime)/time.Second > 10*time.Second || !h.freshClip {
@@ -1062,12 +1518,22 @@ func (h *BufPane) CutLine() bool {
 
 // Cut the selection to the system clipboard
 func (h *BufPane) Cut() bool {
+	if h.inBlock() {
+		if h.Cursor.Num == 0 {
+			h.cutBlock()
+		}
+		return true
+	}
 	if h.Cursor.HasSelection() {
-		h.Cursor.CopySelection(clipboard.ClipboardReg)
+		err := h.Cursor.CopySelection(h.clipRegister())
 		h.Cursor.DeleteSelection()
 		h.Cursor.ResetSelection()
 		h.freshClip = true
-		InfoBar.Message("Cut selection")
+		if err != nil {
+			InfoBar.Error(err)
+		} else {
+			InfoBar.Message("Cut selection")
+		}
 
 		h.Relocate()
 		return true
@@ -1104,84 +1570,159 @@ func (h *BufPane) DeleteLine() bool {
 	return true
 }
 
-// MoveLinesUp moves up the current line or selected lines if any
-func (h *BufPane) MoveLinesUp() bool {
-	if h.Cursor.HasSelection() {
-		if h.Cursor.CurSelection[0].Y == 0 {
-			InfoBar.Message("Cannot move further up")
-			return false
+// DuplicateLineOrSelection duplicates the selection after it, or the lines
+// of the selection below them if it spans several lines, or else the
+// current line, and moves the cursor and the selection to the copy
+func (h *BufPane) DuplicateLineOrSelection() bool {
+	c := h.Cursor
+	if c.HasSelection() && c.CurSelection[0].Y == c.CurSelection[1].Y {
+		start, end := c.CurSelection[0], c.CurSelection[1]
+		if end.LessThan(start) {
+			start, end = end, start
 		}
-		start := h.Cursor.CurSelection[0].Y
-		end := h.Cursor.CurSelection[1].Y
-		sel := 1
-		if start > end {
-			end, start = start, end
-			sel = 0
+		text := string(c.GetSelection())
+		h.Buf.Insert(end, text)
+		copyEnd := end.Move(util.CharacterCountInString(text), h.Buf)
+		c.SetSelectionStart(end)
+		c.SetSelectionEnd(copyEnd)
+		c.Loc = copyEnd
+		InfoBar.Message("Duplicated selection")
+	} else {
+		start, end := h.selectedLines()
+		var lines []string
+		for y := start; y < end; y++ {
+			lines = append(lines, string(h.Buf.LineBytes(y)))
 		}
-
-		compensate := false
-		if h.Cursor.CurSelection[sel].X != 0 {
-			end++
-		} else {
-			compensate = true
+		loc, sel := c.Loc, c.CurSelection
+		eol := buffer.Loc{X: util.CharacterCount(h.Buf.LineBytes(end - 1)), Y: end - 1}
+		h.Buf.Insert(eol, "\n"+strings.Join(lines, "\n"))
+		n := end - start
+		c.Loc = buffer.Loc{X: loc.X, Y: loc.Y + n}
+		if c.HasSelection() {
+			c.SetSelectionStart(buffer.Loc{X: sel[0].X, Y: sel[0].Y + n})
+			c.SetSelectionEnd(buffer.Loc{X: sel[1].X, Y: sel[1].Y + n})
 		}
+		InfoBar.Message("Duplicated line")
+	}
+	h.Relocate()
+	return true
+}
 
-		h.Buf.MoveLinesUp(
-			start,
-			end,
-		)
-		if compensate {
-			h.Cursor.CurSelection[sel].Y -= 1
-		}
-	} else {
-		if h.Cursor.Loc.Y == 0 {
-			InfoBar.Message("Cannot move further up")
-			return false
+// selectedLines returns the lines from start to end, excluded, spanned by
+// the selection, which leaves out the line it ends at the start of, or the
+// line of the cursor
+func (h *BufPane) selectedLines() (int, int) {
+	if !h.Cursor.HasSelection() {
+		return h.Cursor.Y, h.Cursor.Y + 1
+	}
+	start, end := h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+	if end.LessThan(start) {
+		start, end = end, start
+	}
+	if end.X == 0 && end.Y > start.Y {
+		return start.Y, end.Y
+	}
+	return start.Y, end.Y + 1
+}
+
+// reindentMoved gives the lines from start to end, which were moved, the
+// indentation of their new place if the buffer has indent rules
+func (h *BufPane) reindentMoved(start, end int) {
+	if h.Buf.Settings["autoindent"].(bool) && h.Buf.HasIndentRules() {
+		h.Buf.ReindentBlock(start, end)
+	}
+}
+
+// MoveLinesUp moves up the current line or selected lines if any, as a
+// single undo step. With indent rules the lines are reindented for their
+// new place, keeping their relative indentation.
+func (h *BufPane) MoveLinesUp() bool {
+	start, end := h.selectedLines()
+	if start == 0 {
+		InfoBar.Message("Cannot move further up")
+		return false
+	}
+	// the end of a selection at the start of the line after the moved
+	// lines stays at the start of the last one
+	var last *buffer.Loc
+	if h.Cursor.HasSelection() {
+		last = &h.Cursor.CurSelection[1]
+		if last.LessThan(h.Cursor.CurSelection[0]) {
+			last = &h.Cursor.CurSelection[0]
 		}
-		h.Buf.MoveLinesUp(
-			h.Cursor.Loc.Y,
-			h.Cursor.Loc.Y+1,
-		)
+	}
+	compensate := last != nil && last.X == 0 && last.Y == end
+
+	h.Buf.UndoGroup(func() {
+		h.Buf.MoveLinesUp(start, end)
+		h.reindentMoved(start-1, end-2)
+	})
+	if compensate {
+		last.Y--
 	}
 
 	h.Relocate()
 	return true
 }
 
-// MoveLinesDown moves down the current line or selected lines if any
+// MoveLinesDown moves down the current line or selected lines if any, as
+// a single undo step, reindenting them as MoveLinesUp does
 func (h *BufPane) MoveLinesDown() bool {
-	if h.Cursor.HasSelection() {
-		if h.Cursor.CurSelection[1].Y >= h.Buf.LinesNum() {
-			InfoBar.Message("Cannot move further down")
-			return false
-		}
-		start := h.Cursor.CurSelection[0].Y
-		end := h.Cursor.CurSelection[1].Y
-		sel := 1
-		if start > end {
-			end, start = start, end
-			sel = 0
-		}
+	start, end := h.selectedLines()
+	if end >= h.Buf.LinesNum() {
+		InfoBar.Message("Cannot move further down")
+		return false
+	}
 
-		if h.Cursor.CurSelection[sel].X != 0 {
-			end++
-		}
+	h.Buf.UndoGroup(func() {
+		h.Buf.MoveLinesDown(start, end)
+		h.reindentMoved(start+1, end)
+	})
 
-		h.Buf.MoveLinesDown(
-			start,
-			end,
-		)
-	} else {
-		if h.Cursor.Loc.Y >= h.Buf.LinesNum()-1 {
-			InfoBar.Message("Cannot move further down")
+	h.Relocate()
+	return true
+}
+
+// JoinLines joins the lines of the selection, or the current line with
+// the next one, collapsing the whitespace and comment markers between them
+// (see Buffer.JoinLines), and moves the cursor to the last join
+func (h *BufPane) JoinLines() bool {
+	start, end := h.selectedLines()
+	if end-start < 2 {
+		end = start + 2
+	}
+	if end > h.Buf.LinesNum() {
+		InfoBar.Message("No line to join")
+		return false
+	}
+	loc := h.Buf.JoinLines(start, end-1)
+	h.Cursor.ResetSelection()
+	h.Cursor.GotoLoc(loc)
+	h.Relocate()
+	return true
+}
+
+// Reflow rewraps the lines of the selection, or the paragraph of the
+// cursor, to the textwidth option, and moves the cursor to their end
+func (h *BufPane) Reflow() bool {
+	return h.reflow(util.IntOpt(h.Buf.Settings["textwidth"]))
+}
+
+// reflow rewraps the lines of the selection, or the paragraph of the
+// cursor, to width (see Buffer.Reflow)
+func (h *BufPane) reflow(width int) bool {
+	start, end := h.selectedLines()
+	if !h.Cursor.HasSelection() {
+		if util.IsSpacesOrTabs(h.Buf.LineBytes(h.Cursor.Y)) {
+			InfoBar.Message("No paragraph to reflow")
 			return false
 		}
-		h.Buf.MoveLinesDown(
-			h.Cursor.Loc.Y,
-			h.Cursor.Loc.Y+1,
-		)
+		start, end = h.Buf.ParagraphAt(h.Cursor.Y)
+		end++
 	}
-
+	loc := h.Buf.Reflow(start, end-1, width)
+	h.Cursor.ResetSelection()
+	h.Cursor.GotoLoc(loc)
 	h.Relocate()
 	return true
 }
@@ -1189,7 +1730,12 @@ func (h *BufPane) MoveLinesDown() bool {
 // Paste whatever is in the system clipboard into the buffer
 // Delete and paste if the user has a selection
 func (h *BufPane) Paste() bool {
-	clip, err := clipboard.ReadMulti(clipboard.ClipboardReg, h.Cursor.Num, h.Buf.NumCursors())
+	h.Cursor.FillVirtual()
+	if h.Buf.NumCursors() == 1 && h.pasteBlock() {
+		h.Relocate()
+		return true
+	}
+	clip, err := clipboard.ReadMulti(h.clipRegister(), h.Cursor.Order(), h.Buf.NumCursors())
 	if err != nil {
 		InfoBar.Error(err)
 	} else {
@@ -1201,7 +1747,7 @@ func (h *BufPane) Paste() bool {
 
 // PastePrimary pastes from the primary clipboard (only use on linux)
 func (h *BufPane) PastePrimary() bool {
-	clip, err := clipboard.ReadMulti(clipboard.PrimaryReg, h.Cursor.Num, h.Buf.NumCursors())
+	clip, err := clipboard.ReadMulti(clipboard.PrimaryReg, h.Cursor.Order(), h.Buf.NumCursors())
 	if err != nil {
 		InfoBar.Error(err)
 	} else {
@@ -1224,6 +1770,7 @@ func (h *BufPane) paste(clip string) {
 		h.Cursor.ResetSelection()
 	}
 
+	h.Cursor.FillVirtual()
 	h.Buf.Insert(h.Cursor.Loc, clip)
 	// h.Cursor.Loc = h.Cursor.Loc.Move(Count(clip), h.Buf)
 	h.freshClip = false
@@ -1231,25 +1778,49 @@ func (h *BufPane) paste(clip string) {
 }
 
 // JumpToMatchingBrace moves the cursor to the matching brace if it is
-// currently on a brace
+// currently on a brace, or to the matching keyword of the pairs of the
+// syntax file, such as the end of an if
 func (h *BufPane) JumpToMatchingBrace() bool {
-	for _, bp := range buffer.BracePairs {
-		r := h.Cursor.RuneUnder(h.Cursor.X)
-		rl := h.Cursor.RuneUnder(h.Cursor.X - 1)
-		if r == bp[0] || r == bp[1] || rl == bp[0] || rl == bp[1] {
-			matchingBrace, left, found := h.Buf.FindMatchingBrace(bp, h.Cursor.Loc)
-			if found {
-				if left {
-					h.Cursor.GotoLoc(matchingBrace)
-				} else {
-					h.Cursor.GotoLoc(matchingBrace.Move(1, h.Buf))
-				}
-				break
-			} else {
-				return false
-			}
+	from, to, found := h.Buf.MatchBrace(h.Cursor.Loc)
+	if !found {
+		return false
+	}
+	// the cursor goes to the other side of the match, after it when it is
+	// on the brace
+	if from[0] == h.Cursor.Loc {
+		h.Cursor.GotoLoc(to[1])
+	} else {
+		h.Cursor.GotoLoc(to[0])
+	}
+
+	h.Relocate()
+	return true
+}
+
+// SelectInsideBrace selects the text inside the innermost pair of brackets
+// around the cursor, and the brackets along with it if it is selected
+// already, growing to the enclosing pairs when run again
+func (h *BufPane) SelectInsideBrace() bool {
+	c := h.Cursor
+	loc := c.Loc
+	if c.HasSelection() {
+		loc = c.CurSelection[0]
+		if c.CurSelection[1].LessThan(loc) {
+			loc = c.CurSelection[1]
 		}
 	}
+	open, close, ok := h.Buf.EnclosingBrace(loc)
+	if !ok {
+		return false
+	}
+	start, end := open.Move(1, h.Buf), close
+	if c.HasSelection() && (c.CurSelection == [2]buffer.Loc{start, end} || c.CurSelection == [2]buffer.Loc{end, start}) {
+		start, end = open, close.Move(1, h.Buf)
+	}
+	c.SetSelectionStart(start)
+	c.SetSelectionEnd(end)
+	c.OrigSelection = c.CurSelection
+	c.GotoLoc(end)
 
 	h.Relocate()
 	return true
@@ -1266,6 +1837,73 @@ func (h *BufPane) SelectAll() bool {
 	return true
 }
 
+// A selectionExpansion is a selection grown by ExpandSelection, from the
+// range it had
+type selectionExpansion struct {
+	from, to [2]buffer.Loc
+}
+
+// ExpandSelection grows the selection to the smallest word, string or
+// comment, brackets or quotes, lines, indented block or block with its
+// opening and closing lines around it, or the whole buffer
+func (h *BufPane) ExpandSelection() bool {
+	c := h.Cursor
+	from := [2]buffer.Loc{c.Loc, c.Loc}
+	if c.HasSelection() {
+		from = c.CurSelection
+		if from[1].LessThan(from[0]) {
+			from[0], from[1] = from[1], from[0]
+		}
+	}
+	start, end, ok := h.Buf.ExpandRange(from[0], from[1])
+	if !ok {
+		return false
+	}
+
+	if h.expansions == nil {
+		h.expansions = make(map[*buffer.Cursor][]selectionExpansion)
+	}
+	// the expansions of a selection changed since then are forgotten
+	steps := h.expansions[c]
+	if n := len(steps); n > 0 && steps[n-1].to != from {
+		steps = nil
+	}
+	to := [2]buffer.Loc{start, end}
+	h.expansions[c] = append(steps, selectionExpansion{from, to})
+
+	c.SetSelectionStart(start)
+	c.SetSelectionEnd(end)
+	c.OrigSelection = c.CurSelection
+	c.Loc = end
+	h.Relocate()
+	return true
+}
+
+// ShrinkSelection gives back the selection its range before the last
+// ExpandSelection
+func (h *BufPane) ShrinkSelection() bool {
+	c := h.Cursor
+	steps := h.expansions[c]
+	n := len(steps)
+	if n == 0 || !c.HasSelection() || steps[n-1].to != c.CurSelection {
+		delete(h.expansions, c)
+		return false
+	}
+	from := steps[n-1].from
+	h.expansions[c] = steps[:n-1]
+	if from[0] == from[1] {
+		c.ResetSelection()
+		c.GotoLoc(from[0])
+	} else {
+		c.SetSelectionStart(from[0])
+		c.SetSelectionEnd(from[1])
+		c.OrigSelection = c.CurSelection
+		c.Loc = from[1]
+	}
+	h.Relocate()
+	return true
+}
+
 // OpenFile opens a new file in the buffer
 func (h *BufPane) OpenFile() bool {
 	InfoBar.Prompt("> ", "open ", "Open", nil, func(resp string, canceled bool) {
@@ -1304,14 +1942,18 @@ func (h *BufPane) End() bool {
 
 // PageUp scrolls the view up a page
 func (h *BufPane) PageUp() bool {
-	h.ScrollUp(h.BufView().Height)
+	h.smoothScroll(func() {
+		h.ScrollUp(h.BufView().Height)
+	})
 	return true
 }
 
 // PageDown scrolls the view down a page
 func (h *BufPane) PageDown() bool {
-	h.ScrollDown(h.BufView().Height)
-	h.ScrollAdjust()
+	h.smoothScroll(func() {
+		h.ScrollDown(h.BufView().Height)
+		h.ScrollAdjust()
+	})
 	return true
 }
 
@@ -1320,9 +1962,11 @@ func (h *BufPane) SelectPageUp() bool {
 	if !h.Cursor.HasSelection() {
 		h.Cursor.OrigSelection[0] = h.Cursor.Loc
 	}
-	h.MoveCursorUp(h.BufView().Height)
-	h.Cursor.SelectTo(h.Cursor.Loc)
-	h.Relocate()
+	h.smoothScroll(func() {
+		h.MoveCursorUp(h.BufView().Height)
+		h.Cursor.SelectTo(h.Cursor.Loc)
+		h.Relocate()
+	})
 	return true
 }
 
@@ -1331,9 +1975,11 @@ func (h *BufPane) SelectPageDown() bool {
 	if !h.Cursor.HasSelection() {
 		h.Cursor.OrigSelection[0] = h.Cursor.Loc
 	}
-	h.MoveCursorDown(h.BufView().Height)
-	h.Cursor.SelectTo(h.Cursor.Loc)
-	h.Relocate()
+	h.smoothScroll(func() {
+		h.MoveCursorDown(h.BufView().Height)
+		h.Cursor.SelectTo(h.Cursor.Loc)
+		h.Relocate()
+	})
 	return true
 }
 
@@ -1346,8 +1992,10 @@ func (h *BufPane) CursorPageUp() bool {
 		h.Cursor.ResetSelection()
 		h.Cursor.StoreVisualX()
 	}
-	h.MoveCursorUp(h.BufView().Height)
-	h.Relocate()
+	h.smoothScroll(func() {
+		h.MoveCursorUp(h.BufView().Height)
+		h.Relocate()
+	})
 	return true
 }
 
@@ -1360,28 +2008,57 @@ func (h *BufPane) CursorPageDown() bool {
 		h.Cursor.ResetSelection()
 		h.Cursor.StoreVisualX()
 	}
-	h.MoveCursorDown(h.BufView().Height)
-	h.Relocate()
+	h.smoothScroll(func() {
+		h.MoveCursorDown(h.BufView().Height)
+		h.Relocate()
+	})
 	return true
 }
 
 // HalfPageUp scrolls the view up half a page
 func (h *BufPane) HalfPageUp() bool {
-	h.ScrollUp(h.BufView().Height / 2)
+	h.smoothScroll(func() {
+		h.ScrollUp(h.BufView().Height / 2)
+	})
 	return true
 }
 
 // HalfPageDown scrolls the view down half a page
 func (h *BufPane) HalfPageDown() bool {
-	h.ScrollDown(h.BufView().Height / 2)
-	h.ScrollAdjust()
+	h.smoothScroll(func() {
+		h.ScrollDown(h.BufView().Height / 2)
+		h.ScrollAdjust()
+	})
 	return true
 }
 
+// togglePaneSetting turns the boolean option off or on if it is set for
+// the pane with setpane, and returns its new value and whether it is
+func (h *BufPane) togglePaneSetting(option string) (bool, bool) {
+	w, ok := h.BWindow.(*display.BufWindow)
+	if !ok {
+		return false, false
+	}
+	on, ok := w.Settings[option].(bool)
+	if ok {
+		w.SetOption(option, !on)
+	}
+	return !on, ok
+}
+
 // ToggleDiffGutter turns the diff gutter off and on
 func (h *BufPane) ToggleDiffGutter() bool {
+	if on, ok := h.togglePaneSetting("diffgutter"); ok {
+		if on {
+			InfoBar.Message("Enabled diff gutter in the pane")
+		} else {
+			InfoBar.Message("Disabled diff gutter in the pane")
+		}
+		return true
+	}
 	if !h.Buf.Settings["diffgutter"].(bool) {
 		h.Buf.Settings["diffgutter"] = true
+		h.Buf.UpdateDiffBase()
 		h.Buf.UpdateDiff(func(synchronous bool) {
 			screen.Redraw()
 		})
@@ -1395,6 +2072,14 @@ func (h *BufPane) ToggleDiffGutter() bool {
 
 // ToggleRuler turns line numbers off and on
 func (h *BufPane) ToggleRuler() bool {
+	if on, ok := h.togglePaneSetting("ruler"); ok {
+		if on {
+			InfoBar.Message("Enabled ruler in the pane")
+		} else {
+			InfoBar.Message("Disabled ruler in the pane")
+		}
+		return true
+	}
 	if !h.Buf.Settings["ruler"].(bool) {
 		h.Buf.Settings["ruler"] = true
 		InfoBar.Message("Enabled ruler")
@@ -1450,7 +2135,73 @@ func (h *BufPane) CommandMode() bool {
 	return true
 }
 
-// ToggleOverwriteMode lets the user toggle the text overwrite mode
+// CommandPalette opens a fuzzy picker listing all actions and commands
+// along with their keybindings, and runs the chosen one
+func (h *BufPane) CommandPalette() bool {
+	InfoBar.Pick("> ", "Palette", paletteItems(), func(it *display.PickerItem) {
+		if it == nil {
+			return
+		}
+		switch d := it.Data.(type) {
+		case string:
+			// commands usually take arguments so let the user add them
+			CommandEditAction(d + " ")(MainTab().CurPane())
+		case BufKeyAction:
+			MainTab().CurPane().runAction(it.Text)
+		}
+	})
+	return true
+}
+
+// paletteItems lists the actions and commands shown by the command palette
+func paletteItems() []display.PickerItem {
+	keys := make(map[string][]string)
+	for k, v := range config.Bindings["buffer"] {
+		actions, _ := splitActions(v)
+		for _, a := range actions {
+			keys[a] = append(keys[a], k)
+		}
+	}
+	detail := func(kind, name string) string {
+		ks := keys[name]
+		sort.Strings(ks)
+		if kind == "" {
+			return strings.Join(ks, ", ")
+		}
+		return strings.Join(append([]string{kind}, ks...), ", ")
+	}
+
+	var items []display.PickerItem
+	for name, f := range BufKeyActions {
+		if name == "CommandPalette" {
+			continue
+		}
+		items = append(items, display.PickerItem{Text: name, Detail: detail("", name), Data: f})
+	}
+	for name := range commands {
+		items = append(items, display.PickerItem{Text: name, Detail: detail("command", "command:"+name), Data: name})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Text) < strings.ToLower(items[j].Text)
+	})
+	return items
+}
+
+// FindFile opens a fuzzy picker with the files of the current project
+func (h *BufPane) FindFile() bool {
+	h.FindFileCmd(nil)
+	return true
+}
+
+// RecentFiles opens a fuzzy picker with the recently opened files
+func (h *BufPane) RecentFiles() bool {
+	h.RecentCmd(nil)
+	return true
+}
+
+// ToggleOverwriteMode lets the user toggle the text overwrite mode, in which
+// the typed characters replace the ones under the cursor, shown by $(mode)
+// in the statusline
 func (h *BufPane) ToggleOverwriteMode() bool {
 	h.isOverwriteMode = !h.isOverwriteMode
 	return true
@@ -1482,7 +2233,9 @@ func (h *BufPane) ForceQuit() bool {
 	} else if len(Tabs.List) > 1 {
 		Tabs.RemoveTab(h.splitID)
 	} else {
-		screen.Screen.Fini()
+		saveAutosession()
+		closeDebugger()
+		screen.Fini()
 		InfoBar.Close()
 		runtime.Goexit()
 	}
@@ -1491,12 +2244,14 @@ func (h *BufPane) ForceQuit() bool {
 
 // Quit this will close the current tab or view that is open
 func (h *BufPane) Quit() bool {
-	if h.Buf.Modified() {
+	if h.Buf.Unsaved() {
 		if config.GlobalSettings["autosave"].(float64) > 0 {
 			// autosave on means we automatically save when quitting
 			h.SaveCB("Quit", func() {
 				h.ForceQuit()
 			})
+		} else if !confirms("quit") {
+			h.ForceQuit()
 		} else {
 			InfoBar.YNPrompt("Save changes to "+h.Buf.GetName()+" before closing? (y,n,esc)", func(yes, canceled bool) {
 				if !canceled && !yes {
@@ -1518,27 +2273,25 @@ func (h *BufPane) Quit() bool {
 func (h *BufPane) QuitAll() bool {
 	anyModified := false
 	for _, b := range buffer.OpenBuffers {
-		if b.Modified() {
+		if b.Unsaved() {
 			anyModified = true
 			break
 		}
 	}
 
 	quit := func() {
-		for _, b := range buffer.OpenBuffers {
+		saveAutosession()
+		closeDebugger()
+		for _, b := range append([]*buffer.Buffer(nil), buffer.OpenBuffers...) {
 			b.Close()
 		}
-		screen.Screen.Fini()
+		screen.Fini()
 		InfoBar.Close()
 		runtime.Goexit()
 	}
 
-	if anyModified {
-		InfoBar.YNPrompt("Quit micro? (all open buffers will be closed without saving)", func(yes, canceled bool) {
-			if !canceled && yes {
-				quit()
-			}
-		})
+	if anyModified && confirms("quit") {
+		confirm("quit", "Quit micro? (all open buffers will be closed without saving)", quit)
 	} else {
 		quit()
 	}
@@ -1631,39 +2384,6 @@ func (h *BufPane) PreviousSplit() bool {
 	return true
 }
 
-var curmacro []interface{}
-var recording_macro bool
-
-// ToggleMacro toggles recording of a macro
-func (h *BufPane) ToggleMacro() bool {
-	recording_macro = !recording_macro
-	if recording_macro {
-		curmacro = []interface{}{}
-		InfoBar.Message("Recording")
-	} else {
-		InfoBar.Message("Stopped recording")
-	}
-	h.Relocate()
-	return true
-}
-
-// PlayMacro plays back the most recently recorded macro
-func (h *BufPane) PlayMacro() bool {
-	if recording_macro {
-		return false
-	}
-	for _, action := range curmacro {
-		switch t := action.(type) {
-		case rune:
-			h.DoRuneInsert(t)
-		case func(*BufPane) bool:
-			t(h)
-		}
-	}
-	h.Relocate()
-	return true
-}
-
 // SpawnMultiCursor creates a new multiple cursor at the next occurrence of the current selection or current word
 func (h *BufPane) SpawnMultiCursor() bool {
 	spawner := h.Buf.GetCursor(h.Buf.NumCursors() - 1)
@@ -1774,6 +2494,51 @@ func (h *BufPane) SpawnMultiCursorSelect() bool {
 	return true
 }
 
+// SpawnMultiCursorRegex asks for a regular expression and puts a cursor,
+// selecting the match, at every match in the selection, or in the whole
+// buffer if there is no selection
+func (h *BufPane) SpawnMultiCursorRegex() bool {
+	start, end := h.Buf.Start(), h.Buf.End()
+	if h.Buf.NumCursors() == 1 && h.Cursor.HasSelection() {
+		start, end = h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+	}
+	InfoBar.Prompt("Cursors (regex): ", "", "Find", nil, func(resp string, canceled bool) {
+		if canceled || resp == "" {
+			return
+		}
+		matches, err := h.Buf.FindAll(resp, start, end, true)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		if len(matches) == 0 {
+			InfoBar.Message("No matches found")
+			return
+		}
+
+		h.Buf.ClearCursors()
+		for i, m := range matches {
+			c := h.Buf.GetActiveCursor()
+			if i > 0 {
+				c = buffer.NewCursor(h.Buf, m[1])
+				h.Buf.AddCursor(c)
+			}
+			c.SetSelectionStart(m[0])
+			c.SetSelectionEnd(m[1])
+			c.OrigSelection = c.CurSelection
+			c.Loc = m[1]
+			c.StoreVisualX()
+		}
+		h.Buf.SetCurCursor(h.Buf.NumCursors() - 1)
+		h.Buf.MergeCursors()
+		h.Cursor = h.Buf.GetActiveCursor()
+		h.multiWord = false
+		h.Relocate()
+		InfoBar.Message("Added ", h.Buf.NumCursors(), " cursors")
+	})
+	return true
+}
+
 // MouseMultiCursor is a mouse action which puts a new cursor at the mouse position
 func (h *BufPane) MouseMultiCursor(e *tcell.EventMouse) bool {
 	b := h.Buf
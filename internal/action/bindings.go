@@ -10,17 +10,24 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/zyedidia/json5"
 	"github.com/zyedidia/micro/v2/internal/config"
 	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/tcell/v2"
 )
 
-var Binder = map[string]func(e Event, action string){
-	"command":  InfoMapEvent,
-	"buffer":   BufMapEvent,
-	"terminal": TermMapEvent,
+var Binder map[string]func(e Event, action string)
+
+func init() {
+	// set in init since setting the keyprofile option binds keys
+	Binder = map[string]func(e Event, action string){
+		"command":  InfoMapEvent,
+		"buffer":   BufMapEvent,
+		"terminal": TermMapEvent,
+	}
 }
 
 func createBindingsIfNotExist(fname string) {
@@ -49,18 +56,24 @@ func InitBindings() {
 		}
 	}
 
-	for p, bind := range Binder {
+	boundActions = make(map[string]map[string][]boundAction)
+	for p := range Binder {
 		defaults := DefaultBindings(p)
 
 		for k, v := range defaults {
-			BindKey(k, v, bind)
+			bindFrom("default", p, k, v)
+		}
+		for k, v := range profileBindings(p) {
+			bindFrom("profile", p, k, v)
 		}
 	}
+	boundProfile, _ = config.GetGlobalOption("keyprofile").(string)
 
 	for k, v := range parsed {
 		switch val := v.(type) {
 		case string:
-			BindKey(k, val, Binder["buffer"])
+			bindFrom("user", "buffer", k, val)
+			logConflicts(k)
 		case map[string]interface{}:
 			bind, ok := Binder[k]
 			if !ok || bind == nil {
@@ -72,7 +85,7 @@ func InitBindings() {
 				if !ok {
 					screen.TermMessage("Error reading bindings.json: non-string and non-map entry", k)
 				} else {
-					BindKey(e, s, bind)
+					bindFrom("user", k, e, s)
 				}
 			}
 		default:
@@ -81,6 +94,18 @@ func InitBindings() {
 	}
 }
 
+// logConflicts writes any overlap between the user binding k and other
+// buffer bindings to the debug log
+func logConflicts(k string) {
+	event, err := findEvent(k)
+	if err != nil {
+		return
+	}
+	for _, c := range BufBindings.Conflicts(event) {
+		util.Log(util.LogWarn, "editor", "Binding conflict:", c)
+	}
+}
+
 func BindKey(k, v string, bind func(e Event, a string)) {
 	event, err := findEvent(k)
 	if err != nil {
@@ -102,14 +127,72 @@ func BindKey(k, v string, bind func(e Event, a string)) {
 	// }
 }
 
+// A boundAction is an action bound to a key, and where the binding comes
+// from: "default", "profile" for the keyprofile option, "user" for
+// bindings.json or "plugin"
+type boundAction struct {
+	source, action string
+}
+
+// boundActions are the actions bound to each key, by pane type and key
+// name, in the order they were bound: the last one is active and shadows
+// the others
+var boundActions = make(map[string]map[string][]boundAction)
+
+// bindFrom binds the key k to the action v in the pane type, from the
+// source
+func bindFrom(source, pane, k, v string) {
+	event, err := findEvent(k)
+	if err != nil {
+		screen.TermMessage(err)
+		return
+	}
+	Binder[pane](event, v)
+	if boundActions[pane] == nil {
+		boundActions[pane] = make(map[string][]boundAction)
+	}
+	name := event.Name()
+	boundActions[pane][name] = append(boundActions[pane][name], boundAction{source, v})
+}
+
+// forgetBinding removes the bindings of bindings.json and of the plugins
+// from the actions bound to the key named name, and binds the one they
+// shadowed again, or nothing
+func forgetBinding(pane, name string) {
+	var kept []boundAction
+	for _, b := range boundActions[pane][name] {
+		if b.source != "user" && b.source != "plugin" {
+			kept = append(kept, b)
+		}
+	}
+	boundActions[pane][name] = kept
+	event, err := findEvent(name)
+	if err != nil {
+		return
+	}
+	if len(kept) > 0 {
+		Binder[pane](event, kept[len(kept)-1].action)
+	} else {
+		Binder[pane](event, "None")
+		delete(config.Bindings[pane], name)
+	}
+}
+
 var r = regexp.MustCompile("<(.+?)>")
 
+// findEvents parses a key sequence. A sequence is either a list of keys in
+// brackets, possibly mixed with plain characters (`<Ctrl-x><Ctrl-s>`,
+// `<leader>gs`), or a list of keys separated by spaces (`Ctrl-x Ctrl-s`)
 func findEvents(k string) (b KeySequenceEvent, ok bool, err error) {
+	if !r.MatchString(k) {
+		return findSpacedEvents(k)
+	}
+
 	var events []Event = nil
 	for len(k) > 0 {
 		groups := r.FindStringSubmatchIndex(k)
 
-		if len(groups) > 3 {
+		if len(groups) > 3 && groups[0] == 0 {
 			if events == nil {
 				events = make([]Event, 0, 3)
 			}
@@ -123,13 +206,49 @@ func findEvents(k string) (b KeySequenceEvent, ok bool, err error) {
 
 			k = k[groups[3]+1:]
 		} else {
-			return KeySequenceEvent{}, false, nil
+			// a plain character in the sequence
+			c, size := utf8.DecodeRuneInString(k)
+			events = append(events, KeyEvent{
+				code: tcell.KeyRune,
+				mod:  tcell.ModNone,
+				r:    c,
+			})
+			k = k[size:]
 		}
 	}
 
 	return KeySequenceEvent{events}, true, nil
 }
 
+// findSpacedEvents parses a key sequence written as keys separated by
+// spaces such as `Ctrl-x Ctrl-s`
+func findSpacedEvents(k string) (KeySequenceEvent, bool, error) {
+	keys := strings.Fields(k)
+	if len(keys) < 2 {
+		return KeySequenceEvent{}, false, nil
+	}
+
+	events := make([]Event, 0, len(keys))
+	for _, key := range keys {
+		e, ok := findSingleEvent(key)
+		if !ok {
+			return KeySequenceEvent{}, false, errors.New("Invalid event " + key)
+		}
+		events = append(events, e)
+	}
+
+	return KeySequenceEvent{events}, true, nil
+}
+
+// leaderEvent returns the event for the key stored in the leader option
+func leaderEvent() (Event, bool) {
+	leader, ok := config.GetGlobalOption("leader").(string)
+	if !ok || leader == "" || strings.EqualFold(leader, "leader") {
+		return nil, false
+	}
+	return findSingleEvent(leader)
+}
+
 // findSingleEvent will find binding Key 'b' using string 'k'
 func findSingleEvent(k string) (b Event, ok bool) {
 	modifiers := tcell.ModNone
@@ -166,6 +285,26 @@ modSearch:
 		return KeyEvent{}, false
 	}
 
+	if strings.EqualFold(k, "leader") && modifiers == tcell.ModNone {
+		return leaderEvent()
+	}
+
+	// terminals send Ctrl-Space as a NUL character
+	if strings.EqualFold(k, "space") && modifiers == tcell.ModCtrl {
+		return KeyEvent{
+			code: tcell.KeyCtrlSpace,
+			mod:  modifiers,
+		}, true
+	}
+
+	if strings.EqualFold(k, "space") {
+		return KeyEvent{
+			code: tcell.KeyRune,
+			mod:  modifiers,
+			r:    ' ',
+		}, true
+	}
+
 	// Control is handled in a special way, since the terminal sends explicitly
 	// marked escape sequences for control keys
 	// We should check for Control keys first
@@ -208,6 +347,15 @@ modSearch:
 			mod: modifiers,
 		}, true
 	}
+	for clicks, suffix := range clickSuffixes {
+		if code, ok := mouseEvents[strings.TrimSuffix(k, suffix)]; ok && strings.HasSuffix(k, suffix) {
+			return MouseEvent{
+				btn:    code,
+				mod:    modifiers,
+				clicks: clicks,
+			}, true
+		}
+	}
 
 	// If we were given one character, then we've got a rune.
 	if len(k) == 1 {
@@ -242,6 +390,16 @@ func findEvent(k string) (Event, error) {
 // TryBindKey tries to bind a key by writing to config.ConfigDir/bindings.json
 // Returns true if the keybinding already existed and a possible error
 func TryBindKey(k, v string, overwrite bool) (bool, error) {
+	return tryBindKey(k, v, overwrite, "user")
+}
+
+// PluginTryBindKey is TryBindKey for the plugins, whose bindings are
+// listed as theirs by the keys command
+func PluginTryBindKey(k, v string, overwrite bool) (bool, error) {
+	return tryBindKey(k, v, overwrite, "plugin")
+}
+
+func tryBindKey(k, v string, overwrite bool, source string) (bool, error) {
 	var e error
 	var parsed map[string]interface{}
 
@@ -277,12 +435,16 @@ func TryBindKey(k, v string, overwrite bool) (bool, error) {
 		}
 
 		if found && !overwrite {
+			if bound := boundActions["buffer"][key.Name()]; len(bound) > 0 && bound[len(bound)-1].action == v {
+				// the plugin bound the key in an earlier session
+				bound[len(bound)-1].source = source
+			}
 			return true, nil
 		} else if !found {
 			parsed[k] = v
 		}
 
-		BindKey(k, v, Binder["buffer"])
+		bindFrom(source, "buffer", k, v)
 
 		txt, _ := json.MarshalIndent(parsed, "", "    ")
 		return true, ioutil.WriteFile(filename, append(txt, '\n'), 0644)
@@ -322,13 +484,7 @@ func UnbindKey(k string) error {
 			}
 		}
 
-		defaults := DefaultBindings("buffer")
-		if a, ok := defaults[k]; ok {
-			BindKey(k, a, Binder["buffer"])
-		} else if _, ok := config.Bindings["buffer"][k]; ok {
-			BufUnmap(key)
-			delete(config.Bindings["buffer"], k)
-		}
+		forgetBinding("buffer", key.Name())
 
 		txt, _ := json.MarshalIndent(parsed, "", "    ")
 		return ioutil.WriteFile(filename, append(txt, '\n'), 0644)
@@ -0,0 +1,168 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/clipboard"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// blockSelection is the rectangular selection of a pane: the corner it was
+// started from, its other corner, and the locations of the cursors it made,
+// so that moving a cursor in any other way ends it
+type blockSelection struct {
+	y, col       int
+	endY, endCol int
+	cursors      []buffer.Loc
+}
+
+// inBlock returns whether the cursors of the pane are those of its
+// rectangular selection
+func (h *BufPane) inBlock() bool {
+	if h.block == nil || len(h.block.cursors) != h.Buf.NumCursors() {
+		return false
+	}
+	for i, c := range h.Buf.GetCursors() {
+		if c.Loc != h.block.cursors[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// saveBlockCursors records the locations of the cursors of the rectangular
+// selection
+func (h *BufPane) saveBlockCursors() {
+	h.block.cursors = h.block.cursors[:0]
+	for _, c := range h.Buf.GetCursors() {
+		h.block.cursors = append(h.block.cursors, c.Loc)
+	}
+}
+
+// selectBlock selects the rectangle from the corner of the block selection
+// to the visual column col of line y. Without a block selection, it starts
+// one from the cursor.
+func (h *BufPane) selectBlock(y, col int) {
+	if !h.inBlock() {
+		h.block = &blockSelection{y: h.Cursor.Y, col: h.Cursor.GetVisualX() + h.Cursor.Virtual}
+	}
+	y = util.Clamp(y, 0, h.Buf.LinesNum()-1)
+	col = util.Max(col, 0)
+	h.Buf.SelectBlock(h.block.y, h.block.col, y, col)
+	h.Cursor = h.Buf.GetActiveCursor()
+	h.block.endY, h.block.endCol = y, col
+	h.saveBlockCursors()
+	h.Relocate()
+}
+
+// blockEnd returns the line and the visual column of the corner of the
+// block selection moved by the keyboard actions
+func (h *BufPane) blockEnd() (int, int) {
+	if h.inBlock() {
+		return h.block.endY, h.block.endCol
+	}
+	return h.Cursor.Y, h.Cursor.GetVisualX() + h.Cursor.Virtual
+}
+
+// BlockSelectUp extends the rectangular selection one line up, starting
+// one from the cursor if there is none
+func (h *BufPane) BlockSelectUp() bool {
+	y, col := h.blockEnd()
+	h.selectBlock(y-1, col)
+	return true
+}
+
+// BlockSelectDown extends the rectangular selection one line down
+func (h *BufPane) BlockSelectDown() bool {
+	y, col := h.blockEnd()
+	h.selectBlock(y+1, col)
+	return true
+}
+
+// BlockSelectLeft extends the rectangular selection one column left
+func (h *BufPane) BlockSelectLeft() bool {
+	y, col := h.blockEnd()
+	h.selectBlock(y, col-1)
+	return true
+}
+
+// BlockSelectRight extends the rectangular selection one column right,
+// possibly past the end of the lines
+func (h *BufPane) BlockSelectRight() bool {
+	y, col := h.blockEnd()
+	h.selectBlock(y, col+1)
+	return true
+}
+
+// MouseBlockSelect starts a rectangular selection where the mouse is
+// pressed, and extends it while the mouse is dragged
+func (h *BufPane) MouseBlockSelect(e *tcell.EventMouse) bool {
+	mx, my := e.Position()
+	loc := h.LocFromVisual(buffer.Loc{X: mx, Y: my})
+	col := buffer.NewCursor(h.Buf, loc).GetVisualX()
+	// past the end of the line, the column is the one of the mouse
+	if w, ok := h.BWindow.(*display.BufWindow); ok {
+		if x, _, _ := w.ScreenLoc(loc); mx > x {
+			col += mx - x
+		}
+	}
+	if h.mouseReleased {
+		h.Buf.ClearCursors()
+		h.Cursor = h.Buf.GetActiveCursor()
+		h.Cursor.GotoLoc(loc)
+		h.block = nil
+		h.mouseReleased = false
+	}
+	h.selectBlock(loc.Y, col)
+	return true
+}
+
+// copyBlock copies the selections of the cursors of the rectangular
+// selection to the clipboard, one per line
+func (h *BufPane) copyBlock() error {
+	var lines []string
+	for _, c := range h.Buf.GetCursors() {
+		lines = append(lines, string(c.GetSelection()))
+	}
+	h.freshClip = true
+	return clipboard.WriteBlock(lines, h.clipRegister())
+}
+
+// cutBlock cuts the rectangular selection to the clipboard, leaving the
+// cursors of the block where its text was
+func (h *BufPane) cutBlock() {
+	err := h.copyBlock()
+	for _, c := range h.Buf.GetCursors() {
+		if c.HasSelection() {
+			c.DeleteSelection()
+			c.ResetSelection()
+		}
+	}
+	h.block.col = util.Min(h.block.col, h.block.endCol)
+	h.block.endCol = h.block.col
+	h.saveBlockCursors()
+	if err != nil {
+		InfoBar.Error(err)
+	} else {
+		InfoBar.Message("Cut block")
+	}
+	h.Relocate()
+}
+
+// pasteBlock pastes the rectangular selection in the clipboard at the
+// cursor, and returns false if the clipboard has no such selection
+func (h *BufPane) pasteBlock() bool {
+	lines, err := clipboard.ReadBlock(h.clipRegister())
+	if err != nil || len(lines) < 2 {
+		return false
+	}
+	if h.Cursor.HasSelection() {
+		h.Cursor.DeleteSelection()
+		h.Cursor.ResetSelection()
+	}
+	h.Buf.InsertBlock(h.Cursor.Loc, lines)
+	h.freshClip = false
+	InfoBar.Message("Pasted block")
+	return true
+}
@@ -0,0 +1,111 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/project"
+)
+
+// ToggleBookmark removes the bookmarks on the line of the cursor, or puts a
+// numbered bookmark on it if it has none
+func (h *BufPane) ToggleBookmark() bool {
+	names, err := h.Buf.DeleteBookmarksAt(h.Cursor.Y)
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	if len(names) > 0 {
+		InfoBar.Message("Deleted bookmark " + strings.Join(names, ", "))
+		return true
+	}
+	h.setBookmark("")
+	return true
+}
+
+// Bookmarks opens a fuzzy picker listing the bookmarks of all files
+func (h *BufPane) Bookmarks() bool {
+	h.BookmarksCmd(nil)
+	return true
+}
+
+func (h *BufPane) setBookmark(name string) {
+	name, err := h.Buf.SetBookmark(name, h.Cursor.Y)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	InfoBar.Message("Bookmark " + name + " set")
+}
+
+// BookmarkCmd puts the bookmark named by the argument on the line of the
+// cursor, or a numbered one without argument
+func (h *BufPane) BookmarkCmd(args []string) {
+	if len(args) > 1 {
+		InfoBar.Error("Too many arguments")
+		return
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+	h.setBookmark(name)
+}
+
+// DelBookmarkCmd removes the bookmark named by the argument, in any file,
+// or the bookmarks on the line of the cursor without argument
+func (h *BufPane) DelBookmarkCmd(args []string) {
+	if len(args) == 0 {
+		names, err := h.Buf.DeleteBookmarksAt(h.Cursor.Y)
+		if err != nil {
+			InfoBar.Error(err)
+		} else if len(names) == 0 {
+			InfoBar.Message("No bookmark on this line")
+		} else {
+			InfoBar.Message("Deleted bookmark " + strings.Join(names, ", "))
+		}
+		return
+	}
+	for _, name := range args {
+		found, err := buffer.DeleteBookmark(name)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		} else if !found {
+			InfoBar.Error("No bookmark named " + name)
+			return
+		}
+	}
+	InfoBar.Message("Deleted bookmark " + strings.Join(args, ", "))
+}
+
+// BookmarksCmd opens a fuzzy picker listing the bookmarks of all files. The
+// cursor jumps to the line of the chosen bookmark.
+func (h *BufPane) BookmarksCmd(args []string) {
+	bms, err := buffer.AllBookmarks()
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(bms) == 0 {
+		InfoBar.Message("No bookmarks")
+		return
+	}
+	items := make([]display.PickerItem, 0, len(bms))
+	for _, bm := range bms {
+		items = append(items, display.PickerItem{
+			Text:   bm.Name + "  " + projectPath("", bm.Path),
+			Detail: fmt.Sprintf("line %d", bm.Line+1),
+			Data:   bm,
+		})
+	}
+	InfoBar.Pick("Bookmark: ", "Bookmarks", items, func(it *display.PickerItem) {
+		if it == nil {
+			return
+		}
+		bm := it.Data.(project.Bookmark)
+		h.openAt(projectPath("", bm.Path), buffer.Loc{X: 0, Y: bm.Line})
+	})
+}
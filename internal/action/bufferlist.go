@@ -0,0 +1,332 @@
+package action
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A BufferListPane lists the open buffers below the pane it was opened
+// from. Enter switches to the buffer under the cursor and closes the list,
+// '/' filters the list with fuzzy matching and Space selects buffers for
+// the bulk actions: 's' saves and 'x' closes the selected buffers, or the
+// one under the cursor if none is selected, and 'o' closes all the buffers
+// except the one of the pane the list was opened from.
+type BufferListPane struct {
+	*listPane
+
+	// origin is the pane buffers are opened in
+	origin *BufPane
+	// entries are the listed buffers, one per line, and selected the
+	// buffers selected with Space
+	entries  []*buffer.Buffer
+	selected map[*buffer.SharedBuffer]bool
+}
+
+// BufferList opens the list of open buffers
+func (h *BufPane) BufferList() bool {
+	h.BuffersCmd(nil)
+	return true
+}
+
+// BuffersCmd opens a pane below the current one listing the open buffers
+func (h *BufPane) BuffersCmd(args []string) {
+	lp := &BufferListPane{listPane: newListPane(h), origin: h}
+	lp.selected = make(map[*buffer.SharedBuffer]bool)
+	lp.openBelow(h, lp)
+
+	lp.rerender()
+	for i, e := range lp.entries {
+		if e.SharedBuffer == h.Buf.SharedBuffer {
+			lp.Cursor.GotoLoc(buffer.Loc{X: 0, Y: i})
+		}
+	}
+}
+
+// HandleEvent switches to the buffer, selects, filters, saves or closes
+// buffers with the keys of the list, and passes the other events to the
+// bufpane
+func (h *BufferListPane) HandleEvent(event tcell.Event) {
+	switch listKey(event) {
+	case '\r':
+		h.switchTo()
+	case ' ':
+		h.toggleSelected()
+	case '/':
+		h.promptFilter("BufferFilter", h.rerender)
+	case 's':
+		h.saveSelected()
+	case 'x':
+		h.closeBuffers(h.targets())
+	case 'o':
+		var others []*buffer.Buffer
+		for _, b := range h.entries {
+			if !h.isOpen(h.origin) || b.SharedBuffer != h.origin.Buf.SharedBuffer {
+				others = append(others, b)
+			}
+		}
+		h.closeBuffers(others)
+	default:
+		h.BufPane.HandleEvent(event)
+	}
+}
+
+// buffers returns the open buffers which are listed, one for each file
+func (h *BufferListPane) buffers() []*buffer.Buffer {
+	var bufs []*buffer.Buffer
+	seen := make(map[*buffer.SharedBuffer]bool)
+	for _, b := range buffer.OpenBuffers {
+		if b.Type != buffer.BTDefault || seen[b.SharedBuffer] {
+			continue
+		}
+		seen[b.SharedBuffer] = true
+		bufs = append(bufs, b)
+	}
+	return bufs
+}
+
+// rerender lists the buffers matching the filter again
+func (h *BufferListPane) rerender() {
+	h.entries = h.entries[:0]
+	for _, b := range h.buffers() {
+		if _, ok := util.FuzzyMatch(h.filter, b.GetName()); ok {
+			h.entries = append(h.entries, b)
+		}
+	}
+
+	width := 0
+	for _, b := range h.entries {
+		width = util.Max(width, util.CharacterCountInString(filepath.Base(b.GetName())))
+	}
+	var sb strings.Builder
+	modified := 0
+	for _, b := range h.entries {
+		check, mod := " ", " "
+		if h.selected[b.SharedBuffer] {
+			check = "x"
+		}
+		if b.Modified() {
+			mod = "+"
+			modified++
+		}
+		name := filepath.Base(b.GetName())
+		path := ""
+		if b.Path != "" {
+			path = projectPath("", b.AbsPath)
+		}
+		pad := strings.Repeat(" ", width-util.CharacterCountInString(name))
+		fmt.Fprintf(&sb, "[%s] %s %s%s  %s\n", check, mod, name, pad, path)
+	}
+
+	h.setList(fmt.Sprintf("buffers (%d, %d modified)", len(h.entries), modified), sb.String(), 0, len(h.entries)-1)
+}
+
+// current returns the buffer under the cursor, if any
+func (h *BufferListPane) current() *buffer.Buffer {
+	if h.Cursor.Y < len(h.entries) {
+		return h.entries[h.Cursor.Y]
+	}
+	return nil
+}
+
+// targets returns the selected buffers which are listed, or the one under
+// the cursor if none is selected
+func (h *BufferListPane) targets() []*buffer.Buffer {
+	var bufs []*buffer.Buffer
+	for _, b := range h.entries {
+		if h.selected[b.SharedBuffer] {
+			bufs = append(bufs, b)
+		}
+	}
+	if len(bufs) == 0 {
+		if b := h.current(); b != nil {
+			bufs = append(bufs, b)
+		}
+	}
+	return bufs
+}
+
+func (h *BufferListPane) toggleSelected() {
+	b := h.current()
+	if b == nil {
+		return
+	}
+	if h.selected[b.SharedBuffer] {
+		delete(h.selected, b.SharedBuffer)
+	} else {
+		h.selected[b.SharedBuffer] = true
+	}
+	h.rerender()
+	h.CursorDown()
+}
+
+// isOpen returns whether the pane is part of a tab
+func (h *BufferListPane) isOpen(p Pane) bool {
+	for _, t := range Tabs.List {
+		for _, tp := range t.Panes {
+			if tp == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// panesOf returns the panes of all tabs showing the buffer
+func panesOf(b *buffer.Buffer) []*BufPane {
+	var panes []*BufPane
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			if bp, ok := p.(*BufPane); ok && bp.Buf.SharedBuffer == b.SharedBuffer {
+				panes = append(panes, bp)
+			}
+		}
+	}
+	return panes
+}
+
+// focusPane makes p the active pane, and its tab the active tab
+func focusPane(p *BufPane) {
+	for i, t := range Tabs.List {
+		if t == p.tab {
+			Tabs.SetActive(i)
+		}
+	}
+	for i, tp := range p.tab.Panes {
+		if tp == p {
+			p.tab.SetActive(i)
+		}
+	}
+}
+
+// closePane closes the pane p in any tab, and its tab if p is the only
+// pane. The last pane of micro shows an empty buffer instead.
+func closePane(p *BufPane) {
+	switch {
+	case len(p.tab.Panes) > 1:
+		p.Buf.Close()
+		p.Unsplit()
+	case len(Tabs.List) > 1:
+		p.Buf.Close()
+		Tabs.RemoveTab(p.splitID)
+	default:
+		p.OpenBuffer(buffer.NewBufferFromString("", "", buffer.BTDefault))
+	}
+}
+
+// switchTo closes the list and goes to the buffer under the cursor, in the
+// pane showing it or else in the pane the list was opened from
+func (h *BufferListPane) switchTo() {
+	b := h.current()
+	if b == nil {
+		return
+	}
+	closePane(h.BufPane)
+
+	if panes := panesOf(b); len(panes) > 0 {
+		focusPane(panes[0])
+		return
+	}
+
+	target := h.origin
+	if !h.isOpen(target) {
+		target = MainTab().CurPane()
+	}
+	if target != nil && b.Path != "" {
+		target.openAt(b.Path, b.GetActiveCursor().Loc)
+	}
+}
+
+// saveSelected saves the selected buffers, or the one under the cursor
+func (h *BufferListPane) saveSelected() {
+	saved := 0
+	var errs []string
+	for _, b := range h.targets() {
+		if !b.Modified() {
+			continue
+		}
+		if b.Path == "" {
+			errs = append(errs, "no file name for "+b.GetName())
+			continue
+		}
+		if err := b.Save(); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		saved++
+	}
+	h.selected = make(map[*buffer.SharedBuffer]bool)
+	h.rerender()
+
+	msg := fmt.Sprintf("Saved %d buffers", saved)
+	if len(errs) > 0 {
+		InfoBar.Error(msg + ": " + strings.Join(errs, ", "))
+	} else {
+		InfoBar.Message(msg)
+	}
+}
+
+// closeBuffers closes the panes showing the given buffers, after asking
+// whether to discard the changes of the modified ones, which are kept
+// otherwise
+func (h *BufferListPane) closeBuffers(bufs []*buffer.Buffer) {
+	modified := 0
+	for _, b := range bufs {
+		if b.Unsaved() {
+			modified++
+		}
+	}
+
+	close := func(discard bool) {
+		closed := 0
+		for _, b := range bufs {
+			if b.Unsaved() && !discard {
+				continue
+			}
+			panes := panesOf(b)
+			for _, p := range panes {
+				closePane(p)
+			}
+			// buffers opened without a pane
+			for _, ob := range append([]*buffer.Buffer{}, buffer.OpenBuffers...) {
+				if ob.SharedBuffer == b.SharedBuffer {
+					ob.Close()
+				}
+			}
+			delete(h.selected, b.SharedBuffer)
+			closed++
+		}
+
+		// closing panes may have moved the focus out of the list
+		for i, t := range Tabs.List {
+			if t == h.tab {
+				Tabs.SetActive(i)
+			}
+		}
+		for i, p := range h.tab.Panes {
+			if p == h {
+				h.tab.SetActive(i)
+			}
+		}
+		h.rerender()
+		msg := fmt.Sprintf("Closed %d buffers", closed)
+		if kept := len(bufs) - closed; kept > 0 {
+			msg += fmt.Sprintf(", kept %d with unsaved changes", kept)
+		}
+		InfoBar.Message(msg)
+	}
+
+	if modified == 0 {
+		close(false)
+		return
+	}
+	InfoBar.YNPrompt(fmt.Sprintf("Discard the changes of %d buffers? (y,n)", modified), func(yes, canceled bool) {
+		if !canceled {
+			close(yes)
+		}
+	})
+}
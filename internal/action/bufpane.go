@@ -1,8 +1,10 @@
 package action
 
 import (
+	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	luar "layeh.com/gopher-luar"
 
@@ -13,6 +15,8 @@ import (
 	"github.com/zyedidia/micro/v2/internal/display"
 	ulua "github.com/zyedidia/micro/v2/internal/lua"
 	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/tcell/v2"
 )
 
@@ -35,10 +39,20 @@ func BufMouseActionGeneral(a BufMouseAction) PaneMouseAction {
 
 func init() {
 	BufBindings = NewKeyTree()
+
+	// the command palette lists BufKeyActions and macros run them, so they
+	// can't be part of the map's initializer
+	BufKeyActions["CommandPalette"] = (*BufPane).CommandPalette
+	BufKeyActions["PlayMacro"] = (*BufPane).PlayMacro
+	BufKeyActions["Repeat"] = (*BufPane).Repeat
+	runeActions["ModalKeys"] = runeAction{-1, (*BufPane).applyModalKeys}
 }
 
+// LuaAction returns a bindable function which calls the lua function
+// given as "plugin.function". It returns nil if the plugin or the function
+// does not exist.
 func LuaAction(fn string) func(*BufPane) bool {
-	luaFn := strings.Split(fn, ".")
+	luaFn := strings.SplitN(fn, ".", 2)
 	if len(luaFn) <= 1 {
 		return nil
 	}
@@ -47,10 +61,15 @@ func LuaAction(fn string) func(*BufPane) bool {
 	if pl == nil {
 		return nil
 	}
+	if plug := ulua.L.GetGlobal(pl.Name); plug != lua.LNil {
+		if _, ok := ulua.L.GetField(plug, plFn).(*lua.LFunction); !ok {
+			return nil
+		}
+	}
 	return func(h *BufPane) bool {
 		val, err := pl.Call(plFn, luar.New(ulua.L, h))
 		if err != nil {
-			screen.TermMessage(err)
+			InfoBar.Error(err)
 		}
 		if v, ok := val.(lua.LBool); !ok {
 			return false
@@ -60,6 +79,74 @@ func LuaAction(fn string) func(*BufPane) bool {
 	}
 }
 
+// splitActions splits a binding such as "Save,command:set ruler off|Quit"
+// into its actions and the separators between them. A separator character
+// inside quotes or preceded by a backslash is part of the action, which
+// allows command strings such as "command:replace 'a,b' 'c'". A quote only
+// opens at the start of a word, so that "command:run don't,Save" is split.
+func splitActions(action string) ([]string, []byte) {
+	var actions []string
+	var types []byte
+	var cur strings.Builder
+	var quote rune
+	// last is the previous rune of the action, or 0 at its start
+	var last rune
+	escaped := false
+	for _, r := range action {
+		switch {
+		case escaped:
+			if !strings.ContainsRune("&|,", r) {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			cur.WriteRune(r)
+		case (r == '"' || r == '\'') && (last == 0 || last == ':' || unicode.IsSpace(last)):
+			quote = r
+			cur.WriteRune(r)
+		case strings.ContainsRune("&|,", r):
+			actions = append(actions, cur.String())
+			types = append(types, byte(r))
+			cur.Reset()
+			r = 0
+		default:
+			cur.WriteRune(r)
+		}
+		last = r
+	}
+	if escaped {
+		cur.WriteRune('\\')
+	}
+	if action != "" {
+		actions = append(actions, cur.String())
+		types = append(types, ' ')
+	}
+	return actions, types
+}
+
+// removedPluginActions are the actions of the plugins which used to be
+// bundled with micro, and the actions which replace them, so that the
+// bindings made for them keep working unless the plugin is installed
+var removedPluginActions = map[string]string{
+	"comment.comment": "ToggleComment",
+}
+
+// replacedAction returns the action replacing a if it is the action of a
+// removed plugin which isn't installed, or else a
+func replacedAction(a string) string {
+	fn := strings.TrimPrefix(a, "lua:")
+	if name, ok := removedPluginActions[fn]; ok && config.FindPlugin(strings.SplitN(fn, ".", 2)[0]) == nil {
+		return name
+	}
+	return a
+}
+
 // BufMapKey maps an event to an action
 func BufMapEvent(k Event, action string) {
 	config.Bindings["buffer"][k.Name()] = action
@@ -76,25 +163,10 @@ func bufMapKey(k Event, action string) {
 	var actionfns []func(*BufPane) bool
 	var names []string
 	var types []byte
-	for i := 0; ; i++ {
-		if action == "" {
-			break
-		}
-
-		// TODO: fix problem when complex bindings have these
-		// characters (escape them?)
-		idx := strings.IndexAny(action, "&|,")
-		a := action
-		if idx >= 0 {
-			a = action[:idx]
-			types = append(types, action[idx])
-			action = action[idx+1:]
-		} else {
-			types = append(types, ' ')
-			action = ""
-		}
-
+	actions, seps := splitActions(action)
+	for i, a := range actions {
 		var afn func(*BufPane) bool
+		a = replacedAction(a)
 		if strings.HasPrefix(a, "command:") {
 			a = strings.SplitN(a, ":", 2)[1]
 			afn = CommandAction(a)
@@ -126,8 +198,14 @@ func bufMapKey(k Event, action string) {
 			continue
 		}
 		actionfns = append(actionfns, afn)
+		types = append(types, seps[i])
 	}
 	bufAction := func(h *BufPane) bool {
+		b, edits := h.Buf, h.Buf.Edits()
+		byAction := b.Settings["undogroup"] == "action"
+		if byAction {
+			b.UndoBreak()
+		}
 		cursors := h.Buf.GetCursors()
 		success := true
 		for i, a := range actionfns {
@@ -148,6 +226,10 @@ func bufMapKey(k Event, action string) {
 			h = MainTab().CurPane()
 			success = innerSuccess
 		}
+		// moving the cursor ends the undo step of the text typed
+		if byAction || b.Edits() == edits {
+			b.UndoBreak()
+		}
 		return true
 	}
 
@@ -200,6 +282,9 @@ type BufPane struct {
 
 	// We need to keep track of insert key press toggle
 	isOverwriteMode bool
+	// noAbbrev is set by SuppressAbbreviation so that the next boundary
+	// typed doesn't expand an abbreviation
+	noAbbrev bool
 	// This stores when the last click was
 	// This is useful for detecting double and triple clicks
 	lastClickTime time.Time
@@ -212,12 +297,12 @@ type BufPane struct {
 	// freshClip returns true if the clipboard has never been pasted.
 	freshClip bool
 
-	// Was the last mouse event actually a double click?
-	// Useful for detecting triple clicks -- if a double click is detected
-	// but the last mouse event was actually a double click, it's a triple click
-	doubleClick bool
-	// Same here, just to keep track for mouse move events
-	tripleClick bool
+	// clicks is the number of quick clicks of clickButton at lastLoc,
+	// which chooses the double and triple click bindings
+	clicks      int
+	clickButton tcell.ButtonMask
+	// mouseButton is the mouse button held down
+	mouseButton tcell.ButtonMask
 
 	// Last search stores the last successful search for FindNext and FindPrev
 	lastSearch      string
@@ -231,6 +316,74 @@ type BufPane struct {
 
 	// remember original location of a search in case the search is canceled
 	searchOrig buffer.Loc
+
+	// httpResponse is the pane showing the responses to the HTTP requests
+	// sent from the buffer
+	httpResponse *BufPane
+
+	// jumps is the jump list of the pane, with the locations the cursor
+	// jumped from, and jumpIdx is the position in it while going through it
+	// with JumpBack and JumpForward, or len(jumps) otherwise
+	jumps   []jump
+	jumpIdx int
+	// jumping is set while going to a location of the jump list, which
+	// doesn't record a jump
+	jumping bool
+
+	// pendingTimer fires when an unfinished key sequence times out, and
+	// pendingAction is the action bound to the keys typed so far
+	pendingTimer  *time.Timer
+	pendingAction PaneKeyAction
+	pendingID     uint64
+
+	// block is the rectangular selection, while one is selected
+	block *blockSelection
+
+	// register is the register chosen with SelectRegister, used by the
+	// clipboard actions of the next key
+	register clipboard.Register
+
+	// mode is the mode of the modal editing of the modal option, and
+	// modalKeys the keys typed so far of a command of the normal or visual
+	// mode
+	mode      int
+	modalKeys []rune
+	// visualAnchors are the locations the selections of the visual mode
+	// started at, for every cursor
+	visualAnchors map[*buffer.Cursor]buffer.Loc
+	// expansions are the selections grown by ExpandSelection, for every
+	// cursor, which ShrinkSelection goes back through
+	expansions map[*buffer.Cursor][]selectionExpansion
+
+	// lastFind and lastFindChar are the last f, t, F or T motion and its
+	// character, repeated by ; and ,
+	lastFind     string
+	lastFindChar rune
+
+	// readRune receives the next character typed in the pane instead of it
+	// being inserted, for the actions asking for one such as Surround
+	readRune func(r rune)
+
+	// popup is the transient popup shown by CursorPopup
+	popup *display.Popup
+	// completion is the completion popup, while it is shown
+	completion *completion
+
+	// scrollBarDrag is set while the scrollbar is dragged with the mouse
+	scrollBarDrag bool
+
+	// scrollTimer is the next step of a smooth scroll towards scrollTarget,
+	// and scrollResume the start line that was shown when the scroll was
+	// interrupted by the event being handled
+	scrollTimer  *time.Timer
+	scrollTarget display.SLoc
+	scrollResume *display.SLoc
+
+	// diffStart is the start line shown when the pane compared with this
+	// one was last scrolled to match it
+	diffStart display.SLoc
+	// scrollBind is the state of the pane when scrollbind is on in it
+	scrollBind scrollBinding
 }
 
 func NewBufPane(buf *buffer.Buffer, win display.BWindow, tab *Tab) *BufPane {
@@ -241,6 +394,9 @@ func NewBufPane(buf *buffer.Buffer, win display.BWindow, tab *Tab) *BufPane {
 
 	h.Cursor = h.Buf.GetActiveCursor()
 	h.mouseReleased = true
+	// the search the buffer was opened at, such as +/regex on the command
+	// line, is continued by FindNext
+	h.lastSearch, h.lastSearchRegex = buf.LastSearch, buf.LastSearchRegex
 
 	config.RunPluginFn("onBufPaneOpen", luar.New(ulua.L, h))
 
@@ -288,6 +444,7 @@ func (h *BufPane) PluginCBRune(cb string, r rune) bool {
 }
 
 func (h *BufPane) OpenBuffer(b *buffer.Buffer) {
+	h.pushJump(h.Cursor.Loc)
 	h.Buf.Close()
 	h.Buf = b
 	h.BWindow.SetBuffer(b)
@@ -303,6 +460,52 @@ func (h *BufPane) OpenBuffer(b *buffer.Buffer) {
 	h.lastClickTime = time.Time{}
 }
 
+// openAt opens the file at path in this pane and moves the cursor to loc.
+// The file is opened in a new vertical split instead if the current buffer
+// has unsaved changes. It returns the pane showing the file, or nil if the
+// file can't be opened.
+func (h *BufPane) openAt(path string, loc buffer.Loc) *BufPane {
+	abs, _ := filepath.Abs(path)
+	target := h
+	h.pushJump(h.Cursor.Loc)
+	if h.Buf.AbsPath != abs {
+		b, err := buffer.NewBufferFromFile(path, buffer.BTDefault)
+		if err != nil {
+			InfoBar.Error(err)
+			return nil
+		}
+		if h.Buf.Unsaved() {
+			target = h.VSplitBuf(b)
+			target.jumps = h.copyJumps()
+			target.jumpIdx = h.jumpIdx
+		} else {
+			h.OpenBuffer(b)
+		}
+	}
+
+	target.gotoLoc(loc)
+	return target
+}
+
+// gotoLoc moves the cursor to loc, clamped to the buffer, and centers the
+// view on it
+func (h *BufPane) gotoLoc(loc buffer.Loc) {
+	h.Cursor.Deselect(true)
+	loc.Y = util.Clamp(loc.Y, 0, h.Buf.LinesNum()-1)
+	loc.X = util.Clamp(loc.X, 0, util.CharacterCount(h.Buf.LineBytes(loc.Y)))
+	h.Cursor.GotoLoc(loc)
+	h.Center()
+}
+
+// setting returns the value of the option in the pane, which is the one of
+// the buffer unless it is set for the pane with setpane
+func (h *BufPane) setting(option string) interface{} {
+	if w, ok := h.BWindow.(*display.BufWindow); ok {
+		return w.Setting(option)
+	}
+	return h.Buf.Settings[option]
+}
+
 func (h *BufPane) ID() uint64 {
 	return h.splitID
 }
@@ -321,7 +524,13 @@ func (h *BufPane) Name() string {
 
 // HandleEvent executes the tcell event properly
 func (h *BufPane) HandleEvent(event tcell.Event) {
-	if h.Buf.ExternallyModified() && !h.Buf.ReloadDisabled {
+	h.hidePopupOnEvent(event)
+	// a pane bound since its last event starts from the line it shows
+	// before the event scrolls it
+	h.syncBoundScroll()
+
+	// the changes of a followed file are read by FollowFile
+	if h.Buf.ExternallyModified() && !h.Buf.ReloadDisabled && !h.Buf.Settings["follow"].(bool) {
 		InfoBar.YNPrompt("The file on disk has changed. Reload file? (y,n,esc)", func(yes, canceled bool) {
 			if canceled {
 				h.Buf.DisableReload()
@@ -335,35 +544,73 @@ func (h *BufPane) HandleEvent(event tcell.Event) {
 
 	}
 
+	h.interruptSmoothScroll(event)
+
+	// typed is the character inserted, if any, which may open the
+	// completion popup
+	var typed rune
 	switch e := event.(type) {
+	case *scrollTickEvent:
+		e.pane.scrollTick()
 	case *tcell.EventRaw:
 		re := RawEvent{
 			esc: e.EscSeq(),
 		}
 		h.DoKeyEvent(re)
 	case *tcell.EventPaste:
+		// pasted text is not typed again by Repeat
+		editing = false
 		h.paste(e.Text())
 		h.Relocate()
 	case *tcell.EventKey:
+		if f := h.readRune; f != nil {
+			h.readRune = nil
+			InfoBar.Reset()
+			if e.Key() == tcell.KeyRune {
+				f(e.Rune())
+			}
+			break
+		}
+		if h.modalKeyEvent(e) {
+			break
+		}
+		if h.viewKeyEvent(e) {
+			break
+		}
 		ke := KeyEvent{
 			code: e.Key(),
 			mod:  metaToAlt(e.Modifiers()),
 			r:    e.Rune(),
 		}
 
+		// a register chosen for this key is only used by its actions
+		register := h.register
 		done := h.DoKeyEvent(ke)
+		if register != 0 && h.register == register {
+			h.register = 0
+		}
 		if !done && e.Key() == tcell.KeyRune {
 			h.DoRuneInsert(e.Rune())
+			typed = e.Rune()
 		}
 	case *tcell.EventMouse:
 		cancel := false
 		switch e.Buttons() {
 		case tcell.Button1:
-			_, my := e.Position()
+			// a click ends the group of edits as moving the cursor does
+			editing = false
+			mx, my := e.Position()
 			if h.Buf.Type.Kind != buffer.BTInfo.Kind && h.Buf.Settings["statusline"].(bool) && my >= h.GetView().Y+h.GetView().Height-1 {
 				cancel = true
 			}
+			if h.dragScrollBar(mx, my) {
+				cancel = true
+			}
+			if h.mouseButton != e.Buttons() && h.clickGutter(mx, my) {
+				cancel = true
+			}
 		case tcell.ButtonNone:
+			h.scrollBarDrag = false
 			// Mouse event with no click
 			if !h.mouseReleased {
 				// Mouse was just released
@@ -384,9 +631,6 @@ func (h *BufPane) HandleEvent(event tcell.Event) {
 				// events, this still allows the user to make selections, except only after they
 				// release the mouse
 
-				// if !h.doubleClick && !h.tripleClick {
-				// 	h.Cursor.SetSelectionEnd(h.Cursor.Loc)
-				// }
 				if h.Cursor.HasSelection() {
 					h.Cursor.CopySelection(clipboard.PrimaryReg)
 				}
@@ -394,30 +638,42 @@ func (h *BufPane) HandleEvent(event tcell.Event) {
 			}
 		}
 
+		clicks := h.countClicks(e)
 		if !cancel {
 			me := MouseEvent{
-				btn: e.Buttons(),
-				mod: metaToAlt(e.Modifiers()),
+				btn:    e.Buttons(),
+				mod:    metaToAlt(e.Modifiers()),
+				clicks: clicks,
+			}
+			// double and triple clicks which aren't bound are single clicks
+			if !h.DoMouseEvent(me, e) && me.clicks > 0 {
+				me.clicks = 0
+				h.DoMouseEvent(me, e)
 			}
-			h.DoMouseEvent(me, e)
 		}
 	}
 	h.Buf.MergeCursors()
+	h.Buf.UpdateSnippet()
+	h.syncDiffScroll()
+	h.syncBoundScroll()
+	h.followHugeFile()
+	h.updateCompletion(typed)
+	h.refusedEditMessage()
 
 	if h.IsActive() {
 		// Display any gutter messages for this line
-		c := h.Buf.GetActiveCursor()
-		none := true
-		for _, m := range h.Buf.Messages {
-			if c.Y == m.Start.Y || c.Y == m.End.Y {
-				InfoBar.GutterMessage(m.Msg)
-				none = false
-				break
-			}
-		}
-		if none && InfoBar.HasGutter {
-			InfoBar.ClearGutter()
-		}
+		h.displayLineMessage()
+	}
+}
+
+// displayLineMessage shows the most severe message of the cursor's line in
+// the infobar
+func (h *BufPane) displayLineMessage() {
+	c := h.Buf.GetActiveCursor()
+	if m := h.Buf.LineMessage(c.Y); m != nil {
+		InfoBar.GutterMessage(m.Msg)
+	} else if InfoBar.HasGutter {
+		InfoBar.ClearGutter()
 	}
 }
 
@@ -430,35 +686,115 @@ func (h *BufPane) Bindings() *KeyTree {
 
 // DoKeyEvent executes a key event by finding the action it is bound
 // to and executing it (possibly multiple times for multiple cursors)
+// If the event is part of a longer key sequence, the pane waits for the
+// next key for at most `keytimeout` milliseconds. If the sequence so far
+// is itself bound to an action, that action is run when the time is up.
 func (h *BufPane) DoKeyEvent(e Event) bool {
 	binds := h.Bindings()
+	wasPending, pendingAction := binds.Pending(), h.pendingAction
+	pendingEvents := binds.RecordedEvents()
 	action, more := binds.NextEvent(e, nil)
+	h.stopKeyTimer()
 	if action != nil && !more {
 		action(h)
 		binds.ResetEvents()
 		return true
 	} else if action == nil && !more {
 		binds.ResetEvents()
+		if wasPending {
+			// the key does not continue the sequence: finish the keys
+			// typed so far and handle this key on its own
+			h.finishPending(pendingAction, pendingEvents)
+			return h.DoKeyEvent(e)
+		}
+	} else {
+		h.startKeyTimer(action)
 	}
 	return more
 }
 
+func (h *BufPane) startKeyTimer(action PaneKeyAction) {
+	timeout := config.GetGlobalOption("keytimeout").(float64)
+	if timeout <= 0 {
+		return
+	}
+	h.pendingAction = action
+	h.pendingID++
+	id := h.pendingID
+	// the timeout goes through the main loop rather than the events of the
+	// pane, so that it ends the sequence even if another pane has the focus
+	h.pendingTimer = time.AfterFunc(time.Duration(timeout)*time.Millisecond, func() {
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			h.keyTimeout(id)
+		}}
+	})
+	screen.Redraw()
+}
+
+func (h *BufPane) stopKeyTimer() {
+	if h.pendingTimer != nil {
+		h.pendingTimer.Stop()
+		h.pendingTimer = nil
+	}
+	h.pendingAction = nil
+}
+
+// keyTimeout finishes the pending key sequence, running the action bound
+// to the keys pressed so far (if any)
+func (h *BufPane) keyTimeout(id uint64) {
+	if id != h.pendingID || h.pendingTimer == nil {
+		return
+	}
+	action := h.pendingAction
+	events := h.Bindings().RecordedEvents()
+	h.stopKeyTimer()
+	h.Bindings().ResetEvents()
+	h.finishPending(action, events)
+}
+
+// finishPending runs the action bound to an unfinished key sequence. If
+// there is no such action, the characters of the sequence are inserted so
+// that typing the first key of a sequence does not swallow it.
+func (h *BufPane) finishPending(action PaneKeyAction, events []Event) {
+	if action != nil {
+		action(h)
+		return
+	}
+	for _, e := range events {
+		if ke, ok := e.(KeyEvent); ok && ke.code == tcell.KeyRune && ke.mod == tcell.ModNone {
+			h.DoRuneInsert(ke.r)
+		}
+	}
+}
+
+// PendingKeys returns the unfinished key sequence typed in this pane
+func (h *BufPane) PendingKeys() string {
+	if len(h.modalKeys) > 0 {
+		return string(h.modalKeys)
+	}
+	return h.Bindings().PendingName()
+}
+
 func (h *BufPane) execAction(action func(*BufPane) bool, name string, cursor int) bool {
-	if name != "Autocomplete" && name != "CycleAutocompleteBack" {
+	if name != "Autocomplete" && name != "CycleAutocompleteBack" && name != "SpellSuggest" {
 		h.Buf.HasSuggestions = false
 	}
 
 	_, isMulti := MultiActions[name]
 	if (!isMulti && cursor == 0) || isMulti {
 		if h.PluginCB("pre" + name) {
+			b, edits := h.Buf, h.Buf.Edits()
 			success := action(h)
 			success = success && h.PluginCB("on"+name)
 
-			if isMulti {
-				if recording_macro {
-					if name != "ToggleMacro" && name != "PlayMacro" {
-						curmacro = append(curmacro, action)
-					}
+			if cursor == 0 && name != "Repeat" && name != "PlayMacro" {
+				h.noteEdit(b, edits, macroStep{action: name})
+			}
+
+			// an action running for every cursor is recorded once
+			if isMulti && recording_macro && cursor == 0 {
+				if name != "ToggleMacro" && name != "PlayMacro" {
+					recordAction(name)
 				}
 			}
 
@@ -507,6 +843,7 @@ func (h *BufPane) DoMouseEvent(e MouseEvent, te *tcell.EventMouse) bool {
 // DoRuneInsert inserts a given rune into the current buffer
 // (possibly multiple times for multiple cursors)
 func (h *BufPane) DoRuneInsert(r rune) {
+	b, edits := h.Buf, h.Buf.Edits()
 	cursors := h.Buf.GetCursors()
 	for _, c := range cursors {
 		// Insert a character
@@ -515,24 +852,33 @@ func (h *BufPane) DoRuneInsert(r rune) {
 		if !h.PluginCBRune("preRune", r) {
 			continue
 		}
-		if c.HasSelection() {
-			c.DeleteSelection()
-			c.ResetSelection()
-		}
+		c.FillVirtual()
+		// with autoclose, the rune may be typed as part of a pair
+		if h.isOverwriteMode || !h.Buf.Settings["autoclose"].(bool) || !h.Buf.AutoClose(c, r) {
+			if c.HasSelection() {
+				c.DeleteSelection()
+				c.ResetSelection()
+			}
 
-		if h.isOverwriteMode {
-			next := c.Loc
-			next.X++
-			h.Buf.Replace(c.Loc, next, string(r))
-		} else {
-			h.Buf.Insert(c.Loc, string(r))
+			// at the end of a line, overwriting inserts
+			if h.isOverwriteMode && c.X < util.CharacterCount(h.Buf.LineBytes(c.Y)) {
+				next := c.Loc
+				next.X++
+				h.Buf.Replace(c.Loc, next, string(r))
+			} else {
+				h.Buf.Insert(c.Loc, string(r))
+			}
 		}
-		if recording_macro {
-			curmacro = append(curmacro, r)
+		if !util.IsWordChar(r) {
+			h.expandAbbreviation(c.Loc.Move(-1, h.Buf))
 		}
 		h.Relocate()
 		h.PluginCBRune("onRune", r)
 	}
+	if recording_macro {
+		recordRune(r)
+	}
+	h.noteEdit(b, edits, macroStep{text: string(r)})
 }
 
 func (h *BufPane) VSplitIndex(buf *buffer.Buffer, right bool) *BufPane {
@@ -559,6 +905,8 @@ func (h *BufPane) HSplitBuf(buf *buffer.Buffer) *BufPane {
 	return h.HSplitIndex(buf, h.Buf.Settings["splitbottom"].(bool))
 }
 func (h *BufPane) Close() {
+	h.HidePopup()
+	h.closeCompletion()
 	h.Buf.Close()
 }
 
@@ -566,18 +914,9 @@ func (h *BufPane) SetActive(b bool) {
 	h.BWindow.SetActive(b)
 	if b {
 		// Display any gutter messages for this line
-		c := h.Buf.GetActiveCursor()
-		none := true
-		for _, m := range h.Buf.Messages {
-			if c.Y == m.Start.Y || c.Y == m.End.Y {
-				InfoBar.GutterMessage(m.Msg)
-				none = false
-				break
-			}
-		}
-		if none && InfoBar.HasGutter {
-			InfoBar.ClearGutter()
-		}
+		h.displayLineMessage()
+	} else {
+		h.closeCompletion()
 	}
 
 }
@@ -609,8 +948,16 @@ var BufKeyActions = map[string]BufKeyAction{
 	"SelectToStartOfText":       (*BufPane).SelectToStartOfText,
 	"SelectToStartOfTextToggle": (*BufPane).SelectToStartOfTextToggle,
 	"SelectToEndOfLine":         (*BufPane).SelectToEndOfLine,
+	"SelectToSmartHome":         (*BufPane).SelectToSmartHome,
+	"SelectToSmartEnd":          (*BufPane).SelectToSmartEnd,
 	"ParagraphPrevious":         (*BufPane).ParagraphPrevious,
 	"ParagraphNext":             (*BufPane).ParagraphNext,
+	"SelectParagraphPrevious":   (*BufPane).SelectParagraphPrevious,
+	"SelectParagraphNext":       (*BufPane).SelectParagraphNext,
+	"SentencePrevious":          (*BufPane).SentencePrevious,
+	"SentenceNext":              (*BufPane).SentenceNext,
+	"SelectSentencePrevious":    (*BufPane).SelectSentencePrevious,
+	"SelectSentenceNext":        (*BufPane).SelectSentenceNext,
 	"InsertNewline":             (*BufPane).InsertNewline,
 	"Backspace":                 (*BufPane).Backspace,
 	"Delete":                    (*BufPane).Delete,
@@ -622,26 +969,88 @@ var BufKeyActions = map[string]BufKeyAction{
 	"FindLiteral":               (*BufPane).FindLiteral,
 	"FindNext":                  (*BufPane).FindNext,
 	"FindPrevious":              (*BufPane).FindPrevious,
+	"NextError":                 (*BufPane).NextError,
+	"PreviousError":             (*BufPane).PreviousError,
+	"NextDiagnostic":            (*BufPane).NextDiagnostic,
+	"PreviousDiagnostic":        (*BufPane).PreviousDiagnostic,
+	"StageHunk":                 (*BufPane).StageHunk,
+	"UnstageHunk":               (*BufPane).UnstageHunk,
+	"RevertHunk":                (*BufPane).RevertHunk,
+	"GotoDefinition":            (*BufPane).GotoDefinition,
+	"JumpBack":                  (*BufPane).JumpBack,
+	"JumpForward":               (*BufPane).JumpForward,
+	"ToggleBookmark":            (*BufPane).ToggleBookmark,
+	"Bookmarks":                 (*BufPane).Bookmarks,
+	"Colorscheme":               (*BufPane).Colorscheme,
+	"ToggleSpell":               (*BufPane).ToggleSpell,
+	"ToggleFollow":              (*BufPane).ToggleFollow,
+	"SpellSuggest":              (*BufPane).SpellSuggest,
+	"SpellAddWord":              (*BufPane).SpellAddWord,
+	"UnhighlightSearch":         (*BufPane).UnhighlightSearch,
+	"ToggleHighlightSearch":     (*BufPane).ToggleHighlightSearch,
 	"Center":                    (*BufPane).Center,
 	"Undo":                      (*BufPane).Undo,
+	"Unlock":                    (*BufPane).Unlock,
 	"Redo":                      (*BufPane).Redo,
 	"Copy":                      (*BufPane).Copy,
 	"CopyLine":                  (*BufPane).CopyLine,
 	"Cut":                       (*BufPane).Cut,
 	"CutLine":                   (*BufPane).CutLine,
 	"DuplicateLine":             (*BufPane).DuplicateLine,
+	"DuplicateLineOrSelection":  (*BufPane).DuplicateLineOrSelection,
+	"JoinLines":                 (*BufPane).JoinLines,
+	"Reflow":                    (*BufPane).Reflow,
+	"EvaluateSelection":         (*BufPane).EvaluateSelection,
+	"Fold":                      (*BufPane).Fold,
+	"Unfold":                    (*BufPane).Unfold,
+	"ToggleFold":                (*BufPane).ToggleFold,
+	"FoldAll":                   (*BufPane).FoldAll,
+	"UnfoldAll":                 (*BufPane).UnfoldAll,
+	"ToggleBreakpoint":          (*BufPane).ToggleBreakpoint,
+	"DebugContinue":             (*BufPane).DebugContinue,
+	"DebugNext":                 (*BufPane).DebugNext,
+	"DebugStepIn":               (*BufPane).DebugStepIn,
+	"DebugStepOut":              (*BufPane).DebugStepOut,
+	"DebugPause":                (*BufPane).DebugPause,
+	"DebugStop":                 (*BufPane).DebugStop,
+	"SendHTTPRequest":           (*BufPane).SendHTTPRequest,
+	"DBExec":                    (*BufPane).DBExec,
+	"DBCancel":                  (*BufPane).DBCancel,
 	"DeleteLine":                (*BufPane).DeleteLine,
 	"MoveLinesUp":               (*BufPane).MoveLinesUp,
 	"MoveLinesDown":             (*BufPane).MoveLinesDown,
 	"IndentSelection":           (*BufPane).IndentSelection,
 	"OutdentSelection":          (*BufPane).OutdentSelection,
+	"ToggleComment":             (*BufPane).ToggleComment,
 	"Autocomplete":              (*BufPane).Autocomplete,
 	"CycleAutocompleteBack":     (*BufPane).CycleAutocompleteBack,
+	"Complete":                  (*BufPane).Complete,
+	"CompleteNext":              (*BufPane).CompleteNext,
+	"CompletePrevious":          (*BufPane).CompletePrevious,
+	"CompleteAccept":            (*BufPane).CompleteAccept,
+	"CompleteCancel":            (*BufPane).CompleteCancel,
+	"SnippetExpand":             (*BufPane).SnippetExpand,
+	"SnippetNext":               (*BufPane).SnippetNext,
+	"SnippetPrevious":           (*BufPane).SnippetPrevious,
+	"SnippetCancel":             (*BufPane).SnippetCancel,
+	"BlockSelectUp":             (*BufPane).BlockSelectUp,
+	"BlockSelectDown":           (*BufPane).BlockSelectDown,
+	"BlockSelectLeft":           (*BufPane).BlockSelectLeft,
+	"BlockSelectRight":          (*BufPane).BlockSelectRight,
+	"SelectRegister":            (*BufPane).SelectRegister,
+	"Surround":                  (*BufPane).Surround,
+	"ChangeSurround":            (*BufPane).ChangeSurround,
+	"DeleteSurround":            (*BufPane).DeleteSurround,
+	"IncrementNumber":           (*BufPane).IncrementNumber,
+	"NormalMode":                (*BufPane).NormalMode,
+	"DecrementNumber":           (*BufPane).DecrementNumber,
 	"OutdentLine":               (*BufPane).OutdentLine,
 	"IndentLine":                (*BufPane).IndentLine,
 	"Paste":                     (*BufPane).Paste,
 	"PastePrimary":              (*BufPane).PastePrimary,
 	"SelectAll":                 (*BufPane).SelectAll,
+	"ExpandSelection":           (*BufPane).ExpandSelection,
+	"ShrinkSelection":           (*BufPane).ShrinkSelection,
 	"OpenFile":                  (*BufPane).OpenFile,
 	"Start":                     (*BufPane).Start,
 	"End":                       (*BufPane).End,
@@ -655,13 +1064,25 @@ var BufKeyActions = map[string]BufKeyAction{
 	"StartOfTextToggle":         (*BufPane).StartOfTextToggle,
 	"StartOfLine":               (*BufPane).StartOfLine,
 	"EndOfLine":                 (*BufPane).EndOfLine,
+	"SmartHome":                 (*BufPane).SmartHome,
+	"SmartEnd":                  (*BufPane).SmartEnd,
 	"ToggleHelp":                (*BufPane).ToggleHelp,
 	"ToggleKeyMenu":             (*BufPane).ToggleKeyMenu,
 	"ToggleDiffGutter":          (*BufPane).ToggleDiffGutter,
+	"DiffPush":                  (*BufPane).DiffPush,
+	"DiffPull":                  (*BufPane).DiffPull,
 	"ToggleRuler":               (*BufPane).ToggleRuler,
 	"ClearStatus":               (*BufPane).ClearStatus,
 	"ShellMode":                 (*BufPane).ShellMode,
 	"CommandMode":               (*BufPane).CommandMode,
+	"FindFile":                  (*BufPane).FindFile,
+	"RecentFiles":               (*BufPane).RecentFiles,
+	"GotoAnything":              (*BufPane).GotoAnything,
+	"BufferList":                (*BufPane).BufferList,
+	"TogglePreview":             (*BufPane).TogglePreview,
+	"OpenUnderCursor":           (*BufPane).OpenUnderCursor,
+	"SuppressAbbreviation":      (*BufPane).SuppressAbbreviation,
+	"InsertDigraph":             (*BufPane).InsertDigraph,
 	"ToggleOverwriteMode":       (*BufPane).ToggleOverwriteMode,
 	"Escape":                    (*BufPane).Escape,
 	"Quit":                      (*BufPane).Quit,
@@ -673,21 +1094,31 @@ var BufKeyActions = map[string]BufKeyAction{
 	"NextSplit":                 (*BufPane).NextSplit,
 	"PreviousSplit":             (*BufPane).PreviousSplit,
 	"Unsplit":                   (*BufPane).Unsplit,
+	"RotateSplit":               (*BufPane).RotateSplit,
+	"SwapSplit":                 (*BufPane).SwapSplit,
+	"EqualizeSplits":            (*BufPane).EqualizeSplits,
+	"MaximizeSplit":             (*BufPane).MaximizeSplit,
+	"SplitToTab":                (*BufPane).SplitToTab,
 	"VSplit":                    (*BufPane).VSplitAction,
 	"HSplit":                    (*BufPane).HSplitAction,
 	"ToggleMacro":               (*BufPane).ToggleMacro,
-	"PlayMacro":                 (*BufPane).PlayMacro,
 	"Suspend":                   (*BufPane).Suspend,
 	"ScrollUp":                  (*BufPane).ScrollUpAction,
 	"ScrollDown":                (*BufPane).ScrollDownAction,
+	"ScrollLeft":                (*BufPane).ScrollLeftAction,
+	"ScrollRight":               (*BufPane).ScrollRightAction,
 	"SpawnMultiCursor":          (*BufPane).SpawnMultiCursor,
 	"SpawnMultiCursorUp":        (*BufPane).SpawnMultiCursorUp,
 	"SpawnMultiCursorDown":      (*BufPane).SpawnMultiCursorDown,
 	"SpawnMultiCursorSelect":    (*BufPane).SpawnMultiCursorSelect,
+	"SpawnMultiCursorRegex":     (*BufPane).SpawnMultiCursorRegex,
 	"RemoveMultiCursor":         (*BufPane).RemoveMultiCursor,
 	"RemoveAllMultiCursors":     (*BufPane).RemoveAllMultiCursors,
 	"SkipMultiCursor":           (*BufPane).SkipMultiCursor,
 	"JumpToMatchingBrace":       (*BufPane).JumpToMatchingBrace,
+	"SelectInsideBrace":         (*BufPane).SelectInsideBrace,
+	"SendToTerminal":            (*BufPane).SendToTerminal,
+	"SendParagraphToTerminal":   (*BufPane).SendParagraphToTerminal,
 	"JumpLine":                  (*BufPane).JumpLine,
 	"Deselect":                  (*BufPane).Deselect,
 	"ClearInfo":                 (*BufPane).ClearInfo,
@@ -701,6 +1132,9 @@ var BufKeyActions = map[string]BufKeyAction{
 var BufMouseActions = map[string]BufMouseAction{
 	"MousePress":       (*BufPane).MousePress,
 	"MouseMultiCursor": (*BufPane).MouseMultiCursor,
+	"MouseBlockSelect": (*BufPane).MouseBlockSelect,
+	"MouseSelectWord":  (*BufPane).MouseSelectWord,
+	"MouseSelectLine":  (*BufPane).MouseSelectLine,
 }
 
 // MultiActions is a list of actions that should be executed multiple
@@ -733,8 +1167,18 @@ var MultiActions = map[string]bool{
 	"SelectToStartOfText":       true,
 	"SelectToStartOfTextToggle": true,
 	"SelectToEndOfLine":         true,
+	"SelectToSmartHome":         true,
+	"SelectToSmartEnd":          true,
+	"ExpandSelection":           true,
+	"ShrinkSelection":           true,
 	"ParagraphPrevious":         true,
 	"ParagraphNext":             true,
+	"SelectParagraphPrevious":   true,
+	"SelectParagraphNext":       true,
+	"SentencePrevious":          true,
+	"SentenceNext":              true,
+	"SelectSentencePrevious":    true,
+	"SelectSentenceNext":        true,
 	"InsertNewline":             true,
 	"Backspace":                 true,
 	"Delete":                    true,
@@ -746,11 +1190,16 @@ var MultiActions = map[string]bool{
 	"Cut":                       true,
 	"CutLine":                   true,
 	"DuplicateLine":             true,
+	"DuplicateLineOrSelection":  true,
+	"JoinLines":                 true,
+	"Reflow":                    true,
+	"EvaluateSelection":         true,
 	"DeleteLine":                true,
 	"MoveLinesUp":               true,
 	"MoveLinesDown":             true,
 	"IndentSelection":           true,
 	"OutdentSelection":          true,
+	"ToggleComment":             true,
 	"OutdentLine":               true,
 	"IndentLine":                true,
 	"Paste":                     true,
@@ -761,5 +1210,11 @@ var MultiActions = map[string]bool{
 	"StartOfText":               true,
 	"StartOfTextToggle":         true,
 	"EndOfLine":                 true,
+	"SmartHome":                 true,
+	"SmartEnd":                  true,
 	"JumpToMatchingBrace":       true,
+	"SelectInsideBrace":         true,
+	"IncrementNumber":           true,
+	"NormalMode":                true,
+	"DecrementNumber":           true,
 }
@@ -0,0 +1,36 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitActions(t *testing.T) {
+	tests := []struct {
+		binding string
+		actions []string
+		types   string
+	}{
+		{"Save,command:set ruler off|Quit", []string{"Save", "command:set ruler off", "Quit"}, ",| "},
+		{"command:replace 'a,b' 'c'&Save", []string{"command:replace 'a,b' 'c'", "Save"}, "& "},
+		{`command:replace "a|b" c`, []string{`command:replace "a|b" c`}, " "},
+		{`command:replace a\,b c`, []string{"command:replace a,b c"}, " "},
+		{"command:'a,b'", []string{"command:'a,b'"}, " "},
+		// an apostrophe within a word doesn't open a quote
+		{"command:run don't,Save", []string{"command:run don't", "Save"}, ", "},
+		{"", nil, ""},
+	}
+	for _, test := range tests {
+		actions, types := splitActions(test.binding)
+		assert.Equal(t, test.actions, actions, test.binding)
+		assert.Equal(t, test.types, string(types), test.binding)
+	}
+}
+
+func TestReplacedAction(t *testing.T) {
+	assert.Equal(t, "ToggleComment", replacedAction("comment.comment"))
+	assert.Equal(t, "ToggleComment", replacedAction("lua:comment.comment"))
+	assert.Equal(t, "lua:myplugin.format", replacedAction("lua:myplugin.format"))
+	assert.Equal(t, "Save", replacedAction("Save"))
+}
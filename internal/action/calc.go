@@ -0,0 +1,96 @@
+package action
+
+import (
+	"errors"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// evaluate returns the value of an arithmetic expression (see
+// util.Calculate), or else of a Lua expression, which has access to the
+// functions of the plugins, such as string.rep("-", 20)
+func evaluate(expr string) (string, error) {
+	v, err := util.Calculate(expr)
+	if err == nil {
+		return util.FormatNumber(v), nil
+	}
+	fn, lerr := ulua.L.LoadString("return " + expr)
+	if lerr != nil {
+		// the expression is more likely arithmetic than Lua
+		return "", err
+	}
+	ulua.L.Push(fn)
+	if lerr := ulua.L.PCall(0, 1, nil); lerr != nil {
+		return "", lerr
+	}
+	value := ulua.L.Get(-1)
+	ulua.L.Pop(1)
+	switch value := value.(type) {
+	case *lua.LNilType:
+		return "", errors.New("The expression has no value")
+	case lua.LNumber:
+		return util.FormatNumber(float64(value)), nil
+	}
+	return value.String(), nil
+}
+
+// CalcCmd evaluates the expression given as argument, which follows = in
+// the command prompt, and shows its value, or inserts it at the cursor with
+// the -i flag
+func (h *BufPane) CalcCmd(args []string) {
+	expr := strings.TrimSpace(strings.Join(args, " "))
+	insert := false
+	if strings.HasPrefix(expr, "-i ") {
+		insert = true
+		expr = strings.TrimSpace(expr[3:])
+	}
+	if expr == "" {
+		InfoBar.Error("Usage: = [-i] 'expression'")
+		return
+	}
+	v, err := evaluate(expr)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if !insert {
+		InfoBar.Message(expr, " = ", v)
+		return
+	}
+	h.Buf.Insert(h.Cursor.Loc, v)
+	h.Relocate()
+}
+
+// EvaluateSelection replaces the selected expression by its value, the
+// expression being kept before it if it ends with =
+func (h *BufPane) EvaluateSelection() bool {
+	if !h.Cursor.HasSelection() {
+		InfoBar.Message("Select an expression to evaluate")
+		return false
+	}
+	expr := string(h.Cursor.GetSelection())
+	keep := strings.HasSuffix(strings.TrimRight(expr, " \t"), "=")
+	v, err := evaluate(strings.TrimSuffix(strings.TrimSpace(expr), "="))
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	if keep {
+		if !strings.HasSuffix(expr, " ") {
+			v = " " + v
+		}
+		v = expr + v
+	}
+	start, end := h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+	if end.LessThan(start) {
+		start, end = end, start
+	}
+	h.Buf.Replace(start, end, v)
+	h.Cursor.ResetSelection()
+	h.Cursor.GotoLoc(start.Move(util.CharacterCountInString(v), h.Buf))
+	h.Relocate()
+	return true
+}
@@ -0,0 +1,104 @@
+package action
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// Colorscheme opens a fuzzy picker with the colorschemes, which previews
+// them as they are selected
+func (h *BufPane) Colorscheme() bool {
+	h.ColorschemeCmd(nil)
+	return true
+}
+
+// ColorschemeCmd sets the colorscheme option, or without an argument opens
+// a fuzzy picker with the colorschemes. The selected colorscheme is
+// displayed as the user moves through the list, and canceling the picker
+// goes back to the colorscheme in use.
+func (h *BufPane) ColorschemeCmd(args []string) {
+	if len(args) > 0 {
+		if err := SetGlobalOption("colorscheme", args[0]); err != nil {
+			InfoBar.Error(err)
+		}
+		return
+	}
+
+	current := config.GetGlobalOption("colorscheme").(string)
+	seen := make(map[string]bool)
+	var names []string
+	for _, f := range config.ListRuntimeFiles(config.RTColorscheme) {
+		if !seen[f.Name()] && f.Name() != current {
+			seen[f.Name()] = true
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+
+	// the colorscheme in use comes first, so that it is selected
+	items := []display.PickerItem{{Text: current, Detail: "current"}}
+	for _, name := range names {
+		it := display.PickerItem{Text: name}
+		if strings.HasSuffix(name, "-tc") {
+			it.Detail = "true color"
+		}
+		items = append(items, it)
+	}
+
+	preview := func(name string) {
+		if err := config.PreviewColorscheme(name); err != nil {
+			InfoBar.Error(err)
+		}
+	}
+	InfoBar.PickPreview("Colorscheme: ", "Colorscheme", items, func(it *display.PickerItem) {
+		if it != nil {
+			preview(it.Text)
+		} else {
+			preview(current)
+		}
+	}, func(it *display.PickerItem) {
+		if it == nil || it.Text == current {
+			preview(current)
+			return
+		}
+		if err := SetGlobalOption("colorscheme", it.Text); err != nil {
+			InfoBar.Error(err)
+		}
+	})
+}
+
+// ThemeCmd imports a base16 scheme or a VSCode theme as a colorscheme of
+// the config directory, named after the theme unless a name is given:
+// theme import 'file' ['name']
+func (h *BufPane) ThemeCmd(args []string) {
+	if len(args) < 2 || args[0] != "import" {
+		InfoBar.Error("Usage: theme import 'file' ['name']")
+		return
+	}
+	filename, _ := util.ReplaceHome(args[1])
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	name, text, err := config.ImportTheme(data, filepath.Base(filename))
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(args) > 2 {
+		name = args[2]
+	}
+	name = config.ThemeName(name, filename)
+	if _, err := config.SaveColorscheme(name, text); err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	InfoBar.Message("Imported the colorscheme " + name + ", 'colorscheme " + name + "' uses it")
+}
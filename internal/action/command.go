@@ -2,8 +2,10 @@ package action
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,9 +14,13 @@ import (
 	"strings"
 
 	shellquote "github.com/kballard/go-shellquote"
+	homedir "github.com/mitchellh/go-homedir"
+	lua "github.com/yuin/gopher-lua"
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/clipboard"
 	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/project"
 	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/micro/v2/internal/shell"
 	"github.com/zyedidia/micro/v2/internal/util"
@@ -31,38 +37,105 @@ var commands map[string]Command
 
 func InitCommands() {
 	commands = map[string]Command{
-		"set":        {(*BufPane).SetCmd, OptionValueComplete},
-		"reset":      {(*BufPane).ResetCmd, OptionValueComplete},
-		"setlocal":   {(*BufPane).SetLocalCmd, OptionValueComplete},
-		"show":       {(*BufPane).ShowCmd, OptionComplete},
-		"showkey":    {(*BufPane).ShowKeyCmd, nil},
-		"run":        {(*BufPane).RunCmd, nil},
-		"bind":       {(*BufPane).BindCmd, nil},
-		"unbind":     {(*BufPane).UnbindCmd, nil},
-		"quit":       {(*BufPane).QuitCmd, nil},
-		"goto":       {(*BufPane).GotoCmd, nil},
-		"save":       {(*BufPane).SaveCmd, nil},
-		"replace":    {(*BufPane).ReplaceCmd, nil},
-		"replaceall": {(*BufPane).ReplaceAllCmd, nil},
-		"vsplit":     {(*BufPane).VSplitCmd, buffer.FileComplete},
-		"hsplit":     {(*BufPane).HSplitCmd, buffer.FileComplete},
-		"tab":        {(*BufPane).NewTabCmd, buffer.FileComplete},
-		"help":       {(*BufPane).HelpCmd, HelpComplete},
-		"eval":       {(*BufPane).EvalCmd, nil},
-		"log":        {(*BufPane).ToggleLogCmd, nil},
-		"plugin":     {(*BufPane).PluginCmd, PluginComplete},
-		"reload":     {(*BufPane).ReloadCmd, nil},
-		"reopen":     {(*BufPane).ReopenCmd, nil},
-		"cd":         {(*BufPane).CdCmd, buffer.FileComplete},
-		"pwd":        {(*BufPane).PwdCmd, nil},
-		"open":       {(*BufPane).OpenCmd, buffer.FileComplete},
-		"tabmove":    {(*BufPane).TabMoveCmd, nil},
-		"tabswitch":  {(*BufPane).TabSwitchCmd, nil},
-		"term":       {(*BufPane).TermCmd, nil},
-		"memusage":   {(*BufPane).MemUsageCmd, nil},
-		"retab":      {(*BufPane).RetabCmd, nil},
-		"raw":        {(*BufPane).RawCmd, nil},
-		"textfilter": {(*BufPane).TextFilterCmd, nil},
+		"set":                 {(*BufPane).SetCmd, OptionValueComplete},
+		"reset":               {(*BufPane).ResetCmd, OptionComplete},
+		"setlocal":            {(*BufPane).SetLocalCmd, OptionValueComplete},
+		"setpane":             {(*BufPane).SetPaneCmd, OptionValueComplete},
+		"resetpane":           {(*BufPane).ResetPaneCmd, PaneOptionComplete},
+		"show":                {(*BufPane).ShowCmd, OptionComplete},
+		"toggle":              {(*BufPane).ToggleCmd, OptionComplete},
+		"togglelocal":         {(*BufPane).ToggleLocalCmd, OptionComplete},
+		"showkey":             {(*BufPane).ShowKeyCmd, nil},
+		"keys":                {(*BufPane).KeysCmd, nil},
+		"history":             {(*BufPane).HistoryCmd, nil},
+		"run":                 {(*BufPane).RunCmd, buffer.FileComplete},
+		"bind":                {(*BufPane).BindCmd, ArgComplete(nil, ActionComplete)},
+		"unbind":              {(*BufPane).UnbindCmd, nil},
+		"quit":                {(*BufPane).QuitCmd, nil},
+		"goto":                {(*BufPane).GotoCmd, nil},
+		"save":                {(*BufPane).SaveCmd, buffer.FileComplete},
+		"rename":              {(*BufPane).RenameCmd, buffer.FileComplete},
+		"delete":              {(*BufPane).DeleteCmd, nil},
+		"replace":             {(*BufPane).ReplaceCmd, nil},
+		"replaceall":          {(*BufPane).ReplaceAllCmd, nil},
+		"nohlsearch":          {(*BufPane).NoHlsearchCmd, nil},
+		"vsplit":              {(*BufPane).VSplitCmd, buffer.FileComplete},
+		"hsplit":              {(*BufPane).HSplitCmd, buffer.FileComplete},
+		"tab":                 {(*BufPane).NewTabCmd, buffer.FileComplete},
+		"help":                {(*BufPane).HelpCmd, HelpComplete},
+		"eval":                {(*BufPane).EvalCmd, nil},
+		"log":                 {(*BufPane).ToggleLogCmd, LogComplete},
+		"plugin":              {(*BufPane).PluginCmd, PluginComplete},
+		"reload":              {(*BufPane).ReloadCmd, nil},
+		"reload-syntax":       {(*BufPane).ReloadSyntaxCmd, nil},
+		"reopen":              {(*BufPane).ReopenCmd, nil},
+		"session":             {(*BufPane).SessionCmd, SessionComplete},
+		"layout":              {(*BufPane).LayoutCmd, LayoutComplete},
+		"project":             {(*BufPane).ProjectCmd, ProjectComplete},
+		"cd":                  {(*BufPane).CdCmd, buffer.FileComplete},
+		"pwd":                 {(*BufPane).PwdCmd, nil},
+		"stats":               {(*BufPane).StatsCmd, nil},
+		"open":                {(*BufPane).OpenCmd, buffer.FileComplete},
+		"find-file":           {(*BufPane).FindFileCmd, nil},
+		"recent":              {(*BufPane).RecentCmd, nil},
+		"gotoany":             {(*BufPane).GotoAnyCmd, nil},
+		"buffers":             {(*BufPane).BuffersCmd, nil},
+		"runtask":             {(*BufPane).RunTaskCmd, TaskComplete},
+		"debug":               {(*BufPane).DebugCmd, DebugComplete},
+		"http":                {(*BufPane).HTTPCmd, nil},
+		"dbexec":              {(*BufPane).DBExecCmd, nil},
+		"secret":              {(*BufPane).SecretCmd, SecretComplete},
+		"preview":             {(*BufPane).PreviewCmd, nil},
+		"grep":                {(*BufPane).GrepCmd, nil},
+		"grepreplace":         {(*BufPane).GrepReplaceCmd, nil},
+		"diagnostics":         {(*BufPane).DiagnosticsCmd, nil},
+		"build":               {(*BufPane).BuildCmd, nil},
+		"blame":               {(*BufPane).BlameCmd, nil},
+		"diff":                {(*BufPane).DiffCmd, buffer.FileComplete},
+		"diffsaved":           {(*BufPane).DiffSavedCmd, nil},
+		"nodiff":              {(*BufPane).NoDiffCmd, nil},
+		"goto-definition":     {(*BufPane).GotoDefinitionCmd, nil},
+		"tabmove":             {(*BufPane).TabMoveCmd, nil},
+		"tabswitch":           {(*BufPane).TabSwitchCmd, BufferNameComplete},
+		"term":                {(*BufPane).TermCmd, buffer.FileComplete},
+		"repl":                {(*BufPane).ReplCmd, nil},
+		"memusage":            {(*BufPane).MemUsageCmd, nil},
+		"health":              {(*BufPane).HealthCmd, nil},
+		"syntax":              {(*BufPane).SyntaxCmd, SyntaxComplete},
+		"runtime":             {(*BufPane).RuntimeCmd, RuntimeComplete},
+		"retab":               {(*BufPane).RetabCmd, nil},
+		"reflow":              {(*BufPane).ReflowCmd, nil},
+		"=":                   {(*BufPane).CalcCmd, nil},
+		"convert-indentation": {(*BufPane).ConvertIndentationCmd, IndentationComplete},
+		"undo-break":          {(*BufPane).UndoBreakCmd, nil},
+		"undo-group-start":    {(*BufPane).UndoGroupStartCmd, nil},
+		"undo-group-end":      {(*BufPane).UndoGroupEndCmd, nil},
+		"reindent":            {(*BufPane).ReindentCmd, nil},
+		"comment":             {(*BufPane).CommentCmd, nil},
+		"registers":           {(*BufPane).RegistersCmd, nil},
+		"bookmark":            {(*BufPane).BookmarkCmd, nil},
+		"delbookmark":         {(*BufPane).DelBookmarkCmd, nil},
+		"bookmarks":           {(*BufPane).BookmarksCmd, nil},
+		"drafts":              {(*BufPane).DraftsCmd, nil},
+		"colorscheme":         {(*BufPane).ColorschemeCmd, ColorschemeComplete},
+		"theme":               {(*BufPane).ThemeCmd, ThemeComplete},
+		"macro":               {(*BufPane).MacroCmd, MacroComplete},
+		"sort":                {(*BufPane).SortCmd, nil},
+		"uniq":                {(*BufPane).UniqCmd, nil},
+		"reverse":             {(*BufPane).ReverseCmd, nil},
+		"align":               {(*BufPane).AlignCmd, nil},
+		"increment":           {(*BufPane).IncrementCmd, nil},
+		"decrement":           {(*BufPane).DecrementCmd, nil},
+		"case":                {(*BufPane).CaseCmd, CaseComplete},
+		"raw":                 {(*BufPane).RawCmd, nil},
+		"textfilter":          {(*BufPane).TextFilterCmd, buffer.FileComplete},
+		"filter":              {(*BufPane).FilterCmd, buffer.FileComplete},
+		"export":              {(*BufPane).ExportCmd, ExportComplete},
+		"hexfind":             {(*BufPane).HexFindCmd, nil},
+		"unlock":              {(*BufPane).UnlockCmd, nil},
+		"char-info":           {(*BufPane).CharInfoCmd, nil},
+		"insert-unicode":      {(*BufPane).InsertUnicodeCmd, nil},
+		"digraph":             {(*BufPane).DigraphCmd, nil},
 	}
 }
 
@@ -117,6 +190,309 @@ func (h *BufPane) PluginCmd(args []string) {
 // depending on the user's settings
 func (h *BufPane) RetabCmd(args []string) {
 	h.Buf.Retab()
+	h.Relocate()
+}
+
+// ReflowCmd rewraps the lines of the selection, or the paragraph of the
+// cursor, to the given width or else to the textwidth option
+func (h *BufPane) ReflowCmd(args []string) {
+	width := util.IntOpt(h.Buf.Settings["textwidth"])
+	if len(args) > 1 {
+		InfoBar.Error("Usage: reflow [width]")
+		return
+	} else if len(args) == 1 {
+		w, err := strconv.Atoi(args[0])
+		if err != nil || w <= 0 {
+			InfoBar.Error("Invalid width: ", args[0])
+			return
+		}
+		width = w
+	}
+	h.reflow(width)
+}
+
+// UndoBreakCmd ends the undo step of the last edits, so that the next ones
+// are undone apart from them
+func (h *BufPane) UndoBreakCmd(args []string) {
+	h.Buf.UndoBreak()
+}
+
+// UndoGroupStartCmd starts a group of edits undone as a single step, until
+// undo-group-end or the next undo or redo
+func (h *BufPane) UndoGroupStartCmd(args []string) {
+	if !h.Buf.StartUndoGroup() {
+		InfoBar.Error("An undo group is started already")
+	}
+}
+
+// UndoGroupEndCmd ends the group of edits started by undo-group-start
+func (h *BufPane) UndoGroupEndCmd(args []string) {
+	if !h.Buf.EndUndoGroup() {
+		InfoBar.Error("No undo group is started")
+	}
+}
+
+// ConvertIndentationCmd rewrites the indentation of the buffer with tabs or
+// with spaces, setting the tabstospaces option and the tabsize option to the
+// width given, that is the number of spaces of a tab
+func (h *BufPane) ConvertIndentationCmd(args []string) {
+	if len(args) < 1 || len(args) > 2 || args[0] != "tabs" && args[0] != "spaces" {
+		InfoBar.Error("Usage: convert-indentation tabs|spaces [width]")
+		return
+	}
+	if len(args) == 2 {
+		width, err := strconv.Atoi(args[1])
+		if err != nil || width <= 0 {
+			InfoBar.Error("Invalid width ", args[1])
+			return
+		}
+		h.Buf.SetOptionNative("tabsize", float64(width))
+	}
+	h.Buf.SetOptionNative("tabstospaces", args[0] == "spaces")
+	h.Buf.Retab()
+	h.Relocate()
+	InfoBar.Message("Indented with ", args[0])
+}
+
+// ReindentCmd indents the selected lines, or the line of the cursor,
+// according to the indent rules of the buffer
+func (h *BufPane) ReindentCmd(args []string) {
+	if !h.Buf.HasIndentRules() {
+		InfoBar.Error("No indent rules for the filetype ", h.Buf.FileType())
+		return
+	}
+	start, end := h.Cursor.Y, h.Cursor.Y
+	if h.Cursor.HasSelection() {
+		s, e := h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+		if e.LessThan(s) {
+			s, e = e, s
+		}
+		start, end = s.Y, e.Move(-1, h.Buf).Y
+	}
+	h.Buf.UndoGroup(func() {
+		h.Buf.Reindent(start, end)
+	})
+	h.Relocate()
+}
+
+// linesArg returns the selected lines, or all the lines of the buffer but a
+// last empty one, which the sort, uniq and reverse commands change
+func (h *BufPane) linesArg() (int, int) {
+	if h.Cursor.HasSelection() {
+		s, e := h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+		if e.LessThan(s) {
+			s, e = e, s
+		}
+		return s.Y, e.Move(-1, h.Buf).Y
+	}
+	end := h.Buf.LinesNum() - 1
+	if end > 0 && len(h.Buf.LineBytes(end)) == 0 {
+		end--
+	}
+	return 0, end
+}
+
+// afterLinesCmd selects the lines changed by the sort, uniq or reverse
+// commands if they were selected
+func (h *BufPane) afterLinesCmd(start, end, nlines int, selected bool) {
+	end += h.Buf.LinesNum() - nlines
+	h.Cursor.Deselect(true)
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: start})
+	if selected {
+		last := buffer.Loc{X: util.CharacterCount(h.Buf.LineBytes(end)), Y: end}
+		if end+1 < h.Buf.LinesNum() {
+			last = buffer.Loc{X: 0, Y: end + 1}
+		}
+		h.Cursor.SetSelectionStart(h.Cursor.Loc)
+		h.Cursor.SetSelectionEnd(last)
+		h.Cursor.OrigSelection = h.Cursor.CurSelection
+	}
+	h.Relocate()
+}
+
+// SortCmd sorts the selected lines, or the lines of the buffer. The flags
+// sort in reverse order (-r), by number (-n), ignoring case (-i), keeping
+// only the first of equal lines (-u), by a field (-k n) or by the text
+// matched by a regex, or its first group (-e 'regex').
+func (h *BufPane) SortCmd(args []string) {
+	var opts buffer.SortOptions
+	for len(args) > 0 {
+		switch args[0] {
+		case "-r":
+			opts.Reverse = true
+		case "-n":
+			opts.Numeric = true
+		case "-i":
+			opts.IgnoreCase = true
+		case "-u":
+			opts.Unique = true
+		case "-k":
+			if len(args) < 2 {
+				InfoBar.Error("-k requires the number of a field")
+				return
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				InfoBar.Error("Invalid field number: " + args[1])
+				return
+			}
+			opts.Column = n
+			args = args[1:]
+		case "-e":
+			if len(args) < 2 {
+				InfoBar.Error("-e requires a regex")
+				return
+			}
+			re, err := util.CompileRegexp(args[1], h.Buf.Settings["regexengine"].(string))
+			if err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			opts.Key = re
+			args = args[1:]
+		default:
+			InfoBar.Error("Unknown flag: " + args[0])
+			return
+		}
+		args = args[1:]
+	}
+
+	start, end := h.linesArg()
+	n, selected := h.Buf.LinesNum(), h.Cursor.HasSelection()
+	h.Buf.SortLines(start, end, opts)
+	h.afterLinesCmd(start, end, n, selected)
+}
+
+// UniqCmd removes the selected lines, or the lines of the buffer, which are
+// equal to a line before them, ignoring case with -i
+func (h *BufPane) UniqCmd(args []string) {
+	ignoreCase := false
+	for _, a := range args {
+		if a != "-i" {
+			InfoBar.Error("Unknown flag: " + a)
+			return
+		}
+		ignoreCase = true
+	}
+	start, end := h.linesArg()
+	n, selected := h.Buf.LinesNum(), h.Cursor.HasSelection()
+	h.Buf.UniqueLines(start, end, ignoreCase)
+	h.afterLinesCmd(start, end, n, selected)
+}
+
+// ReverseCmd reverses the order of the selected lines, or of the lines of
+// the buffer
+func (h *BufPane) ReverseCmd(args []string) {
+	start, end := h.linesArg()
+	n, selected := h.Buf.LinesNum(), h.Cursor.HasSelection()
+	h.Buf.ReverseLines(start, end)
+	h.afterLinesCmd(start, end, n, selected)
+}
+
+// AlignCmd pads the selected lines, or the paragraph of the cursor, so that
+// the occurrences of the delimiter line up. The flags align the text to the
+// right (-r), limit the padding (-m n) or make the delimiter a regex (-e).
+func (h *BufPane) AlignCmd(args []string) {
+	var opts buffer.AlignOptions
+	isRegex := false
+	// a single argument is the delimiter, even if it starts with -
+	for len(args) > 1 && strings.HasPrefix(args[0], "-") && args[0] != "--" {
+		switch args[0] {
+		case "-r":
+			opts.Right = true
+		case "-e":
+			isRegex = true
+		case "-m":
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				InfoBar.Error("Invalid padding: " + args[1])
+				return
+			}
+			opts.MaxPadding = n
+			args = args[1:]
+		default:
+			InfoBar.Error("Unknown flag: " + args[0])
+			return
+		}
+		args = args[1:]
+	}
+	if len(args) > 1 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		InfoBar.Error("Invalid arguments: align [-r] [-m n] [-e] 'delimiter'")
+		return
+	}
+	expr := args[0]
+	if !isRegex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	delim, err := util.CompileRegexp(expr, h.Buf.Settings["regexengine"].(string))
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+
+	selected := h.Cursor.HasSelection()
+	start, end := h.Cursor.Y, h.Cursor.Y
+	if selected {
+		start, end = h.linesArg()
+	} else {
+		for start > 0 && !util.IsSpaces(h.Buf.LineBytes(start-1)) {
+			start--
+		}
+		for end < h.Buf.LinesNum()-1 && !util.IsSpaces(h.Buf.LineBytes(end+1)) {
+			end++
+		}
+	}
+	n := h.Buf.LinesNum()
+	h.Buf.AlignLines(start, end, delim, opts)
+	h.afterLinesCmd(start, end, n, selected)
+}
+
+// CaseCmd converts the selection of every cursor, or the word under it, to
+// the style given as argument, one of util.CaseStyles
+func (h *BufPane) CaseCmd(args []string) {
+	if len(args) != 1 {
+		InfoBar.Error("Invalid arguments: case " + strings.Join(util.CaseStyles, "|"))
+		return
+	}
+	if _, ok := util.ConvertCase("", args[0]); !ok {
+		InfoBar.Error("Unknown case style " + args[0])
+		return
+	}
+	h.Buf.UndoGroup(func() {
+		for _, c := range h.Buf.GetCursors() {
+			if !c.HasSelection() {
+				c.SelectWord()
+			}
+			if !c.HasSelection() {
+				continue
+			}
+			start, end := c.CurSelection[0], c.CurSelection[1]
+			if end.LessThan(start) {
+				start, end = end, start
+			}
+			text, _ := util.ConvertCase(string(c.GetSelection()), args[0])
+			if text == string(c.GetSelection()) {
+				continue
+			}
+			h.Buf.Replace(start, end, text)
+			// the converted text stays selected
+			end = start.Move(util.CharacterCountInString(text), h.Buf)
+			c.SetSelectionStart(start)
+			c.SetSelectionEnd(end)
+			c.OrigSelection = c.CurSelection
+			c.Loc = end
+		}
+	})
+	h.Relocate()
+}
+
+// CommentCmd comments or uncomments the selected lines, or the line of the
+// cursor
+func (h *BufPane) CommentCmd(args []string) {
+	h.ToggleComment()
 }
 
 // RawCmd opens a new raw view which displays the escape sequences micro
@@ -156,6 +532,138 @@ func (h *BufPane) TextFilterCmd(args []string) {
 	h.Buf.Insert(h.Cursor.Loc, bout.String())
 }
 
+// ExportFormats are the formats of the export command
+var ExportFormats = []string{"html", "ansi"}
+
+// Export returns the text of the buffer in the format, html or ansi, with
+// its highlighting in the colorscheme
+func Export(b *buffer.Buffer, format string, start, end buffer.Loc) (string, error) {
+	switch format {
+	case "html":
+		return b.ExportHTML(start, end), nil
+	case "ansi":
+		return b.ExportANSI(start, end), nil
+	}
+	return "", errors.New("Invalid format " + format + " (expected html or ansi)")
+}
+
+// ExportCmd renders the selection, or the whole buffer, with its
+// highlighting to a standalone HTML page or to text with ANSI escapes, which
+// is written to the given file or else opened in a new tab
+func (h *BufPane) ExportCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Error("usage: export html|ansi [file]")
+		return
+	}
+	start, end := h.Buf.Start(), h.Buf.End()
+	if h.Cursor.HasSelection() {
+		start, end = h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+		if end.LessThan(start) {
+			start, end = end, start
+		}
+	}
+	out, err := Export(h.Buf, args[0], start, end)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+
+	if len(args) > 1 {
+		filename, _ := util.ReplaceHome(args[1])
+		if err := ioutil.WriteFile(filename, []byte(out), 0644); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		InfoBar.Message("Exported to " + args[1])
+		return
+	}
+	width, height := screen.Screen.Size()
+	b := buffer.NewBufferFromString(out, "", buffer.BTDefault)
+	if args[0] == "html" {
+		b.SetOptionNative("filetype", "html")
+	}
+	Tabs.AddTab(NewTabFromBuffer(0, 0, width, height-1-config.GetInfoBarOffset(), b))
+	Tabs.SetActive(len(Tabs.List) - 1)
+}
+
+// HexFindCmd selects the next occurrence of the bytes given in hex, such as
+// `hexfind 7f 45 4c 46` or `hexfind 7f454c46`, in the hex view
+// (see the hex option)
+func (h *BufPane) HexFindCmd(args []string) {
+	if !h.Buf.Settings["hex"].(bool) {
+		InfoBar.Error("hexfind searches the hex view, turn on the hex option first")
+		return
+	}
+	pattern, err := hex.DecodeString(strings.Join(args, ""))
+	if err != nil {
+		InfoBar.Error("Invalid hex pattern: ", err)
+		return
+	}
+	searchLoc := h.Cursor.Loc
+	if h.Cursor.HasSelection() {
+		searchLoc = h.Cursor.CurSelection[1]
+	}
+	match, found, err := h.Buf.FindHex(pattern, searchLoc)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if !found {
+		InfoBar.Message("No matches found")
+		return
+	}
+	h.pushJump(h.Cursor.Loc)
+	h.Cursor.SetSelectionStart(match[0])
+	h.Cursor.SetSelectionEnd(match[1])
+	h.Cursor.OrigSelection[0] = h.Cursor.CurSelection[0]
+	h.Cursor.OrigSelection[1] = h.Cursor.CurSelection[1]
+	h.Cursor.Loc = h.Cursor.CurSelection[1]
+	h.Relocate()
+}
+
+// FilterCmd pipes the selection, or the whole buffer, through the command
+// and replaces it with the output, as a single undo step. Typing | before
+// a command in command mode is the same as filter.
+func (h *BufPane) FilterCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Error("Not enough arguments: filter 'command'")
+		return
+	}
+	start, end := h.Buf.Start(), h.Buf.End()
+	selected := h.Cursor.HasSelection()
+	if selected {
+		start, end = h.Cursor.CurSelection[0], h.Cursor.CurSelection[1]
+		if end.LessThan(start) {
+			start, end = end, start
+		}
+	}
+	input := h.Buf.Substr(start, end)
+	out, err := shell.Filter(args[0], args[1:], input)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	// most commands end their output with a line break even if the input
+	// didn't end with one
+	if !bytes.HasSuffix(input, []byte{'\n'}) {
+		out = bytes.TrimSuffix(out, []byte{'\n'})
+	}
+	if bytes.Equal(out, input) {
+		return
+	}
+
+	h.Buf.UndoGroup(func() {
+		if selected {
+			h.Cursor.Deselect(true)
+			h.Buf.Replace(start, end, string(out))
+		} else {
+			// the lines which didn't change keep the cursors
+			h.Buf.ApplyDiff(string(out))
+		}
+	})
+	h.Relocate()
+}
+
 // TabMoveCmd moves the current tab to a given index (starts at 1). The
 // displaced tabs are moved up.
 func (h *BufPane) TabMoveCmd(args []string) {
@@ -196,13 +704,7 @@ func (h *BufPane) TabMoveCmd(args []string) {
 	// Restrain position to within the valid range
 	idxTo = util.Clamp(idxTo, 0, len(Tabs.List)-1)
 
-	activeTab := Tabs.List[idxFrom]
-	Tabs.RemoveTab(activeTab.ID())
-	Tabs.List = append(Tabs.List, nil)
-	copy(Tabs.List[idxTo+1:], Tabs.List[idxTo:])
-	Tabs.List[idxTo] = activeTab
-	Tabs.UpdateNames()
-	Tabs.SetActive(idxTo)
+	Tabs.MoveTab(idxFrom, idxTo)
 	// InfoBar.Message(fmt.Sprintf("Moved tab from slot %d to %d", idxFrom+1, idxTo+1))
 }
 
@@ -280,6 +782,32 @@ func (h *BufPane) PwdCmd(args []string) {
 	}
 }
 
+// StatsCmd shows the number of lines, words, characters and bytes of the
+// buffer, and of the selections if there are any
+func (h *BufPane) StatsCmd(args []string) {
+	format := func(s buffer.Stats) string {
+		return fmt.Sprintf("%d lines, %d words, %d characters, %d bytes", s.Lines, s.Words, s.Chars, s.Bytes)
+	}
+	msg := format(h.Buf.Stats())
+
+	var sel buffer.Stats
+	selected := false
+	for _, c := range h.Buf.GetCursors() {
+		if c.HasSelection() {
+			s := buffer.TextStats(c.GetSelection())
+			sel.Lines += s.Lines
+			sel.Words += s.Words
+			sel.Chars += s.Chars
+			sel.Bytes += s.Bytes
+			selected = true
+		}
+	}
+	if selected {
+		msg = "Selection: " + format(sel) + " (buffer: " + msg + ")"
+	}
+	InfoBar.Message(msg)
+}
+
 // OpenCmd opens a new buffer with a given filename
 func (h *BufPane) OpenCmd(args []string) {
 	if len(args) > 0 {
@@ -303,7 +831,7 @@ func (h *BufPane) OpenCmd(args []string) {
 			}
 			h.OpenBuffer(b)
 		}
-		if h.Buf.Modified() {
+		if h.Buf.Unsaved() {
 			InfoBar.YNPrompt("Save changes to "+h.Buf.GetName()+" before closing? (y,n,esc)", func(yes, canceled bool) {
 				if !canceled && !yes {
 					open()
@@ -320,8 +848,318 @@ func (h *BufPane) OpenCmd(args []string) {
 	}
 }
 
-// ToggleLogCmd toggles the log view
+// maxProjectFiles limits how many files find-file and grep index
+const maxProjectFiles = 50000
+
+// projectPath converts a path relative to the project root to one that can
+// be opened, relative to the working directory if possible
+func projectPath(root, path string) string {
+	path = filepath.Join(root, filepath.FromSlash(path))
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := util.MakeRelative(path, wd); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// FindFileCmd opens a fuzzy picker with the files of the current project,
+// that is the enclosing git repository or the working directory. The
+// chosen file is opened in the current pane, or in a new split or tab if
+// the first argument is "vsplit", "hsplit" or "tab".
+func (h *BufPane) FindFileCmd(args []string) {
+	var open func(path string)
+	mode := ""
+	if len(args) > 0 {
+		mode = args[0]
+	}
+	switch mode {
+	case "":
+		open = func(path string) { h.OpenCmd([]string{shellquote.Join(path)}) }
+	case "vsplit":
+		open = func(path string) { h.VSplitCmd([]string{path}) }
+	case "hsplit":
+		open = func(path string) { h.HSplitCmd([]string{path}) }
+	case "tab":
+		open = func(path string) { h.NewTabCmd([]string{path}) }
+	default:
+		InfoBar.Error("Invalid argument: ", mode, " (expected vsplit, hsplit or tab)")
+		return
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	root := util.ProjectRoot(wd)
+	InfoBar.Message("Listing the files of ", root, "...")
+	// the files are listed in the background, as grep does, since large
+	// projects take a while to walk
+	go func() {
+		files, err := util.ProjectFiles(root, maxProjectFiles)
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			if err != nil && err != util.ErrTooManyFiles {
+				InfoBar.Error(err)
+				return
+			}
+			h.pickFile(root, files, err == util.ErrTooManyFiles, open)
+		}}
+	}()
+}
+
+// pickFile opens the find-file picker with the files of the project at
+// root, of which only the first ones were listed if truncated is true
+func (h *BufPane) pickFile(root string, files []string, truncated bool, open func(path string)) {
+	ranks := recentRanks()
+	items := make([]display.PickerItem, len(files))
+	for i, f := range files {
+		items[i] = display.PickerItem{Text: f, Recent: ranks[filepath.Join(root, filepath.FromSlash(f))], Path: true}
+	}
+	prompt := "Find file: "
+	if truncated {
+		prompt = fmt.Sprintf("Find file (first %d files): ", len(files))
+	}
+	InfoBar.Pick(prompt, "FindFile", items, func(it *display.PickerItem) {
+		if it == nil {
+			return
+		}
+		open(projectPath(root, it.Text))
+	})
+}
+
+// recentRanks maps the absolute paths of the recently opened files to their
+// rank, 1 for the most recent
+func recentRanks() map[string]int {
+	ranks := make(map[string]int)
+	files, _ := project.Recent("")
+	for i, f := range files {
+		ranks[f.Path] = i + 1
+	}
+	return ranks
+}
+
+// RecentCmd opens a fuzzy picker with the recently opened files, the most
+// recent first. The file of the current buffer is left out so that the
+// first entry is the previous file. The chosen file is opened at the cursor
+// position it had when it was last closed.
+func (h *BufPane) RecentCmd(args []string) {
+	files, err := project.Recent("")
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	home, _ := homedir.Dir()
+
+	var items []display.PickerItem
+	for i, f := range files {
+		if f.Path == h.Buf.AbsPath {
+			continue
+		}
+		if _, err := os.Stat(f.Path); err != nil {
+			continue
+		}
+		text := f.Path
+		if home != "" && strings.HasPrefix(text, home+string(filepath.Separator)) {
+			text = "~" + text[len(home):]
+		}
+		items = append(items, display.PickerItem{
+			Text:   text,
+			Detail: fmt.Sprintf("line %d", f.Line+1),
+			Data:   f,
+			Recent: i + 1,
+			Path:   true,
+		})
+	}
+	if len(items) == 0 {
+		InfoBar.Message("No recent files")
+		return
+	}
+	InfoBar.Pick("Recent file: ", "Recent", items, func(it *display.PickerItem) {
+		if it == nil {
+			return
+		}
+		f := it.Data.(project.RecentFile)
+		h.openAt(projectPath("", f.Path), buffer.Loc{X: f.Col, Y: f.Line})
+	})
+}
+
+// grepFlags holds the flags given to grep and grepreplace
+type grepFlags struct {
+	ignoreCase bool
+	context    int
+	engine     string
+}
+
+// parseGrepArgs parses the flags given to grep and grepreplace and returns
+// the remaining arguments. -i makes the search case-insensitive, -P uses the
+// pcre regex engine and -C n sets the number of context lines.
+func (h *BufPane) parseGrepArgs(args []string) (grepFlags, []string, error) {
+	flags := grepFlags{
+		context: 2,
+		engine:  h.Buf.Settings["regexengine"].(string),
+	}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "-i":
+			flags.ignoreCase = true
+		case "-P":
+			flags.engine = "pcre"
+		case "-C":
+			if len(args) < 2 {
+				return flags, nil, errors.New("-C requires a number of lines")
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 {
+				return flags, nil, errors.New("Invalid number of context lines: " + args[1])
+			}
+			flags.context = n
+			args = args[1:]
+		case "--":
+			return flags, args[1:], nil
+		default:
+			return flags, nil, errors.New("Unknown flag: " + args[0])
+		}
+		args = args[1:]
+	}
+	return flags, args, nil
+}
+
+// projectSearch compiles the search pattern and finds the root of the
+// current project for grep and grepreplace
+func projectSearch(pattern string, flags grepFlags) (util.Regexp, string, error) {
+	if flags.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := util.CompileRegexp(pattern, flags.engine)
+	if err != nil {
+		return nil, "", err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", err
+	}
+	return re, util.ProjectRoot(wd), nil
+}
+
+// GrepCmd searches the files of the current project for a regular
+// expression and shows the results in a new pane. The -i flag makes the
+// search case-insensitive, -P uses the pcre engine and -C n shows n lines
+// of context (2 by default).
+func (h *BufPane) GrepCmd(args []string) {
+	flags, args, err := h.parseGrepArgs(args)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(args) == 0 {
+		InfoBar.Error("Not enough arguments: grep [-i] [-P] [-C n] pattern")
+		return
+	}
+
+	pattern := strings.Join(args, " ")
+	re, root, err := projectSearch(pattern, flags)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	NewSearchPane(h, root, pattern, re, flags.context)
+}
+
+// GrepReplaceCmd searches the files of the current project for a regular
+// expression and opens a pane previewing the replacement of every match,
+// where the selected changes can be applied
+func (h *BufPane) GrepReplaceCmd(args []string) {
+	flags, args, err := h.parseGrepArgs(args)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(args) != 2 {
+		InfoBar.Error("Invalid arguments: grepreplace [-i] [-P] 'search' 'value'")
+		return
+	}
+
+	re, root, err := projectSearch(args[0], flags)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	NewReplacePane(h, root, args[0], re, []byte(args[1]))
+}
+
+// DiagnosticsCmd lists the messages of all open buffers, such as the
+// errors found by linters, in a new pane where Enter jumps to a message
+func (h *BufPane) DiagnosticsCmd(args []string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+
+	var results []util.GrepResult
+	for _, b := range buffer.OpenBuffers {
+		if b.Path == "" || len(b.Messages) == 0 {
+			continue
+		}
+		// paths are relative to the working directory when possible
+		path, err := util.MakeRelative(b.AbsPath, wd)
+		if err != nil || strings.HasPrefix(path, "..") {
+			path = b.AbsPath
+		}
+		r := util.GrepResult{Path: filepath.ToSlash(path)}
+		for _, m := range b.SortedMessages() {
+			pos := m.Pos()
+			text := fmt.Sprintf("%s: %s", m.Kind, m.Msg)
+			if m.Owner != "" {
+				text += " (" + m.Owner + ")"
+			}
+			r.Lines = append(r.Lines, util.GrepLine{Num: pos.Y, Text: text, Match: true, Col: pos.X})
+			r.Matches++
+		}
+		results = append(results, r)
+	}
+	if len(results) == 0 {
+		InfoBar.Message("No diagnostics")
+		return
+	}
+	NewListPane(h, "diagnostics", "", results, [2]string{"diagnostic", "diagnostics"})
+}
+
+// NoHlsearchCmd stops highlighting the matches of the last search until the
+// next search
+func (h *BufPane) NoHlsearchCmd(args []string) {
+	h.UnhighlightSearch()
+}
+
+// LogCmds are the subcommands of the log command
+var LogCmds = []string{"clear", "follow"}
+
+// ToggleLogCmd toggles the log view, or with "log clear" clears it, and
+// with "log follow" opens the log file in a new tab following what is
+// appended to it
 func (h *BufPane) ToggleLogCmd(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "clear":
+			buffer.LogBuf.EventHandler.Remove(buffer.LogBuf.Start(), buffer.LogBuf.End())
+		case "follow":
+			b, err := buffer.NewBufferFromFile(config.LogFile(), buffer.BTDefault)
+			if err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			b.SetOptionNative("follow", true)
+			width, height := screen.Screen.Size()
+			Tabs.AddTab(NewTabFromBuffer(0, 0, width, height-1-config.GetInfoBarOffset(), b))
+			Tabs.SetActive(len(Tabs.List) - 1)
+			b.GetActiveCursor().GotoLoc(b.End())
+		default:
+			InfoBar.Error("Usage: log [clear|follow]")
+		}
+		return
+	}
 	if h.Buf.Type != buffer.BTLog {
 		h.OpenLogBuf()
 	} else {
@@ -334,6 +1172,17 @@ func (h *BufPane) ReloadCmd(args []string) {
 	ReloadConfig()
 }
 
+// ReloadSyntaxCmd rereads the user's syntax files and rehighlights all
+// buffers
+func (h *BufPane) ReloadSyntaxCmd(args []string) {
+	n, bad := buffer.ReloadSyntax()
+	if bad > 0 {
+		InfoBar.Error(fmt.Sprintf("Reloaded the syntax files, %d of %d user syntax files have errors", bad, n))
+		return
+	}
+	InfoBar.Message(fmt.Sprintf("Reloaded the syntax files (%d user syntax files)", n))
+}
+
 func ReloadConfig() {
 	config.InitRuntimeFiles()
 	err := config.ReadSettings()
@@ -344,6 +1193,7 @@ func ReloadConfig() {
 	if err != nil {
 		screen.TermMessage(err)
 	}
+	config.FetchRuntime(false)
 	InitBindings()
 	InitCommands()
 
@@ -484,13 +1334,18 @@ func SetGlobalOptionNative(option string, nativeValue interface{}) error {
 		config.ModifiedSettings[option] = true
 
 		if option == "colorscheme" {
-			// LoadSyntaxFiles()
+			// a colorscheme given by URL is fetched in the background unless
+			// it is cached, and used once it is
+			config.FetchRuntime(false)
 			config.InitColorscheme()
 			for _, b := range buffer.OpenBuffers {
 				b.UpdateRules()
 			}
 		} else if option == "infobar" || option == "keymenu" {
 			Tabs.Resize()
+		} else if option == "keyprofile" {
+			unbindProfile()
+			InitBindings()
 		} else if option == "mouse" {
 			if !nativeValue.(bool) {
 				screen.Screen.DisableMouse()
@@ -504,14 +1359,38 @@ func SetGlobalOptionNative(option string, nativeValue interface{}) error {
 			} else {
 				config.SetAutoTime(0)
 			}
+		} else if option == "localhistory" {
+			config.StartLocalHistory(nativeValue.(float64))
 		} else if option == "paste" {
 			screen.Screen.SetPaste(nativeValue.(bool))
+		} else if option == "kittykeys" {
+			if !nativeValue.(bool) {
+				screen.DisableKittyKeys()
+			} else {
+				screen.QueryKeyboard()
+			}
+		} else if option == "ambiguouswidth" {
+			util.SetAmbiguousWidth(nativeValue.(string))
+			screen.RedrawAll()
+		} else if option == "screenreader" {
+			screen.RedrawAll()
 		} else if option == "clipboard" {
 			m := clipboard.SetMethod(nativeValue.(string))
 			err := clipboard.Initialize(m)
 			if err != nil {
 				return err
 			}
+		} else if option == "clipboardsync" {
+			clipboard.SetSyncedRegister(nativeValue.(string))
+		} else if option == "osc52maxsize" {
+			clipboard.SetMaxTerminalSize(int(nativeValue.(float64)))
+		} else if option == "loglevel" {
+			level, _ := util.ParseLogLevel(nativeValue.(string))
+			util.SetLogLevel(level)
+		} else if option == "logfilter" {
+			util.SetLogFilter(nativeValue.(string))
+		} else if option == "fuzzymatcher" || option == "fuzzycase" {
+			util.SetFuzzyMatcher(config.GetGlobalOption("fuzzymatcher").(string), config.GetGlobalOption("fuzzycase").(string))
 		} else {
 			for _, pl := range config.Plugins {
 				if option == pl.Name {
@@ -612,6 +1491,99 @@ func (h *BufPane) SetLocalCmd(args []string) {
 	}
 }
 
+// SetPaneCmd sets a display option for the pane only, so that the splits
+// of a buffer can show it differently
+func (h *BufPane) SetPaneCmd(args []string) {
+	if len(args) < 2 {
+		InfoBar.Error("Not enough arguments")
+		return
+	}
+	w, ok := h.BWindow.(*display.BufWindow)
+	if !ok {
+		InfoBar.Error("The pane has no options")
+		return
+	}
+
+	option := args[0]
+	if !contains(config.PaneSettings, option) {
+		InfoBar.Error(option, " cannot be set for a pane, only ", strings.Join(config.PaneSettings, ", "))
+		return
+	}
+	nativeValue, err := config.GetNativeValue(option, h.Buf.Settings[option], args[1])
+	if err == nil {
+		err = config.OptionIsValid(option, nativeValue)
+	}
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	w.SetOption(option, nativeValue)
+}
+
+// ResetPaneCmd sets the option of the pane, or all of them, back to the
+// value of its buffer
+func (h *BufPane) ResetPaneCmd(args []string) {
+	w, ok := h.BWindow.(*display.BufWindow)
+	if !ok {
+		return
+	}
+	if len(args) == 0 {
+		args = config.PaneSettings
+	}
+	for _, option := range args {
+		w.SetOption(option, nil)
+	}
+}
+
+// ToggleCmd turns a boolean option on or off, as set does
+func (h *BufPane) ToggleCmd(args []string) {
+	h.toggleOption(args, false)
+}
+
+// ToggleLocalCmd turns a boolean option on or off in the buffer, as
+// setlocal does, for example to type text aligned by hand without
+// autoindent or autoclose
+func (h *BufPane) ToggleLocalCmd(args []string) {
+	h.toggleOption(args, true)
+}
+
+// toggleOption turns the boolean option of args on or off, from its value
+// in the buffer, and shows its new value
+func (h *BufPane) toggleOption(args []string, local bool) {
+	if len(args) != 1 {
+		InfoBar.Error("Usage: toggle 'option'")
+		return
+	}
+	option := args[0]
+	value, ok := h.Buf.Settings[option]
+	if !ok {
+		value, ok = config.GlobalSettings[option]
+	}
+	if !ok {
+		InfoBar.Error(config.ErrInvalidOption)
+		return
+	}
+	on, ok := value.(bool)
+	if !ok {
+		InfoBar.Error(option, " is not an on or off option")
+		return
+	}
+
+	var err error
+	if _, global := config.GlobalSettings[option]; global && !local {
+		err = SetGlobalOptionNative(option, !on)
+	} else {
+		err = h.Buf.SetOptionNative(option, !on)
+	}
+	if err != nil {
+		InfoBar.Error(err)
+	} else if on {
+		InfoBar.Message(option, " is off")
+	} else {
+		InfoBar.Message(option, " is on")
+	}
+}
+
 // ShowCmd shows the value of the given option
 func (h *BufPane) ShowCmd(args []string) {
 	if len(args) < 1 {
@@ -663,6 +1635,13 @@ func (h *BufPane) BindCmd(args []string) {
 	_, err := TryBindKey(args[0], args[1], true)
 	if err != nil {
 		InfoBar.Error(err)
+		return
+	}
+
+	if event, err := findEvent(args[0]); err == nil {
+		if conflicts := BufBindings.Conflicts(event); len(conflicts) > 0 {
+			InfoBar.Message("Warning: ", strings.Join(conflicts, ", "), " (the shorter binding runs after keytimeout)")
+		}
 	}
 }
 
@@ -700,6 +1679,19 @@ func (h *BufPane) QuitCmd(args []string) {
 // GotoCmd is a command that will send the cursor to a certain
 // position in the buffer
 // For example: `goto line`, or `goto line:col`
+// gotoLineNum returns the line of the buffer for a line number given to
+// goto, counted from the end if it is negative. The viewer of a huge file
+// is moved to the line of the file.
+func (h *BufPane) gotoLineNum(line int) int {
+	if line < 0 {
+		line = h.Buf.FileLinesNum() + 1 + line
+	}
+	if h.Buf.Huge != nil {
+		return h.Buf.HugeLine(util.Max(line-1, 0))
+	}
+	return util.Clamp(line-1, 0, h.Buf.LinesNum()-1)
+}
+
 func (h *BufPane) GotoCmd(args []string) {
 	if len(args) <= 0 {
 		InfoBar.Error("Not enough arguments")
@@ -717,11 +1709,9 @@ func (h *BufPane) GotoCmd(args []string) {
 				InfoBar.Error(err)
 				return
 			}
-			if line < 0 {
-				line = h.Buf.LinesNum() + 1 + line
-			}
-			line = util.Clamp(line-1, 0, h.Buf.LinesNum()-1)
+			line = h.gotoLineNum(line)
 			col = util.Clamp(col-1, 0, util.CharacterCount(h.Buf.LineBytes(line)))
+			h.pushJump(h.Cursor.Loc)
 			h.Cursor.GotoLoc(buffer.Loc{col, line})
 		} else {
 			line, err := strconv.Atoi(args[0])
@@ -729,10 +1719,8 @@ func (h *BufPane) GotoCmd(args []string) {
 				InfoBar.Error(err)
 				return
 			}
-			if line < 0 {
-				line = h.Buf.LinesNum() + 1 + line
-			}
-			line = util.Clamp(line-1, 0, h.Buf.LinesNum()-1)
+			line = h.gotoLineNum(line)
+			h.pushJump(h.Cursor.Loc)
 			h.Cursor.GotoLoc(buffer.Loc{0, line})
 		}
 		h.Relocate()
@@ -744,13 +1732,63 @@ func (h *BufPane) SaveCmd(args []string) {
 	if len(args) == 0 {
 		h.Save()
 	} else {
-		h.Buf.SaveAs(args[0])
+		h.saveBufToFile(args[0], "SaveAs", nil)
+	}
+}
+
+// RenameCmd moves the file of the buffer to the given path
+func (h *BufPane) RenameCmd(args []string) {
+	if len(args) != 1 {
+		InfoBar.Error("Usage: rename newpath")
+		return
+	}
+	if err := h.Buf.Rename(args[0]); err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	InfoBar.Message("Renamed to ", h.Buf.Path)
+}
+
+// DeleteCmd moves the file of the buffer to the trash, or removes it, and
+// closes the panes showing it
+func (h *BufPane) DeleteCmd(args []string) {
+	b := h.Buf
+	if b.Path == "" || b.Type != buffer.BTDefault {
+		InfoBar.Error("The buffer has no file")
+		return
+	}
+	name := b.GetName()
+	done := func() {
+		trashed, err := b.DeleteFile()
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		for _, p := range panesOf(b) {
+			// the text of the deleted file is not kept as a draft
+			p.Buf.Settings["drafts"] = false
+			closePane(p)
+		}
+		if trashed {
+			InfoBar.Message("Moved ", name, " to the trash")
+		} else {
+			InfoBar.Message("Deleted ", name)
+		}
+	}
+	if !confirms("delete") {
+		done()
+		return
+	}
+	prompt := "Move " + name + " to the trash?"
+	if !util.HasTrash() {
+		prompt = "Delete " + name + " permanently?"
 	}
+	confirm("delete", prompt, done)
 }
 
 // ReplaceCmd runs search and replace
 func (h *BufPane) ReplaceCmd(args []string) {
-	if len(args) < 2 || len(args) > 4 {
+	if len(args) < 2 || len(args) > 5 {
 		// We need to find both a search and replace expression
 		InfoBar.Error("Invalid replace statement: " + strings.Join(args, " "))
 		return
@@ -758,6 +1796,7 @@ func (h *BufPane) ReplaceCmd(args []string) {
 
 	all := false
 	noRegex := false
+	luaFn := false
 
 	foundSearch := false
 	foundReplace := false
@@ -769,6 +1808,8 @@ func (h *BufPane) ReplaceCmd(args []string) {
 			all = true
 		case "-l":
 			noRegex = true
+		case "-f":
+			luaFn = true
 		default:
 			if !foundSearch {
 				foundSearch = true
@@ -787,14 +1828,13 @@ func (h *BufPane) ReplaceCmd(args []string) {
 		search = regexp.QuoteMeta(search)
 	}
 
-	replace := []byte(replaceStr)
-
-	var regex *regexp.Regexp
+	var regex util.Regexp
 	var err error
+	engine := h.Buf.Settings["regexengine"].(string)
 	if h.Buf.Settings["ignorecase"].(bool) {
-		regex, err = regexp.Compile("(?im)" + search)
+		regex, err = util.CompileRegexp("(?im)"+search, engine)
 	} else {
-		regex, err = regexp.Compile("(?m)" + search)
+		regex, err = util.CompileRegexp("(?m)"+search, engine)
 	}
 	if err != nil {
 		// There was an error with the user's regex
@@ -802,6 +1842,18 @@ func (h *BufPane) ReplaceCmd(args []string) {
 		return
 	}
 
+	replace := []byte(replaceStr)
+	repl := func(src []byte, match []int) []byte {
+		return util.ExpandTemplate(regex, nil, replace, src, match)
+	}
+	if luaFn {
+		repl, err = luaReplacement(replaceStr)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+	}
+
 	nreplaced := 0
 	start := h.Buf.Start()
 	end := h.Buf.End()
@@ -810,76 +1862,120 @@ func (h *BufPane) ReplaceCmd(args []string) {
 		start = h.Cursor.CurSelection[0]
 		end = h.Cursor.CurSelection[1]
 	}
-	if all {
-		nreplaced, _ = h.Buf.ReplaceRegex(start, end, regex, replace)
-	} else {
-		inRange := func(l buffer.Loc) bool {
-			return l.GreaterEqual(start) && l.LessEqual(end)
-		}
+	done := func() {
+		h.Cursor.ResetSelection()
+		h.Buf.RelocateCursors()
+		h.Relocate()
 
-		searchLoc := h.Cursor.Loc
-		var doReplacement func()
-		doReplacement = func() {
-			locs, found, err := h.Buf.FindNext(search, start, end, searchLoc, true, true)
-			if err != nil {
-				InfoBar.Error(err)
-				return
-			}
-			if !found || !inRange(locs[0]) || !inRange(locs[1]) {
-				h.Cursor.ResetSelection()
-				h.Buf.RelocateCursors()
+		var s string
+		if nreplaced > 1 {
+			s = fmt.Sprintf("Replaced %d occurrences of %s", nreplaced, search)
+		} else if nreplaced == 1 {
+			s = fmt.Sprintf("Replaced 1 occurrence of %s", search)
+		} else {
+			s = fmt.Sprintf("Nothing matched %s", search)
+		}
 
-				return
-			}
+		if selection {
+			s += " in selection"
+		}
 
-			h.Cursor.SetSelectionStart(locs[0])
-			h.Cursor.SetSelectionEnd(locs[1])
-			h.Cursor.GotoLoc(locs[0])
+		InfoBar.Message(s)
+	}
 
-			h.Relocate()
+	if all {
+		nreplaced, _ = h.Buf.ReplaceRegexFunc(start, end, regex, repl)
+		done()
+		return
+	}
 
-			InfoBar.YNPrompt("Perform replacement (y,n,esc)", func(yes, canceled bool) {
-				if !canceled && yes {
-					_, nrunes := h.Buf.ReplaceRegex(locs[0], locs[1], regex, replace)
+	inRange := func(l buffer.Loc) bool {
+		return l.GreaterEqual(start) && l.LessEqual(end)
+	}
 
-					searchLoc = locs[0]
-					searchLoc.X += nrunes + locs[0].Diff(locs[1], h.Buf)
-					if end.Y == locs[1].Y {
-						end = end.Move(nrunes, h.Buf)
-					}
-					h.Cursor.Loc = searchLoc
-					nreplaced++
-				} else if !canceled && !yes {
-					searchLoc = locs[0]
-					searchLoc.X += util.CharacterCount(replace)
-				} else if canceled {
-					h.Cursor.ResetSelection()
-					h.Buf.RelocateCursors()
-					return
-				}
-				doReplacement()
-			})
+	searchLoc := h.Cursor.Loc
+	var doReplacement func()
+	doReplacement = func() {
+		locs, found, err := h.Buf.FindNext(search, start, end, searchLoc, true, true)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		if !found || !inRange(locs[0]) || !inRange(locs[1]) {
+			done()
+			return
 		}
-		doReplacement()
-	}
 
-	h.Buf.RelocateCursors()
-	h.Relocate()
+		h.Cursor.SetSelectionStart(locs[0])
+		h.Cursor.SetSelectionEnd(locs[1])
+		h.Cursor.GotoLoc(locs[0])
 
-	var s string
-	if nreplaced > 1 {
-		s = fmt.Sprintf("Replaced %d occurrences of %s", nreplaced, search)
-	} else if nreplaced == 1 {
-		s = fmt.Sprintf("Replaced 1 occurrence of %s", search)
-	} else {
-		s = fmt.Sprintf("Nothing matched %s", search)
-	}
+		h.Relocate()
 
-	if selection {
-		s += " in selection"
+		InfoBar.ChoicePrompt("Perform replacement (y,n,a,q,esc)", "ynaq", func(c rune, canceled bool) {
+			if canceled || c == 'q' {
+				done()
+				return
+			}
+			if c == 'a' {
+				// this match and the ones after it
+				n, _ := h.Buf.ReplaceRegexFunc(locs[0], end, regex, repl)
+				nreplaced += n
+				done()
+				return
+			}
+			if c == 'y' {
+				_, nrunes := h.Buf.ReplaceRegexFunc(locs[0], locs[1], regex, repl)
+
+				searchLoc = locs[0]
+				searchLoc.X += nrunes + locs[0].Diff(locs[1], h.Buf)
+				if end.Y == locs[1].Y {
+					end = end.Move(nrunes, h.Buf)
+				}
+				h.Cursor.Loc = searchLoc
+				nreplaced++
+			} else {
+				searchLoc = locs[1]
+			}
+			if locs[0] == locs[1] {
+				// an empty match would be found again
+				searchLoc = searchLoc.Move(1, h.Buf)
+			}
+			doReplacement()
+		})
 	}
+	doReplacement()
+}
 
-	InfoBar.Message(s)
+// luaReplacement returns the replacement of the matches of the replace
+// command computed by the lua function fn, given as "plugin.function". It
+// is called with the text of the match followed by the texts of its
+// submatches, and returns the replacement text.
+func luaReplacement(fn string) (func(src []byte, match []int) []byte, error) {
+	luaFn := strings.SplitN(fn, ".", 2)
+	pl := config.FindPlugin(luaFn[0])
+	if pl == nil || len(luaFn) != 2 {
+		return nil, errors.New("Invalid lua function: " + fn)
+	}
+	return func(src []byte, match []int) []byte {
+		var args []lua.LValue
+		for i := 0; i+1 < len(match); i += 2 {
+			if match[i] < 0 {
+				args = append(args, lua.LString(""))
+			} else {
+				args = append(args, lua.LString(src[match[i]:match[i+1]]))
+			}
+		}
+		val, err := pl.Call(luaFn[1], args...)
+		if err != nil {
+			InfoBar.Error(err)
+			return src[match[0]:match[1]]
+		}
+		if s, ok := val.(lua.LString); ok {
+			return []byte(s)
+		}
+		return []byte(lua.LVAsString(val))
+	}, nil
 }
 
 // ReplaceAllCmd replaces search term all at once
@@ -888,6 +1984,49 @@ func (h *BufPane) ReplaceAllCmd(args []string) {
 	h.ReplaceCmd(append(args, "-a"))
 }
 
+// termShell returns the command of the shell run by a terminal, given by the
+// termshell option or else the SHELL environment variable
+func (h *BufPane) termShell() ([]string, error) {
+	if sh := h.Buf.Settings["termshell"].(string); sh != "" {
+		return shellquote.Split(sh)
+	}
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return []string{sh}, nil
+	}
+	return nil, errors.New("Shell environment not found")
+}
+
+// newTerminal returns a terminal whose command starts in the directory given
+// by the termdir option, with the variables of the termenv option added to
+// its environment
+func (h *BufPane) newTerminal() (*shell.Terminal, error) {
+	t := new(shell.Terminal)
+	env, err := shellquote.Split(h.Buf.Settings["termenv"].(string))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range env {
+		if !strings.Contains(v, "=") {
+			return nil, fmt.Errorf("termenv: expected NAME=value, got %q", v)
+		}
+	}
+	t.Env = env
+
+	dir := "."
+	if h.Buf.AbsPath != "" {
+		dir = filepath.Dir(h.Buf.AbsPath)
+	}
+	switch h.Buf.Settings["termdir"].(string) {
+	case "buffer":
+		t.Dir = dir
+	case "project":
+		if abs, err := filepath.Abs(dir); err == nil {
+			t.Dir = util.ProjectRoot(abs)
+		}
+	}
+	return t, nil
+}
+
 // TermCmd opens a terminal in the current view
 func (h *BufPane) TermCmd(args []string) {
 	ps := h.tab.Panes
@@ -898,17 +2037,19 @@ func (h *BufPane) TermCmd(args []string) {
 	}
 
 	if len(args) == 0 {
-		sh := os.Getenv("SHELL")
-		if sh == "" {
-			InfoBar.Error("Shell environment not found")
+		sh, err := h.termShell()
+		if err != nil {
+			InfoBar.Error(err)
 			return
 		}
-		args = []string{sh}
+		args = sh
 	}
 
 	term := func(i int, newtab bool) {
-		t := new(shell.Terminal)
-		err := t.Start(args, false, true, nil, nil)
+		t, err := h.newTerminal()
+		if err == nil {
+			err = t.Start(args, false, true, nil, nil)
+		}
 		if err != nil {
 			InfoBar.Error(err)
 			return
@@ -943,7 +2084,7 @@ func (h *BufPane) TermCmd(args []string) {
 
 	for i, p := range ps {
 		if p.ID() == h.ID() {
-			if h.Buf.Modified() {
+			if h.Buf.Unsaved() {
 				InfoBar.YNPrompt("Save changes to "+h.Buf.GetName()+" before closing? (y,n,esc)", func(yes, canceled bool) {
 					if !canceled && !yes {
 						term(i, false)
@@ -961,7 +2102,15 @@ func (h *BufPane) TermCmd(args []string) {
 
 // HandleCommand handles input from the user
 func (h *BufPane) HandleCommand(input string) {
+	cmd := strings.TrimLeft(input, " ")
+	if strings.HasPrefix(cmd, "|") {
+		input = "filter " + cmd[1:]
+	}
 	args, err := shellquote.Split(input)
+	if strings.HasPrefix(cmd, "=") {
+		// the expression is not split, so that its quotes stay in it
+		args, err = []string{"=", cmd[1:]}, nil
+	}
 	if err != nil {
 		InfoBar.Error("Error parsing args ", err)
 		return
@@ -978,6 +2127,7 @@ func (h *BufPane) HandleCommand(input string) {
 	} else {
 		WriteLog("> " + input + "\n")
 		commands[inputCmd].action(h, args[1:])
+		h.refusedEditMessage()
 		WriteLog("\n")
 	}
 }
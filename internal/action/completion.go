@@ -0,0 +1,188 @@
+package action
+
+import (
+	"unicode/utf8"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// completionHeight is the number of candidates the completion popup shows
+// at once
+const completionHeight = 10
+
+// completion is the state of the completion popup of a pane
+type completion struct {
+	popup      *display.Popup
+	candidates []buffer.Candidate
+	// loc is the cursor location the candidates complete
+	loc buffer.Loc
+}
+
+// Complete opens the completion popup with the candidates of all the
+// completion sources for the word before the cursor
+func (h *BufPane) Complete() bool {
+	return h.openCompletion(true)
+}
+
+// openCompletion shows the candidates for the text before the cursor in
+// the completion popup, or closes it if there are none. When the popup is
+// open manually, the absence of candidates is reported.
+func (h *BufPane) openCompletion(manual bool) bool {
+	if h.Buf.NumCursors() > 1 || h.Cursor.HasSelection() {
+		h.closeCompletion()
+		return false
+	}
+	cands := h.Buf.Complete()
+	if len(cands) == 0 {
+		h.closeCompletion()
+		if manual {
+			InfoBar.Message("No completions")
+		}
+		return false
+	}
+
+	comp := h.completion
+	if comp == nil {
+		comp = &completion{popup: display.NewPopup(0, 0, nil)}
+		comp.popup.Border = true
+		comp.popup.Z = 1
+		h.completion = comp
+	}
+
+	// the candidate selected before stays selected while it matches
+	selected := 0
+	if p := comp.popup; p.Selected >= 0 && p.Selected < len(comp.candidates) {
+		prev := comp.candidates[p.Selected]
+		for i, c := range cands {
+			if c.Text == prev.Text && c.Start == prev.Start {
+				selected = i
+				break
+			}
+		}
+	}
+
+	comp.candidates = cands
+	comp.loc = h.Cursor.Loc
+	p := comp.popup
+	p.Lines = make([]display.PopupLine, len(cands))
+	start := h.Cursor.X
+	for i, c := range cands {
+		p.Lines[i].Text = c.Text
+		if c.Label != "" {
+			p.Lines[i].Text = c.Label
+		}
+		p.Lines[i].Detail = c.Source
+		start = util.Min(start, c.Start)
+	}
+	p.Height = util.Min(len(cands), completionHeight)
+	p.Selected = selected
+	h.placePopup(p, buffer.Loc{X: start, Y: h.Cursor.Y})
+	p.Show()
+	return true
+}
+
+// closeCompletion hides the completion popup, if it is shown
+func (h *BufPane) closeCompletion() {
+	if h.completion != nil {
+		h.completion.popup.Hide()
+		h.completion = nil
+	}
+}
+
+// updateCompletion updates the candidates of the completion popup after an
+// event moved the cursor, closing it if the cursor left the line. typed is
+// the character the event inserted, or 0. A word character opens the popup
+// if the autocomplete option is on and the word before the cursor has at
+// least autocompletechars characters, and the completion triggers, such as
+// the one of the pathcomplete option, may open it after any character.
+func (h *BufPane) updateCompletion(typed rune) {
+	if comp := h.completion; comp != nil {
+		if h.Cursor.Y != comp.loc.Y {
+			h.closeCompletion()
+		} else if h.Cursor.Loc != comp.loc {
+			h.openCompletion(false)
+		}
+		return
+	}
+
+	if typed == 0 {
+		return
+	}
+	if h.triggerCompletion(typed) {
+		return
+	}
+	if !util.IsWordChar(typed) || !h.Buf.Settings["autocomplete"].(bool) {
+		return
+	}
+	word, _ := buffer.GetWord(h.Buf)
+	if util.CharacterCount(word) >= util.IntOpt(h.Buf.Settings["autocompletechars"]) {
+		h.openCompletion(false)
+	}
+}
+
+// triggerCompletion opens the completion popup if one of the completion
+// triggers opens it after the character typed, in the buffers of files
+func (h *BufPane) triggerCompletion(typed rune) bool {
+	if h.Buf.Type.Kind != buffer.BTDefault.Kind || !h.Buf.NewCompletionRequest().Triggered(typed) {
+		return false
+	}
+	return h.openCompletion(false)
+}
+
+// CompleteNext selects the next candidate of the completion popup
+func (h *BufPane) CompleteNext() bool {
+	return h.moveCompletion(1)
+}
+
+// CompletePrevious selects the previous candidate of the completion popup
+func (h *BufPane) CompletePrevious() bool {
+	return h.moveCompletion(-1)
+}
+
+func (h *BufPane) moveCompletion(dir int) bool {
+	comp := h.completion
+	if comp == nil {
+		return false
+	}
+	n := len(comp.candidates)
+	comp.popup.Selected = (comp.popup.Selected + dir + n) % n
+	return true
+}
+
+// CompleteAccept replaces the text before the cursor by the candidate
+// selected in the completion popup. A snippet is inserted, its first tab
+// stop selected.
+func (h *BufPane) CompleteAccept() bool {
+	comp := h.completion
+	if comp == nil {
+		return false
+	}
+	c := comp.candidates[comp.popup.Selected]
+	h.closeCompletion()
+
+	start := buffer.Loc{X: c.Start, Y: h.Cursor.Y}
+	if c.Snippet {
+		h.Buf.InsertSnippet(start, h.Cursor.Loc, c.Text)
+	} else {
+		h.Buf.Replace(start, h.Cursor.Loc, c.Text)
+	}
+	buffer.RecordCompletion(c.Text)
+	h.Relocate()
+	// a directory accepted opens the popup with its files, as typing its
+	// separator does
+	if r, _ := utf8.DecodeLastRuneInString(c.Text); !c.Snippet {
+		h.triggerCompletion(r)
+	}
+	return true
+}
+
+// CompleteCancel closes the completion popup
+func (h *BufPane) CompleteCancel() bool {
+	if h.completion == nil {
+		return false
+	}
+	h.closeCompletion()
+	return true
+}
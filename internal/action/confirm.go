@@ -0,0 +1,43 @@
+package action
+
+import (
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// confirms returns whether the dangerous action, one of
+// config.ConfirmedActions, is listed by the confirm option and so must be
+// confirmed before it is done
+func confirms(action string) bool {
+	for _, a := range strings.Split(config.GetGlobalOption("confirm").(string), ",") {
+		if strings.TrimSpace(a) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// confirm asks whether to do the dangerous action and calls done if the
+// user agrees. Answering 'a' agrees and stops asking for it: the action is
+// removed from the confirm option, which is saved to settings.json.
+func confirm(action, prompt string, done func()) {
+	InfoBar.ChoicePrompt(prompt+" (y,n,a=always,esc)", "yna", func(c rune, canceled bool) {
+		if canceled || c == 'n' {
+			return
+		}
+		if c == 'a' {
+			var kept []string
+			for _, a := range strings.Split(config.GetGlobalOption("confirm").(string), ",") {
+				if a = strings.TrimSpace(a); a != "" && a != action {
+					kept = append(kept, a)
+				}
+			}
+			if err := SetGlobalOption("confirm", strings.Join(kept, ",")); err != nil {
+				InfoBar.Error(err)
+				return
+			}
+		}
+		done()
+	})
+}
@@ -0,0 +1,82 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A CopyModePane shows the scrollback and the screen of a terminal pane in
+// its place, in a read-only buffer which is browsed, searched and selected
+// as any other. Enter copies the selection and goes back to the terminal,
+// as Escape and q do without copying it, and Ctrl-t opens the selection, or
+// the whole text, in a new tab.
+type CopyModePane struct {
+	*BufPane
+
+	term *TermPane
+}
+
+// CopyMode replaces the terminal pane with a CopyModePane
+func (t *TermPane) CopyMode() {
+	b := buffer.NewBufferFromString(t.History(), "", buffer.BTScrollback)
+	b.SetName("Copy mode: " + t.Name())
+
+	cp := new(CopyModePane)
+	cp.BufPane = NewBufPaneFromBuf(b, t.tab)
+	cp.term = t
+	cp.SetID(t.ID())
+
+	tab := t.tab
+	i := tab.GetPane(t.ID())
+	tab.Panes[i] = cp
+	tab.Resize()
+	tab.SetActive(i)
+	cp.Cursor.GotoLoc(buffer.Loc{X: 0, Y: b.LinesNum() - 1})
+	cp.Relocate()
+	InfoBar.Message("Copy mode: Enter copies the selection, Escape goes back to the terminal")
+}
+
+// HandleEvent handles the keys leaving copy mode and passes everything else
+// to the bufpane
+func (cp *CopyModePane) HandleEvent(event tcell.Event) {
+	if e, ok := event.(*tcell.EventKey); ok {
+		plain := e.Modifiers() == tcell.ModNone
+		switch {
+		case plain && (e.Key() == tcell.KeyEscape || e.Key() == tcell.KeyRune && e.Rune() == 'q'):
+			cp.exit()
+			return
+		case plain && e.Key() == tcell.KeyEnter:
+			cp.Copy()
+			cp.exit()
+			return
+		case e.Key() == tcell.KeyCtrlT:
+			text := cp.Buf.Bytes()
+			if cp.Cursor.HasSelection() {
+				text = cp.Cursor.GetSelection()
+			}
+			cp.exit()
+			width, height := screen.Screen.Size()
+			b := buffer.NewBufferFromString(string(text), "", buffer.BTDefault)
+			Tabs.AddTab(NewTabFromBuffer(0, 0, width, height-config.GetInfoBarOffset(), b))
+			Tabs.SetActive(len(Tabs.List) - 1)
+			return
+		}
+	}
+	cp.BufPane.HandleEvent(event)
+}
+
+// exit puts the terminal pane back in place of the copy mode
+func (cp *CopyModePane) exit() {
+	tab := cp.tab
+	for i, p := range tab.Panes {
+		if p == cp {
+			tab.Panes[i] = cp.term
+			tab.Resize()
+			tab.SetActive(i)
+			break
+		}
+	}
+	cp.Close()
+}
@@ -0,0 +1,165 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/dbexec"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// dbPane is the pane showing the results of the last statement, reused by
+// the next one
+var dbPane *BufPane
+
+// dbCancel cancels the statement being run, if there is one
+var dbCancel context.CancelFunc
+
+// DBExec runs the selected SQL or the statement under the cursor, see
+// DBExecCmd
+func (h *BufPane) DBExec() bool {
+	h.DBExecCmd(nil)
+	return true
+}
+
+// DBCancel cancels the statement being run by dbexec
+func (h *BufPane) DBCancel() bool {
+	h.DBExecCmd([]string{"cancel"})
+	return true
+}
+
+// statementUnderCursor returns the selected text, or else the SQL
+// statement containing the cursor
+func (h *BufPane) statementUnderCursor() string {
+	if h.Cursor.HasSelection() {
+		return strings.TrimRight(strings.TrimSpace(string(h.Cursor.GetSelection())), ";")
+	}
+	text := string(h.Buf.Bytes())
+	offset := 0
+	for y := 0; y < h.Cursor.Y; y++ {
+		offset += len(h.Buf.LineBytes(y)) + 1
+	}
+	offset += len(util.SliceStart(h.Buf.LineBytes(h.Cursor.Y), h.Cursor.X))
+	start, end := dbexec.Statement(text, offset)
+	return text[start:end]
+}
+
+// DBExecCmd runs the selected SQL or the statement under the cursor with
+// the client command of the dbcmd option in the background, and shows the
+// results below as a table according to the dbformat option.
+// 'dbexec cancel' kills the client running.
+func (h *BufPane) DBExecCmd(args []string) {
+	if len(args) > 0 {
+		if args[0] != "cancel" {
+			InfoBar.Error("Usage: dbexec [cancel]")
+		} else if dbCancel == nil {
+			InfoBar.Message("No statement is running")
+		} else {
+			dbCancel()
+		}
+		return
+	}
+	if dbCancel != nil {
+		InfoBar.Error("A statement is running, cancel it with 'dbexec cancel'")
+		return
+	}
+
+	dbcmd := h.Buf.Settings["dbcmd"].(string)
+	if dbcmd == "" {
+		InfoBar.Error("Set the dbcmd option to the command of the client of the database, such as 'sqlite3 -csv -header db.sqlite'")
+		return
+	}
+	statement := h.statementUnderCursor()
+	if statement == "" {
+		InfoBar.Error("No statement under the cursor")
+		return
+	}
+	expandSecrets([]string{h.taskReplacer().Replace(dbcmd)}, func(strs []string) {
+		command, err := shellquote.Split(strs[0])
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		h.runStatement(command, statement)
+	})
+}
+
+// runStatement runs the statement with the client command in the
+// background and shows the results below
+func (h *BufPane) runStatement(command []string, statement string) {
+	format := h.Buf.Settings["dbformat"].(string)
+	dir := h.projectRoot()
+
+	p := h.dbResultsPane(statement)
+	ctx, cancel := context.WithCancel(context.Background())
+	dbCancel = cancel
+	InfoBar.Message("Running the statement...")
+	go func() {
+		start := time.Now()
+		out, err := dbexec.Run(ctx, command, dir, statement)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			cancel()
+			dbCancel = nil
+			if !p.isOpen() {
+				return
+			}
+			text, msg := dbResults(out, err, format, elapsed)
+			b := p.Buf
+			b.EventHandler.Replace(b.Start(), b.End(), text)
+			b.UndoStack = new(buffer.TEStack)
+			b.RedoStack = new(buffer.TEStack)
+			p.Cursor.GotoLoc(b.Start())
+			p.Relocate()
+			if err != nil {
+				InfoBar.Error(msg)
+			} else {
+				InfoBar.Message(msg)
+			}
+		}}
+	}()
+}
+
+// dbResults returns the text of the results pane for the output of a
+// client, and the message shown in the infobar
+func dbResults(out string, err error, format string, elapsed time.Duration) (string, string) {
+	if err == context.Canceled {
+		return out + "[canceled]\n", "Statement canceled"
+	} else if err != nil {
+		return out + err.Error() + "\n", "Error running the statement"
+	}
+	rows, perr := dbexec.Parse(out, format)
+	if perr != nil || rows == nil {
+		return out, fmt.Sprintf("Done in %v", elapsed)
+	}
+	if len(rows) == 0 {
+		return "", fmt.Sprintf("No rows in %v", elapsed)
+	}
+	return dbexec.Table(rows), fmt.Sprintf("%d rows in %v", len(rows)-1, elapsed)
+}
+
+// dbResultsPane returns the pane showing the results of the last
+// statement if it is still open, or else opens one below h, and shows in it
+// that the statement is running
+func (h *BufPane) dbResultsPane(statement string) *BufPane {
+	p := dbPane
+	if p == nil || !p.isOpen() {
+		b := buffer.NewBufferFromString("", "", buffer.BTLog)
+		p = h.HSplitIndex(b, true)
+		// the focus stays on the buffer
+		h.tab.SetActive(h.tab.GetPane(h.splitID))
+		dbPane = p
+	}
+	name := strings.Fields(statement)
+	if len(name) > 6 {
+		name = append(name[:6], "...")
+	}
+	p.Buf.SetName("dbexec: " + strings.Join(name, " "))
+	p.Buf.EventHandler.Replace(p.Buf.Start(), p.Buf.End(), "[running]\n")
+	return p
+}
@@ -0,0 +1,860 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/dap"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// debugConfigurations are the debug configurations of the filetypes, used
+// when the project settings file has none (see dap.NewConfiguration). Their
+// strings expand %f, %d, %n and %p as the task commands do.
+var debugConfigurations = map[string]map[string]interface{}{
+	"go": {
+		"adapter": "dlv dap --listen 127.0.0.1:38697",
+		"address": "127.0.0.1:38697",
+		"mode":    "debug",
+		"program": "%d",
+	},
+	"python": {
+		"adapter": "python3 -m debugpy.adapter",
+		"program": "%f",
+		"console": "internalConsole",
+	},
+	"c":   {"adapter": "lldb-dap", "program": "%d/%n"},
+	"c++": {"adapter": "lldb-dap", "program": "%d/%n"},
+}
+
+// DebugCmds are the subcommands of the debug command
+var DebugCmds = []string{"start", "stop", "continue", "next", "step", "out", "pause", "eval", "stack", "variables", "console", "clear"}
+
+// debugger is the running debugging session, or nil
+var debugger *debugSession
+
+// A debugSession is a program being debugged through a debug adapter
+type debugSession struct {
+	client *dap.Client
+	config dap.Configuration
+	caps   dap.Capabilities
+	// origin is the pane the sources are opened in
+	origin *BufPane
+	// thread is the thread which stopped last, and stopped whether the
+	// program is stopped
+	thread  int
+	stopped bool
+	// frames is the stack of the thread, and frame the index of the frame
+	// shown, whose variables are vars
+	frames []dap.StackFrame
+	frame  int
+	vars   []*debugVariable
+	// generation changes when the program runs or another frame is shown,
+	// so that the variables which come after that are dropped
+	generation int
+	stopping   bool
+
+	console, stack, variables *DebugPane
+}
+
+// A debugVariable is a line of the variables pane: a scope at depth 0, or a
+// variable below it. A variable with a reference has fields, shown below
+// it when it is expanded.
+type debugVariable struct {
+	depth    int
+	name     string
+	value    string
+	ref      int
+	expanded bool
+}
+
+// A DebugPane shows the output of the program being debugged in the
+// console, the stack of the thread which stopped, or the variables of the
+// frame shown. Enter on a frame of the stack shows its location and its
+// variables, and Enter on a variable expands or collapses its fields.
+type DebugPane struct {
+	*BufPane
+
+	session *debugSession
+	kind    string
+}
+
+// debugDispatch runs f in the main goroutine, where the messages of the
+// adapter are handled
+func debugDispatch(f func()) {
+	shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) { f() }}
+}
+
+// debugOutput writes the output of the adapter to the console
+type debugOutput struct {
+	s *debugSession
+}
+
+func (o debugOutput) Write(p []byte) (int, error) {
+	text := string(p)
+	debugDispatch(func() { o.s.print(text) })
+	return len(p), nil
+}
+
+// debugConfiguration returns the configuration with the given name of the
+// project of the buffer, its first one if name is empty, or else the one
+// of the filetype
+func (h *BufPane) debugConfiguration(name string) (dap.Configuration, error) {
+	configs, err := dap.LoadConfigurations(h.projectRoot())
+	if err != nil {
+		return dap.Configuration{}, err
+	}
+	for _, c := range configs {
+		if name == "" || c.Name == name {
+			return c, nil
+		}
+	}
+	if name != "" {
+		return dap.Configuration{}, errors.New("No debug configuration named " + name)
+	}
+	v, ok := debugConfigurations[h.Buf.FileType()]
+	if !ok {
+		return dap.Configuration{}, errors.New("No debug configuration for " + h.Buf.FileType() + ", add one to the debug array of " + util.ProjectSettingsFile)
+	}
+	c, err := dap.NewConfiguration(v)
+	c.Name = h.Buf.FileType()
+	return c, err
+}
+
+// startDebugger starts a debugging session with the debug configuration
+// name (see debugConfiguration), the buffer being saved first if it is
+// modified
+func (h *BufPane) startDebugger(name string) {
+	if debugger != nil {
+		InfoBar.Error("A debugging session is running, stop it with 'debug stop'")
+		return
+	}
+	c, err := h.debugConfiguration(name)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	c = c.Expand(h.taskReplacer().Replace)
+	command, err := shellquote.Split(c.Adapter)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	dir := h.projectRoot()
+
+	start := func() {
+		s := &debugSession{config: c, origin: h}
+		debugger = s
+		s.pane("console")
+		s.print("[" + c.Name + ": " + c.Adapter + "]\n")
+		InfoBar.Message("Starting the debug adapter...")
+		go func() {
+			client, err := dap.Start(command, dir, c.Address, debugOutput{s})
+			debugDispatch(func() {
+				if err != nil {
+					if debugger == s {
+						debugger = nil
+					}
+					InfoBar.Error("Error starting the debug adapter: ", err)
+					return
+				}
+				s.run(client)
+			})
+		}()
+	}
+	if h.Buf.Modified() && h.Buf.Path != "" {
+		h.SaveCB("Debug", start)
+		return
+	}
+	start()
+}
+
+// run initializes the session with the adapter reached by client, and
+// then launches or attaches to the program
+func (s *debugSession) run(client *dap.Client) {
+	s.client = client
+	client.Dispatch = debugDispatch
+	client.OnEvent = s.handleEvent
+	client.OnClose = s.closed
+	go client.Run()
+
+	if s.stopping {
+		// stopped while it was starting
+		client.Close()
+		return
+	}
+	args := dap.InitializeArguments{
+		ClientID:        "micro",
+		ClientName:      "micro",
+		AdapterID:       s.config.Name,
+		LinesStartAt1:   true,
+		ColumnsStartAt1: true,
+		PathFormat:      "path",
+	}
+	s.request("initialize", args, func(resp *dap.Message) {
+		if !s.check(resp, &s.caps) {
+			s.stop()
+			return
+		}
+		s.request(s.config.Request, s.config.Arguments, func(resp *dap.Message) {
+			if !s.check(resp, nil) {
+				s.stop()
+			}
+		})
+	})
+}
+
+// request sends a request to the adapter, and shows the error if it can't
+func (s *debugSession) request(command string, args interface{}, cb func(resp *dap.Message)) {
+	if s.client == nil {
+		InfoBar.Error("The debug adapter is starting")
+		return
+	}
+	if err := s.client.Request(command, args, cb); err != nil {
+		InfoBar.Error(err)
+	}
+}
+
+// check decodes the body of the response into v if it is not nil, and
+// shows the error of the response and returns false if it failed
+func (s *debugSession) check(resp *dap.Message, v interface{}) bool {
+	err := resp.Err()
+	if err == nil && v != nil {
+		err = resp.Decode(v)
+	}
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	return true
+}
+
+// checkResponse shows the error of a response whose body is not used
+func (s *debugSession) checkResponse(resp *dap.Message) {
+	s.check(resp, nil)
+}
+
+func (s *debugSession) handleEvent(e *dap.Message) {
+	switch e.Event {
+	case "initialized":
+		s.sendAllBreakpoints()
+		if s.caps.SupportsConfigurationDoneRequest {
+			s.request("configurationDone", nil, s.checkResponse)
+		}
+	case "stopped":
+		var body dap.StoppedEvent
+		e.Decode(&body)
+		if body.ThreadID != 0 {
+			s.thread = body.ThreadID
+		}
+		s.stopped = true
+		msg := "Stopped"
+		if body.Reason != "" {
+			msg += " on " + body.Reason
+		}
+		if body.Text != "" {
+			msg += ": " + body.Text
+		}
+		InfoBar.Message(msg)
+		s.withThread(s.loadStack)
+	case "continued":
+		s.resumed()
+	case "output":
+		var body dap.OutputEvent
+		e.Decode(&body)
+		if body.Category != "telemetry" {
+			s.print(body.Output)
+		}
+	case "exited":
+		var body dap.ExitedEvent
+		e.Decode(&body)
+		s.print(fmt.Sprintf("\n[exited with code %d]\n", body.ExitCode))
+	case "terminated":
+		s.stop()
+	}
+}
+
+// closed ends the session when the connection to the adapter is closed
+func (s *debugSession) closed(err error) {
+	if debugger == s {
+		debugger = nil
+	}
+	s.resumed()
+	s.print("\n[debugging session ended]\n")
+	if err != nil {
+		InfoBar.Error("The debug adapter stopped: ", err)
+	} else {
+		InfoBar.Message("Debugging session ended")
+	}
+}
+
+// stop disconnects from the adapter, which terminates the program if it
+// was launched. The adapter is closed if it doesn't answer.
+func (s *debugSession) stop() {
+	if s.stopping {
+		return
+	}
+	s.stopping = true
+	if s.client == nil {
+		return
+	}
+	client := s.client
+	args := dap.DisconnectArguments{TerminateDebuggee: s.config.Request == "launch"}
+	if err := client.Request("disconnect", args, func(*dap.Message) { client.Close() }); err != nil {
+		client.Close()
+		return
+	}
+	time.AfterFunc(2*time.Second, client.Close)
+}
+
+// closeDebugger stops the debugging session if there is one, when micro
+// exits
+func closeDebugger() {
+	if s := debugger; s != nil && s.client != nil {
+		s.client.Close()
+	}
+}
+
+// withThread calls f once the thread to act on is known: the thread which
+// stopped last, or else the first thread of the program
+func (s *debugSession) withThread(f func()) {
+	if s.thread != 0 {
+		f()
+		return
+	}
+	s.request("threads", nil, func(resp *dap.Message) {
+		var body struct {
+			Threads []dap.Thread `json:"threads"`
+		}
+		if !s.check(resp, &body) {
+			return
+		}
+		if len(body.Threads) == 0 {
+			InfoBar.Error("The program has no thread")
+			return
+		}
+		s.thread = body.Threads[0].ID
+		f()
+	})
+}
+
+// resumed forgets the stack and the variables when the program runs again
+func (s *debugSession) resumed() {
+	s.stopped = false
+	s.frames = nil
+	s.vars = nil
+	s.generation++
+	clearStoppedLines()
+	if s.stack.isOpen() {
+		s.stack.render()
+	}
+	if s.variables.isOpen() {
+		s.variables.render()
+	}
+}
+
+// step sends the request command, such as next, to move the thread which
+// stopped
+func (s *debugSession) step(command string) bool {
+	if !s.stopped {
+		InfoBar.Message("The program is not stopped")
+		return false
+	}
+	s.request(command, dap.ThreadArguments{ThreadID: s.thread}, s.checkResponse)
+	s.resumed()
+	return true
+}
+
+// pause stops the program where it runs
+func (s *debugSession) pause() bool {
+	if s.stopped {
+		InfoBar.Message("The program is stopped")
+		return false
+	}
+	s.withThread(func() {
+		s.request("pause", dap.ThreadArguments{ThreadID: s.thread}, s.checkResponse)
+	})
+	return true
+}
+
+// evaluate evaluates the expression in the frame shown, and prints its
+// value in the console
+func (s *debugSession) evaluate(expr string) {
+	args := dap.EvaluateArguments{Expression: expr, Context: "repl"}
+	if s.stopped && s.frame < len(s.frames) {
+		args.FrameID = s.frames[s.frame].ID
+	}
+	s.print("> " + expr + "\n")
+	s.request("evaluate", args, func(resp *dap.Message) {
+		var body dap.EvaluateResponse
+		if err := resp.Err(); err != nil {
+			s.print(err.Error() + "\n")
+		} else if err := resp.Decode(&body); err == nil {
+			s.print(body.Result + "\n")
+		}
+	})
+}
+
+// loadStack shows the stack of the thread which stopped, and the location
+// of its first frame with a source
+func (s *debugSession) loadStack() {
+	gen := s.generation
+	args := dap.StackTraceArguments{ThreadID: s.thread, Levels: 100}
+	s.request("stackTrace", args, func(resp *dap.Message) {
+		var body struct {
+			StackFrames []dap.StackFrame `json:"stackFrames"`
+		}
+		if gen != s.generation || !s.check(resp, &body) {
+			return
+		}
+		s.frames = body.StackFrames
+		s.pane("stack")
+		s.pane("variables")
+		frame := 0
+		for i, f := range s.frames {
+			if f.Source != nil && f.Source.Path != "" {
+				frame = i
+				break
+			}
+		}
+		if len(s.frames) > 0 {
+			s.showFrame(frame)
+		}
+	})
+}
+
+// showFrame shows the location of the i-th frame of the stack in the pane
+// the sources are opened in, and its variables
+func (s *debugSession) showFrame(i int) {
+	s.frame = i
+	s.vars = nil
+	s.generation++
+	s.stack.render()
+	s.variables.render()
+
+	f := s.frames[i]
+	clearStoppedLines()
+	if f.Source != nil && f.Source.Path != "" {
+		loc := buffer.Loc{X: util.Max(f.Column-1, 0), Y: util.Max(f.Line-1, 0)}
+		if h := s.sourcePane().openAt(f.Source.Path, loc); h != nil {
+			h.Buf.SetStoppedLine(loc.Y)
+			s.origin = h
+		}
+	}
+
+	gen := s.generation
+	s.request("scopes", map[string]int{"frameId": f.ID}, func(resp *dap.Message) {
+		var body struct {
+			Scopes []dap.Scope `json:"scopes"`
+		}
+		if gen != s.generation || !s.check(resp, &body) {
+			return
+		}
+		for _, sc := range body.Scopes {
+			v := &debugVariable{name: sc.Name, ref: sc.VariablesReference}
+			s.vars = append(s.vars, v)
+			if !sc.Expensive {
+				s.expand(v)
+			}
+		}
+		s.variables.render()
+	})
+}
+
+// expand shows the fields of the variable v, or the variables of a scope
+func (s *debugSession) expand(v *debugVariable) {
+	gen := s.generation
+	s.request("variables", map[string]int{"variablesReference": v.ref}, func(resp *dap.Message) {
+		var body struct {
+			Variables []dap.Variable `json:"variables"`
+		}
+		if gen != s.generation || !s.check(resp, &body) || v.expanded {
+			return
+		}
+		i := s.varIndex(v)
+		if i < 0 {
+			return
+		}
+		v.expanded = true
+		fields := make([]*debugVariable, len(body.Variables))
+		for j, f := range body.Variables {
+			fields[j] = &debugVariable{depth: v.depth + 1, name: f.Name, value: f.Value, ref: f.VariablesReference}
+		}
+		s.vars = append(s.vars[:i+1], append(fields, s.vars[i+1:]...)...)
+		s.variables.render()
+	})
+}
+
+// collapse hides the fields of the variable v
+func (s *debugSession) collapse(v *debugVariable) {
+	i := s.varIndex(v)
+	j := i + 1
+	for j < len(s.vars) && s.vars[j].depth > v.depth {
+		j++
+	}
+	s.vars = append(s.vars[:i+1], s.vars[j:]...)
+	v.expanded = false
+	s.variables.render()
+}
+
+func (s *debugSession) varIndex(v *debugVariable) int {
+	for i, w := range s.vars {
+		if w == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// sourcePane returns the pane the sources are opened in: the one they were
+// last opened in if it is still open, or else the first pane of a file
+func (s *debugSession) sourcePane() *BufPane {
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			if p == Pane(s.origin) {
+				return s.origin
+			}
+		}
+	}
+	for _, p := range MainTab().Panes {
+		if bp, ok := p.(*BufPane); ok && bp.Buf.Type == buffer.BTDefault {
+			return bp
+		}
+	}
+	return MainTab().CurPane()
+}
+
+// clearStoppedLines removes the marks of the line where the program
+// stopped
+func clearStoppedLines() {
+	for _, b := range buffer.OpenBuffers {
+		b.SetStoppedLine(-1)
+	}
+}
+
+// sendBreakpoints sets the breakpoints of the file of the buffer b
+func (s *debugSession) sendBreakpoints(b *buffer.Buffer) {
+	bps := []dap.SourceBreakpoint{}
+	for _, y := range b.Breakpoints() {
+		bps = append(bps, dap.SourceBreakpoint{Line: y + 1})
+	}
+	args := dap.SetBreakpointsArguments{
+		Source:      dap.Source{Name: filepath.Base(b.AbsPath), Path: b.AbsPath},
+		Breakpoints: bps,
+	}
+	s.request("setBreakpoints", args, s.checkResponse)
+}
+
+// sendAllBreakpoints sets the breakpoints of all the open files
+func (s *debugSession) sendAllBreakpoints() {
+	sent := make(map[*buffer.SharedBuffer]bool)
+	for _, b := range buffer.OpenBuffers {
+		if b.HasBreakpoints() && b.AbsPath != "" && !sent[b.SharedBuffer] {
+			sent[b.SharedBuffer] = true
+			s.sendBreakpoints(b)
+		}
+	}
+}
+
+// print adds text at the end of the console
+func (s *debugSession) print(text string) {
+	p := s.pane("console")
+	b := p.Buf
+	follow := p.Cursor.Loc == b.End()
+	b.EventHandler.Insert(b.End(), text)
+	b.UndoStack = new(buffer.TEStack)
+	b.RedoStack = new(buffer.TEStack)
+	if follow {
+		p.Cursor.GotoLoc(b.End())
+		p.Relocate()
+	}
+}
+
+// pane returns the pane of the given kind if it is open, or else opens it:
+// the console below the pane the session was started from, and the stack
+// and the variables at the right of the console. The focus stays on the
+// current pane.
+func (s *debugSession) pane(kind string) *DebugPane {
+	field := map[string]**DebugPane{"console": &s.console, "stack": &s.stack, "variables": &s.variables}[kind]
+	if p := *field; p.isOpen() {
+		return p
+	}
+	var at *BufPane
+	switch {
+	case kind == "console":
+		at = s.sourcePane()
+	case kind == "variables" && s.stack.isOpen():
+		at = s.stack.BufPane
+	default:
+		at = s.pane("console").BufPane
+	}
+
+	b := buffer.NewBufferFromString("", "", buffer.BTLog)
+	b.SetName("debug " + kind)
+	p := &DebugPane{BufPane: NewBufPaneFromBuf(b, at.tab), session: s, kind: kind}
+	tab := at.tab
+	cur := tab.Panes[tab.active]
+	if kind == "console" {
+		p.splitID = tab.GetNode(at.splitID).HSplit(true)
+	} else {
+		p.splitID = tab.GetNode(at.splitID).VSplit(true)
+	}
+	tab.Panes = append(tab.Panes, p)
+	tab.Resize()
+	for i, tp := range tab.Panes {
+		if tp == cur {
+			tab.SetActive(i)
+		}
+	}
+	*field = p
+	p.render()
+	return p
+}
+
+// isOpen returns whether the pane is still part of an open tab
+func (h *DebugPane) isOpen() bool {
+	if h == nil {
+		return false
+	}
+	for _, t := range Tabs.List {
+		if t != h.tab {
+			continue
+		}
+		for _, p := range t.Panes {
+			if p == h {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render rebuilds the text of the stack or the variables pane
+func (h *DebugPane) render() {
+	if h.kind == "console" || !h.isOpen() {
+		return
+	}
+	s := h.session
+	var sb strings.Builder
+	switch {
+	case h.kind == "stack" && len(s.frames) > 0:
+		for i, f := range s.frames {
+			mark := "  "
+			if i == s.frame {
+				mark = "> "
+			}
+			fmt.Fprintf(&sb, "%s#%d %s", mark, i, f.Name)
+			if f.Source != nil && f.Source.Path != "" {
+				fmt.Fprintf(&sb, "  %s:%d", projectPath("", f.Source.Path), f.Line)
+			}
+			sb.WriteString("\n")
+		}
+	case h.kind == "variables" && len(s.vars) > 0:
+		for _, v := range s.vars {
+			sign := "  "
+			if v.ref > 0 && v.expanded {
+				sign = "- "
+			} else if v.ref > 0 {
+				sign = "+ "
+			}
+			sb.WriteString(strings.Repeat("  ", v.depth) + sign + v.name)
+			if v.depth > 0 {
+				sb.WriteString(" = " + v.value)
+			}
+			sb.WriteString("\n")
+		}
+	case s.stopped:
+	case debugger != s:
+		sb.WriteString("(ended)\n")
+	case s.client == nil:
+		sb.WriteString("(starting)\n")
+	default:
+		sb.WriteString("(running)\n")
+	}
+
+	loc := h.Cursor.Loc
+	h.Buf.EventHandler.Replace(h.Buf.Start(), h.Buf.End(), sb.String())
+	h.Buf.UndoStack = new(buffer.TEStack)
+	h.Buf.RedoStack = new(buffer.TEStack)
+	if h.kind == "stack" && len(s.frames) > 0 {
+		loc = buffer.Loc{X: 0, Y: s.frame}
+	}
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(loc.Y, 0, h.Buf.LinesNum()-1)})
+	h.Relocate()
+}
+
+// HandleEvent shows the frame or expands the variable under the cursor on
+// Enter and passes everything else to the bufpane
+func (h *DebugPane) HandleEvent(event tcell.Event) {
+	if e, ok := event.(*tcell.EventKey); ok && e.Key() == tcell.KeyEnter && e.Modifiers() == tcell.ModNone {
+		s := h.session
+		y := h.Cursor.Y
+		switch {
+		case h.kind == "stack" && y < len(s.frames):
+			s.showFrame(y)
+			return
+		case h.kind == "variables" && y < len(s.vars) && s.vars[y].ref > 0:
+			if s.vars[y].expanded {
+				s.collapse(s.vars[y])
+			} else {
+				s.expand(s.vars[y])
+			}
+			return
+		}
+	}
+	h.BufPane.HandleEvent(event)
+}
+
+// toggleBreakpoint adds a breakpoint on the line y of the buffer or removes
+// the one there, and sets the breakpoints of the file if a program is
+// being debugged
+func (h *BufPane) toggleBreakpoint(y int) bool {
+	if h.Buf.Type != buffer.BTDefault || h.Buf.Path == "" {
+		InfoBar.Error("Only the buffers of files can have breakpoints")
+		return false
+	}
+	if h.Buf.ToggleBreakpoint(y) {
+		InfoBar.Message(fmt.Sprintf("Breakpoint on line %d", y+1))
+	} else {
+		InfoBar.Message(fmt.Sprintf("Removed the breakpoint of line %d", y+1))
+	}
+	if s := debugger; s != nil && s.client != nil {
+		s.sendBreakpoints(h.Buf)
+	}
+	return true
+}
+
+// clickGutter toggles the breakpoint of the line whose gutter or line
+// number is clicked in the buffer of a file, and returns whether one was
+func (h *BufPane) clickGutter(mx, my int) bool {
+	w, ok := h.BWindow.(*display.BufWindow)
+	if !ok || h.Buf.Type != buffer.BTDefault || h.Buf.Path == "" {
+		return false
+	}
+	y, ok := w.GutterLine(mx, my)
+	if !ok {
+		return false
+	}
+	return h.toggleBreakpoint(y)
+}
+
+// ToggleBreakpoint adds a breakpoint of the debugger on the line of the
+// cursor, or removes the one there
+func (h *BufPane) ToggleBreakpoint() bool {
+	return h.toggleBreakpoint(h.Cursor.Y)
+}
+
+// DebugContinue resumes the program being debugged, or starts debugging
+// with the first debug configuration if no program is
+func (h *BufPane) DebugContinue() bool {
+	if debugger == nil {
+		h.startDebugger("")
+		return true
+	}
+	return debugger.step("continue")
+}
+
+// DebugNext runs the program being debugged to the next line of the
+// function where it stopped
+func (h *BufPane) DebugNext() bool {
+	return debugging() && debugger.step("next")
+}
+
+// DebugStepIn runs the program being debugged into the function called on
+// the line where it stopped
+func (h *BufPane) DebugStepIn() bool {
+	return debugging() && debugger.step("stepIn")
+}
+
+// DebugStepOut runs the program being debugged until the function where it
+// stopped returns
+func (h *BufPane) DebugStepOut() bool {
+	return debugging() && debugger.step("stepOut")
+}
+
+// DebugPause stops the program being debugged where it runs
+func (h *BufPane) DebugPause() bool {
+	return debugging() && debugger.pause()
+}
+
+// DebugStop ends the debugging session, terminating the program if it was
+// launched by the debugger
+func (h *BufPane) DebugStop() bool {
+	if !debugging() {
+		return false
+	}
+	debugger.stop()
+	return true
+}
+
+// debugging returns whether a program is being debugged, and shows that
+// none is otherwise
+func debugging() bool {
+	if debugger == nil {
+		InfoBar.Message("No program is being debugged")
+		return false
+	}
+	return true
+}
+
+// DebugCmd starts and controls the debugging sessions: 'debug start [name]'
+// starts debugging with a debug configuration, and the other subcommands
+// act on the program being debugged, see DebugCmds
+func (h *BufPane) DebugCmd(args []string) {
+	if len(args) == 0 {
+		args = []string{"start"}
+	}
+	switch args[0] {
+	case "start":
+		h.startDebugger(strings.Join(args[1:], " "))
+		return
+	case "clear":
+		for _, b := range buffer.OpenBuffers {
+			if b.HasBreakpoints() {
+				b.ClearBreakpoints()
+				if s := debugger; s != nil && s.client != nil {
+					s.sendBreakpoints(b)
+				}
+			}
+		}
+		return
+	}
+	if !contains(DebugCmds, args[0]) {
+		InfoBar.Error("Usage: debug start|stop|continue|next|step|out|pause|eval|stack|variables|console|clear")
+		return
+	}
+	if !debugging() {
+		return
+	}
+	s := debugger
+	switch args[0] {
+	case "stop":
+		s.stop()
+	case "continue":
+		s.step("continue")
+	case "next":
+		s.step("next")
+	case "step":
+		s.step("stepIn")
+	case "out":
+		s.step("stepOut")
+	case "pause":
+		s.pause()
+	case "eval":
+		if len(args) < 2 {
+			InfoBar.Error("Usage: debug eval 'expression'")
+			return
+		}
+		s.evaluate(strings.Join(args[1:], " "))
+	default:
+		s.pane(args[0])
+	}
+}
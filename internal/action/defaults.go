@@ -4,6 +4,7 @@ var termdefaults = map[string]string{
 	"<Ctrl-q><Ctrl-q>": "Exit",
 	"<Ctrl-e><Ctrl-e>": "CommandMode",
 	"<Ctrl-w><Ctrl-w>": "NextSplit",
+	"<Ctrl-y><Ctrl-y>": "CopyMode",
 }
 
 // DefaultBindings returns a map containing micro's default keybindings
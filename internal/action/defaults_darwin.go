@@ -1,8 +1,8 @@
 package action
 
 var bufdefaults = map[string]string{
-	"Up":             "CursorUp",
-	"Down":           "CursorDown",
+	"Up":             "CompletePrevious|CursorUp",
+	"Down":           "CompleteNext|CursorDown",
 	"Right":          "CursorRight",
 	"Left":           "CursorLeft",
 	"ShiftUp":        "SelectUp",
@@ -18,23 +18,25 @@ var bufdefaults = map[string]string{
 	"CtrlLeft":       "StartOfTextToggle",
 	"CtrlRight":      "EndOfLine",
 	"CtrlShiftLeft":  "SelectToStartOfTextToggle",
-	"ShiftHome":      "SelectToStartOfTextToggle",
+	"ShiftHome":      "SelectToSmartHome",
 	"CtrlShiftRight": "SelectToEndOfLine",
-	"ShiftEnd":       "SelectToEndOfLine",
+	"ShiftEnd":       "SelectToSmartEnd",
 	"CtrlUp":         "CursorStart",
 	"CtrlDown":       "CursorEnd",
 	"CtrlShiftUp":    "SelectToStart",
 	"CtrlShiftDown":  "SelectToEnd",
 	"Alt-{":          "ParagraphPrevious",
 	"Alt-}":          "ParagraphNext",
-	"Enter":          "InsertNewline",
+	"Alt-(":          "SentencePrevious",
+	"Alt-)":          "SentenceNext",
+	"Enter":          "CompleteAccept|InsertNewline",
 	"CtrlH":          "Backspace",
 	"Backspace":      "Backspace",
 	"OldBackspace":   "Backspace",
 	"Alt-CtrlH":      "DeleteWordLeft",
 	"Alt-Backspace":  "DeleteWordLeft",
-	"Tab":            "Autocomplete|IndentSelection|InsertTab",
-	"Backtab":        "CycleAutocompleteBack|OutdentSelection|OutdentLine",
+	"Tab":            "CompleteAccept|SnippetNext|SnippetExpand|Autocomplete|IndentSelection|InsertTab",
+	"Backtab":        "SnippetPrevious|CycleAutocompleteBack|OutdentSelection|OutdentLine",
 	"Ctrl-o":         "OpenFile",
 	"Ctrl-s":         "Save",
 	"Ctrl-f":         "Find",
@@ -46,14 +48,19 @@ var bufdefaults = map[string]string{
 	"Ctrl-c":         "CopyLine|Copy",
 	"Ctrl-x":         "Cut",
 	"Ctrl-k":         "CutLine",
-	"Ctrl-d":         "DuplicateLine",
+	"Ctrl-d":         "DuplicateLineOrSelection",
+	"Alt-j":          "JoinLines",
+	"Alt-q":          "Reflow",
+	"Alt-=":          "EvaluateSelection",
+	"Alt-z":          "ToggleFold",
+	"Alt-Z":          "UnfoldAll|FoldAll",
 	"Ctrl-v":         "Paste",
 	"Ctrl-a":         "SelectAll",
 	"Ctrl-t":         "AddTab",
 	"Alt-,":          "PreviousTab",
 	"Alt-.":          "NextTab",
-	"Home":           "StartOfTextToggle",
-	"End":            "EndOfLine",
+	"Home":           "SmartHome",
+	"End":            "SmartEnd",
 	"CtrlHome":       "CursorStart",
 	"CtrlEnd":        "CursorEnd",
 	"PageUp":         "CursorPageUp",
@@ -68,10 +75,15 @@ var bufdefaults = map[string]string{
 	"Ctrl-b":         "ShellMode",
 	"Ctrl-q":         "Quit",
 	"Ctrl-e":         "CommandMode",
+	"Alt-P":          "CommandPalette",
+	"Alt-O":          "GotoAnything",
+	"Alt-/":          "ToggleComment",
+	"CtrlUnderscore": "ToggleComment",
 	"Ctrl-w":         "NextSplit",
 	"Ctrl-u":         "ToggleMacro",
 	"Ctrl-j":         "PlayMacro",
 	"Insert":         "ToggleOverwriteMode",
+	"CtrlSpace":      "Complete",
 
 	// Emacs-style keybindings
 	"Alt-f": "WordRight",
@@ -85,16 +97,32 @@ var bufdefaults = map[string]string{
 	"F2":  "Save",
 	"F3":  "Find",
 	"F4":  "Quit",
+	"F5":  "command:runtask run",
 	"F7":  "Find",
 	"F10": "Quit",
-	"Esc": "Escape,Deselect,ClearInfo,RemoveAllMultiCursors",
+	"Esc": "CompleteCancel|SnippetCancel|Escape,Deselect,ClearInfo,RemoveAllMultiCursors",
+
+	// Debugger
+	"F6":       "DebugContinue",
+	"ShiftF6":  "DebugStop",
+	"F8":       "DebugNext",
+	"F9":       "ToggleBreakpoint",
+	"F11":      "DebugStepIn",
+	"ShiftF11": "DebugStepOut",
 
 	// Mouse bindings
-	"MouseWheelUp":   "ScrollUp",
-	"MouseWheelDown": "ScrollDown",
-	"MouseLeft":      "MousePress",
-	"MouseMiddle":    "PastePrimary",
-	"Ctrl-MouseLeft": "MouseMultiCursor",
+	"MouseWheelUp":         "ScrollUp",
+	"MouseWheelDown":       "ScrollDown",
+	"MouseWheelLeft":       "ScrollLeft",
+	"MouseWheelRight":      "ScrollRight",
+	"Shift-MouseWheelUp":   "ScrollLeft",
+	"Shift-MouseWheelDown": "ScrollRight",
+	"MouseLeft":            "MousePress",
+	"MouseLeftDouble":      "MouseSelectWord",
+	"MouseLeftTriple":      "MouseSelectLine",
+	"MouseMiddle":          "PastePrimary",
+	"Ctrl-MouseLeft":       "MouseMultiCursor",
+	"Alt-MouseLeft":        "MouseBlockSelect",
 
 	"Alt-n":        "SpawnMultiCursor",
 	"AltShiftUp":   "SpawnMultiCursorUp",
@@ -131,6 +159,7 @@ var infodefaults = map[string]string{
 	"CtrlShiftUp":    "SelectToStart",
 	"CtrlShiftDown":  "SelectToEnd",
 	"Enter":          "ExecuteCommand",
+	"Alt-Enter":      "InsertNewline",
 	"CtrlH":          "Backspace",
 	"Backspace":      "Backspace",
 	"OldBackspace":   "Backspace",
@@ -142,7 +171,7 @@ var infodefaults = map[string]string{
 	"Ctrl-y":         "Redo",
 	"Ctrl-c":         "CopyLine|Copy",
 	"Ctrl-x":         "Cut",
-	"Ctrl-k":         "CutLine",
+	"Ctrl-k":         "KillToEnd",
 	"Ctrl-v":         "Paste",
 	"Home":           "StartOfTextToggle",
 	"End":            "EndOfLine",
@@ -152,29 +181,34 @@ var infodefaults = map[string]string{
 	"Ctrl-q":         "AbortCommand",
 	"Ctrl-e":         "EndOfLine",
 	"Ctrl-a":         "StartOfLine",
-	"Ctrl-w":         "DeleteWordLeft",
+	"Ctrl-w":         "KillWordLeft",
 	"Insert":         "ToggleOverwriteMode",
 	"Ctrl-b":         "WordLeft",
 	"Ctrl-f":         "WordRight",
 	"Ctrl-d":         "DeleteWordLeft",
 	"Ctrl-m":         "ExecuteCommand",
-	"Ctrl-n":         "HistoryDown",
-	"Ctrl-p":         "HistoryUp",
-	"Ctrl-u":         "SelectToStart",
+	"Ctrl-n":         "FindMatchNext",
+	"Ctrl-p":         "FindMatchPrevious",
+	"Ctrl-r":         "HistorySearch",
+	"Ctrl-u":         "KillToStart",
 
 	// Emacs-style keybindings
 	"Alt-f": "WordRight",
 	"Alt-b": "WordLeft",
 	"Alt-a": "StartOfText",
 	"Alt-e": "EndOfLine",
+	"Alt-d": "KillWordRight",
+	"Alt-y": "Yank",
 
 	// Integration with file managers
 	"F10": "AbortCommand",
 	"Esc": "AbortCommand",
 
 	// Mouse bindings
-	"MouseWheelUp":   "HistoryUp",
-	"MouseWheelDown": "HistoryDown",
-	"MouseLeft":      "MousePress",
-	"MouseMiddle":    "PastePrimary",
+	"MouseWheelUp":    "HistoryUp",
+	"MouseWheelDown":  "HistoryDown",
+	"MouseLeft":       "MousePress",
+	"MouseLeftDouble": "MouseSelectWord",
+	"MouseLeftTriple": "MouseSelectLine",
+	"MouseMiddle":     "PastePrimary",
 }
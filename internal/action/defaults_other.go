@@ -1,10 +1,11 @@
+//go:build !darwin
 // +build !darwin
 
 package action
 
 var bufdefaults = map[string]string{
-	"Up":             "CursorUp",
-	"Down":           "CursorDown",
+	"Up":             "CompletePrevious|CursorUp",
+	"Down":           "CompleteNext|CursorDown",
 	"Right":          "CursorRight",
 	"Left":           "CursorLeft",
 	"ShiftUp":        "SelectUp",
@@ -20,23 +21,25 @@ var bufdefaults = map[string]string{
 	"AltLeft":        "StartOfTextToggle",
 	"AltRight":       "EndOfLine",
 	"AltShiftLeft":   "SelectToStartOfTextToggle",
-	"ShiftHome":      "SelectToStartOfTextToggle",
+	"ShiftHome":      "SelectToSmartHome",
 	"AltShiftRight":  "SelectToEndOfLine",
-	"ShiftEnd":       "SelectToEndOfLine",
+	"ShiftEnd":       "SelectToSmartEnd",
 	"CtrlUp":         "CursorStart",
 	"CtrlDown":       "CursorEnd",
 	"CtrlShiftUp":    "SelectToStart",
 	"CtrlShiftDown":  "SelectToEnd",
 	"Alt-{":          "ParagraphPrevious",
 	"Alt-}":          "ParagraphNext",
-	"Enter":          "InsertNewline",
+	"Alt-(":          "SentencePrevious",
+	"Alt-)":          "SentenceNext",
+	"Enter":          "CompleteAccept|InsertNewline",
 	"CtrlH":          "Backspace",
 	"Backspace":      "Backspace",
 	"OldBackspace":   "Backspace",
 	"Alt-CtrlH":      "DeleteWordLeft",
 	"Alt-Backspace":  "DeleteWordLeft",
-	"Tab":            "Autocomplete|IndentSelection|InsertTab",
-	"Backtab":        "CycleAutocompleteBack|OutdentSelection|OutdentLine",
+	"Tab":            "CompleteAccept|SnippetNext|SnippetExpand|Autocomplete|IndentSelection|InsertTab",
+	"Backtab":        "SnippetPrevious|CycleAutocompleteBack|OutdentSelection|OutdentLine",
 	"Ctrl-o":         "OpenFile",
 	"Ctrl-s":         "Save",
 	"Ctrl-f":         "Find",
@@ -48,14 +51,19 @@ var bufdefaults = map[string]string{
 	"Ctrl-c":         "CopyLine|Copy",
 	"Ctrl-x":         "Cut",
 	"Ctrl-k":         "CutLine",
-	"Ctrl-d":         "DuplicateLine",
+	"Ctrl-d":         "DuplicateLineOrSelection",
+	"Alt-j":          "JoinLines",
+	"Alt-q":          "Reflow",
+	"Alt-=":          "EvaluateSelection",
+	"Alt-z":          "ToggleFold",
+	"Alt-Z":          "UnfoldAll|FoldAll",
 	"Ctrl-v":         "Paste",
 	"Ctrl-a":         "SelectAll",
 	"Ctrl-t":         "AddTab",
 	"Alt-,":          "PreviousTab",
 	"Alt-.":          "NextTab",
-	"Home":           "StartOfTextToggle",
-	"End":            "EndOfLine",
+	"Home":           "SmartHome",
+	"End":            "SmartEnd",
 	"CtrlHome":       "CursorStart",
 	"CtrlEnd":        "CursorEnd",
 	"PageUp":         "CursorPageUp",
@@ -70,10 +78,15 @@ var bufdefaults = map[string]string{
 	"Ctrl-b":         "ShellMode",
 	"Ctrl-q":         "Quit",
 	"Ctrl-e":         "CommandMode",
+	"Alt-P":          "CommandPalette",
+	"Alt-O":          "GotoAnything",
+	"Alt-/":          "ToggleComment",
+	"CtrlUnderscore": "ToggleComment",
 	"Ctrl-w":         "NextSplit",
 	"Ctrl-u":         "ToggleMacro",
 	"Ctrl-j":         "PlayMacro",
 	"Insert":         "ToggleOverwriteMode",
+	"CtrlSpace":      "Complete",
 
 	// Emacs-style keybindings
 	"Alt-f": "WordRight",
@@ -87,16 +100,32 @@ var bufdefaults = map[string]string{
 	"F2":  "Save",
 	"F3":  "Find",
 	"F4":  "Quit",
+	"F5":  "command:runtask run",
 	"F7":  "Find",
 	"F10": "Quit",
-	"Esc": "Escape,Deselect,ClearInfo,RemoveAllMultiCursors",
+	"Esc": "CompleteCancel|SnippetCancel|Escape,Deselect,ClearInfo,RemoveAllMultiCursors",
+
+	// Debugger
+	"F6":       "DebugContinue",
+	"ShiftF6":  "DebugStop",
+	"F8":       "DebugNext",
+	"F9":       "ToggleBreakpoint",
+	"F11":      "DebugStepIn",
+	"ShiftF11": "DebugStepOut",
 
 	// Mouse bindings
-	"MouseWheelUp":   "ScrollUp",
-	"MouseWheelDown": "ScrollDown",
-	"MouseLeft":      "MousePress",
-	"MouseMiddle":    "PastePrimary",
-	"Ctrl-MouseLeft": "MouseMultiCursor",
+	"MouseWheelUp":         "ScrollUp",
+	"MouseWheelDown":       "ScrollDown",
+	"MouseWheelLeft":       "ScrollLeft",
+	"MouseWheelRight":      "ScrollRight",
+	"Shift-MouseWheelUp":   "ScrollLeft",
+	"Shift-MouseWheelDown": "ScrollRight",
+	"MouseLeft":            "MousePress",
+	"MouseLeftDouble":      "MouseSelectWord",
+	"MouseLeftTriple":      "MouseSelectLine",
+	"MouseMiddle":          "PastePrimary",
+	"Ctrl-MouseLeft":       "MouseMultiCursor",
+	"Alt-MouseLeft":        "MouseBlockSelect",
 
 	"Alt-n":        "SpawnMultiCursor",
 	"Alt-m":        "SpawnMultiCursorSelect",
@@ -133,6 +162,7 @@ var infodefaults = map[string]string{
 	"CtrlShiftUp":    "SelectToStart",
 	"CtrlShiftDown":  "SelectToEnd",
 	"Enter":          "ExecuteCommand",
+	"Alt-Enter":      "InsertNewline",
 	"CtrlH":          "Backspace",
 	"Backspace":      "Backspace",
 	"OldBackspace":   "Backspace",
@@ -144,7 +174,7 @@ var infodefaults = map[string]string{
 	"Ctrl-y":         "Redo",
 	"Ctrl-c":         "CopyLine|Copy",
 	"Ctrl-x":         "Cut",
-	"Ctrl-k":         "CutLine",
+	"Ctrl-k":         "KillToEnd",
 	"Ctrl-v":         "Paste",
 	"Home":           "StartOfTextToggle",
 	"End":            "EndOfLine",
@@ -154,29 +184,34 @@ var infodefaults = map[string]string{
 	"Ctrl-q":         "AbortCommand",
 	"Ctrl-e":         "EndOfLine",
 	"Ctrl-a":         "StartOfLine",
-	"Ctrl-w":         "DeleteWordLeft",
+	"Ctrl-w":         "KillWordLeft",
 	"Insert":         "ToggleOverwriteMode",
 	"Ctrl-b":         "WordLeft",
 	"Ctrl-f":         "WordRight",
 	"Ctrl-d":         "DeleteWordLeft",
 	"Ctrl-m":         "ExecuteCommand",
-	"Ctrl-n":         "HistoryDown",
-	"Ctrl-p":         "HistoryUp",
-	"Ctrl-u":         "SelectToStart",
+	"Ctrl-n":         "FindMatchNext",
+	"Ctrl-p":         "FindMatchPrevious",
+	"Ctrl-r":         "HistorySearch",
+	"Ctrl-u":         "KillToStart",
 
 	// Emacs-style keybindings
 	"Alt-f": "WordRight",
 	"Alt-b": "WordLeft",
 	"Alt-a": "StartOfText",
 	"Alt-e": "EndOfLine",
+	"Alt-d": "KillWordRight",
+	"Alt-y": "Yank",
 
 	// Integration with file managers
 	"F10": "AbortCommand",
 	"Esc": "AbortCommand",
 
 	// Mouse bindings
-	"MouseWheelUp":   "HistoryUp",
-	"MouseWheelDown": "HistoryDown",
-	"MouseLeft":      "MousePress",
-	"MouseMiddle":    "PastePrimary",
+	"MouseWheelUp":    "HistoryUp",
+	"MouseWheelDown":  "HistoryDown",
+	"MouseLeft":       "MousePress",
+	"MouseLeftDouble": "MouseSelectWord",
+	"MouseLeftTriple": "MouseSelectLine",
+	"MouseMiddle":     "PastePrimary",
 }
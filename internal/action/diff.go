@@ -0,0 +1,194 @@
+package action
+
+import (
+	"os"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/git"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// DiffCmd compares the current buffer side by side with the given file, opened
+// in a split to its right, or with the buffer of the other pane of the tab
+func (h *BufPane) DiffCmd(args []string) {
+	if len(args) > 1 {
+		InfoBar.Error("Usage: diff [filename]")
+		return
+	}
+	if len(args) == 1 {
+		b, err := buffer.NewBufferFromFile(args[0], buffer.BTDefault)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		h.DiffSplit(b)
+		return
+	}
+
+	var other *BufPane
+	n := 0
+	for _, p := range h.tab.Panes {
+		if bp, ok := p.(*BufPane); ok && bp != h {
+			other = bp
+			n++
+		}
+	}
+	if n != 1 {
+		InfoBar.Error("Give a file to compare with, or compare the two panes of a tab")
+		return
+	}
+	h.DiffPane(other)
+}
+
+// DiffSavedCmd shows the changes of the buffer which are not saved yet: the
+// version of the file on disk is compared side by side with the buffer in a
+// split to its right, or with "unified" a unified diff opens in a split
+func (h *BufPane) DiffSavedCmd(args []string) {
+	unified := len(args) == 1 && args[0] == "unified"
+	if len(args) > 1 || len(args) == 1 && !unified {
+		InfoBar.Error("Usage: diffsaved [unified]")
+		return
+	}
+	if h.Buf.Path == "" {
+		InfoBar.Error("The buffer has no file to compare with")
+		return
+	}
+	// a file which was never saved compares as empty
+	saved, err := h.Buf.DiskBytes()
+	if err != nil && !os.IsNotExist(err) {
+		InfoBar.Error(err)
+		return
+	}
+
+	text := h.Buf.Bytes()
+	name := h.Buf.GetName()
+	if string(saved) == string(text) {
+		InfoBar.Message("The buffer is the same as the file on disk")
+		return
+	}
+	if unified {
+		patch := git.Unified(saved, text, "a/"+h.Buf.Path, "b/"+h.Buf.Path, 3)
+		b := buffer.NewBufferFromString(strings.TrimSuffix(string(patch), "\n"), "", buffer.BTScratch)
+		b.Type.Readonly = true
+		b.SetOptionNative("filetype", "patch")
+		b.SetName("diffsaved: " + name)
+		h.VSplitIndex(b, true)
+	} else {
+		b := buffer.NewBufferFromString(string(saved), "", buffer.BTScratch)
+		b.Type.Readonly = true
+		b.SetOptionNative("filetype", h.Buf.Settings["filetype"])
+		b.SetName(name + " (saved)")
+		h.DiffSplit(b)
+	}
+	if h.Buf.ExternallyModified() {
+		InfoBar.Message("The file was modified on disk since it was opened or saved")
+	}
+}
+
+// NoDiffCmd stops comparing the current buffer with another one
+func (h *BufPane) NoDiffCmd(args []string) {
+	if h.Buf.DiffPeer() == nil {
+		InfoBar.Error("The buffer is not compared with another one")
+		return
+	}
+	h.Buf.EndDiff()
+}
+
+// DiffSplit opens the buffer b in a split to the right of the pane and
+// compares the two side by side
+func (h *BufPane) DiffSplit(b *buffer.Buffer) *BufPane {
+	bp := h.VSplitIndex(b, true)
+	h.DiffPane(bp)
+	return bp
+}
+
+// DiffPane compares the buffer of the pane with the buffer of another pane:
+// the changes between them are marked in the gutter and within the lines,
+// and the panes scroll together
+func (h *BufPane) DiffPane(other *BufPane) {
+	if h.Buf.SharedBuffer == other.Buf.SharedBuffer {
+		InfoBar.Error("Cannot compare a buffer with itself")
+		return
+	}
+	h.Buf.DiffWith(other.Buf)
+	h.diffStart = display.SLoc{Line: -1}
+	h.syncDiffScroll()
+}
+
+// diffPane returns the pane of the tab showing the buffer this pane's
+// buffer is compared with, or nil
+func (h *BufPane) diffPane() *BufPane {
+	peer := h.Buf.DiffPeer()
+	if peer == nil || h.tab == nil {
+		return nil
+	}
+	for _, p := range h.tab.Panes {
+		if bp, ok := p.(*BufPane); ok && bp.Buf == peer {
+			return bp
+		}
+	}
+	return nil
+}
+
+// syncDiffScroll scrolls the pane compared with this one, if this one
+// scrolled, so that it starts at the corresponding line
+func (h *BufPane) syncDiffScroll() {
+	peer := h.diffPane()
+	if peer == nil {
+		return
+	}
+	v := h.GetView()
+	if v.StartLine == h.diffStart {
+		return
+	}
+	h.diffStart = v.StartLine
+
+	pv := peer.GetView()
+	y := git.MapLine(peer.Buf.Bytes(), h.Buf.Bytes(), v.StartLine.Line)
+	pv.StartLine = display.SLoc{Line: util.Clamp(y, 0, peer.Buf.LinesNum()-1)}
+	pv.StartCol = v.StartCol
+	peer.SetView(pv)
+	peer.diffStart = pv.StartLine
+}
+
+// DiffPush copies the change under the cursor to the buffer compared with
+// the current one, replacing the lines it differs by. This can be undone in
+// the other buffer.
+func (h *BufPane) DiffPush() bool {
+	peer := h.Buf.DiffPeer()
+	if peer == nil {
+		InfoBar.Error("The buffer is not compared with another one")
+		return false
+	}
+	hunk, ok := git.FindHunk(peer.Bytes(), h.Buf.Bytes(), h.Cursor.Y)
+	if !ok {
+		InfoBar.Message("No change on this line")
+		return false
+	}
+	replaceLines(peer, hunk.BaseStart, len(hunk.Base), hunk.Lines)
+	InfoBar.Message("Pushed hunk")
+	return true
+}
+
+// DiffPull replaces the change under the cursor by the corresponding lines
+// of the buffer compared with the current one. This can be undone.
+func (h *BufPane) DiffPull() bool {
+	peer := h.Buf.DiffPeer()
+	if peer == nil {
+		InfoBar.Error("The buffer is not compared with another one")
+		return false
+	}
+	hunk, ok := git.FindHunk(peer.Bytes(), h.Buf.Bytes(), h.Cursor.Y)
+	if !ok {
+		InfoBar.Message("No change on this line")
+		return false
+	}
+	h.Cursor.Deselect(true)
+	replaceLines(h.Buf, hunk.Start, len(hunk.Lines), hunk.Base)
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(hunk.Start, 0, h.Buf.LinesNum()-1)})
+	h.Relocate()
+	InfoBar.Message("Pulled hunk")
+	return true
+}
@@ -0,0 +1,57 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+)
+
+// DraftsCmd opens a fuzzy picker listing the drafts, which opens the chosen
+// one, or goes to the pane showing it if it is open
+func (h *BufPane) DraftsCmd(args []string) {
+	drafts, err := buffer.Drafts()
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(drafts) == 0 {
+		InfoBar.Message("No drafts")
+		return
+	}
+	items := make([]display.PickerItem, 0, len(drafts))
+	for _, d := range drafts {
+		items = append(items, display.PickerItem{
+			Text:   d.Name + "  " + d.Preview,
+			Detail: d.ModTime.Format("Jan _2 15:04"),
+			Data:   d.Name,
+		})
+	}
+	InfoBar.Pick("Draft: ", "Drafts", items, func(it *display.PickerItem) {
+		if it == nil {
+			return
+		}
+		h.openDraft(it.Data.(string))
+	})
+}
+
+// openDraft opens the draft in this pane, or in a new vertical split if the
+// current buffer has unsaved changes
+func (h *BufPane) openDraft(name string) {
+	for _, b := range buffer.OpenBuffers {
+		if b.Draft() == name {
+			if panes := panesOf(b); len(panes) > 0 {
+				focusPane(panes[0])
+				return
+			}
+		}
+	}
+	b, err := buffer.OpenDraft(name)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if h.Buf.Unsaved() {
+		h.VSplitBuf(b)
+	} else {
+		h.OpenBuffer(b)
+	}
+}
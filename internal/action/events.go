@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/tcell/v2"
 )
 
@@ -83,6 +84,44 @@ func (k KeyEvent) Name() string {
 	return s
 }
 
+// isBound returns whether a key of the terminal is bound in any pane
+func isBound(e Event) bool {
+	for _, t := range []*KeyTree{BufBindings, InfoBindings, TermBindings} {
+		if t != nil && t.HasEvent(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyboardEvent returns the event to handle for an event of the terminal:
+// the key of a raw event sent with the kitty keyboard protocol, or nil for
+// the answer of the terminal to the query of the protocol. A key whose
+// event isn't bound is handled as it is sent without the protocol, so that
+// Ctrl-/ still toggles comments when only CtrlUnderscore is bound.
+func KeyboardEvent(event tcell.Event) tcell.Event {
+	e, ok := event.(*tcell.EventRaw)
+	if !ok {
+		return event
+	}
+	seq := e.EscSeq()
+	if screen.IsKeyboardReply(seq) {
+		screen.EnableKittyKeys()
+		return nil
+	}
+	if !screen.KittyKeys || isBound(RawEvent{seq}) {
+		return event
+	}
+	key, legacy, ok := screen.ParseKittyKey(seq)
+	if !ok {
+		return event
+	}
+	if legacy != nil && !isBound(KeyEvent{code: key.Key(), mod: metaToAlt(key.Modifiers()), r: key.Rune()}) {
+		return legacy
+	}
+	return key
+}
+
 // A KeySequence defines a list of consecutive
 // events. All events in the sequence must be KeyEvents
 // or MouseEvents.
@@ -105,6 +144,15 @@ func (k KeySequenceEvent) Name() string {
 type MouseEvent struct {
 	btn tcell.ButtonMask
 	mod tcell.ModMask
+	// clicks is 2 or 3 for a double or triple click, and 0 for a single one
+	clicks int
+}
+
+// clickSuffixes are the suffixes of the names of the double and triple
+// clicks, such as MouseLeftDouble
+var clickSuffixes = map[int]string{
+	2: "Double",
+	3: "Triple",
 }
 
 func (m MouseEvent) Name() string {
@@ -124,7 +172,7 @@ func (m MouseEvent) Name() string {
 
 	for k, v := range mouseEvents {
 		if v == m.btn {
-			return fmt.Sprintf("%s%s", mod, k)
+			return fmt.Sprintf("%s%s%s", mod, k, clickSuffixes[m.clicks])
 		}
 	}
 	return ""
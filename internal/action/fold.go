@@ -0,0 +1,65 @@
+package action
+
+// Fold folds the innermost region of the foldmethod option containing the
+// cursor, or the region around it if it is folded already
+func (h *BufPane) Fold() bool {
+	if !h.Buf.Fold(h.Cursor.Y) {
+		InfoBar.Message("There is no region to fold here")
+		return false
+	}
+	h.moveToFoldStart()
+	h.Relocate()
+	return true
+}
+
+// moveToFoldStart moves the cursors hidden by a fold to the start of the
+// text of the line it is shown with, so that they don't unfold it again
+func (h *BufPane) moveToFoldStart() {
+	for _, c := range h.Buf.GetCursors() {
+		if y := h.Buf.VisibleLine(c.Y); y != c.Y {
+			c.Deselect(true)
+			c.Y = y
+			c.StartOfText()
+		}
+	}
+}
+
+// Unfold unfolds the folds of the line of the cursor
+func (h *BufPane) Unfold() bool {
+	if !h.Buf.Unfold(h.Cursor.Y) {
+		InfoBar.Message("There is no fold here")
+		return false
+	}
+	h.Relocate()
+	return true
+}
+
+// ToggleFold unfolds the line of the cursor if it is folded, and otherwise
+// folds the region containing it
+func (h *BufPane) ToggleFold() bool {
+	if _, ok := h.Buf.IsFolded(h.Cursor.Y); ok {
+		return h.Unfold()
+	}
+	return h.Fold()
+}
+
+// FoldAll folds all the regions of the buffer
+func (h *BufPane) FoldAll() bool {
+	h.Buf.FoldAll()
+	if !h.Buf.HasFolds() {
+		InfoBar.Message("There is no region to fold")
+		return false
+	}
+	h.moveToFoldStart()
+	h.Relocate()
+	return true
+}
+
+// UnfoldAll unfolds all the folds of the buffer
+func (h *BufPane) UnfoldAll() bool {
+	if !h.Buf.UnfoldAll() {
+		return false
+	}
+	h.Relocate()
+	return true
+}
@@ -0,0 +1,67 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+)
+
+// ToggleFollow toggles the follow option of the current buffer, which reads
+// the data appended to its file as `tail -f` does
+func (h *BufPane) ToggleFollow() bool {
+	if h.Buf.Type.Kind != buffer.BTDefault.Kind || h.Buf.Path == "" {
+		InfoBar.Error("Only a file can be followed")
+		return false
+	}
+	following := !h.Buf.Settings["follow"].(bool)
+	h.Buf.SetOptionNative("follow", following)
+	if following {
+		InfoBar.Message("Following the file")
+	} else {
+		InfoBar.Message("Stopped following the file")
+	}
+	return true
+}
+
+// FollowFile reads the data appended to the file of b, a buffer with the
+// follow option. The panes whose view showed the end of the buffer scroll
+// to its new end, and their cursor stays on the last line if it was there,
+// while the panes scrolled up are left alone.
+func FollowFile(b *buffer.Buffer) {
+	type follower struct {
+		h      *BufPane
+		cursor bool
+	}
+	var followers []follower
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			h, ok := p.(*BufPane)
+			if !ok || h.Buf.SharedBuffer != b.SharedBuffer {
+				continue
+			}
+			v := h.GetView()
+			if h.Diff(v.StartLine, h.SLocFromLoc(h.Buf.End())) < h.BufView().Height {
+				last := h.Cursor.Y == h.Buf.LinesNum()-1 && !h.Cursor.HasSelection()
+				followers = append(followers, follower{h, last})
+			}
+		}
+	}
+
+	changed, err := b.ReadAppended()
+	if err != nil {
+		InfoBar.Error(err)
+	}
+	if !changed {
+		return
+	}
+	for _, f := range followers {
+		h := f.h
+		if f.cursor {
+			h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: h.Buf.LinesNum() - 1})
+		}
+		v := h.GetView()
+		end := h.SLocFromLoc(h.Buf.End())
+		if height := h.BufView().Height; h.Diff(v.StartLine, end) >= height {
+			v.StartLine = h.Scroll(end, -height+1)
+			h.SetView(v)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/git"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// gitPath returns the path of the buffer's file for git commands, or reports
+// an error if the buffer has no file
+func (h *BufPane) gitPath() (string, bool) {
+	if h.Buf.Path == "" || h.Buf.Type.Scratch {
+		InfoBar.Error("The buffer is not a file")
+		return "", false
+	}
+	return h.Buf.AbsPath, true
+}
+
+// StageHunk adds the change under the cursor to the git index. The change
+// is taken from the buffer, which doesn't need to be saved.
+func (h *BufPane) StageHunk() bool {
+	path, ok := h.gitPath()
+	if !ok {
+		return false
+	}
+	index, err := git.Show(path, "")
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	hunk, ok := git.FindHunk(index, h.Buf.Bytes(), h.Cursor.Y)
+	if !ok {
+		InfoBar.Message("No unstaged change on this line")
+		return false
+	}
+	if err := git.Apply(path, hunk, false); err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	InfoBar.Message("Staged hunk")
+	return true
+}
+
+// UnstageHunk removes the staged change under the cursor from the git index
+func (h *BufPane) UnstageHunk() bool {
+	path, ok := h.gitPath()
+	if !ok {
+		return false
+	}
+	head, err := git.Show(path, "HEAD")
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	index, err := git.Show(path, "")
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	y := git.MapLine(index, h.Buf.Bytes(), h.Cursor.Y)
+	hunk, ok := git.FindHunk(head, index, y)
+	if !ok {
+		InfoBar.Message("No staged change on this line")
+		return false
+	}
+	if err := git.Apply(path, hunk, true); err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	InfoBar.Message("Unstaged hunk")
+	return true
+}
+
+// RevertHunk discards the unstaged change under the cursor, replacing it in
+// the buffer by the text of the git index. This can be undone.
+func (h *BufPane) RevertHunk() bool {
+	path, ok := h.gitPath()
+	if !ok {
+		return false
+	}
+	index, err := git.Show(path, "")
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	hunk, ok := git.FindHunk(index, h.Buf.Bytes(), h.Cursor.Y)
+	if !ok {
+		InfoBar.Message("No unstaged change on this line")
+		return false
+	}
+
+	h.Cursor.Deselect(true)
+	replaceLines(h.Buf, hunk.Start, len(hunk.Lines), hunk.Base)
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(hunk.Start, 0, h.Buf.LinesNum()-1)})
+	h.Relocate()
+	InfoBar.Message("Reverted hunk")
+	return true
+}
+
+// replaceLines replaces n lines of the buffer, from the line start, by the
+// given lines, which include their line ending
+func replaceLines(b *buffer.Buffer, start, n int, lines []string) {
+	from := buffer.Loc{X: 0, Y: util.Clamp(start, 0, b.LinesNum()-1)}
+	to := buffer.Loc{X: 0, Y: start + n}
+	if to.Y >= b.LinesNum() {
+		to = b.End()
+	}
+	text := strings.Replace(strings.Join(lines, ""), "\r\n", "\n", -1)
+	b.Replace(from, to, text)
+}
+
+// maxBlameAuthor is the width above which author names are truncated in
+// the blame view
+const maxBlameAuthor = 20
+
+// BlameCmd opens a view to the left of the buffer showing the commit,
+// date and author which last changed each line
+func (h *BufPane) BlameCmd(args []string) {
+	path, ok := h.gitPath()
+	if !ok {
+		return
+	}
+	lines, err := git.Blame(path, h.Buf.Bytes())
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+
+	width := 0
+	for _, l := range lines {
+		width = util.Max(width, util.Min(runewidth.StringWidth(l.Author), maxBlameAuthor))
+	}
+
+	var sb strings.Builder
+	for i, l := range lines {
+		commit, date, author := l.Commit, "", ""
+		if strings.Trim(l.Commit, "0") == "" {
+			commit = "        "
+			author = "Not committed"
+		} else {
+			date = l.Time.Format("2006-01-02")
+			author = l.Author
+		}
+		author = runewidth.FillRight(runewidth.Truncate(author, maxBlameAuthor, ""), width)
+		text := ""
+		if i < h.Buf.LinesNum() {
+			text = string(h.Buf.LineBytes(i))
+		}
+		fmt.Fprintf(&sb, "%s %10s %s | %s\n", commit, date, author, text)
+	}
+
+	b := buffer.NewBufferFromString(strings.TrimSuffix(sb.String(), "\n"), "", buffer.BTScratch)
+	b.Type.Readonly = true
+	b.SetName("blame: " + h.Buf.GetName())
+	y := h.Cursor.Y
+	bp := h.VSplitIndex(b, false)
+	bp.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(y, 0, b.LinesNum()-1)})
+	bp.Center()
+}
@@ -1,6 +1,9 @@
 package action
 
-import "github.com/zyedidia/micro/v2/internal/buffer"
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+)
 
 var InfoBar *InfoPane
 var LogBufPane *BufPane
@@ -9,6 +12,42 @@ var LogBufPane *BufPane
 func InitGlobals() {
 	InfoBar = NewInfoBar()
 	buffer.LogBuf = buffer.NewBufferFromString("", "Log", buffer.BTLog)
+
+	display.SetStatusInfoFn("mode", func(b *buffer.Buffer) string {
+		if Tabs == nil {
+			return ""
+		}
+		h := MainTab().CurPane()
+		if h == nil || h.Buf != b {
+			return ""
+		}
+		if name := h.modeName(); name != "" {
+			return name + " "
+		}
+		return ""
+	})
+	display.SetStatusInfoFn("keys", func(b *buffer.Buffer) string {
+		if Tabs == nil {
+			return ""
+		}
+		h := MainTab().CurPane()
+		if h == nil || h.Buf != b {
+			return ""
+		}
+		if keys := h.PendingKeys(); keys != "" {
+			return keys + " "
+		}
+		return ""
+	})
+	display.SetStatusInfoFn("macro", func(b *buffer.Buffer) string {
+		if !recording_macro {
+			return ""
+		}
+		if macroName == defaultMacro {
+			return "recording "
+		}
+		return "recording " + macroName + " "
+	})
 }
 
 // GetInfoBar returns the infobar pane
@@ -0,0 +1,159 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/tags"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// gotoLineRegex matches a "file:line" or "file:line:col" query
+var gotoLineRegex = regexp.MustCompile(`^(.*?):(\d+)(?::(\d+))?$`)
+
+// GotoAnything opens the goto prompt
+func (h *BufPane) GotoAnything() bool {
+	h.GotoAnyCmd(nil)
+	return true
+}
+
+// GotoAnyCmd opens a prompt which jumps to what is typed: a symbol of the
+// buffer after '@', a line (and column) of the buffer after ':', or else an
+// open buffer or a file of the project, chosen with a fuzzy picker, which
+// may be followed by ":line". The arguments are the initial text.
+func (h *BufPane) GotoAnyCmd(args []string) {
+	var files, symbols *display.Picker
+	picker := func(resp string) (*display.Picker, string) {
+		switch {
+		case strings.HasPrefix(resp, "@"):
+			if symbols == nil {
+				symbols = display.NewPicker(h.symbolItems())
+			}
+			return symbols, resp[1:]
+		case strings.HasPrefix(resp, ":"):
+			return nil, ""
+		}
+		if files == nil {
+			files = display.NewPicker(h.fileItems())
+		}
+		if m := gotoLineRegex.FindStringSubmatch(resp); m != nil {
+			resp = m[1]
+		}
+		return files, resp
+	}
+
+	input := strings.Join(args, " ")
+	InfoBar.Prompt("Goto: ", input, "Goto", func(resp string) {
+		p, query := picker(resp)
+		if p != nil {
+			p.Filter(query)
+		}
+		InfoBar.setPicker(p)
+	}, func(resp string, canceled bool) {
+		p, _ := picker(resp)
+		InfoBar.setPicker(nil)
+		if canceled {
+			return
+		}
+
+		if strings.HasPrefix(resp, ":") {
+			h.GotoCmd([]string{resp[1:]})
+			return
+		}
+		it := p.Current()
+		if it == nil {
+			InfoBar.Error("No match for ", resp)
+			return
+		}
+		if loc, ok := it.Data.(buffer.Loc); ok {
+			h.pushJump(h.Cursor.Loc)
+			h.gotoLoc(loc)
+			return
+		}
+
+		path := it.Data.(string)
+		if m := gotoLineRegex.FindStringSubmatch(resp); m != nil {
+			line, _ := strconv.Atoi(m[2])
+			col, _ := strconv.Atoi(m[3])
+			h.openAt(path, buffer.Loc{X: util.Max(col-1, 0), Y: line - 1})
+		} else if abs, _ := filepath.Abs(path); abs != h.Buf.AbsPath {
+			h.OpenCmd([]string{shellquote.Join(path)})
+		}
+	})
+	if p, query := picker(input); p != nil {
+		p.Filter(query)
+		InfoBar.setPicker(p)
+	}
+}
+
+// fileItems returns the items of the goto prompt for the open buffers,
+// followed by the files of the project which aren't open
+func (h *BufPane) fileItems() []display.PickerItem {
+	var items []display.PickerItem
+	open := make(map[string]bool)
+	for _, b := range buffer.OpenBuffers {
+		if b.Path == "" || b.Type != buffer.BTDefault || open[b.AbsPath] {
+			continue
+		}
+		open[b.AbsPath] = true
+		path := projectPath("", b.AbsPath)
+		items = append(items, display.PickerItem{Text: filepath.ToSlash(path), Detail: "buffer", Data: path, Path: true})
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return items
+	}
+	root := util.ProjectRoot(wd)
+	files, _ := util.ProjectFiles(root, maxProjectFiles)
+	ranks := recentRanks()
+	for _, f := range files {
+		path := projectPath(root, f)
+		abs, _ := filepath.Abs(path)
+		if open[abs] {
+			continue
+		}
+		items = append(items, display.PickerItem{Text: f, Data: path, Recent: ranks[abs], Path: true})
+	}
+	return items
+}
+
+// symbolItems returns the items of the goto prompt for the definitions of
+// the buffer, from the tags file if it has tags for the file or else found
+// by their keyword
+func (h *BufPane) symbolItems() []display.PickerItem {
+	var items []display.PickerItem
+	item := func(name, kind string, loc buffer.Loc) display.PickerItem {
+		detail := "line " + strconv.Itoa(loc.Y+1)
+		if kind != "" {
+			detail = kind + ", " + detail
+		}
+		return display.PickerItem{Text: name, Detail: detail, Data: loc}
+	}
+
+	if path, ok := h.tagsFile(); ok && h.Buf.AbsPath != "" {
+		ts, _ := tags.InFile(path, h.Buf.AbsPath)
+		lines, _ := tagLines(h.Buf.AbsPath)
+		for _, t := range ts {
+			if loc, _, ok := tagLocIn(t, lines); ok {
+				items = append(items, item(t.Name, t.Kind, loc))
+			}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Data.(buffer.Loc).LessThan(items[j].Data.(buffer.Loc))
+		})
+	}
+	if len(items) == 0 {
+		for _, s := range h.Buf.Symbols() {
+			items = append(items, item(s.Name, "", s.Loc))
+		}
+	}
+	return items
+}
@@ -0,0 +1,72 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// millis formats a duration in milliseconds
+func millis(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// HealthReport returns the timings of the startup, with the time taken by
+// each plugin and syntax file, and the latency of the input events if it is
+// measured
+func HealthReport() string {
+	var sb strings.Builder
+	timings := util.Timings()
+	width := 0
+	for _, t := range timings {
+		width = util.Max(width, len(t.Name))
+	}
+	sb.WriteString("Startup\n\n")
+	for _, t := range timings {
+		fmt.Fprintf(&sb, "    %-*s %9s", width, t.Name, millis(t.Duration))
+		if t.Count > 1 {
+			fmt.Fprintf(&sb, " (%d calls)", t.Count)
+		}
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString("\nInput latency\n\n")
+	l := util.Latency
+	switch {
+	case util.MeasureLatency && l.Count > 0:
+		fmt.Fprintf(&sb, "    %d events: %s on average, %s at most, %s for the last one\n", l.Count, millis(l.Mean()), millis(l.Max), millis(l.Last))
+	case util.MeasureLatency:
+		sb.WriteString("    No events measured yet\n")
+	default:
+		sb.WriteString("    Not measured, 'health latency' measures it\n")
+	}
+	return sb.String()
+}
+
+// HealthCmd shows the health report in a split, or with the latency
+// argument toggles measuring the time from each input event to the screen
+// drawn after it
+func (h *BufPane) HealthCmd(args []string) {
+	if len(args) > 0 {
+		if args[0] != "latency" {
+			InfoBar.Error("Usage: health [latency]")
+			return
+		}
+		util.MeasureLatency = !util.MeasureLatency
+		if util.MeasureLatency {
+			util.Latency = util.LatencyStats{}
+			InfoBar.Message("Measuring the input latency, 'health' shows it")
+		} else {
+			InfoBar.Message("Stopped measuring the input latency")
+		}
+		return
+	}
+
+	b := buffer.NewBufferFromString(strings.TrimSuffix(HealthReport(), "\n"), "", buffer.BTScratch)
+	b.Type.Readonly = true
+	b.SetName("Health")
+	h.HSplitBuf(b)
+}
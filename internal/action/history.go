@@ -0,0 +1,155 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/git"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A HistoryPane lists the versions of the file of a pane kept in the local
+// history below it, the most recent first, with the lines changed from
+// each version to the current text. Enter restores the version under the
+// cursor in the buffer, which can be undone, and 'd' compares it side by
+// side with the buffer.
+type HistoryPane struct {
+	*listPane
+
+	// src is the pane showing the file
+	src *BufPane
+	// versions are the listed versions, one per line after the first
+	versions []buffer.HistoryVersion
+}
+
+// HistoryCmd opens a pane below the current one listing the versions of its
+// file kept in the local history
+func (h *BufPane) HistoryCmd(args []string) {
+	if h.Buf.Path == "" || h.Buf.Type != buffer.BTDefault {
+		InfoBar.Error("The buffer has no file")
+		return
+	}
+	// the text as it is now can be restored after another version is
+	if err := h.Buf.SnapshotHistory(); err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	versions, err := buffer.History(h.Buf.AbsPath)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(versions) == 0 {
+		InfoBar.Message("No history for ", h.Buf.GetName(), " (see the localhistory option)")
+		return
+	}
+
+	hp := &HistoryPane{listPane: newListPane(h), src: h, versions: versions}
+	hp.openBelow(h, hp)
+	hp.rerender()
+}
+
+// HandleEvent restores the version under the cursor with Enter or compares
+// it with 'd', and passes the other events to the bufpane
+func (h *HistoryPane) HandleEvent(event tcell.Event) {
+	switch listKey(event) {
+	case '\r':
+		h.restore()
+	case 'd':
+		h.diff()
+	default:
+		h.BufPane.HandleEvent(event)
+	}
+}
+
+// rerender lists the versions with the lines changed from them to the text
+// of the buffer
+func (h *HistoryPane) rerender() {
+	current := h.src.Buf.Bytes()
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%d versions, Enter restores, d compares)\n", h.src.Buf.GetName(), len(h.versions))
+	for _, v := range h.versions {
+		changes := "lost"
+		if text, err := buffer.HistoryText(h.src.Buf.AbsPath, v); err == nil {
+			added, removed := 0, 0
+			for _, hunk := range git.Hunks(text, current) {
+				added += len(hunk.Lines)
+				removed += len(hunk.Base)
+			}
+			changes = "current"
+			if added > 0 || removed > 0 {
+				changes = fmt.Sprintf("+%d -%d", added, removed)
+			}
+		}
+		fmt.Fprintf(&sb, "    %s  %-12s  %s\n", v.Time.Format("Jan _2 15:04:05"), ago(now.Sub(v.Time)), changes)
+	}
+	h.setList("history: "+h.src.Buf.GetName(), strings.TrimSuffix(sb.String(), "\n"), 1, len(h.versions))
+}
+
+// ago returns roughly how long ago something happened, d before now
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d h ago", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%d days ago", int(d/(24*time.Hour)))
+}
+
+// current returns the version under the cursor and its text
+func (h *HistoryPane) current() (buffer.HistoryVersion, []byte, bool) {
+	if h.Cursor.Y < 1 || h.Cursor.Y > len(h.versions) {
+		return buffer.HistoryVersion{}, nil, false
+	}
+	v := h.versions[h.Cursor.Y-1]
+	text, err := buffer.HistoryText(h.src.Buf.AbsPath, v)
+	if err != nil {
+		InfoBar.Error(err)
+		return v, nil, false
+	}
+	return v, text, true
+}
+
+// restore replaces the text of the buffer by the version under the cursor
+func (h *HistoryPane) restore() {
+	v, text, ok := h.current()
+	if !ok {
+		return
+	}
+	b := h.src.Buf
+	if b.Type.Readonly {
+		InfoBar.Error("The buffer is read only")
+		return
+	}
+	if string(text) == string(b.Bytes()) {
+		InfoBar.Message("The buffer has the text of this version already")
+		return
+	}
+	y := h.src.Cursor.Y
+	h.src.Cursor.Deselect(true)
+	b.EventHandler.Replace(b.Start(), b.End(), string(text))
+	h.src.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(y, 0, b.LinesNum()-1)})
+	h.src.Relocate()
+	h.rerender()
+	InfoBar.Message("Restored the version of ", v.Time.Format("Jan _2 15:04:05"), " (undo in the buffer to go back)")
+}
+
+// diff compares the version under the cursor side by side with the buffer
+func (h *HistoryPane) diff() {
+	v, text, ok := h.current()
+	if !ok {
+		return
+	}
+	b := buffer.NewBufferFromString(string(text), "", buffer.BTScratch)
+	b.Type.Readonly = true
+	b.SetOptionNative("filetype", h.src.Buf.Settings["filetype"])
+	b.SetName(h.src.Buf.GetName() + " (" + v.Time.Format("Jan _2 15:04") + ")")
+	h.src.DiffSplit(b)
+}
@@ -2,11 +2,16 @@ package action
 
 import (
 	"bytes"
+	"io/ioutil"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/dap"
+	"github.com/zyedidia/micro/v2/internal/dbexec"
+	"github.com/zyedidia/micro/v2/internal/project"
 	"github.com/zyedidia/micro/v2/internal/util"
 )
 
@@ -57,6 +62,58 @@ func HelpComplete(b *buffer.Buffer) ([]string, []string) {
 	return completions, suggestions
 }
 
+// CaseComplete autocompletes the styles of the case command
+func CaseComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := buffer.GetArg(b)
+
+	var suggestions []string
+	for _, style := range util.CaseStyles {
+		if strings.HasPrefix(style, input) {
+			suggestions = append(suggestions, style)
+		}
+	}
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// IndentationComplete autocompletes the styles of the convert-indentation
+// command
+func IndentationComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := buffer.GetArg(b)
+
+	var suggestions []string
+	for _, style := range []string{"spaces", "tabs"} {
+		if strings.HasPrefix(style, input) {
+			suggestions = append(suggestions, style)
+		}
+	}
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// ColorschemeComplete autocompletes the names of the colorschemes
+func ColorschemeComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := buffer.GetArg(b)
+
+	_, suggestions := colorschemeComplete(input)
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
 // colorschemeComplete tab-completes names of colorschemes.
 // This is just a heper value for OptionValueComplete
 func colorschemeComplete(input string) (string, []string) {
@@ -111,6 +168,30 @@ func OptionComplete(b *buffer.Buffer) ([]string, []string) {
 	return completions, suggestions
 }
 
+// PaneOptionComplete autocompletes the options which can be set for a pane
+func PaneOptionComplete(b *buffer.Buffer) ([]string, []string) {
+	return prefixComplete(b, config.PaneSettings)
+}
+
+// optionChoices are the values of the string options which only accept a
+// few values
+var optionChoices = map[string][]string{
+	"ambiguouswidth": {"auto", "narrow", "wide"},
+	"clipboard":      {"external", "internal", "osc52", "terminal"},
+	"colorswatch":    {"auto", "off", "on"},
+	"dbformat":       dbexec.Formats,
+	"diffbase":       {"auto", "disk", "fossil", "git", "hg", "svn"},
+	"fileformat":     {"dos", "unix"},
+	"foldmethod":     buffer.FoldMethods,
+	"keyprofile":     KeyProfiles,
+	"regexengine":    {"go", "pcre"},
+	"sucmd":          {"doas", "sudo"},
+	"tabpath":        {"base", "full", "short"},
+	"taskoutput":     {"pane", "terminal"},
+	"termdir":        {"buffer", "project"},
+	"undogroup":      {"action", "time", "word"},
+}
+
 // OptionValueComplete completes values for various options
 func OptionValueComplete(b *buffer.Buffer) ([]string, []string) {
 	c := b.GetActiveCursor()
@@ -172,29 +253,13 @@ func OptionValueComplete(b *buffer.Buffer) ([]string, []string) {
 		switch inputOpt {
 		case "colorscheme":
 			_, suggestions = colorschemeComplete(input)
-		case "fileformat":
-			if strings.HasPrefix("unix", input) {
-				suggestions = append(suggestions, "unix")
-			}
-			if strings.HasPrefix("dos", input) {
-				suggestions = append(suggestions, "dos")
-			}
-		case "sucmd":
-			if strings.HasPrefix("sudo", input) {
-				suggestions = append(suggestions, "sudo")
-			}
-			if strings.HasPrefix("doas", input) {
-				suggestions = append(suggestions, "doas")
-			}
-		case "clipboard":
-			if strings.HasPrefix("external", input) {
-				suggestions = append(suggestions, "external")
-			}
-			if strings.HasPrefix("internal", input) {
-				suggestions = append(suggestions, "internal")
-			}
-			if strings.HasPrefix("terminal", input) {
-				suggestions = append(suggestions, "terminal")
+		case "filetype":
+			return FiletypeComplete(b)
+		default:
+			for _, v := range optionChoices[inputOpt] {
+				if strings.HasPrefix(v, input) {
+					suggestions = append(suggestions, v)
+				}
 			}
 		}
 	}
@@ -263,28 +328,318 @@ func PluginComplete(b *buffer.Buffer) ([]string, []string) {
 	return completions, suggestions
 }
 
-// PluginNameComplete completes with the names of loaded plugins
-// func PluginNameComplete(b *buffer.Buffer) ([]string, []string) {
-// 	c := b.GetActiveCursor()
-// 	input, argstart := buffer.GetArg(b)
-//
-// 	var suggestions []string
-// 	for _, pp := range config.GetAllPluginPackages(nil) {
-// 		if strings.HasPrefix(pp.Name, input) {
-// 			suggestions = append(suggestions, pp.Name)
-// 		}
-// 	}
-//
-// 	sort.Strings(suggestions)
-// 	completions := make([]string, len(suggestions))
-// 	for i := range suggestions {
-// 		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
-// 	}
-// 	return completions, suggestions
-// }
-
-// // MakeCompletion registers a function from a plugin for autocomplete commands
-// func MakeCompletion(function string) Completion {
-// 	pluginCompletions = append(pluginCompletions, LuaFunctionComplete(function))
-// 	return Completion(-len(pluginCompletions))
-// }
+// SessionComplete completes the subcommands of the session command and the
+// names of the saved sessions
+// ExportComplete completes the formats of the export command, and then the
+// file to write
+func ExportComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+	if args := bytes.Split(l, []byte{' '}); len(args) > 2 {
+		return buffer.FileComplete(b)
+	}
+	input, argstart := buffer.GetArg(b)
+
+	var suggestions []string
+	for _, f := range ExportFormats {
+		if strings.HasPrefix(f, input) {
+			suggestions = append(suggestions, f)
+		}
+	}
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// LayoutComplete completes the subcommands of the layout command and the
+// names of the layout presets
+func LayoutComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := buffer.GetArg(b)
+
+	names := append([]string(nil), LayoutCmds...)
+	for _, f := range config.ListRuntimeFiles(config.RTLayout) {
+		names = append(names, f.Name())
+	}
+
+	var suggestions []string
+	for _, n := range names {
+		if strings.HasPrefix(n, input) {
+			suggestions = append(suggestions, n)
+		}
+	}
+	sort.Strings(suggestions)
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+func SessionComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+	input, argstart := buffer.GetArg(b)
+
+	var names []string
+	if args := bytes.Split(l, []byte{' '}); len(args) == 2 {
+		names = SessionCmds
+	} else if len(args) == 3 {
+		files, _ := ioutil.ReadDir(sessionDir())
+		for _, f := range files {
+			if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+				names = append(names, strings.TrimSuffix(f.Name(), ".json"))
+			}
+		}
+	}
+
+	var suggestions []string
+	for _, n := range names {
+		if strings.HasPrefix(n, input) {
+			suggestions = append(suggestions, n)
+		}
+	}
+	sort.Strings(suggestions)
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// ThemeComplete completes the subcommand of the theme command and the file
+// of the theme
+func ThemeComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+	input, argstart := buffer.GetArg(b)
+
+	switch len(bytes.Split(l, []byte{' '})) {
+	case 2:
+		if strings.HasPrefix("import", input) {
+			return []string{util.SliceEndStr("import", c.X-argstart)}, []string{"import"}
+		}
+	case 3:
+		return buffer.FileComplete(b)
+	}
+	return nil, nil
+}
+
+// MacroComplete completes the subcommands of the macro command and the names
+// of the stored macros
+func MacroComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+	input, argstart := buffer.GetArg(b)
+
+	var names []string
+	if args := bytes.Split(l, []byte{' '}); len(args) == 2 {
+		names = MacroCmds
+	} else if len(args) == 3 && string(args[1]) != "stop" {
+		files, _ := ioutil.ReadDir(filepath.Dir(macroPath(defaultMacro)))
+		for _, f := range files {
+			if !f.IsDir() {
+				names = append(names, f.Name())
+			}
+		}
+	}
+
+	var suggestions []string
+	for _, n := range names {
+		if strings.HasPrefix(n, input) {
+			suggestions = append(suggestions, n)
+		}
+	}
+	sort.Strings(suggestions)
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// ProjectComplete completes the subcommands of the project command and the
+// roots of the known projects
+func ProjectComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+	input, argstart := buffer.GetArg(b)
+
+	var names []string
+	if args := bytes.Split(l, []byte{' '}); len(args) == 2 {
+		names = ProjectCmds
+	} else if len(args) == 3 && string(args[1]) == "open" {
+		projects, _ := project.List()
+		for _, p := range projects {
+			names = append(names, p.Root)
+		}
+	}
+
+	var suggestions []string
+	for _, n := range names {
+		if strings.HasPrefix(n, input) {
+			suggestions = append(suggestions, n)
+		}
+	}
+	sort.Strings(suggestions)
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// ArgComplete returns a completer which completes each argument of a command
+// with the completer at its position: the first completer is used for the
+// first argument, and the last one for the arguments after it. A nil
+// completer leaves its argument without completion.
+func ArgComplete(completers ...buffer.Completer) buffer.Completer {
+	return func(b *buffer.Buffer) ([]string, []string) {
+		c := b.GetActiveCursor()
+		l := util.SliceStart(b.LineBytes(c.Y), c.X)
+		n := len(bytes.Split(l, []byte{' '})) - 1
+		if n < 1 || len(completers) == 0 {
+			return nil, nil
+		}
+		if n > len(completers) {
+			n = len(completers)
+		}
+		if completers[n-1] == nil {
+			return nil, nil
+		}
+		return completers[n-1](b)
+	}
+}
+
+// prefixComplete completes the argument before the cursor with the names
+// starting with it
+func prefixComplete(b *buffer.Buffer, names []string) ([]string, []string) {
+	c := b.GetActiveCursor()
+	input, argstart := buffer.GetArg(b)
+
+	var suggestions []string
+	for _, n := range names {
+		if strings.HasPrefix(n, input) && !contains(suggestions, n) {
+			suggestions = append(suggestions, n)
+		}
+	}
+	sort.Strings(suggestions)
+
+	completions := make([]string, len(suggestions))
+	for i := range suggestions {
+		completions[i] = util.SliceEndStr(suggestions[i], c.X-argstart)
+	}
+	return completions, suggestions
+}
+
+// TaskComplete completes the names of the tasks of the runtask command
+func TaskComplete(b *buffer.Buffer) ([]string, []string) {
+	var names []string
+	for name := range taskOptions {
+		names = append(names, name)
+	}
+	return prefixComplete(b, names)
+}
+
+// DebugComplete completes the subcommands of the debug command, and the
+// names of the debug configurations of the project after start
+func DebugComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+
+	var names []string
+	if args := bytes.Split(l, []byte{' '}); len(args) == 2 {
+		names = DebugCmds
+	} else if len(args) == 3 && string(args[1]) == "start" {
+		if h := MainTab().CurPane(); h != nil {
+			configs, _ := dap.LoadConfigurations(h.projectRoot())
+			for _, dc := range configs {
+				names = append(names, dc.Name)
+			}
+		}
+	}
+	return prefixComplete(b, names)
+}
+
+// SecretComplete completes the subcommands of the secret command, and the
+// names of the secrets after remove and set
+func SecretComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+
+	var names []string
+	if args := bytes.Split(l, []byte{' '}); len(args) == 2 {
+		names = SecretCmds
+	} else if len(args) == 3 && (string(args[1]) == "remove" || string(args[1]) == "set") {
+		names = config.SecretNames()
+	}
+	return prefixComplete(b, names)
+}
+
+// SyntaxComplete completes the subcommands of the syntax command, and the
+// file to check or test
+func SyntaxComplete(b *buffer.Buffer) ([]string, []string) {
+	c := b.GetActiveCursor()
+	l := util.SliceStart(b.LineBytes(c.Y), c.X)
+
+	if args := bytes.Split(l, []byte{' '}); len(args) == 2 {
+		return prefixComplete(b, SyntaxCmds)
+	}
+	return buffer.FileComplete(b)
+}
+
+// RuntimeComplete completes the subcommands of the runtime command
+func RuntimeComplete(b *buffer.Buffer) ([]string, []string) {
+	return prefixComplete(b, RuntimeCmds)
+}
+
+// LogComplete completes the subcommands of the log command
+func LogComplete(b *buffer.Buffer) ([]string, []string) {
+	return prefixComplete(b, LogCmds)
+}
+
+// BufferNameComplete completes with the names of the open buffers
+func BufferNameComplete(b *buffer.Buffer) ([]string, []string) {
+	var names []string
+	for _, ob := range buffer.OpenBuffers {
+		if ob.Type == buffer.BTDefault || ob.Type == buffer.BTHelp {
+			names = append(names, ob.GetName())
+		}
+	}
+	return prefixComplete(b, names)
+}
+
+// PluginNameComplete completes with the names of the loaded plugins
+func PluginNameComplete(b *buffer.Buffer) ([]string, []string) {
+	var names []string
+	for _, pl := range config.Plugins {
+		names = append(names, pl.Name)
+	}
+	return prefixComplete(b, names)
+}
+
+// ActionComplete completes with the names of the actions which can be bound
+// to keys
+func ActionComplete(b *buffer.Buffer) ([]string, []string) {
+	var names []string
+	for name := range BufKeyActions {
+		names = append(names, name)
+	}
+	return prefixComplete(b, names)
+}
+
+// FiletypeComplete completes with the filetypes of the syntax files
+func FiletypeComplete(b *buffer.Buffer) ([]string, []string) {
+	names := []string{"unknown", "off"}
+	for _, f := range config.ListRuntimeFiles(config.RTSyntax) {
+		names = append(names, f.Name())
+	}
+	return prefixComplete(b, names)
+}
@@ -2,6 +2,7 @@ package action
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/config"
@@ -59,6 +60,14 @@ func InfoKeyActionGeneral(a InfoKeyAction) PaneKeyAction {
 type InfoPane struct {
 	*BufPane
 	*info.InfoBuf
+
+	// whether the last key event continued a reverse history search
+	searched bool
+	// whether the last key event killed text, which the next kill adds to
+	killed, killing bool
+	// cycleMatch shows the next or the previous match of the text typed
+	// in an incremental find prompt, and is nil in the other prompts
+	cycleMatch func(resp string, down bool)
 }
 
 func NewInfoPane(ib *info.InfoBuf, w display.BWindow, tab *Tab) *InfoPane {
@@ -90,23 +99,25 @@ func (h *InfoPane) HandleEvent(event tcell.Event) {
 			r:    e.Rune(),
 		}
 
+		h.searched = false
+		h.killing, h.killed = h.killed, false
 		done := h.DoKeyEvent(ke)
+		if !h.searched {
+			// any other key ends a reverse history search
+			h.EndHistorySearch()
+		}
 		hasYN := h.HasYN
-		if e.Key() == tcell.KeyRune && hasYN {
-			if e.Rune() == 'y' && hasYN {
-				h.YNResp = true
-				h.DonePrompt(false)
-			} else if e.Rune() == 'n' && hasYN {
-				h.YNResp = false
-				h.DonePrompt(false)
-			}
+		if e.Key() == tcell.KeyRune && hasYN && strings.ContainsRune(h.Choices, e.Rune()) {
+			h.YNResp = e.Rune() == 'y'
+			h.ChoiceResp = e.Rune()
+			h.DonePrompt(false)
 		}
 		if e.Key() == tcell.KeyRune && !done && !hasYN {
 			h.DoRuneInsert(e.Rune())
 			done = true
 		}
 		if done && h.HasPrompt && !hasYN {
-			resp := string(h.LineBytes(0))
+			resp := h.Response()
 			hist := h.History[h.PromptType]
 			hist[h.HistoryNum] = resp
 			if h.EventCallback != nil {
@@ -145,18 +156,105 @@ func (h *InfoPane) DoKeyEvent(e KeyEvent) bool {
 	return more
 }
 
-// HistoryUp cycles history up
+// Pick starts a prompt which shows a fuzzy picker for the given items.
+// The list is filtered as the user types and donecb is called with the
+// chosen item, or with nil if the prompt was canceled or nothing matched.
+func (h *InfoPane) Pick(prompt, ptype string, items []display.PickerItem, donecb func(*display.PickerItem)) {
+	h.PickPreview(prompt, ptype, items, nil, donecb)
+}
+
+// PickPreview is Pick which also calls previewcb each time another item is
+// selected, as the user moves through the list or types, with nil if
+// nothing matches
+func (h *InfoPane) PickPreview(prompt, ptype string, items []display.PickerItem, previewcb, donecb func(*display.PickerItem)) {
+	p := display.NewPicker(items)
+	last := p.Current()
+	h.Prompt(prompt, "", ptype, func(resp string) {
+		p.Filter(resp)
+		if cur := p.Current(); previewcb != nil && cur != last {
+			last = cur
+			previewcb(cur)
+		}
+	}, func(resp string, canceled bool) {
+		h.setPicker(nil)
+		if canceled {
+			donecb(nil)
+			return
+		}
+		donecb(p.Current())
+	})
+	h.setPicker(p)
+}
+
+// picker returns the active picker, if any
+func (h *InfoPane) picker() *display.Picker {
+	if iw, ok := h.BWindow.(*display.InfoWindow); ok {
+		return iw.Picker
+	}
+	return nil
+}
+
+func (h *InfoPane) setPicker(p *display.Picker) {
+	if iw, ok := h.BWindow.(*display.InfoWindow); ok {
+		iw.Picker = p
+	}
+}
+
+// HistoryUp cycles history up, or selects the next match of a picker
 func (h *InfoPane) HistoryUp() {
+	if p := h.picker(); p != nil {
+		p.Next()
+		return
+	}
 	h.UpHistory(h.History[h.PromptType])
 }
 
-// HistoryDown cycles history down
+// HistoryDown cycles history down, or selects the previous match of a picker
 func (h *InfoPane) HistoryDown() {
+	if p := h.picker(); p != nil {
+		p.Prev()
+		return
+	}
 	h.DownHistory(h.History[h.PromptType])
 }
 
+// HistorySearch searches the history backwards for an entry containing the
+// text typed in the prompt. Repeating it finds older entries.
+func (h *InfoPane) HistorySearch() {
+	if h.picker() != nil {
+		return
+	}
+	h.searched = true
+	h.SearchHistory(h.History[h.PromptType])
+}
+
+// FindMatchNext shows the next match of an incremental find prompt, or
+// cycles history down in the other prompts
+func (h *InfoPane) FindMatchNext() {
+	if h.cycleMatch == nil {
+		h.HistoryDown()
+		return
+	}
+	h.cycleMatch(h.Response(), true)
+}
+
+// FindMatchPrevious shows the previous match of an incremental find prompt,
+// or cycles history up in the other prompts
+func (h *InfoPane) FindMatchPrevious() {
+	if h.cycleMatch == nil {
+		h.HistoryUp()
+		return
+	}
+	h.cycleMatch(h.Response(), false)
+}
+
 // Autocomplete begins autocompletion
 func (h *InfoPane) CommandComplete() {
+	if p := h.picker(); p != nil {
+		p.Next()
+		return
+	}
+
 	b := h.Buf
 	if b.HasSuggestions {
 		b.CycleAutocomplete(true)
@@ -164,7 +262,7 @@ func (h *InfoPane) CommandComplete() {
 	}
 
 	c := b.GetActiveCursor()
-	l := b.LineBytes(0)
+	l := b.LineBytes(c.Y)
 	l = util.SliceStart(l, c.X)
 
 	args := bytes.Split(l, []byte{' '})
@@ -184,6 +282,46 @@ func (h *InfoPane) CommandComplete() {
 	}
 }
 
+// kill removes the text between the cursor and loc from the prompt, for
+// Yank. Consecutive kills are yanked together.
+func (h *InfoPane) kill(loc buffer.Loc) {
+	h.Kill(h.Cursor.Loc, loc, h.killing)
+	h.killed = true
+}
+
+// KillToEnd removes the text from the cursor to the end of the line
+func (h *InfoPane) KillToEnd() {
+	h.kill(buffer.Loc{X: util.CharacterCount(h.LineBytes(h.Cursor.Y)), Y: h.Cursor.Y})
+}
+
+// KillToStart removes the text from the start of the line to the cursor
+func (h *InfoPane) KillToStart() {
+	h.kill(buffer.Loc{X: 0, Y: h.Cursor.Y})
+}
+
+// KillWordLeft removes the word before the cursor
+func (h *InfoPane) KillWordLeft() {
+	loc := h.Cursor.Loc
+	h.Cursor.WordLeft()
+	left := h.Cursor.Loc
+	h.Cursor.GotoLoc(loc)
+	h.kill(left)
+}
+
+// KillWordRight removes the word after the cursor
+func (h *InfoPane) KillWordRight() {
+	loc := h.Cursor.Loc
+	h.Cursor.WordRight()
+	right := h.Cursor.Loc
+	h.Cursor.GotoLoc(loc)
+	h.kill(right)
+}
+
+// Yank inserts the text removed by the last kills at the cursor
+func (h *InfoPane) Yank() {
+	h.InfoBuf.Yank()
+}
+
 // ExecuteCommand completes the prompt
 func (h *InfoPane) ExecuteCommand() {
 	if !h.HasYN {
@@ -198,9 +336,17 @@ func (h *InfoPane) AbortCommand() {
 
 // InfoKeyActions contains the list of all possible key actions the infopane could execute
 var InfoKeyActions = map[string]InfoKeyAction{
-	"HistoryUp":       (*InfoPane).HistoryUp,
-	"HistoryDown":     (*InfoPane).HistoryDown,
-	"CommandComplete": (*InfoPane).CommandComplete,
-	"ExecuteCommand":  (*InfoPane).ExecuteCommand,
-	"AbortCommand":    (*InfoPane).AbortCommand,
+	"HistoryUp":         (*InfoPane).HistoryUp,
+	"HistoryDown":       (*InfoPane).HistoryDown,
+	"HistorySearch":     (*InfoPane).HistorySearch,
+	"FindMatchNext":     (*InfoPane).FindMatchNext,
+	"FindMatchPrevious": (*InfoPane).FindMatchPrevious,
+	"CommandComplete":   (*InfoPane).CommandComplete,
+	"KillToEnd":         (*InfoPane).KillToEnd,
+	"KillToStart":       (*InfoPane).KillToStart,
+	"KillWordLeft":      (*InfoPane).KillWordLeft,
+	"KillWordRight":     (*InfoPane).KillWordRight,
+	"Yank":              (*InfoPane).Yank,
+	"ExecuteCommand":    (*InfoPane).ExecuteCommand,
+	"AbortCommand":      (*InfoPane).AbortCommand,
 }
@@ -0,0 +1,103 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+)
+
+// maxJumps is the number of locations kept in the jump list of a pane
+const maxJumps = 100
+
+// A jump is a location of the jump list. The buffer is used to come back to
+// buffers without a file, which can't be opened again.
+type jump struct {
+	buf  *buffer.Buffer
+	path string
+	loc  buffer.Loc
+}
+
+// pushJump records loc in the current buffer as a location the cursor is
+// jumping from. The locations after the current one are dropped when going
+// through the jump list.
+func (h *BufPane) pushJump(loc buffer.Loc) {
+	if h.jumping {
+		return
+	}
+	j := jump{h.Buf, h.Buf.AbsPath, loc}
+	jumps := h.jumps[:h.jumpIdx]
+	if n := len(jumps); n == 0 || !jumps[n-1].same(j) {
+		jumps = append(jumps, j)
+	}
+	if len(jumps) > maxJumps {
+		jumps = jumps[len(jumps)-maxJumps:]
+	}
+	h.jumps = jumps
+	h.jumpIdx = len(jumps)
+}
+
+// copyJumps returns a copy of the jump list, for a pane opened by a jump
+func (h *BufPane) copyJumps() []jump {
+	jumps := make([]jump, len(h.jumps))
+	copy(jumps, h.jumps)
+	return jumps
+}
+
+func (j jump) same(o jump) bool {
+	return j.loc == o.loc && (j.buf == o.buf || j.path != "" && j.path == o.path)
+}
+
+// reachable returns whether the pane can go to the location of j, which is
+// not the case for a closed buffer without a file
+func (h *BufPane) reachable(j jump) bool {
+	return j.buf == h.Buf || j.path != ""
+}
+
+// JumpBack returns to the location the cursor last jumped from, going
+// further back in the jump list when used again
+func (h *BufPane) JumpBack() bool {
+	if h.jumpIdx == len(h.jumps) && len(h.jumps) > 0 {
+		// the current location is kept to come back to with JumpForward
+		h.pushJump(h.Cursor.Loc)
+		h.jumpIdx = len(h.jumps) - 1
+	}
+	for i := h.jumpIdx - 1; i >= 0; i-- {
+		if h.reachable(h.jumps[i]) {
+			return h.gotoJump(i)
+		}
+	}
+	InfoBar.Message("No older location in the jump list")
+	return false
+}
+
+// JumpForward goes to the next location of the jump list after JumpBack
+func (h *BufPane) JumpForward() bool {
+	for i := h.jumpIdx + 1; i < len(h.jumps); i++ {
+		if h.reachable(h.jumps[i]) {
+			return h.gotoJump(i)
+		}
+	}
+	InfoBar.Message("No newer location in the jump list")
+	return false
+}
+
+// gotoJump moves the cursor to the location i of the jump list, in a new
+// split if the buffer has to be replaced but has unsaved changes
+func (h *BufPane) gotoJump(i int) bool {
+	j := h.jumps[i]
+	target := h
+	h.jumping = true
+	if j.buf == h.Buf {
+		h.gotoLoc(j.loc)
+	} else {
+		target = h.openAt(j.path, j.loc)
+	}
+	h.jumping = false
+	if target == nil {
+		return false
+	}
+	h.jumpIdx = i
+	if target != h {
+		target.jumps = h.copyJumps()
+		target.jumpIdx = i
+	}
+	return true
+}
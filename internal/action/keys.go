@@ -0,0 +1,354 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/json5"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// keySources are the sources of the bindings, in the order the keys pane
+// lists them
+var keySources = []string{"default", "user", "plugin", "profile"}
+
+// A keyEntry is a binding listed by the keys pane
+type keyEntry struct {
+	pane, key string
+	boundAction
+	// shadowed is the binding shadowing this one, if any
+	shadowed *boundAction
+	// conflicts are the other bindings of the same pane overlapping this
+	// one
+	conflicts []string
+}
+
+// A KeysPane lists the bindings by source below the pane it was opened
+// from, with the bindings shadowed by others and the ones overlapping
+// others flagged. '/' filters the list by action with fuzzy matching,
+// Enter binds the action under the cursor to the next key typed instead,
+// 'e' changes the action of the key and 'd' removes the binding, or
+// disables it if it is not one of bindings.json. The changes are written
+// to bindings.json.
+type KeysPane struct {
+	*listPane
+
+	// entries are the listed bindings, one per line, nil for the lines of
+	// the sources
+	entries []*keyEntry
+	// rebinding is the binding whose action is bound to the next key typed
+	rebinding *keyEntry
+}
+
+// KeysCmd opens a pane below the current one listing the bindings
+func (h *BufPane) KeysCmd(args []string) {
+	kp := &KeysPane{listPane: newListPane(h)}
+	kp.openBelow(h, kp)
+	kp.rerender()
+}
+
+// HandleEvent binds the next key typed after Enter, filters, edits or
+// removes bindings with the keys of the list, and passes the other events
+// to the bufpane
+func (h *KeysPane) HandleEvent(event tcell.Event) {
+	if e, ok := event.(*tcell.EventKey); ok && h.rebinding != nil {
+		entry := h.rebinding
+		h.rebinding = nil
+		if e.Key() == tcell.KeyEscape {
+			InfoBar.Message("")
+			return
+		}
+		ke := KeyEvent{code: e.Key(), mod: metaToAlt(e.Modifiers()), r: e.Rune()}
+		h.rebind(entry, ke.Name())
+		return
+	}
+	switch listKey(event) {
+	case '\r':
+		if entry := h.current(); entry != nil {
+			h.rebinding = entry
+			InfoBar.Message("Type the key to bind to ", entry.action, " (Esc cancels)")
+		}
+	case '/':
+		h.promptFilter("KeysFilter", h.rerender)
+	case 'e':
+		h.editAction()
+	case 'd':
+		h.removeBinding()
+	default:
+		h.BufPane.HandleEvent(event)
+	}
+}
+
+// keyEntries returns the bindings of all panes by source, sorted by pane
+// and key
+func keyEntries() map[string][]*keyEntry {
+	trees := map[string][]*KeyTree{
+		"buffer":   {BufBindings},
+		"command":  {InfoBindings, InfoBufBindings},
+		"terminal": {TermBindings},
+	}
+	entries := make(map[string][]*keyEntry)
+	for pane, keys := range boundActions {
+		for key, bound := range keys {
+			var conflicts []string
+			if event, err := findEvent(key); err == nil {
+				for _, t := range trees[pane] {
+					conflicts = append(conflicts, t.Conflicts(event)...)
+				}
+			}
+			last := bound[len(bound)-1]
+			for i, b := range bound {
+				if b.action == "None" && b.source == "default" {
+					continue
+				}
+				e := &keyEntry{pane: pane, key: key, boundAction: b}
+				if i < len(bound)-1 {
+					e.shadowed = &last
+				} else {
+					e.conflicts = conflicts
+				}
+				entries[b.source] = append(entries[b.source], e)
+			}
+		}
+	}
+	for _, list := range entries {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].pane != list[j].pane {
+				return list[i].pane < list[j].pane
+			}
+			return list[i].key < list[j].key
+		})
+	}
+	return entries
+}
+
+// rerender lists the bindings whose action or key match the filter again
+func (h *KeysPane) rerender() {
+	all := keyEntries()
+	h.entries = h.entries[:0]
+	width := 0
+	for _, list := range all {
+		for _, e := range list {
+			width = util.Max(width, util.CharacterCountInString(e.key))
+		}
+	}
+
+	var sb strings.Builder
+	count, flagged := 0, 0
+	for _, source := range keySources {
+		var listed []*keyEntry
+		for _, e := range all[source] {
+			_, ok := util.FuzzyMatch(h.filter, e.action)
+			if _, keyOk := util.FuzzyMatch(h.filter, e.key); ok || keyOk {
+				listed = append(listed, e)
+			}
+		}
+		if len(listed) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s (%d)\n", source, len(listed))
+		h.entries = append(h.entries, nil)
+		for _, e := range listed {
+			note := ""
+			if e.shadowed != nil {
+				note = "  shadowed by " + e.shadowed.source + ": " + e.shadowed.action
+			} else if len(e.conflicts) > 0 {
+				note = "  conflict: " + strings.Join(e.conflicts, ", ")
+			}
+			if note != "" {
+				flagged++
+			}
+			pad := strings.Repeat(" ", width-util.CharacterCountInString(e.key))
+			fmt.Fprintf(&sb, "    %-8s  %s%s  %s%s\n", e.pane, e.key, pad, e.action, note)
+			h.entries = append(h.entries, e)
+			count++
+		}
+	}
+
+	h.setList(fmt.Sprintf("keys (%d, %d flagged)", count, flagged), sb.String(), 0, len(h.entries)-1)
+}
+
+// current returns the binding under the cursor, if any
+func (h *KeysPane) current() *keyEntry {
+	if h.Cursor.Y < len(h.entries) {
+		return h.entries[h.Cursor.Y]
+	}
+	return nil
+}
+
+// rebind binds the action of the entry to the key, after asking when the
+// key is bound already, and disables the key of the entry
+func (h *KeysPane) rebind(entry *keyEntry, key string) {
+	if key == entry.key {
+		return
+	}
+	apply := func() {
+		err := writeBinding(entry.pane, key, entry.action)
+		if err == nil {
+			err = h.unbind(entry)
+		}
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		bindFrom("user", entry.pane, key, entry.action)
+		h.rerender()
+		InfoBar.Message("Bound ", key, " to ", entry.action)
+	}
+	if a, ok := config.Bindings[entry.pane][key]; ok && a != "None" && a != entry.action {
+		InfoBar.YNPrompt(key+" is bound to "+a+", bind it to "+entry.action+" instead? (y,n)", func(yes, canceled bool) {
+			if yes && !canceled {
+				apply()
+			}
+		})
+		return
+	}
+	apply()
+}
+
+// editAction asks for the action bound to the key under the cursor
+func (h *KeysPane) editAction() {
+	entry := h.current()
+	if entry == nil {
+		return
+	}
+	InfoBar.Prompt("Action for "+entry.key+": ", entry.action, "KeyAction", nil, func(resp string, canceled bool) {
+		if canceled || resp == "" || resp == entry.action {
+			return
+		}
+		if err := writeBinding(entry.pane, entry.key, resp); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		bindFrom("user", entry.pane, entry.key, resp)
+		h.rerender()
+	})
+}
+
+// removeBinding removes the binding under the cursor
+func (h *KeysPane) removeBinding() {
+	entry := h.current()
+	if entry == nil {
+		return
+	}
+	if entry.shadowed != nil && entry.source != "user" && entry.source != "plugin" {
+		InfoBar.Message("The binding is shadowed by ", entry.shadowed.source, " already")
+		return
+	}
+	if err := h.unbind(entry); err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	h.rerender()
+}
+
+// unbind removes the binding of the entry from bindings.json, binding the
+// key to the action it shadowed, or binds the key to nothing if the
+// binding is not one of bindings.json and is not shadowed already
+func (h *KeysPane) unbind(entry *keyEntry) error {
+	if entry.source == "user" || entry.source == "plugin" {
+		if err := removeFileBinding(entry.pane, entry.key); err != nil {
+			return err
+		}
+		forgetBinding(entry.pane, entry.key)
+		return nil
+	}
+	if entry.shadowed != nil {
+		return nil
+	}
+	if err := writeBinding(entry.pane, entry.key, "None"); err != nil {
+		return err
+	}
+	bindFrom("user", entry.pane, entry.key, "None")
+	return nil
+}
+
+// writeBinding binds the key to the action in the pane type in
+// bindings.json
+func writeBinding(pane, key, action string) error {
+	return editBindingsFile(pane, key, &action)
+}
+
+// removeFileBinding removes the binding of the key in the pane type from
+// bindings.json
+func removeFileBinding(pane, key string) error {
+	return editBindingsFile(pane, key, nil)
+}
+
+// editBindingsFile sets the binding of the key in the pane type in
+// bindings.json to the action, or removes it if action is nil. The
+// bindings of buffers are the strings at the top of the file, or the
+// entries of "buffer".
+func editBindingsFile(pane, key string, action *string) error {
+	filename := filepath.Join(config.ConfigDir, "bindings.json")
+	createBindingsIfNotExist(filename)
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.New("Error reading bindings.json file: " + err.Error())
+	}
+	var parsed map[string]interface{}
+	if err := json5.Unmarshal(input, &parsed); err != nil {
+		return errors.New("Error reading bindings.json: " + err.Error())
+	}
+	if parsed == nil {
+		parsed = make(map[string]interface{})
+	}
+	event, err := findEvent(key)
+	if err != nil {
+		return err
+	}
+
+	// same returns the key of the map bound to the event
+	same := func(m map[string]interface{}) (string, bool) {
+		for k, v := range m {
+			if _, ok := v.(string); !ok {
+				continue
+			}
+			if e, err := findEvent(k); err == nil && e == event {
+				return k, true
+			}
+		}
+		return "", false
+	}
+
+	maps := []map[string]interface{}{}
+	if pane == "buffer" {
+		maps = append(maps, parsed)
+	}
+	sub, _ := parsed[pane].(map[string]interface{})
+	if sub != nil {
+		maps = append(maps, sub)
+	}
+	found := false
+	for _, m := range maps {
+		if k, ok := same(m); ok {
+			found = true
+			if action == nil {
+				delete(m, k)
+			} else {
+				m[k] = *action
+			}
+		}
+	}
+	if !found && action != nil {
+		if pane == "buffer" {
+			parsed[key] = *action
+		} else {
+			if sub == nil {
+				sub = make(map[string]interface{})
+				parsed[pane] = sub
+			}
+			sub[key] = *action
+		}
+	}
+
+	txt, _ := json.MarshalIndent(parsed, "", "    ")
+	return ioutil.WriteFile(filename, append(txt, '\n'), 0644)
+}
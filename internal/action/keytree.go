@@ -221,6 +221,78 @@ func (k *KeyTree) NextEvent(e Event, mouse *tcell.EventMouse) (PaneKeyAction, bo
 	return nil, more
 }
 
+// Conflicts returns a description of each binding that overlaps with the
+// key sequence e: a shorter binding which is a prefix of e, or longer
+// bindings which start with e. Overlapping bindings still work but the
+// shorter one only runs after the key timeout expires.
+func (k *KeyTree) Conflicts(e Event) []string {
+	var keys []Event
+	switch ev := e.(type) {
+	case KeySequenceEvent:
+		keys = ev.keys
+	case KeyEvent, RawEvent:
+		keys = []Event{ev}
+	default:
+		return nil
+	}
+
+	var conflicts []string
+	n := k.root
+	prefix := KeySequenceEvent{}
+	for i, key := range keys {
+		c, ok := n.children[key]
+		if !ok {
+			return conflicts
+		}
+		prefix.keys = append(prefix.keys, key)
+		if i < len(keys)-1 && len(c.actions) > 0 {
+			conflicts = append(conflicts, prefix.Name()+" is already bound")
+		}
+		n = c
+	}
+	if len(n.children) > 0 {
+		conflicts = append(conflicts, KeySequenceEvent{keys}.Name()+" is the start of a longer key sequence")
+	}
+	return conflicts
+}
+
+// HasEvent returns whether e is one of the keys of a binding, alone or in
+// a key sequence
+func (k *KeyTree) HasEvent(e Event) bool {
+	return k.root.hasEvent(e)
+}
+
+func (n *KeyTreeNode) hasEvent(e Event) bool {
+	if _, ok := n.children[e]; ok {
+		return true
+	}
+	for _, c := range n.children {
+		if c.hasEvent(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pending returns true if the cursor is in the middle of a key sequence
+func (k *KeyTree) Pending() bool {
+	return k.cursor.node != k.root
+}
+
+// RecordedEvents returns the events of the current unfinished sequence
+func (k *KeyTree) RecordedEvents() []Event {
+	return k.cursor.recordedEvents
+}
+
+// PendingName returns the keys of the current unfinished sequence in the
+// bindings.json format, or an empty string if there is no such sequence
+func (k *KeyTree) PendingName() string {
+	if !k.Pending() {
+		return ""
+	}
+	return KeySequenceEvent{k.cursor.recordedEvents}.Name()
+}
+
 // ResetEvents sets the current sequence back to the initial value.
 func (k *KeyTree) ResetEvents() {
 	k.cursor.node = k.root
@@ -229,7 +301,7 @@ func (k *KeyTree) ResetEvents() {
 	k.cursor.mouseInfo = nil
 }
 
-// CurrentEventsStr returns the list of recorded events as a string
+// RecordedEventsStr returns the list of recorded events as a string
 func (k *KeyTree) RecordedEventsStr() string {
 	buf := &bytes.Buffer{}
 	for _, e := range k.cursor.recordedEvents {
@@ -0,0 +1,220 @@
+package action
+
+import (
+	"errors"
+
+	"github.com/zyedidia/json5"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// LayoutCmds are the subcommands of the layout command, the other
+// arguments being the names of layout presets
+var LayoutCmds = []string{"rotate", "swap", "equalize", "maximize", "tab"}
+
+// LayoutCmd arranges the splits of the tab, or applies a layout preset
+func (h *BufPane) LayoutCmd(args []string) {
+	if len(args) != 1 {
+		InfoBar.Error("Usage: layout rotate|swap|equalize|maximize|tab|'preset'")
+		return
+	}
+
+	switch args[0] {
+	case "rotate":
+		h.RotateSplit()
+	case "swap":
+		h.SwapSplit()
+	case "equalize":
+		h.EqualizeSplits()
+	case "maximize":
+		h.MaximizeSplit()
+	case "tab":
+		h.SplitToTab()
+	default:
+		l, err := layoutPreset(args[0])
+		if err == nil {
+			err = h.applyLayout(l)
+		}
+		if err != nil {
+			InfoBar.Error(err)
+		}
+	}
+}
+
+// RotateSplit turns the split containing the current pane, with the splits
+// in it, from splits side by side to splits on top of each other or the
+// other way around
+func (h *BufPane) RotateSplit() bool {
+	if !h.tab.GetNode(h.splitID).Rotate() {
+		InfoBar.Error("There is no split to rotate")
+		return false
+	}
+	h.tab.Resize()
+	return true
+}
+
+// SwapSplit swaps the current pane with the next one of the tab, from left
+// to right and from top to bottom. The current pane stays active, so that
+// swapping it again moves it further.
+func (h *BufPane) SwapSplit() bool {
+	leaves := h.tab.Leaves()
+	if len(leaves) < 2 {
+		InfoBar.Error("There is no split to swap with")
+		return false
+	}
+	var next uint64
+	for i, n := range leaves {
+		if n.ID() == h.splitID {
+			next = leaves[(i+1)%len(leaves)].ID()
+		}
+	}
+	p := h.tab.Panes[h.tab.GetPane(next)]
+	p.SetID(h.splitID)
+	h.SetID(next)
+	h.tab.Resize()
+	return true
+}
+
+// EqualizeSplits gives the same size to the splits of the tab
+func (h *BufPane) EqualizeSplits() bool {
+	h.tab.Equalize()
+	h.tab.Resize()
+	return true
+}
+
+// MaximizeSplit makes the current pane take the whole tab, hiding the
+// other panes until it is run again or another pane is activated
+func (h *BufPane) MaximizeSplit() bool {
+	t := h.tab
+	if t.maximized != nil {
+		t.maximized = nil
+	} else if len(t.Panes) > 1 {
+		t.maximized = t.Panes[t.active]
+	} else {
+		InfoBar.Error("The pane is alone in its tab")
+		return false
+	}
+	t.Resize()
+	return true
+}
+
+// SplitToTab moves the current pane to a new tab
+func (h *BufPane) SplitToTab() bool {
+	t := h.tab
+	if len(t.Panes) < 2 {
+		InfoBar.Error("The pane is alone in its tab")
+		return false
+	}
+	p := t.Panes[t.active]
+	t.removePane(p)
+
+	width, height := screen.Screen.Size()
+	iOffset := config.GetInfoBarOffset()
+	tp := NewTabFromPane(0, 0, width, height-iOffset, p)
+	Tabs.AddTab(tp)
+	Tabs.SetActive(len(Tabs.List) - 1)
+	tp.SetActive(0)
+	return true
+}
+
+// layoutPreset returns the layout preset with the given name, read from
+// the layouts runtime files
+func layoutPreset(name string) (*sessionNode, error) {
+	for _, f := range config.ListRuntimeFiles(config.RTLayout) {
+		if f.Name() != name {
+			continue
+		}
+		data, err := f.Data()
+		if err != nil {
+			return nil, err
+		}
+		l := new(sessionNode)
+		if err := json5.Unmarshal(data, l); err != nil {
+			return nil, errors.New("Error reading layout " + name + ": " + err.Error())
+		}
+		return l, nil
+	}
+	return nil, errors.New("No layout named " + name)
+}
+
+// leaves returns the splits of the layout which have no children, in the
+// order they are displayed
+func (n *sessionNode) leaves() []*sessionNode {
+	if len(n.Children) == 0 {
+		return []*sessionNode{n}
+	}
+	var leaves []*sessionNode
+	for _, c := range n.Children {
+		leaves = append(leaves, c.leaves()...)
+	}
+	return leaves
+}
+
+// applyLayout replaces the splits of the tab by the ones of the layout, the
+// current buffer being shown in the split marked as current or else in the
+// first one left empty. The other panes of the tab are closed, so their
+// buffers must not have unsaved changes.
+func (h *BufPane) applyLayout(l *sessionNode) error {
+	leaves := l.leaves()
+	cur := -1
+	for i, n := range leaves {
+		if n.Current {
+			cur = i
+			break
+		}
+	}
+	for i := 0; cur < 0 && i < len(leaves); i++ {
+		if n := leaves[i]; n.Path == "" && n.Terminal == nil && n.Command == "" {
+			cur = i
+		}
+	}
+	if cur < 0 {
+		return errors.New("The layout has no split for the current buffer")
+	}
+	t := h.tab
+	for _, p := range t.Panes {
+		if bp, ok := p.(*BufPane); ok && bp != h && bp.Buf.Unsaved() {
+			return errors.New("Save or close the modified buffers of the tab before applying a layout")
+		}
+	}
+
+	// the layout is made from an empty pane, and the current pane then
+	// takes the place of the split of the current buffer
+	e := h.VSplitIndex(buffer.NewBufferFromString("", "", buffer.BTDefault), true)
+	for _, p := range append([]Pane(nil), t.Panes...) {
+		if p != e {
+			t.removePane(p)
+			if p != h {
+				p.Close()
+			}
+		}
+	}
+	active := e.restoreLayout(l)
+
+	root := t.Node
+	for len(root.Children()) == 1 {
+		root = root.Children()[0]
+	}
+	restoreSizes(root, l)
+
+	nodes := root.Leaves()
+	if cur >= len(nodes) {
+		cur = 0
+	}
+	id := nodes[cur].ID()
+	i := t.GetPane(id)
+	if active == nil || active == t.Panes[i] {
+		active = h
+	}
+	t.Panes[i].Close()
+	h.SetID(id)
+	t.Panes[i] = h
+	t.Resize()
+	for j, p := range t.Panes {
+		if p == active {
+			t.SetActive(j)
+		}
+	}
+	return nil
+}
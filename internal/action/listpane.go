@@ -0,0 +1,82 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A listPane is a read-only pane below the one it was opened from, which
+// lists entries one per line, such as the open buffers or the bindings.
+// The panes of the lists embed it and handle their keys with listKey.
+type listPane struct {
+	*BufPane
+
+	// filter fuzzy matches the listed entries, for the lists which can be
+	// filtered
+	filter string
+}
+
+// newListPane returns a list pane for the tab of h, which is opened by
+// openBelow once the pane embedding it is made
+func newListPane(h *BufPane) *listPane {
+	b := buffer.NewBufferFromString("", "", buffer.BTSearch)
+	return &listPane{BufPane: NewBufPaneFromBuf(b, h.tab)}
+}
+
+// openBelow splits h to show p, the pane embedding the list, below it and
+// makes it the active pane
+func (l *listPane) openBelow(h *BufPane, p Pane) {
+	tab := h.tab
+	l.splitID = tab.GetNode(h.splitID).HSplit(true)
+	tab.Panes = append(tab.Panes, p)
+	tab.Resize()
+	tab.SetActive(len(tab.Panes) - 1)
+}
+
+// setList replaces the text of the list and names it, with the filter if
+// there is one. The cursor stays on its line, within the lines first to
+// last of the entries, and the change can't be undone.
+func (l *listPane) setList(name, text string, first, last int) {
+	if l.filter != "" {
+		name += " /" + l.filter
+	}
+	l.Buf.SetName(name)
+	y := l.Cursor.Y
+	l.Buf.EventHandler.Replace(l.Buf.Start(), l.Buf.End(), text)
+	l.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(y, first, util.Max(last, first))})
+	l.Buf.UndoStack = new(buffer.TEStack)
+	l.Buf.RedoStack = new(buffer.TEStack)
+}
+
+// promptFilter asks for the filter, calling rerender as it is typed and
+// with the previous filter again if the prompt is canceled
+func (l *listPane) promptFilter(ptype string, rerender func()) {
+	old := l.filter
+	InfoBar.Prompt("Filter: ", l.filter, ptype, func(resp string) {
+		l.filter = resp
+		rerender()
+	}, func(resp string, canceled bool) {
+		if canceled {
+			l.filter = old
+			rerender()
+		}
+	})
+}
+
+// listKey returns the character typed by the key event without modifiers,
+// '\r' for Enter, or 0 for the other events, which the list passes on to
+// its bufpane
+func listKey(event tcell.Event) rune {
+	e, ok := event.(*tcell.EventKey)
+	if !ok || e.Modifiers() != tcell.ModNone {
+		return 0
+	}
+	switch e.Key() {
+	case tcell.KeyEnter:
+		return '\r'
+	case tcell.KeyRune:
+		return e.Rune()
+	}
+	return 0
+}
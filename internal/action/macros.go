@@ -0,0 +1,345 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// A macroStep is a step of a macro: text typed in the buffer, or an action
+// along with the characters it asked for, such as the pair of Surround
+type macroStep struct {
+	text   string
+	action string
+	args   []rune
+}
+
+// MacroCmds are the subcommands of the macro command
+var MacroCmds = []string{"record", "stop", "play", "edit", "delete"}
+
+// defaultMacro is the name of the macro recorded by ToggleMacro
+const defaultMacro = "default"
+
+// macroNameRegex matches the valid macro names, which are used as file names
+var macroNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+var (
+	// curmacro is the macro being recorded, or the last one recorded, and
+	// macroName its name
+	curmacro        []macroStep
+	macroName       = defaultMacro
+	recording_macro bool
+	// lastMacro is the macro played by PlayMacro, the last one recorded or
+	// played
+	lastMacro = defaultMacro
+	// macros are the macros recorded in this session, for when they can't
+	// be stored
+	macros = make(map[string][]macroStep)
+)
+
+// appendStep adds a step to steps, as part of their last step if both are
+// typed text
+func appendStep(steps []macroStep, s macroStep) []macroStep {
+	if n := len(steps); n > 0 && steps[n-1].action == "" && s.action == "" {
+		steps[n-1].text += s.text
+		return steps
+	}
+	return append(steps, s)
+}
+
+// recordRune adds a typed character to the macro being recorded
+func recordRune(r rune) {
+	curmacro = appendStep(curmacro, macroStep{text: string(r)})
+}
+
+// recordAction adds an action, and the characters it asked for, to the macro
+// being recorded
+func recordAction(name string, args ...rune) {
+	curmacro = appendStep(curmacro, macroStep{action: name, args: args})
+}
+
+// ToggleMacro toggles recording of a macro
+func (h *BufPane) ToggleMacro() bool {
+	if recording_macro {
+		h.stopMacro()
+	} else {
+		h.startMacro(defaultMacro)
+	}
+	h.Relocate()
+	return true
+}
+
+func (h *BufPane) startMacro(name string) {
+	recording_macro = true
+	curmacro = []macroStep{}
+	macroName = name
+	InfoBar.Message("Recording")
+}
+
+func (h *BufPane) stopMacro() {
+	recording_macro = false
+	lastMacro = macroName
+	macros[macroName] = curmacro
+	if err := saveMacro(macroName, curmacro); err != nil {
+		InfoBar.Error("Stopped recording, error saving the macro: ", err)
+		return
+	}
+	InfoBar.Message("Stopped recording")
+}
+
+// PlayMacro plays back the most recently recorded or played macro
+func (h *BufPane) PlayMacro() bool {
+	if recording_macro {
+		return false
+	}
+	steps, err := loadMacro(lastMacro)
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	h.playMacro(steps, 1)
+	return true
+}
+
+// playMacro plays the steps of a macro count times. The edits it makes are
+// not recorded for Repeat.
+func (h *BufPane) playMacro(steps []macroStep, count int) {
+	replaying = true
+	defer func() {
+		replaying = false
+	}()
+	for i := 0; i < count; i++ {
+		for _, s := range steps {
+			if s.action == "" {
+				for _, r := range s.text {
+					h.DoRuneInsert(r)
+				}
+			} else if a, ok := runeActions[s.action]; ok {
+				a.apply(h, s.args)
+			} else if _, ok := BufKeyActions[s.action]; ok {
+				h.runAction(s.action)
+			}
+		}
+	}
+	h.Relocate()
+}
+
+// runAction runs the action name for every cursor as if its key was pressed
+func (h *BufPane) runAction(name string) {
+	f := BufKeyActions[name]
+	for j, c := range h.Buf.GetCursors() {
+		h.Buf.SetCurCursor(c.Num)
+		h.Cursor = c
+		h.execAction(f, name, j)
+	}
+}
+
+// macroPath returns the file storing the macro name
+func macroPath(name string) string {
+	return filepath.Join(config.DataDir, "macros", name)
+}
+
+// saveMacro stores a macro in the macros directory of the configuration,
+// as one step per line
+func saveMacro(name string, steps []macroStep) error {
+	if config.DataDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(macroPath(name)), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(macroPath(name), []byte(formatMacro(steps)), 0644)
+}
+
+// loadMacro returns the steps of the macro name. The stored macro is read
+// every time since it may have been edited.
+func loadMacro(name string) ([]macroStep, error) {
+	if config.DataDir != "" {
+		data, err := ioutil.ReadFile(macroPath(name))
+		if err == nil {
+			return parseMacro(string(data))
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if steps, ok := macros[name]; ok {
+		return steps, nil
+	}
+	return nil, errors.New("No macro named " + name)
+}
+
+// formatMacro returns the text of a macro, one step per line: a quoted
+// string for typed text, or the name of an action followed by the quoted
+// characters it asked for
+func formatMacro(steps []macroStep) string {
+	var sb strings.Builder
+	for _, s := range steps {
+		if s.action == "" {
+			sb.WriteString(strconv.Quote(s.text))
+		} else {
+			sb.WriteString(s.action)
+			for _, r := range s.args {
+				sb.WriteString(" " + strconv.QuoteRune(r))
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// parseMacro parses the text of a macro written by formatMacro. Empty lines
+// and the lines starting with # are ignored.
+func parseMacro(text string) ([]macroStep, error) {
+	var steps []macroStep
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		step, err := parseMacroStep(line)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d of the macro: %v", i+1, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseMacroStep(line string) (macroStep, error) {
+	if strings.HasPrefix(line, "\"") {
+		text, err := strconv.Unquote(line)
+		if err != nil {
+			return macroStep{}, errors.New("invalid text " + line)
+		}
+		return macroStep{text: text}, nil
+	}
+	name := line
+	rest := ""
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		name, rest = line[:i], strings.TrimSpace(line[i:])
+	}
+	_, isRune := runeActions[name]
+	if _, ok := BufKeyActions[name]; !ok && !isRune {
+		return macroStep{}, errors.New("unknown action " + name)
+	}
+	step := macroStep{action: name}
+	for rest != "" {
+		if rest[0] != '\'' {
+			return macroStep{}, errors.New("expected a quoted character: " + rest)
+		}
+		r, _, tail, err := strconv.UnquoteChar(rest[1:], '\'')
+		if err != nil || !strings.HasPrefix(tail, "'") {
+			return macroStep{}, errors.New("invalid character " + rest)
+		}
+		step.args = append(step.args, r)
+		rest = strings.TrimSpace(tail[1:])
+	}
+	// a rune action whose nargs is negative takes any number of characters
+	if a, ok := runeActions[name]; ok && a.nargs < 0 && len(step.args) == 0 {
+		return macroStep{}, errors.New(name + " needs characters")
+	} else if ok && a.nargs >= 0 && len(step.args) != a.nargs {
+		return macroStep{}, fmt.Errorf("%s needs %d characters", name, a.nargs)
+	} else if !ok && len(step.args) > 0 {
+		return macroStep{}, errors.New(name + " takes no characters")
+	}
+	return step, nil
+}
+
+// MacroCmd records, plays, edits or deletes the macros. The subcommands are
+// record, stop, play with a count, edit and delete, followed by the name of
+// the macro, the default one being the macro of ToggleMacro.
+func (h *BufPane) MacroCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Error("Not enough arguments")
+		return
+	}
+	name := defaultMacro
+	if len(args) > 1 {
+		name = args[1]
+	}
+	if !macroNameRegex.MatchString(name) {
+		InfoBar.Error("Invalid macro name " + name)
+		return
+	}
+
+	switch args[0] {
+	case "record":
+		if recording_macro {
+			InfoBar.Error("Already recording the macro " + macroName)
+			return
+		}
+		h.startMacro(name)
+	case "stop":
+		if !recording_macro {
+			InfoBar.Error("No macro is being recorded")
+			return
+		}
+		h.stopMacro()
+	case "play":
+		if recording_macro {
+			InfoBar.Error("Can't play a macro while recording one")
+			return
+		}
+		count := 1
+		if len(args) > 2 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 1 {
+				InfoBar.Error("Invalid count " + args[2])
+				return
+			}
+			count = n
+		}
+		steps, err := loadMacro(name)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		lastMacro = name
+		h.playMacro(steps, count)
+	case "edit":
+		if _, err := loadMacro(name); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		if config.DataDir == "" {
+			InfoBar.Error("Macros can't be stored without a configuration directory")
+			return
+		}
+		if _, err := os.Stat(macroPath(name)); os.IsNotExist(err) {
+			if err := saveMacro(name, macros[name]); err != nil {
+				InfoBar.Error(err)
+				return
+			}
+		}
+		b, err := buffer.NewBufferFromFile(macroPath(name), buffer.BTDefault)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		h.HSplitBuf(b)
+	case "delete":
+		_, err := loadMacro(name)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		delete(macros, name)
+		if config.DataDir != "" {
+			if err := os.Remove(macroPath(name)); err != nil && !os.IsNotExist(err) {
+				InfoBar.Error(err)
+				return
+			}
+		}
+		InfoBar.Message("Deleted macro " + name)
+	default:
+		InfoBar.Error("Unknown subcommand " + args[0])
+	}
+}
@@ -0,0 +1,828 @@
+package action
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/clipboard"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// The modes of the modal editing enabled by the modal option. The keys of
+// the insert mode are those of micro, while the characters of the normal
+// and visual modes are commands in the way of vi.
+const (
+	modeNormal = iota
+	modeInsert
+	modeVisual
+	modeVisualLine
+)
+
+// modeNames are the names of the modes shown by $(mode) in the statusline
+var modeNames = []string{"NORMAL", "INSERT", "VISUAL", "VISUAL LINE"}
+
+const (
+	modalOps     = "dcy<>"
+	modalMotions = "hjkl+-wbeWBE0^$G{}%;,"
+	normalCmds   = "xXDCsSYpPJ~iaIAoOvVnN/:.u"
+	visualCmds   = "dxcsy<>~uUJpovV:"
+	textObjects  = "wW\"'`()b{}B[]<>t"
+)
+
+// A modalCmd is a command of the normal or visual mode: an operator such as
+// d followed by a motion, a text object or the operator again, a motion
+// alone, or another command such as x or p, along with a count and a
+// register chosen with "
+type modalCmd struct {
+	register rune
+	// count is 0 without a count
+	count int
+	op    rune
+	// key is the motion, text object or command, such as w, gg, iw or x
+	key string
+	// arg is the character of f, t, F, T and r
+	arg rune
+}
+
+// maxCount is the largest count of a command, which G takes as a line
+// number, and maxRepeat the most times a command repeats its action
+const (
+	maxCount  = 1 << 30
+	maxRepeat = 10000
+)
+
+// n returns the times the command repeats its action
+func (cmd modalCmd) n() int {
+	if cmd.count > 0 {
+		return util.Min(cmd.count, maxRepeat)
+	}
+	return 1
+}
+
+func (cmd modalCmd) isMotion() bool {
+	return cmd.key == "gg" || len(cmd.key) == 1 && strings.Contains(modalMotions+"fFtT", cmd.key)
+}
+
+func (cmd modalCmd) isTextObject() bool {
+	return len(cmd.key) > 1 && (cmd.key[0] == 'i' || cmd.key[0] == 'a')
+}
+
+// The results of parseModal
+const (
+	parsePending = iota
+	parseInvalid
+	parseDone
+)
+
+// parseCount returns the count starting at keys[i], 0 if there is none, and
+// the index of the key after it. The count stops growing at maxCount.
+func parseCount(keys []rune, i int) (int, int) {
+	if i >= len(keys) || keys[i] < '1' || keys[i] > '9' {
+		return 0, i
+	}
+	n := 0
+	for ; i < len(keys) && keys[i] >= '0' && keys[i] <= '9'; i++ {
+		n = util.Min(n*10+int(keys[i]-'0'), maxCount)
+	}
+	return n, i
+}
+
+// parseModal parses the keys typed in the normal mode, or in the visual
+// mode with visual, and returns whether they are a complete command, the
+// start of one or not a command
+func parseModal(keys []rune, visual bool) (modalCmd, int) {
+	var cmd modalCmd
+	i := 0
+	if keys[0] == '"' {
+		if len(keys) < 2 {
+			return cmd, parsePending
+		}
+		cmd.register, i = keys[1], 2
+	}
+	cmd.count, i = parseCount(keys, i)
+	if i == len(keys) {
+		return cmd, parsePending
+	}
+	if k := keys[i]; !visual && strings.ContainsRune(modalOps, k) {
+		cmd.op = k
+		var count int
+		count, i = parseCount(keys, i+1)
+		if c := cmd.count; count > 0 && c > maxCount/count {
+			cmd.count = maxCount
+		} else if count > 0 {
+			cmd.count = util.Max(c, 1) * count
+		}
+		if i == len(keys) {
+			return cmd, parsePending
+		}
+		if keys[i] == k {
+			cmd.key = string(k)
+			return cmd, parseDone
+		}
+	}
+
+	rest := keys[i:]
+	k := rest[0]
+	need := 1
+	switch {
+	case k == 'g':
+		if len(rest) > 1 && rest[1] != 'g' {
+			return cmd, parseInvalid
+		}
+		cmd.key, need = "gg", 2
+	case strings.ContainsRune("fFtT", k) || k == 'r' && cmd.op == 0 && !visual:
+		cmd.key, need = string(k), 2
+		if len(rest) > 1 {
+			cmd.arg = rest[1]
+		}
+	case (k == 'i' || k == 'a') && (cmd.op != 0 || visual):
+		if len(rest) > 1 && !strings.ContainsRune(textObjects, rest[1]) {
+			return cmd, parseInvalid
+		}
+		need = 2
+		cmd.key = string(rest)
+	case strings.ContainsRune(modalMotions, k):
+		cmd.key = string(k)
+	case cmd.op == 0 && !visual && strings.ContainsRune(normalCmds, k):
+		cmd.key = string(k)
+	case visual && strings.ContainsRune(visualCmds, k):
+		cmd.key = string(k)
+	default:
+		return cmd, parseInvalid
+	}
+	if len(rest) < need {
+		return cmd, parsePending
+	}
+	return cmd, parseDone
+}
+
+// modal returns whether the keys of the pane go through the modes of the
+// modal option
+func (h *BufPane) modal() bool {
+	return config.GetGlobalOption("modal").(bool) && h.Buf.Type.Kind != buffer.BTInfo.Kind
+}
+
+// modeName returns the name of the mode of the pane, or "" without the
+// modal option
+func (h *BufPane) modeName() string {
+	if !h.modal() {
+		if h.isOverwriteMode {
+			return "OVERWRITE"
+		}
+		return ""
+	}
+	if h.mode == modeInsert && h.isOverwriteMode {
+		return "REPLACE"
+	}
+	return modeNames[h.mode]
+}
+
+// modalKeyEvent handles a key of the modal editing, and returns false for
+// the keys left to the bindings: all the keys of the insert mode but
+// Escape, and in the other modes the keys such as the arrows or Ctrl-s which
+// aren't characters.
+func (h *BufPane) modalKeyEvent(e *tcell.EventKey) bool {
+	if !h.modal() {
+		return false
+	}
+	if h.mode == modeInsert {
+		if e.Key() != tcell.KeyEscape {
+			return false
+		}
+		h.runAction("NormalMode")
+		return true
+	}
+
+	var r rune
+	switch e.Key() {
+	case tcell.KeyRune:
+		if e.Modifiers()&^tcell.ModShift != 0 {
+			return false
+		}
+		r = e.Rune()
+	case tcell.KeyEnter:
+		// a key for the argument of f or r, and otherwise the + motion
+		r = '\n'
+		if len(h.modalKeys) > 0 {
+			break
+		}
+		r = '+'
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		r = 'h'
+	case tcell.KeyCtrlR:
+		h.modalKeys = nil
+		h.runAction("Redo")
+		return true
+	case tcell.KeyEscape:
+		if len(h.modalKeys) > 0 {
+			h.modalKeys = nil
+		} else if h.mode != modeNormal {
+			h.exitVisual()
+		}
+		return true
+	case tcell.KeyTab:
+		return true
+	default:
+		return false
+	}
+	h.modalKey(r)
+	return true
+}
+
+// modalKey adds a key to the command being typed in the normal or visual
+// mode, and runs the command once it is complete
+func (h *BufPane) modalKey(r rune) {
+	h.modalKeys = append(h.modalKeys, r)
+	cmd, status := parseModal(h.modalKeys, h.mode != modeNormal)
+	if status == parsePending {
+		return
+	}
+	keys := h.modalKeys
+	h.modalKeys = nil
+	if status == parseDone {
+		h.runModal(keys, cmd)
+	}
+}
+
+// runModal runs a complete command typed with keys, and records it for
+// macros and for Repeat. The changes of the visual mode are not repeated.
+func (h *BufPane) runModal(keys []rune, cmd modalCmd) {
+	if cmd.register != 0 {
+		reg, ok := clipboard.NamedRegister(cmd.register)
+		if !ok {
+			InfoBar.Error("Invalid register: ", string(cmd.register))
+			return
+		}
+		h.register = reg
+		defer func() {
+			h.register = 0
+		}()
+	}
+
+	if cmd.key == "." {
+		for i := 0; i < cmd.n(); i++ {
+			h.Repeat()
+		}
+		if h.mode == modeInsert {
+			h.runAction("NormalMode")
+		}
+		h.Relocate()
+		return
+	}
+
+	b, edits := h.Buf, h.Buf.Edits()
+	mode := h.mode
+	if cmd.key == "u" && cmd.op == 0 && mode == modeNormal {
+		for i := 0; i < cmd.n(); i++ {
+			h.Undo()
+		}
+		h.clampCursors()
+	} else {
+		b.UndoGroup(func() {
+			h.execModal(cmd)
+		})
+	}
+
+	if recording_macro {
+		recordAction("ModalKeys", keys...)
+	}
+	// every command of the normal mode is a group of edits of its own,
+	// along with the text typed after it in the insert mode
+	step := macroStep{action: "ModalKeys", args: keys}
+	editing = false
+	switch {
+	case mode != modeNormal:
+	case h.mode == modeInsert:
+		startEdit(step)
+	default:
+		h.noteEdit(b, edits, step)
+		editing = false
+	}
+	h.Relocate()
+}
+
+// eachCursor calls f for every cursor, as the current one
+func (h *BufPane) eachCursor(f func(c *buffer.Cursor)) {
+	for _, c := range h.Buf.GetCursors() {
+		h.Buf.SetCurCursor(c.Num)
+		h.Cursor = c
+		f(c)
+	}
+}
+
+func (h *BufPane) execModal(cmd modalCmd) {
+	switch {
+	case cmd.op != 0:
+		h.eachCursor(func(c *buffer.Cursor) {
+			h.operate(c, cmd)
+		})
+	case cmd.isMotion():
+		h.eachCursor(func(c *buffer.Cursor) {
+			loc, _, _, ok := h.motion(c, cmd)
+			if !ok {
+				return
+			}
+			if cmd.key == "j" || cmd.key == "k" {
+				c.Loc = loc
+			} else {
+				c.GotoLoc(loc)
+			}
+		})
+	case cmd.isTextObject():
+		h.eachCursor(func(c *buffer.Cursor) {
+			if start, end, ok := h.textObject(c, cmd.key); ok {
+				h.visualAnchors[c] = start
+				c.GotoLoc(end.Move(-1, h.Buf))
+			}
+		})
+	case h.mode == modeNormal:
+		h.normalCommand(cmd)
+	default:
+		h.visualCommand(cmd)
+	}
+
+	switch h.mode {
+	case modeNormal:
+		h.clampCursors()
+	case modeVisual, modeVisualLine:
+		h.eachCursor(h.updateVisual)
+	}
+	h.Buf.MergeCursors()
+	h.Cursor = h.Buf.GetActiveCursor()
+}
+
+// clampCursors puts the cursors which are past the end of their line on
+// its last character, as the normal mode has no position after it
+func (h *BufPane) clampCursors() {
+	for _, c := range h.Buf.GetCursors() {
+		if l := lineLen(h.Buf, c.Y); c.X >= l && l > 0 {
+			c.X = l - 1
+		}
+	}
+}
+
+// normalCommand runs a command of the normal mode which is neither a
+// motion nor an operator
+func (h *BufPane) normalCommand(cmd modalCmd) {
+	b := h.Buf
+	n := cmd.n()
+	switch cmd.key {
+	case "v", "V":
+		h.mode = modeVisual
+		if cmd.key == "V" {
+			h.mode = modeVisualLine
+		}
+		h.visualAnchors = make(map[*buffer.Cursor]buffer.Loc)
+		for _, c := range b.GetCursors() {
+			h.visualAnchors[c] = c.Loc
+		}
+	case "n", "N":
+		name := "FindNext"
+		if cmd.key == "N" {
+			name = "FindPrevious"
+		}
+		for i := 0; i < n; i++ {
+			h.runAction(name)
+		}
+	case "/":
+		h.Find()
+	case ":":
+		h.CommandMode()
+	case "p", "P":
+		h.eachCursor(func(c *buffer.Cursor) {
+			h.put(c, cmd.key == "P", n)
+		})
+	case "J":
+		h.eachCursor(func(c *buffer.Cursor) {
+			h.joinLines(c, util.Max(n-1, 1))
+		})
+	default:
+		h.eachCursor(func(c *buffer.Cursor) {
+			h.cursorCommand(c, cmd)
+		})
+	}
+}
+
+// cursorCommand runs a command of the normal mode at the cursor c
+func (h *BufPane) cursorCommand(c *buffer.Cursor, cmd modalCmd) {
+	b := h.Buf
+	n := cmd.n()
+	l := lineLen(b, c.Y)
+	at := func(x int) buffer.Loc {
+		return buffer.Loc{X: x, Y: c.Y}
+	}
+	lastLine := util.Min(c.Y+n-1, b.LinesNum()-1)
+	switch cmd.key {
+	case "x":
+		if l > 0 {
+			h.applyOperator(c, 'd', c.Loc, at(util.Min(c.X+n, l)), false)
+		}
+	case "X":
+		if c.X > 0 {
+			h.applyOperator(c, 'd', at(util.Max(c.X-n, 0)), c.Loc, false)
+		}
+	case "D":
+		h.applyOperator(c, 'd', c.Loc, at(l), false)
+	case "C":
+		h.applyOperator(c, 'c', c.Loc, at(l), false)
+	case "s":
+		h.applyOperator(c, 'c', c.Loc, at(util.Min(c.X+n, l)), false)
+	case "S":
+		h.applyOperator(c, 'c', c.Loc, buffer.Loc{X: 0, Y: lastLine}, true)
+	case "Y":
+		h.applyOperator(c, 'y', c.Loc, buffer.Loc{X: 0, Y: lastLine}, true)
+	case "~":
+		end := util.Min(c.X+n, l)
+		h.changeCase(c.Loc, at(end), '~')
+		c.X = end
+	case "r":
+		if c.X+n <= l {
+			x := c.X
+			b.Replace(c.Loc, at(x+n), strings.Repeat(string(cmd.arg), n))
+			c.GotoLoc(at(x + n - 1))
+		}
+	case "i":
+		h.mode = modeInsert
+	case "a":
+		if l > 0 {
+			c.GotoLoc(at(c.X + 1))
+		}
+		h.mode = modeInsert
+	case "I":
+		c.StartOfText()
+		h.mode = modeInsert
+	case "A":
+		c.GotoLoc(at(l))
+		h.mode = modeInsert
+	case "o":
+		c.GotoLoc(at(l))
+		h.InsertNewline()
+		h.mode = modeInsert
+	case "O":
+		indent := util.GetLeadingWhitespace(b.LineBytes(c.Y))
+		y := c.Y
+		b.Insert(at(0), string(indent)+"\n")
+		c.GotoLoc(buffer.Loc{X: util.CharacterCount(indent), Y: y})
+		h.mode = modeInsert
+	}
+}
+
+// visualCommand runs a command of the visual mode on the selection of every
+// cursor, then goes back to the normal mode
+func (h *BufPane) visualCommand(cmd modalCmd) {
+	switch cmd.key {
+	case "v", "V":
+		mode := modeVisual
+		if cmd.key == "V" {
+			mode = modeVisualLine
+		}
+		if h.mode == mode {
+			h.exitVisual()
+		} else {
+			h.mode = mode
+		}
+		return
+	case "o":
+		h.eachCursor(func(c *buffer.Cursor) {
+			anchor := h.visualAnchors[c]
+			h.visualAnchors[c] = c.Loc
+			c.GotoLoc(anchor)
+		})
+		return
+	case ":":
+		// the command applies to the selection
+		h.mode = modeNormal
+		h.visualAnchors = nil
+		h.CommandMode()
+		return
+	case "p":
+		h.runAction("Paste")
+		h.exitVisual()
+		return
+	}
+
+	linewise := h.mode == modeVisualLine
+	h.eachCursor(func(c *buffer.Cursor) {
+		start, end := h.visualRange(c)
+		y1, y2 := start.Y, end.Y
+		if linewise && end.X == 0 && y2 > y1 {
+			y2--
+		}
+		lines := func(op rune) {
+			h.applyOperator(c, op, buffer.Loc{X: 0, Y: y1}, buffer.Loc{X: 0, Y: y2}, true)
+		}
+		c.ResetSelection()
+		switch cmd.key {
+		case "d", "x", "c", "s", "y":
+			op := rune(cmd.key[0])
+			switch op {
+			case 'x':
+				op = 'd'
+			case 's':
+				op = 'c'
+			}
+			if linewise {
+				lines(op)
+			} else {
+				h.applyOperator(c, op, start, end, false)
+			}
+		case ">", "<":
+			lines(rune(cmd.key[0]))
+		case "~", "u", "U":
+			h.changeCase(start, end, rune(cmd.key[0]))
+			c.GotoLoc(start)
+		case "J":
+			c.GotoLoc(buffer.Loc{X: 0, Y: y1})
+			h.joinLines(c, util.Max(y2-y1, 1))
+		}
+	})
+	if h.mode != modeInsert {
+		h.exitVisual()
+	}
+	h.visualAnchors = nil
+}
+
+// visualRange returns the text selected by the cursor c in the visual
+// modes, from its anchor to the character under it, or the whole lines
+// between them in the visual line mode
+func (h *BufPane) visualRange(c *buffer.Cursor) (buffer.Loc, buffer.Loc) {
+	start, ok := h.visualAnchors[c]
+	if !ok {
+		start = c.Loc
+	}
+	end := c.Loc
+	if end.LessThan(start) {
+		start, end = end, start
+	}
+	if h.mode == modeVisualLine {
+		return buffer.Loc{X: 0, Y: start.Y}, h.lineEnd(end.Y)
+	}
+	return start, end.Move(1, h.Buf)
+}
+
+func (h *BufPane) updateVisual(c *buffer.Cursor) {
+	start, end := h.visualRange(c)
+	c.SetSelectionStart(start)
+	c.SetSelectionEnd(end)
+}
+
+func (h *BufPane) exitVisual() {
+	h.mode = modeNormal
+	h.visualAnchors = nil
+	for _, c := range h.Buf.GetCursors() {
+		c.ResetSelection()
+	}
+	h.clampCursors()
+}
+
+// lineEnd returns the start of the line after y, or the end of the buffer
+// for the last line
+func (h *BufPane) lineEnd(y int) buffer.Loc {
+	if y+1 < h.Buf.LinesNum() {
+		return buffer.Loc{X: 0, Y: y + 1}
+	}
+	return h.Buf.End()
+}
+
+// operate applies the operator of cmd at the cursor c to the text its
+// motion moves over, to its text object, or to count lines for a doubled
+// operator such as dd
+func (h *BufPane) operate(c *buffer.Cursor, cmd modalCmd) {
+	b := h.Buf
+	if cmd.key == string(cmd.op) {
+		y2 := util.Min(c.Y+cmd.n()-1, b.LinesNum()-1)
+		h.applyOperator(c, cmd.op, c.Loc, buffer.Loc{X: 0, Y: y2}, true)
+		return
+	}
+	if cmd.isTextObject() {
+		if start, end, ok := h.textObject(c, cmd.key); ok {
+			h.applyOperator(c, cmd.op, start, end, false)
+		}
+		return
+	}
+
+	// cw changes the word as ce, without the whitespace after it
+	if cmd.op == 'c' && (cmd.key == "w" || cmd.key == "W") && charClass(newWalker(b, c.Loc).char(), false) != 0 {
+		if cmd.key == "w" {
+			cmd.key = "e"
+		} else {
+			cmd.key = "E"
+		}
+	}
+	loc, linewise, inclusive, ok := h.motion(c, cmd)
+	if !ok {
+		return
+	}
+	start, end := c.Loc, loc
+	if end.LessThan(start) {
+		start, end = end, start
+	}
+	if linewise {
+		h.applyOperator(c, cmd.op, start, end, true)
+		return
+	}
+	if inclusive {
+		end.X = util.Min(end.X+1, lineLen(b, end.Y))
+	}
+	if (cmd.key == "w" || cmd.key == "W") && end.Y > start.Y && end.X <= firstNonBlank(b, end.Y) {
+		// dw at the last word of a line stops at its end
+		end = buffer.Loc{X: lineLen(b, end.Y-1), Y: end.Y - 1}
+	}
+	if start.LessThan(end) {
+		h.applyOperator(c, cmd.op, start, end, false)
+	}
+}
+
+// applyOperator applies the operator op at the cursor c to the text from
+// start to end, or to the lines from the line of start to the line of end
+// with linewise. It deletes (d), changes (c), yanks (y) or shifts (> and <)
+// the text. The text deleted, changed or yanked goes to the register of the
+// pane, ending with a line break when it is made of lines.
+func (h *BufPane) applyOperator(c *buffer.Cursor, op rune, start, end buffer.Loc, linewise bool) {
+	b := h.Buf
+	c.ResetSelection()
+	if op == '>' || op == '<' {
+		y2 := end.Y
+		if !linewise && end.X == 0 && y2 > start.Y {
+			y2--
+		}
+		for y := start.Y; y <= y2; y++ {
+			h.shiftLine(y, op == '>')
+		}
+		c.GotoLoc(buffer.Loc{X: 0, Y: start.Y})
+		c.StartOfText()
+		return
+	}
+
+	if !linewise {
+		h.yank(c, string(b.Substr(start, end)))
+		if op != 'y' {
+			b.Remove(start, end)
+		}
+		c.GotoLoc(start)
+		if op == 'c' {
+			h.mode = modeInsert
+		}
+		return
+	}
+
+	y1, y2 := start.Y, end.Y
+	from, to := buffer.Loc{X: 0, Y: y1}, h.lineEnd(y2)
+	text := string(b.Substr(from, to))
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	h.yank(c, text)
+	switch op {
+	case 'y':
+		if y1 != c.Y {
+			c.GotoLoc(from)
+			c.StartOfText()
+		}
+	case 'd':
+		if to == b.End() && y1 > 0 {
+			// the line break before the last line goes along with it
+			from = buffer.Loc{X: lineLen(b, y1-1), Y: y1 - 1}
+		}
+		b.Remove(from, to)
+		c.GotoLoc(buffer.Loc{X: 0, Y: util.Min(y1, b.LinesNum()-1)})
+		c.StartOfText()
+	case 'c':
+		indent := util.GetLeadingWhitespace(b.LineBytes(y1))
+		b.Replace(from, buffer.Loc{X: lineLen(b, y2), Y: y2}, string(indent))
+		c.GotoLoc(buffer.Loc{X: util.CharacterCount(indent), Y: y1})
+		h.mode = modeInsert
+	}
+}
+
+// yank writes text to the register of the pane, for the cursor c
+func (h *BufPane) yank(c *buffer.Cursor, text string) {
+	clipboard.WriteMulti(text, h.clipRegister(), c.Order(), h.Buf.NumCursors())
+	h.freshClip = false
+}
+
+// put pastes the register count times after the cursor c, or before it
+// with before. Lines, whose text ends with a line break, go below or above
+// the line of c.
+func (h *BufPane) put(c *buffer.Cursor, before bool, count int) {
+	b := h.Buf
+	clip, err := clipboard.ReadMulti(h.clipRegister(), c.Order(), b.NumCursors())
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if clip == "" {
+		return
+	}
+	text := strings.Repeat(clip, count)
+	if strings.HasSuffix(clip, "\n") {
+		y := c.Y
+		if !before {
+			y++
+		}
+		if y < b.LinesNum() {
+			b.Insert(buffer.Loc{X: 0, Y: y}, text)
+		} else {
+			b.Insert(b.End(), "\n"+strings.TrimSuffix(text, "\n"))
+		}
+		c.GotoLoc(buffer.Loc{X: 0, Y: y})
+		c.StartOfText()
+		return
+	}
+	loc := c.Loc
+	if !before && lineLen(b, c.Y) > 0 {
+		loc.X++
+	}
+	b.Insert(loc, text)
+	c.GotoLoc(loc.Move(util.CharacterCountInString(text)-1, b))
+}
+
+// joinLines joins the line of the cursor c with the n lines after it, as J
+// does, separating them by a space unless a line is empty or the next one
+// starts with a closing parenthesis
+func (h *BufPane) joinLines(c *buffer.Cursor, n int) {
+	b := h.Buf
+	for i := 0; i < n && c.Y+1 < b.LinesNum(); i++ {
+		line, next := b.LineBytes(c.Y), b.LineBytes(c.Y+1)
+		indent := util.GetLeadingWhitespace(next)
+		rest := next[len(indent):]
+		sep := " "
+		if len(line) == 0 || len(rest) == 0 || rest[0] == ')' || util.IsWhitespace(rune(line[len(line)-1])) {
+			sep = ""
+		}
+		x := util.CharacterCount(line)
+		b.Replace(buffer.Loc{X: x, Y: c.Y}, buffer.Loc{X: util.CharacterCount(indent), Y: c.Y + 1}, sep)
+		c.GotoLoc(buffer.Loc{X: x, Y: c.Y})
+	}
+}
+
+// changeCase toggles the case of the letters from start to end with ~, or
+// makes them lower case with u or upper case with U
+func (h *BufPane) changeCase(start, end buffer.Loc, key rune) {
+	text := []rune(string(h.Buf.Substr(start, end)))
+	changed := false
+	for i, r := range text {
+		switch {
+		case key == 'U' || key == '~' && unicode.IsLower(r):
+			text[i] = unicode.ToUpper(r)
+		case key == 'u' || key == '~' && unicode.IsUpper(r):
+			text[i] = unicode.ToLower(r)
+		}
+		changed = changed || text[i] != r
+	}
+	if changed {
+		h.Buf.Replace(start, end, string(text))
+	}
+}
+
+// shiftLine indents a non-empty line y by one level, or outdents it by one
+// level, a tab or as many spaces as the tabsize option
+func (h *BufPane) shiftLine(y int, right bool) {
+	b := h.Buf
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	if right {
+		if lineLen(b, y) > 0 {
+			b.Insert(buffer.Loc{X: 0, Y: y}, b.IndentString(tabsize))
+		}
+		return
+	}
+	indent := util.GetLeadingWhitespace(b.LineBytes(y))
+	n := 0
+	if len(indent) > 0 && indent[0] == '\t' {
+		n = 1
+	} else {
+		for n < len(indent) && n < tabsize && indent[n] == ' ' {
+			n++
+		}
+	}
+	if n > 0 {
+		b.Remove(buffer.Loc{X: 0, Y: y}, buffer.Loc{X: n, Y: y})
+	}
+}
+
+// NormalMode goes back to the normal mode of the modal option, moving the
+// cursor back onto the last character inserted
+func (h *BufPane) NormalMode() bool {
+	if !h.modal() {
+		return false
+	}
+	h.mode = modeNormal
+	h.Cursor.ResetSelection()
+	if h.Cursor.X > 0 {
+		h.Cursor.Left()
+	}
+	h.Relocate()
+	return true
+}
+
+// applyModalKeys runs the commands typed with keys in the normal and visual
+// modes, which is how macros and Repeat replay them
+func (h *BufPane) applyModalKeys(keys []rune) bool {
+	for _, r := range keys {
+		h.modalKey(r)
+	}
+	return true
+}
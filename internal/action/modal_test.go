@@ -0,0 +1,136 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+)
+
+func init() {
+	ulua.L = lua.NewState()
+	config.InitGlobalSettings()
+	config.GlobalSettings["backup"] = false
+	config.GlobalSettings["fastdirty"] = true
+}
+
+func TestParseModal(t *testing.T) {
+	tests := []struct {
+		keys   string
+		visual bool
+		cmd    modalCmd
+		result int
+	}{
+		{"x", false, modalCmd{key: "x"}, parseDone},
+		{"3x", false, modalCmd{count: 3, key: "x"}, parseDone},
+		{"10j", false, modalCmd{count: 10, key: "j"}, parseDone},
+		{"0", false, modalCmd{key: "0"}, parseDone},
+		{"3", false, modalCmd{count: 3}, parsePending},
+
+		// registers
+		{`"`, false, modalCmd{}, parsePending},
+		{`"a`, false, modalCmd{register: 'a'}, parsePending},
+		{`"ayy`, false, modalCmd{register: 'a', op: 'y', key: "y"}, parseDone},
+		{`"+2p`, false, modalCmd{register: '+', count: 2, key: "p"}, parseDone},
+
+		// operators, with the counts before and after them multiplied
+		{"d", false, modalCmd{op: 'd'}, parsePending},
+		{"dd", false, modalCmd{op: 'd', key: "d"}, parseDone},
+		{"3dd", false, modalCmd{count: 3, op: 'd', key: "d"}, parseDone},
+		{"d2w", false, modalCmd{count: 2, op: 'd', key: "w"}, parseDone},
+		{"2d3w", false, modalCmd{count: 6, op: 'd', key: "w"}, parseDone},
+		{"d2", false, modalCmd{count: 2, op: 'd'}, parsePending},
+		{"dgg", false, modalCmd{op: 'd', key: "gg"}, parseDone},
+		{"dt)", false, modalCmd{op: 'd', key: "t", arg: ')'}, parseDone},
+		{"dx", false, modalCmd{op: 'd'}, parseInvalid},
+		{"dy", false, modalCmd{op: 'd'}, parseInvalid},
+
+		// text objects
+		{"di", false, modalCmd{op: 'd', key: "i"}, parsePending},
+		{"diw", false, modalCmd{op: 'd', key: "iw"}, parseDone},
+		{`ca"`, false, modalCmd{op: 'c', key: `a"`}, parseDone},
+		{"diq", false, modalCmd{op: 'd'}, parseInvalid},
+		{"iw", true, modalCmd{key: "iw"}, parseDone},
+		{"i", false, modalCmd{key: "i"}, parseDone},
+
+		// the commands taking a character
+		{"g", false, modalCmd{key: "gg"}, parsePending},
+		{"gg", false, modalCmd{key: "gg"}, parseDone},
+		{"gx", false, modalCmd{}, parseInvalid},
+		{"f", false, modalCmd{key: "f"}, parsePending},
+		{"2fa", false, modalCmd{count: 2, key: "f", arg: 'a'}, parseDone},
+		{"rx", false, modalCmd{key: "r", arg: 'x'}, parseDone},
+		{"r", true, modalCmd{}, parseInvalid},
+
+		// the commands of the visual mode, where d is not an operator
+		{"d", true, modalCmd{key: "d"}, parseDone},
+		{"U", true, modalCmd{key: "U"}, parseDone},
+		{"U", false, modalCmd{}, parseInvalid},
+		{"q", false, modalCmd{}, parseInvalid},
+	}
+	for _, test := range tests {
+		cmd, result := parseModal([]rune(test.keys), test.visual)
+		assert.Equal(t, test.result, result, test.keys)
+		if result != parseInvalid {
+			assert.Equal(t, test.cmd, cmd, test.keys)
+		}
+	}
+
+	// the counts are capped, and the times a command repeats even more so
+	cmd, _ := parseModal([]rune("999999999x"), false)
+	assert.Equal(t, 999999999, cmd.count)
+	assert.Equal(t, maxRepeat, cmd.n())
+	cmd, _ = parseModal([]rune("99999999999999999999G"), false)
+	assert.Equal(t, maxCount, cmd.count)
+	cmd, _ = parseModal([]rune("99999d99999w"), false)
+	assert.Equal(t, maxCount, cmd.count)
+}
+
+func TestModalMotions(t *testing.T) {
+	b := buffer.NewBufferFromString("foo.bar baz(qux)\n\n  last line", "", buffer.BTDefault)
+	loc := func(x, y int) buffer.Loc {
+		return buffer.Loc{X: x, Y: y}
+	}
+
+	assert.Equal(t, loc(3, 0), wordForward(b, loc(0, 0), false))
+	assert.Equal(t, loc(8, 0), wordForward(b, loc(0, 0), true))
+	assert.Equal(t, loc(15, 0), wordForward(b, loc(12, 0), false))
+	// an empty line is a word
+	assert.Equal(t, loc(0, 1), wordForward(b, loc(15, 0), false))
+	assert.Equal(t, loc(2, 2), wordForward(b, loc(0, 1), false))
+
+	assert.Equal(t, loc(2, 0), wordEnd(b, loc(0, 0), false))
+	assert.Equal(t, loc(6, 0), wordEnd(b, loc(0, 0), true))
+	assert.Equal(t, loc(5, 2), wordEnd(b, loc(15, 0), false))
+
+	assert.Equal(t, loc(4, 0), wordBackward(b, loc(7, 0), false))
+	assert.Equal(t, loc(0, 0), wordBackward(b, loc(7, 0), true))
+	assert.Equal(t, loc(0, 1), wordBackward(b, loc(2, 2), false))
+
+	l, ok := findChar(b, loc(0, 0), "f", 'a', 2)
+	assert.True(t, ok)
+	assert.Equal(t, loc(9, 0), l)
+	l, ok = findChar(b, loc(0, 0), "t", 'a', 1)
+	assert.True(t, ok)
+	assert.Equal(t, loc(4, 0), l)
+	_, ok = findChar(b, loc(0, 0), "f", 'z', 3)
+	assert.False(t, ok)
+	l, ok = findChar(b, loc(15, 0), "F", 'b', 1)
+	assert.True(t, ok)
+	assert.Equal(t, loc(8, 0), l)
+
+	assert.Equal(t, loc(0, 1), paragraphForward(b, loc(0, 0)))
+	assert.Equal(t, loc(11, 2), paragraphForward(b, loc(0, 1)))
+	assert.Equal(t, loc(0, 1), paragraphBackward(b, loc(3, 2)))
+	assert.Equal(t, loc(0, 0), paragraphBackward(b, loc(0, 1)))
+
+	l, ok = matchBrace(b, loc(0, 0))
+	assert.True(t, ok)
+	assert.Equal(t, loc(15, 0), l)
+	l, ok = matchBrace(b, loc(15, 0))
+	assert.True(t, ok)
+	assert.Equal(t, loc(11, 0), l)
+}
@@ -0,0 +1,414 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A walker moves through a buffer character by character, the end of each
+// line being a line break
+type walker struct {
+	b   *buffer.Buffer
+	loc buffer.Loc
+	// line holds the characters of line y
+	y    int
+	line []rune
+}
+
+func newWalker(b *buffer.Buffer, loc buffer.Loc) *walker {
+	return &walker{b: b, loc: loc, y: -1}
+}
+
+func (w *walker) runes() []rune {
+	if w.y != w.loc.Y {
+		w.line = []rune(string(w.b.LineBytes(w.loc.Y)))
+		w.y = w.loc.Y
+	}
+	return w.line
+}
+
+// char returns the character at the location, '\n' at the end of a line
+func (w *walker) char() rune {
+	if l := w.runes(); w.loc.X < len(l) {
+		return l[w.loc.X]
+	}
+	return '\n'
+}
+
+// emptyLine returns whether the location is on an empty line
+func (w *walker) emptyLine() bool {
+	return len(w.runes()) == 0
+}
+
+func (w *walker) next() bool {
+	if w.loc.X < len(w.runes()) {
+		w.loc.X++
+		return true
+	}
+	if w.loc.Y+1 < w.b.LinesNum() {
+		w.loc = buffer.Loc{X: 0, Y: w.loc.Y + 1}
+		return true
+	}
+	return false
+}
+
+func (w *walker) prev() bool {
+	if w.loc.X > 0 {
+		w.loc.X--
+		return true
+	}
+	if w.loc.Y > 0 {
+		w.loc.Y--
+		w.loc.X = len(w.runes())
+		return true
+	}
+	return false
+}
+
+// charClass returns the class of the characters of a word: 0 for
+// whitespace, 1 for word characters and 2 for punctuation, which is part of
+// the words with bigWord, the WORDs of W, B and E
+func charClass(r rune, bigWord bool) int {
+	switch {
+	case r == ' ' || r == '\t' || r == '\n':
+		return 0
+	case bigWord || util.IsWordChar(r):
+		return 1
+	}
+	return 2
+}
+
+// wordForward returns the start of the word after loc, an empty line being
+// a word
+func wordForward(b *buffer.Buffer, loc buffer.Loc, bigWord bool) buffer.Loc {
+	w := newWalker(b, loc)
+	if cls := charClass(w.char(), bigWord); cls != 0 {
+		for charClass(w.char(), bigWord) == cls {
+			if !w.next() {
+				return w.loc
+			}
+		}
+	}
+	for charClass(w.char(), bigWord) == 0 {
+		if w.emptyLine() && w.loc != loc {
+			break
+		}
+		if !w.next() {
+			break
+		}
+	}
+	return w.loc
+}
+
+// wordEnd returns the end of the word at or after the character after loc
+func wordEnd(b *buffer.Buffer, loc buffer.Loc, bigWord bool) buffer.Loc {
+	w := newWalker(b, loc)
+	if !w.next() {
+		return loc
+	}
+	for charClass(w.char(), bigWord) == 0 {
+		if !w.next() {
+			return w.loc
+		}
+	}
+	cls := charClass(w.char(), bigWord)
+	for {
+		end := w.loc
+		if !w.next() || charClass(w.char(), bigWord) != cls {
+			return end
+		}
+	}
+}
+
+// wordBackward returns the start of the word before loc, an empty line
+// being a word
+func wordBackward(b *buffer.Buffer, loc buffer.Loc, bigWord bool) buffer.Loc {
+	w := newWalker(b, loc)
+	if !w.prev() {
+		return loc
+	}
+	for charClass(w.char(), bigWord) == 0 {
+		if w.emptyLine() || !w.prev() {
+			return w.loc
+		}
+	}
+	cls := charClass(w.char(), bigWord)
+	for {
+		start := w.loc
+		if !w.prev() || charClass(w.char(), bigWord) != cls {
+			return start
+		}
+	}
+}
+
+func lineLen(b *buffer.Buffer, y int) int {
+	return util.CharacterCount(b.LineBytes(y))
+}
+
+func firstNonBlank(b *buffer.Buffer, y int) int {
+	return util.CharacterCount(util.GetLeadingWhitespace(b.LineBytes(y)))
+}
+
+func isBlankLine(b *buffer.Buffer, y int) bool {
+	return firstNonBlank(b, y) == lineLen(b, y)
+}
+
+// paragraphForward returns the start of the blank line after the paragraph
+// at or after loc, or the end of the buffer
+func paragraphForward(b *buffer.Buffer, loc buffer.Loc) buffer.Loc {
+	y, last := loc.Y, b.LinesNum()-1
+	for y < last && isBlankLine(b, y) {
+		y++
+	}
+	for y < last && !isBlankLine(b, y) {
+		y++
+	}
+	if !isBlankLine(b, y) {
+		return buffer.Loc{X: lineLen(b, y), Y: y}
+	}
+	return buffer.Loc{X: 0, Y: y}
+}
+
+// paragraphBackward returns the start of the blank line before the
+// paragraph at or before loc, or the start of the buffer
+func paragraphBackward(b *buffer.Buffer, loc buffer.Loc) buffer.Loc {
+	y := loc.Y
+	for y > 0 && isBlankLine(b, y) {
+		y--
+	}
+	for y > 0 && !isBlankLine(b, y) {
+		y--
+	}
+	return buffer.Loc{X: 0, Y: y}
+}
+
+// matchBrace returns the start of the bracket or keyword matching the one
+// at loc, or else the bracket matching the first one after loc on its line
+func matchBrace(b *buffer.Buffer, loc buffer.Loc) (buffer.Loc, bool) {
+	if from, to, ok := b.MatchBrace(loc); ok && !loc.LessThan(from[0]) && loc.LessThan(from[1]) {
+		return to[0], true
+	}
+	line := []rune(string(b.LineBytes(loc.Y)))
+	for x := loc.X + 1; x < len(line); x++ {
+		for _, bp := range buffer.BracePairs {
+			if line[x] == bp[0] || line[x] == bp[1] {
+				_, to, ok := b.MatchBrace(buffer.Loc{X: x, Y: loc.Y})
+				return to[0], ok
+			}
+		}
+	}
+	return loc, false
+}
+
+// findChar returns the location of the nth character r after loc on its
+// line for f, before it for F, or next to it on the side of loc for t and T
+func findChar(b *buffer.Buffer, loc buffer.Loc, key string, r rune, n int) (buffer.Loc, bool) {
+	line := []rune(string(b.LineBytes(loc.Y)))
+	step := 1
+	if key == "F" || key == "T" {
+		step = -1
+	}
+	x := loc.X
+	for i := 0; i < n; i++ {
+		x += step
+		for x >= 0 && x < len(line) && line[x] != r {
+			x += step
+		}
+		if x < 0 || x >= len(line) {
+			return loc, false
+		}
+	}
+	if key == "t" || key == "T" {
+		x -= step
+		if x == loc.X {
+			return loc, false
+		}
+	}
+	return buffer.Loc{X: x, Y: loc.Y}, true
+}
+
+// motion returns where the motion of cmd moves the cursor c, whether it
+// moves by lines as j and G do, and whether an operator includes the
+// character it moves to as with e and f. It returns false if the cursor
+// can't move, such as for h at the start of a line.
+func (h *BufPane) motion(c *buffer.Cursor, cmd modalCmd) (loc buffer.Loc, linewise, inclusive, ok bool) {
+	b := h.Buf
+	n := cmd.n()
+	loc = c.Loc
+	last := b.LinesNum() - 1
+	switch cmd.key {
+	case "h":
+		if loc.X == 0 {
+			return loc, false, false, false
+		}
+		loc.X = util.Max(loc.X-n, 0)
+	case "l":
+		l := lineLen(b, loc.Y)
+		if loc.X >= l {
+			return loc, false, false, false
+		}
+		loc.X = util.Min(loc.X+n, l)
+	case "j", "k", "+", "-":
+		y := loc.Y + n
+		if cmd.key == "k" || cmd.key == "-" {
+			y = loc.Y - n
+		}
+		y = util.Clamp(y, 0, last)
+		if y == loc.Y {
+			return loc, false, false, false
+		}
+		if cmd.key == "j" || cmd.key == "k" {
+			// keeps the column the cursor had before going through shorter
+			// lines
+			moved := *c
+			moved.UpN(loc.Y - y)
+			loc = moved.Loc
+		} else {
+			loc = buffer.Loc{X: firstNonBlank(b, y), Y: y}
+		}
+		linewise = true
+	case "0":
+		loc.X = 0
+	case "^":
+		loc.X = firstNonBlank(b, loc.Y)
+	case "$":
+		loc.Y = util.Min(loc.Y+n-1, last)
+		loc.X = util.Max(lineLen(b, loc.Y)-1, 0)
+		inclusive = true
+	case "w", "W":
+		for i := 0; i < n; i++ {
+			loc = wordForward(b, loc, cmd.key == "W")
+		}
+	case "b", "B":
+		for i := 0; i < n; i++ {
+			loc = wordBackward(b, loc, cmd.key == "B")
+		}
+	case "e", "E":
+		for i := 0; i < n; i++ {
+			loc = wordEnd(b, loc, cmd.key == "E")
+		}
+		inclusive = true
+	case "G", "gg":
+		y := last
+		if cmd.key == "gg" {
+			y = 0
+		}
+		if cmd.count > 0 {
+			y = util.Clamp(cmd.count-1, 0, last)
+		}
+		loc = buffer.Loc{X: firstNonBlank(b, y), Y: y}
+		linewise = true
+	case "}":
+		for i := 0; i < n; i++ {
+			loc = paragraphForward(b, loc)
+		}
+	case "{":
+		for i := 0; i < n; i++ {
+			loc = paragraphBackward(b, loc)
+		}
+	case "%":
+		loc, ok = matchBrace(b, loc)
+		return loc, false, true, ok
+	case "f", "t", "F", "T":
+		h.lastFind, h.lastFindChar = cmd.key, cmd.arg
+		loc, ok = findChar(b, loc, cmd.key, cmd.arg, n)
+		return loc, false, cmd.key == "f" || cmd.key == "t", ok
+	case ";", ",":
+		key := h.lastFind
+		if key == "" {
+			return loc, false, false, false
+		}
+		if cmd.key == "," {
+			key = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}[key]
+		}
+		// t and T search from the next character, so as not to stop next
+		// to the character they stopped at
+		from := loc
+		switch key {
+		case "t":
+			from.X++
+		case "T":
+			from.X--
+		}
+		loc, ok = findChar(b, from, key, h.lastFindChar, n)
+		if !ok {
+			loc = c.Loc
+		}
+		return loc, false, key == "f" || key == "t", ok
+	}
+	return loc, linewise, inclusive, true
+}
+
+// wordObject returns the range of the word at loc, along with the
+// whitespace after it, or before it if there is none, for an aw object
+func wordObject(b *buffer.Buffer, loc buffer.Loc, around, bigWord bool) (buffer.Loc, buffer.Loc) {
+	line := []rune(string(b.LineBytes(loc.Y)))
+	if len(line) == 0 {
+		return loc, loc
+	}
+	x := util.Min(loc.X, len(line)-1)
+	class := func(i int) int {
+		return charClass(line[i], bigWord)
+	}
+	cls := class(x)
+	start, end := x, x+1
+	for start > 0 && class(start-1) == cls {
+		start--
+	}
+	for end < len(line) && class(end) == cls {
+		end++
+	}
+	if around {
+		if cls == 0 {
+			// the whitespace along with the word after it
+			if end < len(line) {
+				next := class(end)
+				for end < len(line) && class(end) == next {
+					end++
+				}
+			}
+		} else {
+			e := end
+			for e < len(line) && class(e) == 0 {
+				e++
+			}
+			if e > end {
+				end = e
+			} else {
+				for start > 0 && class(start-1) == 0 {
+					start--
+				}
+			}
+		}
+	}
+	return buffer.Loc{X: start, Y: loc.Y}, buffer.Loc{X: end, Y: loc.Y}
+}
+
+// textObject returns the range of the text object key, such as iw or a(,
+// at the cursor c. The inner objects of brackets, quotes and tags leave out
+// the delimiters, which the around objects include.
+func (h *BufPane) textObject(c *buffer.Cursor, key string) (buffer.Loc, buffer.Loc, bool) {
+	around := key[0] == 'a'
+	r := []rune(key)[1]
+	var start, end buffer.Loc
+	switch r {
+	case 'w', 'W':
+		start, end = wordObject(h.Buf, c.Loc, around, r == 'W')
+	default:
+		switch r {
+		case 'b':
+			r = '('
+		case 'B':
+			r = '{'
+		}
+		open, close, ok := h.Buf.FindSurround(c.Loc, r)
+		if !ok {
+			return start, end, false
+		}
+		start, end = open[1], close[0]
+		if around {
+			start, end = open[0], close[1]
+		}
+	}
+	return start, end, start.LessThan(end)
+}
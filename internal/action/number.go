@@ -0,0 +1,84 @@
+package action
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// IncrementNumber adds the incrementstep option to the number under or
+// after the cursor
+func (h *BufPane) IncrementNumber() bool {
+	return h.addToNumber(h.Cursor, 1)
+}
+
+// DecrementNumber subtracts the incrementstep option from the number under
+// or after the cursor
+func (h *BufPane) DecrementNumber() bool {
+	return h.addToNumber(h.Cursor, -1)
+}
+
+// addToNumber adds count times the incrementstep option to the number of
+// the cursor c
+func (h *BufPane) addToNumber(c *buffer.Cursor, count int64) bool {
+	step := int64(util.IntOpt(h.Buf.Settings["incrementstep"]))
+	if !h.Buf.IncrementNumber(c, count*step) {
+		return false
+	}
+	h.Relocate()
+	return true
+}
+
+// IncrementCmd adds the incrementstep option to the number of every cursor,
+// as many times as the count argument tells. With -s, the second cursor
+// adds twice as much, the third one three times and so on, which numbers
+// a list.
+func (h *BufPane) IncrementCmd(args []string) {
+	h.incrementCursors(args, 1)
+}
+
+// DecrementCmd subtracts from the number of every cursor as IncrementCmd
+// adds to it
+func (h *BufPane) DecrementCmd(args []string) {
+	h.incrementCursors(args, -1)
+}
+
+func (h *BufPane) incrementCursors(args []string, sign int64) {
+	count, sequence := int64(1), false
+	for _, a := range args {
+		if a == "-s" {
+			sequence = true
+			continue
+		}
+		n, err := strconv.ParseInt(a, 10, 64)
+		if err != nil || n < 1 {
+			InfoBar.Error("Invalid count: " + a)
+			return
+		}
+		count = n
+	}
+
+	// the sequence follows the order of the cursors in the buffer
+	cursors := append([]*buffer.Cursor(nil), h.Buf.GetCursors()...)
+	sort.SliceStable(cursors, func(i, j int) bool {
+		return cursors[i].Loc.LessThan(cursors[j].Loc)
+	})
+
+	found := false
+	h.Buf.UndoGroup(func() {
+		for i, c := range cursors {
+			n := count
+			if sequence {
+				n *= int64(i + 1)
+			}
+			if h.addToNumber(c, sign*n) {
+				found = true
+			}
+		}
+	})
+	if !found {
+		InfoBar.Message("No number to change")
+	}
+}
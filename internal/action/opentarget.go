@@ -0,0 +1,130 @@
+package action
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	luar "layeh.com/gopher-luar"
+)
+
+// targetResolvers are the lua functions, given as "plugin.function",
+// which resolve the targets of OpenUnderCursor for a filetype
+var targetResolvers = make(map[string]string)
+
+// SetTargetResolver sets the lua function which resolves the targets of
+// OpenUnderCursor in the buffers of the filetype. It is called with the
+// bufpane, the text of the target and its kind ("url", "file" or
+// "import"), and returns the path of the file or the URL to open, or an
+// empty string to resolve the target as for the other filetypes.
+func SetTargetResolver(ft, fn string) {
+	if fn == "" {
+		delete(targetResolvers, ft)
+		return
+	}
+	targetResolvers[ft] = fn
+}
+
+// resolveTarget returns the file or the URL a target refers to, from the
+// resolver of the filetype if it gives one
+func (h *BufPane) resolveTarget(t buffer.Target) string {
+	if fn, ok := targetResolvers[h.Buf.FileType()]; ok {
+		luaFn := strings.SplitN(fn, ".", 2)
+		if pl := config.FindPlugin(luaFn[0]); pl != nil && len(luaFn) == 2 {
+			val, err := pl.Call(luaFn[1], luar.New(ulua.L, h), luar.New(ulua.L, t.Text), luar.New(ulua.L, t.Kind.String()))
+			if err != nil {
+				InfoBar.Error(err)
+			} else if s, ok := val.(lua.LString); ok && s != "" {
+				return string(s)
+			}
+		}
+	}
+	if t.Kind == buffer.TargetURL {
+		return t.Text
+	}
+	return h.Buf.ResolveTarget(t)
+}
+
+// OpenUnderCursor opens what is under the cursor: a URL in the browser, or
+// a file, which may be followed by ":line:col", or the file included or
+// imported by the line in a new pane
+func (h *BufPane) OpenUnderCursor() bool {
+	t, ok := h.Buf.TargetAt(h.Cursor.Loc)
+	if !ok {
+		InfoBar.Error("Nothing to open under the cursor")
+		return false
+	}
+	target := h.resolveTarget(t)
+	if target == "" {
+		InfoBar.Error("No file found for ", t.Text)
+		return false
+	}
+
+	if isURL(target) {
+		if err := openURL(target); err != nil {
+			InfoBar.Error(err)
+			return false
+		}
+		InfoBar.Message("Opened ", target)
+		return true
+	}
+
+	b, err := buffer.NewBufferFromFile(projectPath("", target), buffer.BTDefault)
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	p := h.VSplitBuf(b)
+	if t.Line > 0 {
+		p.gotoLoc(buffer.Loc{X: t.Col - 1, Y: t.Line - 1})
+	}
+	return true
+}
+
+// isURL returns whether the target of OpenUnderCursor is a URL rather
+// than a path
+func isURL(s string) bool {
+	return strings.Contains(s, "://") || strings.HasPrefix(s, "www.")
+}
+
+// openURL opens a URL with the $BROWSER command if it is set, or else with
+// the command opening URLs on the system
+func openURL(url string) error {
+	if strings.HasPrefix(url, "www.") {
+		url = "http://" + url
+	}
+	var cmd []string
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		args, err := shellquote.Split(browser)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return errors.New("Empty $BROWSER command")
+		}
+		cmd = append(args, url)
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = []string{"open", url}
+		case "windows":
+			cmd = []string{"rundll32", "url.dll,FileProtocolHandler", url}
+		default:
+			cmd = []string{"xdg-open", url}
+		}
+	}
+
+	c := exec.Command(cmd[0], cmd[1:]...)
+	if err := c.Start(); err != nil {
+		return err
+	}
+	go c.Wait()
+	return nil
+}
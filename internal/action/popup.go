@@ -0,0 +1,64 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// CursorPopup shows a popup with the given lines next to the cursor, below
+// it if there is room and above it otherwise. The popup is transient: it is
+// hidden by the next key press, paste or click in the pane. The returned
+// popup may be changed further before the next redraw.
+func (h *BufPane) CursorPopup(lines []string) *display.Popup {
+	h.HidePopup()
+
+	p := display.NewPopup(0, 0, lines)
+	p.Border = true
+	p.Z = 1
+	h.placePopup(p, h.Cursor.Loc)
+
+	p.Show()
+	h.popup = p
+	return p
+}
+
+// placePopup moves the popup next to the location loc of the buffer, below
+// it if there is room for the size of the popup and above it otherwise
+func (h *BufPane) placePopup(p *display.Popup, loc buffer.Loc) {
+	x, y := 0, 0
+	if w, ok := h.BWindow.(*display.BufWindow); ok {
+		x, y, _ = w.ScreenLoc(loc)
+	}
+	p.X, p.Y = x, y+1
+
+	_, ph := p.Size()
+	v := h.GetView()
+	if y+1+ph > v.Y+v.Height && y-ph >= v.Y {
+		p.Y = y - ph
+	}
+}
+
+// HidePopup hides the popup shown by CursorPopup, if any
+func (h *BufPane) HidePopup() {
+	if h.popup != nil {
+		h.popup.Hide()
+		h.popup = nil
+	}
+}
+
+// hidePopupOnEvent hides the transient popup of the pane on the events
+// that dismiss it
+func (h *BufPane) hidePopupOnEvent(event tcell.Event) {
+	if h.popup == nil {
+		return
+	}
+	switch e := event.(type) {
+	case *tcell.EventKey, *tcell.EventPaste, *tcell.EventRaw:
+		h.HidePopup()
+	case *tcell.EventMouse:
+		if e.Buttons() != tcell.ButtonNone {
+			h.HidePopup()
+		}
+	}
+}
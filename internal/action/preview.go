@@ -0,0 +1,127 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A PreviewPane shows the markdown document of the pane it was opened from
+// rendered for the terminal. It is rendered again when the document is
+// edited, and scrolls along with it: when one of the panes is scrolled,
+// the other one shows the same part of the document.
+type PreviewPane struct {
+	*BufPane
+
+	source  *BufPane
+	preview *buffer.Preview
+	// key describes the text which was rendered, and srcLine and line the
+	// start lines of the panes when they were last synchronized
+	key           string
+	srcLine, line int
+}
+
+// TogglePreview opens or closes the markdown preview of the buffer
+func (h *BufPane) TogglePreview() bool {
+	h.PreviewCmd(nil)
+	return true
+}
+
+// PreviewCmd opens a pane on the right of the current one with the
+// markdown preview of its buffer, or closes it if it is open. An image is
+// shown as a picture or in hex instead.
+func (h *BufPane) PreviewCmd(args []string) {
+	if h.Buf.Image != nil {
+		h.Buf.SetOptionNative("imageview", !h.Buf.Settings["imageview"].(bool))
+		return
+	}
+	for _, p := range h.tab.Panes {
+		if pp, ok := p.(*PreviewPane); ok && (pp.source == h || pp.BufPane == h) {
+			closePane(pp.BufPane)
+			return
+		}
+	}
+
+	b := buffer.NewBufferFromString("", "", buffer.BTLog)
+	b.SetName("Preview " + h.Buf.GetName())
+	b.SetOptionNative("softwrap", true)
+	b.SetOptionNative("wordwrap", true)
+	b.SetOptionNative("ruler", false)
+
+	pp := new(PreviewPane)
+	pp.BufPane = NewBufPaneFromBuf(b, h.tab)
+	pp.source = h
+	pp.srcLine, pp.line = -1, -1
+
+	tab := h.tab
+	pp.splitID = tab.GetNode(h.splitID).VSplit(true)
+	tab.Panes = append(tab.Panes, pp)
+	tab.Resize()
+	// the focus stays on the document
+	tab.SetActive(tab.GetPane(h.splitID))
+}
+
+// Display renders the document again if it changed and synchronizes the
+// scrolling before displaying the pane
+func (p *PreviewPane) Display() {
+	if p.sourceOpen() {
+		p.update()
+	}
+	p.BufPane.Display()
+}
+
+// sourceOpen returns whether the pane of the document is still open
+func (p *PreviewPane) sourceOpen() bool {
+	for _, tp := range p.source.tab.Panes {
+		if tp == p.source {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PreviewPane) update() {
+	src := p.source.Buf
+	width := util.Max(p.GetView().Width-1, 1)
+	key := fmt.Sprintf("%p %d %d %d %d", src.SharedBuffer, src.Edits(), src.UndoStack.Len(), src.RedoStack.Len(), width)
+	rendered := key != p.key
+	if rendered {
+		p.key = key
+		lines := make([]string, src.LinesNum())
+		for i := range lines {
+			lines[i] = string(src.LineBytes(i))
+		}
+		p.preview = buffer.RenderMarkdown(lines, width)
+
+		b := p.Buf
+		b.EventHandler.Replace(b.Start(), b.End(), strings.Join(p.preview.Lines, "\n"))
+		b.UndoStack = new(buffer.TEStack)
+		b.RedoStack = new(buffer.TEStack)
+		for i, m := range p.preview.Matches {
+			b.SetMatch(i, m)
+		}
+		p.Buf.SetName("Preview " + src.GetName())
+	}
+
+	srcView, view := p.source.GetView(), p.GetView()
+	switch {
+	case rendered || srcView.StartLine.Line != p.srcLine:
+		// the document was scrolled or edited
+		p.srcLine = srcView.StartLine.Line
+		p.line = p.preview.PreviewLine(p.srcLine)
+		view.StartLine = display.SLoc{Line: p.line, Row: 0}
+		p.Cursor.GotoLoc(buffer.Loc{X: 0, Y: p.line})
+	case view.StartLine.Line != p.line:
+		// the preview was scrolled
+		p.line = view.StartLine.Line
+		if p.line < len(p.preview.Source) {
+			p.srcLine = p.preview.Source[p.line]
+			srcView.StartLine = display.SLoc{Line: p.srcLine, Row: 0}
+			screen.Redraw()
+		}
+	}
+}
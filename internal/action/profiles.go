@@ -0,0 +1,156 @@
+package action
+
+import "github.com/zyedidia/micro/v2/internal/config"
+
+// keyProfiles are the bindings of the keyprofile option by pane type, for
+// the users of other editors. They are bound over the default bindings and
+// under the ones of bindings.json.
+var keyProfiles = map[string]map[string]map[string]string{
+	"emacs": {
+		"buffer": {
+			"Ctrl-f":           "CursorRight",
+			"Ctrl-b":           "CursorLeft",
+			"Ctrl-n":           "CursorDown",
+			"Ctrl-p":           "CursorUp",
+			"Ctrl-a":           "StartOfLine",
+			"Ctrl-e":           "EndOfLine",
+			"Alt-f":            "WordRight",
+			"Alt-b":            "WordLeft",
+			"Alt-{":            "ParagraphPrevious",
+			"Alt-}":            "ParagraphNext",
+			"Alt-a":            "SentencePrevious",
+			"Alt-e":            "SentenceNext",
+			"Alt-q":            "Reflow",
+			"Alt-<":            "CursorStart",
+			"Alt->":            "CursorEnd",
+			"Ctrl-v":           "PageDown",
+			"Alt-v":            "PageUp",
+			"Ctrl-l":           "Center",
+			"Ctrl-d":           "Delete",
+			"Alt-d":            "DeleteWordRight",
+			"Ctrl-k":           "SelectToEndOfLine,Cut",
+			"Ctrl-w":           "Cut",
+			"Alt-w":            "Copy",
+			"Ctrl-y":           "Paste",
+			"CtrlUnderscore":   "Undo",
+			"Ctrl-s":           "Find",
+			"Ctrl-r":           "FindPrevious",
+			"Alt-%":            "command-edit:replace ",
+			"Ctrl-g":           "Escape|Deselect",
+			"F1":               "ToggleHelp",
+			"Alt-x":            "CommandMode",
+			"Ctrl-x":           "None",
+			"<Ctrl-x><Ctrl-s>": "Save",
+			"<Ctrl-x><Ctrl-w>": "SaveAs",
+			"<Ctrl-x><Ctrl-f>": "OpenFile",
+			"<Ctrl-x><Ctrl-c>": "QuitAll",
+			"<Ctrl-x>k":        "Quit",
+			"<Ctrl-x>b":        "BufferList",
+			"<Ctrl-x>h":        "SelectAll",
+			"<Ctrl-x>u":        "Undo",
+			"<Ctrl-x>o":        "NextSplit",
+			"<Ctrl-x>0":        "Unsplit",
+			"<Ctrl-x>2":        "HSplit",
+			"<Ctrl-x>3":        "VSplit",
+		},
+		"command": {
+			"Ctrl-g": "AbortCommand",
+			"Ctrl-y": "Yank",
+		},
+	},
+	"nano": {
+		"buffer": {
+			"Ctrl-o":         "Save",
+			"Ctrl-x":         "Quit",
+			"Ctrl-r":         "OpenFile",
+			"Ctrl-w":         "Find",
+			"Alt-w":          "FindNext",
+			"Alt-q":          "FindPrevious",
+			"CtrlBackslash":  "command-edit:replace ",
+			"Ctrl-k":         "CutLine",
+			"Ctrl-u":         "Paste",
+			"Alt-6":          "CopyLine",
+			"Alt-u":          "Undo",
+			"Alt-e":          "Redo",
+			"Ctrl-f":         "CursorRight",
+			"Ctrl-b":         "CursorLeft",
+			"Ctrl-n":         "CursorDown",
+			"Ctrl-p":         "CursorUp",
+			"Ctrl-a":         "StartOfLine",
+			"Ctrl-e":         "EndOfLine",
+			"Ctrl-y":         "PageUp",
+			"Ctrl-v":         "PageDown",
+			"Alt-\\":         "CursorStart",
+			"Alt-/":          "CursorEnd",
+			"CtrlUnderscore": "JumpLine",
+			"Ctrl-d":         "Delete",
+			"Ctrl-t":         "ShellMode",
+			"Ctrl-g":         "ToggleHelp",
+			"Alt-x":          "CommandMode",
+			"Alt-3":          "ToggleComment",
+			"Alt-]":          "JumpToMatchingBrace",
+			"Ctrl-j":         "Reflow",
+		},
+		"command": {
+			"Ctrl-c": "AbortCommand",
+		},
+	},
+	"vscode": {
+		"buffer": {
+			"Ctrl-p":         "FindFile",
+			"F1":             "CommandPalette",
+			"Ctrl-g":         "JumpLine",
+			"Alt-h":          "ToggleHelp",
+			"Ctrl-d":         "SpawnMultiCursor",
+			"Ctrl-l":         "SelectLine",
+			"Ctrl-w":         "Quit",
+			"CtrlUnderscore": "ToggleComment",
+			"CtrlBackslash":  "VSplit",
+			"CtrlSpace":      "Autocomplete",
+			"F3":             "FindNext",
+			"ShiftF3":        "FindPrevious",
+			"F8":             "NextDiagnostic",
+			"ShiftF8":        "PreviousDiagnostic",
+			"F12":            "GotoDefinition",
+			"F5":             "DebugContinue",
+			"ShiftF5":        "DebugStop",
+			"F9":             "ToggleBreakpoint",
+			"F10":            "DebugNext",
+			"F11":            "DebugStepIn",
+			"ShiftF11":       "DebugStepOut",
+			"Alt-Left":       "JumpBack",
+			"Alt-Right":      "JumpForward",
+			"AltShiftUp":     "DuplicateLineOrSelection",
+			"AltShiftDown":   "DuplicateLineOrSelection",
+			"Ctrl-]":         "IndentSelection|IndentLine",
+		},
+	},
+}
+
+// KeyProfiles are the values of the keyprofile option
+var KeyProfiles = []string{"default", "emacs", "nano", "vscode"}
+
+// boundProfile is the profile whose bindings are bound
+var boundProfile string
+
+// profileBindings returns the bindings of the keyprofile option for the
+// pane type
+func profileBindings(pane string) map[string]string {
+	profile, _ := config.GetGlobalOption("keyprofile").(string)
+	return keyProfiles[profile][pane]
+}
+
+// unbindProfile binds to nothing the keys of the profile bound before, so
+// that binding the defaults and another profile leaves none of them
+func unbindProfile() {
+	for p, bindings := range keyProfiles[boundProfile] {
+		for k := range bindings {
+			e, err := findEvent(k)
+			if err != nil {
+				continue
+			}
+			Binder[p](e, "None")
+			delete(config.Bindings[p], e.Name())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package action
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/project"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// ProjectCmds are the subcommands of the project command
+var ProjectCmds = []string{"open", "root"}
+
+// ProjectCmd switches to another project or shows the current one
+func (h *BufPane) ProjectCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Error("Usage: project open ['dir']|root")
+		return
+	}
+
+	switch args[0] {
+	case "open":
+		if len(args) > 1 {
+			if err := h.openProject(args[1]); err != nil {
+				InfoBar.Error(err)
+			}
+			return
+		}
+		h.pickProject()
+	case "root":
+		wd, err := os.Getwd()
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		if root, ok := util.FindProjectRoot(wd); ok {
+			InfoBar.Message(root)
+		} else {
+			InfoBar.Message("Not in a project")
+		}
+	default:
+		InfoBar.Error("Unknown project command " + args[0])
+	}
+}
+
+// pickProject opens a picker with the known projects, the most recently
+// used first
+func (h *BufPane) pickProject() {
+	projects, err := project.List()
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(projects) == 0 {
+		InfoBar.Message("No known projects")
+		return
+	}
+	items := make([]display.PickerItem, len(projects))
+	for i, p := range projects {
+		items[i] = display.PickerItem{
+			Text:   p.Root,
+			Detail: p.LastUsed.Format("2006-01-02 15:04"),
+		}
+	}
+	InfoBar.Pick("Project: ", "Project", items, func(it *display.PickerItem) {
+		if it == nil {
+			return
+		}
+		if err := h.openProject(it.Text); err != nil {
+			InfoBar.Error(err)
+		}
+	})
+}
+
+// openProject switches to the project containing dir. The autosession of
+// the current project is saved and the one of the new project restored, or
+// if it has none, the tabs are replaced by an empty pane and a picker with
+// the files of the project.
+func (h *BufPane) openProject(dir string) error {
+	dir, err := util.ReplaceHome(dir)
+	if err != nil {
+		return err
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(dir); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return errors.New(dir + " is not a directory")
+	}
+	root, ok := util.FindProjectRoot(dir)
+	if !ok {
+		root = dir
+	}
+	for _, b := range buffer.OpenBuffers {
+		if b.Unsaved() {
+			return errors.New("Save or close the modified buffers before switching projects")
+		}
+	}
+
+	// the state of the project being left is saved even if micro was
+	// started with files
+	autosession = true
+	saveAutosession()
+	if err := project.Touch(root); err != nil {
+		return err
+	}
+
+	s, err := readSession(autosessionPath(root))
+	if os.IsNotExist(err) {
+		s = &session{Dir: root, Tabs: []sessionTab{{&sessionNode{Active: true}}}}
+		if err := loadSession(s); err != nil {
+			return err
+		}
+		MainTab().CurPane().FindFileCmd(nil)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := loadSession(s); err != nil {
+		return err
+	}
+	InfoBar.Message("Opened project " + filepath.Base(root))
+	return nil
+}
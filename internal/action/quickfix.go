@@ -0,0 +1,171 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/quickfix"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// errorList holds the messages found in the output of the last build, in
+// the order they are listed
+var errorList struct {
+	entries []quickfix.Entry
+	// current is the index of the message last jumped to, or -1
+	current int
+	pane    *SearchPane
+}
+
+// BuildCmd runs the given command, or the buildcmd option, in the working
+// directory and lists the messages of its output located in files,
+// according to the errorformat option, in a new pane
+func (h *BufPane) BuildCmd(args []string) {
+	command := h.Buf.Settings["buildcmd"].(string)
+	if len(args) > 0 {
+		command = shellquote.Join(args...)
+	}
+	format, err := quickfix.ParseFormat(h.Buf.Settings["errorformat"].(string))
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+
+	InfoBar.Message("Running " + command + "...")
+	go func() {
+		out, err := quickfix.Run(wd, command)
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			if err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			h.showErrors(wd, command, format.Parse(out))
+		}}
+	}()
+}
+
+// showErrors replaces the list of errors by the messages found in the
+// output of command, run in the directory wd, and lists them in a pane
+// below h
+func (h *BufPane) showErrors(wd, command string, entries []quickfix.Entry) {
+	if sp := errorList.pane; sp != nil && sp.tab == MainTab() && sp.isOpen() && len(sp.tab.Panes) > 1 {
+		sp.ForceQuit()
+	}
+	errorList.entries = errorList.entries[:0]
+	errorList.current = -1
+	errorList.pane = nil
+
+	if len(entries) == 0 {
+		InfoBar.Message(command + ": no errors")
+		return
+	}
+
+	// the messages are grouped by file, paths relative to the working
+	// directory when possible
+	var results []util.GrepResult
+	files := make(map[string]int)
+	var byFile [][]quickfix.Entry
+	for _, e := range entries {
+		path := e.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		}
+		if rel, err := util.MakeRelative(path, wd); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		e.Path = filepath.ToSlash(path)
+
+		i, ok := files[e.Path]
+		if !ok {
+			i = len(results)
+			files[e.Path] = i
+			results = append(results, util.GrepResult{Path: e.Path})
+			byFile = append(byFile, nil)
+		}
+		results[i].Lines = append(results[i].Lines, util.GrepLine{Num: e.Line, Text: errorText(e), Match: true, Col: e.Col})
+		results[i].Matches++
+		byFile[i] = append(byFile[i], e)
+	}
+	for _, es := range byFile {
+		errorList.entries = append(errorList.entries, es...)
+	}
+
+	// the pane is opened below the pane the build was started from, or
+	// the current one if it was closed
+	if t := MainTab(); h.tab != t || t.Panes[t.GetPane(h.splitID)] != Pane(h) {
+		if h = MainTab().CurPane(); h == nil {
+			return
+		}
+	}
+	sp := NewListPane(h, "build: "+command, "", results, [2]string{"error", "errors"})
+	sp.onOpen = func(*BufPane) {
+		if i := sp.matchIndex(sp.Cursor.Y); i >= 0 {
+			errorList.current = i
+		}
+	}
+	errorList.pane = sp
+
+	noun := "errors"
+	if len(entries) == 1 {
+		noun = "error"
+	}
+	InfoBar.Message(fmt.Sprintf("%s: %d %s", command, len(entries), noun))
+}
+
+// errorText returns the text of a message of the list of errors
+func errorText(e quickfix.Entry) string {
+	if e.Kind != "" {
+		return e.Kind + ": " + e.Text
+	}
+	return e.Text
+}
+
+// NextError jumps to the location of the next message of the last build
+func (h *BufPane) NextError() bool {
+	return h.gotoError(1)
+}
+
+// PreviousError jumps to the location of the previous message of the last
+// build
+func (h *BufPane) PreviousError() bool {
+	return h.gotoError(-1)
+}
+
+func (h *BufPane) gotoError(dir int) bool {
+	n := len(errorList.entries)
+	if n == 0 {
+		InfoBar.Message("No errors")
+		return false
+	}
+	i := errorList.current + dir
+	if i < 0 || i >= n {
+		InfoBar.Message("No more errors")
+		return false
+	}
+	errorList.current = i
+	e := errorList.entries[i]
+	loc := buffer.Loc{X: e.Col, Y: e.Line}
+
+	// the file is opened where the list opens it, if it is shown
+	if sp := errorList.pane; sp != nil && sp.tab == MainTab() && sp.isOpen() {
+		if y := sp.matchLine(i); y >= 0 {
+			sp.Cursor.GotoLoc(buffer.Loc{X: 0, Y: y})
+			sp.Relocate()
+		}
+		sp.open(e.Path, loc)
+	} else if h.openAt(projectPath("", e.Path), loc) == nil {
+		return false
+	}
+	InfoBar.Message(fmt.Sprintf("(%d of %d) %s", i+1, n, errorText(e)))
+	return true
+}
@@ -0,0 +1,203 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// A recovery is what EmergencySave writes when micro exits unexpectedly:
+// the layout of the tabs and the text of the buffers with unsaved changes,
+// which are restored the next time micro starts
+type recovery struct {
+	Time    time.Time      `json:"time"`
+	Reason  string         `json:"reason"`
+	Session *session       `json:"session,omitempty"`
+	Files   []recoveryFile `json:"files"`
+}
+
+// A recoveryFile is a modified buffer, whose text is stored in the file
+// Text of the recovery directory. Path is empty for unnamed buffers.
+type recoveryFile struct {
+	Path string `json:"path,omitempty"`
+	Text string `json:"text"`
+}
+
+// recoveryDir returns the directory storing the recoveries, one per
+// subdirectory named after the time it was written
+func recoveryDir() string {
+	return filepath.Join(config.StateDir, "recovery")
+}
+
+// lastRecovery returns the directory of the most recent recovery which
+// hasn't been restored or discarded yet
+func lastRecovery() (string, bool) {
+	dirs, err := ioutil.ReadDir(recoveryDir())
+	if err != nil || len(dirs) == 0 {
+		return "", false
+	}
+	return filepath.Join(recoveryDir(), dirs[len(dirs)-1].Name()), true
+}
+
+// bufferText returns the text of the buffer with '\n' line endings
+func bufferText(b *buffer.Buffer) []byte {
+	var text bytes.Buffer
+	for i := 0; i < b.LinesNum(); i++ {
+		if i > 0 {
+			text.WriteByte('\n')
+		}
+		text.Write(b.LineBytes(i))
+	}
+	return text.Bytes()
+}
+
+// EmergencySave writes the modified buffers and the layout of the tabs to
+// the recovery directory, which it returns, when micro is killed or
+// crashes. It must not panic since it runs while micro is dying.
+func EmergencySave(reason string) (dir string, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			dir, err = "", fmt.Errorf("%v", e)
+		}
+	}()
+
+	r := &recovery{Time: time.Now(), Reason: reason}
+	if Tabs != nil && len(Tabs.List) > 0 {
+		r.Session = currentSession()
+	}
+
+	// the recoveries which weren't restored yet are kept
+	dir = filepath.Join(recoveryDir(), fmt.Sprintf("%020d", r.Time.UnixNano()))
+	for _, b := range buffer.OpenBuffers {
+		if !b.Modified() || b.Type != buffer.BTDefault {
+			continue
+		}
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return "", err
+		}
+		f := recoveryFile{Path: b.AbsPath, Text: strconv.Itoa(len(r.Files)) + ".txt"}
+		if b.Path == "" {
+			f.Path = ""
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.Text), bufferText(b), 0600); err != nil {
+			return "", err
+		}
+		r.Files = append(r.Files, f)
+	}
+	if len(r.Files) == 0 {
+		return "", nil
+	}
+
+	data, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return dir, ioutil.WriteFile(filepath.Join(dir, "recovery.json"), append(data, '\n'), 0600)
+}
+
+// OfferRecovery asks to restore what EmergencySave wrote the last time
+// micro exited, and returns whether there is a recovery. The tabs of the
+// recovered session replace the open ones if withLayout is set, which is
+// the case when micro is started without files, and the autosession is
+// loaded instead if the recovery is declined. Otherwise the recovered
+// buffers are opened in new tabs. Canceling the prompt keeps the recovery
+// for the next time.
+func OfferRecovery(withLayout bool) bool {
+	dir, ok := lastRecovery()
+	if !ok {
+		return false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "recovery.json"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return false
+	}
+	r := new(recovery)
+	if err := json.Unmarshal(data, r); err != nil {
+		InfoBar.Error("Error reading the recovery " + dir + ": " + err.Error())
+		return false
+	}
+
+	msg := fmt.Sprintf("Micro exited (%s) on %s with %d unsaved buffers. Restore them? (y,n)",
+		r.Reason, r.Time.Format("Mon Jan _2 15:04"), len(r.Files))
+	InfoBar.YNPrompt(msg, func(yes, canceled bool) {
+		if canceled || !yes {
+			if !canceled {
+				os.RemoveAll(dir)
+				InfoBar.Message("Discarded the recovered buffers")
+			}
+			if withLayout {
+				LoadAutosession()
+			}
+			return
+		}
+		if withLayout {
+			autosession = true
+		}
+		if err := r.restore(dir, withLayout); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		os.RemoveAll(dir)
+		InfoBar.Message("Restored ", len(r.Files), " buffers, save them to keep their changes")
+	})
+	return true
+}
+
+// restore opens the recovered session and puts back the unsaved changes of
+// its buffers, which can be undone to get the text of the files
+func (r *recovery) restore(dir string, withLayout bool) error {
+	// the recovered text is at least as recent as the backups, which would
+	// otherwise be offered when the files are opened
+	for _, f := range r.Files {
+		if f.Path != "" {
+			buffer.RemoveBackupOf(f.Path)
+		}
+	}
+	if withLayout && r.Session != nil {
+		if err := loadSession(r.Session); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range r.Files {
+		text, err := ioutil.ReadFile(filepath.Join(dir, f.Text))
+		if err != nil {
+			return errors.New("Error reading the recovery " + dir + ": " + err.Error())
+		}
+		var b *buffer.Buffer
+		for _, ob := range buffer.OpenBuffers {
+			if f.Path != "" && ob.AbsPath == f.Path {
+				b = ob
+				break
+			}
+		}
+		if b == nil {
+			if f.Path != "" {
+				b, err = buffer.NewBufferFromFile(f.Path, buffer.BTDefault)
+				if err != nil {
+					return err
+				}
+			} else {
+				b = buffer.NewBufferFromString("", "", buffer.BTDefault)
+			}
+			w, h := screen.Screen.Size()
+			Tabs.AddTab(NewTabFromBuffer(0, 0, w, h-config.GetInfoBarOffset(), b))
+			Tabs.SetActive(len(Tabs.List) - 1)
+		}
+		if !bytes.Equal(bufferText(b), text) {
+			b.Replace(b.Start(), b.End(), string(text))
+		}
+	}
+	return nil
+}
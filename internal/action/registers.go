@@ -0,0 +1,58 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/clipboard"
+)
+
+// clipRegister returns the register used by the clipboard actions: the one
+// chosen with SelectRegister for the key pressed after it, or the clipboard
+// register
+func (h *BufPane) clipRegister() clipboard.Register {
+	if h.register != 0 {
+		return h.register
+	}
+	return clipboard.ClipboardReg
+}
+
+// SelectRegister asks for the name of a register, a letter or a digit, used
+// instead of the clipboard by the copy, cut or paste action of the next key
+func (h *BufPane) SelectRegister() bool {
+	h.askRune("Register: ", func(r rune) {
+		reg, ok := clipboard.NamedRegister(r)
+		if !ok {
+			InfoBar.Error("Invalid register " + string(r))
+			return
+		}
+		h.register = reg
+		InfoBar.Message("Register " + clipboard.RegisterName(reg))
+	})
+	return true
+}
+
+// RegistersCmd opens a pane listing the registers and their text
+func (h *BufPane) RegistersCmd(args []string) {
+	var sb strings.Builder
+	for _, r := range clipboard.Registers() {
+		text, err := clipboard.Read(r)
+		if err != nil {
+			text = err.Error()
+		}
+		name := clipboard.RegisterName(r)
+		if r == clipboard.SyncedReg {
+			name += " (system clipboard)"
+		}
+		fmt.Fprintf(&sb, "%s\n", name)
+		for _, l := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			fmt.Fprintf(&sb, "    %s\n", l)
+		}
+	}
+
+	b := buffer.NewBufferFromString(strings.TrimSuffix(sb.String(), "\n"), "", buffer.BTScratch)
+	b.Type.Readonly = true
+	b.SetName("Registers")
+	h.HSplitBuf(b)
+}
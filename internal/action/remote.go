@@ -0,0 +1,51 @@
+package action
+
+import (
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// OpenFileAt shows the file at path, focusing the pane of the tabs already
+// showing it or else opening it in a new tab, and moves the cursor to loc
+// unless it is {-1, -1}. The empty buffer micro starts with when it isn't
+// given files is replaced instead of opening a new tab. This is what the
+// remote control socket opens files with.
+func OpenFileAt(path string, loc buffer.Loc) (*BufPane, error) {
+	abs, _ := filepath.Abs(path)
+	var target *BufPane
+	for i, t := range Tabs.List {
+		for j, p := range t.Panes {
+			if h, ok := p.(*BufPane); ok && h.Buf.AbsPath == abs && target == nil {
+				Tabs.SetActive(i)
+				t.SetActive(j)
+				target = h
+			}
+		}
+	}
+
+	if target == nil {
+		b, err := buffer.NewBufferFromFile(path, buffer.BTDefault)
+		if err != nil {
+			return nil, err
+		}
+		cur := MainTab().CurPane()
+		if cur != nil && cur.Buf.Type == buffer.BTDefault && cur.Buf.Path == "" && !cur.Buf.Modified() {
+			cur.OpenBuffer(b)
+			target = cur
+		} else {
+			width, height := screen.Screen.Size()
+			Tabs.AddTab(NewTabFromBuffer(0, 0, width, height-1-config.GetInfoBarOffset(), b))
+			Tabs.SetActive(len(Tabs.List) - 1)
+			target = MainTab().CurPane()
+		}
+	}
+
+	if loc.X >= 0 && loc.Y >= 0 {
+		target.pushJump(target.Cursor.Loc)
+		target.gotoLoc(loc)
+	}
+	return target, nil
+}
@@ -0,0 +1,61 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+)
+
+var (
+	// lastEdit is the last group of edits, the steps of the keys which
+	// edited the buffer one after the other, such as the text typed
+	// between two cursor movements
+	lastEdit []macroStep
+	// editing is whether the next edit belongs to the group of lastEdit
+	editing bool
+	// replaying is whether a macro or lastEdit is being played, whose
+	// edits are not recorded
+	replaying bool
+)
+
+// noteEdit adds the step of a key to lastEdit if it edited the buffer b,
+// whose Edits were edits before the key, and otherwise ends the group of
+// lastEdit
+func (h *BufPane) noteEdit(b *buffer.Buffer, edits int, s macroStep) {
+	if replaying || b.Type.Kind == buffer.BTInfo.Kind {
+		return
+	}
+	if b.Edits() == edits {
+		editing = false
+		return
+	}
+	if !editing {
+		lastEdit = nil
+		editing = true
+	}
+	lastEdit = appendStep(lastEdit, s)
+}
+
+// startEdit starts a new group of edits with the step of a key which
+// starts inserting text without editing the buffer, such as the i of the
+// modal option
+func startEdit(s macroStep) {
+	if replaying {
+		return
+	}
+	lastEdit = []macroStep{s}
+	editing = true
+}
+
+// Repeat replays the last group of edits at every cursor, as a single undo
+// step
+func (h *BufPane) Repeat() bool {
+	if len(lastEdit) == 0 {
+		InfoBar.Message("No edit to repeat")
+		return false
+	}
+	// the text typed next starts a new group
+	editing = false
+	h.Buf.UndoGroup(func() {
+		h.playMacro(lastEdit, 1)
+	})
+	return true
+}
@@ -0,0 +1,171 @@
+package action
+
+import (
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// replCommands are the interpreters started for the filetypes when the
+// replcmd option is empty
+var replCommands = map[string]string{
+	"clojure":    "clj",
+	"elixir":     "iex",
+	"haskell":    "ghci",
+	"javascript": "node",
+	"julia":      "julia",
+	"lua":        "lua",
+	"ocaml":      "ocaml",
+	"python":     "python3",
+	"python2":    "python2",
+	"r":          "R",
+	"ruby":       "irb",
+	"sql":        "psql",
+}
+
+// replTerm is the terminal pane text is sent to, the one last used
+var replTerm *TermPane
+
+// replCommand returns the command of the interpreter for the buffer, given
+// by the replcmd option or else its filetype, or the shell (see termShell)
+func (h *BufPane) replCommand() string {
+	if cmd := h.Buf.Settings["replcmd"].(string); cmd != "" {
+		return cmd
+	}
+	if cmd, ok := replCommands[h.Buf.FileType()]; ok {
+		return cmd
+	}
+	if sh, err := h.termShell(); err == nil {
+		return shellquote.Join(sh...)
+	}
+	return "sh"
+}
+
+// isRunning returns whether the terminal pane is still open, possibly in
+// copy mode, and its command running
+func (t *TermPane) isRunning() bool {
+	if t.Status != shell.TTRunning {
+		return false
+	}
+	for _, tab := range Tabs.List {
+		for _, p := range tab.Panes {
+			if p == t {
+				return true
+			}
+			if cp, ok := p.(*CopyModePane); ok && cp.term == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// openRepl starts command in a terminal pane below h, which text is sent
+// to from then on
+func (h *BufPane) openRepl(command string) (*TermPane, error) {
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := h.openTerminal(args, true)
+	if err != nil {
+		return nil, err
+	}
+	replTerm = tp
+	return tp, nil
+}
+
+// ReplCmd starts the given command, or the interpreter for the filetype
+// (see replCommand), in a terminal pane below the current one, which text
+// is sent to by SendToTerminal
+func (h *BufPane) ReplCmd(args []string) {
+	command := h.replCommand()
+	if len(args) > 0 {
+		command = shellquote.Join(args...)
+	}
+	if _, err := h.openRepl(command); err != nil {
+		InfoBar.Error(err)
+	}
+}
+
+// sendToTerminal sends text to the terminal pane last used, the first one
+// of the tab, or else to the interpreter for the filetype, started in a
+// pane below. The lines are ended as with Enter, and an indented last line
+// is followed by an empty one, which ends the block in interpreters such
+// as python.
+func (h *BufPane) sendToTerminal(text string) bool {
+	t := replTerm
+	if t == nil || !t.isRunning() {
+		t = nil
+		for _, p := range h.tab.Panes {
+			if tp, ok := p.(*TermPane); ok && tp.isRunning() {
+				t = tp
+				break
+			}
+		}
+	}
+	if t == nil {
+		var err error
+		if t, err = h.openRepl(h.replCommand()); err != nil {
+			InfoBar.Error(err)
+			return false
+		}
+	}
+	replTerm = t
+
+	text = strings.TrimRight(text, "\n")
+	lines := strings.Split(text, "\n")
+	if last := lines[len(lines)-1]; len(util.GetLeadingWhitespace([]byte(last))) > 0 && len(lines) > 1 {
+		text += "\n"
+	}
+	t.WriteString(strings.Replace(text, "\n", "\r", -1) + "\r")
+	return true
+}
+
+// SendToTerminal sends the selection, or else the current line, to a
+// terminal pane running an interpreter (see sendToTerminal) and moves the
+// cursor to the next line without a selection
+func (h *BufPane) SendToTerminal() bool {
+	c := h.Cursor
+	if c.HasSelection() {
+		return h.sendToTerminal(string(c.GetSelection()))
+	}
+	if !h.sendToTerminal(string(h.Buf.LineBytes(c.Y))) {
+		return false
+	}
+	if c.Y+1 < h.Buf.LinesNum() {
+		c.GotoLoc(buffer.Loc{X: 0, Y: c.Y + 1})
+		h.Relocate()
+	}
+	return true
+}
+
+// SendParagraphToTerminal sends the lines of the paragraph at the cursor to
+// a terminal pane as SendToTerminal does, and moves the cursor to the line
+// after it
+func (h *BufPane) SendParagraphToTerminal() bool {
+	b := h.Buf
+	blank := func(y int) bool {
+		return len(strings.TrimSpace(string(b.LineBytes(y)))) == 0
+	}
+	y1, y2 := h.Cursor.Y, h.Cursor.Y
+	if blank(y1) {
+		return false
+	}
+	for y1 > 0 && !blank(y1-1) {
+		y1--
+	}
+	for y2+1 < b.LinesNum() && !blank(y2+1) {
+		y2++
+	}
+	end := buffer.Loc{X: util.CharacterCount(b.LineBytes(y2)), Y: y2}
+	if !h.sendToTerminal(string(b.Substr(buffer.Loc{X: 0, Y: y1}, end))) {
+		return false
+	}
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Min(y2+1, b.LinesNum()-1)})
+	h.Relocate()
+	return true
+}
@@ -0,0 +1,108 @@
+package action
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/rest"
+	"github.com/zyedidia/micro/v2/internal/shell"
+)
+
+// httpClient sends the requests of the http command
+var httpClient = &http.Client{Timeout: time.Minute}
+
+// SendHTTPRequest sends the HTTP request under the cursor, see HTTPCmd
+func (h *BufPane) SendHTTPRequest() bool {
+	h.HTTPCmd(nil)
+	return true
+}
+
+// HTTPCmd sends the request of the block of the cursor in a request file,
+// with the variables of the file and of the environment replaced in it, and
+// shows the response in a pane on the right when it comes
+func (h *BufPane) HTTPCmd(args []string) {
+	lines := make([]string, h.Buf.LinesNum())
+	for y := range lines {
+		lines[y] = h.Buf.Line(y)
+	}
+	f := rest.Parse(lines)
+	r, ok := f.RequestAt(h.Cursor.Y)
+	if !ok {
+		InfoBar.Error("No HTTP request under the cursor")
+		return
+	}
+	r, err := f.Expand(r)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	strs := []string{r.URL, r.Body}
+	for _, hd := range r.Header {
+		strs = append(strs, hd[1])
+	}
+	expandSecrets(strs, func(strs []string) {
+		r.URL, r.Body = strs[0], strs[1]
+		for i := range r.Header {
+			r.Header[i][1] = strs[i+2]
+		}
+		h.sendHTTPRequest(r)
+	})
+}
+
+// sendHTTPRequest sends the request in the background and shows the
+// response when it comes
+func (h *BufPane) sendHTTPRequest(r rest.Request) {
+	InfoBar.Message("Sending " + r.Method + " " + r.URL + "...")
+	go func() {
+		resp, err := r.Send(httpClient)
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			if err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			if !h.isOpen() {
+				return
+			}
+			h.showHTTPResponse(r.Method+" "+r.URL, resp)
+			InfoBar.Message(resp.Summary())
+		}}
+	}()
+}
+
+// showHTTPResponse shows the response in the response pane of h, opened
+// on its right if it isn't open. The focus stays on the request file.
+func (h *BufPane) showHTTPResponse(name string, resp *rest.Response) {
+	p := h.httpResponse
+	if p == nil || !p.isOpen() {
+		b := buffer.NewBufferFromString("", "", buffer.BTLog)
+		b.SetOptionNative("filetype", "http")
+		b.SetOptionNative("softwrap", true)
+		p = NewBufPaneFromBuf(b, h.tab)
+		p.splitID = h.tab.GetNode(h.splitID).VSplit(true)
+		h.tab.Panes = append(h.tab.Panes, p)
+		h.tab.Resize()
+		h.tab.SetActive(h.tab.GetPane(h.splitID))
+		h.httpResponse = p
+	}
+
+	b := p.Buf
+	b.SetName("Response " + name)
+	b.EventHandler.Replace(b.Start(), b.End(), resp.Format())
+	b.UndoStack = new(buffer.TEStack)
+	b.RedoStack = new(buffer.TEStack)
+	p.Cursor.GotoLoc(b.Start())
+	p.Relocate()
+}
+
+// isOpen returns whether the pane is still part of an open tab
+func (h *BufPane) isOpen() bool {
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			if p == Pane(h) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// RuntimeCmds are the subcommands of the runtime command
+var RuntimeCmds = []string{"list", "update"}
+
+// RuntimeCmd fetches the runtime files given by URL again with "runtime
+// update", using the new colorscheme and syntax files right away, or lists
+// the files of their cache with "runtime list", in the log
+func (h *BufPane) RuntimeCmd(args []string) {
+	if len(args) != 1 || args[0] != "list" && args[0] != "update" {
+		InfoBar.Error("Usage: runtime list|update")
+		return
+	}
+	if h.Buf.Type != buffer.BTLog {
+		h.OpenLogBuf()
+	}
+	if args[0] == "list" {
+		config.ListRemoteFiles(buffer.LogBuf)
+		return
+	}
+
+	if config.FetchRuntime(true) {
+		InfoBar.Message("Updating the runtime files...")
+	} else {
+		InfoBar.Message("No runtime files are fetched from URLs")
+	}
+}
+
+// RuntimeFetched uses the runtime files once they are fetched in the
+// background: the colorscheme and the syntax files right away, and the
+// plugins when micro restarts. The files fetched by "runtime update" are
+// written to the log.
+func RuntimeFetched(r *config.RemoteResult) {
+	plugins := r.Apply()
+	if r.Update {
+		fmt.Fprint(buffer.LogBuf, r.Log)
+	}
+	if r.Changed() {
+		if err := config.InitColorscheme(); err != nil {
+			InfoBar.Error(err)
+		}
+		buffer.ReloadSyntax()
+	}
+	if plugins {
+		fmt.Fprintln(buffer.LogBuf, "Restart micro to use the plugins fetched")
+	}
+	if r.Err != nil {
+		if r.Update {
+			fmt.Fprintln(buffer.LogBuf, r.Err)
+		}
+		InfoBar.Error(r.Err)
+	} else if plugins {
+		InfoBar.Message("Restart micro to use the plugins fetched")
+	} else if r.Update {
+		InfoBar.Message("Updated the runtime files")
+	}
+}
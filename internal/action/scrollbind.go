@@ -0,0 +1,71 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The panes of a tab with the scrollbind option scroll together: when one
+// of them scrolls by some lines, the others scroll by as many lines. The
+// offset between their lines when they were bound is kept, even when a
+// pane cannot scroll past the start or the end of its buffer for a while.
+
+// A scrollBinding is the state of a pane bound with scrollbind
+type scrollBinding struct {
+	// bound is whether the pane was bound when it was last synced
+	bound bool
+	// line is the line the pane would start at if its buffer were long
+	// enough, and shown the line it was scrolled to
+	line, shown int
+}
+
+// scrollBound returns whether the scrollbind option is on in the pane
+func (h *BufPane) scrollBound() bool {
+	w, ok := h.BWindow.(*display.BufWindow)
+	return ok && w.Setting("scrollbind") == true
+}
+
+// syncBoundScroll scrolls the other bound panes of the tab by the lines
+// this pane scrolled by since it was last synced, if it is bound. The
+// panes compared with diff scroll with the pane they are compared with
+// instead.
+func (h *BufPane) syncBoundScroll() {
+	if !h.scrollBound() || h.tab == nil {
+		h.scrollBind.bound = false
+		return
+	}
+	start := h.GetView().StartLine.Line
+	if !h.scrollBind.bound {
+		h.scrollBind = scrollBinding{bound: true, line: start, shown: start}
+		return
+	}
+	delta := start - h.scrollBind.shown
+	if delta == 0 {
+		return
+	}
+	h.scrollBind.line += delta
+	h.scrollBind.shown = start
+
+	diffPeer := h.diffPane()
+	for _, p := range h.tab.Panes {
+		bp, ok := p.(*BufPane)
+		if !ok || bp == h || bp == diffPeer {
+			continue
+		}
+		if !bp.scrollBound() {
+			bp.scrollBind.bound = false
+			continue
+		}
+		v := bp.GetView()
+		if !bp.scrollBind.bound {
+			bp.scrollBind = scrollBinding{bound: true, line: v.StartLine.Line, shown: v.StartLine.Line}
+		}
+		bp.scrollBind.line += delta
+		y := util.Clamp(bp.scrollBind.line, 0, bp.Buf.LinesNum()-1)
+		bp.scrollBind.shown = y
+		if y != v.StartLine.Line || v.StartLine.Row != 0 {
+			v.StartLine = display.SLoc{Line: y}
+			bp.SetView(v)
+		}
+	}
+}
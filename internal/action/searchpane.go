@@ -0,0 +1,530 @@
+package action
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A searchLine records what a line of the results buffer refers to
+type searchLine struct {
+	// file is the index of the result, or -1 for lines that don't belong
+	// to one
+	file int
+	// line is the line number in the file, or -1 for the file header
+	line int
+}
+
+// A SearchPane shows the results of a project-wide search. The results are
+// added as they are found. Enter on a result opens the file at that line and
+// Enter or Tab on a file's header collapses or expands its results.
+//
+// For a search and replace the pane previews every change. Space toggles
+// whether the change under the cursor (or all changes of a file) will be
+// made and Ctrl-s applies the selected changes.
+type SearchPane struct {
+	*BufPane
+
+	re util.Regexp
+	// replace is set for a search and replace preview
+	replace     bool
+	replacement []byte
+	// skip stores the changes which won't be applied by path and line
+	skip map[string]map[int]bool
+
+	// origin is the pane results are opened in
+	origin *BufPane
+	// onOpen is called with the pane a result was opened in
+	onOpen  func(*BufPane)
+	root    string
+	context int
+	// noun names what the results are, in singular and plural
+	noun    [2]string
+	results []util.GrepResult
+	// collapsed stores which results are collapsed by path
+	collapsed map[string]bool
+	lines     []searchLine
+	done      bool
+	stop      chan struct{}
+}
+
+// NewSearchPane starts searching the files of the project at root for the
+// given regular expression and opens a pane below h which displays the
+// results
+func NewSearchPane(h *BufPane, root string, pattern string, re util.Regexp, context int) *SearchPane {
+	return newSearchPane(h, "grep: "+pattern, root, re, context, nil)
+}
+
+// NewReplacePane starts searching the files of the project at root for the
+// given regular expression and opens a pane below h which previews
+// replacing each match with replace, in which '$1' expands to the first
+// submatch and so on
+func NewReplacePane(h *BufPane, root string, pattern string, re util.Regexp, replace []byte) *SearchPane {
+	return newSearchPane(h, "replace: "+pattern, root, re, 0, replace)
+}
+
+// NewListPane opens a pane below h listing the given results, which don't
+// come from a search. The lines of each result are given with their paths
+// relative to root and are all shown as matches. noun names the results in
+// singular and plural.
+func NewListPane(h *BufPane, name, root string, results []util.GrepResult, noun [2]string) *SearchPane {
+	sp := openSearchPane(h, name, root, nil, 0, nil)
+	sp.noun = noun
+	for _, r := range results {
+		sp.addResult(r)
+	}
+	sp.done = true
+	return sp
+}
+
+func newSearchPane(h *BufPane, name, root string, re util.Regexp, context int, replace []byte) *SearchPane {
+	sp := openSearchPane(h, name, root, re, context, replace)
+
+	// the files are listed in the background as well, since large projects
+	// take a while to walk
+	go func() {
+		files, err := util.ProjectFiles(root, maxProjectFiles)
+		if err != nil && err != util.ErrTooManyFiles {
+			shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+				sp.finish()
+				InfoBar.Error(err)
+			}}
+			return
+		}
+		results := make(chan util.GrepResult)
+		util.Grep(root, files, re, context, results, sp.stop)
+		for r := range results {
+			r := r
+			shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+				sp.addResult(r)
+			}}
+		}
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			sp.finish()
+		}}
+	}()
+
+	return sp
+}
+
+// openSearchPane opens an empty results pane below h
+func openSearchPane(h *BufPane, name, root string, re util.Regexp, context int, replace []byte) *SearchPane {
+	b := buffer.NewBufferFromString("", "", buffer.BTSearch)
+	b.SetName(name)
+
+	sp := new(SearchPane)
+	sp.BufPane = NewBufPaneFromBuf(b, h.tab)
+	sp.origin = h
+	sp.re = re
+	sp.replace = replace != nil
+	sp.replacement = replace
+	sp.skip = make(map[string]map[int]bool)
+	sp.root = root
+	sp.context = context
+	sp.noun = [2]string{"match", "matches"}
+	sp.collapsed = make(map[string]bool)
+	sp.lines = []searchLine{{-1, -1}}
+	sp.stop = make(chan struct{})
+
+	tab := h.tab
+	sp.splitID = tab.GetNode(h.splitID).HSplit(true)
+	tab.Panes = append(tab.Panes, sp)
+	tab.Resize()
+	tab.SetActive(len(tab.Panes) - 1)
+	return sp
+}
+
+// HandleEvent handles the keys used to navigate the results and passes
+// everything else to the bufpane
+func (h *SearchPane) HandleEvent(event tcell.Event) {
+	if e, ok := event.(*tcell.EventKey); ok {
+		switch {
+		case e.Key() == tcell.KeyEnter && e.Modifiers() == tcell.ModNone:
+			h.activate(true)
+			return
+		case e.Key() == tcell.KeyTab && e.Modifiers() == tcell.ModNone:
+			h.activate(false)
+			return
+		case h.replace && e.Key() == tcell.KeyRune && e.Rune() == ' ':
+			h.toggleChange()
+			return
+		case h.replace && e.Key() == tcell.KeyCtrlS:
+			h.confirmApply()
+			return
+		}
+	}
+	h.BufPane.HandleEvent(event)
+}
+
+// isOpen returns whether the pane is still part of its tab
+func (h *SearchPane) isOpen() bool {
+	for _, p := range h.tab.Panes {
+		if p == h {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *SearchPane) addResult(r util.GrepResult) {
+	if !h.isOpen() {
+		h.cancel()
+		return
+	}
+	h.results = append(h.results, r)
+	h.appendText(h.renderResult(len(h.results) - 1))
+}
+
+func (h *SearchPane) finish() {
+	h.done = true
+	if !h.isOpen() {
+		return
+	}
+	matches := 0
+	for _, r := range h.results {
+		matches += r.Matches
+	}
+	if h.replace {
+		InfoBar.Message(fmt.Sprintf("%d changes in %d files, press Ctrl-s to apply the selected changes", matches, len(h.results)))
+	} else {
+		InfoBar.Message(fmt.Sprintf("grep: %d matches in %d files", matches, len(h.results)))
+	}
+}
+
+// cancel stops the search if it is still running
+func (h *SearchPane) cancel() {
+	if !h.done {
+		h.done = true
+		close(h.stop)
+	}
+}
+
+// renderResult returns the text for the i-th result and records its lines
+func (h *SearchPane) renderResult(i int) string {
+	r := h.results[i]
+	var sb strings.Builder
+
+	sign := "-"
+	if h.collapsed[r.Path] {
+		sign = "+"
+	}
+	if h.replace {
+		fmt.Fprintf(&sb, "%s %s (%d of %d changes)\n", sign, r.Path, r.Matches-len(h.skip[r.Path]), r.Matches)
+	} else {
+		noun := h.noun[1]
+		if r.Matches == 1 {
+			noun = h.noun[0]
+		}
+		fmt.Fprintf(&sb, "%s %s (%d %s)\n", sign, r.Path, r.Matches, noun)
+	}
+	h.lines = append(h.lines, searchLine{i, -1})
+	if h.collapsed[r.Path] {
+		return sb.String()
+	}
+
+	for j, l := range r.Lines {
+		if h.replace {
+			check := "x"
+			if h.skip[r.Path][l.Num] {
+				check = " "
+			}
+			newText, _ := util.ReplaceRange(h.re, []byte(l.Text), h.replacement, 0, len(l.Text))
+			fmt.Fprintf(&sb, "  [%s] %6d- %s\n", check, l.Num+1, l.Text)
+			fmt.Fprintf(&sb, "      %6d+ %s\n", l.Num+1, newText)
+			h.lines = append(h.lines, searchLine{i, l.Num}, searchLine{i, l.Num})
+			continue
+		}
+
+		if h.context > 0 && j > 0 && l.Num > r.Lines[j-1].Num+1 {
+			sb.WriteString("      --\n")
+			h.lines = append(h.lines, searchLine{-1, -1})
+		}
+		sep := '-'
+		if l.Match {
+			sep = ':'
+		}
+		fmt.Fprintf(&sb, "%6d%c %s\n", l.Num+1, sep, l.Text)
+		h.lines = append(h.lines, searchLine{i, l.Num})
+	}
+	sb.WriteString("\n")
+	h.lines = append(h.lines, searchLine{-1, -1})
+	return sb.String()
+}
+
+// appendText adds text at the end of the results. The results buffer is
+// readonly and its text should not be undoable, so the event handler is
+// used directly and the undo stack is cleared.
+func (h *SearchPane) appendText(text string) {
+	// the buffer always ends with an empty line, which is replaced by
+	// the new text
+	h.lines = append(h.lines[:h.Buf.LinesNum()-1], h.lines[h.Buf.LinesNum():]...)
+	h.lines = append(h.lines, searchLine{-1, -1})
+	// keep the cursor in place even if it is at the end of the buffer
+	loc := h.Cursor.Loc
+	h.Buf.EventHandler.Insert(h.Buf.End(), text)
+	h.Cursor.GotoLoc(loc)
+	h.Buf.UndoStack = new(buffer.TEStack)
+	h.Buf.RedoStack = new(buffer.TEStack)
+}
+
+// rerender rebuilds the whole results buffer
+func (h *SearchPane) rerender() {
+	var sb strings.Builder
+	h.lines = h.lines[:0]
+	for i := range h.results {
+		sb.WriteString(h.renderResult(i))
+	}
+	h.lines = append(h.lines, searchLine{-1, -1})
+	loc := h.Cursor.Loc
+	h.Buf.EventHandler.Replace(h.Buf.Start(), h.Buf.End(), sb.String())
+	h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: util.Clamp(loc.Y, 0, h.Buf.LinesNum()-1)})
+	h.Buf.UndoStack = new(buffer.TEStack)
+	h.Buf.RedoStack = new(buffer.TEStack)
+}
+
+// activate acts on the line under the cursor: a result is opened if open
+// is true, and a file header toggles whether its results are collapsed
+func (h *SearchPane) activate(open bool) {
+	y := h.Cursor.Y
+	if y >= len(h.lines) || h.lines[y].file < 0 {
+		return
+	}
+	l := h.lines[y]
+	r := h.results[l.file]
+
+	if l.line >= 0 && open {
+		col := 0
+		for _, gl := range r.Lines {
+			if gl.Num == l.line && gl.Match {
+				col = gl.Col
+				break
+			}
+		}
+		h.open(r.Path, buffer.Loc{X: col, Y: l.line})
+		return
+	}
+
+	h.collapsed[r.Path] = !h.collapsed[r.Path]
+	h.rerender()
+	for i, sl := range h.lines {
+		if sl.file == l.file && sl.line == -1 {
+			h.Cursor.GotoLoc(buffer.Loc{X: 0, Y: i})
+			break
+		}
+	}
+	h.Relocate()
+}
+
+// open opens the file at the given location in the pane the search was
+// started from, or in a new split if that pane is gone or has unsaved changes
+func (h *SearchPane) open(path string, loc buffer.Loc) {
+	path = projectPath(h.root, path)
+
+	var target *BufPane
+	for i, p := range h.tab.Panes {
+		if p == h.origin {
+			target = h.origin
+			h.tab.SetActive(i)
+			break
+		}
+	}
+
+	if target == nil {
+		b, err := buffer.NewBufferFromFile(path, buffer.BTDefault)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		target = h.HSplitIndex(b, false)
+	}
+	if target = target.openAt(path, loc); target == nil {
+		return
+	}
+	h.origin = target
+	if h.onOpen != nil {
+		h.onOpen(target)
+	}
+}
+
+// matchIndex returns the index of the match on the line y of a list pane
+// among all its matches, or -1 if the line is not a match
+func (h *SearchPane) matchIndex(y int) int {
+	if y >= len(h.lines) || h.lines[y].file < 0 || h.lines[y].line < 0 {
+		return -1
+	}
+	file := h.lines[y].file
+	i := 0
+	for k := 0; k < file; k++ {
+		i += len(h.results[k].Lines)
+	}
+	for _, l := range h.lines[:y] {
+		if l.file == file && l.line >= 0 {
+			i++
+		}
+	}
+	return i
+}
+
+// matchLine returns the line of a list pane showing the match i, or -1 if it
+// is not shown because its file is collapsed
+func (h *SearchPane) matchLine(i int) int {
+	file := 0
+	for file < len(h.results) && i >= len(h.results[file].Lines) {
+		i -= len(h.results[file].Lines)
+		file++
+	}
+	for y, l := range h.lines {
+		if l.file == file && l.line >= 0 {
+			if i == 0 {
+				return y
+			}
+			i--
+		}
+	}
+	return -1
+}
+
+// toggleChange toggles whether the change under the cursor will be applied.
+// On a file header it toggles all changes of the file.
+func (h *SearchPane) toggleChange() {
+	y := h.Cursor.Y
+	if y >= len(h.lines) || h.lines[y].file < 0 {
+		return
+	}
+	l := h.lines[y]
+	r := h.results[l.file]
+	skip := h.skip[r.Path]
+	if skip == nil {
+		skip = make(map[int]bool)
+		h.skip[r.Path] = skip
+	}
+
+	if l.line >= 0 {
+		if skip[l.line] {
+			delete(skip, l.line)
+		} else {
+			skip[l.line] = true
+		}
+	} else if len(skip) > 0 {
+		h.skip[r.Path] = nil
+	} else {
+		for _, gl := range r.Lines {
+			skip[gl.Num] = true
+		}
+	}
+	h.rerender()
+}
+
+// confirmApply applies the selected changes, asking first if they are in
+// more files than the confirmfiles option
+func (h *SearchPane) confirmApply() {
+	n, files := 0, 0
+	for _, r := range h.results {
+		if m := r.Matches - len(h.skip[r.Path]); m > 0 {
+			n += m
+			files++
+		}
+	}
+	if n == 0 {
+		InfoBar.Message("No changes selected")
+		return
+	}
+	if files <= util.IntOpt(config.GetGlobalOption("confirmfiles")) || !confirms("replace") {
+		h.apply()
+		return
+	}
+	confirm("replace", fmt.Sprintf("Apply %d changes in %d files?", n, files), h.apply)
+}
+
+// apply makes the selected changes through the buffers of the files, so
+// that they can be undone per file. The files which weren't open are opened
+// in new tabs, unless the replacesave option is on, which saves the files
+// without unsaved changes instead. Lines which no longer contain the
+// previewed text are left alone.
+func (h *SearchPane) apply() {
+	save := config.GetGlobalOption("replacesave").(bool)
+	changed, files, stale := 0, 0, 0
+	var opened []*buffer.Buffer
+	var errs []string
+	for _, r := range h.results {
+		if r.Matches == len(h.skip[r.Path]) {
+			continue
+		}
+
+		b, isNew, err := searchBuffer(projectPath(h.root, r.Path))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		wasModified := b.Modified()
+
+		var valid []int
+		for _, l := range r.Lines {
+			if !l.Match || h.skip[r.Path][l.Num] {
+				continue
+			}
+			if l.Num < b.LinesNum() && string(b.LineBytes(l.Num)) == l.Text {
+				valid = append(valid, l.Num)
+			} else {
+				stale++
+			}
+		}
+		if len(valid) > 0 {
+			b.ReplaceRegexLines(valid, h.re, h.replacement)
+			changed += len(valid)
+			files++
+
+			if save && !wasModified {
+				if err := b.Save(); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+		if isNew {
+			if len(valid) > 0 && (!save || b.Modified()) {
+				opened = append(opened, b)
+			} else {
+				b.Close()
+			}
+		}
+	}
+
+	h.ForceQuit()
+	width, height := screen.Screen.Size()
+	for _, b := range opened {
+		Tabs.AddTab(NewTabFromBuffer(0, 0, width, height-1-config.GetInfoBarOffset(), b))
+	}
+	msg := fmt.Sprintf("Changed %d lines in %d files", changed, files)
+	if len(opened) > 0 {
+		msg += fmt.Sprintf(", opened %d files in new tabs", len(opened))
+	}
+	if stale > 0 {
+		msg += fmt.Sprintf(", skipped %d lines that changed since the search", stale)
+	}
+	if len(errs) > 0 {
+		InfoBar.Error(msg + ": " + strings.Join(errs, ", "))
+	} else {
+		InfoBar.Message(msg)
+	}
+}
+
+// searchBuffer returns the open buffer of the file at path, or else a new
+// buffer of the file along with true, which must be closed if it isn't
+// opened in a pane so that it does not keep the file open and locked
+func searchBuffer(path string) (*buffer.Buffer, bool, error) {
+	abs, _ := filepath.Abs(path)
+	for _, b := range buffer.OpenBuffers {
+		if b.Type == buffer.BTDefault && b.AbsPath == abs {
+			return b, false, nil
+		}
+	}
+	b, err := buffer.NewBufferFromFile(path, buffer.BTDefault)
+	return b, err == nil, err
+}
@@ -0,0 +1,125 @@
+package action
+
+import (
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// SecretCmds are the subcommands of the secret command
+var SecretCmds = []string{"list", "lock", "remove", "set", "unlock"}
+
+// unlockSecrets asks the master passphrase of the secrets file, twice when
+// it is created, and calls then once the secrets are unlocked
+func unlockSecrets(then func()) {
+	if config.SecretsUnlocked() {
+		then()
+		return
+	}
+	if config.SecretsExist() {
+		InfoBar.SecretPrompt("Master passphrase: ", func(pass string, canceled bool) {
+			if canceled {
+				return
+			}
+			if err := config.UnlockSecrets(pass); err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			then()
+		})
+		return
+	}
+	InfoBar.SecretPrompt("New master passphrase: ", func(pass string, canceled bool) {
+		if canceled {
+			return
+		}
+		if pass == "" {
+			InfoBar.Error("The passphrase can't be empty")
+			return
+		}
+		InfoBar.SecretPrompt("Repeat the passphrase: ", func(again string, canceled bool) {
+			if canceled {
+				return
+			}
+			if again != pass {
+				InfoBar.Error("The passphrases don't match")
+				return
+			}
+			if err := config.UnlockSecrets(pass); err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			then()
+		})
+	})
+}
+
+// expandSecrets replaces the references to the secrets in the strings, and
+// calls then with them. The master passphrase is asked first if it is
+// needed.
+func expandSecrets(strs []string, then func([]string)) {
+	out := make([]string, len(strs))
+	for i, s := range strs {
+		v, err := config.ExpandSecrets(s)
+		if err == config.ErrSecretsLocked {
+			unlockSecrets(func() { expandSecrets(strs, then) })
+			return
+		} else if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		out[i] = v
+	}
+	then(out)
+}
+
+// SecretCmd manages the secrets used by the integrations and the plugins
+// (see SecretCmds): 'secret set name' asks the value of a secret and 'secret
+// unlock' the master passphrase
+func (h *BufPane) SecretCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Error("Usage: secret list|lock|remove|set|unlock")
+		return
+	}
+	switch args[0] {
+	case "unlock":
+		unlockSecrets(func() { InfoBar.Message("Secrets unlocked") })
+	case "lock":
+		config.LockSecrets()
+		InfoBar.Message("Secrets locked")
+	case "list":
+		if names := config.SecretNames(); len(names) > 0 {
+			InfoBar.Message(strings.Join(names, " "))
+		} else {
+			InfoBar.Message("No secrets")
+		}
+	case "set", "remove":
+		if len(args) != 2 {
+			InfoBar.Error("Usage: secret " + args[0] + " 'name'")
+			return
+		}
+		name := args[1]
+		if args[0] == "remove" {
+			if err := config.RemoveSecret(name); err != nil {
+				InfoBar.Error(err)
+			} else {
+				InfoBar.Message("Removed the secret " + name)
+			}
+			return
+		}
+		unlockSecrets(func() {
+			InfoBar.SecretPrompt("Value of "+name+": ", func(value string, canceled bool) {
+				if canceled {
+					return
+				}
+				if err := config.SetSecret(name, value); err != nil {
+					InfoBar.Error(err)
+				} else {
+					InfoBar.Message("Saved the secret " + name)
+				}
+			})
+		})
+	default:
+		InfoBar.Error("Usage: secret list|lock|remove|set|unlock")
+	}
+}
@@ -0,0 +1,445 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/display"
+	"github.com/zyedidia/micro/v2/internal/project"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/internal/views"
+)
+
+// A session is the state of the editor which is saved by `session save`:
+// the working directory and the layout of the tabs
+type session struct {
+	Dir    string       `json:"dir"`
+	Tabs   []sessionTab `json:"tabs"`
+	Active int          `json:"active"`
+}
+
+type sessionTab struct {
+	Layout *sessionNode `json:"layout"`
+}
+
+// A sessionNode is a split of a tab. Splits with children are divided in
+// panes side by side (vsplit) or on top of each other (hsplit), the others
+// show a file, a terminal, or an empty buffer if they have neither.
+type sessionNode struct {
+	Split    string         `json:"split,omitempty"`
+	Children []*sessionNode `json:"children,omitempty"`
+	// Size is the proportion of the parent split taken by this split
+	Size float64 `json:"size,omitempty"`
+
+	Path      string      `json:"path,omitempty"`
+	Cursor    *buffer.Loc `json:"cursor,omitempty"`
+	StartLine int         `json:"startline,omitempty"`
+	Active    bool        `json:"active,omitempty"`
+
+	Terminal *sessionTerminal `json:"terminal,omitempty"`
+
+	// Command is a command run in the split, and Current marks the split
+	// showing the current buffer, in the layout presets
+	Command string `json:"command,omitempty"`
+	Current bool   `json:"current,omitempty"`
+}
+
+// A sessionTerminal is a terminal pane, which is started again with its
+// command in its working directory when the session is loaded
+type sessionTerminal struct {
+	Command []string `json:"command"`
+	Dir     string   `json:"dir,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	// Last is the last command entered in the terminal, typed again when
+	// the sessionreplay option is on
+	Last string `json:"last,omitempty"`
+}
+
+// SessionCmds are the subcommands of the session command
+var SessionCmds = []string{"save", "load"}
+
+// autosession is set if the autosession of the working directory is saved
+// when micro exits, which is the case when micro is started without files
+var autosession bool
+
+// sessionDir returns the directory storing the named sessions. The
+// sessions are stored with the project of the working directory if it is
+// in one.
+func sessionDir() string {
+	if wd, err := os.Getwd(); err == nil {
+		if root, ok := util.FindProjectRoot(wd); ok {
+			return filepath.Join(project.StateDir(root), "sessions")
+		}
+	}
+	return filepath.Join(config.StateDir, "sessions")
+}
+
+// autosessionPath returns the file storing the autosession of dir. All the
+// directories of a project share the autosession of the project.
+func autosessionPath(dir string) string {
+	if root, ok := util.FindProjectRoot(dir); ok {
+		return filepath.Join(project.StateDir(root), "autosession.json")
+	}
+	return filepath.Join(config.StateDir, "sessions", "auto", util.EscapePath(dir)+".json")
+}
+
+// sessionPath returns the file storing the session with the given name
+func sessionPath(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+		return "", errors.New("Invalid session name: " + name)
+	}
+	return filepath.Join(sessionDir(), name+".json"), nil
+}
+
+// SessionCmd saves or loads a named session
+func (h *BufPane) SessionCmd(args []string) {
+	if len(args) != 2 {
+		InfoBar.Error("Usage: session save|load 'name'")
+		return
+	}
+	path, err := sessionPath(args[1])
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+
+	switch args[0] {
+	case "save":
+		if err := writeSession(path, currentSession()); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		InfoBar.Message("Saved session " + args[1])
+	case "load":
+		s, err := readSession(path)
+		if os.IsNotExist(err) {
+			InfoBar.Error("No session named " + args[1])
+			return
+		} else if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		if err := loadSession(s); err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		InfoBar.Message("Loaded session " + args[1])
+	default:
+		InfoBar.Error("Unknown session command " + args[0])
+	}
+}
+
+// LoadAutosession restores the autosession of the working directory if
+// the autosession option is on. It is called at startup when no files are
+// given, and makes micro save the autosession when it exits.
+func LoadAutosession() {
+	if !config.GetGlobalOption("autosession").(bool) {
+		return
+	}
+	autosession = true
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	s, err := readSession(autosessionPath(wd))
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if err := loadSession(s); err != nil {
+		InfoBar.Error(err)
+	}
+}
+
+// saveAutosession saves the session as the autosession of the working
+// directory, if there are files open
+func saveAutosession() {
+	if !autosession || !config.GetGlobalOption("autosession").(bool) {
+		return
+	}
+	s := currentSession()
+	files := false
+	for _, t := range s.Tabs {
+		files = files || t.Layout.hasFiles()
+	}
+	if files {
+		if err := writeSession(autosessionPath(s.Dir), s); err != nil {
+			screen.TermMessage(err)
+		}
+	}
+}
+
+func (n *sessionNode) hasFiles() bool {
+	if n.Path != "" || n.Terminal != nil {
+		return true
+	}
+	for _, c := range n.Children {
+		if c.hasFiles() {
+			return true
+		}
+	}
+	return false
+}
+
+func readSession(path string) (*session, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(session)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.New("Error reading session " + path + ": " + err.Error())
+	}
+	return s, nil
+}
+
+func writeSession(path string, s *session) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// currentSession returns the session of the open tabs
+func currentSession() *session {
+	s := &session{Active: Tabs.Active()}
+	s.Dir, _ = os.Getwd()
+	for _, t := range Tabs.List {
+		s.Tabs = append(s.Tabs, sessionTab{t.sessionLayout(t.Node)})
+	}
+	return s
+}
+
+// sessionLayout returns the layout of the split n of the tab. Only the
+// panes showing a file or a terminal are saved, the other ones are saved as
+// empty panes.
+func (t *Tab) sessionLayout(n *views.Node) *sessionNode {
+	sn := new(sessionNode)
+	if n.IsLeaf() {
+		for i, p := range t.Panes {
+			if p.ID() != n.ID() {
+				continue
+			}
+			sn.Active = i == t.active
+			if cp, ok := p.(*CopyModePane); ok {
+				p = cp.term
+			}
+			if bp, ok := p.(*BufPane); ok && bp.Buf.Type == buffer.BTDefault && bp.Buf.Path != "" {
+				sn.Path = bp.Buf.AbsPath
+				loc := bp.Cursor.Loc
+				sn.Cursor = &loc
+				sn.StartLine = bp.GetView().StartLine.Line
+			} else if tp, ok := p.(*TermPane); ok && len(tp.Command()) > 0 {
+				sn.Terminal = &sessionTerminal{
+					Command: tp.Command(),
+					Dir:     tp.WorkingDir(),
+					Env:     tp.Env,
+					Last:    tp.LastCommand,
+				}
+			}
+		}
+		return sn
+	}
+
+	sn.Split = "hsplit"
+	if n.Kind == views.STHoriz {
+		sn.Split = "vsplit"
+	}
+	for _, c := range n.Children() {
+		cn := t.sessionLayout(c)
+		if n.Kind == views.STHoriz {
+			cn.Size = float64(c.W) / float64(n.W)
+		} else {
+			cn.Size = float64(c.H) / float64(n.H)
+		}
+		sn.Children = append(sn.Children, cn)
+	}
+	return sn
+}
+
+// loadSession replaces the open tabs by the ones of the session. The
+// buffers must not have unsaved changes.
+func loadSession(s *session) error {
+	if len(s.Tabs) == 0 {
+		return errors.New("The session has no tabs")
+	}
+	for _, b := range buffer.OpenBuffers {
+		if b.Unsaved() {
+			return errors.New("Save or close the modified buffers before loading a session")
+		}
+	}
+	if s.Dir != "" {
+		if err := os.Chdir(s.Dir); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range Tabs.List {
+		for _, p := range t.Panes {
+			p.Close()
+		}
+	}
+	w, h := screen.Screen.Size()
+	iOffset := config.GetInfoBarOffset()
+	Tabs.List = nil
+	for range s.Tabs {
+		b := buffer.NewBufferFromString("", "", buffer.BTDefault)
+		Tabs.List = append(Tabs.List, NewTabFromBuffer(0, 0, w, h-iOffset, b))
+	}
+	Tabs.Resize()
+
+	for i, st := range s.Tabs {
+		Tabs.SetActive(i)
+		t := Tabs.List[i]
+		p := t.Panes[0].(*BufPane)
+		active := p.restoreLayout(st.Layout)
+		restoreSizes(t.Node, st.Layout)
+		t.Resize()
+		for j, tp := range t.Panes {
+			if tp == active {
+				t.SetActive(j)
+			}
+		}
+	}
+	Tabs.SetActive(util.Clamp(s.Active, 0, len(Tabs.List)-1))
+	return nil
+}
+
+// restoreLayout splits the pane according to the layout and opens its
+// files and terminals. It returns the pane which was active in the layout.
+func (h *BufPane) restoreLayout(n *sessionNode) Pane {
+	if n == nil {
+		return h
+	}
+	if len(n.Children) == 0 {
+		var p Pane = h
+		if n.Terminal != nil {
+			if tp, err := h.openSessionTerminal(n.Terminal); err != nil {
+				InfoBar.Error(err)
+			} else {
+				p = tp
+			}
+		} else if n.Path != "" {
+			if _, err := os.Stat(n.Path); err == nil {
+				h.openSessionFile(n)
+			}
+		} else if n.Command != "" {
+			h.HandleCommand(n.Command)
+		}
+		if n.Active {
+			return p
+		}
+		return nil
+	}
+
+	panes := []*BufPane{h}
+	for i := 1; i < len(n.Children); i++ {
+		b := buffer.NewBufferFromString("", "", buffer.BTDefault)
+		if n.Split == "vsplit" {
+			panes = append(panes, panes[i-1].VSplitIndex(b, true))
+		} else {
+			panes = append(panes, panes[i-1].HSplitIndex(b, true))
+		}
+	}
+	var active Pane
+	for i, c := range n.Children {
+		if a := panes[i].restoreLayout(c); a != nil {
+			active = a
+		}
+	}
+	return active
+}
+
+// openSessionFile opens the file of a split of a session and restores its
+// cursor and scroll position
+func (h *BufPane) openSessionFile(n *sessionNode) {
+	path := n.Path
+	if wd, err := os.Getwd(); err == nil && strings.HasPrefix(path, wd+string(filepath.Separator)) {
+		path, _ = util.MakeRelative(path, wd)
+	}
+	var loc buffer.Loc
+	if n.Cursor != nil {
+		loc = *n.Cursor
+	}
+	if h.openAt(path, loc) == nil {
+		return
+	}
+	v := h.GetView()
+	v.StartLine = display.SLoc{Line: util.Clamp(n.StartLine, 0, h.Buf.LinesNum()-1)}
+	h.SetView(v)
+	h.Relocate()
+}
+
+// openSessionTerminal starts the terminal of a split of a session in place
+// of the pane, and types its last command again if the sessionreplay option
+// is on
+func (h *BufPane) openSessionTerminal(st *sessionTerminal) (*TermPane, error) {
+	if !TermEmuSupported {
+		return nil, errors.New("Terminal emulator is not supported on this system")
+	}
+	t := new(shell.Terminal)
+	if _, err := os.Stat(st.Dir); err == nil {
+		t.Dir = st.Dir
+	}
+	t.Env = st.Env
+	args := st.Command
+	if len(args) == 0 {
+		sh, err := h.termShell()
+		if err != nil {
+			return nil, err
+		}
+		args = sh
+	}
+	if err := t.Start(args, false, true, nil, nil); err != nil {
+		return nil, err
+	}
+	v := h.GetView()
+	tp, err := NewTermPane(v.X, v.Y, v.Width, v.Height, t, h.ID(), h.tab)
+	if err != nil {
+		return nil, err
+	}
+	h.Close()
+	h.tab.Panes[h.tab.GetPane(h.ID())] = tp
+	if st.Last != "" && config.GetGlobalOption("sessionreplay").(bool) {
+		tp.WriteString(st.Last + "\r")
+	}
+	return tp, nil
+}
+
+// restoreSizes resizes the splits of the tree n to the sizes of the layout,
+// as long as they have the same shape
+func restoreSizes(n *views.Node, sn *sessionNode) {
+	children := n.Children()
+	if sn == nil || len(children) != len(sn.Children) {
+		return
+	}
+	if (n.Kind == views.STHoriz) != (sn.Split == "vsplit") {
+		return
+	}
+	total := n.H
+	if n.Kind == views.STHoriz {
+		total = n.W
+	}
+	for i := 0; i+1 < len(children); i++ {
+		if size := int(sn.Children[i].Size*float64(total) + 0.5); size > 0 {
+			n.ResizeChild(i, size)
+		}
+	}
+	for i, c := range children {
+		restoreSizes(c, sn.Children[i])
+	}
+}
@@ -0,0 +1,112 @@
+package action
+
+import (
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// smoothScrollTick is the time between two steps of a smooth scroll
+const smoothScrollTick = 10 * time.Millisecond
+
+// A scrollTickEvent is posted to the event loop for each step of a smooth
+// scroll
+type scrollTickEvent struct {
+	*tcell.EventTime
+	pane *BufPane
+}
+
+func (e *scrollTickEvent) EscSeq() string {
+	return ""
+}
+
+// smoothScroll runs an action that moves the view and, if the smoothscroll
+// option is on, animates the view from where it was to where the action
+// moved it, smoothscroll lines at a time. A smooth scroll that is running
+// continues from the line it showed, towards the new target.
+func (h *BufPane) smoothScroll(action func()) {
+	v := h.GetView()
+	from := v.StartLine
+	if h.scrollResume != nil {
+		from = *h.scrollResume
+		h.scrollResume = nil
+	}
+	action()
+
+	if util.IntOpt(h.Buf.Settings["smoothscroll"]) <= 0 || config.GetGlobalOption("screenreader").(bool) {
+		return
+	}
+	v = h.GetView()
+	if h.Diff(from, v.StartLine) == 0 {
+		return
+	}
+	h.scrollTarget = v.StartLine
+	v.StartLine = from
+	h.SetView(v)
+	h.scheduleScrollTick()
+}
+
+func (h *BufPane) scheduleScrollTick() {
+	h.scrollTimer = time.AfterFunc(smoothScrollTick, func() {
+		ev := &scrollTickEvent{new(tcell.EventTime), h}
+		ev.SetEventNow()
+		screen.Events <- ev
+	})
+}
+
+// scrollTick moves the view one step closer to the target of the smooth
+// scroll
+func (h *BufPane) scrollTick() {
+	if h.scrollTimer == nil {
+		return
+	}
+	h.scrollTimer = nil
+
+	v := h.GetView()
+	n := util.Max(util.IntOpt(h.Buf.Settings["smoothscroll"]), 1)
+	diff := h.Diff(v.StartLine, h.scrollTarget)
+	if util.Abs(diff) <= n {
+		v.StartLine = h.scrollTarget
+	} else if diff > 0 {
+		v.StartLine = h.Scroll(v.StartLine, n)
+	} else {
+		v.StartLine = h.Scroll(v.StartLine, -n)
+	}
+	h.SetView(v)
+	h.syncBoundScroll()
+
+	if v.StartLine != h.scrollTarget {
+		h.scheduleScrollTick()
+	}
+}
+
+// interruptSmoothScroll ends a running smooth scroll on the events that
+// may move the view or the cursor, moving the view to the target of the
+// scroll. The line that was shown is kept in scrollResume so that a new
+// smooth scroll started by the event continues from it.
+func (h *BufPane) interruptSmoothScroll(event tcell.Event) {
+	h.scrollResume = nil
+	if h.scrollTimer == nil {
+		return
+	}
+	switch e := event.(type) {
+	case *tcell.EventKey, *tcell.EventPaste, *tcell.EventRaw:
+	case *tcell.EventMouse:
+		if e.Buttons() == tcell.ButtonNone {
+			return
+		}
+	default:
+		return
+	}
+
+	h.scrollTimer.Stop()
+	h.scrollTimer = nil
+	v := h.GetView()
+	shown := v.StartLine
+	h.scrollResume = &shown
+	v.StartLine = h.scrollTarget
+	h.SetView(v)
+}
@@ -0,0 +1,45 @@
+package action
+
+// SnippetExpand replaces the prefix of a snippet before the cursor by the
+// snippet, and selects its first tab stop
+func (h *BufPane) SnippetExpand() bool {
+	if h.Buf.NumCursors() > 1 || h.Cursor.HasSelection() {
+		return false
+	}
+	s, start, ok := h.Buf.SnippetBefore(h.Cursor.Loc)
+	if !ok {
+		return false
+	}
+	h.Buf.InsertSnippet(start, h.Cursor.Loc, s.Body)
+	h.Relocate()
+	return true
+}
+
+// SnippetNext selects the next tab stop of the snippet being filled in
+func (h *BufPane) SnippetNext() bool {
+	if !h.Buf.SnippetNext() {
+		return false
+	}
+	h.Relocate()
+	return true
+}
+
+// SnippetPrevious selects the previous tab stop of the snippet being filled
+// in
+func (h *BufPane) SnippetPrevious() bool {
+	if !h.Buf.SnippetPrevious() {
+		return false
+	}
+	h.Relocate()
+	return true
+}
+
+// SnippetCancel stops visiting the tab stops of the snippet being filled
+// in, leaving its text as it is
+func (h *BufPane) SnippetCancel() bool {
+	if !h.Buf.InSnippet() {
+		return false
+	}
+	h.Buf.EndSnippet()
+	return true
+}
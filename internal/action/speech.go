@@ -0,0 +1,125 @@
+package action
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/util"
+	luar "layeh.com/gopher-luar"
+)
+
+// speech is the process speaking the last announcement, which is stopped
+// by the next one
+var speech *exec.Cmd
+
+// Speak announces text through the speechcmd option, after the onSpeak
+// callbacks of the plugins, which cancel it by returning false. The option
+// is either a command, which is given the text on its standard input, such
+// as `espeak`, or unix:PATH or tcp:HOST:PORT for a socket, which is sent
+// the text as a line.
+func Speak(text string) {
+	if text == "" {
+		return
+	}
+	if ok, err := config.RunPluginFnBool("onSpeak", luar.New(ulua.L, text)); err != nil {
+		util.Log(util.LogError, "editor", err)
+	} else if !ok {
+		return
+	}
+
+	speechcmd := config.GetGlobalOption("speechcmd").(string)
+	if speechcmd == "" {
+		return
+	}
+	for _, network := range []string{"unix", "tcp"} {
+		if addr := strings.TrimPrefix(speechcmd, network+":"); addr != speechcmd {
+			go func() {
+				conn, err := net.DialTimeout(network, addr, time.Second)
+				if err != nil {
+					util.Log(util.LogError, "editor", "Error connecting to the speech socket:", err)
+					return
+				}
+				defer conn.Close()
+				conn.SetWriteDeadline(time.Now().Add(time.Second))
+				conn.Write([]byte(text + "\n"))
+			}()
+			return
+		}
+	}
+
+	if speech != nil {
+		speech.Process.Kill()
+	}
+	args, err := shellquote.Split(speechcmd)
+	if err != nil || len(args) == 0 {
+		util.Log(util.LogError, "editor", "Error parsing speechcmd:", err)
+		return
+	}
+	speech = exec.Command(args[0], args[1:]...)
+	speech.Stdin = strings.NewReader(text)
+	if err := speech.Start(); err != nil {
+		util.Log(util.LogError, "editor", "Error running speechcmd:", err)
+		speech = nil
+		return
+	}
+	go speech.Wait()
+}
+
+// announced is what Announce last spoke about
+var announced struct {
+	pane *BufPane
+	loc  buffer.Loc
+	msg  string
+}
+
+// characterName returns how a character is spoken
+func characterName(r rune) string {
+	switch r {
+	case ' ':
+		return "space"
+	case '\t':
+		return "tab"
+	case '\n':
+		return "end of line"
+	}
+	return string(r)
+}
+
+// Announce speaks what the last event changed when the screenreader option
+// is on: the message of the info bar, and the line and the column of the
+// cursor, with the text of the line when the cursor moved to another line or
+// the character under the cursor otherwise
+func Announce() {
+	if !config.GetGlobalOption("screenreader").(bool) {
+		return
+	}
+
+	var parts []string
+	if InfoBar.Msg != announced.msg {
+		announced.msg = InfoBar.Msg
+		if InfoBar.Msg != "" {
+			parts = append(parts, InfoBar.Msg)
+		}
+	}
+	if h := MainTab().CurPane(); h != nil && !InfoBar.HasPrompt {
+		loc := h.Cursor.Loc
+		if h != announced.pane || loc.Y != announced.loc.Y {
+			line := string(h.Buf.LineBytes(loc.Y))
+			if strings.TrimSpace(line) == "" {
+				line = "blank"
+			}
+			parts = append(parts, fmt.Sprintf("line %d column %d", loc.Y+1, loc.X+1), line)
+		} else if loc.X != announced.loc.X {
+			parts = append(parts, characterName(h.Cursor.RuneUnder(loc.X)), fmt.Sprintf("column %d", loc.X+1))
+		}
+		announced.pane, announced.loc = h, loc
+	}
+	Speak(strings.Join(parts, ", "))
+}
@@ -0,0 +1,88 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/spell"
+)
+
+// maxSpellSuggestions is the number of suggestions offered for a
+// misspelled word
+const maxSpellSuggestions = 8
+
+// ToggleSpell toggles spell checking for the current buffer
+func (h *BufPane) ToggleSpell() bool {
+	if h.Buf.Settings["spell"].(bool) {
+		h.Buf.SetOptionNative("spell", false)
+		InfoBar.Message("Disabled spell checking")
+		return true
+	}
+	if _, err := h.Buf.Dictionary(); err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	h.Buf.SetOptionNative("spell", true)
+	InfoBar.Message("Enabled spell checking")
+	return true
+}
+
+// spellWord returns the spell checked word under the cursor, or just
+// before it
+func (h *BufPane) spellWord() (spell.Word, bool) {
+	for _, w := range h.Buf.Words(h.Cursor.Y) {
+		if h.Cursor.X >= w.Start && h.Cursor.X <= w.End {
+			return w, true
+		}
+	}
+	return spell.Word{}, false
+}
+
+// SpellSuggest replaces the word under the cursor by the first spelling
+// suggestion. The other suggestions are shown in the statusline and are
+// cycled through like autocompletions, the original word being the last.
+func (h *BufPane) SpellSuggest() bool {
+	if h.Buf.HasSuggestions {
+		h.Buf.CycleAutocomplete(true)
+		return true
+	}
+
+	d, err := h.Buf.Dictionary()
+	if err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	w, ok := h.spellWord()
+	if !ok {
+		InfoBar.Message("No word under the cursor")
+		return false
+	}
+	suggestions := d.Suggest(w.Text, maxSpellSuggestions)
+	if len(suggestions) == 0 {
+		InfoBar.Message("No spelling suggestions for " + w.Text)
+		return false
+	}
+
+	start := buffer.Loc{X: w.Start, Y: h.Cursor.Y}
+	end := buffer.Loc{X: w.End, Y: h.Cursor.Y}
+	suggestions = append(suggestions, string(h.Buf.Substr(start, end)))
+	h.Cursor.Deselect(true)
+	h.Buf.Remove(start, end)
+	h.Cursor.GotoLoc(start)
+	return h.Buf.Autocomplete(func(*buffer.Buffer) ([]string, []string) {
+		return suggestions, suggestions
+	})
+}
+
+// SpellAddWord adds the word under the cursor to the personal dictionary
+func (h *BufPane) SpellAddWord() bool {
+	w, ok := h.spellWord()
+	if !ok {
+		InfoBar.Message("No word under the cursor")
+		return false
+	}
+	if err := spell.AddPersonal(w.Text); err != nil {
+		InfoBar.Error(err)
+		return false
+	}
+	InfoBar.Message("Added " + w.Text + " to the personal dictionary")
+	return true
+}
@@ -0,0 +1,99 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+)
+
+// surroundOp changes the text around a cursor, and returns whether it did
+type surroundOp func(b *buffer.Buffer, c *buffer.Cursor) bool
+
+// Surround asks for a character and surrounds the selection of each cursor,
+// or the word under it, with the brackets or quotes it belongs to
+func (h *BufPane) Surround() bool {
+	h.askRune("Surround with: ", func(r rune) {
+		h.runRuneAction("Surround", r)
+	})
+	return true
+}
+
+// ChangeSurround asks for the character of the pair around each cursor and
+// the character of the pair replacing it, t standing for a tag
+func (h *BufPane) ChangeSurround() bool {
+	h.askRune("Change surrounding: ", func(from rune) {
+		h.askRune("Change surrounding "+string(from)+" to: ", func(to rune) {
+			h.runRuneAction("ChangeSurround", from, to)
+		})
+	})
+	return true
+}
+
+// DeleteSurround asks for the character of the pair around each cursor and
+// removes it, t standing for a tag
+func (h *BufPane) DeleteSurround() bool {
+	h.askRune("Delete surrounding: ", func(r rune) {
+		h.runRuneAction("DeleteSurround", r)
+	})
+	return true
+}
+
+// A runeAction is the part of an action run once it got the characters it
+// asked for, which is what macros record and replay so that they don't ask
+// for the characters again
+type runeAction struct {
+	nargs int
+	apply func(h *BufPane, args []rune) bool
+}
+
+var runeActions = map[string]runeAction{
+	"Surround": {1, func(h *BufPane, args []rune) bool {
+		return h.applySurround(func(b *buffer.Buffer, c *buffer.Cursor) bool {
+			return b.Surround(c, args[0])
+		}, "Nothing to surround")
+	}},
+	"ChangeSurround": {2, func(h *BufPane, args []rune) bool {
+		return h.applySurround(func(b *buffer.Buffer, c *buffer.Cursor) bool {
+			return b.ChangeSurround(c, args[0], args[1])
+		}, "No surrounding "+string(args[0]))
+	}},
+	"DeleteSurround": {1, func(h *BufPane, args []rune) bool {
+		return h.applySurround(func(b *buffer.Buffer, c *buffer.Cursor) bool {
+			return b.DeleteSurround(c, args[0])
+		}, "No surrounding "+string(args[0]))
+	}},
+}
+
+// runRuneAction applies the rune action name with the characters it asked
+// for, and records it in the macro being recorded and for Repeat
+func (h *BufPane) runRuneAction(name string, args ...rune) {
+	b, edits := h.Buf, h.Buf.Edits()
+	runeActions[name].apply(h, args)
+	if recording_macro {
+		recordAction(name, args...)
+	}
+	h.noteEdit(b, edits, macroStep{action: name, args: args})
+}
+
+// askRune shows prompt in the infobar and calls f with the next character
+// typed in the pane. Any other key cancels it.
+func (h *BufPane) askRune(prompt string, f func(r rune)) {
+	InfoBar.Message(prompt)
+	h.readRune = f
+}
+
+// applySurround applies op to every cursor, as a single undo step, and
+// shows failure if it changed nothing
+func (h *BufPane) applySurround(op surroundOp, failure string) bool {
+	done := false
+	h.Buf.UndoGroup(func() {
+		for _, c := range h.Buf.GetCursors() {
+			if op(h.Buf, c) {
+				done = true
+			}
+		}
+	})
+	h.Relocate()
+	if !done {
+		InfoBar.Message(failure)
+	}
+	return done
+}
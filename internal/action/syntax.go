@@ -0,0 +1,102 @@
+package action
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/quickfix"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// SyntaxCmds are the subcommands of the syntax command
+var SyntaxCmds = []string{"check", "test"}
+
+// yamlErrorLine finds the line of a yaml syntax error
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// SyntaxCmd checks a syntax file with "syntax check", reporting its errors
+// and its likely mistakes, or runs a syntax test with "syntax test". The
+// current buffer is checked or tested unless a file is given, and the
+// problems are its messages, or else they are listed in a pane as the
+// errors of a build.
+func (h *BufPane) SyntaxCmd(args []string) {
+	if len(args) < 1 || len(args) > 2 || args[0] != "check" && args[0] != "test" {
+		InfoBar.Error("Usage: syntax check|test [file]")
+		return
+	}
+	text := h.Buf.Bytes()
+	path := ""
+	if len(args) == 2 {
+		path, _ = util.ReplaceHome(args[1])
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		text = data
+	}
+
+	var msgs []*buffer.Message
+	passed := "No problems found in the syntax file"
+	if args[0] == "check" {
+		for _, err := range highlight.Validate(text) {
+			msgs = append(msgs, syntaxMessage(err, buffer.MTError))
+		}
+		for _, err := range highlight.Lint(text) {
+			msgs = append(msgs, syntaxMessage(err, buffer.MTWarning))
+		}
+	} else {
+		ft, failed, err := buffer.RunSyntaxTest(string(text))
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		msgs = failed
+		passed = "The syntax test of " + ft + " passed"
+	}
+
+	if path == "" {
+		h.Buf.SetMessages("syntax", msgs)
+		if len(msgs) == 0 {
+			InfoBar.Message(passed)
+		} else {
+			InfoBar.Error(fmt.Sprintf("%d problems found, see the messages of the buffer", len(msgs)))
+		}
+		return
+	}
+	if len(msgs) == 0 {
+		InfoBar.Message(passed)
+		return
+	}
+	var entries []quickfix.Entry
+	for _, m := range msgs {
+		kind := "error"
+		if m.Kind == buffer.MTWarning {
+			kind = "warning"
+		}
+		entries = append(entries, quickfix.Entry{Path: path, Line: m.Start.Y, Col: m.Start.X, Kind: kind, Text: m.Msg})
+	}
+	wd, _ := os.Getwd()
+	h.showErrors(wd, "syntax "+strings.Join(args, " "), entries)
+}
+
+// syntaxMessage returns the message of an error of a syntax file, at its
+// position when it has one
+func syntaxMessage(err error, kind buffer.MsgType) *buffer.Message {
+	loc := buffer.Loc{X: 0, Y: 0}
+	msg := err.Error()
+	if se, ok := err.(*highlight.SyntaxError); ok {
+		loc = buffer.Loc{X: se.Column - 1, Y: se.Line - 1}
+		msg = se.Msg
+	} else if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		loc.Y = n - 1
+	}
+	return buffer.NewMessage("syntax", msg, loc, buffer.Loc{X: loc.X + 1, Y: loc.Y}, kind)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/zyedidia/micro/v2/internal/config"
 	"github.com/zyedidia/micro/v2/internal/display"
 	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/micro/v2/internal/views"
 	"github.com/zyedidia/tcell/v2"
 )
@@ -14,6 +15,9 @@ import (
 type TabList struct {
 	*display.TabWindow
 	List []*Tab
+
+	// dragging is the tab whose name is being dragged on the tab bar
+	dragging *Tab
 }
 
 // NewTabList creates a TabList from a list of buffers by creating a Tab
@@ -40,9 +44,26 @@ func NewTabList(bufs []*buffer.Buffer) *TabList {
 // correct
 func (t *TabList) UpdateNames() {
 	t.Names = t.Names[:0]
-	for _, p := range t.List {
-		t.Names = append(t.Names, p.Panes[p.active].Name())
+	for i, p := range t.List {
+		pane := p.Panes[p.active]
+		var b *buffer.Buffer
+		if bp, ok := pane.(*BufPane); ok {
+			b = bp.Buf
+		}
+		t.Names = append(t.Names, display.FormatTabName(i, b, pane.Name()))
+	}
+}
+
+// UpdateTerminal sets the title of the terminal and tells it the directory
+// for the active pane of the active tab
+func (t *TabList) UpdateTerminal() {
+	i := t.Active()
+	pane := t.List[i].Panes[t.List[i].active]
+	var b *buffer.Buffer
+	if bp := t.List[i].CurPane(); bp != nil {
+		b = bp.Buf
 	}
+	display.UpdateTerminal(i, b, pane.Name())
 }
 
 // AddTab adds a new tab to this TabList
@@ -104,6 +125,10 @@ func (t *TabList) HandleEvent(event tcell.Event) {
 		mx, my := e.Position()
 		switch e.Buttons() {
 		case tcell.Button1:
+			if t.List[t.Active()].moving != nil {
+				// a pane is being dragged over the tab bar
+				break
+			}
 			if my == t.Y && mx == 0 {
 				t.Scroll(-4)
 				return
@@ -111,9 +136,29 @@ func (t *TabList) HandleEvent(event tcell.Event) {
 				t.Scroll(4)
 				return
 			}
+			if t.dragging != nil {
+				// move the dragged tab to the slot under the mouse
+				ind := t.LocFromVisual(buffer.Loc{X: mx, Y: my})
+				if ind != -1 && t.List[ind] != t.dragging {
+					for i, p := range t.List {
+						if p == t.dragging {
+							t.MoveTab(i, ind)
+							break
+						}
+					}
+				}
+				return
+			}
 			if len(t.List) > 1 {
+				if ind := t.CloseFromVisual(buffer.Loc{X: mx, Y: my}); ind != -1 {
+					t.CloseTab(ind)
+					return
+				}
 				ind := t.LocFromVisual(buffer.Loc{mx, my})
 				if ind != -1 {
+					if t.List[t.Active()].release {
+						t.dragging = t.List[ind]
+					}
 					t.SetActive(ind)
 					return
 				}
@@ -121,6 +166,19 @@ func (t *TabList) HandleEvent(event tcell.Event) {
 					return
 				}
 			}
+		case tcell.ButtonNone:
+			t.dragging = nil
+			// a pane dropped on the name of another tab is moved to it
+			if a := t.List[t.Active()]; a.moving != nil {
+				ind := t.LocFromVisual(buffer.Loc{X: mx, Y: my})
+				if ind != -1 && ind != t.Active() {
+					p := a.moving
+					a.moving = nil
+					a.release = true
+					t.MovePane(p, t.List[ind])
+					return
+				}
+			}
 		case tcell.WheelUp:
 			if my == t.Y {
 				t.Scroll(4)
@@ -136,6 +194,73 @@ func (t *TabList) HandleEvent(event tcell.Event) {
 	t.List[t.Active()].HandleEvent(event)
 }
 
+// CloseTab closes the tab at index i and all its panes, after asking for
+// confirmation if buffers shown in it are modified
+func (t *TabList) CloseTab(i int) {
+	tab := t.List[i]
+	closeTab := func() {
+		for _, p := range tab.Panes {
+			p.Close()
+		}
+		t.RemoveTab(tab.Panes[0].ID())
+	}
+	if len(t.List) == 1 {
+		return
+	}
+
+	for _, p := range tab.Panes {
+		if bp, ok := p.(*BufPane); ok && bp.Buf.Unsaved() {
+			InfoBar.YNPrompt("Close tab? (modified buffers will be closed without saving)", func(yes, canceled bool) {
+				if !canceled && yes {
+					closeTab()
+				}
+			})
+			return
+		}
+	}
+	closeTab()
+}
+
+// MoveTab moves the tab at index from to the index to, shifting the tabs
+// in between, and makes it active
+func (t *TabList) MoveTab(from, to int) {
+	tab := t.List[from]
+	copy(t.List[from:], t.List[from+1:])
+	t.List = t.List[:len(t.List)-1]
+	t.List = append(t.List, nil)
+	copy(t.List[to+1:], t.List[to:])
+	t.List[to] = tab
+	t.UpdateNames()
+	t.SetActive(to)
+}
+
+// MovePane moves a pane to the tab dest, in a split to the right of the
+// active pane of dest which becomes the active tab. If the pane was the
+// only one of its tab, the tab is closed.
+func (t *TabList) MovePane(p Pane, dest *Tab) {
+	src := p.Tab()
+	if src == dest {
+		return
+	}
+	if len(src.Panes) == 1 {
+		t.RemoveTab(p.ID())
+	} else {
+		src.removePane(p)
+	}
+
+	n := dest.GetNode(dest.Panes[dest.active].ID())
+	p.SetTab(dest)
+	p.SetID(n.VSplit(true))
+	dest.Panes = append(dest.Panes, p)
+	for i, d := range t.List {
+		if d == dest {
+			t.SetActive(i)
+		}
+	}
+	dest.Resize()
+	dest.SetActive(len(dest.Panes) - 1)
+}
+
 // Display updates the names and then displays the tab bar
 func (t *TabList) Display() {
 	t.UpdateNames()
@@ -166,6 +291,10 @@ type Tab struct {
 	active int
 
 	resizing *views.Node // node currently being resized
+	moving   Pane        // pane being dragged by its statusline
+	// maximized is the pane taking the whole tab, until another pane is
+	// activated or MaximizeSplit is run again
+	maximized Pane
 	// captures whether the mouse is released
 	release bool
 }
@@ -204,6 +333,10 @@ func NewTabFromPane(x, y, width, height int, pane Pane) *Tab {
 func (t *Tab) HandleEvent(event tcell.Event) {
 	switch e := event.(type) {
 	case *tcell.EventMouse:
+		if t.maximized != nil {
+			// the other panes and the borders of the splits are hidden
+			break
+		}
 		mx, my := e.Position()
 		switch e.Buttons() {
 		case tcell.Button1:
@@ -220,6 +353,9 @@ func (t *Tab) HandleEvent(event tcell.Event) {
 				t.Resize()
 				return
 			}
+			if t.moving != nil {
+				return
+			}
 
 			if wasReleased {
 				t.resizing = t.GetMouseSplitNode(buffer.Loc{mx, my})
@@ -232,6 +368,9 @@ func (t *Tab) HandleEvent(event tcell.Event) {
 					inpane := mx >= v.X && mx < v.X+v.Width && my >= v.Y && my < v.Y+v.Height
 					if inpane {
 						t.SetActive(i)
+						if isPaneHeader(p, my) && (len(t.Panes) > 1 || len(Tabs.List) > 1) {
+							t.moving = p
+						}
 						break
 					}
 				}
@@ -239,6 +378,17 @@ func (t *Tab) HandleEvent(event tcell.Event) {
 		case tcell.ButtonNone:
 			t.resizing = nil
 			t.release = true
+			if t.moving != nil {
+				for _, p := range t.Panes {
+					v := p.GetView()
+					inpane := mx >= v.X && mx < v.X+v.Width && my >= v.Y && my < v.Y+v.Height
+					if inpane && p != t.moving {
+						t.MovePane(t.moving, p, mx, my)
+						break
+					}
+				}
+				t.moving = nil
+			}
 		default:
 			for _, p := range t.Panes {
 				v := p.GetView()
@@ -254,8 +404,62 @@ func (t *Tab) HandleEvent(event tcell.Event) {
 	t.Panes[t.active].HandleEvent(event)
 }
 
+// isPaneHeader returns whether the screen line y is the statusline of the
+// pane p, by which the pane can be dragged
+func isPaneHeader(p Pane, y int) bool {
+	if bp, ok := p.(*BufPane); ok && !bp.Buf.Settings["statusline"].(bool) {
+		return false
+	}
+	v := p.GetView()
+	return y == v.Y+v.Height-1
+}
+
+// MovePane moves the pane p next to the pane target, on the side of target
+// where the mouse is at mx, my: in a vertical split if it is in the left or
+// right third of target and in a horizontal split above or below otherwise
+func (t *Tab) MovePane(p, target Pane, mx, my int) {
+	if len(t.Panes) < 2 || p == target {
+		return
+	}
+	v := target.GetView()
+	t.removePane(p)
+
+	n := t.GetNode(target.ID())
+	var id uint64
+	switch {
+	case mx < v.X+v.Width/3:
+		id = n.VSplit(false)
+	case mx >= v.X+v.Width-v.Width/3:
+		id = n.VSplit(true)
+	default:
+		id = n.HSplit(my >= v.Y+v.Height/2)
+	}
+	p.SetID(id)
+	t.Panes = append(t.Panes, p)
+	t.Resize()
+	t.SetActive(len(t.Panes) - 1)
+}
+
+// removePane removes the pane p and its split from the tab without closing
+// it, the other splits taking its space
+func (t *Tab) removePane(p Pane) {
+	for i, o := range t.Panes {
+		if o == p {
+			t.GetNode(p.ID()).Unsplit()
+			t.RemovePane(i)
+			break
+		}
+	}
+	t.Resize()
+	t.SetActive(util.Clamp(t.active, 0, len(t.Panes)-1))
+}
+
 // SetActive changes the currently active pane to the specified index
 func (t *Tab) SetActive(i int) {
+	if t.maximized != nil && (i >= len(t.Panes) || t.Panes[i] != t.maximized) {
+		t.maximized = nil
+		t.Resize()
+	}
 	t.active = i
 	for j, p := range t.Panes {
 		if j == i {
@@ -296,13 +500,54 @@ func (t *Tab) Resize() {
 		p.SetView(pv)
 		p.Resize(n.W-offset, n.H)
 	}
+	if t.maximized != nil {
+		if t.GetNode(t.maximized.ID()) == nil {
+			t.maximized = nil
+			return
+		}
+		pv := t.maximized.GetView()
+		pv.X, pv.Y = t.X, t.Y
+		t.maximized.SetView(pv)
+		t.maximized.Resize(t.W, t.H)
+	}
+}
+
+// Visible returns the panes displayed in the tab: all of them, or only the
+// maximized pane
+func (t *Tab) Visible() []Pane {
+	if t.maximized != nil {
+		return []Pane{t.maximized}
+	}
+	return t.Panes
+}
+
+// Display displays the borders between the splits, unless a pane is
+// maximized
+func (t *Tab) Display() {
+	if t.maximized == nil {
+		t.UIWindow.Display()
+	}
 }
 
 // CurPane returns the currently active pane
 func (t *Tab) CurPane() *BufPane {
-	p, ok := t.Panes[t.active].(*BufPane)
-	if !ok {
-		return nil
+	switch p := t.Panes[t.active].(type) {
+	case *BufPane:
+		return p
+	case *SearchPane:
+		return p.BufPane
+	case *BufferListPane:
+		return p.BufPane
+	case *KeysPane:
+		return p.BufPane
+	case *HistoryPane:
+		return p.BufPane
+	case *PreviewPane:
+		return p.BufPane
+	case *CopyModePane:
+		return p.BufPane
+	case *DebugPane:
+		return p.BufPane
 	}
-	return p
+	return nil
 }
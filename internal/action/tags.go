@@ -0,0 +1,192 @@
+package action
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/tags"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// GotoDefinition jumps to the definition of the word under the cursor
+// according to the tags file of the project
+func (h *BufPane) GotoDefinition() bool {
+	name := string(h.Buf.WordAt(h.Cursor.Loc))
+	if name == "" {
+		InfoBar.Error("No word under the cursor")
+		return false
+	}
+	h.gotoTag(name)
+	return true
+}
+
+// GotoDefinitionCmd jumps to the definition of the given symbol, or of the
+// word under the cursor
+func (h *BufPane) GotoDefinitionCmd(args []string) {
+	if len(args) == 0 {
+		h.GotoDefinition()
+		return
+	}
+	h.gotoTag(args[0])
+}
+
+// tagsFile returns the tags file which applies to the buffer, which is the
+// closest one to the buffer's file or else to the working directory
+func (h *BufPane) tagsFile() (string, bool) {
+	if h.Buf.AbsPath != "" {
+		if path, ok := tags.Locate(filepath.Dir(h.Buf.AbsPath)); ok {
+			return path, true
+		}
+	}
+	return tags.Locate(".")
+}
+
+// gotoTag jumps to the definition of name, or lets the user choose the
+// definition to jump to if there are several
+func (h *BufPane) gotoTag(name string) {
+	path, ok := h.tagsFile()
+	if !ok {
+		InfoBar.Error("No tags file found")
+		return
+	}
+	ts, err := tags.Lookup(path, name)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	if len(ts) == 0 {
+		InfoBar.Error("Tag not found: " + name)
+		return
+	}
+
+	if len(ts) == 1 {
+		loc, _, ok := tagLoc(ts[0])
+		if !ok {
+			InfoBar.Error("Definition of " + name + " not found in " + ts[0].Path)
+			return
+		}
+		h.openAt(ts[0].Path, loc)
+		return
+	}
+
+	// several definitions, grouped by file in the order of the tags file
+	var results []util.GrepResult
+	files := make(map[string]int)
+	for _, t := range ts {
+		loc, text, ok := tagLoc(t)
+		if !ok {
+			continue
+		}
+		i, ok := files[t.Path]
+		if !ok {
+			i = len(results)
+			files[t.Path] = i
+			results = append(results, util.GrepResult{Path: filepath.ToSlash(projectPath("", t.Path))})
+		}
+		if t.Kind != "" {
+			text += "  (" + t.Kind + ")"
+		}
+		results[i].Lines = append(results[i].Lines, util.GrepLine{Num: loc.Y, Text: text, Match: true, Col: loc.X})
+		results[i].Matches++
+	}
+	if len(results) == 0 {
+		InfoBar.Error("Definition of " + name + " not found")
+		return
+	}
+	NewListPane(h, "tag: "+name, "", results, [2]string{"definition", "definitions"})
+}
+
+// tagLoc returns the location of the definition of a tag and the text of
+// its line. The text of an open buffer is used if there is one, since it may
+// differ from the file.
+func tagLoc(t tags.Tag) (buffer.Loc, string, bool) {
+	lines, ok := tagLines(t.Path)
+	if !ok {
+		return buffer.Loc{}, "", false
+	}
+	return tagLocIn(t, lines)
+}
+
+// tagLines returns the lines of the file at path, from its buffer if it is
+// open
+func tagLines(path string) ([]string, bool) {
+	var lines []string
+	for _, b := range buffer.OpenBuffers {
+		if b.AbsPath == path {
+			for i := 0; i < b.LinesNum(); i++ {
+				lines = append(lines, b.Line(i))
+			}
+			return lines, true
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return strings.Split(string(data), "\n"), true
+}
+
+// tagLocIn is tagLoc with the lines of the tag's file
+func tagLocIn(t tags.Tag, lines []string) (buffer.Loc, string, bool) {
+	y := t.FindLine(lines)
+	if y < 0 {
+		return buffer.Loc{}, "", false
+	}
+	line := strings.TrimRight(lines[y], "\r")
+	x := 0
+	if i := strings.Index(line, t.Name); i >= 0 {
+		x = util.CharacterCountInString(line[:i])
+	}
+	return buffer.Loc{X: x, Y: y}, line, true
+}
+
+// tagsPending stores the directories in which the tags are being generated.
+// The value is set when the generation must run again because a file was
+// saved in the meantime.
+var tagsPending = make(map[string]bool)
+
+// regenerateTags runs the tagscommand in the background after the buffer
+// is saved, if the tagsonsave option is on. The tags are generated in the
+// directory of the buffer's tags file, or in the working directory if
+// there is none yet.
+func (h *BufPane) regenerateTags() {
+	if !h.Buf.Settings["tagsonsave"].(bool) {
+		return
+	}
+	dir, err := os.Getwd()
+	if path, ok := h.tagsFile(); ok {
+		dir, err = filepath.Dir(path), nil
+	}
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	generateTags(dir)
+}
+
+func generateTags(dir string) {
+	if _, running := tagsPending[dir]; running {
+		tagsPending[dir] = true
+		return
+	}
+	tagsPending[dir] = false
+
+	command := config.GetGlobalOption("tagscommand").(string)
+	go func() {
+		err := tags.Generate(dir, command)
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			again := tagsPending[dir]
+			delete(tagsPending, dir)
+			if err != nil {
+				InfoBar.Error(err)
+			} else if again {
+				generateTags(dir)
+			}
+		}}
+	}()
+}
@@ -0,0 +1,251 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/shell"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// taskOptions are the options giving the command of each task
+var taskOptions = map[string]string{
+	"build": "buildcmd",
+	"run":   "runcmd",
+	"test":  "testcmd",
+}
+
+// taskCommands are the commands of the run and test tasks for the
+// filetypes when their option is empty
+var taskCommands = map[string]map[string]string{
+	"run": {
+		"go":         "go run %f",
+		"javascript": "node %f",
+		"julia":      "julia %f",
+		"lua":        "lua %f",
+		"perl":       "perl %f",
+		"php":        "php %f",
+		"python":     "python3 %f",
+		"python2":    "python2 %f",
+		"r":          "Rscript %f",
+		"ruby":       "ruby %f",
+		"rust":       "cargo run",
+		"shell":      "sh %f",
+	},
+	"test": {
+		"go":         "go test ./...",
+		"javascript": "npm test",
+		"python":     "python3 -m pytest",
+		"rust":       "cargo test",
+	},
+}
+
+// taskPane is the pane showing the output of the last task, reused by the
+// next one
+var taskPane *BufPane
+
+// taskCommand returns the command of the task for the buffer, given by the
+// task's option or else by the filetype
+func (h *BufPane) taskCommand(task string) (string, error) {
+	opt, ok := taskOptions[task]
+	if !ok {
+		return "", errors.New("Unknown task " + task + " (expected build, run or test)")
+	}
+	if cmd := h.Buf.Settings[opt].(string); cmd != "" {
+		return cmd, nil
+	}
+	if cmd, ok := taskCommands[task][h.Buf.FileType()]; ok {
+		return cmd, nil
+	}
+	return "", errors.New("No " + task + " command for " + h.Buf.FileType() + ", set the " + opt + " option")
+}
+
+// expandTask splits the command of a task into its arguments and replaces
+// in each one %f by the path of the buffer's file, %d by its directory, %n
+// by its name without extension, %p by the root of its project and %% by %
+func (h *BufPane) expandTask(command string) ([]string, error) {
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("Empty task command")
+	}
+
+	r := h.taskReplacer()
+	for i, a := range args {
+		if strings.Contains(a, "%f") && h.Buf.AbsPath == "" {
+			return nil, errors.New("The buffer has no file")
+		}
+		args[i] = r.Replace(a)
+	}
+	return args, nil
+}
+
+// taskReplacer returns the replacer of the %f, %d, %n, %p and %% sequences
+// of the task commands, see expandTask
+func (h *BufPane) taskReplacer() *strings.Replacer {
+	path := h.Buf.AbsPath
+	dir, _ := os.Getwd()
+	if path != "" {
+		dir = filepath.Dir(path)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.NewReplacer("%%", "%", "%f", path, "%d", dir, "%n", name, "%p", util.ProjectRoot(dir))
+}
+
+// projectRoot returns the root of the project of the buffer's file, or of
+// the working directory for a buffer without a file
+func (h *BufPane) projectRoot() string {
+	dir, _ := os.Getwd()
+	if h.Buf.AbsPath != "" {
+		dir = filepath.Dir(h.Buf.AbsPath)
+	}
+	return util.ProjectRoot(dir)
+}
+
+// RunTaskCmd runs the build, run or test task of the buffer: the command
+// of the buildcmd, runcmd or testcmd option, or the usual one for the
+// filetype, in the working directory. The buffer is saved first if it is
+// modified. The output is shown in a pane below or in a terminal according
+// to the taskoutput option. Extra arguments are added to the command.
+func (h *BufPane) RunTaskCmd(args []string) {
+	if len(args) == 0 {
+		InfoBar.Error("Not enough arguments")
+		return
+	}
+	command, err := h.taskCommand(args[0])
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	cmd, err := h.expandTask(command)
+	if err != nil {
+		InfoBar.Error(err)
+		return
+	}
+	cmd = append(cmd, args[1:]...)
+
+	run := func() {
+		var err error
+		if h.Buf.Settings["taskoutput"].(string) == "terminal" {
+			_, err = h.openTerminal(cmd, true)
+		} else {
+			err = h.runTaskInPane(cmd)
+		}
+		if err != nil {
+			InfoBar.Error(err)
+		}
+	}
+	if h.Buf.Modified() && h.Buf.Path != "" {
+		h.SaveCB("RunTask", run)
+		return
+	}
+	run()
+}
+
+// openTerminal starts cmd in a terminal pane below h. If wait is set the
+// pane stays open when the command exits until a key is pressed.
+func (h *BufPane) openTerminal(cmd []string, wait bool) (*TermPane, error) {
+	if !TermEmuSupported {
+		return nil, errors.New("Terminal emulator is not supported on this system")
+	}
+	t, err := h.newTerminal()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Start(cmd, false, wait, nil, nil); err != nil {
+		return nil, err
+	}
+
+	tab := h.tab
+	id := tab.GetNode(h.splitID).HSplit(true)
+	v := h.GetView()
+	tp, err := NewTermPane(v.X, v.Y, v.Width, v.Height, t, id, tab)
+	if err != nil {
+		return nil, err
+	}
+	tab.Panes = append(tab.Panes, tp)
+	tab.Resize()
+	// the focus stays on the buffer
+	tab.SetActive(tab.GetPane(h.splitID))
+	return tp, nil
+}
+
+// runTaskInPane runs cmd in the background and shows its output in a pane
+// below h as it is written
+func (h *BufPane) runTaskInPane(cmd []string) error {
+	c := exec.Command(cmd[0], cmd[1:]...)
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	c.Stdout, c.Stderr = w, w
+	if err := c.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return err
+	}
+	w.Close()
+
+	p := h.taskOutputPane(shellquote.Join(cmd...))
+	appendOutput := func(text string) {
+		shell.Jobs <- shell.JobFunction{Function: func(string, []interface{}) {
+			b := p.Buf
+			b.EventHandler.Insert(b.End(), text)
+			b.UndoStack = new(buffer.TEStack)
+			b.RedoStack = new(buffer.TEStack)
+			p.Cursor.GotoLoc(b.End())
+			p.Relocate()
+		}}
+	}
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				appendOutput(string(buf[:n]))
+			}
+			if err != nil {
+				break
+			}
+		}
+		r.Close()
+		status := "done"
+		if err := c.Wait(); err != nil {
+			status = err.Error()
+		}
+		appendOutput(fmt.Sprintf("\n[%s]\n", status))
+	}()
+	return nil
+}
+
+// taskOutputPane returns the pane showing the output of the last task if
+// it is still open, emptied, or else opens one below h
+func (h *BufPane) taskOutputPane(name string) *BufPane {
+	if p := taskPane; p != nil {
+		for _, t := range Tabs.List {
+			for _, tp := range t.Panes {
+				if tp == p {
+					p.Buf.SetName(name)
+					p.Buf.EventHandler.Remove(p.Buf.Start(), p.Buf.End())
+					return p
+				}
+			}
+		}
+	}
+
+	b := buffer.NewBufferFromString("", "", buffer.BTLog)
+	b.SetName(name)
+	p := h.HSplitIndex(b, true)
+	// the focus stays on the buffer
+	h.tab.SetActive(h.tab.GetPane(h.splitID))
+	taskPane = p
+	return p
+}
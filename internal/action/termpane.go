@@ -99,7 +99,7 @@ func (t *TermPane) Quit() {
 	} else if len(Tabs.List) > 1 {
 		Tabs.RemoveTab(t.id)
 	} else {
-		screen.Screen.Fini()
+		screen.Fini()
 		InfoBar.Close()
 		runtime.Goexit()
 	}
@@ -120,6 +120,9 @@ func (t *TermPane) Unsplit() {
 // does not have mouse support, the emulator will support selections and
 // copy-paste
 func (t *TermPane) HandleEvent(event tcell.Event) {
+	// text is sent to the terminal last used
+	replTerm = t
+
 	if e, ok := event.(*tcell.EventKey); ok {
 		ke := KeyEvent{
 			code: e.Key(),
@@ -194,10 +197,17 @@ func (t *TermPane) HandleEvent(event tcell.Event) {
 	}
 }
 
-// Exit closes the termpane
+// Exit closes the termpane, asking first if its command is still running
 func (t *TermPane) Exit() {
-	t.Terminal.Close()
-	t.Quit()
+	exit := func() {
+		t.Terminal.Close()
+		t.Quit()
+	}
+	if t.Status == shell.TTRunning && confirms("closeterm") {
+		confirm("closeterm", "The terminal is still running "+t.Name()+". Close it?", exit)
+		return
+	}
+	exit()
 }
 
 // CommandMode opens the termpane's command mode
@@ -231,4 +241,5 @@ var TermKeyActions = map[string]TermKeyAction{
 	"Exit":        (*TermPane).Exit,
 	"CommandMode": (*TermPane).CommandMode,
 	"NextSplit":   (*TermPane).NextSplit,
+	"CopyMode":    (*TermPane).CopyMode,
 }
@@ -0,0 +1,88 @@
+package action
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// CharInfoCmd shows the code points of the character under the cursor with
+// their names and categories, its UTF-8 bytes and its digraph
+func (h *BufPane) CharInfoCmd(args []string) {
+	rest := util.SliceEnd(h.Buf.LineBytes(h.Cursor.Y), h.Cursor.X)
+	if len(rest) > 0 {
+		r, combc, _ := util.DecodeCharacter(rest)
+		InfoBar.Message(util.DescribeCharacter(r, combc))
+	} else if h.Cursor.Y == h.Buf.LinesNum()-1 {
+		InfoBar.Error("No character under the cursor")
+	} else if h.Buf.Endings == buffer.FFDos {
+		InfoBar.Message(util.DescribeCharacter('\r', []rune{'\n'}))
+	} else {
+		InfoBar.Message(util.DescribeCharacter('\n', nil))
+	}
+}
+
+// InsertUnicodeCmd inserts the characters of the given code points, such as
+// U+00E9, as if they were typed
+func (h *BufPane) InsertUnicodeCmd(args []string) {
+	if len(args) < 1 {
+		InfoBar.Error("Usage: insert-unicode 'codepoint'...")
+		return
+	}
+	var runes []rune
+	for _, a := range args {
+		r, err := util.ParseCodePoint(a)
+		if err != nil {
+			InfoBar.Error(err)
+			return
+		}
+		runes = append(runes, r)
+	}
+	h.insertRunes(runes)
+}
+
+// DigraphCmd inserts the characters of the given digraphs, such as e' for
+// é, as if they were typed
+func (h *BufPane) DigraphCmd(args []string) {
+	if len(args) < 1 {
+		InfoBar.Error("Usage: digraph 'digraph'...")
+		return
+	}
+	var runes []rune
+	for _, a := range args {
+		r, ok := util.Digraph(a)
+		if !ok {
+			InfoBar.Error("Unknown digraph: ", a)
+			return
+		}
+		runes = append(runes, r)
+	}
+	h.insertRunes(runes)
+}
+
+// InsertDigraph prompts for a digraph and inserts its character once its
+// two characters are typed
+func (h *BufPane) InsertDigraph() bool {
+	InfoBar.Prompt("Digraph: ", "", "Digraph", func(resp string) {
+		if util.CharacterCountInString(resp) == 2 {
+			InfoBar.DonePrompt(false)
+		}
+	}, func(resp string, canceled bool) {
+		if canceled {
+			return
+		}
+		if r, ok := util.Digraph(resp); ok {
+			h.insertRunes([]rune{r})
+		} else {
+			InfoBar.Error("Unknown digraph: ", resp)
+		}
+	})
+	return true
+}
+
+// insertRunes types the runes at each cursor
+func (h *BufPane) insertRunes(runes []rune) {
+	for _, r := range runes {
+		h.DoRuneInsert(r)
+	}
+	h.Relocate()
+}
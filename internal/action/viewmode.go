@@ -0,0 +1,101 @@
+package action
+
+import (
+	humanize "github.com/dustin/go-humanize"
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// viewKeys are the actions of the keys of the view mode, which pages
+// through the buffer as a pager does
+var viewKeys = map[rune]string{
+	' ': "PageDown",
+	'f': "PageDown",
+	'b': "PageUp",
+	'd': "HalfPageDown",
+	'u': "HalfPageUp",
+	'j': "ScrollDown",
+	'k': "ScrollUp",
+	'g': "CursorStart",
+	'G': "CursorEnd",
+	'/': "Find",
+	'n': "FindNext",
+	'N': "FindPrevious",
+	'q': "Quit",
+}
+
+// viewKeyEvent handles a key of the view mode (see the viewmode option),
+// and returns false for the keys left to the bindings. The characters
+// which have no action in the view mode do nothing, rather than being
+// refused as edits.
+func (h *BufPane) viewKeyEvent(e *tcell.EventKey) bool {
+	if !h.Buf.Settings["viewmode"].(bool) || h.Buf.Type.Kind == buffer.BTInfo.Kind {
+		return false
+	}
+	if e.Key() != tcell.KeyRune || e.Modifiers()&^tcell.ModShift != 0 {
+		return false
+	}
+	if name, ok := viewKeys[e.Rune()]; ok {
+		h.runAction(name)
+	}
+	return true
+}
+
+// refusedEditMessage tells that the last event tried to edit the readonly
+// buffer, and how to edit it anyway
+func (h *BufPane) refusedEditMessage() {
+	if !h.Buf.EditRefused() {
+		return
+	}
+	if h.Buf.Type.Kind == buffer.BTDefault.Kind {
+		InfoBar.Error("The buffer is readonly, 'unlock' allows editing it")
+	} else {
+		InfoBar.Error("The buffer is readonly")
+	}
+}
+
+// followHugeFile moves the buffer of the viewer of a huge file along the
+// file when the view gets near one of its ends, keeping the same lines in
+// view
+func (h *BufPane) followHugeFile() {
+	v := h.GetView()
+	if delta := h.Buf.FollowView(v.StartLine.Line, v.Height); delta != 0 {
+		v.StartLine.Line = util.Clamp(v.StartLine.Line+delta, 0, h.Buf.LinesNum()-1)
+	}
+}
+
+// Unlock allows editing the buffer, see UnlockCmd
+func (h *BufPane) Unlock() bool {
+	h.UnlockCmd(nil)
+	return true
+}
+
+// UnlockCmd turns the readonly and viewmode options of the buffer off, so
+// that it can be edited. The huge file of the viewer is loaded whole in the
+// pane after asking it.
+func (h *BufPane) UnlockCmd(args []string) {
+	if h.Buf.Huge != nil {
+		size := humanize.Bytes(uint64(h.Buf.Huge.Size()))
+		InfoBar.YNPrompt("Load the whole file ("+size+") to edit it? (y,n,esc)", func(yes, canceled bool) {
+			if !yes || canceled {
+				return
+			}
+			b, err := h.Buf.LoadHugeFile()
+			if err != nil {
+				InfoBar.Error(err)
+				return
+			}
+			h.OpenBuffer(b)
+			InfoBar.Message("The buffer can be edited")
+		})
+		return
+	}
+	if h.Buf.Type.Kind != buffer.BTDefault.Kind {
+		InfoBar.Error("This buffer can't be edited")
+		return
+	}
+	h.Buf.SetOptionNative("readonly", false)
+	h.Buf.SetOptionNative("viewmode", false)
+	InfoBar.Message("The buffer can be edited")
+}
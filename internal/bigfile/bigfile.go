@@ -0,0 +1,252 @@
+// Package bigfile maps huge files in memory to browse them without reading
+// them whole: their lines are indexed as they are reached, and searched by
+// streaming through the mapping
+package bigfile
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// markStep is the number of lines between two marks of the index
+const markStep = 1024
+
+// ChunkSize is the number of bytes which the searches match at once before
+// looking at the lines of a chunk
+var ChunkSize = 4 << 20
+
+// A File is a file mapped in memory, read-only
+type File struct {
+	data  []byte
+	unmap func() error
+
+	// marks are the offsets of the lines 0, markStep, 2*markStep... of the
+	// part of the file indexed so far, which has lines lines and ends at
+	// the offset next, where the next line starts
+	marks       []int
+	lines, next int
+	complete    bool
+
+	// lastLine and lastOffset are the last line which was looked up and its
+	// offset, from which the lines after it are found
+	lastLine, lastOffset int
+}
+
+// Open maps the file at path in memory
+func Open(path string) (*File, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	f := new(File)
+	if info.Size() > 0 {
+		if f.data, f.unmap, err = mapFile(fd, int(info.Size())); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Close unmaps the file, whose lines can't be used anymore
+func (f *File) Close() error {
+	data, unmap := f.data, f.unmap
+	f.data, f.unmap = nil, nil
+	f.marks, f.lines, f.next, f.complete = nil, 0, 0, false
+	if unmap != nil && data != nil {
+		return unmap()
+	}
+	return nil
+}
+
+// Size returns the size of the file in bytes
+func (f *File) Size() int {
+	return len(f.data)
+}
+
+// indexTo indexes the lines of the file until line n is reached, or the
+// end of the file
+func (f *File) indexTo(n int) {
+	for !f.complete && f.lines <= n {
+		f.indexLine()
+	}
+}
+
+// indexLine indexes the line starting at next
+func (f *File) indexLine() {
+	if f.lines%markStep == 0 {
+		f.marks = append(f.marks, f.next)
+	}
+	f.lines++
+	i := bytes.IndexByte(f.data[f.next:], '\n')
+	if i < 0 {
+		f.complete = true
+		return
+	}
+	f.next += i + 1
+}
+
+// Count returns the number of lines indexed so far, and whether they are
+// all of the lines of the file
+func (f *File) Count() (int, bool) {
+	if f.lines == 0 {
+		f.indexTo(0)
+	}
+	return f.lines, f.complete
+}
+
+// IndexAll indexes the whole file and returns its number of lines
+func (f *File) IndexAll() int {
+	f.indexTo(int(^uint(0) >> 1))
+	return f.lines
+}
+
+// Offset returns the offset of the start of line n, and false if the file
+// has less lines
+func (f *File) Offset(n int) (int, bool) {
+	if n < 0 {
+		return 0, false
+	}
+	f.indexTo(n)
+	if n >= f.lines {
+		return 0, false
+	}
+	l, off := n/markStep*markStep, f.marks[n/markStep]
+	if f.lastLine <= n && f.lastLine > l {
+		l, off = f.lastLine, f.lastOffset
+	}
+	for ; l < n; l++ {
+		off += bytes.IndexByte(f.data[off:], '\n') + 1
+	}
+	f.lastLine, f.lastOffset = n, off
+	return off, true
+}
+
+// end returns the offset of the end of the line starting at off, before
+// its line ending
+func (f *File) end(off int) int {
+	i := bytes.IndexByte(f.data[off:], '\n')
+	if i < 0 {
+		return len(f.data)
+	}
+	return off + i
+}
+
+// line returns the line starting at off, without its line ending
+func (f *File) line(off int) []byte {
+	return bytes.TrimSuffix(f.data[off:f.end(off)], []byte{'\r'})
+}
+
+// Lines returns at most n lines from line start, without their line
+// endings. They are the mapped bytes of the file, not a copy.
+func (f *File) Lines(start, n int) [][]byte {
+	off, ok := f.Offset(start)
+	if !ok {
+		return nil
+	}
+	var lines [][]byte
+	for len(lines) < n {
+		lines = append(lines, f.line(off))
+		e := f.end(off)
+		if e == len(f.data) {
+			break
+		}
+		off = e + 1
+	}
+	return lines
+}
+
+// LineAt returns the line containing the offset and the offset of its
+// start
+func (f *File) LineAt(off int) (int, int) {
+	off = util.Clamp(off, 0, len(f.data))
+	for !f.complete && f.next <= off {
+		f.indexLine()
+	}
+	if f.lines == 0 {
+		f.indexTo(0)
+	}
+	k := sort.Search(len(f.marks), func(i int) bool { return f.marks[i] > off }) - 1
+	start := f.marks[k]
+	l := k*markStep + bytes.Count(f.data[start:off], []byte{'\n'})
+	if i := bytes.LastIndexByte(f.data[start:off], '\n'); i >= 0 {
+		start += i + 1
+	}
+	return l, start
+}
+
+// Search returns the offsets of the start and the end of the first match of
+// r starting at the offset from or after it, or of the last match ending
+// at from or before it if down is false. The regular expression is matched
+// against each line, as in the buffers.
+func (f *File) Search(r util.Regexp, from int, down bool) (int, int, bool) {
+	from = util.Clamp(from, 0, len(f.data))
+	if down {
+		return f.searchDown(r, from)
+	}
+	return f.searchUp(r, from)
+}
+
+// prefilter returns whether a chunk which r doesn't match can be skipped
+// without looking at its lines, which isn't the case when r depends on
+// where the lines start and end
+func prefilter(r util.Regexp) bool {
+	s := r.String()
+	return !strings.ContainsAny(s, "^$") && !strings.Contains(s, "(?<")
+}
+
+// lineStart returns the offset of the start of the line containing off
+func (f *File) lineStart(off int) int {
+	return bytes.LastIndexByte(f.data[:off], '\n') + 1
+}
+
+func (f *File) searchDown(r util.Regexp, from int) (int, int, bool) {
+	filter := prefilter(r)
+	for pos := f.lineStart(from); pos <= len(f.data); {
+		end := f.end(util.Min(pos+ChunkSize, len(f.data)))
+		if !filter || r.Match(f.data[pos:end]) {
+			for off := pos; off <= end; off = f.end(off) + 1 {
+				l := f.line(off)
+				for _, m := range r.FindAllIndex(l, -1) {
+					if off+m[0] >= from {
+						return off + m[0], off + m[1], true
+					}
+				}
+			}
+		}
+		pos = end + 1
+	}
+	return 0, 0, false
+}
+
+func (f *File) searchUp(r util.Regexp, from int) (int, int, bool) {
+	filter := prefilter(r)
+	for end := f.end(from); end >= 0; {
+		pos := f.lineStart(util.Max(end-ChunkSize, 0))
+		if !filter || r.Match(f.data[pos:end]) {
+			last := -1
+			var match []int
+			for off := pos; off <= end; off = f.end(off) + 1 {
+				l := f.line(off)
+				for _, m := range r.FindAllIndex(l, -1) {
+					if off+m[1] <= from {
+						last, match = off, m
+					}
+				}
+			}
+			if match != nil {
+				return last + match[0], last + match[1], true
+			}
+		}
+		end = pos - 1
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,89 @@
+package bigfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+func openTemp(t *testing.T, content string) *File {
+	tmp, err := ioutil.TempFile("", "micro-bigfile")
+	assert.NoError(t, err)
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(content)
+	assert.NoError(t, err)
+	tmp.Close()
+	f, err := Open(tmp.Name())
+	assert.NoError(t, err)
+	return f
+}
+
+func TestLines(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+	f := openTemp(t, sb.String())
+	defer f.Close()
+
+	// the lines are indexed as they are reached
+	assert.Equal(t, [][]byte{[]byte("line 2"), []byte("line 3")}, f.Lines(2, 2))
+	n, complete := f.Count()
+	assert.False(t, complete)
+	assert.True(t, n < 3000)
+
+	assert.Equal(t, [][]byte{[]byte("line 2047"), []byte("line 2048")}, f.Lines(2047, 2))
+	assert.Equal(t, [][]byte{[]byte("line 1500")}, f.Lines(1500, 1))
+	assert.Equal(t, [][]byte{[]byte("line 2999"), []byte("")}, f.Lines(2999, 5))
+	assert.Nil(t, f.Lines(3001, 1))
+	assert.Equal(t, 3001, f.IndexAll())
+
+	off, ok := f.Offset(1025)
+	assert.True(t, ok)
+	assert.Equal(t, "line 1025", string(f.line(off)))
+	line, start := f.LineAt(off + 3)
+	assert.Equal(t, 1025, line)
+	assert.Equal(t, off, start)
+	line, _ = f.LineAt(f.Size())
+	assert.Equal(t, 3000, line)
+}
+
+func TestSearch(t *testing.T) {
+	defer func(n int) { ChunkSize = n }(ChunkSize)
+	ChunkSize = 64
+
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "entry %d\n", i)
+	}
+	content := sb.String()
+	f := openTemp(t, content)
+	defer f.Close()
+
+	find := func(expr string, from int, down bool) string {
+		r, err := util.CompileRegexp(expr, "go")
+		assert.NoError(t, err)
+		s, e, ok := f.Search(r, from, down)
+		if !ok {
+			return ""
+		}
+		line, _ := f.LineAt(s)
+		return fmt.Sprintf("%d:%s", line, content[s:e])
+	}
+
+	assert.Equal(t, "150:entry 150", find("entry 15[0-9]", 0, true))
+	assert.Equal(t, "159:entry 159", find("entry 15[0-9]", len(content), false))
+	from := strings.Index(content, "entry 151")
+	assert.Equal(t, "151:entry 151", find("entry 15[0-9]", from, true))
+	assert.Equal(t, "150:entry 150", find("entry 15[0-9]", from, false))
+	assert.Equal(t, "", find("entry 15[0-9]", strings.Index(content, "entry 160"), true))
+	// the anchors match at the start of each line
+	assert.Equal(t, "7:entry 7", find("^entry 7$", 0, true))
+	assert.Equal(t, "199:entry 199", find("^entry \\d+$", len(content), false))
+	assert.Equal(t, "", find("nothing", 0, true))
+}
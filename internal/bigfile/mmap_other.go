@@ -0,0 +1,17 @@
+// +build plan9 nacl windows
+
+package bigfile
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mapFile reads the file, which can't be mapped in memory on this system
+func mapFile(fd *os.File, size int) ([]byte, func() error, error) {
+	data, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, nil, nil
+}
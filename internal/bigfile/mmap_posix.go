@@ -0,0 +1,17 @@
+// +build linux darwin dragonfly solaris openbsd netbsd freebsd
+
+package bigfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the size bytes of the file in memory, read-only
+func mapFile(fd *os.File, size int) ([]byte, func() error, error) {
+	data, err := syscall.Mmap(int(fd.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,64 @@
+package buffer
+
+import (
+	"github.com/zyedidia/json5"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// globalAbbreviations is the name of the abbreviations file used by all
+// filetypes
+const globalAbbreviations = "all"
+
+// Abbreviations returns the abbreviations of a filetype, from the
+// abbreviation runtime files named after it and the "all" files. Each one
+// is a JSON object mapping the abbreviations to their expansions, and the
+// ones of the filetype take precedence.
+func Abbreviations(filetype string) map[string]string {
+	abbrevs := make(map[string]string)
+	for _, name := range []string{globalAbbreviations, filetype} {
+		for _, f := range config.ListRuntimeFiles(config.RTAbbrev) {
+			if f.Name() != name {
+				continue
+			}
+			data, err := f.Data()
+			if err != nil {
+				continue
+			}
+			var parsed map[string]string
+			if err := json5.Unmarshal(data, &parsed); err != nil {
+				continue
+			}
+			for k, v := range parsed {
+				if k != "" {
+					abbrevs[k] = v
+				}
+			}
+		}
+	}
+	return abbrevs
+}
+
+// AbbreviationBefore returns the expansion of the abbreviation of the
+// buffer's filetype ending at loc, the longest if several do, and the
+// start of the abbreviation. An abbreviation starting with a word
+// character must start a word.
+func (b *Buffer) AbbreviationBefore(loc Loc) (string, Loc, bool) {
+	if !b.Settings["abbreviations"].(bool) {
+		return "", loc, false
+	}
+	line := []rune(string(b.LineBytes(loc.Y)))
+	x := util.Clamp(loc.X, 0, len(line))
+	expansion, n := "", 0
+	for abbrev, exp := range Abbreviations(b.FileType()) {
+		a := []rune(abbrev)
+		if len(a) > x || len(a) <= n || string(line[x-len(a):x]) != abbrev {
+			continue
+		}
+		if util.IsWordChar(a[0]) && x-len(a) > 0 && util.IsWordChar(line[x-len(a)-1]) {
+			continue
+		}
+		expansion, n = exp, len(a)
+	}
+	return expansion, Loc{X: x - n, Y: loc.Y}, n > 0
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+func TestAbbreviations(t *testing.T) {
+	config.PluginAddRuntimeFileFromMemory(config.RTAbbrev, "all", `{"teh": "the", "(c)": "©", "sop": "global"}`)
+	config.PluginAddRuntimeFileFromMemory(config.RTAbbrev, "abbrevtest", `{
+		// comments are allowed
+		"sop": "System.out.print",
+		"sopl": "System.out.println",
+	}`)
+
+	assert.Equal(t, map[string]string{"teh": "the", "(c)": "©", "sop": "global"}, Abbreviations("unknown"))
+	assert.Equal(t, "System.out.print", Abbreviations("abbrevtest")["sop"])
+
+	b := NewBufferFromString("teh xteh sopl é(c)", "", BTDefault)
+	b.Settings["filetype"] = "abbrevtest"
+	exp, start, ok := b.AbbreviationBefore(Loc{X: 3, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, "the", exp)
+	assert.Equal(t, Loc{X: 0, Y: 0}, start)
+
+	// inside a word
+	_, _, ok = b.AbbreviationBefore(Loc{X: 8, Y: 0})
+	assert.False(t, ok)
+
+	// the longest one
+	exp, start, _ = b.AbbreviationBefore(Loc{X: 13, Y: 0})
+	assert.Equal(t, "System.out.println", exp)
+	assert.Equal(t, Loc{X: 9, Y: 0}, start)
+
+	// not starting with a word character
+	exp, start, _ = b.AbbreviationBefore(Loc{X: 18, Y: 0})
+	assert.Equal(t, "©", exp)
+	assert.Equal(t, Loc{X: 15, Y: 0}, start)
+
+	b.Settings["abbreviations"] = false
+	_, _, ok = b.AbbreviationBefore(Loc{X: 3, Y: 0})
+	assert.False(t, ok)
+}
@@ -0,0 +1,141 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// AlignOptions tell how AlignLines pads the text between the delimiters
+type AlignOptions struct {
+	// Right aligns the text before each delimiter to the right, padding it
+	// on the left, the text after the last delimiter included
+	Right bool
+	// MaxPadding, when positive, is the largest number of spaces added
+	// before a delimiter. The delimiter of a shorter text is then left out
+	// of line.
+	MaxPadding int
+}
+
+// An alignLine is a line split by the delimiters
+type alignLine struct {
+	indent []byte
+	cells  [][]byte
+	delims [][]byte
+}
+
+// AlignLines pads the lines from start to end so that the matches of delim
+// line up, the first match of each line, the second one and so on. The
+// whitespace around the text between the matches becomes a single space if
+// any line had whitespace there, and the lines without a match are left
+// as they are. The change is a single undo step.
+func (b *Buffer) AlignLines(start, end int, delim util.Regexp, opts AlignOptions) {
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	width := func(cell []byte) int {
+		return util.StringWidth(cell, util.CharacterCount(cell), tabsize)
+	}
+
+	var lines []alignLine
+	// widths are the largest widths of the text before each match, and
+	// before and after tell whether any line had whitespace around it
+	var widths []int
+	var before, after []bool
+	for y := start; y <= end; y++ {
+		line := b.LineBytes(y)
+		indent := util.GetLeadingWhitespace(line)
+		l := alignLine{indent: indent}
+		rest := line[len(indent):]
+		last := 0
+		for _, m := range delim.FindAllIndex(rest, -1) {
+			if m[1] == m[0] {
+				continue
+			}
+			cell := rest[last:m[0]]
+			k := len(l.cells)
+			if k == len(widths) {
+				widths = append(widths, 0)
+				before = append(before, false)
+				after = append(after, false)
+			}
+			if k > 0 && len(cell) > 0 && util.IsWhitespace(rune(cell[0])) {
+				after[k-1] = true
+			}
+			if len(cell) > 0 && util.IsWhitespace(rune(cell[len(cell)-1])) {
+				before[k] = true
+			}
+			cell = bytes.TrimSpace(cell)
+			if w := width(cell); w > widths[k] {
+				widths[k] = w
+			}
+			l.cells = append(l.cells, cell)
+			l.delims = append(l.delims, rest[m[0]:m[1]])
+			last = m[1]
+		}
+		if len(l.delims) > 0 {
+			cell := rest[last:]
+			if len(cell) > 0 && util.IsWhitespace(rune(cell[0])) {
+				after[len(l.delims)-1] = true
+			}
+			l.cells = append(l.cells, bytes.TrimSpace(cell))
+		}
+		lines = append(lines, l)
+	}
+
+	// with right alignment the text after the last delimiter of the lines
+	// is aligned too
+	var lastWidth int
+	if opts.Right {
+		for _, l := range lines {
+			if n := len(l.cells); n > 0 {
+				if w := width(l.cells[n-1]); w > lastWidth {
+					lastWidth = w
+				}
+			}
+		}
+	}
+
+	aligned := make([][]byte, 0, len(lines))
+	for i, l := range lines {
+		if len(l.delims) == 0 {
+			aligned = append(aligned, b.LineBytes(start+i))
+			continue
+		}
+		var sb strings.Builder
+		sb.Write(l.indent)
+		for k, cell := range l.cells {
+			w := lastWidth
+			if k < len(l.delims) {
+				w = widths[k]
+			} else if !opts.Right {
+				w = 0
+			}
+			pad := w - width(cell)
+			if pad < 0 {
+				pad = 0
+			} else if opts.MaxPadding > 0 && pad > opts.MaxPadding {
+				pad = opts.MaxPadding
+			}
+			if opts.Right {
+				sb.WriteString(strings.Repeat(" ", pad))
+				sb.Write(cell)
+			} else {
+				sb.Write(cell)
+				sb.WriteString(strings.Repeat(" ", pad))
+			}
+			if k == len(l.delims) {
+				break
+			}
+			if before[k] {
+				sb.WriteByte(' ')
+			}
+			sb.Write(l.delims[k])
+			if after[k] {
+				sb.WriteByte(' ')
+			}
+		}
+		// without the space after a last delimiter followed by nothing
+		aligned = append(aligned, []byte(strings.TrimRight(sb.String(), " ")))
+	}
+	b.replaceLines(start, end, aligned)
+}
@@ -0,0 +1,34 @@
+package buffer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func alignedLines(text, delim string, opts AlignOptions) string {
+	b := NewBufferFromString(text, "", BTDefault)
+	defer b.Close()
+	b.AlignLines(0, b.LinesNum()-1, regexp.MustCompile(delim), opts)
+	return string(b.Bytes())
+}
+
+func TestAlignLines(t *testing.T) {
+	assert.Equal(t, "\ta   = 1\n\tfoo = 22\n\tnone\n\tb   = 3",
+		alignedLines("\ta = 1\n\tfoo=22\n\tnone\n\tb=3", "=", AlignOptions{}))
+
+	// every delimiter lines up, and nothing is added after the last one
+	assert.Equal(t, "| a   | bb |\n| ccc | d  |",
+		alignedLines("| a | bb |\n|ccc|d|", `\|`, AlignOptions{}))
+
+	// the text after the last delimiter is right aligned too
+	assert.Equal(t, "x =   1\ny = 100",
+		alignedLines("x = 1\ny = 100", "=", AlignOptions{Right: true}))
+	assert.Equal(t, "  a, bbb\n  c,   d",
+		alignedLines("  a,bbb\n  c, d", ",", AlignOptions{Right: true}))
+
+	// a shorter text is padded with at most MaxPadding spaces
+	assert.Equal(t, "a   = 1\nlong = 2",
+		alignedLines("a = 1\nlong = 2", "=", AlignOptions{MaxPadding: 2}))
+}
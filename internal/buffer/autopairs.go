@@ -0,0 +1,145 @@
+package buffer
+
+import (
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// closerOf returns the character closing the pair opened by r, according
+// to the autoclosepairs option
+func (b *Buffer) closerOf(r rune) (rune, bool) {
+	pairs := []rune(b.Settings["autoclosepairs"].(string))
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] == r {
+			return pairs[i+1], true
+		}
+	}
+	return 0, false
+}
+
+// isCloser returns whether r closes a pair of the autoclosepairs option
+func (b *Buffer) isCloser(r rune) bool {
+	pairs := []rune(b.Settings["autoclosepairs"].(string))
+	for i := 1; i < len(pairs); i += 2 {
+		if pairs[i] == r {
+			return true
+		}
+	}
+	return false
+}
+
+// groupAt returns the name of the highlighting group of the character at
+// loc, or "" if it isn't highlighted
+func (b *Buffer) groupAt(loc Loc) string {
+	if !b.Settings["syntax"].(bool) || b.SyntaxDef == nil || loc.X < 0 {
+		return ""
+	}
+	start := -1
+	name := ""
+	for x, g := range b.Match(loc.Y) {
+		if x <= loc.X && x > start {
+			start, name = x, g.String()
+		}
+	}
+	return name
+}
+
+// InStringOrComment returns whether the text typed at loc is in a string
+// or a comment, according to the highlighting of the buffer
+func (b *Buffer) InStringOrComment(loc Loc) bool {
+	if loc.X == 0 {
+		return false
+	}
+	before := b.groupAt(loc.Move(-1, b))
+	if strings.HasPrefix(before, "comment") {
+		return true
+	}
+	if !strings.HasPrefix(before, "constant.string") {
+		return false
+	}
+	// the cursor is either inside the string or after its closing quote
+	if loc.X < util.CharacterCount(b.LineBytes(loc.Y)) {
+		return strings.HasPrefix(b.groupAt(loc), "constant.string")
+	}
+	return !strings.ContainsRune("\"'`", b.RuneAt(Loc{X: loc.X - 1, Y: loc.Y}))
+}
+
+// BetweenPair returns whether the characters around loc are a pair of the
+// autoclosepairs option, such as the cursor in ()
+func (b *Buffer) BetweenPair(loc Loc) bool {
+	if loc.X == 0 {
+		return false
+	}
+	closer, ok := b.closerOf(b.RuneAt(Loc{X: loc.X - 1, Y: loc.Y}))
+	return ok && b.RuneAt(loc) == closer
+}
+
+// AutoClose inserts the character r at the cursor c, typed by the user, as
+// the autoclose option does, and returns whether it did. Typing a character
+// opening a pair inserts its closing character as well, except in strings
+// and comments and before a word, and wraps the selection in the pair if
+// there is one. Typing the closing character of a pair before the same
+// character skips over it. A quote is not paired after or before a word,
+// so that apostrophes are typed as such.
+func (b *Buffer) AutoClose(c *Cursor, r rune) bool {
+	closer, opens := b.closerOf(r)
+
+	if c.HasSelection() {
+		if !opens {
+			return false
+		}
+		b.wrapSelection(c, string(r), string(closer))
+		return true
+	}
+
+	next := b.RuneAt(c.Loc)
+	if next == r && (b.isCloser(r) || opens && closer == r) && !b.escaped(c.Loc) {
+		c.Right()
+		return true
+	}
+	if !opens || b.InStringOrComment(c.Loc) || util.IsWordChar(next) {
+		return false
+	}
+	if closer == r && c.X > 0 && util.IsWordChar(b.RuneAt(Loc{X: c.X - 1, Y: c.Y})) {
+		return false
+	}
+
+	b.Insert(c.Loc, string(r)+string(closer))
+	c.Left()
+	return true
+}
+
+// wrapSelection inserts open before the selection of c and close after it,
+// keeping the text between them selected
+func (b *Buffer) wrapSelection(c *Cursor, open, close string) {
+	start, end := c.CurSelection[0], c.CurSelection[1]
+	if end.LessThan(start) {
+		start, end = end, start
+	}
+	b.Insert(end, close)
+	b.Insert(start, open)
+	n := util.CharacterCountInString(open)
+	start = Loc{X: start.X + n, Y: start.Y}
+	if end.Y == start.Y {
+		end = Loc{X: end.X + n, Y: end.Y}
+	}
+	c.SetSelectionStart(start)
+	c.SetSelectionEnd(end)
+	c.GotoLoc(end)
+}
+
+// escaped returns whether the character at loc follows a backslash
+func (b *Buffer) escaped(loc Loc) bool {
+	return loc.X > 0 && b.RuneAt(Loc{X: loc.X - 1, Y: loc.Y}) == '\\'
+}
+
+// DeletePair removes the pair around the cursor c if it is between the
+// characters of a pair, and returns whether it did
+func (b *Buffer) DeletePair(c *Cursor) bool {
+	if c.HasSelection() || !b.BetweenPair(c.Loc) {
+		return false
+	}
+	b.Remove(Loc{X: c.X - 1, Y: c.Y}, Loc{X: c.X + 1, Y: c.Y})
+	return true
+}
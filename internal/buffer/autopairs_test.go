@@ -0,0 +1,105 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pairsSyntax = `filetype: test
+
+detect:
+    filename: "\\.test$"
+
+rules:
+    - constant.string:
+        start: "\""
+        end: "\""
+        rules: []
+    - comment:
+        start: "//"
+        end: "$"
+        rules: []
+`
+
+func TestInStringOrComment(t *testing.T) {
+	b := syntaxBuffer(t, []byte(pairsSyntax), "x \"str\" y // z\n\"open")
+	b.Highlighter.HighlightMatches(b, 0, b.End().Y)
+	defer b.Close()
+
+	assert.False(t, b.InStringOrComment(Loc{X: 0, Y: 0}))
+	assert.False(t, b.InStringOrComment(Loc{X: 2, Y: 0}))
+	assert.True(t, b.InStringOrComment(Loc{X: 3, Y: 0}))
+	assert.True(t, b.InStringOrComment(Loc{X: 6, Y: 0}))
+	assert.False(t, b.InStringOrComment(Loc{X: 7, Y: 0}))
+	assert.True(t, b.InStringOrComment(Loc{X: 14, Y: 0}))
+	assert.True(t, b.InStringOrComment(Loc{X: 5, Y: 1}))
+}
+
+func TestAutoClose(t *testing.T) {
+	b := syntaxBuffer(t, []byte(pairsSyntax), "f\nword\n\"str\"")
+	b.Highlighter.HighlightMatches(b, 0, b.End().Y)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	// an opening bracket is closed, and typing its closer moves over it
+	c.GotoLoc(Loc{X: 1, Y: 0})
+	assert.True(t, b.AutoClose(c, '('))
+	assert.Equal(t, "f()", string(b.LineBytes(0)))
+	assert.Equal(t, Loc{X: 2, Y: 0}, c.Loc)
+	assert.True(t, b.AutoClose(c, ')'))
+	assert.Equal(t, "f()", string(b.LineBytes(0)))
+	assert.Equal(t, Loc{X: 3, Y: 0}, c.Loc)
+	assert.False(t, b.AutoClose(c, 'x'))
+
+	// a bracket before a word and a quote after a word are not closed
+	c.GotoLoc(Loc{X: 0, Y: 1})
+	assert.False(t, b.AutoClose(c, '['))
+	c.GotoLoc(Loc{X: 4, Y: 1})
+	assert.False(t, b.AutoClose(c, '\''))
+	assert.True(t, b.AutoClose(c, '{'))
+	assert.Equal(t, "word{}", string(b.LineBytes(1)))
+
+	// nothing is closed in a string
+	c.GotoLoc(Loc{X: 2, Y: 2})
+	assert.False(t, b.AutoClose(c, '('))
+
+	// the backspace between a pair deletes it
+	c.GotoLoc(Loc{X: 5, Y: 1})
+	assert.True(t, b.BetweenPair(c.Loc))
+	assert.True(t, b.DeletePair(c))
+	assert.Equal(t, "word", string(b.LineBytes(1)))
+	assert.False(t, b.DeletePair(c))
+}
+
+func TestAutoCloseSelection(t *testing.T) {
+	b := NewBufferFromString("a word\nand\nmore", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	c.SetSelectionStart(Loc{X: 2, Y: 0})
+	c.SetSelectionEnd(Loc{X: 6, Y: 0})
+	assert.True(t, b.AutoClose(c, '"'))
+	assert.Equal(t, "a \"word\"", string(b.LineBytes(0)))
+	assert.Equal(t, "word", string(c.GetSelection()))
+
+	c.SetSelectionStart(Loc{X: 1, Y: 1})
+	c.SetSelectionEnd(Loc{X: 2, Y: 2})
+	assert.True(t, b.AutoClose(c, '('))
+	assert.Equal(t, "a(nd\nmo)re", string(b.LineBytes(1))+"\n"+string(b.LineBytes(2)))
+	assert.Equal(t, "nd\nmo", string(c.GetSelection()))
+
+	// a closing character replaces the selection
+	assert.False(t, b.AutoClose(c, ')'))
+}
+
+func TestAutoClosePairsOption(t *testing.T) {
+	b := NewBufferFromString("", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("autoclosepairs", "<>")
+	c := b.GetActiveCursor()
+
+	assert.False(t, b.AutoClose(c, '('))
+	assert.True(t, b.AutoClose(c, '<'))
+	assert.Equal(t, "<>", string(b.Bytes()))
+}
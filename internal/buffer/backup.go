@@ -62,15 +62,20 @@ func (b *Buffer) RequestBackup() {
 	}
 }
 
-// Backup saves the current buffer to ConfigDir/backups
+// Backup saves the current buffer to StateDir/backups, or to its draft if
+// it keeps one
 func (b *Buffer) Backup() error {
+	if b.KeepsDraft() {
+		b.requestedBackup = false
+		return b.SaveDraft()
+	}
 	if !b.Settings["backup"].(bool) || b.Path == "" || b.Type != BTDefault {
 		return nil
 	}
 
 	backupdir, err := util.ReplaceHome(b.Settings["backupdir"].(string))
 	if backupdir == "" || err != nil {
-		backupdir = filepath.Join(config.ConfigDir, "backups")
+		backupdir = filepath.Join(config.StateDir, "backups")
 	}
 	if _, err := os.Stat(backupdir); os.IsNotExist(err) {
 		os.Mkdir(backupdir, os.ModePerm)
@@ -112,15 +117,20 @@ func (b *Buffer) RemoveBackup() {
 	if !b.Settings["backup"].(bool) || b.Settings["permbackup"].(bool) || b.Path == "" || b.Type != BTDefault {
 		return
 	}
-	f := filepath.Join(config.ConfigDir, "backups", util.EscapePath(b.AbsPath))
+	f := filepath.Join(config.StateDir, "backups", util.EscapePath(b.AbsPath))
 	os.Remove(f)
 }
 
+// RemoveBackupOf removes the backup of the file at path, if there is one
+func RemoveBackupOf(path string) {
+	os.Remove(filepath.Join(config.StateDir, "backups", util.EscapePath(path)))
+}
+
 // ApplyBackup applies the corresponding backup file to this buffer (if one exists)
 // Returns true if a backup was applied
 func (b *Buffer) ApplyBackup(fsize int64) bool {
 	if b.Settings["backup"].(bool) && !b.Settings["permbackup"].(bool) && len(b.Path) > 0 && b.Type == BTDefault {
-		backupfile := filepath.Join(config.ConfigDir, "backups", util.EscapePath(b.AbsPath))
+		backupfile := filepath.Join(config.StateDir, "backups", util.EscapePath(b.AbsPath))
 		if info, err := os.Stat(backupfile); err == nil {
 			backup, err := os.Open(backupfile)
 			if err == nil {
@@ -133,6 +143,7 @@ func (b *Buffer) ApplyBackup(fsize int64) bool {
 					// recover
 					b.LineArray = NewLineArray(uint64(fsize), FFAuto, backup)
 					b.isModified = true
+					b.hash.gen++
 					return true
 				} else if choice%2 == 1 {
 					// delete
@@ -0,0 +1,74 @@
+package buffer
+
+import (
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// SelectBlock replaces the cursors of the buffer by a rectangular selection
+// from the visual column startCol of line startY to the visual column endCol
+// of line endY: each line of the block has a cursor selecting the text
+// between the two columns, on the side of endCol. A tab crossing the left
+// column is part of the block and a tab crossing the right column is not.
+// The lines ending before the left column are left out, except line endY
+// whose cursor is the active one.
+func (b *Buffer) SelectBlock(startY, startCol, endY, endCol int) {
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	left, right := util.Min(startCol, endCol), util.Max(startCol, endCol)
+	top, bottom := util.Min(startY, endY), util.Max(startY, endY)
+
+	b.ClearCursors()
+	n := 0
+	for y := top; y <= bottom; y++ {
+		line := b.LineBytes(y)
+		if y != endY && util.StringWidth(line, util.CharacterCount(line), tabsize) < left {
+			continue
+		}
+		c := b.GetActiveCursor()
+		if n > 0 {
+			c = NewCursor(b, Loc{})
+			b.AddCursor(c)
+		}
+		start := Loc{X: util.GetCharPosInLine(line, left, tabsize), Y: y}
+		end := Loc{X: util.GetCharPosInLine(line, right, tabsize), Y: y}
+		c.SetSelectionStart(start)
+		c.SetSelectionEnd(end)
+		c.OrigSelection = c.CurSelection
+		if endCol < startCol {
+			c.GotoLoc(start)
+		} else {
+			c.GotoLoc(end)
+		}
+		c.LastVisualX = endCol
+		if y == endY {
+			b.SetCurCursor(n)
+		}
+		n++
+	}
+	b.UpdateCursors()
+}
+
+// InsertBlock inserts the lines of a rectangular selection at loc, each one
+// at the visual column of loc on the lines from the line of loc, as a single
+// undo step. The lines shorter than the column are padded with spaces, and
+// lines are added at the end of the buffer if there are not enough of them.
+func (b *Buffer) InsertBlock(loc Loc, lines []string) {
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	col := util.StringWidth(b.LineBytes(loc.Y), loc.X, tabsize)
+	b.UndoGroup(func() {
+		for i, text := range lines {
+			y := loc.Y + i
+			if y >= b.LinesNum() {
+				b.Insert(b.End(), "\n")
+			}
+			line := b.LineBytes(y)
+			n := util.CharacterCount(line)
+			if width := util.StringWidth(line, n, tabsize); width < col {
+				b.Insert(Loc{X: n, Y: y}, strings.Repeat(" ", col-width))
+				line = b.LineBytes(y)
+			}
+			b.Insert(Loc{X: util.GetCharPosInLine(line, col, tabsize), Y: y}, text)
+		}
+	})
+}
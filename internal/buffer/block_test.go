@@ -0,0 +1,57 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func blockSelections(b *Buffer) []string {
+	var sel []string
+	for _, c := range b.GetCursors() {
+		sel = append(sel, string(c.GetSelection()))
+	}
+	return sel
+}
+
+func TestSelectBlock(t *testing.T) {
+	b := NewBufferFromString("abcdef\nab\n\tcdef\nabcdefgh", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("tabsize", float64(4))
+
+	b.SelectBlock(0, 2, 3, 5)
+	assert.Equal(t, []string{"cde", "", "\tc", "cde"}, blockSelections(b))
+	active := b.GetActiveCursor()
+	assert.Equal(t, Loc{X: 5, Y: 3}, active.Loc)
+	assert.Equal(t, 5, active.LastVisualX)
+
+	// selecting towards the left leaves the cursors on the left side, and
+	// the line of the active cursor is kept even if it is too short
+	b.SelectBlock(3, 6, 1, 4)
+	assert.Equal(t, []string{"", "cd", "ef"}, blockSelections(b))
+	assert.Equal(t, Loc{X: 2, Y: 1}, b.GetActiveCursor().Loc)
+	assert.Equal(t, Loc{X: 4, Y: 3}, b.GetCursor(2).Loc)
+
+	// typing on every line of a block replaces its text
+	for _, c := range b.GetCursors() {
+		c.DeleteSelection()
+		c.ResetSelection()
+	}
+	assert.Equal(t, "abcdef\nab\n\tef\nabcdgh", string(b.Bytes()))
+
+	b.SelectBlock(0, 1, 0, 1)
+	assert.Equal(t, 1, b.NumCursors())
+	assert.False(t, b.GetActiveCursor().HasSelection())
+}
+
+func TestInsertBlock(t *testing.T) {
+	b := NewBufferFromString("abcd\nx\n\tyz", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("tabsize", float64(4))
+
+	b.InsertBlock(Loc{X: 2, Y: 0}, []string{"12", "34", "56", "78"})
+	assert.Equal(t, "ab12cd\nx 34\n56\tyz\n  78", string(b.Bytes()))
+
+	b.Undo()
+	assert.Equal(t, "abcd\nx\n\tyz", string(b.Bytes()))
+}
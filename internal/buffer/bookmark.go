@@ -0,0 +1,181 @@
+package buffer
+
+import (
+	"errors"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/project"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// Bookmarks returns the bookmarks of the buffer, at the lines they moved to
+// while the buffer was edited
+func (b *Buffer) Bookmarks() []project.Bookmark {
+	bms := make([]project.Bookmark, len(b.bookmarks))
+	for i, bm := range b.bookmarks {
+		bm.Path = b.AbsPath
+		bms[i] = bm
+	}
+	return bms
+}
+
+// HasBookmarks returns whether the buffer has any bookmark
+func (b *Buffer) HasBookmarks() bool {
+	return len(b.bookmarks) > 0
+}
+
+// BookmarksAt returns the names of the bookmarks on the line y
+func (b *Buffer) BookmarksAt(y int) []string {
+	var names []string
+	for _, bm := range b.bookmarks {
+		if bm.Line == y {
+			names = append(names, bm.Name)
+		}
+	}
+	return names
+}
+
+// SetBookmark puts the bookmark name on the line y and returns its name. A
+// bookmark with the same name in any file is replaced, and an empty name
+// gives the bookmark the smallest free number.
+func (b *Buffer) SetBookmark(name string, y int) (string, error) {
+	if b.Path == "" || b.Type != BTDefault {
+		return "", errors.New("Only the buffers of files can have bookmarks")
+	}
+	if name == "" {
+		all, err := AllBookmarks()
+		if err != nil {
+			return "", err
+		}
+		name = project.NextNumber(all)
+	}
+	for _, buf := range OpenBuffers {
+		buf.removeBookmark(name)
+	}
+	b.removeBookmark(name)
+	b.bookmarks = append(b.bookmarks, project.Bookmark{Name: name, Line: y})
+	project.SortBookmarks(b.bookmarks)
+	return name, b.saveBookmarks()
+}
+
+// DeleteBookmarksAt removes the bookmarks on the line y and returns their
+// names
+func (b *Buffer) DeleteBookmarksAt(y int) ([]string, error) {
+	names := b.BookmarksAt(y)
+	for _, name := range names {
+		b.removeBookmark(name)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return names, b.saveBookmarks()
+}
+
+// AllBookmarks returns the bookmarks of all files, sorted as by
+// project.SortBookmarks. The bookmarks of the open buffers are at the lines
+// they moved to while the buffers were edited.
+func AllBookmarks() ([]project.Bookmark, error) {
+	stored, err := project.Bookmarks()
+	if err != nil {
+		return nil, err
+	}
+	open := make(map[string]bool)
+	var bms []project.Bookmark
+	for _, buf := range OpenBuffers {
+		if buf.Path == "" || open[buf.AbsPath] {
+			continue
+		}
+		open[buf.AbsPath] = true
+		bms = append(bms, buf.Bookmarks()...)
+	}
+	for _, bm := range stored {
+		if !open[bm.Path] {
+			bms = append(bms, bm)
+		}
+	}
+	project.SortBookmarks(bms)
+	return bms, nil
+}
+
+// DeleteBookmark removes the bookmark name, from an open buffer or from the
+// stored bookmarks of a file which isn't open, and returns whether it
+// existed
+func DeleteBookmark(name string) (bool, error) {
+	for _, buf := range OpenBuffers {
+		if buf.removeBookmark(name) {
+			return true, buf.saveBookmarks()
+		}
+	}
+	all, err := project.Bookmarks()
+	if err != nil {
+		return false, err
+	}
+	for _, bm := range all {
+		if bm.Name != name {
+			continue
+		}
+		file, err := project.FileBookmarks(bm.Path)
+		if err != nil {
+			return false, err
+		}
+		kept := file[:0]
+		for _, f := range file {
+			if f.Name != name {
+				kept = append(kept, f)
+			}
+		}
+		return true, project.SetFileBookmarks(bm.Path, kept)
+	}
+	return false, nil
+}
+
+func (b *Buffer) removeBookmark(name string) bool {
+	for i, bm := range b.bookmarks {
+		if bm.Name == name {
+			b.bookmarks = append(b.bookmarks[:i:i], b.bookmarks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// saveBookmarks stores the bookmarks of the buffer at their current lines
+func (b *Buffer) saveBookmarks() error {
+	if b.Path == "" || config.StateDir == "" {
+		return nil
+	}
+	return project.SetFileBookmarks(b.AbsPath, b.Bookmarks())
+}
+
+// loadBookmarks reads the stored bookmarks of the buffer's file
+func (b *Buffer) loadBookmarks() {
+	bms, err := project.FileBookmarks(b.AbsPath)
+	if err != nil {
+		util.Log(util.LogError, "buffer", "Error reading bookmarks:", err)
+		return
+	}
+	for i := range bms {
+		// the file may have changed since the bookmarks were saved
+		bms[i].Line = util.Clamp(bms[i].Line, 0, b.LinesNum()-1)
+	}
+	b.bookmarks = bms
+}
+
+// moveBookmarks moves the bookmarks after a text event changed the text
+// between start and end. A bookmark follows its line when lines are
+// inserted at its start, and the bookmarks of removed lines move to the
+// line the removal started on.
+func (b *SharedBuffer) moveBookmarks(eventType int, start, end Loc) {
+	for i := range b.bookmarks {
+		y := &b.bookmarks[i].Line
+		if eventType == TextEventInsert {
+			if *y > start.Y || *y == start.Y && start.X == 0 && end.Y > start.Y {
+				*y += end.Y - start.Y
+			}
+		} else if *y > end.Y {
+			*y -= end.Y - start.Y
+		} else if *y > start.Y {
+			*y = start.Y
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package buffer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/project"
+)
+
+func TestBookmarks(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "f.txt")
+	b := NewBufferFromString("a\nb\nc\nd\ne", path, BTDefault)
+	name, err := b.SetBookmark("", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", name)
+	b.SetBookmark("x", 3)
+	name, _ = b.SetBookmark("", 4)
+	assert.Equal(t, "2", name)
+
+	// the bookmarks follow the lines they are on
+	b.Insert(Loc{X: 0, Y: 1}, "new\n")
+	assert.Equal(t, []string{"1"}, b.BookmarksAt(2))
+	b.Remove(Loc{X: 0, Y: 3}, Loc{X: 0, Y: 5})
+	assert.Equal(t, []string{"2", "x"}, b.BookmarksAt(3))
+	b.Insert(Loc{X: 1, Y: 2}, "\n")
+	assert.Equal(t, []string{"1"}, b.BookmarksAt(2))
+
+	// they are stored at their new lines when the buffer is saved
+	assert.Nil(t, b.Save())
+	stored, err := project.FileBookmarks(b.AbsPath)
+	assert.Nil(t, err)
+	assert.Equal(t, []project.Bookmark{
+		{Name: "1", Path: b.AbsPath, Line: 2},
+		{Name: "2", Path: b.AbsPath, Line: 4},
+		{Name: "x", Path: b.AbsPath, Line: 4},
+	}, stored)
+	b.Close()
+
+	b, err = NewBufferFromFile(path, BTDefault)
+	assert.Nil(t, err)
+	defer b.Close()
+	assert.Equal(t, []string{"2", "x"}, b.BookmarksAt(4))
+	names, err := b.DeleteBookmarksAt(4)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2", "x"}, names)
+	found, err := DeleteBookmark("1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.False(t, b.HasBookmarks())
+
+	_, err = NewBufferFromString("", "", BTDefault).SetBookmark("", 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,85 @@
+package buffer
+
+import "sort"
+
+// Breakpoints returns the lines of the breakpoints of the buffer, sorted,
+// at the lines they moved to while the buffer was edited
+func (b *Buffer) Breakpoints() []int {
+	return append([]int(nil), b.breakpoints...)
+}
+
+// HasBreakpoints returns whether the buffer has any breakpoint
+func (b *Buffer) HasBreakpoints() bool {
+	return len(b.breakpoints) > 0
+}
+
+// IsBreakpoint returns whether there is a breakpoint on the line y
+func (b *Buffer) IsBreakpoint(y int) bool {
+	i := sort.SearchInts(b.breakpoints, y)
+	return i < len(b.breakpoints) && b.breakpoints[i] == y
+}
+
+// ToggleBreakpoint adds a breakpoint on the line y, or removes the one
+// there, and returns whether there is one now
+func (b *Buffer) ToggleBreakpoint(y int) bool {
+	i := sort.SearchInts(b.breakpoints, y)
+	if i < len(b.breakpoints) && b.breakpoints[i] == y {
+		b.breakpoints = append(b.breakpoints[:i], b.breakpoints[i+1:]...)
+		return false
+	}
+	b.breakpoints = append(b.breakpoints, 0)
+	copy(b.breakpoints[i+1:], b.breakpoints[i:])
+	b.breakpoints[i] = y
+	return true
+}
+
+// ClearBreakpoints removes the breakpoints of the buffer
+func (b *Buffer) ClearBreakpoints() {
+	b.breakpoints = nil
+}
+
+// StoppedLine returns the line where the program being debugged is
+// stopped, and false if it isn't stopped in the buffer
+func (b *Buffer) StoppedLine() (int, bool) {
+	return b.stoppedLine - 1, b.stoppedLine > 0
+}
+
+// SetStoppedLine shows that the program being debugged is stopped on the
+// line y, or nowhere in the buffer if y is negative
+func (b *Buffer) SetStoppedLine(y int) {
+	b.stoppedLine = y + 1
+}
+
+// moveBreakpoints moves the breakpoints and the stopped line with the lines
+// after a text event changed the text between start and end, as the
+// bookmarks move. The breakpoints of removed lines move to the line of the
+// removal, where they are merged.
+func (b *SharedBuffer) moveBreakpoints(eventType int, start, end Loc) {
+	moved := b.breakpoints[:0]
+	for _, y := range b.breakpoints {
+		y = movedLine(y, eventType, start, end)
+		if len(moved) == 0 || moved[len(moved)-1] != y {
+			moved = append(moved, y)
+		}
+	}
+	b.breakpoints = moved
+	if b.stoppedLine > 0 {
+		b.stoppedLine = movedLine(b.stoppedLine-1, eventType, start, end) + 1
+	}
+}
+
+// movedLine returns the line y moved to after a text event changed the
+// text between start and end
+func movedLine(y, eventType int, start, end Loc) int {
+	n := end.Y - start.Y
+	if eventType == TextEventInsert {
+		if y > start.Y || y == start.Y && start.X == 0 && n > 0 {
+			y += n
+		}
+	} else if y > end.Y {
+		y -= n
+	} else if y > start.Y {
+		y = start.Y
+	}
+	return y
+}
@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakpoints(t *testing.T) {
+	b := NewBufferFromString("a\nb\nc\nd\ne", "", BTDefault)
+	defer b.Close()
+
+	assert.True(t, b.ToggleBreakpoint(3))
+	assert.True(t, b.ToggleBreakpoint(1))
+	assert.True(t, b.ToggleBreakpoint(2))
+	assert.False(t, b.ToggleBreakpoint(2))
+	assert.Equal(t, []int{1, 3}, b.Breakpoints())
+	assert.True(t, b.IsBreakpoint(3))
+	assert.False(t, b.IsBreakpoint(2))
+	b.SetStoppedLine(3)
+
+	b.Insert(Loc{0, 0}, "x\n")
+	assert.Equal(t, []int{2, 4}, b.Breakpoints())
+	y, ok := b.StoppedLine()
+	assert.True(t, ok)
+	assert.Equal(t, 4, y)
+
+	// the breakpoints of removed lines are merged
+	b.Remove(Loc{0, 2}, Loc{0, 5})
+	assert.Equal(t, []int{2}, b.Breakpoints())
+
+	b.SetStoppedLine(-1)
+	_, ok = b.StoppedLine()
+	assert.False(t, ok)
+	b.ClearBreakpoints()
+	assert.False(t, b.HasBreakpoints())
+}
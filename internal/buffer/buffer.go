@@ -6,6 +6,7 @@ import (
 	"crypto/md5"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"io/ioutil"
 	"os"
@@ -21,7 +22,9 @@ import (
 
 	dmp "github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/graphics"
 	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/project"
 	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/micro/v2/pkg/highlight"
@@ -64,6 +67,16 @@ var (
 	// BTStdout is a buffer that only writes to stdout
 	// when closed
 	BTStdout = BufType{6, false, true, true}
+	// BTSearch is a buffer that lists search results
+	BTSearch = BufType{7, true, true, false}
+	// BTScrollback is a buffer that shows the scrollback of a terminal
+	BTScrollback = BufType{8, true, true, false}
+	// BTFilter is the buffer of the standard input in filter mode, which
+	// is saved to the standard output when micro exits
+	BTFilter = BufType{9, false, false, true}
+	// BTView is the buffer of the viewer of a huge file, which holds the
+	// lines of the file around the view
+	BTView = BufType{10, true, true, false}
 
 	// ErrFileTooLarge is returned when the file is too large to hash
 	// (fastdirty is automatically enabled)
@@ -88,6 +101,10 @@ type SharedBuffer struct {
 
 	toStdout bool
 
+	// Image is the picture in an image file, which is shown rather than its
+	// hex dump while the imageview option is on
+	Image image.Image
+
 	// Settings customized by the user
 	Settings map[string]interface{}
 
@@ -102,9 +119,47 @@ type SharedBuffer struct {
 	diffBaseLineCount int
 	diffLock          sync.RWMutex
 	diff              map[int]DiffStatus
+	diffChanges       map[int][]DiffChange
+
+	// diffPeer is the buffer compared with this one in a diff view, and
+	// savedDiffBase and savedDiffGutter the diff base and diffgutter option
+	// to restore after the comparison
+	diffPeer        *Buffer
+	savedDiffBase   []byte
+	savedDiffGutter bool
+
+	// snippet is the snippet whose tab stops are being visited, or nil
+	snippet *snippetSession
+	// txn is the transaction started on the buffer, or nil
+	txn *transaction
+
+	// bookmarks are the bookmarks of the file, see SetBookmark
+	bookmarks []project.Bookmark
+
+	// folds are the folded regions, see Fold
+	folds []Fold
+
+	// breakpoints are the sorted lines of the breakpoints of the debugger
+	breakpoints []int
+	// stoppedLine is the line where the program being debugged is stopped
+	// plus one, or 0
+	stoppedLine int
 
 	requestedBackup bool
 
+	// draft is the name of the draft keeping the text of the buffer, see
+	// SaveDraft
+	draft string
+
+	// diskSize is the size of the file when it was last read or written, and
+	// followStop stops watching it for the follow option
+	diskSize   int64
+	followStop chan struct{}
+
+	// locked is whether this instance holds the lock of the file, see the
+	// filelock option
+	locked bool
+
 	// ReloadDisabled allows the user to disable reloads if they
 	// are viewing a file that is constantly changing
 	ReloadDisabled bool
@@ -124,21 +179,40 @@ type SharedBuffer struct {
 
 	// Hash of the original buffer -- empty if fastdirty is on
 	origHash [md5.Size]byte
+	// hash is the state of the hashing of the text against origHash, see
+	// hashModified
+	hash hashState
+
+	// historyGen is the gen of hash when the text was last added to the
+	// local history, and historyDisk whether the file on disk was added
+	historyGen  uint64
+	historyDisk bool
 }
 
 func (b *SharedBuffer) insert(pos Loc, value []byte) {
 	b.isModified = true
+	b.hash.gen++
 	b.HasSuggestions = false
+	b.countLines(pos.Y, pos.Y, -1)
 	b.LineArray.insert(pos, value)
 
 	inslines := bytes.Count(value, []byte{'\n'})
+	b.countLines(pos.Y, pos.Y+inslines, 1)
+	if b.txn != nil && b.txn.start >= 0 && pos.Y <= b.txn.end {
+		// the lines changed so far in the transaction moved down
+		b.txn.end += inslines
+	}
 	b.MarkModified(pos.Y, pos.Y+inslines)
 }
 func (b *SharedBuffer) remove(start, end Loc) []byte {
 	b.isModified = true
+	b.hash.gen++
 	b.HasSuggestions = false
 	defer b.MarkModified(start.Y, end.Y)
-	return b.LineArray.remove(start, end)
+	b.countLines(start.Y, end.Y, -1)
+	sub := b.LineArray.remove(start, end)
+	b.countLines(start.Y, start.Y, 1)
+	return sub
 }
 
 // MarkModified marks the buffer as modified for this frame
@@ -146,6 +220,17 @@ func (b *SharedBuffer) remove(start, end Loc) []byte {
 func (b *SharedBuffer) MarkModified(start, end int) {
 	b.ModifiedThisFrame = true
 
+	if t := b.txn; t != nil {
+		// the lines are highlighted when the transaction ends
+		if t.start < 0 || start < t.start {
+			t.start = start
+		}
+		if end > t.end {
+			t.end = end
+		}
+		return
+	}
+
 	if !b.Settings["syntax"].(bool) || b.SyntaxDef == nil {
 		return
 	}
@@ -153,11 +238,29 @@ func (b *SharedBuffer) MarkModified(start, end int) {
 	start = util.Clamp(start, 0, len(b.lines)-1)
 	end = util.Clamp(end, 0, len(b.lines)-1)
 
-	l := -1
-	for i := start; i <= end; i++ {
-		l = util.Max(b.Highlighter.ReHighlightStates(b, i), l)
+	// the states are updated until they are the same as before the
+	// modification, and the lines up to there are highlighted again when
+	// they are displayed, so that the cost of an edit doesn't depend on
+	// the size of the buffer
+	l := b.Highlighter.ReHighlightStatesRange(b, start, end)
+	for i := start; i <= l; i++ {
+		b.SetRehighlight(i, true)
+	}
+}
+
+// Match returns the syntax highlighting matches of the given line. The
+// line is highlighted first if it was modified, or the lines before it,
+// since it was last highlighted.
+func (b *SharedBuffer) Match(lineN int) highlight.LineMatch {
+	if b.Rehighlight(lineN) {
+		b.SetRehighlight(lineN, false)
+		if b.Settings["syntax"].(bool) && b.SyntaxDef != nil {
+			// a separate highlighter doesn't interfere with the one which
+			// may be computing the states in the background
+			highlight.NewHighlighter(b.SyntaxDef).HighlightMatches(b, lineN, lineN)
+		}
 	}
-	b.Highlighter.HighlightMatches(b, start, l)
+	return b.LineArray.Match(lineN)
 }
 
 // DisableReload disables future reloads of this sharedbuffer
@@ -202,6 +305,31 @@ type Buffer struct {
 	// This is hacky. Maybe it would be better to move all the visual x logic
 	// from buffer to display, but it would require rewriting a lot of code.
 	GetVisualX func(loc Loc) int
+
+	// LastSearch is the search whose matches are highlighted when the
+	// hlsearch option is on, and LastSearchRegex tells whether it is a
+	// regular expression. HighlightSearch is cleared by the nohlsearch
+	// command until the next search.
+	LastSearch      string
+	LastSearchRegex bool
+	HighlightSearch bool
+
+	// scope caches the result of Scope for the statusline, and search the
+	// regular expression of the last search and its matches
+	scope  scopeCache
+	search searchCache
+
+	// filterOutput is the text last saved in a BTFilter buffer, written to
+	// the standard output by Fini
+	filterOutput []byte
+
+	// editRefused is whether an edit was refused because the buffer is
+	// readonly, since the last call to EditRefused
+	editRefused bool
+
+	// Huge is the huge file shown by the buffer of the viewer, which holds
+	// a part of its lines
+	Huge *HugeFile
 }
 
 // NewBufferFromFileAtLoc opens a new buffer with a given cursor location
@@ -236,6 +364,9 @@ func NewBufferFromFileAtLoc(path string, btype BufType, cursorLoc Loc) (*Buffer,
 	if serr == nil && fileInfo.IsDir() {
 		return nil, errors.New("Error: " + filename + " is a directory and cannot be opened")
 	}
+	if serr == nil && btype == BTDefault && isHuge(fileInfo.Size()) {
+		return NewHugeFileBuffer(filename, cursorLoc)
+	}
 
 	file, err := os.Open(filename)
 	if err == nil {
@@ -249,7 +380,35 @@ func NewBufferFromFileAtLoc(path string, btype BufType, cursorLoc Loc) (*Buffer,
 	} else if err != nil {
 		return nil, err
 	} else {
-		buf = NewBuffer(file, util.FSize(file), filename, cursorLoc, btype)
+		reader := bufio.NewReader(file)
+		sample, _ := reader.Peek(binarySample)
+		if IsBinary(sample) {
+			// binary files are shown in the hex view rather than as text
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return nil, err
+			}
+			dump := HexDump(data)
+			buf = NewBuffer(strings.NewReader(dump), int64(len(dump)), filename, cursorLoc, btype)
+			buf.Settings["hex"] = true
+			buf.Image, _ = graphics.Decode(data)
+			if prompt != nil && buf.Image != nil && buf.Settings["imageview"].(bool) {
+				prompt.Message("Image file shown as a picture, 'set imageview off' shows it in hex")
+			} else if prompt != nil {
+				prompt.Message("Binary file shown in hex, 'set hex off' shows it as text")
+			}
+		} else {
+			buf = NewBuffer(reader, util.FSize(file), filename, cursorLoc, btype)
+			if buf.Settings["detectindent"].(bool) {
+				buf.detectIndentSettings()
+			}
+			if buf.Settings["hex"].(bool) {
+				buf.Settings["hex"] = false
+				if err := buf.SetOptionNative("hex", true); err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
 
 	if readonly && prompt != nil {
@@ -288,6 +447,12 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 
 	b := new(Buffer)
 
+	// the input is given back unchanged if the buffer isn't saved
+	var input bytes.Buffer
+	if btype == BTFilter {
+		r = io.TeeReader(r, &input)
+	}
+
 	found := false
 	if len(path) > 0 {
 		for _, buf := range OpenBuffers {
@@ -333,6 +498,10 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 			b.Settings["encoding"] = "utf-8"
 		}
 
+		if btype == BTDefault && path != "" && b.Settings["filelock"].(bool) {
+			b.lock()
+		}
+
 		hasBackup = b.ApplyBackup(size)
 
 		if !hasBackup {
@@ -357,9 +526,13 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 
 		// The last time this file was modified
 		b.UpdateModTime()
+
+		if btype == BTDefault && path != "" && config.StateDir != "" {
+			b.loadBookmarks()
+		}
 	}
 
-	if b.Settings["readonly"].(bool) && b.Type == BTDefault {
+	if (b.Settings["readonly"].(bool) || b.Settings["viewmode"].(bool)) && b.Type == BTDefault {
 		b.Type.Readonly = true
 	}
 
@@ -370,12 +543,19 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 		b.Settings["fileformat"] = "dos"
 	}
 
+	// the filetype set by a modeline picks the syntax, and its other options
+	// override the ones of the filetype
+	modeline := b.modeline()
+	if ft, ok := modeline["filetype"]; ok {
+		b.Settings["filetype"] = ft
+	}
 	b.UpdateRules()
 	// init local settings again now that we know the filetype
 	config.InitLocalSettings(b.Settings, b.Path)
+	b.applyModeline(modeline)
 
-	if _, err := os.Stat(filepath.Join(config.ConfigDir, "buffers")); os.IsNotExist(err) {
-		os.Mkdir(filepath.Join(config.ConfigDir, "buffers"), os.ModePerm)
+	if _, err := os.Stat(filepath.Join(config.StateDir, "buffers")); os.IsNotExist(err) {
+		os.Mkdir(filepath.Join(config.StateDir, "buffers"), os.ModePerm)
 	}
 
 	if startcursor.X != -1 && startcursor.Y != -1 {
@@ -390,6 +570,10 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 	b.AddCursor(NewCursor(b, b.StartCursor))
 	b.GetActiveCursor().Relocate()
 
+	if btype == BTFilter {
+		b.filterOutput = input.Bytes()
+	}
+
 	if !b.Settings["fastdirty"].(bool) && !found {
 		if size > LargeFileThreshold {
 			// If the file is larger than LargeFileThreshold fastdirty needs to be on
@@ -401,11 +585,25 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 		}
 	}
 
+	if b.Settings["diffgutter"].(bool) {
+		b.UpdateDiffBase()
+	}
+
 	err := config.RunPluginFn("onBufferOpen", luar.New(ulua.L, b))
 	if err != nil {
 		screen.TermMessage(err)
 	}
 
+	if btype == BTDefault && b.Path != "" && config.StateDir != "" {
+		if err := project.Open(b.AbsPath); err != nil {
+			util.Log(util.LogError, "buffer", "Error recording recent file:", err)
+		}
+	}
+
+	if b.Settings["follow"].(bool) {
+		b.startFollow()
+	}
+
 	OpenBuffers = append(OpenBuffers, b)
 
 	return b
@@ -415,6 +613,7 @@ func NewBuffer(r io.Reader, size int64, path string, startcursor Loc, btype BufT
 func (b *Buffer) Close() {
 	for i, buf := range OpenBuffers {
 		if b == buf {
+			b.EndDiff()
 			b.Fini()
 			copy(OpenBuffers[i:], OpenBuffers[i+1:])
 			OpenBuffers[len(OpenBuffers)-1] = nil
@@ -431,11 +630,38 @@ func (b *Buffer) Fini() {
 		b.Serialize()
 	}
 	b.RemoveBackup()
+	if b.draft != "" || b.UndoStack.Len() > 0 {
+		if err := b.SaveDraft(); err != nil {
+			util.Log(util.LogError, "buffer", "Error saving draft:", err)
+		}
+	}
+
+	if b.Type == BTDefault && b.Path != "" && config.StateDir != "" {
+		c := b.GetActiveCursor().Loc
+		if err := project.SaveCursor(b.AbsPath, c.Y, c.X); err != nil {
+			util.Log(util.LogError, "buffer", "Error recording recent file:", err)
+		}
+	}
 
 	if b.Type == BTStdout {
 		fmt.Fprint(util.Stdout, string(b.Bytes()))
 	}
+	if b.Type == BTFilter {
+		util.Stdout.Write(b.filterOutput)
+	}
 
+	b.stopFollow()
+	if b.Huge != nil {
+		b.Huge.Close()
+	}
+	// the lock is kept while the file is open in another pane
+	shared := false
+	for _, o := range OpenBuffers {
+		shared = shared || o != b && o.SharedBuffer == b.SharedBuffer
+	}
+	if !shared {
+		b.unlock()
+	}
 	atomic.StoreInt32(&(b.fini), int32(1))
 }
 
@@ -444,7 +670,9 @@ func (b *Buffer) Fini() {
 func (b *Buffer) GetName() string {
 	name := b.name
 	if name == "" {
-		if b.Path == "" {
+		if b.Path == "" && b.draft != "" {
+			return "Draft " + b.draft
+		} else if b.Path == "" {
 			return "No name"
 		}
 		name = b.Path
@@ -455,14 +683,31 @@ func (b *Buffer) GetName() string {
 	return name
 }
 
-//SetName changes the name for this buffer
+// SetName changes the name for this buffer
 func (b *Buffer) SetName(s string) {
 	b.name = s
 }
 
+// refuseEdit returns whether the buffer is readonly, noting the edit it
+// refuses for EditRefused
+func (b *Buffer) refuseEdit() bool {
+	if b.Type.Readonly {
+		b.editRefused = true
+	}
+	return b.Type.Readonly
+}
+
+// EditRefused returns whether an edit of the buffer was refused because it
+// is readonly since the last call
+func (b *Buffer) EditRefused() bool {
+	refused := b.editRefused
+	b.editRefused = false
+	return refused
+}
+
 // Insert inserts the given string of text at the start location
 func (b *Buffer) Insert(start Loc, text string) {
-	if !b.Type.Readonly {
+	if !b.refuseEdit() {
 		b.EventHandler.cursors = b.cursors
 		b.EventHandler.active = b.curCursor
 		b.EventHandler.Insert(start, text)
@@ -482,6 +727,45 @@ func (b *Buffer) Remove(start, end Loc) {
 	}
 }
 
+// Replace replaces the text between the start and end locations
+func (b *Buffer) Replace(start, end Loc, text string) {
+	if !b.refuseEdit() {
+		b.EventHandler.Replace(start, end, text)
+	}
+}
+
+// MultipleReplace applies the deltas as a single text event
+func (b *Buffer) MultipleReplace(deltas []Delta) {
+	if !b.refuseEdit() {
+		b.EventHandler.MultipleReplace(deltas)
+	}
+}
+
+// ApplyDiff changes the text of the buffer to text, in a transaction if
+// none is started
+func (b *Buffer) ApplyDiff(text string) {
+	if !b.refuseEdit() {
+		if b.BeginTransaction() {
+			defer b.CommitTransaction()
+		}
+		b.EventHandler.ApplyDiff(text)
+	}
+}
+
+// Undo undoes the last text event, unless the buffer is readonly
+func (b *Buffer) Undo() {
+	if !b.refuseEdit() {
+		b.EventHandler.Undo()
+	}
+}
+
+// Redo redoes the last undone text event, unless the buffer is readonly
+func (b *Buffer) Redo() {
+	if !b.refuseEdit() {
+		b.EventHandler.Redo()
+	}
+}
+
 // FileType returns the buffer's filetype
 func (b *Buffer) FileType() string {
 	return b.Settings["filetype"].(string)
@@ -500,28 +784,47 @@ func (b *Buffer) ExternallyModified() bool {
 // UpdateModTime updates the modtime of this file
 func (b *Buffer) UpdateModTime() (err error) {
 	b.ModTime, err = util.GetModTime(b.Path)
+	b.updateDiskSize(b.Path)
 	return
 }
 
-// ReOpen reloads the current buffer from disk
-func (b *Buffer) ReOpen() error {
-	file, err := os.Open(b.Path)
+// DiskBytes returns the text of the file of the buffer as it is on disk,
+// decoded with the encoding of the buffer, or as a hex dump in the hex view
+func (b *Buffer) DiskBytes() ([]byte, error) {
+	data, err := ioutil.ReadFile(b.Path)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return b.decodeFile(data)
+}
 
+// decodeFile returns the text of the buffer for the contents of its file
+func (b *Buffer) decodeFile(data []byte) ([]byte, error) {
+	if b.Settings["hex"].(bool) {
+		return []byte(HexDump(data)), nil
+	}
 	enc, err := htmlindex.Get(b.Settings["encoding"].(string))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return ioutil.ReadAll(transform.NewReader(bytes.NewReader(data), enc.NewDecoder()))
+}
 
-	reader := bufio.NewReader(transform.NewReader(file, enc.NewDecoder()))
-	data, err := ioutil.ReadAll(reader)
-	txt := string(data)
-
+// ReOpen reloads the current buffer from disk
+func (b *Buffer) ReOpen() error {
+	data, err := ioutil.ReadFile(b.Path)
 	if err != nil {
 		return err
 	}
+	if b.Settings["hex"].(bool) && b.Image != nil {
+		b.Image, _ = graphics.Decode(data)
+	}
+	data, err = b.decodeFile(data)
+	if err != nil {
+		return err
+	}
+	txt := string(data)
+
 	b.EventHandler.ApplyDiff(txt)
 
 	err = b.UpdateModTime()
@@ -530,6 +833,9 @@ func (b *Buffer) ReOpen() error {
 	}
 	b.isModified = false
 	b.RelocateCursors()
+	if b.Settings["diffgutter"].(bool) {
+		b.UpdateDiffBase()
+	}
 	return err
 }
 
@@ -587,14 +893,10 @@ func (b *Buffer) Modified() bool {
 		return false
 	}
 
-	if b.Settings["fastdirty"].(bool) {
+	if b.Settings["fastdirty"].(bool) || !b.isModified {
 		return b.isModified
 	}
-
-	var buff [md5.Size]byte
-
-	calcHash(b, &buff)
-	return buff != b.origHash
+	return b.hashModified()
 }
 
 // Size returns the number of bytes in the current buffer
@@ -613,38 +915,75 @@ func (b *Buffer) Size() int {
 	return nb
 }
 
-// calcHash calculates md5 hash of all lines in the buffer
-func calcHash(b *Buffer, out *[md5.Size]byte) error {
-	h := md5.New()
+// A checkedSyntaxFile is the content of a user's syntax file when it was
+// validated, with the errors it contained
+type checkedSyntaxFile struct {
+	data string
+	errs []error
+}
 
-	size := 0
-	if len(b.lines) > 0 {
-		n, e := h.Write(b.lines[0].data)
-		if e != nil {
-			return e
-		}
-		size += n
+// checkedSyntax stores the user's syntax files which were validated by name
+var checkedSyntax = make(map[string]checkedSyntaxFile)
 
-		for _, l := range b.lines[1:] {
-			n, e = h.Write([]byte{'\n'})
-			if e != nil {
-				return e
-			}
-			size += n
-			n, e = h.Write(l.data)
-			if e != nil {
-				return e
-			}
-			size += n
-		}
+// validateSyntaxFile returns the errors of a user's syntax file. The errors
+// are reported the first time a version of the file is validated.
+func validateSyntaxFile(name string, data []byte) []error {
+	if c, ok := checkedSyntax[name]; ok && c.data == string(data) {
+		return c.errs
 	}
+	errs := highlight.Validate(data)
+	checkedSyntax[name] = checkedSyntaxFile{string(data), errs}
+	if len(errs) > 0 {
+		screen.TermMessage(syntaxErrorsMessage(name, errs))
+	}
+	return errs
+}
 
-	if size > LargeFileThreshold {
-		return ErrFileTooLarge
+// invalidSyntaxFile returns whether the syntax file is a user's syntax file
+// which was found to have errors
+func invalidSyntaxFile(name string, data []byte) bool {
+	c, ok := checkedSyntax[name]
+	return ok && len(c.errs) > 0 && c.data == string(data)
+}
+
+func syntaxErrorsMessage(name string, errs []error) string {
+	msg := "Errors in syntax file " + name + ", the file is ignored:"
+	for _, err := range errs {
+		msg += "\n    " + err.Error()
 	}
+	return msg
+}
 
-	h.Sum((*out)[:0])
-	return nil
+// ReloadSyntax rereads the user's syntax files, reports their errors and
+// updates the highlighting of all buffers. It returns the number of user's
+// syntax files and the number of them which have errors.
+func ReloadSyntax() (int, int) {
+	config.ReloadSyntaxFiles()
+	checkedSyntax = make(map[string]checkedSyntaxFile)
+	invalidateSyntaxCatalog()
+
+	files := config.ListRealRuntimeFiles(config.RTSyntax)
+	var msgs []string
+	for _, f := range files {
+		data, err := f.Data()
+		if err != nil {
+			msgs = append(msgs, "Error loading syntax file "+f.Name()+": "+err.Error())
+			continue
+		}
+		errs := highlight.Validate(data)
+		checkedSyntax[f.Name()] = checkedSyntaxFile{string(data), errs}
+		if len(errs) > 0 {
+			msgs = append(msgs, syntaxErrorsMessage(f.Name(), errs))
+		}
+	}
+	if len(msgs) > 0 {
+		screen.TermMessage(strings.Join(msgs, "\n\n"))
+	}
+
+	for _, b := range OpenBuffers {
+		b.UpdateRules()
+	}
+	return len(files), len(msgs)
 }
 
 // UpdateRules updates the syntax rules and filetype for this buffer
@@ -657,128 +996,25 @@ func (b *Buffer) UpdateRules() {
 	if ft == "off" {
 		return
 	}
-	syntaxFile := ""
-	foundDef := false
-	var header *highlight.Header
-	// search for the syntax file in the user's custom syntax files
-	for _, f := range config.ListRealRuntimeFiles(config.RTSyntax) {
-		data, err := f.Data()
-		if err != nil {
-			screen.TermMessage("Error loading syntax file " + f.Name() + ": " + err.Error())
-			continue
-		}
-
-		header, err = highlight.MakeHeaderYaml(data)
-		if err != nil {
-			screen.TermMessage("Error parsing header for syntax file " + f.Name() + ": " + err.Error())
-		}
-		file, err := highlight.ParseFile(data)
-		if err != nil {
-			screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-			continue
-		}
-
-		if ((ft == "unknown" || ft == "") && highlight.MatchFiletype(header.FtDetect, b.Path, b.lines[0].data)) || header.FileType == ft {
-			syndef, err := highlight.ParseDef(file, header)
-			if err != nil {
-				screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-				continue
-			}
-			b.SyntaxDef = syndef
-			syntaxFile = f.Name()
-			foundDef = true
-			break
-		}
-	}
-
-	// search in the default syntax files
-	for _, f := range config.ListRuntimeFiles(config.RTSyntaxHeader) {
-		data, err := f.Data()
-		if err != nil {
-			screen.TermMessage("Error loading syntax header file " + f.Name() + ": " + err.Error())
-			continue
-		}
-
-		header, err = highlight.MakeHeader(data)
-		if err != nil {
-			screen.TermMessage("Error reading syntax header file", f.Name(), err)
-			continue
-		}
-
-		if ft == "unknown" || ft == "" {
-			if highlight.MatchFiletype(header.FtDetect, b.Path, b.lines[0].data) {
-				syntaxFile = f.Name()
-				break
-			}
-		} else if header.FileType == ft {
-			syntaxFile = f.Name()
-			break
-		}
-	}
-
-	if syntaxFile != "" && !foundDef {
-		// we found a syntax file using a syntax header file
-		for _, f := range config.ListRuntimeFiles(config.RTSyntax) {
-			if f.Name() == syntaxFile {
-				data, err := f.Data()
-				if err != nil {
-					screen.TermMessage("Error loading syntax file " + f.Name() + ": " + err.Error())
-					continue
-				}
-
-				file, err := highlight.ParseFile(data)
-				if err != nil {
-					screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-					continue
-				}
-
-				syndef, err := highlight.ParseDef(file, header)
-				if err != nil {
-					screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-					continue
-				}
-				b.SyntaxDef = syndef
-				break
-			}
+	start := time.Now()
+	defer func() {
+		util.RecordTiming("syntax "+b.Settings["filetype"].(string), start)
+	}()
+	// only the headers of the syntax files are read to detect the filetype,
+	// the rules of the detected one are parsed the first time it is used
+	catalog := loadSyntaxCatalog()
+	sf := catalog.detect(ft, b.Path, b.lines[0].data)
+	if sf != nil {
+		def, errs := catalog.loadDef(sf)
+		for _, err := range errs {
+			screen.TermMessage("Error parsing syntax file " + sf.file.Name() + ": " + err.Error())
 		}
-	}
-
-	if b.SyntaxDef != nil && highlight.HasIncludes(b.SyntaxDef) {
-		includes := highlight.GetIncludes(b.SyntaxDef)
-
-		var files []*highlight.File
-		for _, f := range config.ListRuntimeFiles(config.RTSyntax) {
-			data, err := f.Data()
-			if err != nil {
-				screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-				continue
-			}
-			header, err := highlight.MakeHeaderYaml(data)
-			if err != nil {
-				screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-				continue
-			}
-
-			for _, i := range includes {
-				if header.FileType == i {
-					file, err := highlight.ParseFile(data)
-					if err != nil {
-						screen.TermMessage("Error parsing syntax file " + f.Name() + ": " + err.Error())
-						continue
-					}
-					files = append(files, file)
-					break
-				}
-			}
-			if len(files) >= len(includes) {
-				break
-			}
+		if def != nil {
+			b.SyntaxDef = def
 		}
-
-		highlight.ResolveIncludes(b.SyntaxDef, files)
 	}
 
-	if b.Highlighter == nil || syntaxFile != "" {
+	if b.Highlighter == nil || sf != nil {
 		if b.SyntaxDef != nil {
 			b.Settings["filetype"] = b.SyntaxDef.FileType
 		}
@@ -1035,33 +1271,6 @@ func (b *Buffer) FindMatchingBrace(braceType [2]rune, start Loc) (Loc, bool, boo
 	return start, true, false
 }
 
-// Retab changes all tabs to spaces or vice versa
-func (b *Buffer) Retab() {
-	toSpaces := b.Settings["tabstospaces"].(bool)
-	tabsize := util.IntOpt(b.Settings["tabsize"])
-	dirty := false
-
-	for i := 0; i < b.LinesNum(); i++ {
-		l := b.LineBytes(i)
-
-		ws := util.GetLeadingWhitespace(l)
-		if len(ws) != 0 {
-			if toSpaces {
-				ws = bytes.ReplaceAll(ws, []byte{'\t'}, bytes.Repeat([]byte{' '}, tabsize))
-			} else {
-				ws = bytes.ReplaceAll(ws, bytes.Repeat([]byte{' '}, tabsize), []byte{'\t'})
-			}
-		}
-
-		l = bytes.TrimLeft(l, " \t")
-		b.lines[i].data = append(ws, l...)
-		b.MarkModified(i, i)
-		dirty = true
-	}
-
-	b.isModified = dirty
-}
-
 // ParseCursorLocation turns a cursor location like 10:5 (LINE:COL)
 // into a loc
 func ParseCursorLocation(cursorPositions []string) (Loc, error) {
@@ -1087,6 +1296,27 @@ func ParseCursorLocation(cursorPositions []string) (Loc, error) {
 	return startpos, err
 }
 
+// SplitPathLocation splits FILE:LINE:COL or FILE:LINE, as printed by
+// compilers and grep, into the path and the location, when the file exists
+// without the position but not with it
+func SplitPathLocation(arg string) (string, Loc, bool) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, Loc{}, false
+	}
+	path, cursor := util.GetPathAndCursorPosition(arg)
+	if cursor == nil {
+		return arg, Loc{}, false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return arg, Loc{}, false
+	}
+	loc, err := ParseCursorLocation(cursor)
+	if err != nil {
+		return arg, Loc{}, false
+	}
+	return path, loc, true
+}
+
 // Line returns the string representation of the given line number
 func (b *Buffer) Line(i int) string {
 	return string(b.LineBytes(i))
@@ -1102,15 +1332,25 @@ func (b *Buffer) updateDiffSync() {
 	defer b.diffLock.Unlock()
 
 	b.diff = make(map[int]DiffStatus)
+	b.diffChanges = nil
 
 	if b.diffBase == nil {
 		return
 	}
 
+	// the changes within the lines are only shown when comparing buffers
+	var baseLines []string
+	if b.diffPeer != nil {
+		b.diffChanges = make(map[int][]DiffChange)
+		baseLines = strings.SplitAfter(string(b.diffBase), "\n")
+	}
+
 	differ := dmp.New()
 	baseRunes, bufferRunes, _ := differ.DiffLinesToRunes(string(b.diffBase), string(b.Bytes()))
 	diffs := differ.DiffMainRunes(baseRunes, bufferRunes, false)
-	lineN := 0
+	lineN, baseN := 0, 0
+	// the lines of the base deleted right before lineN
+	deletedStart, deletedCount := 0, 0
 
 	for _, diff := range diffs {
 		lineCount := len([]rune(diff.Text))
@@ -1118,10 +1358,14 @@ func (b *Buffer) updateDiffSync() {
 		switch diff.Type {
 		case dmp.DiffEqual:
 			lineN += lineCount
+			baseN += lineCount
 		case dmp.DiffInsert:
 			var status DiffStatus
 			if b.diff[lineN] == DSDeletedAbove {
 				status = DSModified
+				for i := 0; i < lineCount && i < deletedCount && baseLines != nil; i++ {
+					b.diffChanges[lineN+i] = lineChanges(baseLines[deletedStart+i], string(b.LineBytes(lineN+i)))
+				}
 			} else {
 				status = DSAdded
 			}
@@ -1131,6 +1375,8 @@ func (b *Buffer) updateDiffSync() {
 			}
 		case dmp.DiffDelete:
 			b.diff[lineN] = DSDeletedAbove
+			deletedStart, deletedCount = baseN, lineCount
+			baseN += lineCount
 		}
 	}
 }
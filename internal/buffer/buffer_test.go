@@ -1,7 +1,9 @@
 package buffer
 
 import (
+	"io/ioutil"
 	"math/rand"
+	"os"
 	"strings"
 	"testing"
 
@@ -25,6 +27,19 @@ func init() {
 	config.GlobalSettings["fastdirty"] = true
 }
 
+// withStateDir points config.StateDir to a temporary directory, which it
+// returns with a function restoring StateDir and removing the directory
+func withStateDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "micro-state")
+	assert.NoError(t, err)
+	old := config.StateDir
+	config.StateDir = dir
+	return dir, func() {
+		config.StateDir = old
+		os.RemoveAll(dir)
+	}
+}
+
 func check(t *testing.T, before []string, operations []operation, after []string) {
 	assert := assert.New(t)
 
@@ -320,3 +335,58 @@ func BenchmarkEdit1000000Lines100Cursors(b *testing.B) {
 func BenchmarkEdit1000000Lines1000Cursors(b *testing.B) {
 	benchEdit(b, 1000000, 1000)
 }
+
+func TestSplitPathLocation(t *testing.T) {
+	f, err := ioutil.TempFile("", "micro_split_test")
+	assert.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	path, loc, ok := SplitPathLocation(f.Name() + ":10:5:")
+	assert.True(t, ok)
+	assert.Equal(t, f.Name(), path)
+	assert.Equal(t, Loc{X: 4, Y: 9}, loc)
+
+	_, loc, ok = SplitPathLocation(f.Name() + ":3")
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 0, Y: 2}, loc)
+
+	// existing files and files which don't exist either way are left alone
+	_, _, ok = SplitPathLocation(f.Name())
+	assert.False(t, ok)
+	_, _, ok = SplitPathLocation(f.Name() + "x:3")
+	assert.False(t, ok)
+}
+
+func TestReadonly(t *testing.T) {
+	b := NewBufferFromString("abc", "", BTDefault)
+	assert.NoError(t, b.SetOptionNative("readonly", true))
+	assert.False(t, b.EditRefused())
+
+	b.Insert(b.Start(), "x")
+	b.Replace(b.Start(), b.End(), "y")
+	b.Undo()
+	assert.Equal(t, "abc", string(b.Bytes()))
+	assert.True(t, b.EditRefused())
+	assert.False(t, b.EditRefused())
+
+	// the view mode is readonly as well, until both options are off
+	assert.NoError(t, b.SetOptionNative("viewmode", true))
+	assert.NoError(t, b.SetOptionNative("readonly", false))
+	assert.True(t, b.Type.Readonly)
+	assert.NoError(t, b.SetOptionNative("viewmode", false))
+	b.Insert(b.Start(), "x")
+	assert.Equal(t, "xabc", string(b.Bytes()))
+	assert.False(t, b.EditRefused())
+}
+
+func TestCursorOrder(t *testing.T) {
+	b := NewBufferFromString("one\ntwo\nthree\n", "", BTDefault)
+	b.GetActiveCursor().GotoLoc(Loc{0, 2})
+	b.AddCursor(NewCursor(b, Loc{1, 0}))
+	b.AddCursor(NewCursor(b, Loc{0, 1}))
+
+	assert.Equal(t, 2, b.GetCursor(0).Order())
+	assert.Equal(t, 0, b.GetCursor(1).Order())
+	assert.Equal(t, 1, b.GetCursor(2).Order())
+}
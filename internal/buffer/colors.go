@@ -0,0 +1,151 @@
+package buffer
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+var (
+	// hexColorRegex matches the colors such as #fff or #a0b0c0ff, which
+	// aren't part of a word or of an html character reference
+	hexColorRegex = regexp.MustCompile(`(?:^|[^\w&#])(#(?:[[:xdigit:]]{8}|[[:xdigit:]]{6}|[[:xdigit:]]{3,4}))\b`)
+	rgbColorRegex = regexp.MustCompile(`\brgba?\(\s*(\d{1,3}%?)\s*[,\s]\s*(\d{1,3}%?)\s*[,\s]\s*(\d{1,3}%?)\s*(?:[,/]\s*[\d.]+%?\s*)?\)`)
+	hslColorRegex = regexp.MustCompile(`\bhsla?\(\s*([\d.]+)(?:deg)?\s*[,\s]\s*([\d.]+)%\s*[,\s]\s*([\d.]+)%\s*(?:[,/]\s*[\d.]+%?\s*)?\)`)
+)
+
+// swatchFiletypes are the filetypes whose colors are shown when the
+// colorswatch option is auto: stylesheets and configuration files
+var swatchFiletypes = map[string]bool{
+	"css":        true,
+	"html":       true,
+	"ini":        true,
+	"json":       true,
+	"less":       true,
+	"micro":      true,
+	"sass":       true,
+	"scss":       true,
+	"svg":        true,
+	"toml":       true,
+	"xml":        true,
+	"xresources": true,
+	"yaml":       true,
+}
+
+// A ColorLiteral is a color written in a line, such as #ff8800 or
+// rgb(255, 136, 0), from the character Start to End (excluded)
+type ColorLiteral struct {
+	Start, End int
+	Color      tcell.Color
+}
+
+// ColorLiterals returns the colors written in the line y, if they are
+// shown in the buffer according to the colorswatch option
+func (b *Buffer) ColorLiterals(y int) []ColorLiteral {
+	switch b.Settings["colorswatch"].(string) {
+	case "off":
+		return nil
+	case "auto":
+		if !swatchFiletypes[b.FileType()] {
+			return nil
+		}
+	}
+	return FindColors(b.LineBytes(y))
+}
+
+// FindColors returns the hexadecimal, rgb() and hsl() colors of a line, in
+// the order they are written
+func FindColors(line []byte) []ColorLiteral {
+	var colors []ColorLiteral
+	add := func(start, end int, c tcell.Color) {
+		colors = append(colors, ColorLiteral{util.RunePos(line, start), util.RunePos(line, end), c})
+	}
+
+	for _, m := range hexColorRegex.FindAllSubmatchIndex(line, -1) {
+		hex := string(line[m[2]+1 : m[3]])
+		if len(hex) <= 4 {
+			// #rgb is #rrggbb
+			var sb strings.Builder
+			for _, c := range hex[:3] {
+				sb.WriteRune(c)
+				sb.WriteRune(c)
+			}
+			hex = sb.String()
+		}
+		v, _ := strconv.ParseInt(hex[:6], 16, 32)
+		add(m[2], m[3], tcell.NewHexColor(int32(v)))
+	}
+	for _, m := range rgbColorRegex.FindAllSubmatchIndex(line, -1) {
+		var rgb [3]int32
+		for i := range rgb {
+			rgb[i] = int32(math.Round(colorComponent(string(line[m[2+2*i]:m[3+2*i]]), 255)))
+		}
+		add(m[0], m[1], tcell.NewRGBColor(rgb[0], rgb[1], rgb[2]))
+	}
+	for _, m := range hslColorRegex.FindAllSubmatchIndex(line, -1) {
+		h, _ := strconv.ParseFloat(string(line[m[2]:m[3]]), 64)
+		s, _ := strconv.ParseFloat(string(line[m[4]:m[5]]), 64)
+		l, _ := strconv.ParseFloat(string(line[m[6]:m[7]]), 64)
+		add(m[0], m[1], hslColor(h, math.Min(s, 100)/100, math.Min(l, 100)/100))
+	}
+
+	sort.Slice(colors, func(i, j int) bool {
+		return colors[i].Start < colors[j].Start
+	})
+	return colors
+}
+
+// colorComponent returns the value of a component such as 128 or 50% of
+// a color whose components go up to max
+func colorComponent(s string, max float64) float64 {
+	if strings.HasSuffix(s, "%") {
+		v, _ := strconv.ParseFloat(s[:len(s)-1], 64)
+		return math.Min(v, 100) / 100 * max
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return math.Min(v, max)
+}
+
+// hslColor returns the color of the hue h in degrees and the saturation
+// s and lightness l between 0 and 1
+func hslColor(h, s, l float64) tcell.Color {
+	h = math.Mod(h, 360) / 60
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 1:
+		r, g = c, x
+	case h < 2:
+		r, g = x, c
+	case h < 3:
+		g, b = c, x
+	case h < 4:
+		g, b = x, c
+	case h < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	component := func(v float64) int32 {
+		return int32(math.Round((v + m) * 255))
+	}
+	return tcell.NewRGBColor(component(r), component(g), component(b))
+}
+
+// SwatchStyle returns the style of a color literal, drawn on its color
+// with black or white text, whichever is more readable
+func SwatchStyle(c tcell.Color) tcell.Style {
+	r, g, b := c.RGB()
+	fg := tcell.ColorWhite
+	if 299*r+587*g+114*b > 128000 {
+		fg = tcell.ColorBlack
+	}
+	return tcell.StyleDefault.Foreground(fg).Background(c)
+}
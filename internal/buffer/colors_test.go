@@ -0,0 +1,41 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/tcell/v2"
+)
+
+func TestFindColors(t *testing.T) {
+	colors := FindColors([]byte("é: #f80; b: rgb(255, 50%, 0) hsl(120deg 100% 25%) #a0b0c0ff"))
+	assert.Equal(t, []ColorLiteral{
+		{3, 7, tcell.NewHexColor(0xff8800)},
+		{12, 28, tcell.NewRGBColor(255, 128, 0)},
+		{29, 49, tcell.NewRGBColor(0, 128, 0)},
+		{50, 59, tcell.NewHexColor(0xa0b0c0)},
+	}, colors)
+
+	// not colors
+	assert.Empty(t, FindColors([]byte("a#fff &#123; #12 #abcdeg issue#1234 rgb(1, 2)")))
+}
+
+func TestColorLiterals(t *testing.T) {
+	b := NewBufferFromString("color: #000000", "", BTDefault)
+	b.Settings["filetype"] = "css"
+	assert.Len(t, b.ColorLiterals(0), 1)
+	b.Settings["filetype"] = "go"
+	assert.Empty(t, b.ColorLiterals(0))
+	b.Settings["colorswatch"] = "on"
+	assert.Len(t, b.ColorLiterals(0), 1)
+	b.Settings["colorswatch"] = "off"
+	assert.Empty(t, b.ColorLiterals(0))
+}
+
+func TestSwatchStyle(t *testing.T) {
+	fg, bg, _ := SwatchStyle(tcell.NewHexColor(0xffff00)).Decompose()
+	assert.Equal(t, tcell.ColorBlack, fg)
+	assert.Equal(t, tcell.NewHexColor(0xffff00), bg)
+	fg, _, _ = SwatchStyle(tcell.NewHexColor(0x000080)).Decompose()
+	assert.Equal(t, tcell.ColorWhite, fg)
+}
@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// CommentMarkers returns the markers of the comments of the buffer: those
+// given by the commenttype option if it is set, such as "// %s" for line
+// comments or "/* %s */" for block comments, or those of the syntax file of
+// its filetype. Without either, lines are commented with #.
+func (b *Buffer) CommentMarkers() highlight.CommentMarkers {
+	if ct, ok := b.Settings["commenttype"].(string); ok {
+		if i := strings.Index(ct, "%s"); i >= 0 {
+			start, end := strings.TrimSpace(ct[:i]), strings.TrimSpace(ct[i+2:])
+			if end == "" {
+				return highlight.CommentMarkers{Line: start}
+			}
+			return highlight.CommentMarkers{BlockStart: start, BlockEnd: end}
+		}
+	}
+	if b.SyntaxDef != nil {
+		if c := b.SyntaxDef.Comment; c.Line != "" || c.BlockStart != "" {
+			return c
+		}
+	}
+	return highlight.CommentMarkers{Line: "#"}
+}
+
+// ToggleComment comments the lines from start to end, or uncomments them if
+// they are all commented, as a single undo step, and returns whether they
+// were commented. The lines are commented with line comments, whose markers
+// are aligned on the smallest indentation of the lines, or wrapped in a
+// block comment if the language has no line comments. Blank lines are left
+// as they are.
+func (b *Buffer) ToggleComment(start, end int) bool {
+	m := b.CommentMarkers()
+	end = util.Min(end, b.LinesNum()-1)
+	commented := false
+	b.UndoGroup(func() {
+		if m.Line != "" {
+			commented = b.toggleLineComments(start, end, m.Line)
+		} else {
+			commented = b.toggleBlockComment(start, end, m.BlockStart, m.BlockEnd)
+		}
+	})
+	return commented
+}
+
+func (b *Buffer) toggleLineComments(start, end int, marker string) bool {
+	indent := -1
+	all := true
+	for y := start; y <= end; y++ {
+		line := b.LineBytes(y)
+		if util.IsSpacesOrTabs(line) {
+			continue
+		}
+		ws := len(util.GetLeadingWhitespace(line))
+		if indent < 0 || ws < indent {
+			indent = ws
+		}
+		if !bytes.HasPrefix(line[ws:], []byte(marker)) {
+			all = false
+		}
+	}
+	if indent < 0 {
+		return false
+	}
+
+	for y := start; y <= end; y++ {
+		line := b.LineBytes(y)
+		if util.IsSpacesOrTabs(line) {
+			continue
+		}
+		if !all {
+			b.Insert(Loc{X: indent, Y: y}, marker+" ")
+			continue
+		}
+		// the space following the marker goes with it
+		ws := len(util.GetLeadingWhitespace(line))
+		n := util.CharacterCountInString(marker)
+		if bytes.HasPrefix(line[ws+len(marker):], []byte{' '}) {
+			n++
+		}
+		b.Remove(Loc{X: ws, Y: y}, Loc{X: ws + n, Y: y})
+	}
+	return !all
+}
+
+func (b *Buffer) toggleBlockComment(start, end int, open, close string) bool {
+	for start <= end && util.IsSpacesOrTabs(b.LineBytes(start)) {
+		start++
+	}
+	for end >= start && util.IsSpacesOrTabs(b.LineBytes(end)) {
+		end--
+	}
+	if start > end {
+		return false
+	}
+
+	first := b.LineBytes(start)
+	ws := len(util.GetLeadingWhitespace(first))
+	last := bytes.TrimRight(b.LineBytes(end), " \t")
+	text := first[ws:]
+	if start == end {
+		text = last[ws:]
+	}
+	if len(text) >= len(open)+len(close) && bytes.HasPrefix(text, []byte(open)) && bytes.HasSuffix(last, []byte(close)) {
+		// the end marker is removed first, so that the start marker stays
+		// in place on a single line
+		i := len(last) - len(close)
+		if i > 0 && last[i-1] == ' ' && (start != end || i-1 >= ws+len(open)) {
+			i--
+		}
+		b.Remove(Loc{X: util.CharacterCount(last[:i]), Y: end}, Loc{X: util.CharacterCount(last), Y: end})
+
+		n := util.CharacterCountInString(open)
+		if bytes.HasPrefix(b.LineBytes(start)[ws+len(open):], []byte{' '}) {
+			n++
+		}
+		b.Remove(Loc{X: ws, Y: start}, Loc{X: ws + n, Y: start})
+		return false
+	}
+
+	b.Insert(Loc{X: util.CharacterCount(last), Y: end}, " "+close)
+	b.Insert(Loc{X: ws, Y: start}, open+" ")
+	return true
+}
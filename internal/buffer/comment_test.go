@@ -0,0 +1,67 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+func TestCommentMarkers(t *testing.T) {
+	b := NewBufferFromString("", "", BTDefault)
+	defer b.Close()
+	assert.Equal(t, highlight.CommentMarkers{Line: "#"}, b.CommentMarkers())
+
+	b.SyntaxDef = &highlight.Def{Comment: highlight.CommentMarkers{Line: "--", BlockStart: "{-", BlockEnd: "-}"}}
+	assert.Equal(t, "--", b.CommentMarkers().Line)
+
+	b.SetOptionNative("commenttype", "/* %s */")
+	assert.Equal(t, highlight.CommentMarkers{BlockStart: "/*", BlockEnd: "*/"}, b.CommentMarkers())
+	b.SetOptionNative("commenttype", "// %s")
+	assert.Equal(t, highlight.CommentMarkers{Line: "//"}, b.CommentMarkers())
+}
+
+func TestToggleLineComments(t *testing.T) {
+	b := NewBufferFromString("func() {\n\tx\n\n\t\ty\n}", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("commenttype", "// %s")
+
+	assert.True(t, b.ToggleComment(1, 3))
+	assert.Equal(t, "func() {\n\t// x\n\n\t// \ty\n}", string(b.Bytes()))
+	assert.False(t, b.ToggleComment(1, 3))
+	assert.Equal(t, "func() {\n\tx\n\n\t\ty\n}", string(b.Bytes()))
+
+	// a mix of commented and uncommented lines is commented
+	b.ToggleComment(1, 1)
+	assert.True(t, b.ToggleComment(0, 1))
+	assert.Equal(t, "// func() {\n// \t// x\n\n\t\ty\n}", string(b.Bytes()))
+
+	// a marker without a space is removed as well
+	b = NewBufferFromString("  //x\n  // y", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("commenttype", "// %s")
+	assert.False(t, b.ToggleComment(0, 1))
+	assert.Equal(t, "  x\n  y", string(b.Bytes()))
+
+	// the toggling is undone in one step
+	b.Undo()
+	assert.Equal(t, "  //x\n  // y", string(b.Bytes()))
+}
+
+func TestToggleBlockComment(t *testing.T) {
+	b := NewBufferFromString("a {\n  color: red;  \n}\n\n", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("commenttype", "/* %s */")
+
+	assert.True(t, b.ToggleComment(0, 3))
+	assert.Equal(t, "/* a {\n  color: red;  \n} */\n\n", string(b.Bytes()))
+	assert.False(t, b.ToggleComment(0, 3))
+	assert.Equal(t, "a {\n  color: red;  \n}\n\n", string(b.Bytes()))
+
+	assert.True(t, b.ToggleComment(1, 1))
+	assert.Equal(t, "a {\n  /* color: red; */  \n}\n\n", string(b.Bytes()))
+	assert.False(t, b.ToggleComment(1, 1))
+	assert.Equal(t, "a {\n  color: red;  \n}\n\n", string(b.Bytes()))
+
+	assert.False(t, b.ToggleComment(3, 4))
+}
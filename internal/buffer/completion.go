@@ -0,0 +1,447 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A Candidate is a completion of the text before the cursor, suggested by a
+// completion source
+type Candidate struct {
+	// Text replaces the text from Start to the cursor when the candidate is
+	// accepted
+	Text string
+	// Label is shown in the completion popup and matched against the text
+	// being completed instead of Text, if set
+	Label string
+	// Source is the name of the source of the candidate
+	Source string
+	// Start is the column of the cursor's line where the completed text
+	// starts
+	Start int
+	// Snippet is whether Text is the body of a snippet, inserted with
+	// InsertSnippet when the candidate is accepted
+	Snippet bool
+
+	score int
+	// bias is added to the score of the candidate's match
+	bias int
+}
+
+// A CompletionRequest describes the text before the cursor to complete
+type CompletionRequest struct {
+	Buf *Buffer
+	Loc Loc
+	// Word is the word before the cursor, which starts at WordStart
+	Word      string
+	WordStart int
+	// Path is the text before the cursor which may be a path, up to the
+	// previous whitespace, quote or bracket, and starts at PathStart
+	Path      string
+	PathStart int
+}
+
+// A CompletionSource returns the candidates completing the text of a
+// request, in any order
+type CompletionSource func(r *CompletionRequest) []Candidate
+
+type completionSource struct {
+	name string
+	fn   CompletionSource
+}
+
+// completionSources are the sources of the completion popup, in the order
+// they were registered
+var completionSources []completionSource
+
+// A CompletionTrigger returns whether the rune just typed before the cursor
+// opens the completion popup for the request
+type CompletionTrigger func(r *CompletionRequest, typed rune) bool
+
+type completionTrigger struct {
+	name string
+	fn   CompletionTrigger
+}
+
+// pathBias is the score added to the files completing a path which looks
+// like one, to rank them above the words
+const pathBias = 1000
+
+// completionTriggers are the triggers opening the completion popup as the
+// text is typed, besides the autocomplete option
+var completionTriggers []completionTrigger
+
+// maxCandidates is the number of candidates returned by Complete at most
+const maxCandidates = 100
+
+// completionUses maps the text of the candidates accepted so far to the
+// value of completionTick when they were last accepted
+var completionUses = make(map[string]int)
+var completionTick int
+
+func init() {
+	RegisterCompletionSource("words", wordCandidates)
+	RegisterCompletionSource("files", fileCandidates)
+	RegisterCompletionSource("snippets", snippetCandidates)
+	RegisterCompletionTrigger("paths", pathTrigger)
+}
+
+// RegisterCompletionSource adds a source of candidates to the completion
+// popup, or replaces the source with the same name
+func RegisterCompletionSource(name string, fn CompletionSource) {
+	for i, s := range completionSources {
+		if s.name == name {
+			completionSources[i].fn = fn
+			return
+		}
+	}
+	completionSources = append(completionSources, completionSource{name, fn})
+}
+
+// AddCompletionSource adds a source of candidates completing the word
+// before the cursor to the completion popup, for plugins: fn returns the
+// words which may replace the given word
+func AddCompletionSource(name string, fn func(b *Buffer, word string) []string) {
+	RegisterCompletionSource(name, func(r *CompletionRequest) (cands []Candidate) {
+		defer func() {
+			if err := recover(); err != nil {
+				util.Log(util.LogError, "buffer", "Error in completion source "+name+":", err)
+				cands = nil
+			}
+		}()
+		for _, w := range fn(r.Buf, r.Word) {
+			cands = append(cands, Candidate{Text: w, Start: r.WordStart})
+		}
+		return cands
+	})
+}
+
+// RegisterCompletionTrigger adds a trigger opening the completion popup,
+// or replaces the trigger with the same name
+func RegisterCompletionTrigger(name string, fn CompletionTrigger) {
+	for i, t := range completionTriggers {
+		if t.name == name {
+			completionTriggers[i].fn = fn
+			return
+		}
+	}
+	completionTriggers = append(completionTriggers, completionTrigger{name, fn})
+}
+
+// AddCompletionTrigger adds a trigger opening the completion popup, for
+// plugins: fn is called with the buffer and the text of the line before
+// the cursor after each character typed, and returns whether to open it
+func AddCompletionTrigger(name string, fn func(b *Buffer, before string) bool) {
+	RegisterCompletionTrigger(name, func(r *CompletionRequest, typed rune) (open bool) {
+		defer func() {
+			if err := recover(); err != nil {
+				util.Log(util.LogError, "buffer", "Error in completion trigger "+name+":", err)
+				open = false
+			}
+		}()
+		line := []rune(string(r.Buf.LineBytes(r.Loc.Y)))
+		return fn(r.Buf, string(line[:r.Loc.X]))
+	})
+}
+
+// Triggered returns whether one of the completion triggers opens the
+// completion popup after the rune typed before the cursor
+func (r *CompletionRequest) Triggered(typed rune) bool {
+	for _, t := range completionTriggers {
+		if t.fn(r, typed) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathTrigger opens the completion popup when a path separator is typed in
+// a path which looks like one, with the pathcomplete option
+func pathTrigger(r *CompletionRequest, typed rune) bool {
+	if typed != '/' && typed != os.PathSeparator || !r.Buf.Settings["pathcomplete"].(bool) {
+		return false
+	}
+	return r.looksLikePath()
+}
+
+// looksLikePath returns whether the path before the cursor is most likely a
+// path: a path starting with ./, ../ or ~/, a path starting with / after a
+// quote, or an absolute path with a directory such as /etc/
+func (r *CompletionRequest) looksLikePath() bool {
+	p := filepath.ToSlash(r.Path)
+	for _, prefix := range []string{"./", "../", "~/"} {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	if !strings.HasPrefix(p, "/") {
+		return false
+	}
+	if r.PathStart > 0 {
+		line := []rune(string(r.Buf.LineBytes(r.Loc.Y)))
+		if strings.ContainsRune("\"'`", line[r.PathStart-1]) {
+			return true
+		}
+	}
+	return len(p) > 2 && strings.Index(p[1:], "/") > 0
+}
+
+// RecordCompletion records that a candidate with the given text was
+// accepted, so that it ranks higher the next times it is suggested
+func RecordCompletion(text string) {
+	completionTick++
+	completionUses[text] = completionTick
+}
+
+// recencyBonus returns the score added to a candidate with the given text,
+// the highest for the one accepted last, weighted by the fuzzyrecency option
+func recencyBonus(text string) int {
+	t, ok := completionUses[text]
+	if !ok {
+		return 0
+	}
+	return int(config.GetGlobalOption("fuzzyrecency").(float64) * 128 / float64(completionTick-t+1))
+}
+
+// isPathBoundary returns whether r ends a path searching backwards from
+// the cursor
+func isPathBoundary(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("\"'`()[]{}<>,;=", r)
+}
+
+// NewCompletionRequest returns the request completing the text before the
+// active cursor of the buffer
+func (b *Buffer) NewCompletionRequest() *CompletionRequest {
+	c := b.GetActiveCursor()
+	line := []rune(string(b.LineBytes(c.Y)))
+	x := util.Clamp(c.X, 0, len(line))
+
+	r := &CompletionRequest{Buf: b, Loc: Loc{X: x, Y: c.Y}}
+	r.WordStart = x
+	for r.WordStart > 0 && util.IsWordChar(line[r.WordStart-1]) {
+		r.WordStart--
+	}
+	r.Word = string(line[r.WordStart:x])
+	r.PathStart = x
+	for r.PathStart > 0 && !isPathBoundary(line[r.PathStart-1]) {
+		r.PathStart--
+	}
+	r.Path = string(line[r.PathStart:x])
+	return r
+}
+
+// Complete returns the candidates of all the completion sources for the
+// text before the active cursor, the best first. The candidates which don't
+// fuzzy match the text they complete are left out, and the others are
+// ranked by how well they match it and how recently they were accepted.
+func (b *Buffer) Complete() []Candidate {
+	return b.NewCompletionRequest().Complete()
+}
+
+// Complete returns the ranked candidates of all the completion sources for
+// the request
+func (r *CompletionRequest) Complete() []Candidate {
+	line := []rune(string(r.Buf.LineBytes(r.Loc.Y)))
+
+	type key struct {
+		text  string
+		start int
+	}
+	seen := make(map[key]bool)
+	var cands []Candidate
+	for _, s := range completionSources {
+		for _, c := range s.fn(r) {
+			if c.Start < 0 || c.Start > r.Loc.X || seen[key{c.Text, c.Start}] {
+				continue
+			}
+			seen[key{c.Text, c.Start}] = true
+
+			prefix := string(line[c.Start:r.Loc.X])
+			match := c.Text
+			if c.Label != "" {
+				match = c.Label
+			}
+			if !c.Snippet && c.Text == prefix {
+				continue
+			}
+			score, ok := util.FuzzyMatch(prefix, match)
+			if !ok {
+				continue
+			}
+			c.score = score + recencyBonus(c.Text) + c.bias
+			if c.Source == "" {
+				c.Source = s.name
+			}
+			cands = append(cands, c)
+		}
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].score > cands[j].score
+	})
+	if len(cands) > maxCandidates {
+		cands = cands[:maxCandidates]
+	}
+	return cands
+}
+
+// wordCandidates suggests the words of the open buffers, those of the
+// buffer being completed first, nearest to the cursor first, and then the
+// words of its word lists starting with the same letter as the word
+func wordCandidates(r *CompletionRequest) []Candidate {
+	if r.Word == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var cands []Candidate
+	addLine := func(l []byte) {
+		for len(l) > 0 {
+			i := 0
+			for i < len(l) {
+				c, size := utf8.DecodeRune(l[i:])
+				if !util.IsWordChar(c) {
+					break
+				}
+				i += size
+			}
+			if i > 0 {
+				w := string(l[:i])
+				if !seen[w] {
+					seen[w] = true
+					cands = append(cands, Candidate{Text: w, Start: r.WordStart})
+				}
+				l = l[i:]
+			} else {
+				_, size := utf8.DecodeRune(l)
+				l = l[size:]
+			}
+		}
+	}
+
+	b := r.Buf
+	for i := r.Loc.Y; i >= 0; i-- {
+		addLine(b.LineBytes(i))
+	}
+	for i := r.Loc.Y + 1; i < b.LinesNum(); i++ {
+		addLine(b.LineBytes(i))
+	}
+	for _, o := range OpenBuffers {
+		if o.SharedBuffer == b.SharedBuffer || o.Type.Kind != BTDefault.Kind && o.Type.Kind != BTScratch.Kind {
+			continue
+		}
+		for i := 0; i < o.LinesNum(); i++ {
+			addLine(o.LineBytes(i))
+		}
+	}
+
+	for rank, words := range b.wordLists() {
+		for _, w := range words {
+			if seen[w] || !sameStart(w, r.Word) {
+				continue
+			}
+			seen[w] = true
+			cands = append(cands, Candidate{Text: w, Start: r.WordStart, bias: -wordListBias * (rank + 1)})
+		}
+	}
+	return cands
+}
+
+// fileCandidates suggests the files of the directory of the path before
+// the cursor, if it has a directory, in the directories given by
+// completionDirs. Hidden files are only suggested when the file name being
+// completed starts with a dot.
+func fileCandidates(r *CompletionRequest) []Candidate {
+	sep := string(os.PathSeparator)
+	i := strings.LastIndex(r.Path, sep)
+	if i < 0 {
+		return nil
+	}
+	dir, name := r.Path[:i+1], r.Path[i+1:]
+
+	start := r.PathStart + util.CharacterCountInString(r.Path[:i+1])
+	bias := 0
+	if r.looksLikePath() {
+		bias = pathBias
+	}
+	var cands []Candidate
+	seen := make(map[string]bool)
+	for _, d := range completionDirs(r.Buf, dir) {
+		files, err := ioutil.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			n := f.Name()
+			if strings.HasPrefix(n, ".") && !strings.HasPrefix(name, ".") {
+				continue
+			}
+			if f.IsDir() {
+				n += sep
+			}
+			if !seen[n] {
+				seen[n] = true
+				cands = append(cands, Candidate{Text: n, Start: start, bias: bias})
+			}
+		}
+	}
+	return cands
+}
+
+// completionDirs returns the directories which the directory of a path
+// being completed in the buffer b may be, the first ones first. An
+// absolute directory is itself, or relative to the root of the project if
+// it doesn't exist. A directory starting with ./ or ../ is relative to the
+// directory of the buffer, and other relative directories are relative to
+// the directory of the buffer, the root of its project or the working
+// directory.
+func completionDirs(b *Buffer, dir string) []string {
+	dir, _ = util.ReplaceHome(dir)
+	wd, _ := os.Getwd()
+	bufDir := wd
+	if b.Path != "" {
+		bufDir = filepath.Dir(b.AbsPath)
+	}
+	root := util.ProjectRoot(bufDir)
+
+	if filepath.IsAbs(dir) {
+		if _, err := os.Stat(dir); err != nil {
+			return []string{filepath.Join(root, dir)}
+		}
+		return []string{dir}
+	}
+	p := filepath.ToSlash(dir)
+	if strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") {
+		return []string{filepath.Join(bufDir, dir)}
+	}
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, d := range []string{bufDir, root, wd} {
+		if d = filepath.Join(d, dir); !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// snippetCandidates suggests the snippets of the filetype of the buffer
+// whose prefix matches the word before the cursor
+func snippetCandidates(r *CompletionRequest) []Candidate {
+	if r.Word == "" {
+		return nil
+	}
+	var cands []Candidate
+	for _, s := range Snippets(r.Buf.FileType()) {
+		cands = append(cands, Candidate{Text: s.Body, Label: s.Prefix, Start: r.WordStart, Snippet: true})
+	}
+	return cands
+}
@@ -0,0 +1,176 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func texts(cands []Candidate) []string {
+	var res []string
+	for _, c := range cands {
+		res = append(res, c.Text)
+	}
+	return res
+}
+
+func fromSource(cands []Candidate, source string) []Candidate {
+	var res []Candidate
+	for _, c := range cands {
+		if c.Source == source {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func TestNewCompletionRequest(t *testing.T) {
+	b := NewBufferFromString("open(\"./dir/fi_le", "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().GotoLoc(Loc{X: 17, Y: 0})
+	r := b.NewCompletionRequest()
+	assert.Equal(t, "fi_le", r.Word)
+	assert.Equal(t, 12, r.WordStart)
+	assert.Equal(t, "./dir/fi_le", r.Path)
+	assert.Equal(t, 6, r.PathStart)
+}
+
+func TestCompleteWords(t *testing.T) {
+	b := NewBufferFromString("foobar fooqux\nfob fbar\nfo", "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().GotoLoc(Loc{X: 2, Y: 2})
+
+	cands := b.Complete()
+	assert.Equal(t, []string{"fob", "foobar", "fooqux"}, texts(cands))
+	assert.Equal(t, "words", cands[0].Source)
+	assert.Equal(t, 0, cands[0].Start)
+
+	RecordCompletion("fooqux")
+	defer delete(completionUses, "fooqux")
+	assert.Equal(t, "fooqux", b.Complete()[0].Text)
+}
+
+func TestCompleteWordLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-wordlists")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, projectWordsFile), []byte("fooproj bar\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "words.txt"), []byte("# fooignored\nfoouser foobar\n"), 0644))
+
+	b := NewBufferFromString("foobar\nfo", filepath.Join(dir, "a.txt"), BTDefault)
+	defer b.Close()
+	b.Settings["wordlists"] = "words.txt"
+	b.GetActiveCursor().GotoLoc(Loc{X: 2, Y: 1})
+	// the words of the buffer come first, then those of the project
+	assert.Equal(t, []string{"foobar", "fooproj", "foouser"}, texts(fromSource(b.Complete(), "words")))
+}
+
+func TestCompleteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-complete")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "alpha.go"), nil, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".alpha"), nil, 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "beta"), 0755))
+
+	path := filepath.Join(dir, "al")
+	b := NewBufferFromString(path, "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().GotoLoc(Loc{X: len(path), Y: 0})
+	cands := fromSource(b.Complete(), "files")
+	assert.Equal(t, []string{"alpha.go"}, texts(cands))
+	assert.Equal(t, len(path)-2, cands[0].Start)
+
+	path = filepath.Join(dir, "") + string(os.PathSeparator)
+	b.Replace(Loc{X: 0, Y: 0}, b.End(), path)
+	b.GetActiveCursor().GotoLoc(Loc{X: len(path), Y: 0})
+	assert.Equal(t, []string{"alpha.go", "beta" + string(os.PathSeparator)}, texts(fromSource(b.Complete(), "files")))
+}
+
+func TestCompleteFilesRelative(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-complete")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "shared"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "src", "local.go"), nil, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "shared", "util.go"), nil, 0644))
+
+	b := NewBufferFromString("", filepath.Join(dir, "src", "main.go"), BTDefault)
+	defer b.Close()
+	complete := func(text string) []string {
+		text = filepath.FromSlash(text)
+		b.Replace(b.Start(), b.End(), text)
+		b.GetActiveCursor().GotoLoc(b.End())
+		return texts(fromSource(b.Complete(), "files"))
+	}
+	sep := string(os.PathSeparator)
+	// ./ is relative to the directory of the buffer
+	assert.Equal(t, []string{"local.go", "pkg" + sep}, complete(`"./`))
+	assert.Equal(t, []string{"shared" + sep, "src" + sep}, complete(`"../`))
+	// other relative paths are also relative to the root of the project
+	assert.Equal(t, []string{"util.go"}, complete("shared/"))
+	if _, err := os.Stat(filepath.FromSlash("/shared")); os.IsNotExist(err) {
+		assert.Equal(t, []string{"util.go"}, complete(`"/shared/`))
+	}
+}
+
+func TestPathTrigger(t *testing.T) {
+	b := NewBufferFromString("", "", BTDefault)
+	defer b.Close()
+	triggered := func(text string, typed rune) bool {
+		b.Replace(b.Start(), b.End(), text)
+		b.GetActiveCursor().GotoLoc(b.End())
+		return b.NewCompletionRequest().Triggered(typed)
+	}
+	assert.True(t, triggered("import './", '/'))
+	assert.True(t, triggered("cd ../", '/'))
+	assert.True(t, triggered("~/", '/'))
+	assert.True(t, triggered(`#include "/`, '/'))
+	assert.True(t, triggered("cat /etc/", '/'))
+	assert.False(t, triggered("x = a /", '/'))
+	assert.False(t, triggered("// ", ' '))
+	assert.False(t, triggered("//", '/'))
+	assert.False(t, triggered("./a", 'a'))
+
+	b.Settings["pathcomplete"] = false
+	assert.False(t, triggered("./", '/'))
+}
+
+func TestAddCompletionTrigger(t *testing.T) {
+	AddCompletionTrigger("test", func(b *Buffer, before string) bool {
+		return strings.HasSuffix(before, "->")
+	})
+	defer func() {
+		completionTriggers = completionTriggers[:len(completionTriggers)-1]
+	}()
+	b := NewBufferFromString("p->x", "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().GotoLoc(Loc{X: 3, Y: 0})
+	assert.True(t, b.NewCompletionRequest().Triggered('>'))
+	b.GetActiveCursor().GotoLoc(Loc{X: 4, Y: 0})
+	assert.False(t, b.NewCompletionRequest().Triggered('x'))
+}
+
+func TestAddCompletionSource(t *testing.T) {
+	AddCompletionSource("test", func(b *Buffer, word string) []string {
+		return []string{word + "zzle", "other"}
+	})
+	defer func() {
+		completionSources = completionSources[:len(completionSources)-1]
+	}()
+
+	b := NewBufferFromString("x fi", "", BTDefault)
+	defer b.Close()
+	b.GetActiveCursor().GotoLoc(Loc{X: 4, Y: 0})
+	cands := b.Complete()
+	assert.Equal(t, []string{"fizzle"}, texts(cands))
+	assert.Equal(t, "test", cands[0].Source)
+	assert.Equal(t, 2, cands[0].Start)
+}
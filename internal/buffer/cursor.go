@@ -1,6 +1,8 @@
 package buffer
 
 import (
+	"strings"
+
 	"github.com/zyedidia/micro/v2/internal/clipboard"
 	"github.com/zyedidia/micro/v2/internal/util"
 )
@@ -22,6 +24,9 @@ type Cursor struct {
 
 	// Last cursor x position
 	LastVisualX int
+	// Virtual is the number of cells past the end of its line the cursor
+	// is moved to with the virtualedit option
+	Virtual int
 
 	// The current selection as a range of character numbers (inclusive)
 	CurSelection [2]Loc
@@ -54,7 +59,7 @@ func (c *Cursor) Buf() *Buffer {
 // Goto puts the cursor at the given cursor's location and gives
 // the current cursor its selection too
 func (c *Cursor) Goto(b Cursor) {
-	c.X, c.Y, c.LastVisualX = b.X, b.Y, b.LastVisualX
+	c.X, c.Y, c.LastVisualX, c.Virtual = b.X, b.Y, b.LastVisualX, b.Virtual
 	c.OrigSelection, c.CurSelection = b.OrigSelection, b.CurSelection
 }
 
@@ -93,6 +98,7 @@ func (c *Cursor) GetCharPosInLine(b []byte, visualPos int) int {
 // Start moves the cursor to the start of the line it is on
 func (c *Cursor) Start() {
 	c.X = 0
+	c.Virtual = 0
 	c.LastVisualX = c.GetVisualX()
 }
 
@@ -124,17 +130,70 @@ func (c *Cursor) IsStartOfText() bool {
 // End moves the cursor to the end of the line it is on
 func (c *Cursor) End() {
 	c.X = util.CharacterCount(c.buf.LineBytes(c.Y))
+	c.Virtual = 0
 	c.LastVisualX = c.GetVisualX()
 }
 
+// EndOfText moves the cursor after the last non-whitespace rune of the
+// line it is on
+func (c *Cursor) EndOfText() {
+	c.End()
+	for c.X > 0 && util.IsWhitespace(c.RuneUnder(c.X-1)) {
+		c.X--
+	}
+	c.StoreVisualX()
+}
+
+// IsEndOfText returns whether the cursor is after the last non-whitespace
+// rune of the line it is on
+func (c *Cursor) IsEndOfText() bool {
+	x := util.CharacterCount(c.buf.LineBytes(c.Y))
+	for x > 0 && util.IsWhitespace(c.RuneUnder(x-1)) {
+		x--
+	}
+	return c.X == x && c.Virtual == 0
+}
+
+// virtualEdit returns whether the cursor may move past the end of the
+// lines, which it does not with softwrap
+func (c *Cursor) virtualEdit() bool {
+	v, _ := c.buf.Settings["virtualedit"].(bool)
+	wrap, _ := c.buf.Settings["softwrap"].(bool)
+	return v && !wrap
+}
+
+// FillVirtual inserts the spaces up to the cursor when it is past the end
+// of its line, so that the text inserted next goes where it is shown
+func (c *Cursor) FillVirtual() {
+	if c.Virtual > 0 {
+		n := c.Virtual
+		c.Virtual = 0
+		c.buf.Insert(c.Loc, strings.Repeat(" ", n))
+	}
+}
+
 // CopySelection copies the user's selection to either "primary"
 // or "clipboard"
-func (c *Cursor) CopySelection(target clipboard.Register) {
+func (c *Cursor) CopySelection(target clipboard.Register) error {
 	if c.HasSelection() {
 		if target != clipboard.PrimaryReg || c.buf.Settings["useprimary"].(bool) {
-			clipboard.WriteMulti(string(c.GetSelection()), target, c.Num, c.buf.NumCursors())
+			return clipboard.WriteMulti(string(c.GetSelection()), target, c.Order(), c.buf.NumCursors())
 		}
 	}
+	return nil
+}
+
+// Order returns the rank of the cursor among the cursors of the buffer
+// sorted by location, which matches the texts copied by multiple cursors
+// with the cursors pasting them, whatever the order they were added in
+func (c *Cursor) Order() int {
+	n := 0
+	for _, o := range c.buf.cursors {
+		if o != c && o.Loc.LessThan(c.Loc) {
+			n++
+		}
+	}
+	return n
 }
 
 // ResetSelection resets the user's selection
@@ -232,6 +291,9 @@ func (c *Cursor) AddLineToSelection() {
 // UpN moves the cursor up N lines (if possible)
 func (c *Cursor) UpN(amount int) {
 	proposedY := c.Y - amount
+	if c.buf.HasFolds() {
+		proposedY = c.buf.MoveVisibleLines(c.Y, -amount)
+	}
 	if proposedY < 0 {
 		proposedY = 0
 	} else if proposedY >= len(c.buf.lines) {
@@ -252,6 +314,12 @@ func (c *Cursor) UpN(amount int) {
 	}
 
 	c.Y = proposedY
+	// with virtualedit, the cursor keeps its column past the end of the
+	// shorter lines
+	c.Virtual = 0
+	if c.virtualEdit() && c.X == util.CharacterCount(bytes) {
+		c.Virtual = util.Max(c.LastVisualX-c.GetVisualX(), 0)
+	}
 }
 
 // DownN moves the cursor down N lines (if possible)
@@ -272,6 +340,11 @@ func (c *Cursor) Down() {
 // Left moves the cursor left one cell (if possible) or to
 // the previous line if it is at the beginning
 func (c *Cursor) Left() {
+	if c.Virtual > 0 {
+		c.Virtual--
+		c.LastVisualX = c.GetVisualX() + c.Virtual
+		return
+	}
 	if c.Loc == c.buf.Start() {
 		return
 	}
@@ -287,6 +360,12 @@ func (c *Cursor) Left() {
 // Right moves the cursor right one cell (if possible) or
 // to the next line if it is at the end
 func (c *Cursor) Right() {
+	// with virtualedit, the cursor goes on past the end of the line
+	if c.virtualEdit() && c.X >= util.CharacterCount(c.buf.LineBytes(c.Y)) {
+		c.Virtual++
+		c.LastVisualX = c.GetVisualX() + c.Virtual
+		return
+	}
 	if c.Loc == c.buf.End() {
 		return
 	}
@@ -314,6 +393,9 @@ func (c *Cursor) Relocate() {
 	} else if c.X > util.CharacterCount(c.buf.LineBytes(c.Y)) {
 		c.X = util.CharacterCount(c.buf.LineBytes(c.Y))
 	}
+	if c.X != util.CharacterCount(c.buf.LineBytes(c.Y)) {
+		c.Virtual = 0
+	}
 }
 
 // SelectWord selects the word the cursor is currently on
@@ -454,5 +536,6 @@ func (c *Cursor) RuneUnder(x int) rune {
 }
 
 func (c *Cursor) StoreVisualX() {
+	c.Virtual = 0
 	c.LastVisualX = c.GetVisualX()
 }
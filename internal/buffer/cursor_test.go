@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndOfText(t *testing.T) {
+	b := NewBufferFromString("  foo bar  \t\n", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	c.EndOfText()
+	assert.Equal(t, Loc{9, 0}, c.Loc)
+	assert.True(t, c.IsEndOfText())
+	c.End()
+	assert.Equal(t, Loc{12, 0}, c.Loc)
+	assert.False(t, c.IsEndOfText())
+
+	// a blank line ends at its start
+	c.GotoLoc(Loc{0, 1})
+	assert.True(t, c.IsEndOfText())
+}
+
+func TestVirtualEdit(t *testing.T) {
+	b := NewBufferFromString("long line\nab\nmore", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	// without virtualedit, the cursor stops at the end of the line
+	c.GotoLoc(Loc{2, 1})
+	c.Right()
+	assert.Equal(t, Loc{0, 2}, c.Loc)
+	assert.Equal(t, 0, c.Virtual)
+
+	b.Settings["virtualedit"] = true
+	c.GotoLoc(Loc{2, 1})
+	c.Right()
+	c.Right()
+	assert.Equal(t, Loc{2, 1}, c.Loc)
+	assert.Equal(t, 2, c.Virtual)
+	c.Left()
+	assert.Equal(t, 1, c.Virtual)
+
+	// the cursor keeps its column moving to a shorter line
+	c.GotoLoc(Loc{7, 0})
+	c.Down()
+	assert.Equal(t, Loc{2, 1}, c.Loc)
+	assert.Equal(t, 5, c.Virtual)
+	c.Down()
+	assert.Equal(t, Loc{4, 2}, c.Loc)
+	assert.Equal(t, 3, c.Virtual)
+	c.Up()
+	c.Up()
+	assert.Equal(t, Loc{7, 0}, c.Loc)
+	assert.Equal(t, 0, c.Virtual)
+
+	// the spaces up to the cursor are inserted before the text typed
+	c.GotoLoc(Loc{2, 1})
+	c.Right()
+	c.Right()
+	c.FillVirtual()
+	b.Insert(c.Loc, "x")
+	assert.Equal(t, "long line\nab  x\nmore", string(b.Bytes()))
+	assert.Equal(t, Loc{5, 1}, c.Loc)
+	assert.Equal(t, 0, c.Virtual)
+
+	// virtualedit does nothing with softwrap
+	b.Settings["softwrap"] = true
+	c.End()
+	c.Right()
+	assert.Equal(t, Loc{0, 2}, c.Loc)
+	assert.Equal(t, 0, c.Virtual)
+}
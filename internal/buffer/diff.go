@@ -0,0 +1,92 @@
+package buffer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// A DiffChange is a range of characters of a modified line which differ
+// from the corresponding line of the diff base
+type DiffChange struct {
+	Start, End int
+}
+
+// DiffWith compares the buffer with another one, side by side: each buffer
+// is the diff base of the other, the changes within their modified lines
+// are computed as well (see DiffChanges) and their diff gutter is shown.
+// They are compared until EndDiff is called on either of them.
+func (b *Buffer) DiffWith(peer *Buffer) {
+	b.EndDiff()
+	peer.EndDiff()
+	b.setDiffPeer(peer)
+	peer.setDiffPeer(b)
+}
+
+// EndDiff stops comparing the buffer with the one given to DiffWith,
+// restoring the diff base and the diffgutter option of both
+func (b *Buffer) EndDiff() {
+	peer := b.diffPeer
+	if peer == nil {
+		return
+	}
+	b.setDiffPeer(nil)
+	peer.setDiffPeer(nil)
+}
+
+// DiffPeer returns the buffer this buffer is compared with, or nil
+func (b *Buffer) DiffPeer() *Buffer {
+	return b.diffPeer
+}
+
+func (b *Buffer) setDiffPeer(peer *Buffer) {
+	if peer == nil {
+		if b.diffPeer == nil {
+			return
+		}
+		b.diffPeer = nil
+		b.SetOptionNative("diffgutter", b.savedDiffGutter)
+		b.SetDiffBase(b.savedDiffBase)
+		return
+	}
+
+	if b.diffPeer == nil {
+		b.savedDiffBase = b.diffBase
+		b.savedDiffGutter = b.Settings["diffgutter"].(bool)
+	}
+	b.diffPeer = peer
+	b.SetOptionNative("diffgutter", true)
+	b.SetDiffBase(peer.Bytes())
+}
+
+// DiffChanges returns the ranges of characters of a modified line which
+// differ from the buffer it is compared with
+func (b *Buffer) DiffChanges(lineN int) []DiffChange {
+	b.diffLock.RLock()
+	defer b.diffLock.RUnlock()
+	return b.diffChanges[lineN]
+}
+
+// lineChanges returns the ranges of characters of line which are not in
+// base
+func lineChanges(base, line string) []DiffChange {
+	base = strings.TrimRight(base, "\r\n")
+
+	differ := dmp.New()
+	diffs := differ.DiffCleanupSemantic(differ.DiffMain(base, line, false))
+
+	var changes []DiffChange
+	x := 0
+	for _, d := range diffs {
+		n := utf8.RuneCountInString(d.Text)
+		switch d.Type {
+		case dmp.DiffEqual:
+			x += n
+		case dmp.DiffInsert:
+			changes = append(changes, DiffChange{x, x + n})
+			x += n
+		}
+	}
+	return changes
+}
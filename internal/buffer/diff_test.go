@@ -0,0 +1,69 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineChanges(t *testing.T) {
+	assert.Nil(t, lineChanges("same\n", "same"))
+	assert.Equal(t, []DiffChange{{4, 7}}, lineChanges("foo()\r\n", "foo(bar)"))
+	assert.Equal(t, []DiffChange{{0, 3}}, lineChanges("héllo wörld", "bye wörld"))
+}
+
+func TestDiffWith(t *testing.T) {
+	a := NewBufferFromString("one\ntwo\nthree\nfour\n", "", BTDefault)
+	b := NewBufferFromString("one\ntwo!\nthree\nfive\nsix\n", "", BTDefault)
+	a.SetDiffBase([]byte("one\n"))
+
+	a.DiffWith(b)
+	assert.Equal(t, b, a.DiffPeer())
+	assert.Equal(t, a, b.DiffPeer())
+	assert.Equal(t, true, a.Settings["diffgutter"])
+
+	assert.Equal(t, DiffStatus(DSUnchanged), b.DiffStatus(0))
+	assert.Equal(t, DiffStatus(DSModified), b.DiffStatus(1))
+	assert.Equal(t, []DiffChange{{3, 4}}, b.DiffChanges(1))
+	assert.Equal(t, DiffStatus(DSModified), b.DiffStatus(3))
+	assert.Equal(t, DiffStatus(DSModified), b.DiffStatus(4))
+	assert.Nil(t, b.DiffChanges(4))
+	assert.Nil(t, a.DiffChanges(1))
+
+	b.EndDiff()
+	assert.Nil(t, a.DiffPeer())
+	assert.Nil(t, b.DiffPeer())
+	assert.Equal(t, false, a.Settings["diffgutter"])
+	assert.Equal(t, DiffStatus(DSAdded), a.DiffStatus(1))
+	assert.Nil(t, b.DiffChanges(1))
+}
+
+func TestDiffBaseDisk(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	// hg can't be run here, so the file on disk is used
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".hg"), 0755))
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", "")
+	defer os.Setenv("PATH", oldPath)
+	path := filepath.Join(dir, "notes.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("one\n"), 0644))
+	b, err := NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	defer b.Close()
+
+	assert.NoError(t, b.SetOptionNative("diffgutter", true))
+	assert.Equal(t, "hg", b.diffBaseSource())
+	b.Insert(b.End(), "two\n")
+	b.UpdateDiff(func(bool) {})
+	assert.Equal(t, DiffStatus(DSAdded), b.DiffStatus(1))
+
+	assert.NoError(t, b.SetOptionNative("diffbase", "disk"))
+	assert.NoError(t, b.Save())
+	b.UpdateDiff(func(bool) {})
+	assert.Equal(t, DiffStatus(DSUnchanged), b.DiffStatus(1))
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/internal/vcs"
+)
+
+// diffBaseSource returns the source of the diff base of the buffer set by
+// the diffbase option, auto being resolved to the version control system of
+// the directory of the file or else to disk
+func (b *Buffer) diffBaseSource() string {
+	src := b.Settings["diffbase"].(string)
+	if src == "auto" {
+		src = vcs.Detect(filepath.Dir(b.AbsPath))
+		if src == "" {
+			src = "disk"
+		}
+	}
+	return src
+}
+
+// UpdateDiffBase sets the diff base of the buffer from the source chosen by
+// the diffbase option. The file on disk is used when the version control
+// system doesn't know the file, as the buffer is then all new to it.
+func (b *Buffer) UpdateDiffBase() {
+	if b.Type.Scratch || b.Path == "" || b.diffPeer != nil {
+		return
+	}
+	if _, err := os.Stat(b.AbsPath); err != nil {
+		return
+	}
+
+	if src := b.diffBaseSource(); src != "disk" {
+		base, err := vcs.Base(b.AbsPath, src)
+		if err == nil {
+			b.SetDiffBase(base)
+			return
+		}
+		util.Log(util.LogError, "buffer", "Error reading the diff base from", src+":", err)
+	}
+	b.SetDiffBase(b.Bytes())
+}
@@ -0,0 +1,125 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// A Draft is the text of a buffer without a file, kept in the drafts of
+// the state directory so that it outlives micro (see the drafts option)
+type Draft struct {
+	Name    string
+	ModTime time.Time
+	// Preview is the first line of the draft which isn't blank
+	Preview string
+}
+
+func draftDir() string {
+	return filepath.Join(config.StateDir, "drafts")
+}
+
+// Drafts returns the drafts, the most recently modified first
+func Drafts() ([]Draft, error) {
+	infos, err := ioutil.ReadDir(draftDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var drafts []Draft
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		d := Draft{Name: info.Name(), ModTime: info.ModTime()}
+		if data, err := ioutil.ReadFile(filepath.Join(draftDir(), d.Name)); err == nil {
+			for _, l := range bytes.Split(data, []byte{'\n'}) {
+				if l = bytes.TrimSpace(l); len(l) > 0 {
+					d.Preview = string(l)
+					break
+				}
+			}
+		}
+		drafts = append(drafts, d)
+	}
+	sort.SliceStable(drafts, func(i, j int) bool {
+		return drafts[i].ModTime.After(drafts[j].ModTime)
+	})
+	return drafts, nil
+}
+
+// OpenDraft returns a new buffer with the text of the draft, which is kept
+// up to date with the buffer
+func OpenDraft(name string) (*Buffer, error) {
+	data, err := ioutil.ReadFile(filepath.Join(draftDir(), filepath.Base(name)))
+	if err != nil {
+		return nil, errors.New("No draft named " + name)
+	}
+	b := NewBufferFromString(string(data), "", BTDefault)
+	b.draft = filepath.Base(name)
+	return b, nil
+}
+
+// KeepsDraft returns whether the text of the buffer is kept as a draft,
+// which is the case of the edited buffers without a file and scratch
+// buffers when the drafts option is on
+func (b *Buffer) KeepsDraft() bool {
+	return b.Settings["drafts"].(bool) && config.StateDir != "" && b.Path == "" &&
+		(b.Type == BTDefault || b.Type == BTScratch)
+}
+
+// Unsaved returns whether closing the buffer loses changes, which are kept
+// in its draft if it keeps one
+func (b *Buffer) Unsaved() bool {
+	return b.Modified() && !b.KeepsDraft()
+}
+
+// Draft returns the name of the draft of the buffer, or an empty string if
+// it has none
+func (b *Buffer) Draft() string {
+	return b.draft
+}
+
+// SaveDraft writes the text of the buffer to its draft, which is named
+// after the current time the first time. The draft of an empty buffer is
+// removed instead.
+func (b *Buffer) SaveDraft() error {
+	if !b.KeepsDraft() {
+		return nil
+	}
+	data := b.Bytes()
+	if len(data) == 0 {
+		b.RemoveDraft()
+		return nil
+	}
+	if err := os.MkdirAll(draftDir(), os.ModePerm); err != nil {
+		return err
+	}
+	if b.draft == "" {
+		name := time.Now().Format("2006-01-02-150405")
+		b.draft = name
+		for i := 2; ; i++ {
+			if _, err := os.Stat(filepath.Join(draftDir(), b.draft)); os.IsNotExist(err) {
+				break
+			}
+			b.draft = name + "-" + strconv.Itoa(i)
+		}
+	}
+	return ioutil.WriteFile(filepath.Join(draftDir(), b.draft), data, 0644)
+}
+
+// RemoveDraft removes the draft of the buffer, if it has one
+func (b *Buffer) RemoveDraft() {
+	if b.draft != "" {
+		os.Remove(filepath.Join(draftDir(), b.draft))
+		b.draft = ""
+	}
+}
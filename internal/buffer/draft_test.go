@@ -0,0 +1,56 @@
+package buffer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrafts(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	b := NewBufferFromString("", "", BTDefault)
+	assert.False(t, b.KeepsDraft())
+	b.Settings["drafts"] = true
+	b.Insert(b.Start(), "\n  notes\nmore\n")
+	assert.True(t, b.Modified())
+	assert.False(t, b.Unsaved())
+	b.Close()
+
+	drafts, err := Drafts()
+	assert.NoError(t, err)
+	assert.Len(t, drafts, 1)
+	assert.Equal(t, "notes", drafts[0].Preview)
+	name := drafts[0].Name
+
+	d, err := OpenDraft(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "\n  notes\nmore\n", string(d.Bytes()))
+	assert.Equal(t, name, d.Draft())
+	assert.Equal(t, "Draft "+name, d.GetName())
+
+	// a second draft gets another name
+	s := NewBufferFromString("output", "", BTScratch)
+	s.Settings["drafts"] = true
+	s.Insert(s.End(), " annotated")
+	assert.NoError(t, s.SaveDraft())
+	assert.NotEqual(t, name, s.Draft())
+
+	// a scratch buffer kept as a draft can be saved as a file
+	path := filepath.Join(dir, "out.txt")
+	assert.NoError(t, s.SaveAs(path))
+	assert.Equal(t, "", s.Draft())
+	assert.Equal(t, BTDefault, s.Type)
+	drafts, _ = Drafts()
+	assert.Len(t, drafts, 1)
+
+	// emptying a draft removes it
+	d.Settings["drafts"] = true
+	d.Remove(d.Start(), d.End())
+	d.Close()
+	drafts, _ = Drafts()
+	assert.Len(t, drafts, 0)
+	s.Close()
+}
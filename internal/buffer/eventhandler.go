@@ -3,6 +3,7 @@ package buffer
 import (
 	"bytes"
 	"time"
+	"unicode/utf8"
 
 	dmp "github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/zyedidia/micro/v2/internal/config"
@@ -21,8 +22,6 @@ const (
 	TextEventRemove = -1
 	// TextEventReplace represents a replace event
 	TextEventReplace = 0
-
-	undoThreshold = 1000 // If two events are less than n milliseconds apart, undo both of them
 )
 
 // TextEvent holds data for a manipulation on some text that can be undone
@@ -32,6 +31,10 @@ type TextEvent struct {
 	EventType int
 	Deltas    []Delta
 	Time      time.Time
+
+	// group is the undo step of the event, whose events are undone and
+	// redone together
+	group int
 }
 
 // A Delta is a change to the buffer
@@ -52,6 +55,7 @@ func (eh *EventHandler) DoTextEvent(t *TextEvent, useUndo bool) {
 	}
 
 	if len(t.Deltas) != 1 {
+		eh.buf.snippet = nil
 		return
 	}
 
@@ -75,37 +79,51 @@ func (eh *EventHandler) DoTextEvent(t *TextEvent, useUndo bool) {
 	}
 	end := t.Deltas[0].End
 
+	move := func(loc Loc) Loc {
+		return moveLoc(loc, t.EventType, start, end, lastnl, textX, eh.buf.LineArray)
+	}
+	eh.buf.moveSnippet(t.EventType, start, end, move)
+	eh.buf.moveBookmarks(t.EventType, start, end)
+	eh.buf.moveFolds(t.EventType, start, end)
+	eh.buf.moveBreakpoints(t.EventType, start, end)
 	for _, c := range eh.cursors {
-		move := func(loc Loc) Loc {
-			if t.EventType == TextEventInsert {
-				if start.Y != loc.Y && loc.GreaterThan(start) {
-					loc.Y += end.Y - start.Y
-				} else if loc.Y == start.Y && loc.GreaterEqual(start) {
-					loc.Y += end.Y - start.Y
-					if lastnl >= 0 {
-						loc.X += textX - start.X
-					} else {
-						loc.X += textX
-					}
-				}
-				return loc
-			} else {
-				if loc.Y != end.Y && loc.GreaterThan(end) {
-					loc.Y -= end.Y - start.Y
-				} else if loc.Y == end.Y && loc.GreaterEqual(end) {
-					loc = loc.MoveLA(-DiffLA(start, end, eh.buf.LineArray), eh.buf.LineArray)
-				}
-				return loc
-			}
-		}
 		c.Loc = move(c.Loc)
 		c.CurSelection[0] = move(c.CurSelection[0])
 		c.CurSelection[1] = move(c.CurSelection[1])
 		c.OrigSelection[0] = move(c.OrigSelection[0])
 		c.OrigSelection[1] = move(c.OrigSelection[1])
-		c.Relocate()
-		c.LastVisualX = c.GetVisualX()
+		// a transaction relocates the cursors when it ends
+		if eh.buf.txn == nil {
+			c.Relocate()
+			c.LastVisualX = c.GetVisualX()
+		}
+	}
+}
+
+// moveLoc returns the location loc after a text event of the given type
+// changed the text between start and end. lastnl is the index of the last
+// line break of an inserted text, and textX the number of characters after
+// it, or in the whole text if it has none.
+func moveLoc(loc Loc, eventType int, start, end Loc, lastnl, textX int, la *LineArray) Loc {
+	if eventType == TextEventInsert {
+		if start.Y != loc.Y && loc.GreaterThan(start) {
+			loc.Y += end.Y - start.Y
+		} else if loc.Y == start.Y && loc.GreaterEqual(start) {
+			loc.Y += end.Y - start.Y
+			if lastnl >= 0 {
+				loc.X += textX - start.X
+			} else {
+				loc.X += textX
+			}
+		}
+		return loc
+	}
+	if loc.Y != end.Y && loc.GreaterThan(end) {
+		loc.Y -= end.Y - start.Y
+	} else if loc.Y == end.Y && loc.GreaterEqual(end) {
+		loc = loc.MoveLA(-DiffLA(start, end, la), la)
 	}
+	return loc
 }
 
 // ExecuteTextEvent runs a text event
@@ -144,6 +162,18 @@ type EventHandler struct {
 	active    int
 	UndoStack *TEStack
 	RedoStack *TEStack
+
+	// group is the undo group of the events being executed, or 0, and
+	// groups the number of groups and steps started so far
+	group, groups int
+	// step is the undo step of the last event executed outside of a group,
+	// and broken is set when the next event starts a new step
+	step   int
+	broken bool
+	// open is set when group was started by StartUndoGroup
+	open bool
+	// edits is the number of events executed, not counting undo and redo
+	edits int
 }
 
 // NewEventHandler returns a new EventHandler
@@ -230,11 +260,103 @@ func (eh *EventHandler) Replace(start, end Loc, replace string) {
 	eh.Insert(start, replace)
 }
 
+// UndoGroup runs fn and undoes and redoes the text events it executes as a
+// single step, apart from the events executed before and after it
+func (eh *EventHandler) UndoGroup(fn func()) {
+	if eh.group != 0 {
+		fn()
+		return
+	}
+	eh.groups++
+	eh.group = eh.groups
+	defer func() {
+		eh.group = 0
+		eh.broken = true
+	}()
+	fn()
+}
+
+// StartUndoGroup starts an undo group lasting until EndUndoGroup, Undo or
+// Redo, whose events are undone as a single step. It returns false if a
+// group is started already.
+func (eh *EventHandler) StartUndoGroup() bool {
+	if eh.group != 0 {
+		return false
+	}
+	eh.groups++
+	eh.group = eh.groups
+	eh.open = true
+	return true
+}
+
+// EndUndoGroup ends the group started by StartUndoGroup, and returns false
+// if there is none
+func (eh *EventHandler) EndUndoGroup() bool {
+	if !eh.open {
+		return false
+	}
+	eh.group = 0
+	eh.open = false
+	eh.broken = true
+	return true
+}
+
+// UndoBreak ends the undo step of the last events, so that the next ones
+// are undone apart from them
+func (eh *EventHandler) UndoBreak() {
+	eh.broken = true
+}
+
+// continuesStep returns whether the event t, executed outside of a group,
+// is part of the undo step of the last event according to the undogroup
+// option: events less than undotimeout milliseconds apart for time, a word
+// typed with the characters following it or the characters deleted in a
+// row for word, and anything until the next action for action.
+func (eh *EventHandler) continuesStep(t *TextEvent) bool {
+	last := eh.UndoStack.Peek()
+	if eh.broken || last == nil || last.group != eh.step {
+		return false
+	}
+	switch eh.buf.Settings["undogroup"] {
+	case "action":
+		return true
+	case "word":
+		if t.EventType != last.EventType || t.EventType == TextEventReplace {
+			return false
+		}
+		if t.EventType == TextEventRemove {
+			return true
+		}
+		r, _ := utf8.DecodeRune(t.Deltas[0].Text)
+		lr, _ := utf8.DecodeLastRune(last.Deltas[len(last.Deltas)-1].Text)
+		return !util.IsWordChar(r) || util.IsWordChar(lr)
+	}
+	timeout := time.Duration(util.IntOpt(eh.buf.Settings["undotimeout"])) * time.Millisecond
+	return t.Time.Sub(last.Time) < timeout
+}
+
+// Edits returns the number of text events executed so far, not counting
+// undo and redo, which tells whether an action edited the buffer
+func (eh *EventHandler) Edits() int {
+	return eh.edits
+}
+
 // Execute a textevent and add it to the undo stack
 func (eh *EventHandler) Execute(t *TextEvent) {
 	if eh.RedoStack.Len() > 0 {
 		eh.RedoStack = new(TEStack)
 	}
+	if eh.group != 0 {
+		t.group = eh.group
+	} else {
+		if !eh.continuesStep(t) {
+			eh.groups++
+			eh.step = eh.groups
+		}
+		t.group = eh.step
+		eh.broken = false
+	}
+	eh.edits++
 	eh.UndoStack.Push(t)
 
 	b, err := config.RunPluginFnBool("onBeforeTextEvent", luar.New(ulua.L, eh.buf), luar.New(ulua.L, t))
@@ -251,25 +373,19 @@ func (eh *EventHandler) Execute(t *TextEvent) {
 
 // Undo the first event in the undo stack
 func (eh *EventHandler) Undo() {
+	// undoing leaves the snippet being visited, whose mirrors would change
+	// the text back
+	eh.buf.snippet = nil
+	eh.EndUndoGroup()
+	eh.broken = true
 	t := eh.UndoStack.Peek()
 	if t == nil {
 		return
 	}
-
-	startTime := t.Time.UnixNano() / int64(time.Millisecond)
-	endTime := startTime - (startTime % undoThreshold)
-
-	for {
-		t = eh.UndoStack.Peek()
-		if t == nil {
-			return
-		}
-
-		if t.Time.UnixNano()/int64(time.Millisecond) < endTime {
-			return
-		}
-
+	group := t.group
+	for t != nil && t.group == group {
 		eh.UndoOneEvent()
+		t = eh.UndoStack.Peek()
 	}
 }
 
@@ -300,25 +416,17 @@ func (eh *EventHandler) UndoOneEvent() {
 
 // Redo the first event in the redo stack
 func (eh *EventHandler) Redo() {
+	eh.buf.snippet = nil
+	eh.EndUndoGroup()
+	eh.broken = true
 	t := eh.RedoStack.Peek()
 	if t == nil {
 		return
 	}
-
-	startTime := t.Time.UnixNano() / int64(time.Millisecond)
-	endTime := startTime - (startTime % undoThreshold) + undoThreshold
-
-	for {
-		t = eh.RedoStack.Peek()
-		if t == nil {
-			return
-		}
-
-		if t.Time.UnixNano()/int64(time.Millisecond) > endTime {
-			return
-		}
-
+	group := t.group
+	for t != nil && t.group == group {
 		eh.RedoOneEvent()
+		t = eh.RedoStack.Peek()
 	}
 }
 
@@ -0,0 +1,79 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdits(t *testing.T) {
+	b := NewBufferFromString("abc", "", BTDefault)
+	defer b.Close()
+
+	n := b.Edits()
+	b.Insert(Loc{X: 3, Y: 0}, "d")
+	b.Remove(Loc{X: 0, Y: 0}, Loc{X: 1, Y: 0})
+	assert.Equal(t, n+2, b.Edits())
+
+	// undo and redo don't make new edits
+	b.Undo()
+	b.Redo()
+	assert.Equal(t, n+2, b.Edits())
+	assert.Equal(t, "bcd", string(b.Bytes()))
+}
+
+func TestUndoGroupWord(t *testing.T) {
+	b := NewBufferFromString("", "", BTDefault)
+	defer b.Close()
+	b.Settings["undogroup"] = "word"
+
+	for i, c := range "ab cd" {
+		b.Insert(Loc{X: i, Y: 0}, string(c))
+	}
+	b.Undo()
+	assert.Equal(t, "ab ", string(b.Bytes()))
+	b.Undo()
+	assert.Equal(t, "", string(b.Bytes()))
+}
+
+func TestUndoGroupTime(t *testing.T) {
+	b := NewBufferFromString("", "", BTDefault)
+	defer b.Close()
+
+	b.Insert(Loc{X: 0, Y: 0}, "a")
+	b.Insert(Loc{X: 1, Y: 0}, "b")
+	b.Undo()
+	assert.Equal(t, "", string(b.Bytes()))
+
+	b.Settings["undotimeout"] = float64(0)
+	b.Insert(Loc{X: 0, Y: 0}, "a")
+	b.Insert(Loc{X: 1, Y: 0}, "b")
+	b.Undo()
+	assert.Equal(t, "a", string(b.Bytes()))
+}
+
+func TestUndoBreakAndGroups(t *testing.T) {
+	b := NewBufferFromString("", "", BTDefault)
+	defer b.Close()
+	b.Settings["undogroup"] = "action"
+
+	b.Insert(Loc{X: 0, Y: 0}, "a")
+	b.UndoBreak()
+	b.Insert(Loc{X: 1, Y: 0}, "b")
+	b.Undo()
+	assert.Equal(t, "a", string(b.Bytes()))
+
+	b.Settings["undotimeout"] = float64(0)
+	b.Settings["undogroup"] = "time"
+	assert.True(t, b.StartUndoGroup())
+	assert.False(t, b.StartUndoGroup())
+	b.Insert(Loc{X: 1, Y: 0}, "b")
+	b.Insert(Loc{X: 2, Y: 0}, "c")
+	assert.True(t, b.EndUndoGroup())
+	assert.False(t, b.EndUndoGroup())
+	b.Insert(Loc{X: 3, Y: 0}, "d")
+	b.Undo()
+	assert.Equal(t, "abc", string(b.Bytes()))
+	b.Undo()
+	assert.Equal(t, "a", string(b.Bytes()))
+}
@@ -0,0 +1,216 @@
+package buffer
+
+import (
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// expandPairs are the brackets and quotes ExpandRange grows to
+const expandPairs = "([{\"'`"
+
+// ExpandRange returns the smallest range strictly containing the text from
+// start to end among the word at it, the string or comment it is in
+// according to the highlighting, the text inside the brackets or quotes
+// around it and then along with them, its lines, the block of lines
+// indented at least as much, the block along with the lines opening and
+// closing it, and the whole buffer. It returns false if the text is the
+// whole buffer.
+func (b *Buffer) ExpandRange(start, end Loc) (Loc, Loc, bool) {
+	var best [2]Loc
+	found := false
+	add := func(s, e Loc) {
+		if s.GreaterThan(start) || e.LessThan(end) || s == start && e == end {
+			return
+		}
+		if !found || s.Diff(e, b) < best[0].Diff(best[1], b) {
+			best, found = [2]Loc{s, e}, true
+		}
+	}
+
+	if start.Y == end.Y {
+		if s, e, ok := b.wordRange(start); ok {
+			add(s, e)
+		}
+	}
+	if s, e, ok := b.syntaxRange(start); ok {
+		add(s, e)
+		if e.X > 0 && s.X < e.X-1 && s.Y == e.Y {
+			line := []rune(string(b.LineBytes(s.Y)))
+			if q := line[s.X]; strings.ContainsRune("\"'`", q) && line[e.X-1] == q {
+				add(Loc{X: s.X + 1, Y: s.Y}, Loc{X: e.X - 1, Y: e.Y})
+			}
+		}
+	}
+	for _, r := range expandPairs {
+		b.addSurround(start, end, r, add)
+	}
+
+	y1, y2 := start.Y, end.Y
+	if end.X == 0 && y2 > y1 {
+		y2--
+	}
+	add(Loc{X: b.indentEnd(y1), Y: y1}, Loc{X: util.CharacterCount(b.LineBytes(y2)), Y: y2})
+	if by, ey, ok := b.indentBlock(y1, y2); ok {
+		add(Loc{X: b.indentEnd(by), Y: by}, Loc{X: util.CharacterCount(b.LineBytes(ey)), Y: ey})
+		hy, fy := b.blockEnds(by, ey)
+		add(Loc{X: b.indentEnd(hy), Y: hy}, Loc{X: util.CharacterCount(b.LineBytes(fy)), Y: fy})
+	}
+	add(b.Start(), b.End())
+	return best[0], best[1], found
+}
+
+// wordRange returns the word under loc, or ending at it
+func (b *Buffer) wordRange(loc Loc) (Loc, Loc, bool) {
+	line := []rune(string(b.LineBytes(loc.Y)))
+	x := loc.X
+	if x >= len(line) || !util.IsWordChar(line[x]) {
+		if x == 0 || x > len(line) || !util.IsWordChar(line[x-1]) {
+			return loc, loc, false
+		}
+		x--
+	}
+	s, e := x, x+1
+	for s > 0 && util.IsWordChar(line[s-1]) {
+		s--
+	}
+	for e < len(line) && util.IsWordChar(line[e]) {
+		e++
+	}
+	return Loc{X: s, Y: loc.Y}, Loc{X: e, Y: loc.Y}, true
+}
+
+// syntaxRange returns the string or comment the character at loc is in,
+// according to the highlighting of the buffer. A comment or string going on
+// over several lines, such as a block comment, is followed through them.
+func (b *Buffer) syntaxRange(loc Loc) (Loc, Loc, bool) {
+	if loc.X >= util.CharacterCount(b.LineBytes(loc.Y)) {
+		return loc, loc, false
+	}
+	group := b.groupAt(loc)
+	if !strings.HasPrefix(group, "constant.string") && !strings.HasPrefix(group, "comment") {
+		return loc, loc, false
+	}
+	start := loc
+	for {
+		prev := Loc{X: start.X - 1, Y: start.Y}
+		if start.X == 0 {
+			if start.Y == 0 {
+				break
+			}
+			l := util.CharacterCount(b.LineBytes(start.Y - 1))
+			if l == 0 {
+				break
+			}
+			prev = Loc{X: l - 1, Y: start.Y - 1}
+		}
+		if b.groupAt(prev) != group {
+			break
+		}
+		start = prev
+	}
+	end := loc
+	for {
+		next := Loc{X: end.X + 1, Y: end.Y}
+		if next.X >= util.CharacterCount(b.LineBytes(end.Y)) {
+			if end.Y+1 >= b.LinesNum() || len(b.LineBytes(end.Y+1)) == 0 {
+				break
+			}
+			next = Loc{X: 0, Y: end.Y + 1}
+		}
+		if b.groupAt(next) != group {
+			break
+		}
+		end = next
+	}
+	return start, Loc{X: end.X + 1, Y: end.Y}, true
+}
+
+// addSurround calls add with the inside of the innermost pair of r around
+// the text from start to end, and with the pair along with it
+func (b *Buffer) addSurround(start, end Loc, r rune, add func(s, e Loc)) {
+	loc := start
+	for {
+		open, close, ok := b.FindSurround(loc, r)
+		if !ok {
+			return
+		}
+		if !open[0].GreaterThan(start) && !close[1].LessThan(end) && (open[0] != start || close[1] != end) {
+			add(open[1], close[0])
+			add(open[0], close[1])
+			return
+		}
+		if open[0] == b.Start() {
+			return
+		}
+		// looks for a pair around this one
+		loc = open[0].Move(-1, b)
+	}
+}
+
+// indentEnd returns the position of the first non-blank character of line y
+func (b *Buffer) indentEnd(y int) int {
+	return util.CharacterCount(util.GetLeadingWhitespace(b.LineBytes(y)))
+}
+
+func (b *Buffer) blankLine(y int) bool {
+	return len(strings.TrimSpace(string(b.LineBytes(y)))) == 0
+}
+
+// indentBlock returns the first and last non-blank lines of the block of
+// lines around the lines from y1 to y2 which are indented at least as much
+// as the least indented of them. It returns false if they aren't indented.
+func (b *Buffer) indentBlock(y1, y2 int) (int, int, bool) {
+	indent := -1
+	for y := y1; y <= y2; y++ {
+		if !b.blankLine(y) && (indent < 0 || b.indentEnd(y) < indent) {
+			indent = b.indentEnd(y)
+		}
+	}
+	if indent <= 0 {
+		return y1, y2, false
+	}
+	inBlock := func(y int) bool {
+		return b.blankLine(y) || b.indentEnd(y) >= indent
+	}
+	for y1 > 0 && inBlock(y1-1) {
+		y1--
+	}
+	for y2+1 < b.LinesNum() && inBlock(y2+1) {
+		y2++
+	}
+	for y1 < y2 && b.blankLine(y1) {
+		y1++
+	}
+	for y2 > y1 && b.blankLine(y2) {
+		y2--
+	}
+	return y1, y2, true
+}
+
+// blockEnds returns the line opening the block of lines from y1 to y2, the
+// less indented line before it, and the line closing it, the less indented
+// line after it if it starts with a closing bracket or end
+func (b *Buffer) blockEnds(y1, y2 int) (int, int) {
+	indent := b.indentEnd(y1)
+	header := y1
+	for y := y1 - 1; y >= 0; y-- {
+		if !b.blankLine(y) {
+			if b.indentEnd(y) < indent {
+				header = y
+			}
+			break
+		}
+	}
+	footer := y2
+	for y := y2 + 1; y < b.LinesNum(); y++ {
+		if !b.blankLine(y) {
+			text := strings.TrimSpace(string(b.LineBytes(y)))
+			if b.indentEnd(y) < indent && (strings.ContainsAny(text[:1], ")]}") || strings.HasPrefix(text, "end")) {
+				footer = y
+			}
+			break
+		}
+	}
+	return header, footer
+}
@@ -0,0 +1,66 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expandSteps returns the texts ExpandRange grows to from loc, until the
+// whole buffer
+func expandSteps(b *Buffer, loc Loc) []string {
+	var steps []string
+	start, end := loc, loc
+	for {
+		s, e, ok := b.ExpandRange(start, end)
+		if !ok {
+			return steps
+		}
+		steps = append(steps, string(b.Substr(s, e)))
+		start, end = s, e
+	}
+}
+
+func TestExpandRange(t *testing.T) {
+	b := NewBufferFromString("func f() {\n\tif g(a, \"b c\") {\n\t\tx()\n\t}\n}\n", "", BTDefault)
+	defer b.Close()
+
+	assert.Equal(t, []string{
+		"b",
+		"b c",
+		"\"b c\"",
+		"a, \"b c\"",
+		"(a, \"b c\")",
+		"if g(a, \"b c\") {",
+		"if g(a, \"b c\") {\n\t\tx()\n\t}",
+		"\n\tif g(a, \"b c\") {\n\t\tx()\n\t}\n",
+		"{\n\tif g(a, \"b c\") {\n\t\tx()\n\t}\n}",
+		"func f() {\n\tif g(a, \"b c\") {\n\t\tx()\n\t}\n}",
+		"func f() {\n\tif g(a, \"b c\") {\n\t\tx()\n\t}\n}\n",
+	}, expandSteps(b, Loc{X: 10, Y: 1}))
+
+	// an indented block grows to the lines opening and closing it
+	b = NewBufferFromString("def f():\n    a = 1\n    b = 2\n\nx\n", "", BTDefault)
+	defer b.Close()
+	assert.Equal(t, []string{
+		"a",
+		"a = 1",
+		"a = 1\n    b = 2",
+		"def f():\n    a = 1\n    b = 2",
+		"def f():\n    a = 1\n    b = 2\n\nx\n",
+	}, expandSteps(b, Loc{X: 4, Y: 1}))
+
+	// the whole buffer has nothing around it
+	s, e, ok := b.ExpandRange(b.Start(), b.End())
+	assert.False(t, ok)
+	assert.Equal(t, s, e)
+}
+
+func TestExpandRangeHighlighting(t *testing.T) {
+	b := highlightedBuffer(t, "if x /* a\nb c */ else")
+	defer b.Close()
+
+	s, e, ok := b.ExpandRange(Loc{X: 0, Y: 1}, Loc{X: 1, Y: 1})
+	assert.True(t, ok)
+	assert.Equal(t, "/* a\nb c */", string(b.Substr(s, e)))
+}
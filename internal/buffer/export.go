@@ -0,0 +1,215 @@
+package buffer
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// styledText calls emit with the runs of text from start to end sharing the
+// style given to them by the highlighting and the colorscheme, and with a
+// line break between the lines
+func (b *Buffer) styledText(start, end Loc, emit func(text string, style tcell.Style)) {
+	// the runs of the same style are joined
+	var run strings.Builder
+	runStyle := config.DefStyle
+	f := func(text string, style tcell.Style) {
+		if style != runStyle || text == "\n" {
+			if run.Len() > 0 {
+				emit(run.String(), runStyle)
+				run.Reset()
+			}
+			runStyle = style
+		}
+		if text == "\n" {
+			emit(text, style)
+			return
+		}
+		run.WriteString(text)
+	}
+	defer func() {
+		if run.Len() > 0 {
+			emit(run.String(), runStyle)
+		}
+	}()
+
+	highlight := b.Settings["syntax"].(bool) && b.SyntaxDef != nil
+	for y := start.Y; y <= end.Y; y++ {
+		line := []rune(string(b.LineBytes(y)))
+		x0, x1 := 0, len(line)
+		if y == start.Y {
+			x0 = start.X
+		}
+		if y == end.Y && end.X < x1 {
+			x1 = end.X
+		}
+
+		// the style of the line changes at the matches of its highlighting
+		var starts []int
+		match := b.Match(y)
+		if highlight {
+			for x := range match {
+				starts = append(starts, x)
+			}
+			sort.Ints(starts)
+		}
+		style := config.DefStyle
+		from := x0
+		for _, x := range starts {
+			if x > x0 && x < x1 && x > from {
+				f(string(line[from:x]), style)
+				from = x
+			}
+			if x >= x1 {
+				break
+			}
+			style = config.GetColor(match[x].String())
+		}
+		if from < x1 {
+			f(string(line[from:x1]), style)
+		}
+		if y < end.Y {
+			f("\n", config.DefStyle)
+		}
+	}
+}
+
+// cssColor returns the CSS color of a tcell color, or "" for the default one
+func cssColor(c tcell.Color) string {
+	if v := c.Hex(); v >= 0 {
+		return fmt.Sprintf("#%06x", v)
+	}
+	return ""
+}
+
+// ExportHTML returns the text from start to end as a standalone HTML page,
+// with the colors and attributes the display gives it
+func (b *Buffer) ExportHTML(start, end Loc) string {
+	// the colors of the page are left out of the styles of the text
+	pageFg, pageBg, _ := config.DefStyle.Decompose()
+	css := func(style tcell.Style, page bool) string {
+		fg, bg, attr := style.Decompose()
+		if attr&tcell.AttrReverse != 0 {
+			fg, bg = bg, fg
+		}
+		var props []string
+		if c := cssColor(fg); c != "" && (page || fg != pageFg) {
+			props = append(props, "color:"+c)
+		}
+		if c := cssColor(bg); c != "" && (page || bg != pageBg) {
+			props = append(props, "background-color:"+c)
+		}
+		if attr&tcell.AttrBold != 0 {
+			props = append(props, "font-weight:bold")
+		}
+		if attr&tcell.AttrItalic != 0 {
+			props = append(props, "font-style:italic")
+		}
+		if attr&tcell.AttrDim != 0 {
+			props = append(props, "opacity:0.7")
+		}
+		if attr&tcell.AttrUnderline != 0 {
+			props = append(props, "text-decoration:underline")
+		} else if attr&tcell.AttrStrikeThrough != 0 {
+			props = append(props, "text-decoration:line-through")
+		}
+		return strings.Join(props, ";")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n", html.EscapeString(b.GetName()))
+	sb.WriteString("</head>\n<body>\n")
+	page := css(config.DefStyle, true)
+	if page != "" {
+		page += ";"
+	}
+	fmt.Fprintf(&sb, "<pre style=\"%spadding:1em;tab-size:%d\">", page, int(b.Settings["tabsize"].(float64)))
+	b.styledText(start, end, func(text string, style tcell.Style) {
+		text = html.EscapeString(text)
+		if s := css(style, false); s != "" {
+			fmt.Fprintf(&sb, "<span style=\"%s\">%s</span>", s, text)
+		} else {
+			sb.WriteString(text)
+		}
+	})
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+// ansiColor returns the SGR parameters of a tcell color as the foreground
+// color, or the background one with bg, and "" for the default one
+func ansiColor(c tcell.Color, bg bool) string {
+	if !c.Valid() {
+		return ""
+	}
+	base := 38
+	if bg {
+		base = 48
+	}
+	if c.IsRGB() {
+		r, g, b := c.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, r, g, b)
+	}
+	n := int(c - tcell.ColorValid)
+	switch {
+	case n < 8:
+		return fmt.Sprint(base - 8 + n)
+	case n < 16:
+		return fmt.Sprint(base + 52 + n - 8)
+	}
+	return fmt.Sprintf("%d;5;%d", base, n)
+}
+
+// ExportANSI returns the text from start to end with the ANSI escape
+// sequences giving it the colors and attributes the display gives it
+func (b *Buffer) ExportANSI(start, end Loc) string {
+	attrs := []struct {
+		attr tcell.AttrMask
+		code string
+	}{
+		{tcell.AttrBold, "1"},
+		{tcell.AttrDim, "2"},
+		{tcell.AttrItalic, "3"},
+		{tcell.AttrUnderline, "4"},
+		{tcell.AttrBlink, "5"},
+		{tcell.AttrReverse, "7"},
+		{tcell.AttrStrikeThrough, "9"},
+	}
+
+	var sb strings.Builder
+	styled := false
+	b.styledText(start, end, func(text string, style tcell.Style) {
+		if text == "\n" || style == tcell.StyleDefault {
+			if styled {
+				sb.WriteString("\x1b[0m")
+				styled = false
+			}
+			sb.WriteString(text)
+			return
+		}
+		fg, bg, attr := style.Decompose()
+		codes := []string{"0"}
+		for _, a := range attrs {
+			if attr&a.attr != 0 {
+				codes = append(codes, a.code)
+			}
+		}
+		if c := ansiColor(fg, false); c != "" {
+			codes = append(codes, c)
+		}
+		if c := ansiColor(bg, true); c != "" {
+			codes = append(codes, c)
+		}
+		sb.WriteString("\x1b[" + strings.Join(codes, ";") + "m" + text)
+		styled = true
+	})
+	if styled {
+		sb.WriteString("\x1b[0m")
+	}
+	return sb.String()
+}
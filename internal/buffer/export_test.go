@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/tcell/v2"
+)
+
+func TestExport(t *testing.T) {
+	colorscheme, defStyle := config.Colorscheme, config.DefStyle
+	defer func() {
+		config.Colorscheme, config.DefStyle = colorscheme, defStyle
+	}()
+	config.DefStyle = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
+	config.Colorscheme = map[string]tcell.Style{
+		"statement": config.DefStyle.Foreground(tcell.ColorRed).Bold(true),
+		"comment":   config.DefStyle.Foreground(tcell.PaletteColor(100)),
+	}
+
+	b := highlightedBuffer(t, "if a < b /* c */\nelse")
+	defer b.Close()
+
+	page := b.ExportHTML(b.Start(), b.End())
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.Contains(t, page, `<pre style="color:#ffffff;background-color:#000000;`)
+	assert.Contains(t, page, `<span style="color:#ff0000;font-weight:bold">if</span> a &lt; b `+
+		`<span style="color:#878700">/* c */</span>`+"\n"+`<span style="color:#ff0000;font-weight:bold">else</span></pre>`)
+
+	// a range starts and ends in the middle of the runs
+	assert.Equal(t, "\x1b[0;1;91;40mf\x1b[0;97;40m a\x1b[0m",
+		b.ExportANSI(Loc{X: 1, Y: 0}, Loc{X: 4, Y: 0}))
+	assert.Equal(t, "\x1b[0;38;5;100;40m c */\x1b[0m\n\x1b[0;1;91;40mel\x1b[0m",
+		b.ExportANSI(Loc{X: 11, Y: 0}, Loc{X: 2, Y: 1}))
+}
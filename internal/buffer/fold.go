@@ -0,0 +1,303 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// FoldMethods are the values of the foldmethod option
+var FoldMethods = []string{"auto", "bracket", "indent"}
+
+// A Fold is a region of lines which can be folded: the lines after Start,
+// up to End, are hidden behind it
+type Fold struct {
+	Start, End int
+}
+
+// FoldRegions returns the regions of the buffer which can be folded, one
+// per start line, sorted by start line. Depending on the foldmethod option,
+// they are the lines between an opening bracket and the line of the
+// bracket matching it, which is part of the region unless code other than
+// brackets follows it as in "} else {", or the lines indented more than the
+// line before them. The auto method uses the brackets, and the indentation
+// for the lines which don't start a region of brackets.
+func (b *Buffer) FoldRegions() []Fold {
+	method := b.Settings["foldmethod"].(string)
+	byStart := make(map[int]int)
+	if method != "indent" {
+		for _, f := range b.bracketRegions() {
+			byStart[f.Start] = util.Max(byStart[f.Start], f.End)
+		}
+	}
+	if method != "bracket" {
+		for _, f := range b.indentRegions() {
+			if _, ok := byStart[f.Start]; !ok {
+				byStart[f.Start] = f.End
+			}
+		}
+	}
+	regions := make([]Fold, 0, len(byStart))
+	for start, end := range byStart {
+		regions = append(regions, Fold{start, end})
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Start < regions[j].Start
+	})
+	return regions
+}
+
+// bracketRegions returns the regions between the brackets of the code,
+// those of strings and comments being skipped
+func (b *Buffer) bracketRegions() []Fold {
+	var regions []Fold
+	var open []int
+	s := newBraceScanner(b)
+	for y := 0; y < b.LinesNum(); y++ {
+		s.load(y)
+		for x, r := range s.line {
+			if s.classes[x] != classCode {
+				continue
+			}
+			switch r {
+			case '{', '[', '(':
+				open = append(open, y)
+			case '}', ']', ')':
+				if len(open) == 0 {
+					continue
+				}
+				start := open[len(open)-1]
+				open = open[:len(open)-1]
+				end := y
+				if strings.TrimLeft(string(s.line[x+1:]), " \t)]};,") != "" {
+					end--
+				}
+				if end > start {
+					regions = append(regions, Fold{start, end})
+				}
+			}
+		}
+	}
+	return regions
+}
+
+// indentRegions returns the regions of the lines indented more than the
+// line before them, up to the last non-blank one
+func (b *Buffer) indentRegions() []Fold {
+	type header struct{ y, indent int }
+	var regions []Fold
+	var stack []header
+	last := -1
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	for y := 0; y <= b.LinesNum(); y++ {
+		indent := -1
+		if y < b.LinesNum() {
+			if b.blankLine(y) {
+				continue
+			}
+			ws := util.GetLeadingWhitespace(b.LineBytes(y))
+			indent = util.StringWidth(ws, util.CharacterCount(ws), tabsize)
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			h := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if last > h.y {
+				regions = append(regions, Fold{h.y, last})
+			}
+		}
+		stack = append(stack, header{y, indent})
+		last = y
+	}
+	return regions
+}
+
+// Folds returns the folded regions, sorted by start line. They may be
+// nested.
+func (b *Buffer) Folds() []Fold {
+	return append([]Fold(nil), b.folds...)
+}
+
+// HasFolds returns whether some regions are folded
+func (b *Buffer) HasFolds() bool {
+	return len(b.folds) > 0
+}
+
+// SetFolds replaces the folded regions, those which are out of the buffer
+// or hide no line being left out
+func (b *Buffer) SetFolds(folds []Fold) {
+	b.folds = nil
+	for _, f := range folds {
+		if f.Start >= 0 && f.End > f.Start && f.End < b.LinesNum() {
+			b.folds = append(b.folds, f)
+		}
+	}
+	b.sortFolds()
+}
+
+func (b *SharedBuffer) sortFolds() {
+	sort.Slice(b.folds, func(i, j int) bool {
+		fi, fj := b.folds[i], b.folds[j]
+		return fi.Start < fj.Start || fi.Start == fj.Start && fi.End > fj.End
+	})
+}
+
+// Fold folds the innermost region containing the line y which isn't folded
+// yet, so that folding again folds the regions around it, and returns
+// false if there is none
+func (b *Buffer) Fold(y int) bool {
+	regions := b.FoldRegions()
+	for i := len(regions) - 1; i >= 0; i-- {
+		f := regions[i]
+		if f.Start > y || f.End < y || b.isFolded(f) {
+			continue
+		}
+		b.folds = append(b.folds, f)
+		b.sortFolds()
+		return true
+	}
+	return false
+}
+
+func (b *Buffer) isFolded(f Fold) bool {
+	for _, g := range b.folds {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Unfold unfolds the folds containing the line y, and returns false if
+// there is none
+func (b *Buffer) Unfold(y int) bool {
+	kept := b.folds[:0]
+	for _, f := range b.folds {
+		if f.Start > y || f.End < y {
+			kept = append(kept, f)
+		}
+	}
+	unfolded := len(kept) < len(b.folds)
+	b.folds = kept
+	return unfolded
+}
+
+// FoldAll folds all the regions of the buffer
+func (b *Buffer) FoldAll() {
+	b.folds = b.FoldRegions()
+	b.sortFolds()
+}
+
+// UnfoldAll unfolds all the folds, and returns false if there was none
+func (b *Buffer) UnfoldAll() bool {
+	unfolded := len(b.folds) > 0
+	b.folds = nil
+	return unfolded
+}
+
+// IsFolded returns whether the line y is shown with the lines it hides, and
+// the last of them
+func (b *Buffer) IsFolded(y int) (int, bool) {
+	end, ok := -1, false
+	if b.LineHidden(y) {
+		return end, ok
+	}
+	for _, f := range b.folds {
+		if f.Start == y {
+			end, ok = util.Max(end, f.End), true
+		}
+	}
+	return end, ok
+}
+
+// LineHidden returns whether the line y is hidden by a fold
+func (b *Buffer) LineHidden(y int) bool {
+	for _, f := range b.folds {
+		if f.Start < y && f.End >= y {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleLine returns the line y if it is shown, or else the line of the
+// outermost fold hiding it
+func (b *Buffer) VisibleLine(y int) int {
+	for hidden := true; hidden; {
+		hidden = false
+		for _, f := range b.folds {
+			if f.Start < y && f.End >= y {
+				y, hidden = f.Start, true
+				break
+			}
+		}
+	}
+	return y
+}
+
+// MoveVisibleLines returns the line n shown lines after the line y, or
+// before it if n is negative, stopping at the first or last line shown
+func (b *Buffer) MoveVisibleLines(y, n int) int {
+	y = b.VisibleLine(util.Clamp(y, 0, b.LinesNum()-1))
+	if len(b.folds) == 0 {
+		return util.Clamp(y+n, 0, b.LinesNum()-1)
+	}
+	for ; n > 0; n-- {
+		next := y + 1
+		if end, ok := b.IsFolded(y); ok {
+			next = end + 1
+		}
+		if next >= b.LinesNum() {
+			break
+		}
+		y = next
+	}
+	for ; n < 0 && y > 0; n++ {
+		y = b.VisibleLine(y - 1)
+	}
+	return y
+}
+
+// UnfoldCursors unfolds the folds hiding a cursor, and returns whether
+// there was one
+func (b *Buffer) UnfoldCursors() bool {
+	unfolded := false
+	for _, c := range b.GetCursors() {
+		for b.LineHidden(c.Y) {
+			b.Unfold(c.Y)
+			unfolded = true
+		}
+	}
+	return unfolded
+}
+
+// moveFolds moves the folds after a text event changed the text between
+// start and end. The folds move with the lines inserted or removed before
+// them, and stay on an edit within the line they are shown with, but an
+// edit of the lines they hide, or which splits or joins their first line,
+// unfolds them.
+func (b *SharedBuffer) moveFolds(eventType int, start, end Loc) {
+	kept := b.folds[:0]
+	n := end.Y - start.Y
+	for _, f := range b.folds {
+		if eventType == TextEventInsert {
+			switch {
+			case start.Y < f.Start || start.Y == f.Start && start.X == 0 && n > 0:
+				f.Start, f.End = f.Start+n, f.End+n
+			case start.Y == f.Start && n == 0 || start.Y > f.End:
+			default:
+				continue
+			}
+		} else {
+			switch {
+			case end.Y < f.Start || end.Y == f.Start && end.X == 0 && n > 0:
+				f.Start, f.End = f.Start-n, f.End-n
+			case start.Y == f.Start && n == 0 || start.Y > f.End:
+			default:
+				continue
+			}
+		}
+		kept = append(kept, f)
+	}
+	b.folds = kept
+}
@@ -0,0 +1,88 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldRegions(t *testing.T) {
+	tests := []struct {
+		text, method string
+		regions      []Fold
+	}{
+		{"func f() {\n\tx()\n}\n", "bracket", []Fold{{0, 2}}},
+		{"if a {\n\tb\n} else {\n\tc\n}", "bracket", []Fold{{0, 1}, {2, 4}}},
+		{"f(a,\n  b,\n  c)", "bracket", []Fold{{0, 2}}},
+		{"a:\n  b\n\n  c:\n    d\ne", "indent", []Fold{{0, 4}, {3, 4}}},
+		{"a {\n  b\n}\nc:\n  d", "auto", []Fold{{0, 2}, {3, 4}}},
+		{"a {\n  b\n}\nc:\n  d", "bracket", []Fold{{0, 2}}},
+	}
+	for _, tt := range tests {
+		b := NewBufferFromString(tt.text, "", BTDefault)
+		b.Settings["foldmethod"] = tt.method
+		assert.Equal(t, tt.regions, b.FoldRegions(), tt.text)
+		b.Close()
+	}
+}
+
+func TestFold(t *testing.T) {
+	b := NewBufferFromString("a {\n  b {\n    c\n  }\n}\nd", "", BTDefault)
+	defer b.Close()
+
+	assert.True(t, b.Fold(2))
+	assert.Equal(t, []Fold{{1, 3}}, b.Folds())
+	// folding again folds the region around the fold
+	assert.True(t, b.Fold(2))
+	assert.Equal(t, []Fold{{0, 4}, {1, 3}}, b.Folds())
+	assert.False(t, b.Fold(2))
+	assert.False(t, b.Fold(5))
+
+	_, ok := b.IsFolded(1)
+	assert.False(t, ok)
+	end, ok := b.IsFolded(0)
+	assert.True(t, ok)
+	assert.Equal(t, 4, end)
+	assert.True(t, b.LineHidden(3))
+	assert.Equal(t, 0, b.VisibleLine(2))
+	assert.Equal(t, 5, b.MoveVisibleLines(0, 1))
+	assert.Equal(t, 0, b.MoveVisibleLines(5, -1))
+	assert.Equal(t, 5, b.MoveVisibleLines(0, 10))
+
+	assert.True(t, b.Unfold(2))
+	assert.False(t, b.HasFolds())
+	assert.False(t, b.UnfoldAll())
+
+	b.FoldAll()
+	assert.Equal(t, []Fold{{0, 4}, {1, 3}}, b.Folds())
+	assert.True(t, b.UnfoldAll())
+}
+
+func TestMoveFolds(t *testing.T) {
+	b := NewBufferFromString("x\na {\n  b\n}\ny", "", BTDefault)
+	defer b.Close()
+	b.SetFolds([]Fold{{1, 3}})
+
+	// lines inserted before the fold move it
+	b.Insert(Loc{0, 0}, "w\n")
+	assert.Equal(t, []Fold{{2, 4}}, b.Folds())
+	b.Insert(Loc{0, 2}, "\n")
+	assert.Equal(t, []Fold{{3, 5}}, b.Folds())
+	// an edit of the first line keeps it
+	b.Insert(Loc{1, 3}, "a")
+	assert.Equal(t, []Fold{{3, 5}}, b.Folds())
+	b.Remove(Loc{0, 0}, Loc{0, 1})
+	assert.Equal(t, []Fold{{2, 4}}, b.Folds())
+	// the lines after it don't move it
+	b.Insert(Loc{0, 5}, "z\n")
+	assert.Equal(t, []Fold{{2, 4}}, b.Folds())
+
+	// an edit of the lines it hides unfolds it
+	b.Insert(Loc{0, 3}, "c")
+	assert.False(t, b.HasFolds())
+
+	b.SetFolds([]Fold{{2, 4}, {4, 8}, {3, 2}})
+	assert.Equal(t, []Fold{{2, 4}}, b.Folds())
+	b.Remove(Loc{0, 1}, Loc{0, 3})
+	assert.False(t, b.HasFolds())
+}
@@ -0,0 +1,136 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// followInterval is how often the file of a buffer with the follow option
+// is checked for appended data
+const followInterval = 500 * time.Millisecond
+
+// FollowUpdates receives the buffers with the follow option whose file has
+// changed, which the main loop passes to ReadAppended
+var FollowUpdates = make(chan *Buffer, 100)
+
+// updateDiskSize records the size of the file as it was read or written,
+// which is where the data appended to it starts
+func (b *Buffer) updateDiskSize(path string) {
+	if info, err := os.Stat(path); err == nil {
+		b.diskSize = info.Size()
+	}
+}
+
+// startFollow watches the file of the buffer for the follow option
+func (b *Buffer) startFollow() {
+	if b.followStop != nil || b.Type.Kind != BTDefault.Kind || b.Path == "" {
+		return
+	}
+	stop := make(chan struct{})
+	b.followStop = stop
+	path := b.Path
+	go func() {
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+		var modTime time.Time
+		size := int64(-1)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if atomic.LoadInt32(&b.fini) == 1 {
+				return
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.Size() == size && info.ModTime().Equal(modTime) {
+				continue
+			}
+			size, modTime = info.Size(), info.ModTime()
+			FollowUpdates <- b
+		}
+	}()
+}
+
+// stopFollow stops watching the file of the buffer
+func (b *Buffer) stopFollow() {
+	if b.followStop != nil {
+		close(b.followStop)
+		b.followStop = nil
+	}
+}
+
+// ReadAppended adds the data appended to the file since it was last read
+// or written to the end of the buffer, and returns whether the text
+// changed. The appended lines aren't an edit: they are left out of the undo
+// history, and the buffer stays unmodified if it was. A file which got
+// shorter, as a log being rotated, is read again if the buffer isn't
+// modified.
+func (b *Buffer) ReadAppended() (bool, error) {
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return false, err
+	}
+	size := info.Size()
+	if size == b.diskSize {
+		b.ModTime = info.ModTime()
+		return false, nil
+	}
+	if size < b.diskSize || b.Settings["hex"].(bool) {
+		if b.Modified() {
+			b.diskSize = size
+			b.ModTime = info.ModTime()
+			return false, nil
+		}
+		return true, b.ReOpen()
+	}
+
+	file, err := os.Open(b.Path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	data := make([]byte, size-b.diskSize)
+	if _, err := io.ReadFull(io.NewSectionReader(file, b.diskSize, int64(len(data))), data); err != nil {
+		return false, err
+	}
+	if encoding := b.Settings["encoding"].(string); strings.ToLower(encoding) != "utf-8" {
+		enc, err := htmlindex.Get(encoding)
+		if err != nil {
+			return false, err
+		}
+		if data, _, err = transform.Bytes(enc.NewDecoder(), data); err != nil {
+			return false, err
+		}
+	}
+	if b.Endings == FFDos {
+		data = bytes.Replace(data, []byte{'\r', '\n'}, []byte{'\n'}, -1)
+	}
+
+	modified := b.Modified()
+	b.EventHandler.cursors = b.cursors
+	b.EventHandler.active = b.curCursor
+	b.EventHandler.DoTextEvent(&TextEvent{
+		C:         *b.GetActiveCursor(),
+		EventType: TextEventInsert,
+		Deltas:    []Delta{{data, b.End(), Loc{}}},
+		Time:      time.Now(),
+	}, false)
+	if !modified {
+		if !b.Settings["fastdirty"].(bool) {
+			calcHash(b, &b.origHash)
+		}
+		b.isModified = false
+	}
+	b.diskSize = size
+	b.ModTime = info.ModTime()
+	return true, nil
+}
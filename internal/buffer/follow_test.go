@@ -0,0 +1,58 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAppended(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "app.log")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("one\n"), 0644))
+	b, err := NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	defer b.Close()
+
+	changed, err := b.ReadAppended()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	write := func(text string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.NoError(t, err)
+		f.WriteString(text)
+		f.Close()
+	}
+	write("two\nthr")
+	changed, err = b.ReadAppended()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	write("ee\n")
+	b.ReadAppended()
+	assert.Equal(t, "one\ntwo\nthree\n", string(b.Bytes()))
+	assert.False(t, b.Modified())
+	assert.False(t, b.ExternallyModified())
+	assert.Equal(t, 0, b.UndoStack.Len())
+
+	// an edit stays undoable after the appended lines
+	b.Insert(b.Start(), "> ")
+	write("four\n")
+	b.ReadAppended()
+	assert.True(t, b.Modified())
+	b.Undo()
+	assert.Equal(t, "one\ntwo\nthree\nfour\n", string(b.Bytes()))
+
+	// a rotated log is read again
+	assert.NoError(t, b.Save())
+	assert.NoError(t, ioutil.WriteFile(path, []byte("new\n"), 0644))
+	changed, err = b.ReadAppended()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "new\n", string(b.Bytes()))
+}
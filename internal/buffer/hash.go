@@ -0,0 +1,150 @@
+package buffer
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sync"
+
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// hashSyncSize is the number of bytes to hash from which the text of a
+// buffer is hashed in the background rather than when it is asked whether
+// it is modified
+const hashSyncSize = 1 << 20
+
+// A hashState tells whether the text of a buffer without fastdirty differs
+// from the text which origHash was computed from. The text is hashed again
+// when it changed since it was last hashed, which gen counts.
+type hashState struct {
+	gen uint64
+
+	// hashedGen is the gen which was last hashed, and modified whether the
+	// text differed from origHash then
+	hashedGen uint64
+	modified  bool
+
+	// job is the hashing running in the background, and done the job which
+	// it finished, which the main loop takes
+	job  *hashJob
+	lock sync.Mutex
+	done *hashJob
+}
+
+// A hashJob hashes a snapshot of the lines of a buffer: the sums of the
+// lines which didn't change since they were summed, and a copy of the
+// others, whose sums it adds
+type hashJob struct {
+	gen   uint64
+	sums  []uint64
+	stale []int
+	data  [][]byte
+	hash  [md5.Size]byte
+}
+
+// lineSum returns the checksum of a line, a 64-bit FNV-1a hash
+func lineSum(data []byte) uint64 {
+	sum := uint64(14695981039346656037)
+	for _, c := range data {
+		sum ^= uint64(c)
+		sum *= 1099511628211
+	}
+	return sum
+}
+
+// newHashJob returns the job hashing the lines of the buffer as they are
+// now, and the number of bytes which it has to hash
+func (b *SharedBuffer) newHashJob() (*hashJob, int) {
+	j := &hashJob{gen: b.hash.gen, sums: make([]uint64, len(b.lines))}
+	size := 8 * len(b.lines)
+	for i := range b.lines {
+		l := &b.lines[i]
+		if l.summed {
+			j.sums[i] = l.sum
+			continue
+		}
+		j.stale = append(j.stale, i)
+		j.data = append(j.data, append([]byte(nil), l.data...))
+		size += len(l.data)
+	}
+	return j, size
+}
+
+// run sums the lines which changed and hashes the sums of all the lines
+func (j *hashJob) run() {
+	for i, y := range j.stale {
+		j.sums[y] = lineSum(j.data[i])
+	}
+	j.data = nil
+
+	h := md5.New()
+	var buf [8]byte
+	for _, sum := range j.sums {
+		binary.LittleEndian.PutUint64(buf[:], sum)
+		h.Write(buf[:])
+	}
+	h.Sum(j.hash[:0])
+}
+
+// finish records the result of a job which hashed the text as it is now,
+// keeping the sums of its lines for the next time it is hashed
+func (b *SharedBuffer) finish(j *hashJob) {
+	for _, y := range j.stale {
+		b.lines[y].sum, b.lines[y].summed = j.sums[y], true
+	}
+	b.hash.hashedGen = j.gen
+	b.hash.modified = j.hash != b.origHash
+}
+
+// hashModified returns whether the text differs from origHash. Only the
+// lines which changed since the text was last hashed are hashed again, and
+// when that is a lot of text it is hashed in the background: the buffer
+// counts as modified until the job is done and the screen redrawn.
+func (b *SharedBuffer) hashModified() bool {
+	s := &b.hash
+	s.lock.Lock()
+	done := s.done
+	s.done = nil
+	s.lock.Unlock()
+	if done != nil {
+		s.job = nil
+		if done.gen == s.gen {
+			b.finish(done)
+		}
+	}
+	if s.hashedGen == s.gen {
+		return s.modified
+	}
+	if s.job != nil {
+		return true
+	}
+
+	j, size := b.newHashJob()
+	if size < hashSyncSize {
+		j.run()
+		b.finish(j)
+		return s.modified
+	}
+	s.job = j
+	go func() {
+		j.run()
+		s.lock.Lock()
+		s.done = j
+		s.lock.Unlock()
+		screen.Redraw()
+	}()
+	return true
+}
+
+// calcHash calculates the hash of all lines in the buffer, which is the
+// original hash that it is compared to
+func calcHash(b *Buffer, out *[md5.Size]byte) error {
+	if b.Size() > LargeFileThreshold {
+		return ErrFileTooLarge
+	}
+	j, _ := b.newHashJob()
+	j.run()
+	*out = j.hash
+	b.finish(j)
+	return nil
+}
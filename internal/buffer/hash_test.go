@@ -0,0 +1,58 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHashedBuffer(text string) *Buffer {
+	b := NewBufferFromString(text, "", BTDefault)
+	b.Settings["fastdirty"] = false
+	calcHash(b, &b.origHash)
+	return b
+}
+
+func TestHashModified(t *testing.T) {
+	b := newHashedBuffer("one\ntwo\nthree")
+	assert.False(t, b.Modified())
+
+	b.Insert(Loc{3, 1}, "!")
+	assert.True(t, b.Modified())
+	// only the changed line is hashed again
+	j, _ := b.newHashJob()
+	assert.Empty(t, j.stale)
+	b.Insert(Loc{0, 2}, "x\n")
+	j, _ = b.newHashJob()
+	assert.Equal(t, []int{2, 3}, j.stale)
+
+	b.Remove(Loc{0, 2}, Loc{0, 3})
+	b.Remove(Loc{3, 1}, Loc{4, 1})
+	assert.False(t, b.Modified())
+	b.Replace(Loc{0, 0}, Loc{3, 0}, "one")
+	assert.False(t, b.Modified())
+}
+
+func TestHashBackground(t *testing.T) {
+	b := newHashedBuffer(strings.Repeat("line\n", 2*hashSyncSize/8))
+
+	b.Insert(Loc{0, 10}, "x")
+	b.Remove(Loc{0, 10}, Loc{1, 10})
+	// the buffer is modified until the hashing is done
+	assert.True(t, b.Modified())
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Modified() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, b.Modified())
+
+	b.Insert(Loc{0, 10}, "x")
+	deadline = time.Now().Add(5 * time.Second)
+	for (b.hash.job != nil || b.hash.hashedGen != b.hash.gen) && time.Now().Before(deadline) {
+		b.Modified()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, b.Modified())
+}
@@ -0,0 +1,210 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// binarySample is the number of bytes at the start of a file which IsBinary
+// looks at
+const binarySample = 8000
+
+// hexWidth is the number of bytes shown on a line of the hex view
+const hexWidth = 16
+
+// IsBinary returns whether data, the start of a file, looks like binary
+// content rather than text: it has a null byte, or many bytes which are
+// neither printable nor whitespace nor valid UTF-8
+func IsBinary(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	bad := 0
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			// a character may be cut at the end of the sample
+			if len(data)-i < utf8.UTFMax && len(data) == binarySample {
+				break
+			}
+			bad++
+		} else if r < 32 && r != '\n' && r != '\r' && r != '\t' && r != '\f' && r != '\v' && r != 0x1b {
+			bad++
+		}
+		i += size
+	}
+	return bad > 0 && bad*10 > len(data)
+}
+
+// HexDump returns the hex view of data, as `hexdump -C` shows it: lines of
+// the offset, 16 bytes in hex and those bytes as ASCII characters
+func HexDump(data []byte) string {
+	var sb strings.Builder
+	for off := 0; off < len(data); off += hexWidth {
+		if off > 0 {
+			sb.WriteByte('\n')
+		}
+		line := data[off:]
+		if len(line) > hexWidth {
+			line = line[:hexWidth]
+		}
+		fmt.Fprintf(&sb, "%08x  ", off)
+		for i := 0; i < hexWidth; i++ {
+			if i < len(line) {
+				fmt.Fprintf(&sb, "%02x ", line[i])
+			} else {
+				sb.WriteString("   ")
+			}
+			if i == hexWidth/2-1 {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(" |")
+		for _, c := range line {
+			if c < 32 || c > 126 {
+				c = '.'
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('|')
+	}
+	return sb.String()
+}
+
+// ParseHexDump returns the bytes of a hex view, along with the location of
+// each of them in the text. On each line, the offset, which has more than
+// two digits, and the ASCII column after the first | are left out, so that
+// bytes are inserted, removed and changed by editing the hex column.
+func ParseHexDump(text []byte) ([]byte, []Loc, error) {
+	var data []byte
+	var locs []Loc
+	for y, line := range bytes.Split(text, []byte{'\n'}) {
+		if i := bytes.IndexByte(line, '|'); i >= 0 {
+			line = line[:i]
+		}
+		first := true
+		for x := 0; x < len(line); {
+			if line[x] == ' ' || line[x] == '\t' || line[x] == '\r' {
+				x++
+				continue
+			}
+			end := x
+			for end < len(line) && line[end] != ' ' && line[end] != '\t' && line[end] != '\r' {
+				end++
+			}
+			field := string(line[x:end])
+			if first && len(field) > 2 {
+				// the offset
+				first = false
+				x = end
+				continue
+			}
+			first = false
+			v, err := strconv.ParseUint(field, 16, 8)
+			if err != nil || len(field) != 2 {
+				return nil, nil, fmt.Errorf("Invalid byte %q on line %d of the hex view", field, y+1)
+			}
+			data = append(data, byte(v))
+			locs = append(locs, Loc{X: x, Y: y})
+			x = end
+		}
+	}
+	return data, locs, nil
+}
+
+// rawBytes returns the content of the buffer as it is saved to disk, with
+// its line endings and encoding
+func (b *Buffer) rawBytes() ([]byte, error) {
+	text := b.Bytes()
+	if b.Endings == FFDos {
+		text = bytes.Replace(text, []byte{'\n'}, []byte{'\r', '\n'}, -1)
+	}
+	enc, err := htmlindex.Get(b.Settings["encoding"].(string))
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := transform.Bytes(enc.NewEncoder(), text)
+	return out, err
+}
+
+// setHex switches the buffer between its text and its hex view (see the hex
+// option). The undo history is cleared, and the buffer stays unmodified if
+// it was.
+func (b *Buffer) setHex(hex bool) error {
+	var text []byte
+	if hex {
+		data, err := b.rawBytes()
+		if err != nil {
+			return err
+		}
+		text = []byte(HexDump(data))
+	} else {
+		data, _, err := ParseHexDump(b.Bytes())
+		if err != nil {
+			return err
+		}
+		enc, err := htmlindex.Get(b.Settings["encoding"].(string))
+		if err != nil {
+			return err
+		}
+		if text, _, err = transform.Bytes(enc.NewDecoder(), data); err != nil {
+			return err
+		}
+		if b.Endings == FFDos {
+			text = bytes.Replace(text, []byte{'\r', '\n'}, []byte{'\n'}, -1)
+		}
+	}
+
+	modified := b.Modified()
+	b.EventHandler.Replace(b.Start(), b.End(), string(text))
+	b.UndoStack = new(TEStack)
+	b.RedoStack = new(TEStack)
+	for _, c := range b.GetCursors() {
+		c.ResetSelection()
+		c.GotoLoc(b.Start())
+	}
+	if !modified {
+		if !b.Settings["fastdirty"].(bool) {
+			calcHash(b, &b.origHash)
+		}
+		b.isModified = false
+	}
+	return nil
+}
+
+// FindHex returns the range of the hex view showing the first occurrence of
+// the bytes after from, or before it if there is none after it
+func (b *Buffer) FindHex(pattern []byte, from Loc) ([2]Loc, bool, error) {
+	if len(pattern) == 0 {
+		return [2]Loc{}, false, errors.New("Empty hex pattern")
+	}
+	data, locs, err := ParseHexDump(b.Bytes())
+	if err != nil {
+		return [2]Loc{}, false, err
+	}
+	start := 0
+	for start < len(locs) && locs[start].LessThan(from) {
+		start++
+	}
+	i := -1
+	if start < len(data) {
+		if j := bytes.Index(data[start:], pattern); j >= 0 {
+			i = start + j
+		}
+	}
+	if i < 0 {
+		i = bytes.Index(data, pattern)
+	}
+	if i < 0 {
+		return [2]Loc{}, false, nil
+	}
+	last := locs[i+len(pattern)-1]
+	return [2]Loc{locs[i], {X: last.X + 2, Y: last.Y}}, true, nil
+}
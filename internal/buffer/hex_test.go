@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBinary(t *testing.T) {
+	assert.False(t, IsBinary([]byte("hello\n\tworld\r\n")))
+	assert.False(t, IsBinary([]byte("héllo wörld")))
+	assert.True(t, IsBinary([]byte("ELF\x00\x01")))
+	assert.True(t, IsBinary([]byte{0xff, 0xfe, 0x01, 0x02, 0x90}))
+}
+
+func TestHexDump(t *testing.T) {
+	data := []byte("0123456789abcdef\x00\xffz")
+	dump := HexDump(data)
+	assert.Equal(t, "00000000  30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66  |0123456789abcdef|\n"+
+		"00000010  00 ff 7a                                          |..z|", dump)
+
+	parsed, locs, err := ParseHexDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, data, parsed)
+	assert.Equal(t, Loc{X: 10, Y: 0}, locs[0])
+	assert.Equal(t, Loc{X: 35, Y: 0}, locs[8])
+	assert.Equal(t, Loc{X: 16, Y: 1}, locs[18])
+
+	// bytes are inserted without an offset, and the ASCII column is ignored
+	parsed, _, err = ParseHexDump([]byte("00000000  41 42 |zz|\n43"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ABC"), parsed)
+	_, _, err = ParseHexDump([]byte("00000000  41 4g"))
+	assert.Error(t, err)
+}
+
+func TestHexBuffer(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "f.bin")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("\x00\x01AB"), 0644))
+	b, err := NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	defer b.Close()
+	assert.True(t, b.Settings["hex"].(bool))
+	assert.False(t, b.Modified())
+
+	match, found, err := b.FindHex([]byte("AB"), b.Start())
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, [2]Loc{{X: 16, Y: 0}, {X: 21, Y: 0}}, match)
+
+	// a byte is changed and one inserted, and the view is rewritten from the
+	// saved bytes
+	b.Replace(Loc{X: 13, Y: 0}, Loc{X: 15, Y: 0}, "ff 0a")
+	assert.NoError(t, b.Save())
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\x00\xff\x0aAB"), data)
+	assert.Equal(t, HexDump(data), string(b.Bytes()))
+
+	// the text view doesn't change the file
+	assert.NoError(t, b.SetOptionNative("hex", false))
+	assert.Equal(t, "\x00�\nAB", string(b.Bytes()))
+	assert.False(t, b.Modified())
+
+	b = NewBufferFromString("ab\ncd", "", BTDefault)
+	assert.NoError(t, b.SetOptionNative("hex", true))
+	assert.Equal(t, HexDump([]byte("ab\ncd")), string(b.Bytes()))
+	assert.NoError(t, b.SetOptionNative("hex", false))
+	assert.Equal(t, "ab\ncd", string(b.Bytes()))
+}
@@ -0,0 +1,139 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+const testSyntax = `filetype: test
+
+detect:
+    filename: "\\.test$"
+
+rules:
+    - statement: "\\b(if|else)\\b"
+    - comment:
+        start: "/\\*"
+        end: "\\*/"
+        rules: []
+`
+
+func highlightedBuffer(t *testing.T, text string) *Buffer {
+	b := syntaxBuffer(t, []byte(testSyntax), text)
+	b.Highlighter.HighlightMatches(b, 0, b.End().Y)
+	return b
+}
+
+// checkHighlighting checks that the matches of every line of b are the
+// same as when highlighting its text from scratch
+func checkHighlighting(t *testing.T, b *Buffer) {
+	fresh := highlightedBuffer(t, string(b.Bytes()))
+	for i := 0; i < b.LinesNum(); i++ {
+		assert.Equal(t, fresh.Match(i), b.Match(i), "line %d", i)
+	}
+}
+
+func TestIncrementalHighlighting(t *testing.T) {
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = "if x else y"
+	}
+	b := highlightedBuffer(t, strings.Join(lines, "\n"))
+
+	// an edit which doesn't change the state only rehighlights its line
+	b.Insert(Loc{0, 10}, "else ")
+	for i := 0; i < b.LinesNum(); i++ {
+		assert.Equal(t, i == 10, b.Rehighlight(i), "line %d", i)
+	}
+	checkHighlighting(t, b)
+
+	// opening a comment changes the state of the following lines
+	b.Insert(Loc{0, 20}, "/*")
+	for i := 0; i < b.LinesNum(); i++ {
+		assert.Equal(t, i >= 20, b.Rehighlight(i), "line %d", i)
+	}
+	checkHighlighting(t, b)
+
+	// closing and reopening it on a line keeps the state of the line
+	b.Insert(Loc{5, 30}, "*/ if /*")
+	for i := 0; i < b.LinesNum(); i++ {
+		assert.Equal(t, i == 30, b.Rehighlight(i), "line %d", i)
+	}
+	checkHighlighting(t, b)
+
+	// a multiline edit goes through all modified lines
+	b.Insert(Loc{0, 50}, "/*\nif\n*/\nelse /*\n*/")
+	checkHighlighting(t, b)
+	b.Remove(Loc{0, 20}, Loc{0, 45})
+	checkHighlighting(t, b)
+	b.Insert(Loc{0, 0}, "/*")
+	checkHighlighting(t, b)
+}
+
+// embedding syntaxes: doc embeds tmpl in fenced blocks, tmpl includes
+// inner at its top level and embeds doc again in its actions
+var embedSyntaxes = []string{`filetype: doc
+
+rules:
+    - special: "^#.*"
+    - default:
+        start: "^~~~tmpl$"
+        end: "^~~~$"
+        limit-group: special
+        rules:
+            - include: "tmpl"
+`, `filetype: tmpl
+
+rules:
+    - include: "inner"
+    - default:
+        start: "{{"
+        end: "}}"
+        limit-group: special
+        rules:
+            - include: "doc"
+            - constant: "\\bx\\b"
+`, `filetype: inner
+
+rules:
+    - statement: "\\bif\\b"
+`}
+
+func TestEmbeddedHighlighting(t *testing.T) {
+	var files []*highlight.File
+	var def *highlight.Def
+	for i, s := range embedSyntaxes {
+		f, err := highlight.ParseFile([]byte(s))
+		assert.Nil(t, err)
+		if i == 0 {
+			header, err := highlight.MakeHeaderYaml([]byte(s))
+			assert.Nil(t, err)
+			def, err = highlight.ParseDef(f, header)
+			assert.Nil(t, err)
+		}
+		files = append(files, f)
+	}
+	highlight.ResolveIncludes(def, files)
+
+	b := NewBufferFromString("if x\n~~~tmpl\nif {{ x }}\n~~~\nif", "", BTDefault)
+	b.SyntaxDef = def
+	b.Highlighter = highlight.NewHighlighter(def)
+	b.Highlighter.HighlightStates(b)
+	b.Highlighter.HighlightMatches(b, 0, b.End().Y)
+
+	statement := highlight.Groups["statement"]
+	special := highlight.Groups["special"]
+	constant := highlight.Groups["constant"]
+
+	assert.NotEqual(t, statement, b.Match(0)[0])
+	assert.Equal(t, special, b.Match(1)[0])
+	// the rules of inner are included in tmpl and thus in the fenced block
+	assert.Equal(t, statement, b.Match(2)[0])
+	assert.Equal(t, special, b.Match(2)[3])
+	assert.Equal(t, constant, b.Match(2)[6])
+	assert.Equal(t, special, b.Match(3)[0])
+	assert.NotEqual(t, statement, b.Match(4)[0])
+}
@@ -0,0 +1,169 @@
+package buffer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The local history keeps the versions of the files of the buffers taken
+// every few minutes when their text changed, whether or not they were
+// saved (see the localhistory option). The versions of a file are stored
+// in a directory of StateDir/history named after the file, each one in a
+// file named after the hash of its text, so that a text which comes back
+// is stored once. The index of the directory lists the versions, one per
+// line with the time it was taken and its hash, from the oldest.
+
+// maxHistoryVersions is the number of versions kept per file, above which
+// the oldest ones are removed
+const maxHistoryVersions = 100
+
+// historyIndex is the name of the index of the versions of a file
+const historyIndex = "index"
+
+// A HistoryVersion is a version of a file kept in the local history
+type HistoryVersion struct {
+	Time time.Time
+	Hash string
+}
+
+// historyDir returns the directory of the versions of the file at path
+func historyDir(path string) string {
+	return filepath.Join(config.StateDir, "history", util.EscapePath(path))
+}
+
+// readHistory returns the versions listed in the index of the directory,
+// from the oldest
+func readHistory(dir string) ([]HistoryVersion, error) {
+	f, err := os.Open(filepath.Join(dir, historyIndex))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var versions []HistoryVersion
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		nsec, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, HistoryVersion{Time: time.Unix(0, nsec), Hash: fields[1]})
+	}
+	return versions, scanner.Err()
+}
+
+// writeHistory writes the index of the directory listing the versions
+func writeHistory(dir string, versions []HistoryVersion) error {
+	var sb strings.Builder
+	for _, v := range versions {
+		fmt.Fprintf(&sb, "%d %s\n", v.Time.UnixNano(), v.Hash)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, historyIndex), []byte(sb.String()), 0600)
+}
+
+// History returns the versions of the file at path kept in the local
+// history, the most recent first
+func History(path string) ([]HistoryVersion, error) {
+	versions, err := readHistory(historyDir(path))
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions, err
+}
+
+// HistoryText returns the text of a version of the file at path
+func HistoryText(path string, v HistoryVersion) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(historyDir(path), filepath.Base(v.Hash)))
+	if os.IsNotExist(err) {
+		return nil, errors.New("The version of " + v.Time.Format("Jan _2 15:04") + " is lost")
+	}
+	return data, err
+}
+
+// AddHistory adds the text as the version of the file at path taken at the
+// given time, unless it is the text of the most recent version, and
+// returns whether it did. The oldest versions above maxHistoryVersions are
+// removed.
+func AddHistory(path string, text []byte, t time.Time) (bool, error) {
+	dir := historyDir(path)
+	versions, err := readHistory(dir)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(text)
+	hash := hex.EncodeToString(sum[:])
+	if len(versions) > 0 && versions[len(versions)-1].Hash == hash {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return false, err
+	}
+	object := filepath.Join(dir, hash)
+	if _, err := os.Stat(object); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(object, text, 0600); err != nil {
+			return false, err
+		}
+	}
+	versions = append(versions, HistoryVersion{Time: t, Hash: hash})
+
+	var removed []HistoryVersion
+	if len(versions) > maxHistoryVersions {
+		removed = versions[:len(versions)-maxHistoryVersions]
+		versions = versions[len(versions)-maxHistoryVersions:]
+	}
+	if err := writeHistory(dir, versions); err != nil {
+		return false, err
+	}
+	kept := make(map[string]bool)
+	for _, v := range versions {
+		kept[v.Hash] = true
+	}
+	for _, v := range removed {
+		if !kept[v.Hash] {
+			os.Remove(filepath.Join(dir, v.Hash))
+		}
+	}
+	return true, nil
+}
+
+// SnapshotHistory adds the text of the buffer to the local history of its
+// file if it changed since it was last added. The file as it was on disk
+// is added before the first change, so that the text the buffer was opened
+// with can be restored.
+func (b *Buffer) SnapshotHistory() error {
+	if b.Path == "" || b.Type != BTDefault || config.StateDir == "" || b.hash.gen == b.historyGen {
+		return nil
+	}
+	if !b.historyDisk {
+		b.historyDisk = true
+		if info, err := os.Stat(b.Path); err == nil {
+			if data, err := b.DiskBytes(); err == nil {
+				if _, err := AddHistory(b.AbsPath, data, info.ModTime()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	b.historyGen = b.hash.gen
+	_, err := AddHistory(b.AbsPath, b.Bytes(), time.Now())
+	return err
+}
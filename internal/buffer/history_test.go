@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddHistory(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "notes.txt")
+	start := time.Unix(1000, 0)
+	added, err := AddHistory(path, []byte("one"), start)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	// the text of the most recent version is not added again
+	added, err = AddHistory(path, []byte("one"), start.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, added)
+	_, err = AddHistory(path, []byte("two"), start.Add(2*time.Minute))
+	assert.NoError(t, err)
+	_, err = AddHistory(path, []byte("one"), start.Add(3*time.Minute))
+	assert.NoError(t, err)
+
+	versions, err := History(path)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 3)
+	assert.True(t, versions[0].Time.Equal(start.Add(3*time.Minute)))
+	// a text which comes back is stored once
+	assert.Equal(t, versions[0].Hash, versions[2].Hash)
+	files, _ := ioutil.ReadDir(historyDir(path))
+	assert.Len(t, files, 3)
+
+	text, err := HistoryText(path, versions[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(text))
+
+	// the oldest versions are removed with the texts no other one has
+	for i := 0; i < maxHistoryVersions; i++ {
+		_, err = AddHistory(path, []byte(strconv.Itoa(i)), start.Add(time.Duration(4+i)*time.Minute))
+		assert.NoError(t, err)
+	}
+	versions, _ = History(path)
+	assert.Len(t, versions, maxHistoryVersions)
+	_, err = HistoryText(path, HistoryVersion{Hash: versions[0].Hash})
+	assert.NoError(t, err)
+	files, _ = ioutil.ReadDir(historyDir(path))
+	assert.Len(t, files, maxHistoryVersions+1)
+}
+
+func TestSnapshotHistory(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "file.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("saved\n"), 0644))
+	b, err := NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	defer b.Close()
+
+	// an unchanged buffer is not added
+	assert.NoError(t, b.SnapshotHistory())
+	versions, _ := History(b.AbsPath)
+	assert.Len(t, versions, 0)
+
+	// the file on disk is added before the first change
+	b.Insert(b.Start(), "edited ")
+	assert.NoError(t, b.SnapshotHistory())
+	versions, _ = History(b.AbsPath)
+	assert.Len(t, versions, 2)
+	text, _ := HistoryText(b.AbsPath, versions[1])
+	assert.Equal(t, "saved\n", string(text))
+	text, _ = HistoryText(b.AbsPath, versions[0])
+	assert.Equal(t, "edited saved\n", string(text))
+
+	assert.NoError(t, b.SnapshotHistory())
+	versions, _ = History(b.AbsPath)
+	assert.Len(t, versions, 2)
+}
@@ -0,0 +1,206 @@
+package buffer
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/bigfile"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// hugeWindow is the number of lines of a huge file which the buffer of the
+// viewer holds
+const hugeWindow = 3000
+
+// hugeMargin is the number of lines between the view and an end of the
+// buffer of the viewer from which it is moved along the file
+const hugeMargin = 1000
+
+// maxHugeLine is the number of bytes from which the long lines of a huge
+// file are cut in the viewer
+const maxHugeLine = 1 << 16
+
+// A HugeFile is a file above the size of the hugefile option, which the
+// viewer maps in memory and shows by parts rather than reading it. Top is
+// the line of the file on the first line of the buffer of the viewer.
+type HugeFile struct {
+	*bigfile.File
+	Path string
+	Top  int
+
+	// atEnd is whether the buffer holds the last line of the file
+	atEnd bool
+}
+
+// isHuge returns whether a file of this size is opened in the viewer,
+// according to the hugefile option in megabytes
+func isHuge(size int64) bool {
+	mb := config.GetGlobalOption("hugefile").(float64)
+	return mb > 0 && float64(size) >= mb*1024*1024
+}
+
+// NewHugeFileBuffer opens the file at path in the viewer of huge files,
+// read-only, with the cursor at loc
+func NewHugeFileBuffer(path string, loc Loc) (*Buffer, error) {
+	f, err := bigfile.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	b := NewBufferFromString("", "", BTView)
+	b.Huge = &HugeFile{File: f, Path: path}
+	b.SetName(path)
+	b.Settings["viewmode"] = true
+
+	b.showHugeLines(loc.Y - hugeWindow/2)
+	c := b.GetActiveCursor()
+	c.GotoLoc(Loc{util.Max(loc.X, 0), util.Clamp(loc.Y-b.Huge.Top, 0, b.LinesNum()-1)})
+	c.Relocate()
+	if prompt != nil {
+		prompt.Message("Huge file opened read-only in the viewer, 'unlock' loads it to edit it")
+	}
+	return b, nil
+}
+
+// showHugeLines replaces the lines of the buffer of the viewer with those
+// of the file from line top, moving the cursors along with their text
+func (b *Buffer) showHugeLines(top int) {
+	h := b.Huge
+	top = util.Max(top, 0)
+	lines := h.Lines(top, hugeWindow)
+	if len(lines) < hugeWindow && top > 0 {
+		// the buffer ends with the end of the file
+		top = util.Max(h.IndexAll()-hugeWindow, 0)
+		lines = h.Lines(top, hugeWindow)
+	}
+	_, more := h.Offset(top + len(lines))
+	h.atEnd = !more
+
+	var sb strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		if len(l) > maxHugeLine {
+			l = l[:maxHugeLine]
+		}
+		sb.Write(l)
+	}
+
+	delta := h.Top - top
+	type cursorLocs struct{ loc, cur0, cur1, orig0, orig1 Loc }
+	var locs []cursorLocs
+	for _, c := range b.cursors {
+		locs = append(locs, cursorLocs{c.Loc, c.CurSelection[0], c.CurSelection[1], c.OrigSelection[0], c.OrigSelection[1]})
+	}
+	b.EventHandler.Replace(b.Start(), b.End(), sb.String())
+	b.UndoStack = new(TEStack)
+	b.RedoStack = new(TEStack)
+	h.Top = top
+
+	move := func(l Loc) Loc {
+		return clamp(Loc{l.X, l.Y + delta}, b.LineArray)
+	}
+	for i, c := range b.cursors {
+		c.Loc = move(locs[i].loc)
+		c.CurSelection = [2]Loc{move(locs[i].cur0), move(locs[i].cur1)}
+		c.OrigSelection = [2]Loc{move(locs[i].orig0), move(locs[i].orig1)}
+	}
+}
+
+// FileLine returns the line of the file shown on line y of the buffer, which
+// is y unless the buffer is the viewer of a huge file
+func (b *Buffer) FileLine(y int) int {
+	if b.Huge != nil {
+		return b.Huge.Top + y
+	}
+	return y
+}
+
+// FileLinesNum returns the number of lines of the file shown by the buffer.
+// The whole file of the viewer is indexed to count them.
+func (b *Buffer) FileLinesNum() int {
+	if b.Huge != nil {
+		return b.Huge.IndexAll()
+	}
+	return b.LinesNum()
+}
+
+// HugeLine returns the line of the buffer showing line n of the huge file,
+// after moving the buffer along the file when it doesn't hold it
+func (b *Buffer) HugeLine(n int) int {
+	h := b.Huge
+	if n < h.Top || n >= h.Top+b.LinesNum() {
+		b.showHugeLines(n - hugeWindow/2)
+	}
+	return util.Clamp(n-h.Top, 0, b.LinesNum()-1)
+}
+
+// FollowView moves the buffer of the viewer along the huge file when the
+// view, showing height lines from line y, gets near one of its ends. It
+// returns by how many lines the text of the buffer moved down.
+func (b *Buffer) FollowView(y, height int) int {
+	h := b.Huge
+	if h == nil {
+		return 0
+	}
+	nearTop := y < hugeMargin && h.Top > 0
+	nearEnd := y+height > b.LinesNum()-hugeMargin && !h.atEnd
+	if !nearTop && !nearEnd {
+		return 0
+	}
+	top := h.Top
+	b.showHugeLines(h.Top + y + height/2 - hugeWindow/2)
+	return top - h.Top
+}
+
+// hugeOffset returns the offset in the huge file of a location of the buffer
+func (b *Buffer) hugeOffset(l Loc) int {
+	off, _ := b.Huge.Offset(b.FileLine(l.Y))
+	return off + len(util.SliceStart(b.LineBytes(l.Y), l.X))
+}
+
+// hugeLoc returns the location in the buffer of an offset in the huge file,
+// moving the buffer along the file to hold it
+func (b *Buffer) hugeLoc(off int) Loc {
+	n, start := b.Huge.LineAt(off)
+	y := b.HugeLine(n)
+	l := b.LineBytes(y)
+	return Loc{util.RunePos(l, util.Min(off-start, len(l))), y}
+}
+
+// findHuge searches the whole huge file, from the offset of from and
+// around its end, and shows the match found in the buffer
+func (b *Buffer) findHuge(r util.Regexp, from Loc, down bool) ([2]Loc, bool) {
+	s, e, found := b.Huge.Search(r, b.hugeOffset(from), down)
+	if !found {
+		wrap := 0
+		if !down {
+			wrap = b.Huge.Size()
+		}
+		s, e, found = b.Huge.Search(r, wrap, down)
+	}
+	if !found {
+		return [2]Loc{}, false
+	}
+	start := b.hugeLoc(s)
+	return [2]Loc{start, b.hugeLoc(e)}, true
+}
+
+// LoadHugeFile reads the whole file of the viewer in a new buffer which can
+// be edited, with the cursor on the same character
+func (b *Buffer) LoadHugeFile() (*Buffer, error) {
+	f, err := os.Open(b.Huge.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := b.GetActiveCursor()
+	loc := Loc{c.X, b.FileLine(c.Y)}
+	nb := NewBuffer(bufio.NewReader(f), util.FSize(f), b.Huge.Path, loc, BTDefault)
+	if nb.Settings["detectindent"].(bool) {
+		nb.detectIndentSettings()
+	}
+	return nb, nil
+}
@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+func TestHugeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-hugefile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func(v interface{}) { config.GlobalSettings["hugefile"] = v }(config.GlobalSettings["hugefile"])
+	config.GlobalSettings["hugefile"] = 0.01
+
+	var sb strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	path := filepath.Join(dir, "huge.log")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(sb.String()), 0644))
+
+	b, err := NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	defer b.Close()
+	assert.NotNil(t, b.Huge)
+	assert.True(t, b.Type.Readonly)
+	assert.Equal(t, hugeWindow, b.LinesNum())
+	assert.Equal(t, "line 0", b.Line(0))
+
+	y := b.HugeLine(8000)
+	assert.Equal(t, "line 8000", b.Line(y))
+	assert.Equal(t, 8000, b.FileLine(y))
+	// the window ends with the file
+	y = b.HugeLine(9990)
+	assert.Equal(t, "line 9990", b.Line(y))
+	assert.Equal(t, 10001, b.FileLine(b.LinesNum()-1)+1)
+	b.HugeLine(8000)
+
+	// the buffer moves along the file when the view nears its start
+	b.GetActiveCursor().GotoLoc(Loc{2, 10})
+	top := b.Huge.Top
+	delta := b.FollowView(10, 20)
+	assert.True(t, delta > 0)
+	assert.Equal(t, top-delta, b.Huge.Top)
+	c := b.GetActiveCursor()
+	assert.Equal(t, Loc{2, 10 + delta}, c.Loc)
+	assert.Equal(t, fmt.Sprintf("line %d", top+10), b.Line(c.Y))
+	assert.Equal(t, 0, b.FollowView(10+delta, 20))
+
+	// the whole file is searched
+	match, found, err := b.FindNext("line 9500$", b.Start(), b.End(), c.Loc, true, true)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 9500, b.FileLine(match[0].Y))
+	assert.Equal(t, Loc{9, match[0].Y}, match[1])
+	match, found, _ = b.FindNext("line 12$", b.Start(), b.End(), match[0], true, true)
+	assert.True(t, found)
+	assert.Equal(t, 12, b.FileLine(match[0].Y))
+	assert.Equal(t, 10001, b.FileLinesNum())
+
+	c.GotoLoc(match[0])
+	nb, err := b.LoadHugeFile()
+	assert.NoError(t, err)
+	defer nb.Close()
+	assert.Nil(t, nb.Huge)
+	assert.Equal(t, 10001, nb.LinesNum())
+	assert.Equal(t, Loc{0, 12}, nb.GetActiveCursor().Loc)
+}
@@ -0,0 +1,100 @@
+package buffer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// indentRules returns the patterns of the lines changing the indentation of
+// the buffer: the indentpattern and dedentpattern options, or the indent
+// patterns of its syntax file for the options which are empty
+func (b *Buffer) indentRules() highlight.IndentRules {
+	var rules highlight.IndentRules
+	if b.SyntaxDef != nil {
+		rules = b.SyntaxDef.Indent
+	}
+	if p, ok := b.Settings["indentpattern"].(string); ok && p != "" {
+		if re, err := regexp.Compile(p); err == nil {
+			rules.Increase = re
+		}
+	}
+	if p, ok := b.Settings["dedentpattern"].(string); ok && p != "" {
+		if re, err := regexp.Compile(p); err == nil {
+			rules.Decrease = re
+		}
+	}
+	return rules
+}
+
+// HasIndentRules returns whether the indentation of the buffer is computed
+// from the lines changing it rather than copied from the previous line
+func (b *Buffer) HasIndentRules() bool {
+	rules := b.indentRules()
+	return rules.Increase != nil || rules.Decrease != nil
+}
+
+// IncreasesIndent returns whether the lines after line y are indented one
+// level more than it
+func (b *Buffer) IncreasesIndent(y int) bool {
+	re := b.indentRules().Increase
+	return re != nil && re.Match(b.LineBytes(y))
+}
+
+// DecreasesIndent returns whether line y is indented one level less than
+// the lines before it
+func (b *Buffer) DecreasesIndent(y int) bool {
+	re := b.indentRules().Decrease
+	return re != nil && re.Match(b.LineBytes(y))
+}
+
+// LineIndent returns the indentation of line y according to the indent
+// rules: the indentation of the previous line which is not blank, one level
+// more if it increases the indentation and one level less if line y
+// decreases it
+func (b *Buffer) LineIndent(y int) string {
+	p := y - 1
+	for p >= 0 && util.IsSpacesOrTabs(b.LineBytes(p)) {
+		p--
+	}
+	if p < 0 {
+		return ""
+	}
+
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	indent := string(util.GetLeadingWhitespace(b.LineBytes(p)))
+	if b.IncreasesIndent(p) {
+		indent += b.IndentString(tabsize)
+	}
+	if b.DecreasesIndent(y) {
+		unit := b.IndentString(tabsize)
+		switch {
+		case strings.HasSuffix(indent, unit):
+			indent = indent[:len(indent)-len(unit)]
+		case strings.HasSuffix(indent, "\t"):
+			indent = indent[:len(indent)-1]
+		default:
+			trimmed := strings.TrimRight(indent, " ")
+			indent = indent[:util.Max(len(trimmed), len(indent)-tabsize)]
+		}
+	}
+	return indent
+}
+
+// Reindent replaces the indentation of the lines from start to end by the
+// one given by the indent rules. The blank lines are emptied.
+func (b *Buffer) Reindent(start, end int) {
+	for y := start; y <= end && y < b.LinesNum(); y++ {
+		line := b.LineBytes(y)
+		ws := util.GetLeadingWhitespace(line)
+		indent := ""
+		if !util.IsSpacesOrTabs(line) {
+			indent = b.LineIndent(y)
+		}
+		if string(ws) != indent {
+			b.Replace(Loc{X: 0, Y: y}, Loc{X: util.CharacterCount(ws), Y: y}, indent)
+		}
+	}
+}
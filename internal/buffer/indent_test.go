@@ -0,0 +1,99 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+const indentSyntax = `filetype: test
+
+detect:
+    filename: "\\.test$"
+
+indent:
+    increase: "[{(]\\s*$"
+    decrease: "^\\s*[})]"
+
+rules: []
+`
+
+func indentedBuffer(t *testing.T, text string) *Buffer {
+	return syntaxBuffer(t, []byte(indentSyntax), text)
+}
+
+func syntaxBuffer(t *testing.T, syntax []byte, text string) *Buffer {
+	f, err := highlight.ParseFile(syntax)
+	assert.Nil(t, err)
+	header, err := highlight.MakeHeaderYaml(syntax)
+	assert.Nil(t, err)
+	def, err := highlight.ParseDef(f, header)
+	assert.Nil(t, err)
+
+	b := NewBufferFromString(text, "", BTDefault)
+	b.SyntaxDef = def
+	b.Highlighter = highlight.NewHighlighter(def)
+	b.Highlighter.HighlightStates(b)
+	return b
+}
+
+func TestLineIndent(t *testing.T) {
+	b := indentedBuffer(t, "func() {\n\tx\n\n}\n  if {\n  }")
+	defer b.Close()
+	assert.True(t, b.HasIndentRules())
+	assert.True(t, b.IncreasesIndent(0))
+	assert.False(t, b.IncreasesIndent(1))
+	assert.True(t, b.DecreasesIndent(3))
+
+	assert.Equal(t, "\t", b.LineIndent(1))
+	assert.Equal(t, "\t", b.LineIndent(2))
+	assert.Equal(t, "", b.LineIndent(3))
+	assert.Equal(t, "", b.LineIndent(0))
+
+	b.SetOptionNative("tabstospaces", true)
+	b.SetOptionNative("tabsize", float64(2))
+	assert.Equal(t, "  ", b.LineIndent(5))
+	b.SetOptionNative("tabsize", float64(4))
+	assert.Equal(t, "  ", b.LineIndent(5))
+}
+
+func TestBuiltinIndentRules(t *testing.T) {
+	// the indent rules of the syntax files are embedded in the binary
+	syntax, err := config.Asset("runtime/syntax/go.yaml")
+	assert.Nil(t, err)
+	b := syntaxBuffer(t, syntax, "func f() {\nx\n}")
+	defer b.Close()
+	assert.True(t, b.HasIndentRules())
+	b.Reindent(0, 2)
+	assert.Equal(t, "func f() {\n\tx\n}", string(b.Bytes()))
+}
+
+func TestReindent(t *testing.T) {
+	b := indentedBuffer(t, "if {\nx\n  \n    if (\n  y\n   )\n        }\nz")
+	defer b.Close()
+	b.Reindent(0, b.LinesNum()-1)
+	assert.Equal(t, "if {\n\tx\n\n\tif (\n\t\ty\n\t)\n}\nz", string(b.Bytes()))
+}
+
+func TestIndentOptions(t *testing.T) {
+	b := NewBufferFromString("def f():\n  pass\nelse:", "", BTDefault)
+	defer b.Close()
+	assert.False(t, b.HasIndentRules())
+
+	b.SetOptionNative("indentpattern", ":$")
+	b.SetOptionNative("dedentpattern", "^\\s*else")
+	b.SetOptionNative("tabstospaces", true)
+	b.SetOptionNative("tabsize", float64(2))
+	assert.True(t, b.HasIndentRules())
+	b.Reindent(0, 2)
+	assert.Equal(t, "def f():\n  pass\nelse:", string(b.Bytes()))
+
+	// the options take precedence over the syntax file
+	b = indentedBuffer(t, "a:\nb")
+	defer b.Close()
+	b.SetOptionNative("indentpattern", ":$")
+	assert.True(t, b.IncreasesIndent(0))
+	assert.Equal(t, "\t", b.LineIndent(1))
+}
@@ -41,6 +41,11 @@ type Line struct {
 	match       highlight.LineMatch
 	rehighlight bool
 	lock        sync.Mutex
+
+	// sum is the checksum of data when summed is true, which is reset when
+	// the line changes, see lineSum
+	sum    uint64
+	summed bool
 }
 
 const (
@@ -58,6 +63,9 @@ type LineArray struct {
 	lines    []Line
 	Endings  FileFormat
 	initsize uint64
+
+	// stats are the counts of the lines, nil until they are asked
+	stats *lineStats
 }
 
 // Append efficiently appends lines together
@@ -170,48 +178,63 @@ func (la *LineArray) Bytes() []byte {
 	return b.Bytes()
 }
 
-// newlineBelow adds a newline below the given line number
-func (la *LineArray) newlineBelow(y int) {
-	la.lines = append(la.lines, Line{
-		data:        []byte{' '},
-		state:       nil,
-		match:       nil,
-		rehighlight: false,
-	})
-	copy(la.lines[y+2:], la.lines[y+1:])
-	la.lines[y+1] = Line{
-		data:        []byte{},
-		state:       la.lines[y].state,
-		match:       nil,
-		rehighlight: false,
-	}
-}
-
-// Inserts a byte array at a given location
+// Inserts a byte array at a given location. The lines of the text are
+// added all at once, so that inserting a large paste takes a time
+// proportional to its size rather than to its size times the number of lines
 func (la *LineArray) insert(pos Loc, value []byte) {
 	x, y := runeToByteIndex(pos.X, la.lines[pos.Y].data), pos.Y
-	for i := 0; i < len(value); i++ {
-		if value[i] == '\n' || (value[i] == '\r' && i < len(value)-1 && value[i+1] == '\n') {
-			la.split(Loc{x, y})
-			x = 0
-			y++
-
-			if value[i] == '\r' {
-				i++
-			}
 
-			continue
+	// the lines of the text, without their line endings
+	var parts [][]byte
+	for {
+		i := bytes.IndexByte(value, '\n')
+		if i < 0 {
+			parts = append(parts, value)
+			break
+		}
+		part := value[:i]
+		if len(part) > 0 && part[len(part)-1] == '\r' {
+			part = part[:len(part)-1]
 		}
-		la.insertByte(Loc{x, y}, value[i])
-		x++
+		parts = append(parts, part)
+		value = value[i+1:]
+	}
+
+	line := &la.lines[y]
+	line.summed = false
+	if len(parts) == 1 {
+		n := len(value)
+		line.data = append(line.data, value...)
+		copy(line.data[x+n:], line.data[x:])
+		copy(line.data[x:], value)
+		return
 	}
-}
 
-// InsertByte inserts a byte at a given location
-func (la *LineArray) insertByte(pos Loc, value byte) {
-	la.lines[pos.Y].data = append(la.lines[pos.Y].data, 0)
-	copy(la.lines[pos.Y].data[pos.X+1:], la.lines[pos.Y].data[pos.X:])
-	la.lines[pos.Y].data[pos.X] = value
+	// the text after the position goes to the end of the last line, which
+	// takes the highlighting state of the line
+	n := len(parts) - 1
+	tail := append([]byte{}, line.data[x:]...)
+	line.data = append(line.data[:x], parts[0]...)
+	state := line.state
+	line.state = nil
+	line.match = nil
+	line.rehighlight = true
+
+	la.lines = append(la.lines, make([]Line, n)...)
+	copy(la.lines[y+1+n:], la.lines[y+1:])
+	for i := 1; i <= n; i++ {
+		data := append([]byte{}, parts[i]...)
+		if i == n {
+			data = append(data, tail...)
+		}
+		la.lines[y+i] = Line{
+			data:        data,
+			state:       nil,
+			match:       nil,
+			rehighlight: i < n,
+		}
+	}
+	la.lines[y+n].state = state
 }
 
 // joinLines joins the two lines a and b
@@ -220,24 +243,13 @@ func (la *LineArray) joinLines(a, b int) {
 	la.deleteLine(b)
 }
 
-// split splits a line at a given position
-func (la *LineArray) split(pos Loc) {
-	la.newlineBelow(pos.Y)
-	la.insert(Loc{0, pos.Y + 1}, la.lines[pos.Y].data[pos.X:])
-	la.lines[pos.Y+1].state = la.lines[pos.Y].state
-	la.lines[pos.Y].state = nil
-	la.lines[pos.Y].match = nil
-	la.lines[pos.Y+1].match = nil
-	la.lines[pos.Y].rehighlight = true
-	la.deleteToEnd(Loc{pos.X, pos.Y})
-}
-
 // removes from start to end
 func (la *LineArray) remove(start, end Loc) []byte {
 	sub := la.Substr(start, end)
 	startX := runeToByteIndex(start.X, la.lines[start.Y].data)
 	endX := runeToByteIndex(end.X, la.lines[end.Y].data)
 	if start.Y == end.Y {
+		la.lines[start.Y].summed = false
 		la.lines[start.Y].data = append(la.lines[start.Y].data[:startX], la.lines[start.Y].data[endX:]...)
 	} else {
 		la.deleteLines(start.Y+1, end.Y-1)
@@ -251,11 +263,13 @@ func (la *LineArray) remove(start, end Loc) []byte {
 // deleteToEnd deletes from the end of a line to the position
 func (la *LineArray) deleteToEnd(pos Loc) {
 	la.lines[pos.Y].data = la.lines[pos.Y].data[:pos.X]
+	la.lines[pos.Y].summed = false
 }
 
 // deleteFromStart deletes from the start of a line to the position
 func (la *LineArray) deleteFromStart(pos Loc) {
 	la.lines[pos.Y].data = la.lines[pos.Y].data[pos.X+1:]
+	la.lines[pos.Y].summed = false
 }
 
 // deleteLine deletes the line number
@@ -270,6 +284,7 @@ func (la *LineArray) deleteLines(y1, y2 int) {
 // DeleteByte deletes the byte at a position
 func (la *LineArray) deleteByte(pos Loc) {
 	la.lines[pos.Y].data = la.lines[pos.Y].data[:pos.X+copy(la.lines[pos.Y].data[pos.X:], la.lines[pos.Y].data[pos.X+1:])]
+	la.lines[pos.Y].summed = false
 }
 
 // Substr returns the string representation between two locations
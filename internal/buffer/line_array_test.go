@@ -59,3 +59,13 @@ func TestRemove(t *testing.T) {
 	bytes := la.Bytes()
 	assert.Equal(t, unicode_txt, string(bytes))
 }
+
+func TestInsertLines(t *testing.T) {
+	la := NewLineArray(0, FFAuto, strings.NewReader("abc\ndef"))
+	la.insert(Loc{1, 0}, []byte("1\r\n2\n\n3\r"))
+	assert.Equal(t, "a1\n2\n\n3\rbc\ndef", string(la.Bytes()))
+	assert.Equal(t, 5, la.LinesNum())
+	for i, rehighlight := range []bool{true, true, true, false, false} {
+		assert.Equal(t, rehighlight, la.lines[i].rehighlight)
+	}
+}
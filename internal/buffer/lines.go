@@ -0,0 +1,79 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// JoinLines joins the lines from start to end into one, as a single undo
+// step, and returns the location of the last join. The whitespace around
+// each join is collapsed into a single space, which is left out next to a
+// bracket, and the comment marker of a line joined to a comment is
+// removed. A blank line is joined by removing it.
+func (b *Buffer) JoinLines(start, end int) Loc {
+	end = util.Min(end, b.LinesNum()-1)
+	loc := Loc{X: 0, Y: start}
+	marker := b.CommentMarkers().Line
+	b.UndoGroup(func() {
+		for y := start; y < end; y++ {
+			cur, next := b.LineBytes(start), b.LineBytes(start+1)
+			if util.IsSpacesOrTabs(cur) {
+				b.Remove(Loc{X: 0, Y: start}, Loc{X: 0, Y: start + 1})
+				loc = Loc{X: 0, Y: start}
+				continue
+			}
+
+			left := bytes.TrimRightFunc(cur, unicode.IsSpace)
+			ws := util.GetLeadingWhitespace(next)
+			rest := next[len(ws):]
+			skip := util.CharacterCount(ws)
+			if marker != "" && bytes.HasPrefix(bytes.TrimLeftFunc(cur, unicode.IsSpace), []byte(marker)) &&
+				bytes.HasPrefix(rest, []byte(marker)) {
+				rest = rest[len(marker):]
+				skip += util.CharacterCountInString(marker)
+				trimmed := bytes.TrimLeftFunc(rest, unicode.IsSpace)
+				skip += len(rest) - len(trimmed)
+				rest = trimmed
+			}
+
+			sep := " "
+			last, _ := utf8.DecodeLastRune(left)
+			if len(rest) == 0 || strings.ContainsRune("([{", last) || strings.ContainsRune(")]}", rune(rest[0])) {
+				sep = ""
+			}
+			loc = Loc{X: util.CharacterCount(left), Y: start}
+			b.Replace(loc, Loc{X: skip, Y: start + 1}, sep)
+		}
+	})
+	return loc
+}
+
+// ReindentBlock shifts the indentation of the lines from start to end so
+// that the first one which isn't blank gets the indentation given by the
+// indent rules, the indentation of the others staying relative to it
+func (b *Buffer) ReindentBlock(start, end int) {
+	first := start
+	for first <= end && util.IsSpacesOrTabs(b.LineBytes(first)) {
+		first++
+	}
+	if first > end {
+		return
+	}
+	cur := string(util.GetLeadingWhitespace(b.LineBytes(first)))
+	target := b.LineIndent(first)
+	if cur == target {
+		return
+	}
+	for y := first; y <= end; y++ {
+		line := b.LineBytes(y)
+		ws := string(util.GetLeadingWhitespace(line))
+		if util.IsSpacesOrTabs(line) || !strings.HasPrefix(ws, cur) {
+			continue
+		}
+		b.Replace(Loc{X: 0, Y: y}, Loc{X: util.CharacterCountInString(ws), Y: y}, target+ws[len(cur):])
+	}
+}
@@ -0,0 +1,46 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinLines(t *testing.T) {
+	tests := []struct {
+		text       string
+		start, end int
+		out        string
+		x          int
+	}{
+		{"foo  \n    bar\nbaz", 0, 1, "foo bar\nbaz", 3},
+		{"a\n  b\n\tc", 0, 2, "a b c", 3},
+		{"call(\n    x,\n    y\n)", 0, 3, "call(x, y)", 9},
+		{"\n    x", 0, 1, "    x", 0},
+		{"x\n", 0, 1, "x", 1},
+		{"# one\n  # two", 0, 1, "# one two", 5},
+		{"x = 1\n# note", 0, 1, "x = 1 # note", 5},
+	}
+	for _, tt := range tests {
+		b := NewBufferFromString(tt.text, "", BTDefault)
+		loc := b.JoinLines(tt.start, tt.end)
+		assert.Equal(t, tt.out, string(b.Bytes()), tt.text)
+		assert.Equal(t, Loc{X: tt.x, Y: tt.start}, loc, tt.text)
+
+		// the join is undone at once
+		b.Undo()
+		assert.Equal(t, tt.text, string(b.Bytes()), tt.text)
+		b.Close()
+	}
+}
+
+func TestReindentBlock(t *testing.T) {
+	// a block moved into the if takes its indentation, relatively
+	b := indentedBuffer(t, "if {\n\tx\n    y (\n        z\n\n    )\n}")
+	defer b.Close()
+	b.ReindentBlock(2, 5)
+	assert.Equal(t, "if {\n\tx\n\ty (\n\t    z\n\n\t)\n}", string(b.Bytes()))
+
+	b.ReindentBlock(0, 1)
+	assert.Equal(t, "if {\n\tx\n\ty (\n\t    z\n\n\t)\n}", string(b.Bytes()))
+}
@@ -0,0 +1,142 @@
+package buffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+const lockMsg = `This file is being edited by another instance of micro, %s,
+since %s. Saving it from both would lose the changes of one of them.
+
+The file is
+
+%s
+
+* 'readonly' will open the file readonly.
+* 'takeover' will take the lock of the file: the other instance will be
+  unable to save it.
+* 'edit' will open the file without locking it.
+
+Options: [r]eadonly, [t]akeover, [e]dit: `
+
+// A fileLock tells which micro instance edits a file, see the filelock
+// option. It is stored in StateDir/locks as the pid and the host name of
+// the instance.
+type fileLock struct {
+	pid  int
+	host string
+}
+
+func (l fileLock) String() string {
+	return fmt.Sprintf("pid %d on %s", l.pid, l.host)
+}
+
+// ours returns whether the lock belongs to this instance
+func (l fileLock) ours() bool {
+	host, _ := os.Hostname()
+	return l.pid == os.Getpid() && l.host == host
+}
+
+// stale returns whether the instance holding the lock has exited. An
+// instance on another host is never known to have exited.
+func (l fileLock) stale() bool {
+	host, _ := os.Hostname()
+	return l.host == host && !processAlive(l.pid)
+}
+
+// lockPath returns the lock file of the file at path
+func lockPath(path string) string {
+	return filepath.Join(config.StateDir, "locks", util.EscapePath(path))
+}
+
+// readLock returns the lock of the file at path, if it is locked
+func readLock(path string) (fileLock, bool) {
+	data, err := ioutil.ReadFile(lockPath(path))
+	if err != nil {
+		return fileLock{}, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return fileLock{}, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return fileLock{}, false
+	}
+	return fileLock{pid, fields[1]}, true
+}
+
+// writeLock locks the file at path for this instance
+func writeLock(path string) error {
+	dir := filepath.Join(config.StateDir, "locks")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	return ioutil.WriteFile(lockPath(path), []byte(fmt.Sprintf("%d %s\n", os.Getpid(), host)), 0644)
+}
+
+// lock takes the lock of the file of the buffer when it is opened with the
+// filelock option. If another instance holds it, the user chooses between
+// opening the file readonly, taking the lock over and editing it without the
+// lock.
+func (b *Buffer) lock() {
+	if config.StateDir == "" || screen.Screen == nil {
+		return
+	}
+	if l, ok := readLock(b.AbsPath); ok && !l.ours() && !l.stale() {
+		info, err := os.Stat(lockPath(b.AbsPath))
+		if err != nil {
+			return
+		}
+		since := info.ModTime().Format("Mon Jan _2 at 15:04, 2006")
+		msg := fmt.Sprintf(lockMsg, l, since, b.AbsPath)
+		choice := screen.TermPrompt(msg, []string{"r", "t", "e", "readonly", "takeover", "edit"}, true)
+		switch choice % 3 {
+		case 0:
+			b.Settings["readonly"] = true
+			return
+		case 2:
+			b.Settings["filelock"] = false
+			return
+		}
+	}
+	if err := writeLock(b.AbsPath); err != nil {
+		screen.TermMessage("Error locking the file: ", err)
+		return
+	}
+	b.locked = true
+}
+
+// unlock removes the lock of the file of the buffer, unless another
+// instance took it over
+func (b *Buffer) unlock() {
+	if !b.locked {
+		return
+	}
+	b.locked = false
+	if l, ok := readLock(b.AbsPath); ok && l.ours() {
+		os.Remove(lockPath(b.AbsPath))
+	}
+}
+
+// checkLock returns an error if the file is saved while another instance
+// holds its lock
+func (b *Buffer) checkLock(filename string) error {
+	if !b.Settings["filelock"].(bool) || config.StateDir == "" {
+		return nil
+	}
+	abs, _ := filepath.Abs(filename)
+	if l, ok := readLock(abs); ok && !l.ours() && !l.stale() {
+		return fmt.Errorf("The file is locked by micro (%s), 'setlocal filelock off' saves it anyway", l)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// +build plan9 nacl windows
+
+package buffer
+
+// processAlive returns whether the process pid is running, which isn't
+// known on this system, so that a lock is only taken over by the user
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,11 @@
+// +build linux darwin dragonfly solaris openbsd netbsd freebsd
+
+package buffer
+
+import "syscall"
+
+// processAlive returns whether the process pid is running
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
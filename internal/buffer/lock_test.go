@@ -0,0 +1,50 @@
+package buffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLock(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "f.txt")
+	b := NewBufferFromString("text", path, BTDefault)
+	b.Settings["filelock"] = true
+	assert.NoError(t, b.checkLock(path))
+
+	assert.NoError(t, writeLock(path))
+	l, ok := readLock(path)
+	assert.True(t, ok)
+	assert.True(t, l.ours())
+	assert.NoError(t, b.checkLock(path))
+
+	// the lock of a running instance refuses the save, unlike the lock of an
+	// instance which exited
+	host, _ := os.Hostname()
+	other := fmt.Sprintf("%d %s\n", os.Getppid(), host)
+	assert.NoError(t, ioutil.WriteFile(lockPath(path), []byte(other), 0644))
+	assert.Error(t, b.Save())
+	b.Settings["filelock"] = false
+	assert.NoError(t, b.checkLock(path))
+	b.Settings["filelock"] = true
+
+	if !processAlive(1 << 30) {
+		stale := fmt.Sprintf("%d %s\n", 1<<30, host)
+		assert.NoError(t, ioutil.WriteFile(lockPath(path), []byte(stale), 0644))
+		assert.NoError(t, b.checkLock(path))
+	}
+
+	// a lock taken over by another instance is left to it
+	b.locked = true
+	assert.NoError(t, ioutil.WriteFile(lockPath(path), []byte(other), 0644))
+	b.unlock()
+	_, ok = readLock(path)
+	assert.True(t, ok)
+}
@@ -0,0 +1,295 @@
+package buffer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+var (
+	// fenceRegex matches the lines opening and closing fenced code blocks
+	// and captures the fence and the language
+	fenceRegex = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([^\\s`]*)")
+	ruleRegex  = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	// setextRegex matches the line underlining a heading
+	setextRegex = regexp.MustCompile(`^(=+|-+)\s*$`)
+	listRegex   = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?(.*)$`)
+	quoteRegex  = regexp.MustCompile(`^\s*>\s?(.*)$`)
+)
+
+// A Preview is a markdown document rendered for the terminal: the text of
+// its lines, their highlighting and the line of the document each one was
+// rendered from
+type Preview struct {
+	Lines   []string
+	Matches []highlight.LineMatch
+	Source  []int
+}
+
+// previewLine is a rendered line as it is built, with the highlight group
+// of each of its characters
+type previewLine struct {
+	text   []rune
+	groups []highlight.Group
+}
+
+func (l *previewLine) add(s string, g highlight.Group) {
+	for _, r := range s {
+		l.text = append(l.text, r)
+		l.groups = append(l.groups, g)
+	}
+}
+
+// match returns the highlighting of the line, which changes group where
+// the group of the characters changes
+func (l *previewLine) match() highlight.LineMatch {
+	m := make(highlight.LineMatch)
+	var last highlight.Group
+	for i, g := range l.groups {
+		if g != last {
+			m[i] = g
+			last = g
+		}
+	}
+	if last != 0 {
+		m[len(l.groups)] = 0
+	}
+	return m
+}
+
+// RenderMarkdown renders the lines of a markdown document: the markers of
+// headings, emphasis, code, links and quotes are replaced by highlighting,
+// list items get bullets and code blocks are indented and highlighted with
+// the syntax of their language. Width is the width of the rules.
+func RenderMarkdown(src []string, width int) *Preview {
+	p := new(Preview)
+	add := func(l *previewLine, from int) {
+		p.Lines = append(p.Lines, string(l.text))
+		p.Matches = append(p.Matches, l.match())
+		p.Source = append(p.Source, from)
+	}
+	rule := func(r string, from int) {
+		l := new(previewLine)
+		l.add(strings.Repeat(r, width), highlight.GetGroup("comment"))
+		add(l, from)
+	}
+
+	special := highlight.GetGroup("special")
+	var fence, lang string
+	var code []string
+	start := 0
+	endCode := func() {
+		for i, m := range highlightCode(lang, code) {
+			l := new(previewLine)
+			l.add("    ", 0)
+			for j, r := range []rune(code[i]) {
+				if g, ok := m[j]; ok {
+					l.add(string(r), g)
+				} else {
+					l.add(string(r), l.groups[len(l.groups)-1])
+				}
+			}
+			add(l, start+i)
+		}
+		code = nil
+	}
+
+	paragraph := false
+	for i, line := range src {
+		if fence != "" {
+			if m := fenceRegex.FindStringSubmatch(line); m != nil && m[2] == "" && strings.HasPrefix(m[1], fence) {
+				endCode()
+				fence = ""
+			} else {
+				code = append(code, line)
+			}
+			continue
+		}
+		if m := fenceRegex.FindStringSubmatch(line); m != nil {
+			fence, lang, start = m[1], m[2], i+1
+			paragraph = false
+			continue
+		}
+
+		// the previous line of text is a heading underlined by this one
+		if paragraph && setextRegex.MatchString(line) {
+			last := len(p.Lines) - 1
+			for k := range p.Matches[last] {
+				delete(p.Matches[last], k)
+			}
+			p.Matches[last][0] = special
+			r := "─"
+			if line[0] == '=' {
+				r = "═"
+			}
+			l := new(previewLine)
+			l.add(strings.Repeat(r, len([]rune(p.Lines[last]))), special)
+			add(l, i)
+			paragraph = false
+			continue
+		}
+		paragraph = false
+
+		l := new(previewLine)
+		switch {
+		case strings.TrimSpace(line) == "":
+		case ruleRegex.MatchString(line):
+			rule("─", i)
+			continue
+		case headerRegex.MatchString(line):
+			m := headerRegex.FindStringSubmatch(line)
+			renderInline(l, m[2], special)
+			add(l, i)
+			switch len(m[1]) {
+			case 1:
+				rule("═", i)
+			case 2:
+				rule("─", i)
+			}
+			continue
+		case quoteRegex.MatchString(line):
+			m := quoteRegex.FindStringSubmatch(line)
+			l.add("│ ", highlight.GetGroup("comment"))
+			renderInline(l, m[1], highlight.GetGroup("statement"))
+		case listRegex.MatchString(line):
+			m := listRegex.FindStringSubmatch(line)
+			l.add(m[1], 0)
+			bullet := m[2]
+			if !unicode.IsDigit(rune(bullet[0])) {
+				bullet = "•"
+			}
+			l.add(bullet+" ", highlight.GetGroup("identifier"))
+			switch strings.TrimSpace(m[3]) {
+			case "[ ]":
+				l.add("☐ ", highlight.GetGroup("identifier"))
+			case "[x]", "[X]":
+				l.add("☑ ", highlight.GetGroup("identifier"))
+			}
+			renderInline(l, m[4], 0)
+		default:
+			renderInline(l, strings.TrimRight(line, " \t"), 0)
+			paragraph = true
+		}
+		add(l, i)
+	}
+	if fence != "" {
+		endCode()
+	}
+	return p
+}
+
+// highlightCode returns the highlighting of the lines of a code block with
+// the syntax of the language, which is a filetype or the extension of its
+// files, and no highlighting if it is unknown
+func highlightCode(lang string, code []string) []highlight.LineMatch {
+	var def *highlight.Def
+	if lang != "" {
+		catalog := loadSyntaxCatalog()
+		sf := catalog.find(strings.ToLower(lang))
+		if sf == nil {
+			sf = catalog.detect("", "code."+lang, nil)
+		}
+		if sf != nil {
+			def, _ = catalog.loadDef(sf)
+		}
+	}
+	if def == nil {
+		matches := make([]highlight.LineMatch, len(code))
+		for i := range matches {
+			matches[i] = highlight.LineMatch{}
+		}
+		return matches
+	}
+	return highlight.NewHighlighter(def).HighlightString(strings.Join(code, "\n"))
+}
+
+// closing returns the index in s of the delimiter closing the one at i,
+// which is followed by text, or -1
+func closing(s string, i int, delim string) int {
+	open := i + len(delim)
+	if open >= len(s) || s[open] == ' ' {
+		return -1
+	}
+	j := strings.Index(s[open:], delim)
+	if j <= 0 {
+		return -1
+	}
+	return open + j
+}
+
+// renderInline adds the text of a line to l without the markers of
+// emphasis, code and links, which are highlighted instead, and the other
+// characters with the group g
+func renderInline(l *previewLine, s string, g highlight.Group) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && unicode.IsPunct(rune(s[i+1])):
+			l.add(s[i+1:i+2], g)
+			i += 2
+			continue
+		case c == '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j >= 0 {
+				l.add(s[i+1:i+1+j], highlight.GetGroup("constant.string"))
+				i += j + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__") || strings.HasPrefix(s[i:], "~~"):
+			group := highlight.GetGroup("type")
+			if c == '~' {
+				group = highlight.GetGroup("comment")
+			}
+			if j := closing(s, i, s[i:i+2]); j >= 0 {
+				renderInline(l, s[i+2:j], group)
+				i = j + 2
+				continue
+			}
+		case c == '*' || c == '_' && (i == 0 || !isWordByte(s[i-1])):
+			if j := closing(s, i, s[i:i+1]); j >= 0 {
+				renderInline(l, s[i+1:j], highlight.GetGroup("type"))
+				i = j + 1
+				continue
+			}
+		case c == '[' || c == '!' && strings.HasPrefix(s[i:], "!["):
+			open := i + 1
+			if c == '!' {
+				open++
+			}
+			if j := strings.Index(s[open:], "]("); j >= 0 {
+				if k := strings.IndexByte(s[open+j:], ')'); k >= 0 {
+					renderInline(l, s[open:open+j], highlight.GetGroup("underlined"))
+					i = open + j + k + 1
+					continue
+				}
+			}
+		case c == '<':
+			if j := strings.IndexByte(s[i:], '>'); j >= 0 && strings.Contains(s[i:i+j], "://") {
+				l.add(s[i+1:i+j], highlight.GetGroup("underlined"))
+				i += j + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		l.add(s[i:i+size], g)
+		i += size
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// PreviewLine returns the first line of the preview rendered from the line
+// y of the document or from a line after it
+func (p *Preview) PreviewLine(y int) int {
+	for i, s := range p.Source {
+		if s >= y {
+			return i
+		}
+	}
+	return util.Max(len(p.Source)-1, 0)
+}
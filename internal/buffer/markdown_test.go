@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+const previewSyntax = `filetype: previewlang
+
+detect:
+    filename: "\\.pvl$"
+
+rules:
+    - statement: "\\bif\\b"
+`
+
+func TestRenderMarkdown(t *testing.T) {
+	src := strings.Split(`# Title *here*
+
+Some **bold**, `+"`code`"+` and a [link](http://x.org).
+- item
+  1. sub é
+- [x] done
+> quoted
+Other
+-----
+`+"```pvl"+`
+if x
+`+"```", "\n")
+	p := RenderMarkdown(src, 5)
+
+	assert.Equal(t, []string{
+		"Title here",
+		"═════",
+		"",
+		"Some bold, code and a link.",
+		"• item",
+		"  1. sub é",
+		"• ☑ done",
+		"│ quoted",
+		"Other",
+		"─────",
+		"    if x",
+	}, p.Lines)
+	assert.Equal(t, []int{0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 10}, p.Source)
+
+	special := highlight.GetGroup("special")
+	typ := highlight.GetGroup("type")
+	assert.Equal(t, highlight.LineMatch{0: special, 6: typ, 10: 0}, p.Matches[0])
+	assert.Equal(t, highlight.LineMatch{
+		5:  typ,
+		9:  0,
+		11: highlight.GetGroup("constant.string"),
+		15: 0,
+		22: highlight.GetGroup("underlined"),
+		26: 0,
+	}, p.Matches[3])
+	assert.Equal(t, highlight.LineMatch{0: special}, p.Matches[8])
+
+	assert.Equal(t, 4, p.PreviewLine(3))
+	assert.Equal(t, 10, p.PreviewLine(9))
+	assert.Equal(t, 10, p.PreviewLine(100))
+}
+
+func TestRenderMarkdownCode(t *testing.T) {
+	config.PluginAddRuntimeFileFromMemory(config.RTSyntax, "previewlang", previewSyntax)
+
+	// the language of the block is a filetype or an extension
+	for _, lang := range []string{"previewlang", "pvl"} {
+		p := RenderMarkdown([]string{"```" + lang, "x if", "~~~", "```"}, 10)
+		assert.Equal(t, []string{"    x if", "    ~~~"}, p.Lines)
+		statement := highlight.GetGroup("statement")
+		assert.Equal(t, statement, p.Matches[0][6], lang)
+		assert.Equal(t, highlight.Group(0), p.Matches[1][4])
+	}
+
+	// unclosed blocks and unknown languages
+	p := RenderMarkdown([]string{"```nolang", "if"}, 10)
+	assert.Equal(t, []string{"    if"}, p.Lines)
+	assert.Equal(t, highlight.LineMatch{}, p.Matches[0])
+}
@@ -0,0 +1,324 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// The classes of the characters for matching brackets and keywords, those
+// of strings and comments not matching those of the code
+const (
+	classCode = iota
+	classString
+	classComment
+)
+
+// A braceScanner goes through the characters of a buffer along with their
+// class according to the highlighting, caching those of one line
+type braceScanner struct {
+	b         *Buffer
+	highlight bool
+	y         int
+	line      []rune
+	classes   []byte
+}
+
+func newBraceScanner(b *Buffer) *braceScanner {
+	return &braceScanner{
+		b:         b,
+		highlight: b.Settings["syntax"].(bool) && b.SyntaxDef != nil,
+		y:         -1,
+	}
+}
+
+func (s *braceScanner) load(y int) {
+	if s.y == y {
+		return
+	}
+	s.y = y
+	s.line = []rune(string(s.b.LineBytes(y)))
+	s.classes = make([]byte, len(s.line))
+	if !s.highlight {
+		return
+	}
+	match := s.b.Match(y)
+	starts := make([]int, 0, len(match))
+	for x := range match {
+		starts = append(starts, x)
+	}
+	sort.Ints(starts)
+	for i, x := range starts {
+		end := len(s.line)
+		if i+1 < len(starts) {
+			end = util.Min(starts[i+1], end)
+		}
+		var class byte = classCode
+		switch name := match[x].String(); {
+		case strings.HasPrefix(name, "constant.string"):
+			class = classString
+		case strings.HasPrefix(name, "comment"):
+			class = classComment
+		}
+		for j := x; j >= 0 && j < end; j++ {
+			s.classes[j] = class
+		}
+	}
+}
+
+// at returns the character at loc and its class, or false past the end of
+// its line
+func (s *braceScanner) at(loc Loc) (rune, byte, bool) {
+	s.load(loc.Y)
+	if loc.X < 0 || loc.X >= len(s.line) {
+		return 0, 0, false
+	}
+	return s.line[loc.X], s.classes[loc.X], true
+}
+
+// next returns the character after loc, going through the line breaks and
+// empty lines, or false at the end of the buffer
+func (s *braceScanner) next(loc Loc) (Loc, bool) {
+	s.load(loc.Y)
+	if loc.X+1 < len(s.line) {
+		return Loc{X: loc.X + 1, Y: loc.Y}, true
+	}
+	for y := loc.Y + 1; y < s.b.LinesNum(); y++ {
+		if len(s.b.LineBytes(y)) > 0 {
+			return Loc{X: 0, Y: y}, true
+		}
+	}
+	return loc, false
+}
+
+// prev returns the character before loc as next does after it
+func (s *braceScanner) prev(loc Loc) (Loc, bool) {
+	if loc.X > 0 {
+		return Loc{X: loc.X - 1, Y: loc.Y}, true
+	}
+	for y := loc.Y - 1; y >= 0; y-- {
+		if n := util.CharacterCount(s.b.LineBytes(y)); n > 0 {
+			return Loc{X: n - 1, Y: y}, true
+		}
+	}
+	return loc, false
+}
+
+// matchBracket returns the bracket matching the one of the pair bp at loc,
+// among the characters of the same class
+func (s *braceScanner) matchBracket(loc Loc, bp [2]rune) (Loc, bool) {
+	r, class, _ := s.at(loc)
+	forward := r == bp[0]
+	depth := 0
+	for ok := true; ok; {
+		if c, cl, _ := s.at(loc); cl == class {
+			switch c {
+			case bp[0]:
+				depth++
+			case bp[1]:
+				depth--
+			}
+			if depth == 0 {
+				return loc, true
+			}
+		}
+		if forward {
+			loc, ok = s.next(loc)
+		} else {
+			loc, ok = s.prev(loc)
+		}
+	}
+	return loc, false
+}
+
+// KeywordPairs returns the pairs of keywords of the syntax file of the
+// buffer's filetype, such as if and end, which are matched as brackets are
+func (b *Buffer) KeywordPairs() [][2]string {
+	if b.SyntaxDef == nil {
+		return nil
+	}
+	return b.SyntaxDef.Pairs
+}
+
+// MatchBrace returns the range of the bracket at loc, the one under it or
+// else the one before it, and the range of the bracket matching it. The
+// brackets of strings and comments are skipped when matching one which
+// isn't in a string or a comment, according to the highlighting. Without a
+// bracket, the keyword under or before loc is matched if it belongs to the
+// KeywordPairs, the keywords of strings and comments being skipped.
+func (b *Buffer) MatchBrace(loc Loc) (from, to [2]Loc, ok bool) {
+	s := newBraceScanner(b)
+	for _, l := range []Loc{loc, {X: loc.X - 1, Y: loc.Y}} {
+		r, _, found := s.at(l)
+		if !found {
+			continue
+		}
+		for _, bp := range BracePairs {
+			if r != bp[0] && r != bp[1] {
+				continue
+			}
+			m, ok := s.matchBracket(l, bp)
+			if !ok {
+				return from, to, false
+			}
+			return [2]Loc{l, l.Move(1, b)}, [2]Loc{m, m.Move(1, b)}, true
+		}
+	}
+	return s.matchKeyword(loc)
+}
+
+// matchKeyword returns the ranges of the keyword at loc and of the keyword
+// matching it
+func (s *braceScanner) matchKeyword(loc Loc) (from, to [2]Loc, ok bool) {
+	pairs := s.b.KeywordPairs()
+	if len(pairs) == 0 {
+		return from, to, false
+	}
+	start, end, ok := s.b.wordRange(loc)
+	if !ok {
+		return from, to, false
+	}
+	if _, class, _ := s.at(start); class != classCode {
+		return from, to, false
+	}
+	s.load(start.Y)
+	word := string(s.line[start.X:end.X])
+	from = [2]Loc{start, end}
+
+	// the keywords opening the pairs closed by the closing keyword, such as
+	// function and if for end, count as the same one
+	closer, forward := "", false
+	for _, p := range pairs {
+		if p[0] == word {
+			closer, forward = p[1], true
+			break
+		} else if p[1] == word {
+			closer = word
+		}
+	}
+	if closer == "" {
+		return from, to, false
+	}
+	opens := make(map[string]bool)
+	for _, p := range pairs {
+		if p[1] == closer {
+			opens[p[0]] = true
+		}
+	}
+
+	depth := 0
+	words := s.wordsAfter
+	if !forward {
+		words = s.wordsBefore
+	}
+	found := false
+	words(from, func(w string, r [2]Loc) bool {
+		if opens[w] {
+			if forward {
+				depth++
+			} else {
+				depth--
+			}
+		} else if w == closer {
+			if forward {
+				depth--
+			} else {
+				depth++
+			}
+		}
+		if depth < 0 {
+			to, found = r, true
+			return false
+		}
+		return true
+	})
+	return from, to, found
+}
+
+// wordsAfter calls f with the words of the code after the range r, along
+// with their ranges, until it returns false
+func (s *braceScanner) wordsAfter(r [2]Loc, f func(w string, r [2]Loc) bool) {
+	x := r[1].X
+	for y := r[1].Y; y < s.b.LinesNum(); y++ {
+		s.load(y)
+		for x < len(s.line) {
+			if !util.IsWordChar(s.line[x]) {
+				x++
+				continue
+			}
+			e := x
+			for e < len(s.line) && util.IsWordChar(s.line[e]) {
+				e++
+			}
+			if s.classes[x] == classCode && !f(string(s.line[x:e]), [2]Loc{{X: x, Y: y}, {X: e, Y: y}}) {
+				return
+			}
+			x = e
+		}
+		x = 0
+	}
+}
+
+// wordsBefore calls f with the words of the code before the range r, from
+// the last one, along with their ranges, until it returns false
+func (s *braceScanner) wordsBefore(r [2]Loc, f func(w string, r [2]Loc) bool) {
+	x := r[0].X
+	for y := r[0].Y; y >= 0; y-- {
+		s.load(y)
+		if x < 0 {
+			x = len(s.line)
+		}
+		for x > 0 {
+			if !util.IsWordChar(s.line[x-1]) {
+				x--
+				continue
+			}
+			b := x
+			for b > 0 && util.IsWordChar(s.line[b-1]) {
+				b--
+			}
+			if s.classes[b] == classCode && !f(string(s.line[b:x]), [2]Loc{{X: b, Y: y}, {X: x, Y: y}}) {
+				return
+			}
+			x = b
+		}
+		x = -1
+	}
+}
+
+// EnclosingBrace returns the innermost pair of brackets around loc, the
+// brackets of strings and comments being skipped unless loc is in one
+func (b *Buffer) EnclosingBrace(loc Loc) (open, close Loc, ok bool) {
+	s := newBraceScanner(b)
+	_, class, found := s.at(loc)
+	if !found {
+		_, class, _ = s.at(Loc{X: loc.X - 1, Y: loc.Y})
+	}
+	var closers []rune
+	l := loc
+	for {
+		if l, ok = s.prev(l); !ok || l == loc {
+			return open, close, false
+		}
+		r, cl, _ := s.at(l)
+		if cl != class {
+			continue
+		}
+		for _, bp := range BracePairs {
+			switch r {
+			case bp[1]:
+				closers = append(closers, r)
+			case bp[0]:
+				if n := len(closers); n > 0 {
+					if closers[n-1] == bp[1] {
+						closers = closers[:n-1]
+					}
+					continue
+				}
+				close, ok = s.matchBracket(l, bp)
+				return l, close, ok
+			}
+		}
+	}
+}
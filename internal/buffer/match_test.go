@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchBrace(t *testing.T) {
+	b := highlightedBuffer(t, "f(a /* ) */, [b]\n)")
+	defer b.Close()
+
+	// the bracket in the comment is skipped
+	from, to, ok := b.MatchBrace(Loc{X: 1, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, [2]Loc{{X: 1, Y: 0}, {X: 2, Y: 0}}, from)
+	assert.Equal(t, [2]Loc{{X: 0, Y: 1}, {X: 1, Y: 1}}, to)
+
+	// the bracket before the cursor matches when there is none under it
+	from, to, ok = b.MatchBrace(Loc{X: 1, Y: 1})
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 0, Y: 1}, from[0])
+	assert.Equal(t, Loc{X: 1, Y: 0}, to[0])
+
+	_, to, ok = b.MatchBrace(Loc{X: 13, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 15, Y: 0}, to[0])
+
+	// a bracket in a comment matches among those of the comment only
+	_, _, ok = b.MatchBrace(Loc{X: 7, Y: 0})
+	assert.False(t, ok)
+
+	_, _, ok = b.MatchBrace(Loc{X: 3, Y: 0})
+	assert.False(t, ok)
+}
+
+func TestMatchKeyword(t *testing.T) {
+	b := highlightedBuffer(t, "if a\n  do /* end */\n  end\nend\n")
+	defer b.Close()
+	b.SyntaxDef.Pairs = [][2]string{{"if", "end"}, {"do", "end"}}
+
+	from, to, ok := b.MatchBrace(Loc{X: 1, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, [2]Loc{{X: 0, Y: 0}, {X: 2, Y: 0}}, from)
+	assert.Equal(t, [2]Loc{{X: 0, Y: 3}, {X: 3, Y: 3}}, to)
+
+	// the keyword ending at the cursor matches backward
+	_, to, ok = b.MatchBrace(Loc{X: 5, Y: 2})
+	assert.True(t, ok)
+	assert.Equal(t, [2]Loc{{X: 2, Y: 1}, {X: 4, Y: 1}}, to)
+
+	// keywords in comments and other words don't match
+	_, _, ok = b.MatchBrace(Loc{X: 9, Y: 1})
+	assert.False(t, ok)
+	_, _, ok = b.MatchBrace(Loc{X: 3, Y: 0})
+	assert.False(t, ok)
+}
+
+func TestEnclosingBrace(t *testing.T) {
+	b := highlightedBuffer(t, "f(a, [b /* ] */], c)")
+	defer b.Close()
+
+	open, close, ok := b.EnclosingBrace(Loc{X: 7, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 5, Y: 0}, open)
+	assert.Equal(t, Loc{X: 15, Y: 0}, close)
+
+	// the pairs before the location are left out
+	open, close, ok = b.EnclosingBrace(Loc{X: 18, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 1, Y: 0}, open)
+	assert.Equal(t, Loc{X: 19, Y: 0}, close)
+
+	_, _, ok = b.EnclosingBrace(Loc{X: 0, Y: 0})
+	assert.False(t, ok)
+}
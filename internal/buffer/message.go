@@ -1,10 +1,14 @@
 package buffer
 
 import (
+	"sort"
+
 	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
 	"github.com/zyedidia/tcell/v2"
 )
 
+// MsgType is the severity of a message
 type MsgType int
 
 const (
@@ -13,7 +17,20 @@ const (
 	MTError
 )
 
-// Message represents the information for a gutter message
+func (t MsgType) String() string {
+	switch t {
+	case MTWarning:
+		return "warning"
+	case MTError:
+		return "error"
+	}
+	return "info"
+}
+
+// Message represents the information for a gutter message. Messages are
+// the diagnostics of a buffer: linters, language servers and plugins publish
+// them, grouped by owner, and they are shown in the gutter, underlined in
+// the text and listed by the diagnostics command.
 type Message struct {
 	// The Msg iteslf
 	Msg string
@@ -65,6 +82,85 @@ func (b *Buffer) AddMessage(m *Message) {
 	b.Messages = append(b.Messages, m)
 }
 
+// SetMessages replaces all the messages of the given owner, which is how
+// a source of diagnostics publishes a new set of results
+func (b *Buffer) SetMessages(owner string, msgs []*Message) {
+	b.ClearMessages(owner)
+	for _, m := range msgs {
+		m.Owner = owner
+		b.AddMessage(m)
+	}
+	screen.Redraw()
+}
+
+// MessageUpdates receives the updates published by PublishMessages, which
+// the main loop applies
+var MessageUpdates = make(chan func(), 100)
+
+// PublishMessages is like SetMessages but may be called from any goroutine,
+// for example by an asynchronous linter. The messages are replaced by the
+// main loop.
+func (b *Buffer) PublishMessages(owner string, msgs []*Message) {
+	MessageUpdates <- func() {
+		b.SetMessages(owner, msgs)
+	}
+}
+
+// LineMessage returns the most severe message on the given line, or nil if
+// there is none
+func (b *Buffer) LineMessage(y int) *Message {
+	var msg *Message
+	for _, m := range b.Messages {
+		if (m.Start.Y == y || m.End.Y == y) && (msg == nil || m.Kind > msg.Kind) {
+			msg = m
+		}
+	}
+	return msg
+}
+
+// Pos returns where the message starts. Messages for a whole line start at
+// the beginning of the line.
+func (m *Message) Pos() Loc {
+	if m.Start.X < 0 {
+		return Loc{0, m.Start.Y}
+	}
+	return m.Start
+}
+
+// SortedMessages returns the messages ordered by position
+func (b *Buffer) SortedMessages() []*Message {
+	msgs := make([]*Message, len(b.Messages))
+	copy(msgs, b.Messages)
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].Pos().LessThan(msgs[j].Pos())
+	})
+	return msgs
+}
+
+// NextMessage returns the first message starting after loc, or before it if
+// down is false, wrapping around the buffer. It returns nil if the buffer has
+// no messages.
+func (b *Buffer) NextMessage(loc Loc, down bool) *Message {
+	msgs := b.SortedMessages()
+	if len(msgs) == 0 {
+		return nil
+	}
+	if down {
+		for _, m := range msgs {
+			if m.Pos().GreaterThan(loc) {
+				return m
+			}
+		}
+		return msgs[0]
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Pos().LessThan(loc) {
+			return msgs[i]
+		}
+	}
+	return msgs[len(msgs)-1]
+}
+
 func (b *Buffer) removeMsg(i int) {
 	copy(b.Messages[i:], b.Messages[i+1:])
 	b.Messages[len(b.Messages)-1] = nil
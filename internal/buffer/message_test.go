@@ -0,0 +1,33 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessages(t *testing.T) {
+	b := NewBufferFromString("one\ntwo\nthree\nfour\n", "", BTDefault)
+	assert.Nil(t, b.NextMessage(Loc{0, 0}, true))
+
+	b.SetMessages("lint", []*Message{
+		NewMessageAtLine("", "warn", 3, MTWarning),
+		NewMessage("", "err", Loc{1, 2}, Loc{3, 2}, MTError),
+	})
+	b.AddMessage(NewMessage("other", "info", Loc{0, 0}, Loc{1, 0}, MTInfo))
+	assert.Len(t, b.Messages, 3)
+	assert.Equal(t, "lint", b.Messages[0].Owner)
+
+	assert.Equal(t, "err", b.LineMessage(2).Msg)
+	assert.Nil(t, b.LineMessage(1))
+
+	assert.Equal(t, "warn", b.NextMessage(Loc{0, 0}, true).Msg)
+	assert.Equal(t, "err", b.NextMessage(Loc{0, 2}, true).Msg)
+	assert.Equal(t, "info", b.NextMessage(Loc{1, 2}, true).Msg)
+	assert.Equal(t, "warn", b.NextMessage(Loc{1, 2}, false).Msg)
+	assert.Equal(t, "err", b.NextMessage(Loc{0, 0}, false).Msg)
+
+	b.SetMessages("lint", nil)
+	assert.Len(t, b.Messages, 1)
+	assert.Equal(t, "info", b.Messages[0].Msg)
+}
@@ -0,0 +1,250 @@
+package buffer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// modelineOptions are the options which modelines may set, which only
+// change how the text is shown and indented. The others are ignored so
+// that opening a file cannot run commands or write files.
+var modelineOptions = map[string]bool{
+	"autoindent":   true,
+	"colorcolumn":  true,
+	"eofnewline":   true,
+	"filetype":     true,
+	"rmtrailingws": true,
+	"softwrap":     true,
+	"spell":        true,
+	"spelllang":    true,
+	"tabsize":      true,
+	"tabstospaces": true,
+	"wordwrap":     true,
+}
+
+var (
+	// a vim modeline is "vim: ts=4 sw=4 et" or "vim: set ts=4 sw=4 et:",
+	// after the start of the line or a blank
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex)(?:[<=>]?\d+)?:\s*(.*)$`)
+	// an emacs modeline is "-*- mode: c; tab-width: 4 -*-" or "-*- c -*-"
+	emacsModeline = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	// a micro modeline is "micro: tabsize=4 tabstospaces=on"
+	microModeline = regexp.MustCompile(`(?:^|\s)micro:\s*(.*)$`)
+)
+
+// vimOptions are the micro names of the vim options
+var vimOptions = map[string]string{
+	"ai":           "autoindent",
+	"autoindent":   "autoindent",
+	"cc":           "colorcolumn",
+	"colorcolumn":  "colorcolumn",
+	"et":           "tabstospaces",
+	"expandtab":    "tabstospaces",
+	"fixeol":       "eofnewline",
+	"fixendofline": "eofnewline",
+	"ft":           "filetype",
+	"filetype":     "filetype",
+	"spell":        "spell",
+	"spl":          "spelllang",
+	"spelllang":    "spelllang",
+	"syn":          "filetype",
+	"syntax":       "filetype",
+	"wrap":         "softwrap",
+}
+
+// modelineFiletypes are the micro filetypes of the names of vim and emacs
+// which differ
+var modelineFiletypes = map[string]string{
+	"bash":         "shell",
+	"sh":           "shell",
+	"shell-script": "shell",
+	"zsh":          "shell",
+	"js":           "javascript",
+	"py":           "python",
+	"emacs-lisp":   "lisp",
+	"text":         "unknown",
+}
+
+// ParseModeline returns the options which a vim, emacs or micro modeline in
+// the line sets, by micro name with their values as text, or nil if the
+// line has no modeline. Only the options of modelineOptions are kept.
+func ParseModeline(line string) map[string]string {
+	var opts map[string]string
+	if m := microModeline.FindStringSubmatch(line); m != nil {
+		opts = parseMicroModeline(m[1])
+	} else if m := vimModeline.FindStringSubmatch(line); m != nil {
+		opts = parseVimModeline(m[1])
+	} else if m := emacsModeline.FindStringSubmatch(line); m != nil {
+		opts = parseEmacsModeline(m[1])
+	}
+	for k := range opts {
+		if !modelineOptions[k] {
+			delete(opts, k)
+		}
+	}
+	if ft, ok := opts["filetype"]; ok {
+		ft = strings.ToLower(ft)
+		if alias, ok := modelineFiletypes[ft]; ok {
+			ft = alias
+		}
+		opts["filetype"] = ft
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return opts
+}
+
+// parseMicroModeline parses the options of a micro modeline, "name=value"
+// separated by blanks, or "name" for a boolean option which is on
+func parseMicroModeline(s string) map[string]string {
+	opts := make(map[string]string)
+	for _, f := range strings.Fields(s) {
+		if i := strings.IndexByte(f, '='); i > 0 {
+			opts[f[:i]] = f[i+1:]
+		} else if modelineOptions[f] {
+			opts[f] = "on"
+		}
+	}
+	return opts
+}
+
+// parseVimModeline parses the options of a vim modeline, separated by
+// blanks or colons, or by blanks up to a colon after "set"
+func parseVimModeline(s string) map[string]string {
+	var fields []string
+	if strings.HasPrefix(s, "set ") || strings.HasPrefix(s, "se ") {
+		s = s[strings.IndexByte(s, ' ')+1:]
+		if i := strings.IndexByte(s, ':'); i >= 0 {
+			s = s[:i]
+		}
+		fields = strings.Fields(s)
+	} else {
+		fields = strings.FieldsFunc(s, func(r rune) bool {
+			return r == ':' || r == ' ' || r == '\t'
+		})
+	}
+
+	opts := make(map[string]string)
+	var tabstop, shiftwidth string
+	for _, f := range fields {
+		name, value := f, ""
+		if i := strings.IndexByte(f, '='); i > 0 {
+			name, value = f[:i], f[i+1:]
+		}
+		switch name {
+		case "ts", "tabstop":
+			tabstop = value
+			continue
+		case "sw", "shiftwidth":
+			shiftwidth = value
+			continue
+		}
+		if value == "" {
+			value = "on"
+			if _, ok := vimOptions[name]; !ok && strings.HasPrefix(name, "no") {
+				name, value = name[2:], "off"
+			}
+		}
+		if option, ok := vimOptions[name]; ok {
+			opts[option] = value
+		}
+	}
+	setIndent(opts, tabstop, shiftwidth)
+	return opts
+}
+
+// parseEmacsModeline parses the variables of an emacs modeline, "name:
+// value" separated by semicolons, or the mode alone
+func parseEmacsModeline(s string) map[string]string {
+	opts := make(map[string]string)
+	if !strings.Contains(s, ":") {
+		opts["filetype"] = strings.TrimSuffix(strings.TrimSpace(s), "-mode")
+		return opts
+	}
+	var tabWidth, offset string
+	for _, v := range strings.Split(s, ";") {
+		i := strings.IndexByte(v, ':')
+		if i < 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(v[:i]))
+		value := strings.TrimSpace(v[i+1:])
+		switch {
+		case name == "mode":
+			opts["filetype"] = strings.TrimSuffix(value, "-mode")
+		case name == "tab-width":
+			tabWidth = value
+		case name == "indent-tabs-mode":
+			if value == "nil" {
+				opts["tabstospaces"] = "on"
+			} else {
+				opts["tabstospaces"] = "off"
+			}
+		case name == "fill-column":
+			opts["colorcolumn"] = value
+		case strings.HasSuffix(name, "-offset"):
+			offset = value
+		}
+	}
+	setIndent(opts, tabWidth, offset)
+	return opts
+}
+
+// setIndent sets the tabsize option from the width of the tabs and of the
+// indentation of a modeline: micro indents by the width of its tabs, which
+// is the width of the indentation when it is made of spaces or the tabs
+// have no width
+func setIndent(opts map[string]string, tabs, indent string) {
+	if indent != "" && indent != "0" && (tabs == "" || opts["tabstospaces"] == "on") {
+		opts["tabsize"] = indent
+	} else if tabs != "" {
+		opts["tabsize"] = tabs
+	}
+}
+
+// modeline returns the options set by the modelines in the first and the
+// last lines of the buffer, the lines at the end overriding the ones at the
+// start, if the modeline option is on
+func (b *Buffer) modeline() map[string]string {
+	n := int(b.Settings["modelines"].(float64))
+	if !b.Settings["modeline"].(bool) || n <= 0 || b.Path == "" {
+		return nil
+	}
+	opts := make(map[string]string)
+	parse := func(y int) {
+		for k, v := range ParseModeline(string(b.LineBytes(y))) {
+			opts[k] = v
+		}
+	}
+	lines := b.LinesNum()
+	for y := 0; y < n && y < lines; y++ {
+		parse(y)
+	}
+	for y := lines - n; y < lines; y++ {
+		if y >= n {
+			parse(y)
+		}
+	}
+	return opts
+}
+
+// applyModeline sets the options of a modeline in the buffer only, unless
+// the modeline option was turned off for the buffer. The invalid values
+// are logged and ignored.
+func (b *Buffer) applyModeline(opts map[string]string) {
+	if !b.Settings["modeline"].(bool) {
+		return
+	}
+	for option, value := range opts {
+		native, err := config.GetNativeValue(option, b.Settings[option], value)
+		if err != nil {
+			util.Log(util.LogWarn, "buffer", "Modeline of "+b.Path+":", err)
+			continue
+		}
+		b.Settings[option] = native
+	}
+}
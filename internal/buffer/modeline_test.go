@@ -0,0 +1,72 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+func TestParseModeline(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]string
+	}{
+		{"# vim: ts=4 sw=4 et", map[string]string{"tabsize": "4", "tabstospaces": "on"}},
+		{"/* vim: set ts=8 sw=2 expandtab ft=c: */", map[string]string{"tabsize": "2", "tabstospaces": "on", "filetype": "c"}},
+		{"// vim: ts=8 sw=4 noet", map[string]string{"tabsize": "8", "tabstospaces": "off"}},
+		{"# vi:nowrap:ai:sw=3", map[string]string{"softwrap": "off", "autoindent": "on", "tabsize": "3"}},
+		{"# vim: ft=sh", map[string]string{"filetype": "shell"}},
+		{"# -*- mode: Python; tab-width: 4; indent-tabs-mode: nil -*-", map[string]string{"filetype": "python", "tabsize": "4", "tabstospaces": "on"}},
+		{";; -*- emacs-lisp -*-", map[string]string{"filetype": "lisp"}},
+		{"# micro: tabsize=4 tabstospaces=on softwrap", map[string]string{"tabsize": "4", "tabstospaces": "on", "softwrap": "on"}},
+		// the options which are not allowed are ignored
+		{"# micro: autosave=1 savecmd=rm tabsize=2", map[string]string{"tabsize": "2"}},
+		{"# vim: foldmethod=marker", nil},
+		{"the review: ts=4", nil},
+		{"nothing here", nil},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, ParseModeline(test.line), test.line)
+	}
+}
+
+func TestModelineBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-modeline")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	text := "# vim: ts=2 et\nx = 1\n"
+	for i := 0; i < 10; i++ {
+		text += "y = 2\n"
+	}
+	text += "# micro: tabsize=3 wordwrap=on\n"
+	path := filepath.Join(dir, "script")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(text), 0644))
+
+	b, err := NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	// the last lines override the first ones
+	assert.Equal(t, float64(3), b.Settings["tabsize"])
+	assert.Equal(t, true, b.Settings["tabstospaces"])
+	assert.Equal(t, true, b.Settings["wordwrap"])
+	b.Close()
+
+	config.GlobalSettings["modeline"] = false
+	b, err = NewBufferFromFile(path, BTDefault)
+	config.GlobalSettings["modeline"] = true
+	assert.NoError(t, err)
+	assert.Equal(t, float64(4), b.Settings["tabsize"])
+	b.Close()
+
+	// the lines in the middle are not read
+	text = "\n\n\n\n\n\n# vim: ts=7\n\n\n\n\n\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(text), 0644))
+	b, err = NewBufferFromFile(path, BTDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(4), b.Settings["tabsize"])
+	b.Close()
+}
@@ -0,0 +1,73 @@
+package buffer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// numberTokenRegex matches the hexadecimal, octal and decimal integers
+var numberTokenRegex = regexp.MustCompile(`0[xX][0-9a-fA-F]+|0[oO][0-7]+|[0-9]+`)
+
+// IncrementNumber adds delta to the integer under the cursor, or to the
+// first one after it on its line, and puts the cursor on its last digit.
+// Hexadecimal (0x) and octal (0o) integers keep their prefix, case and
+// number of digits, and can't become negative, while a decimal integer
+// following a minus sign which isn't part of a word is negative. It
+// returns false if there is no integer.
+func (b *Buffer) IncrementNumber(c *Cursor, delta int64) bool {
+	line := b.LineBytes(c.Y)
+	x := len(util.SliceStart(line, c.X))
+	for _, m := range numberTokenRegex.FindAllIndex(line, -1) {
+		if m[1] <= x {
+			continue
+		}
+		start, end := m[0], m[1]
+		tok := string(line[start:end])
+		base, prefix, digits := 10, "", tok
+		if len(tok) > 2 && (tok[1] == 'x' || tok[1] == 'X') {
+			base, prefix, digits = 16, tok[:2], tok[2:]
+		} else if len(tok) > 2 && (tok[1] == 'o' || tok[1] == 'O') {
+			base, prefix, digits = 8, tok[:2], tok[2:]
+		} else if start > 0 && line[start-1] == '-' && (start == 1 || !util.IsWordChar(rune(line[start-2]))) {
+			start--
+			digits = "-" + digits
+		}
+
+		n, err := strconv.ParseInt(digits, base, 64)
+		if err != nil {
+			return false
+		}
+		n += delta
+		var text string
+		if base == 10 {
+			text = strconv.FormatInt(n, 10)
+			if len(digits) > 1 && digits[0] == '0' && n >= 0 && len(text) < len(digits) {
+				// keeps the leading zeros of a number such as 007
+				text = strings.Repeat("0", len(digits)-len(text)) + text
+			}
+		} else {
+			if n < 0 {
+				n = 0
+			}
+			text = strconv.FormatInt(n, base)
+			if strings.ContainsAny(digits, "ABCDEF") {
+				text = strings.ToUpper(text)
+			}
+			if len(text) < len(digits) {
+				text = strings.Repeat("0", len(digits)-len(text)) + text
+			}
+			text = prefix + text
+		}
+
+		from := Loc{X: util.RunePos(line, start), Y: c.Y}
+		to := Loc{X: util.RunePos(line, end), Y: c.Y}
+		b.Replace(from, to, text)
+		c.Deselect(true)
+		c.GotoLoc(Loc{X: from.X + len(text) - 1, Y: c.Y})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncrementNumber(t *testing.T) {
+	tests := []struct {
+		line  string
+		x     int
+		delta int64
+		after string
+		cx    int
+	}{
+		{"a 9 b", 0, 1, "a 10 b", 3},
+		{"a 9 b", 2, -10, "a -1 b", 3},
+		{"x = -5;", 0, 2, "x = -3;", 5},
+		// a minus sign in a word is not a sign
+		{"a-5", 0, 1, "a-6", 2},
+		{"0x0f", 1, 1, "0x10", 3},
+		{"0xFF", 0, 1, "0x100", 4},
+		{"0o7", 0, 1, "0o10", 3},
+		{"0x01", 0, -5, "0x00", 3},
+		{"007", 0, 1, "008", 2},
+		{"id 12 and 34", 5, 1, "id 12 and 35", 11},
+		{"no number", 0, 1, "no number", 0},
+	}
+	for _, tt := range tests {
+		b := NewBufferFromString(tt.line, "", BTDefault)
+		c := b.GetActiveCursor()
+		c.GotoLoc(Loc{X: tt.x, Y: 0})
+		ok := b.IncrementNumber(c, tt.delta)
+		assert.Equal(t, tt.after != tt.line, ok, tt.line)
+		assert.Equal(t, tt.after, string(b.Bytes()), tt.line)
+		if ok {
+			assert.Equal(t, tt.cx, c.X, tt.line)
+		}
+		b.Close()
+	}
+}
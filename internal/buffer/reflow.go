@@ -0,0 +1,130 @@
+package buffer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// bulletRegex matches the bullet starting a list item, and the spaces
+// after it
+var bulletRegex = regexp.MustCompile(`^([-*+•]|\d+[.)])\s+`)
+
+// reflowLine is a line split into its leader, the indentation and comment
+// marker before its text, and its text
+type reflowLine struct {
+	leader, text string
+	comment      bool
+}
+
+// splitLeader splits a line into its leader and its text, the first of the
+// comment markers starting the text being part of the leader, with the
+// spaces after it
+func splitLeader(line string, markers []string) reflowLine {
+	text := strings.TrimLeft(line, " \t")
+	l := reflowLine{leader: line[:len(line)-len(text)]}
+	for _, m := range markers {
+		// a star is only a marker followed by a space, unlike *p
+		if strings.HasPrefix(text, m) && (m != "*" || len(text) == 1 || text[1] == ' ' || text[1] == '\t') {
+			rest := strings.TrimLeft(text[len(m):], " \t")
+			l.leader += text[:len(text)-len(rest)]
+			text, l.comment = rest, true
+			break
+		}
+	}
+	l.text = strings.TrimRight(text, " \t")
+	return l
+}
+
+// reflowMarkers returns the markers starting the lines of comments, and of
+// quotes in Markdown
+func (b *Buffer) reflowMarkers() []string {
+	var markers []string
+	m := b.CommentMarkers()
+	if m.Line != "" {
+		markers = append(markers, m.Line)
+	}
+	if m.BlockStart == "/*" {
+		// the lines inside the block comments of C and the like
+		markers = append(markers, "*")
+	}
+	if b.FileType() == "markdown" {
+		markers = append(markers, ">")
+	}
+	return markers
+}
+
+// Reflow rewraps the lines from start to end to the given width, as a
+// single undo step. Each paragraph, a run of lines separated by blank
+// lines, list items and changes between comments and code, is filled with
+// as many words as fit on each line. Comment markers, the bullets of list
+// items and the indentation of the second line of a paragraph, if it
+// differs from the first one, are kept at the start of each line. It
+// returns the end of the rewrapped lines.
+func (b *Buffer) Reflow(start, end, width int) Loc {
+	end = util.Min(end, b.LinesNum()-1)
+	markers := b.reflowMarkers()
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	visual := func(s string) int {
+		return util.StringWidth([]byte(s), util.CharacterCountInString(s), tabsize)
+	}
+
+	var lines []reflowLine
+	for y := start; y <= end; y++ {
+		lines = append(lines, splitLeader(string(b.LineBytes(y)), markers))
+	}
+
+	var out []string
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if l.text == "" {
+			out = append(out, strings.TrimRight(l.leader, " \t"))
+			i++
+			continue
+		}
+		first, cont := l.leader, l.leader
+		if m := bulletRegex.FindString(l.text); m != "" {
+			first += m
+			cont += strings.Repeat(" ", visual(m))
+			l.text = l.text[len(m):]
+		} else if i+1 < len(lines) && lines[i+1].text != "" && lines[i+1].comment == l.comment &&
+			!bulletRegex.MatchString(lines[i+1].text) {
+			cont = lines[i+1].leader
+		}
+
+		words := strings.Fields(l.text)
+		for i++; i < len(lines); i++ {
+			n := lines[i]
+			if n.text == "" || n.comment != l.comment || bulletRegex.MatchString(n.text) {
+				break
+			}
+			words = append(words, strings.Fields(n.text)...)
+		}
+
+		line := first
+		empty := true
+		for _, w := range words {
+			if !empty && visual(line)+1+visual(w) > width {
+				out = append(out, line)
+				line, empty = cont, true
+			}
+			if !empty {
+				line += " "
+			}
+			line += w
+			empty = false
+		}
+		out = append(out, line)
+	}
+
+	text := strings.Join(out, "\n")
+	endLoc := Loc{X: util.CharacterCount(b.LineBytes(end)), Y: end}
+	if string(b.Substr(Loc{X: 0, Y: start}, endLoc)) != text {
+		b.UndoGroup(func() {
+			b.Replace(Loc{X: 0, Y: start}, endLoc, text)
+		})
+	}
+	last := start + len(out) - 1
+	return Loc{X: util.CharacterCount(b.LineBytes(last)), Y: last}
+}
@@ -0,0 +1,33 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReflow(t *testing.T) {
+	tests := []struct {
+		text, commenttype string
+		width             int
+		out               string
+	}{
+		{"one two three four\nfive six", "", 10, "one two\nthree four\nfive six"},
+		{"a b\n\n  c d e", "", 5, "a b\n\n  c d\n  e"},
+		{"// one two three\n//\n// four", "// %s", 12, "// one two\n// three\n//\n// four"},
+		{"  # one two three four", "", 12, "  # one two\n  # three\n  # four"},
+		{"- one two three\n- four five\n1. six seven", "", 9, "- one two\n  three\n- four\n  five\n1. six\n   seven"},
+		{"Term: one two\n      three four five", "", 16, "Term: one two\n      three four\n      five"},
+		{"verylongword x", "", 4, "verylongword\nx"},
+		{"/* a\n * b c d\n */", "/* %s */", 6, "/* a\n * b c\n * d\n */"},
+		{"x := 1 // one\n// two", "// %s", 40, "x := 1 // one\n// two"},
+	}
+	for _, tt := range tests {
+		b := NewBufferFromString(tt.text, "", BTDefault)
+		b.Settings["commenttype"] = tt.commenttype
+		end := b.Reflow(0, b.LinesNum()-1, tt.width)
+		assert.Equal(t, tt.out, string(b.Bytes()), tt.text)
+		assert.Equal(t, b.End(), end, tt.text)
+		b.Close()
+	}
+}
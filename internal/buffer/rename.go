@@ -0,0 +1,124 @@
+package buffer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/project"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// fileOf returns an error unless the buffer shows a file which can be
+// renamed or deleted
+func (b *Buffer) fileOf() error {
+	if b.Path == "" || b.Type != BTDefault {
+		return errors.New("The buffer has no file")
+	}
+	if _, err := os.Stat(b.AbsPath); err != nil {
+		return errors.New("The file of the buffer is not saved")
+	}
+	return nil
+}
+
+// Rename moves the file of the buffer to newpath and shows it at its new
+// path, along with its lock, its bookmarks and the cursor and undo history
+// stored for it. The text of the buffer and its undo history are kept.
+func (b *Buffer) Rename(newpath string) error {
+	if err := b.fileOf(); err != nil {
+		return err
+	}
+	newpath, _ = util.ReplaceHome(newpath)
+	abs, err := filepath.Abs(newpath)
+	if err != nil {
+		return err
+	}
+	if abs == b.AbsPath {
+		return nil
+	}
+	if _, err := os.Lstat(abs); err == nil {
+		return errors.New(newpath + " exists already")
+	}
+	if err := b.checkLock(abs); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(abs); !dirExists(dir) {
+		if !b.Settings["mkparents"].(bool) {
+			return errors.New("Parent dirs don't exist, enable 'mkparents' for auto creation")
+		}
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(b.AbsPath, abs); err != nil {
+		return err
+	}
+
+	old := b.AbsPath
+	locked := b.locked
+	b.unlock()
+	b.RemoveBackup()
+	if config.StateDir != "" {
+		os.Remove(filepath.Join(config.StateDir, "buffers", util.EscapePath(old)))
+	}
+
+	b.Path = newpath
+	b.AbsPath = abs
+	b.ModTime, _ = util.GetModTime(abs)
+	if locked {
+		if err := writeLock(abs); err == nil {
+			b.locked = true
+		}
+	}
+	// the bookmarks stored at the old path are replaced as they have the
+	// same names
+	if len(b.bookmarks) > 0 {
+		if err := b.saveBookmarks(); err != nil {
+			util.Log(util.LogError, "buffer", "Error moving bookmarks:", err)
+		}
+	}
+	b.UpdateRules()
+	if !b.Modified() {
+		return b.Serialize()
+	}
+	return nil
+}
+
+// DeleteFile moves the file of the buffer to the trash of the system, or
+// removes it if there is no trash, and returns whether it was trashed. The
+// buffer keeps its text but no longer has a file, and what was stored for
+// the file is removed.
+func (b *Buffer) DeleteFile() (bool, error) {
+	if err := b.fileOf(); err != nil {
+		return false, err
+	}
+	trashed := util.HasTrash()
+	if trashed {
+		if err := util.MoveToTrash(b.AbsPath); err != nil {
+			return false, err
+		}
+	} else if err := os.Remove(b.AbsPath); err != nil {
+		return false, err
+	}
+
+	b.unlock()
+	b.RemoveBackup()
+	if config.StateDir != "" {
+		os.Remove(filepath.Join(config.StateDir, "buffers", util.EscapePath(b.AbsPath)))
+		if len(b.bookmarks) > 0 {
+			if err := project.SetFileBookmarks(b.AbsPath, nil); err != nil {
+				util.Log(util.LogError, "buffer", "Error removing bookmarks:", err)
+			}
+		}
+	}
+	b.Path = ""
+	b.AbsPath = ""
+	b.bookmarks = nil
+	return trashed, nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,53 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+func TestRename(t *testing.T) {
+	dir, done := withStateDir(t)
+	defer done()
+
+	path := filepath.Join(dir, "a.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("one"), 0644))
+	b := NewBufferFromString("one", path, BTDefault)
+	defer b.Close()
+	b.Settings["saveundo"] = true
+	b.Settings["eofnewline"] = false
+	b.Settings["undotimeout"] = float64(0)
+	b.ModTime, _ = util.GetModTime(path)
+	b.Insert(Loc{3, 0}, " two")
+	assert.NoError(t, b.Save())
+	state := func(p string) string { return filepath.Join(dir, "buffers", util.EscapePath(p)) }
+	_, err := os.Stat(state(path))
+	assert.NoError(t, err)
+
+	newpath := filepath.Join(dir, "sub", "b.go")
+	b.Settings["mkparents"] = false
+	assert.Error(t, b.Rename(newpath))
+	b.Settings["mkparents"] = true
+	assert.NoError(t, ioutil.WriteFile(path+"x", nil, 0644))
+	assert.Error(t, b.Rename(path+"x"))
+
+	assert.NoError(t, b.Rename(newpath))
+	assert.Equal(t, newpath, b.AbsPath)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	data, err := ioutil.ReadFile(newpath)
+	assert.NoError(t, err)
+	assert.Equal(t, "one two", string(data))
+	_, err = os.Stat(state(path))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(state(newpath))
+	assert.NoError(t, err)
+
+	// the undo history is kept
+	b.Undo()
+	assert.Equal(t, "one", string(b.Bytes()))
+}
@@ -0,0 +1,127 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// indentSample is the number of lines DetectIndent looks at
+const indentSample = 10000
+
+// IndentStyle is the indentation of a file, as found by DetectIndent
+type IndentStyle struct {
+	// TabLines and SpaceLines are the numbers of lines indented with tabs
+	// and with spaces
+	TabLines, SpaceLines int
+	// Width is the number of spaces of a level of indentation, or 0 if it
+	// isn't known
+	Width int
+}
+
+// Tabs returns whether the file is indented with tabs
+func (s IndentStyle) Tabs() bool {
+	return s.TabLines > s.SpaceLines
+}
+
+// Mixed returns whether some lines are indented with tabs and others with
+// spaces
+func (s IndentStyle) Mixed() bool {
+	return s.TabLines > 0 && s.SpaceLines > 0
+}
+
+// DetectIndent returns how the lines of the buffer are indented, and false
+// if none of them are. The width of the indentation with spaces is the most
+// common difference between the indentation of consecutive lines. The lines
+// starting with spaces and a `*`, as the middle of a C comment, are left
+// out.
+func (b *Buffer) DetectIndent() (IndentStyle, bool) {
+	var style IndentStyle
+	var deltas [9]int
+	prev := 0
+	for i := 0; i < b.LinesNum() && i < indentSample; i++ {
+		line := b.LineBytes(i)
+		ws := util.GetLeadingWhitespace(line)
+		rest := line[len(ws):]
+		if len(rest) == 0 {
+			continue
+		}
+		if len(ws) == 0 {
+			prev = 0
+			continue
+		}
+		if ws[0] == '\t' {
+			style.TabLines++
+			prev = -1
+			continue
+		}
+		if rest[0] == '*' {
+			continue
+		}
+		n := len(ws) - len(bytes.TrimLeft(ws, " "))
+		style.SpaceLines++
+		if prev >= 0 {
+			if d := util.Abs(n - prev); d >= 2 && d < len(deltas) {
+				deltas[d]++
+			}
+		}
+		prev = n
+	}
+	for d := range deltas {
+		if deltas[d] > deltas[style.Width] {
+			style.Width = d
+		}
+	}
+	return style, style.TabLines+style.SpaceLines > 0
+}
+
+// detectIndentSettings sets the tabstospaces and tabsize options of the
+// buffer of a file to the indentation of its lines, for the detectindent
+// option, and warns if they mix tabs and spaces
+func (b *Buffer) detectIndentSettings() {
+	style, ok := b.DetectIndent()
+	if !ok {
+		return
+	}
+	b.Settings["tabstospaces"] = !style.Tabs()
+	if !style.Tabs() && style.Width > 0 {
+		b.Settings["tabsize"] = float64(style.Width)
+	}
+	if style.Mixed() && prompt != nil {
+		prompt.Message(fmt.Sprintf("Mixed indentation: %d lines with tabs and %d with spaces, 'convert-indentation' rewrites it", style.TabLines, style.SpaceLines))
+	}
+}
+
+// Retab rewrites the indentation of the lines with tabs or with spaces, as
+// the tabstospaces option says, keeping its width, as a single undoable
+// edit. An indentation which isn't a multiple of tabsize keeps spaces after
+// its tabs.
+func (b *Buffer) Retab() {
+	toSpaces := b.Settings["tabstospaces"].(bool)
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	if tabsize <= 0 {
+		return
+	}
+	b.UndoGroup(func() {
+		for i := 0; i < b.LinesNum(); i++ {
+			ws := util.GetLeadingWhitespace(b.LineBytes(i))
+			width := 0
+			for _, c := range ws {
+				if c == '\t' {
+					width += tabsize - width%tabsize
+				} else {
+					width++
+				}
+			}
+			indent := strings.Repeat(" ", width)
+			if !toSpaces {
+				indent = strings.Repeat("\t", width/tabsize) + strings.Repeat(" ", width%tabsize)
+			}
+			if indent != string(ws) {
+				b.Replace(Loc{X: 0, Y: i}, Loc{X: len(ws), Y: i}, indent)
+			}
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectIndent(t *testing.T) {
+	b := NewBufferFromString("a\n  b\n    c\n\n    d\n  e\n", "", BTDefault)
+	style, ok := b.DetectIndent()
+	assert.True(t, ok)
+	assert.False(t, style.Tabs())
+	assert.False(t, style.Mixed())
+	assert.Equal(t, 2, style.Width)
+
+	b = NewBufferFromString("/**\n * doc\n */\nf {\n\tg\n\t\th\n    i\n}\n", "", BTDefault)
+	style, ok = b.DetectIndent()
+	assert.True(t, ok)
+	assert.True(t, style.Tabs())
+	assert.True(t, style.Mixed())
+	assert.Equal(t, IndentStyle{TabLines: 2, SpaceLines: 1, Width: 0}, style)
+
+	b = NewBufferFromString("a\nb\n", "", BTDefault)
+	_, ok = b.DetectIndent()
+	assert.False(t, ok)
+}
+
+func TestRetab(t *testing.T) {
+	b := NewBufferFromString("a\n\tb\n      c\n\t  d\n", "", BTDefault)
+	b.Settings["tabsize"] = float64(4)
+	b.Settings["tabstospaces"] = true
+	b.Retab()
+	assert.Equal(t, "a\n    b\n      c\n      d\n", string(b.Bytes()))
+
+	b.Settings["tabstospaces"] = false
+	b.Retab()
+	assert.Equal(t, "a\n\tb\n\t  c\n\t  d\n", string(b.Bytes()))
+
+	// both conversions are undone one at a time
+	b.Undo()
+	assert.Equal(t, "a\n    b\n      c\n      d\n", string(b.Bytes()))
+	b.Undo()
+	assert.Equal(t, "a\n\tb\n      c\n\t  d\n", string(b.Bytes()))
+}
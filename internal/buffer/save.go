@@ -21,8 +21,9 @@ import (
 )
 
 // LargeFileThreshold is the number of bytes when fastdirty is forced
-// because hashing is too slow
-const LargeFileThreshold = 50000
+// because hashing the file when it is opened or saved is too slow. The
+// edits are hashed line by line afterwards, see hashModified.
+const LargeFileThreshold = 50 * 1000 * 1000
 
 // overwriteFile opens the given file for writing, truncating if one exists, and then calls
 // the supplied function with the file as io.Writer object, also making sure the file is
@@ -66,6 +67,12 @@ func overwriteFile(name string, enc encoding.Encoding, fn func(io.Writer) error,
 	return
 }
 
+// IsPermissionError returns whether err is the failure to write a file the
+// user has no permission to modify, which may be saved with the sucmd then
+func IsPermissionError(err error) bool {
+	return os.IsPermission(err) && runtime.GOOS != "windows"
+}
+
 // Save saves the buffer to its default path
 func (b *Buffer) Save() error {
 	return b.SaveAs(b.Path)
@@ -73,7 +80,7 @@ func (b *Buffer) Save() error {
 
 // SaveAs saves the buffer to a specified path (filename), creating the file if it does not exist
 func (b *Buffer) SaveAs(filename string) error {
-	return b.saveToFile(filename, false)
+	return b.saveToFile(filename, false, true)
 }
 
 func (b *Buffer) SaveWithSudo() error {
@@ -81,22 +88,39 @@ func (b *Buffer) SaveWithSudo() error {
 }
 
 func (b *Buffer) SaveAsWithSudo(filename string) error {
-	return b.saveToFile(filename, true)
+	return b.saveToFile(filename, true, true)
+}
+
+// ForceSaveAs saves the buffer to filename without running the savecheck
+// command, after it failed
+func (b *Buffer) ForceSaveAs(filename string) error {
+	return b.saveToFile(filename, false, false)
 }
 
-func (b *Buffer) saveToFile(filename string, withSudo bool) error {
+// ForceSaveAsWithSudo is ForceSaveAs with sudo
+func (b *Buffer) ForceSaveAsWithSudo(filename string) error {
+	return b.saveToFile(filename, true, false)
+}
+
+func (b *Buffer) saveToFile(filename string, withSudo, check bool) error {
 	var err error
 	if b.Type.Readonly {
 		return errors.New("Cannot save readonly buffer")
 	}
-	if b.Type.Scratch {
+	if b.Type.Scratch && !b.KeepsDraft() {
 		return errors.New("Cannot save scratch buffer")
 	}
+	if err := b.checkLock(filename); err != nil {
+		return err
+	}
 	if withSudo && runtime.GOOS == "windows" {
 		return errors.New("Save with sudo not supported on Windows")
 	}
 
-	if b.Settings["rmtrailingws"].(bool) {
+	// the hex view is saved as the bytes it shows
+	hex := b.Settings["hex"].(bool)
+
+	if b.Settings["rmtrailingws"].(bool) && !hex {
 		for i, l := range b.lines {
 			leftover := util.CharacterCount(bytes.TrimRightFunc(l.data, unicode.IsSpace))
 
@@ -107,7 +131,7 @@ func (b *Buffer) saveToFile(filename string, withSudo bool) error {
 		b.RelocateCursors()
 	}
 
-	if b.Settings["eofnewline"].(bool) {
+	if b.Settings["eofnewline"].(bool) && !hex {
 		end := b.End()
 		if b.RuneAt(Loc{end.X - 1, end.Y}) != '\n' {
 			b.insert(end, []byte{'\n'})
@@ -117,6 +141,7 @@ func (b *Buffer) saveToFile(filename string, withSudo bool) error {
 	// Update the last time this file was updated after saving
 	defer func() {
 		b.ModTime, _ = util.GetModTime(filename)
+		b.updateDiskSize(filename)
 		err = b.Serialize()
 	}()
 
@@ -147,6 +172,17 @@ func (b *Buffer) saveToFile(filename string, withSudo bool) error {
 		return err
 	}
 
+	// the text is checked as it would be written, before the file is
+	if check && !hex && filename != "" {
+		data, err := enc.NewEncoder().Bytes(b.LineArray.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := b.saveCheck(absFilename, data); err != nil {
+			return err
+		}
+	}
+
 	fwriter := func(file io.Writer) (e error) {
 		if len(b.lines) == 0 {
 			return
@@ -177,9 +213,48 @@ func (b *Buffer) saveToFile(filename string, withSudo bool) error {
 		return
 	}
 
+	var raw []byte
+	if hex {
+		if raw, _, err = ParseHexDump(b.Bytes()); err != nil {
+			return err
+		}
+		enc = encoding.Nop
+		fwriter = func(file io.Writer) (e error) {
+			fileSize, e = file.Write(raw)
+			return
+		}
+	}
+	// reflow rewrites the offsets and the ASCII column of the hex view after
+	// its bytes are saved
+	reflow := func() {
+		if hex {
+			b.EventHandler.ApplyDiff(HexDump(raw))
+			b.RelocateCursors()
+		}
+	}
+
+	if b.Type == BTFilter && filename == "" {
+		var out bytes.Buffer
+		w := transform.NewWriter(&out, enc.NewEncoder())
+		if err = fwriter(w); err != nil {
+			return err
+		}
+		if err = w.Close(); err != nil {
+			return err
+		}
+		b.filterOutput = out.Bytes()
+		reflow()
+		if !b.Settings["fastdirty"].(bool) {
+			calcHash(b, &b.origHash)
+		}
+		b.isModified = false
+		return nil
+	}
+
 	if err = overwriteFile(absFilename, enc, fwriter, withSudo); err != nil {
 		return err
 	}
+	reflow()
 
 	if !b.Settings["fastdirty"].(bool) {
 		if fileSize > LargeFileThreshold {
@@ -194,6 +269,21 @@ func (b *Buffer) saveToFile(filename string, withSudo bool) error {
 	absPath, _ := filepath.Abs(filename)
 	b.AbsPath = absPath
 	b.isModified = false
+	// a draft saved to a file is done with
+	b.RemoveDraft()
+	if b.Type == BTScratch {
+		b.Type = BTDefault
+	}
 	b.UpdateRules()
+	// the diff gutter may be shown by a pane only, with the setpane command
+	if (b.Settings["diffgutter"].(bool) || b.diffBase != nil) && b.diffBaseSource() == "disk" {
+		b.UpdateDiffBase()
+	}
+	if len(b.bookmarks) > 0 {
+		// the bookmarks are stored at the lines they moved to while editing
+		if err := b.saveBookmarks(); err != nil {
+			util.Log(util.LogError, "buffer", "Error saving bookmarks:", err)
+		}
+	}
 	return err
 }
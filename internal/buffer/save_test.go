@@ -0,0 +1,73 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+func TestSaveFilter(t *testing.T) {
+	util.Stdout.Reset()
+	defer util.Stdout.Reset()
+
+	// the input is given back as it is when the buffer isn't saved
+	b := NewBufferFromString("a\r\nb\r\n", "", BTFilter)
+	b.Insert(b.Start(), "x")
+	b.Fini()
+	assert.Equal(t, "a\r\nb\r\n", util.Stdout.String())
+	util.Stdout.Reset()
+
+	b = NewBufferFromString("a\r\nb\r\n", "", BTFilter)
+	b.Insert(b.Start(), "x")
+	assert.NoError(t, b.Save())
+	assert.False(t, b.Modified())
+	assert.Equal(t, "", b.Path)
+	b.Insert(b.Start(), "y")
+	b.Fini()
+	assert.Equal(t, "xa\r\nb\r\n", util.Stdout.String())
+}
+
+func TestSaveCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-savecheck-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data.txt")
+
+	b := NewBufferFromString("good\nbad\n", path, BTDefault)
+	defer b.Close()
+	// the check fails on the lines which say bad
+	check := `awk '/bad/ { print FILENAME ":" NR ": found " $0; e = 1 } END { exit e }'`
+	b.Settings["savecheck"] = check
+
+	err = b.Save()
+	assert.True(t, IsCheckError(err))
+	assert.Equal(t, check+": data.txt:2: found bad", err.Error())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	if assert.Len(t, b.Messages, 1) {
+		assert.Equal(t, "found bad", b.Messages[0].Msg)
+		assert.Equal(t, Loc{0, 1}, b.Messages[0].Start)
+		assert.Equal(t, "savecheck", b.Messages[0].Owner)
+	}
+
+	assert.NoError(t, b.ForceSaveAs(path))
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "good\nbad\n", string(data))
+
+	os.Remove(path)
+	b.Replace(Loc{0, 1}, Loc{3, 1}, "ok")
+	assert.NoError(t, b.Save())
+	assert.Empty(t, b.Messages)
+}
+
+func TestIsPermissionError(t *testing.T) {
+	err := &os.PathError{Op: "open", Path: "/etc/hosts", Err: os.ErrPermission}
+	assert.Equal(t, runtime.GOOS != "windows", IsPermissionError(err))
+	assert.False(t, IsPermissionError(&os.PathError{Op: "open", Path: "/etc/hosts", Err: os.ErrNotExist}))
+	assert.False(t, IsPermissionError(&CheckError{Command: "false"}))
+}
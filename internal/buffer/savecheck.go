@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	shellquote "github.com/kballard/go-shellquote"
+	"github.com/zyedidia/micro/v2/internal/quickfix"
+)
+
+// saveCheckTimeout is how long the savecheck command may run before the
+// check fails
+const saveCheckTimeout = 10 * time.Second
+
+// A CheckError is the failure of the savecheck command on the text of a
+// buffer which was being saved, and which wasn't written
+type CheckError struct {
+	Command string
+	// Output is the output and the error output of the command
+	Output string
+}
+
+func (e *CheckError) Error() string {
+	msg := strings.TrimSpace(e.Output)
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	if msg == "" {
+		return e.Command + " failed"
+	}
+	return e.Command + ": " + msg
+}
+
+// IsCheckError returns whether err is the failure of the savecheck command,
+// after which the buffer may still be saved without the check
+func IsCheckError(err error) bool {
+	_, ok := err.(*CheckError)
+	return ok
+}
+
+// saveCheck runs the savecheck command on the text to be written to
+// filename, which is copied to a temporary file of the same name whose path
+// ends the command. The messages located in the file by the errorformat
+// option replace those of the savecheck owner.
+func (b *Buffer) saveCheck(filename string, data []byte) error {
+	command := b.Settings["savecheck"].(string)
+	if command == "" {
+		return nil
+	}
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "micro-savecheck")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Base(filename)
+	tmp := filepath.Join(dir, base)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), saveCheckTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], tmp)...)
+	cmd.Dir = filepath.Dir(filename)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err = cmd.Run()
+	output := strings.Replace(out.String(), tmp, base, -1)
+	if ctx.Err() == context.DeadlineExceeded {
+		output = "timed out"
+	} else if err == nil {
+		b.ClearMessages("savecheck")
+		return nil
+	} else if _, ok := err.(*exec.ExitError); !ok {
+		// a command which can't be run fails too, the save can be forced
+		output = err.Error()
+	}
+
+	var msgs []*Message
+	if format, err := quickfix.ParseFormat(b.Settings["errorformat"].(string)); err == nil {
+		for _, e := range format.Parse(output) {
+			if filepath.Base(e.Path) != base {
+				continue
+			}
+			kind := MsgType(MTError)
+			if e.Kind == "warning" {
+				kind = MTWarning
+			}
+			loc := Loc{e.Col, e.Line}
+			msgs = append(msgs, NewMessage("", e.Text, loc, loc, kind))
+		}
+	}
+	b.SetMessages("savecheck", msgs)
+	return &CheckError{Command: command, Output: output}
+}
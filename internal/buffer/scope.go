@@ -0,0 +1,95 @@
+package buffer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// scopeRegex matches the lines starting a definition with a keyword, such
+// as "func (b *Buffer) Name(", "def name(" or "pub fn name<T>(", and
+// captures the name
+var scopeRegex = regexp.MustCompile(`^\s*(?:(?:export|default|public|private|protected|internal|static|async|pub(?:\([^)]*\))?|unsafe|final|abstract|override|virtual|inline|local|extern)\s+)*(?:func|function|def|fn|fun|class|struct|interface|impl|trait|enum|module|namespace|sub|proc|procedure)\b\s*(?:\([^)]*\)\s*)?([\w$]+(?:(?:\.|::)[\w$]+)*)`)
+
+// headerRegex matches the headers of markup languages such as markdown
+var headerRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// maxScopeLines limits how many lines are read backwards by Scope
+const maxScopeLines = 5000
+
+// scopeCache is the result of the last call to Scope, which is computed
+// again when the line or the text changes
+type scopeCache struct {
+	valid          bool
+	y, edits, undo int
+	name           string
+}
+
+// Scope returns the names of the definitions containing line y, such as a
+// class and its method, separated by dots. The definitions are recognized
+// by their keyword and their content by its indentation, which works for
+// most languages without parsing them.
+func (b *Buffer) Scope(y int) string {
+	c := &b.scope
+	if c.valid && c.y == y && c.edits == b.Edits() && c.undo == b.UndoStack.Len() {
+		return c.name
+	}
+
+	tabsize := util.IntOpt(b.Settings["tabsize"])
+	var names []string
+	indent := -1
+	for i := y; i >= 0 && i > y-maxScopeLines; i-- {
+		l := b.LineBytes(i)
+		if util.IsSpacesOrTabs(l) {
+			continue
+		}
+		ws := util.GetLeadingWhitespace(l)
+		w := util.StringWidth(ws, util.CharacterCount(ws), tabsize)
+		if indent >= 0 && w >= indent {
+			continue
+		}
+		indent = w
+		if m := scopeRegex.FindSubmatch(l); m != nil {
+			names = append(names, string(m[1]))
+		}
+		if w == 0 {
+			break
+		}
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+
+	*c = scopeCache{true, y, b.Edits(), b.UndoStack.Len(), strings.Join(names, ".")}
+	return c.name
+}
+
+// A Symbol is a definition found by Symbols
+type Symbol struct {
+	// Name is the name of the definition preceded by the names of the
+	// definitions containing it, as returned by Scope
+	Name string
+	Loc  Loc
+}
+
+// Symbols returns the definitions of the buffer recognized by Scope, or its
+// headers if it is a markdown file
+func (b *Buffer) Symbols() []Symbol {
+	var syms []Symbol
+	markdown := b.Settings["filetype"] == "markdown"
+	for y := 0; y < b.LinesNum(); y++ {
+		l := b.LineBytes(y)
+		if markdown {
+			if m := headerRegex.FindSubmatch(l); m != nil {
+				syms = append(syms, Symbol{string(m[1]) + " " + string(m[2]), Loc{0, y}})
+			}
+			continue
+		}
+		if m := scopeRegex.FindSubmatchIndex(l); m != nil {
+			x := util.CharacterCount(l[:m[2]])
+			syms = append(syms, Symbol{b.Scope(y), Loc{x, y}})
+		}
+	}
+	return syms
+}
@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	b := NewBufferFromString(`class Shape:
+    def area(self):
+
+        return 0
+
+    size = 1
+
+func (b *Buffer) Scope(y int) string {
+	if y > 0 {
+		return ""
+	}
+}
+x = 1`, "", BTDefault)
+
+	for y, scope := range []string{"Shape", "Shape.area", "Shape.area", "Shape.area", "Shape.area", "Shape", "Shape",
+		"Scope", "Scope", "Scope", "Scope", "", ""} {
+		assert.Equal(t, scope, b.Scope(y), y)
+	}
+
+	// the cached name is computed again after an edit
+	b.Insert(Loc{X: 0, Y: 7}, "pub fn other() {\n")
+	assert.Equal(t, "other", b.Scope(7))
+	b.Undo()
+	assert.Equal(t, "Scope", b.Scope(7))
+}
+
+func TestSymbols(t *testing.T) {
+	b := NewBufferFromString("class Shape:\n    def area(self):\n        return 0\n\ndef main():\n    pass", "", BTDefault)
+	assert.Equal(t, []Symbol{
+		{"Shape", Loc{6, 0}},
+		{"Shape.area", Loc{8, 1}},
+		{"main", Loc{4, 4}},
+	}, b.Symbols())
+
+	b = NewBufferFromString("# Title\n\ntext\n## Part ##\n", "", BTDefault)
+	b.Settings["filetype"] = "markdown"
+	assert.Equal(t, []Symbol{{"# Title", Loc{0, 0}}, {"## Part", Loc{0, 3}}}, b.Symbols())
+}
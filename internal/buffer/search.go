@@ -2,11 +2,31 @@ package buffer
 
 import (
 	"regexp"
+	"sort"
 
 	"github.com/zyedidia/micro/v2/internal/util"
 )
 
-func (b *Buffer) findDown(r *regexp.Regexp, start, end Loc) ([2]Loc, bool) {
+// lineRange returns the byte offsets on line y of the characters start.X and
+// end.X, or of the start and end of the line if y is not the first or last
+// line of the range
+func (b *Buffer) lineRange(y int, start, end Loc) (int, int) {
+	l := b.LineBytes(y)
+	nchars := util.CharacterCount(l)
+	from, to := 0, len(l)
+	if y == start.Y {
+		from = len(util.SliceStart(l, util.Clamp(start.X, 0, nchars)))
+	}
+	if y == end.Y {
+		to = len(util.SliceStart(l, util.Clamp(end.X, 0, nchars)))
+	}
+	return from, to
+}
+
+// The search functions match r against the part of each line within the
+// searched range, which the pcre engine may look behind
+
+func (b *Buffer) findDown(r util.Regexp, start, end Loc) ([2]Loc, bool) {
 	lastcn := util.CharacterCount(b.LineBytes(b.LinesNum() - 1))
 	if start.Y > b.LinesNum()-1 {
 		start.X = lastcn - 1
@@ -23,38 +43,18 @@ func (b *Buffer) findDown(r *regexp.Regexp, start, end Loc) ([2]Loc, bool) {
 
 	for i := start.Y; i <= end.Y; i++ {
 		l := b.LineBytes(i)
-		charpos := 0
-
-		if i == start.Y && start.Y == end.Y {
-			nchars := util.CharacterCount(l)
-			start.X = util.Clamp(start.X, 0, nchars)
-			end.X = util.Clamp(end.X, 0, nchars)
-			l = util.SliceStart(l, end.X)
-			l = util.SliceEnd(l, start.X)
-			charpos = start.X
-		} else if i == start.Y {
-			nchars := util.CharacterCount(l)
-			start.X = util.Clamp(start.X, 0, nchars)
-			l = util.SliceEnd(l, start.X)
-			charpos = start.X
-		} else if i == end.Y {
-			nchars := util.CharacterCount(l)
-			end.X = util.Clamp(end.X, 0, nchars)
-			l = util.SliceStart(l, end.X)
-		}
-
-		match := r.FindIndex(l)
+		from, to := b.lineRange(i, start, end)
 
-		if match != nil {
-			start := Loc{charpos + util.RunePos(l, match[0]), i}
-			end := Loc{charpos + util.RunePos(l, match[1]), i}
+		if match := util.FindAllIndexRange(r, l, from, to, 1); match != nil {
+			start := Loc{util.RunePos(l, match[0][0]), i}
+			end := Loc{util.RunePos(l, match[0][1]), i}
 			return [2]Loc{start, end}, true
 		}
 	}
 	return [2]Loc{}, false
 }
 
-func (b *Buffer) findUp(r *regexp.Regexp, start, end Loc) ([2]Loc, bool) {
+func (b *Buffer) findUp(r util.Regexp, start, end Loc) ([2]Loc, bool) {
 	lastcn := util.CharacterCount(b.LineBytes(b.LinesNum() - 1))
 	if start.Y > b.LinesNum()-1 {
 		start.X = lastcn - 1
@@ -71,32 +71,12 @@ func (b *Buffer) findUp(r *regexp.Regexp, start, end Loc) ([2]Loc, bool) {
 
 	for i := end.Y; i >= start.Y; i-- {
 		l := b.LineBytes(i)
-		charpos := 0
-
-		if i == start.Y && start.Y == end.Y {
-			nchars := util.CharacterCount(l)
-			start.X = util.Clamp(start.X, 0, nchars)
-			end.X = util.Clamp(end.X, 0, nchars)
-			l = util.SliceStart(l, end.X)
-			l = util.SliceEnd(l, start.X)
-			charpos = start.X
-		} else if i == start.Y {
-			nchars := util.CharacterCount(l)
-			start.X = util.Clamp(start.X, 0, nchars)
-			l = util.SliceEnd(l, start.X)
-			charpos = start.X
-		} else if i == end.Y {
-			nchars := util.CharacterCount(l)
-			end.X = util.Clamp(end.X, 0, nchars)
-			l = util.SliceStart(l, end.X)
-		}
-
-		all_matches := r.FindAllIndex(l, -1)
+		from, to := b.lineRange(i, start, end)
 
-		if all_matches != nil {
-			match := all_matches[len(all_matches)-1]
-			start := Loc{charpos + util.RunePos(l, match[0]), i}
-			end := Loc{charpos + util.RunePos(l, match[1]), i}
+		if all := util.FindAllIndexRange(r, l, from, to, -1); all != nil {
+			last := all[len(all)-1]
+			start := Loc{util.RunePos(l, last[0]), i}
+			end := Loc{util.RunePos(l, last[1]), i}
 			return [2]Loc{start, end}, true
 		}
 	}
@@ -112,22 +92,15 @@ func (b *Buffer) FindNext(s string, start, end, from Loc, down bool, useRegex bo
 		return [2]Loc{}, false, nil
 	}
 
-	var r *regexp.Regexp
-	var err error
-
-	if !useRegex {
-		s = regexp.QuoteMeta(s)
-	}
-
-	if b.Settings["ignorecase"].(bool) {
-		r, err = regexp.Compile("(?i)" + s)
-	} else {
-		r, err = regexp.Compile(s)
-	}
-
+	r, err := b.compileSearch(s, useRegex)
 	if err != nil {
 		return [2]Loc{}, false, err
 	}
+	if b.Huge != nil && start == b.Start() && end == b.End() {
+		// the whole file of the viewer rather than the part in the buffer
+		l, found := b.findHuge(r, from, down)
+		return l, found, nil
+	}
 
 	var found bool
 	var l [2]Loc
@@ -145,10 +118,130 @@ func (b *Buffer) FindNext(s string, start, end, from Loc, down bool, useRegex bo
 	return l, found, nil
 }
 
+// FindAll returns the occurrences of a given string or regex between start
+// and end, leaving out the empty matches
+func (b *Buffer) FindAll(s string, start, end Loc, useRegex bool) ([][2]Loc, error) {
+	if s == "" {
+		return nil, nil
+	}
+	r, err := b.compileSearch(s, useRegex)
+	if err != nil {
+		return nil, err
+	}
+	if start.GreaterThan(end) {
+		start, end = end, start
+	}
+
+	var matches [][2]Loc
+	for y := start.Y; y <= end.Y && y < b.LinesNum(); y++ {
+		from, to := b.lineRange(y, start, end)
+		l := b.LineBytes(y)
+		for _, m := range util.FindAllIndexRange(r, l, from, to, -1) {
+			if m[0] == m[1] {
+				continue
+			}
+			matches = append(matches, [2]Loc{{util.RunePos(l, m[0]), y}, {util.RunePos(l, m[1]), y}})
+		}
+	}
+	return matches, nil
+}
+
+// compileSearch returns the regular expression for a search, taking the
+// ignorecase and regexengine options into account
+func (b *Buffer) compileSearch(s string, useRegex bool) (util.Regexp, error) {
+	if !useRegex {
+		s = regexp.QuoteMeta(s)
+	}
+	if b.Settings["ignorecase"].(bool) {
+		s = "(?i)" + s
+	}
+	return util.CompileRegexp(s, b.Settings["regexengine"].(string))
+}
+
+// A searchCache is the regular expression of the last search, compiled
+// again when the search or the options it depends on change, and the
+// matches counted by SearchMatchCount, counted again when the text changes
+type searchCache struct {
+	valid             bool
+	search, engine    string
+	regex, ignorecase bool
+	re                util.Regexp
+
+	// starts are the starts of the first max matches of counted in the
+	// text of gen
+	counted util.Regexp
+	gen     uint64
+	max     int
+	starts  []Loc
+}
+
+// SearchHighlightRegex returns the regular expression of the search whose
+// matches should be highlighted, or nil if there is none
+func (b *Buffer) SearchHighlightRegex() util.Regexp {
+	if !b.HighlightSearch || b.LastSearch == "" || !b.Settings["hlsearch"].(bool) {
+		return nil
+	}
+	c := &b.search
+	engine := b.Settings["regexengine"].(string)
+	ignorecase := b.Settings["ignorecase"].(bool)
+	if !c.valid || c.search != b.LastSearch || c.regex != b.LastSearchRegex || c.engine != engine || c.ignorecase != ignorecase {
+		*c = searchCache{valid: true, search: b.LastSearch, engine: engine, regex: b.LastSearchRegex, ignorecase: ignorecase}
+		if r, err := b.compileSearch(b.LastSearch, b.LastSearchRegex); err == nil {
+			c.re = r
+		}
+	}
+	return c.re
+}
+
+// LineMatches returns the character ranges of the matches of r on line y
+func (b *Buffer) LineMatches(r util.Regexp, y int) [][2]int {
+	l := b.LineBytes(y)
+	var matches [][2]int
+	for _, m := range r.FindAllIndex(l, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		matches = append(matches, [2]int{util.RunePos(l, m[0]), util.RunePos(l, m[1])})
+	}
+	return matches
+}
+
+// SearchMatchCount returns the number of the match of r which starts at or
+// before loc, counting from 1, and the total number of matches. Counting
+// stops after max matches. The matches are only counted again when r or
+// the text change, since the statusline shows them on every redraw.
+func (b *Buffer) SearchMatchCount(r util.Regexp, loc Loc, max int) (int, int) {
+	c := &b.search
+	if c.counted != r || c.gen != b.hash.gen || c.max != max {
+		c.counted, c.gen, c.max, c.starts = r, b.hash.gen, max, c.starts[:0]
+		for y := 0; y < b.LinesNum() && len(c.starts) < max; y++ {
+			for _, m := range b.LineMatches(r, y) {
+				c.starts = append(c.starts, Loc{m[0], y})
+				if len(c.starts) >= max {
+					break
+				}
+			}
+		}
+	}
+	cur := sort.Search(len(c.starts), func(i int) bool {
+		return loc.LessThan(c.starts[i])
+	})
+	return cur, len(c.starts)
+}
+
 // ReplaceRegex replaces all occurrences of 'search' with 'replace' in the given area
 // and returns the number of replacements made and the number of runes
 // added or removed on the last line of the range
-func (b *Buffer) ReplaceRegex(start, end Loc, search *regexp.Regexp, replace []byte) (int, int) {
+func (b *Buffer) ReplaceRegex(start, end Loc, search util.Regexp, replace []byte) (int, int) {
+	return b.ReplaceRegexFunc(start, end, search, func(src []byte, match []int) []byte {
+		return util.ExpandTemplate(search, nil, replace, src, match)
+	})
+}
+
+// ReplaceRegexFunc is like ReplaceRegex, but the replacement of each match
+// is returned by repl, given the line and the indices of the match and its
+// submatches in it
+func (b *Buffer) ReplaceRegexFunc(start, end Loc, search util.Regexp, repl func(src []byte, match []int) []byte) (int, int) {
 	if start.GreaterThan(end) {
 		start, end = end, start
 	}
@@ -159,36 +252,43 @@ func (b *Buffer) ReplaceRegex(start, end Loc, search *regexp.Regexp, replace []b
 	var deltas []Delta
 	for i := start.Y; i <= end.Y; i++ {
 		l := b.lines[i].data
-		charpos := 0
-
-		if start.Y == end.Y && i == start.Y {
-			l = util.SliceStart(l, end.X)
-			l = util.SliceEnd(l, start.X)
-			charpos = start.X
-		} else if i == start.Y {
-			l = util.SliceEnd(l, start.X)
-			charpos = start.X
-		} else if i == end.Y {
-			l = util.SliceStart(l, end.X)
-		}
-		newText := search.ReplaceAllFunc(l, func(in []byte) []byte {
-			result := []byte{}
-			for _, submatches := range search.FindAllSubmatchIndex(in, -1) {
-				result = search.Expand(result, replace, in, submatches)
-			}
-			found++
-			if i == end.Y {
-				netrunes += util.CharacterCount(result) - util.CharacterCount(in)
-			}
-			return result
+		from, to := b.lineRange(i, start, end)
+
+		newText, n := util.ReplaceRangeFunc(search, l, from, to, func(m []int) []byte {
+			return repl(l, m)
 		})
+		found += n
+		if i == end.Y {
+			netrunes += util.CharacterCount(newText) - util.CharacterCount(l[from:to])
+		}
 
-		from := Loc{charpos, i}
-		to := Loc{charpos + util.CharacterCount(l), i}
+		fromLoc := Loc{util.RunePos(l, from), i}
+		toLoc := Loc{util.RunePos(l, to), i}
 
-		deltas = append(deltas, Delta{newText, from, to})
+		deltas = append(deltas, Delta{newText, fromLoc, toLoc})
 	}
 	b.MultipleReplace(deltas)
 
 	return found, netrunes
 }
+
+// ReplaceRegexLines replaces all occurrences of 'search' with 'replace' on
+// the given lines as a single undoable event and returns the number of
+// replacements made
+func (b *Buffer) ReplaceRegexLines(lines []int, search util.Regexp, replace []byte) int {
+	found := 0
+	var deltas []Delta
+	for _, y := range lines {
+		if y < 0 || y >= b.LinesNum() {
+			continue
+		}
+		l := b.LineBytes(y)
+		newText, n := util.ReplaceRange(search, l, replace, 0, len(l))
+		found += n
+		deltas = append(deltas, Delta{newText, Loc{0, y}, Loc{util.CharacterCount(l), y}})
+	}
+	if len(deltas) > 0 {
+		b.MultipleReplace(deltas)
+	}
+	return found
+}
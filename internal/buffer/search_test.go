@@ -0,0 +1,112 @@
+package buffer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceRegexLines(t *testing.T) {
+	b := NewBufferFromString("foo one\nfoo two\nfoo foo\n", "", BTDefault)
+
+	n := b.ReplaceRegexLines([]int{0, 2, 10}, regexp.MustCompile("f(o+)"), []byte("b${1}x"))
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "boox one\nfoo two\nboox boox\n", string(b.Bytes()))
+
+	b.Undo()
+	assert.Equal(t, "foo one\nfoo two\nfoo foo\n", string(b.Bytes()))
+}
+
+func TestSearchMatchCount(t *testing.T) {
+	b := NewBufferFromString("foo bar foo\nbaz\nFOO\n", "", BTDefault)
+	assert.Nil(t, b.SearchHighlightRegex())
+
+	b.LastSearch = "foo"
+	b.HighlightSearch = true
+	r := b.SearchHighlightRegex()
+	assert.NotNil(t, r)
+
+	assert.Equal(t, [][2]int{{0, 3}, {8, 11}}, b.LineMatches(r, 0))
+
+	cur, total := b.SearchMatchCount(r, Loc{8, 0}, 100)
+	assert.Equal(t, 2, cur)
+	assert.Equal(t, 3, total)
+
+	cur, total = b.SearchMatchCount(r, Loc{0, 1}, 2)
+	assert.Equal(t, 2, cur)
+	assert.Equal(t, 2, total)
+
+	// the regex is compiled again and the matches counted again only when
+	// they change
+	assert.True(t, r == b.SearchHighlightRegex())
+	b.Insert(Loc{0, 1}, "foo ")
+	cur, total = b.SearchMatchCount(r, Loc{0, 1}, 100)
+	assert.Equal(t, 3, cur)
+	assert.Equal(t, 4, total)
+	b.SetOptionNative("ignorecase", false)
+	r = b.SearchHighlightRegex()
+	_, total = b.SearchMatchCount(r, Loc{0, 1}, 100)
+	assert.Equal(t, 3, total)
+
+	b.SetOptionNative("hlsearch", false)
+	assert.Nil(t, b.SearchHighlightRegex())
+}
+
+func TestFindNextLookaround(t *testing.T) {
+	b := NewBufferFromString("foobar bazbar\n", "", BTDefault)
+	b.SetOptionNative("regexengine", "pcre")
+
+	match, found, err := b.FindNext("(?<=baz)bar", b.Start(), b.End(), b.Start(), true, true)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, [2]Loc{{10, 0}, {13, 0}}, match)
+
+	// the lookbehind sees the text before the replaced range
+	r, _ := b.compileSearch("(?<=baz)bar", true)
+	n, _ := b.ReplaceRegex(match[0], match[1], r, []byte("qux"))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "foobar bazqux\n", string(b.Bytes()))
+}
+
+func TestFindRange(t *testing.T) {
+	for _, engine := range []string{"go", "pcre"} {
+		b := NewBufferFromString("aaa\n", "", BTDefault)
+		b.SetOptionNative("regexengine", engine)
+
+		// the match overlapping the one before the start is found
+		match, found, err := b.FindNext("aa", b.Start(), b.End(), Loc{1, 0}, true, false)
+		assert.Nil(t, err)
+		assert.True(t, found, engine)
+		assert.Equal(t, [2]Loc{{1, 0}, {3, 0}}, match, engine)
+
+		match, found, _ = b.FindNext("aa", b.Start(), b.End(), Loc{2, 0}, false, false)
+		assert.True(t, found, engine)
+		assert.Equal(t, [2]Loc{{0, 0}, {2, 0}}, match, engine)
+
+		// the matches running past the end of the range are cut at its end
+		b = NewBufferFromString("aaaa\n", "", BTDefault)
+		b.SetOptionNative("regexengine", engine)
+		r, _ := b.compileSearch("a+", true)
+		n, _ := b.ReplaceRegex(Loc{0, 0}, Loc{2, 0}, r, []byte("b"))
+		assert.Equal(t, 1, n, engine)
+		assert.Equal(t, "baa\n", string(b.Bytes()), engine)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	b := NewBufferFromString("a=1, b=22\nc=333\n", "", BTDefault)
+
+	matches, err := b.FindAll(`\d+`, b.Start(), b.End(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, [][2]Loc{{{2, 0}, {3, 0}}, {{7, 0}, {9, 0}}, {{2, 1}, {5, 1}}}, matches)
+
+	// the text within the range is matched on its own
+	matches, _ = b.FindAll(`\d+`, Loc{3, 0}, Loc{4, 1}, true)
+	assert.Equal(t, [][2]Loc{{{7, 0}, {9, 0}}, {{2, 1}, {4, 1}}}, matches)
+
+	matches, _ = b.FindAll(`x*`, b.Start(), b.End(), true)
+	assert.Empty(t, matches)
+	_, err = b.FindAll(`(`, b.Start(), b.End(), true)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,106 @@
+package buffer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// ParagraphAt returns the first and last lines of the paragraph of line y,
+// the lines which aren't blank around it, y being one of them
+func (b *Buffer) ParagraphAt(y int) (int, int) {
+	y1, y2 := y, y
+	for y1 > 0 && !b.blankLine(y1-1) {
+		y1--
+	}
+	for y2+1 < b.LinesNum() && !b.blankLine(y2+1) {
+		y2++
+	}
+	return y1, y2
+}
+
+// sentenceStarts returns the starts of the sentences of the lines from y1
+// to y2. A sentence ends with a period, an exclamation or question mark,
+// possibly followed by closing brackets or quotes, and then whitespace or
+// the end of a line.
+func (b *Buffer) sentenceStarts(y1, y2 int) []Loc {
+	var starts []Loc
+	atStart, ending := true, false
+	for y := y1; y <= y2; y++ {
+		line := b.LineBytes(y)
+		for x := 0; len(line) > 0; x++ {
+			r, _, size := util.DecodeCharacter(line)
+			line = line[size:]
+			switch {
+			case unicode.IsSpace(r):
+				atStart = atStart || ending
+				ending = false
+				continue
+			case atStart:
+				starts = append(starts, Loc{X: x, Y: y})
+				atStart = false
+			}
+			if strings.ContainsRune(".!?", r) {
+				ending = true
+			} else if !strings.ContainsRune(`)]"'’”`, r) {
+				ending = false
+			}
+		}
+		atStart = atStart || ending
+		ending = false
+	}
+	return starts
+}
+
+// SentenceNext returns the start of the sentence after loc, or the blank
+// line ending its paragraph, or the end of the buffer
+func (b *Buffer) SentenceNext(loc Loc) Loc {
+	y := loc.Y
+	if b.blankLine(y) {
+		for y < b.LinesNum() && b.blankLine(y) {
+			y++
+		}
+		if y == b.LinesNum() {
+			return b.End()
+		}
+		return b.sentenceStarts(y, y)[0]
+	}
+	y1, y2 := b.ParagraphAt(y)
+	for _, s := range b.sentenceStarts(y1, y2) {
+		if loc.LessThan(s) {
+			return s
+		}
+	}
+	if y2+1 < b.LinesNum() {
+		return Loc{X: 0, Y: y2 + 1}
+	}
+	return b.End()
+}
+
+// SentencePrevious returns the start of the sentence of loc, or of the one
+// before if loc is at its start, or the blank line starting its paragraph,
+// or the start of the buffer
+func (b *Buffer) SentencePrevious(loc Loc) Loc {
+	y := loc.Y
+	if b.blankLine(y) {
+		for y >= 0 && b.blankLine(y) {
+			y--
+		}
+		if y < 0 {
+			return b.Start()
+		}
+		loc = Loc{X: util.CharacterCount(b.LineBytes(y)), Y: y}
+	}
+	y1, y2 := b.ParagraphAt(y)
+	starts := b.sentenceStarts(y1, y2)
+	for i := len(starts) - 1; i >= 0; i-- {
+		if starts[i].LessThan(loc) {
+			return starts[i]
+		}
+	}
+	if y1 > 0 {
+		return Loc{X: 0, Y: y1 - 1}
+	}
+	return b.Start()
+}
@@ -0,0 +1,29 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentences(t *testing.T) {
+	b := NewBufferFromString("One. Two (really) and\nmore.  \"Three?\" Mr.x four\n\n  Five.", "", BTDefault)
+	defer b.Close()
+
+	var locs []Loc
+	for loc := b.Start(); loc != b.End(); {
+		loc = b.SentenceNext(loc)
+		locs = append(locs, loc)
+	}
+	assert.Equal(t, []Loc{{5, 0}, {7, 1}, {16, 1}, {0, 2}, {2, 3}, b.End()}, locs)
+
+	locs = nil
+	for loc := b.End(); loc != b.Start(); {
+		loc = b.SentencePrevious(loc)
+		locs = append(locs, loc)
+	}
+	assert.Equal(t, []Loc{{2, 3}, {0, 2}, {16, 1}, {7, 1}, {5, 0}, {0, 0}}, locs)
+
+	// from the middle of a sentence to its start
+	assert.Equal(t, Loc{X: 5, Y: 0}, b.SentencePrevious(Loc{X: 3, Y: 1}))
+}
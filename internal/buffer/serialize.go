@@ -20,9 +20,10 @@ type SerializedBuffer struct {
 	EventHandler *EventHandler
 	Cursor       Loc
 	ModTime      time.Time
+	Folds        []Fold
 }
 
-// Serialize serializes the buffer to config.ConfigDir/buffers
+// Serialize serializes the buffer to config.StateDir/buffers
 func (b *Buffer) Serialize() error {
 	if !b.Settings["savecursor"].(bool) && !b.Settings["saveundo"].(bool) {
 		return nil
@@ -31,26 +32,27 @@ func (b *Buffer) Serialize() error {
 		return nil
 	}
 
-	name := filepath.Join(config.ConfigDir, "buffers", util.EscapePath(b.AbsPath))
+	name := filepath.Join(config.StateDir, "buffers", util.EscapePath(b.AbsPath))
 
 	return overwriteFile(name, encoding.Nop, func(file io.Writer) error {
 		err := gob.NewEncoder(file).Encode(SerializedBuffer{
 			b.EventHandler,
 			b.GetActiveCursor().Loc,
 			b.ModTime,
+			b.folds,
 		})
 		return err
 	}, false)
 }
 
-// Unserialize loads the buffer info from config.ConfigDir/buffers
+// Unserialize loads the buffer info from config.StateDir/buffers
 func (b *Buffer) Unserialize() error {
 	// If either savecursor or saveundo is turned on, we need to load the serialized information
 	// from ~/.config/micro/buffers
 	if b.Path == "" {
 		return nil
 	}
-	file, err := os.Open(filepath.Join(config.ConfigDir, "buffers", util.EscapePath(b.AbsPath)))
+	file, err := os.Open(filepath.Join(config.StateDir, "buffers", util.EscapePath(b.AbsPath)))
 	if err == nil {
 		defer file.Close()
 		var buffer SerializedBuffer
@@ -61,6 +63,10 @@ func (b *Buffer) Unserialize() error {
 		}
 		if b.Settings["savecursor"].(bool) {
 			b.StartCursor = buffer.Cursor
+			// the folds are only kept in the file they were made in
+			if b.ModTime == buffer.ModTime {
+				b.SetFolds(buffer.Folds)
+			}
 		}
 
 		if b.Settings["saveundo"].(bool) {
@@ -6,6 +6,11 @@ import (
 )
 
 func (b *Buffer) SetOptionNative(option string, nativeValue interface{}) error {
+	if option == "hex" && nativeValue != b.Settings[option] {
+		if err := b.setHex(nativeValue.(bool)); err != nil {
+			return err
+		}
+	}
 	b.Settings[option] = nativeValue
 
 	if option == "fastdirty" {
@@ -13,7 +18,7 @@ func (b *Buffer) SetOptionNative(option string, nativeValue interface{}) error {
 			if !b.Modified() {
 				e := calcHash(b, &b.origHash)
 				if e == ErrFileTooLarge {
-					b.Settings["fastdirty"] = false
+					b.Settings["fastdirty"] = true
 				}
 			}
 		}
@@ -37,8 +42,16 @@ func (b *Buffer) SetOptionNative(option string, nativeValue interface{}) error {
 		}
 	} else if option == "encoding" {
 		b.isModified = true
-	} else if option == "readonly" && b.Type.Kind == BTDefault.Kind {
-		b.Type.Readonly = nativeValue.(bool)
+	} else if option == "follow" {
+		if nativeValue.(bool) {
+			b.startFollow()
+		} else {
+			b.stopFollow()
+		}
+	} else if option == "diffbase" || (option == "diffgutter" && nativeValue.(bool)) {
+		b.UpdateDiffBase()
+	} else if (option == "readonly" || option == "viewmode") && b.Type.Kind == BTDefault.Kind {
+		b.Type.Readonly = b.Settings["readonly"].(bool) || b.Settings["viewmode"].(bool)
 	}
 
 	if b.OptionCallback != nil {
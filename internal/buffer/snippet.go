@@ -0,0 +1,602 @@
+package buffer
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zyedidia/json5"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A Snippet is a template of text inserted in place of its prefix
+type Snippet struct {
+	Prefix      string
+	Body        string
+	Description string
+}
+
+// Snippets returns the snippets of a filetype, from the snippet runtime
+// files named after it. A snippets file is either a JSON object of
+// snippets in the format of VSCode, each with a prefix (or a list of
+// prefixes), a body (a string or a list of lines) and a description, or a
+// list of snippets starting with a line "snippet <prefix>" followed by the
+// lines of the body, indented by a tab.
+func Snippets(filetype string) []Snippet {
+	var snippets []Snippet
+	for _, f := range config.ListRuntimeFiles(config.RTSnippet) {
+		if f.Name() != filetype {
+			continue
+		}
+		data, err := f.Data()
+		if err != nil {
+			continue
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte{'{'}) {
+			snippets = append(snippets, parseJSONSnippets(data)...)
+		} else {
+			snippets = append(snippets, parseSnippets(data)...)
+		}
+	}
+	return snippets
+}
+
+// parseSnippets parses a snippets file. Lines starting with # outside of
+// a body are comments.
+func parseSnippets(data []byte) []Snippet {
+	var snippets []Snippet
+	var body []string
+	end := func() {
+		if len(snippets) > 0 && body != nil {
+			snippets[len(snippets)-1].Body = strings.Join(body, "\n")
+		}
+		body = nil
+	}
+
+	for _, l := range bytes.Split(data, []byte{'\n'}) {
+		line := strings.TrimRight(string(l), "\r")
+		switch {
+		case strings.HasPrefix(line, "\t") && len(snippets) > 0:
+			body = append(body, line[1:])
+		case strings.HasPrefix(line, "snippet "):
+			end()
+			if name := strings.TrimSpace(line[len("snippet "):]); name != "" {
+				snippets = append(snippets, Snippet{Prefix: name})
+			}
+		case strings.TrimSpace(line) == "" && body != nil:
+			body = append(body, "")
+		default:
+			end()
+		}
+	}
+	end()
+
+	// trailing empty lines separate snippets and are not part of the body
+	for i := range snippets {
+		snippets[i].Body = strings.TrimRight(snippets[i].Body, "\n")
+	}
+	return snippets
+}
+
+// parseJSONSnippets parses a snippets file in the format of VSCode, whose
+// snippets are sorted by name. The snippets which are not valid are left
+// out.
+func parseJSONSnippets(data []byte) []Snippet {
+	var parsed map[string]struct {
+		Prefix      interface{} `json:"prefix"`
+		Body        interface{} `json:"body"`
+		Description string      `json:"description"`
+	}
+	if err := json5.Unmarshal(data, &parsed); err != nil {
+		return nil
+	}
+
+	// strs returns the value of a field which is a string or a list of
+	// strings
+	strs := func(v interface{}) []string {
+		switch v := v.(type) {
+		case string:
+			return []string{v}
+		case []interface{}:
+			var res []string
+			for _, s := range v {
+				if s, ok := s.(string); ok {
+					res = append(res, s)
+				}
+			}
+			return res
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(parsed))
+	for name := range parsed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var snippets []Snippet
+	for _, name := range names {
+		s := parsed[name]
+		body := strings.Join(strs(s.Body), "\n")
+		for _, prefix := range strs(s.Prefix) {
+			snippets = append(snippets, Snippet{Prefix: prefix, Body: body, Description: s.Description})
+		}
+	}
+	return snippets
+}
+
+// SnippetBefore returns the snippet of the filetype of the buffer whose
+// prefix is the text before loc, the longest if several are, and the start
+// of the prefix. A prefix starting with a word character must start a word.
+func (b *Buffer) SnippetBefore(loc Loc) (Snippet, Loc, bool) {
+	line := []rune(string(b.LineBytes(loc.Y)))
+	x := util.Clamp(loc.X, 0, len(line))
+	var found Snippet
+	start, ok := loc, false
+	for _, s := range Snippets(b.FileType()) {
+		p := []rune(s.Prefix)
+		n := len(p)
+		if n == 0 || n > x || string(line[x-n:x]) != s.Prefix || ok && n <= len([]rune(found.Prefix)) {
+			continue
+		}
+		if util.IsWordChar(p[0]) && x-n > 0 && util.IsWordChar(line[x-n-1]) {
+			continue
+		}
+		found, start, ok = s, Loc{X: x - n, Y: loc.Y}, true
+	}
+	return found, start, ok
+}
+
+// A snippetNode is a piece of the body of a snippet: a text, a tab stop, a
+// placeholder (a tab stop with a default content), a choice or a variable
+type snippetNode struct {
+	text string
+	// num is the number of the tab stop, or -1
+	num      int
+	variable string
+	children []snippetNode
+	choices  []string
+}
+
+// parseSnippetBody parses the body of a snippet in the syntax of VSCode:
+// $N and ${N} are tab stops, ${N:default} placeholders, which may contain
+// other placeholders, ${N|one,two|} choices, and $NAME, ${NAME} and
+// ${NAME:default} variables. \ escapes $, } and \.
+func parseSnippetBody(body string) []snippetNode {
+	p := &snippetParser{body: []rune(body)}
+	return p.parse(false)
+}
+
+type snippetParser struct {
+	body []rune
+	i    int
+}
+
+func (p *snippetParser) peek(off int) rune {
+	if p.i+off < len(p.body) {
+		return p.body[p.i+off]
+	}
+	return 0
+}
+
+// parse parses nodes until the end of the body or, if nested, the closing
+// brace of the placeholder, which is consumed
+func (p *snippetParser) parse(nested bool) []snippetNode {
+	var nodes []snippetNode
+	var text []rune
+	flush := func() {
+		if len(text) > 0 {
+			nodes = append(nodes, snippetNode{text: string(text), num: -1})
+			text = nil
+		}
+	}
+
+	for p.i < len(p.body) {
+		r := p.body[p.i]
+		switch {
+		case r == '\\' && strings.ContainsRune("$}\\", p.peek(1)):
+			text = append(text, p.peek(1))
+			p.i += 2
+		case r == '}' && nested:
+			p.i++
+			flush()
+			return nodes
+		case r == '$':
+			start := p.i
+			if n, ok := p.element(); ok {
+				flush()
+				nodes = append(nodes, n)
+			} else {
+				p.i = start + 1
+				text = append(text, '$')
+			}
+		default:
+			text = append(text, r)
+			p.i++
+		}
+	}
+	flush()
+	return nodes
+}
+
+// number parses the digits at the current position, if any
+func (p *snippetParser) number() (int, bool) {
+	start := p.i
+	for p.i < len(p.body) && p.body[p.i] >= '0' && p.body[p.i] <= '9' {
+		p.i++
+	}
+	if p.i == start {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(p.body[start:p.i]))
+	return n, err == nil
+}
+
+// name parses the name of a variable at the current position, if any
+func (p *snippetParser) name() (string, bool) {
+	start := p.i
+	for p.i < len(p.body) {
+		r := p.body[p.i]
+		if r != '_' && !unicode.IsLetter(r) && (p.i == start || !unicode.IsDigit(r)) {
+			break
+		}
+		p.i++
+	}
+	return string(p.body[start:p.i]), p.i > start
+}
+
+// element parses the tab stop, placeholder, choice or variable starting
+// with the $ at the current position
+func (p *snippetParser) element() (snippetNode, bool) {
+	p.i++
+	n := snippetNode{num: -1}
+	if p.peek(0) != '{' {
+		if num, ok := p.number(); ok {
+			n.num = num
+			return n, true
+		}
+		name, ok := p.name()
+		n.variable = name
+		return n, ok
+	}
+
+	p.i++
+	if num, ok := p.number(); ok {
+		n.num = num
+	} else if name, ok := p.name(); ok {
+		n.variable = name
+	} else {
+		return n, false
+	}
+
+	switch p.peek(0) {
+	case '}':
+		p.i++
+		return n, true
+	case ':':
+		p.i++
+		n.children = p.parse(true)
+		return n, true
+	case '|':
+		if n.num < 0 {
+			return n, false
+		}
+		p.i++
+		var choice []rune
+		for p.i < len(p.body) {
+			r := p.body[p.i]
+			switch {
+			case r == '\\' && strings.ContainsRune(",|\\", p.peek(1)):
+				choice = append(choice, p.peek(1))
+				p.i += 2
+				continue
+			case r == ',':
+				n.choices = append(n.choices, string(choice))
+				choice = nil
+			case r == '|' && p.peek(1) == '}':
+				n.choices = append(n.choices, string(choice))
+				p.i += 2
+				return n, true
+			default:
+				choice = append(choice, r)
+			}
+			p.i++
+		}
+	}
+	return n, false
+}
+
+// A snippetField is an occurrence of a tab stop in the text of an expanded
+// snippet, from the character start to end
+type snippetField struct {
+	num        int
+	start, end int
+}
+
+// expandSnippet returns the text of the nodes of the body of a snippet, with
+// the locations of the tab stops, in the order of the text. The tab stops
+// without a default content take the content of the first placeholder of
+// the same number, so that they mirror it. vars gives the value of a
+// variable, if it is known, and the variables which are not take their
+// default content.
+func expandSnippet(nodes []snippetNode, vars func(string) (string, bool)) (string, []snippetField) {
+	e := &snippetExpander{vars: vars, defaults: make(map[int]string)}
+	e.collectDefaults(nodes)
+	e.expand(nodes)
+	return e.sb.String(), e.fields
+}
+
+type snippetExpander struct {
+	vars     func(string) (string, bool)
+	defaults map[int]string
+
+	sb     strings.Builder
+	n      int // characters written so far
+	fields []snippetField
+}
+
+// collectDefaults finds the contents of the placeholders and choices,
+// expanded without the mirrors they contain
+func (e *snippetExpander) collectDefaults(nodes []snippetNode) {
+	for _, n := range nodes {
+		if _, ok := e.defaults[n.num]; n.num >= 0 && !ok {
+			if len(n.choices) > 0 {
+				e.defaults[n.num] = n.choices[0]
+			} else if len(n.children) > 0 {
+				sub := &snippetExpander{vars: e.vars, defaults: make(map[int]string)}
+				sub.expand(n.children)
+				e.defaults[n.num] = sub.sb.String()
+			}
+		}
+		e.collectDefaults(n.children)
+	}
+}
+
+func (e *snippetExpander) write(s string) {
+	e.sb.WriteString(s)
+	e.n += utf8.RuneCountInString(s)
+}
+
+func (e *snippetExpander) expand(nodes []snippetNode) {
+	for _, n := range nodes {
+		switch {
+		case n.num >= 0:
+			i := len(e.fields)
+			e.fields = append(e.fields, snippetField{num: n.num, start: e.n})
+			switch {
+			case len(n.choices) > 0:
+				e.write(n.choices[0])
+			case len(n.children) > 0:
+				e.expand(n.children)
+			default:
+				e.write(e.defaults[n.num])
+			}
+			e.fields[i].end = e.n
+		case n.variable != "":
+			if v, ok := e.vars(n.variable); ok {
+				e.write(v)
+			} else {
+				e.expand(n.children)
+			}
+		default:
+			e.write(n.text)
+		}
+	}
+}
+
+// snippetVariable returns the value of a variable of a snippet inserted in
+// the buffer at the location loc, in place of the text selected
+func (b *Buffer) snippetVariable(name string, loc Loc, selected string) (string, bool) {
+	switch name {
+	case "TM_FILENAME":
+		return filepath.Base(b.Path), b.Path != ""
+	case "TM_FILENAME_BASE":
+		base := filepath.Base(b.Path)
+		return strings.TrimSuffix(base, filepath.Ext(base)), b.Path != ""
+	case "TM_DIRECTORY":
+		return filepath.Dir(b.AbsPath), b.AbsPath != ""
+	case "TM_FILEPATH":
+		return b.AbsPath, b.AbsPath != ""
+	case "TM_LINE_INDEX":
+		return strconv.Itoa(loc.Y), true
+	case "TM_LINE_NUMBER":
+		return strconv.Itoa(loc.Y + 1), true
+	case "TM_CURRENT_LINE":
+		return string(b.LineBytes(loc.Y)), true
+	case "TM_SELECTED_TEXT":
+		return selected, true
+	}
+	return "", false
+}
+
+// snippetSession is the state of a snippet inserted in a buffer while the
+// cursor visits its tab stops. Its locations follow the changes of the
+// buffer.
+type snippetSession struct {
+	// start and end span the text of the snippet
+	start, end Loc
+	fields     []snippetLoc
+	// stops are the numbers of the tab stops in the order they are
+	// visited, 0 last, and cur the index of the current one
+	stops []int
+	cur   int
+}
+
+// A snippetLoc is an occurrence of a tab stop in the buffer
+type snippetLoc struct {
+	num        int
+	start, end Loc
+}
+
+// InsertSnippet replaces the text between start and end by the body of a
+// snippet, as a single undo step, and selects its first tab stop. The lines
+// of the body after the first are indented like the line of start, and their
+// leading tabs are replaced by the indentation of the buffer. The next tab
+// stops are visited in the order of their numbers with SnippetNext, $0 last
+// or the end of the snippet if it has none, and the text typed in a tab stop
+// is copied to its mirrors (see UpdateSnippet).
+func (b *Buffer) InsertSnippet(start, end Loc, body string) {
+	indent := string(util.GetLeadingWhitespace(b.LineBytes(start.Y)))
+	indentString := b.IndentString(util.IntOpt(b.Settings["tabsize"]))
+	lines := strings.Split(body, "\n")
+	for i := 1; i < len(lines); i++ {
+		tabs := len(lines[i]) - len(strings.TrimLeft(lines[i], "\t"))
+		lines[i] = indent + strings.Repeat(indentString, tabs) + lines[i][tabs:]
+	}
+
+	selected := string(b.Substr(start, end))
+	vars := func(name string) (string, bool) {
+		return b.snippetVariable(name, start, selected)
+	}
+	text, fields := expandSnippet(parseSnippetBody(strings.Join(lines, "\n")), vars)
+
+	b.snippet = nil
+	b.UndoGroup(func() {
+		b.Replace(start, end, text)
+	})
+
+	s := &snippetSession{start: start, end: start.Move(util.CharacterCountInString(text), b)}
+	nums := make(map[int]bool)
+	for _, f := range fields {
+		s.fields = append(s.fields, snippetLoc{f.num, start.Move(f.start, b), start.Move(f.end, b)})
+		if !nums[f.num] && f.num != 0 {
+			s.stops = append(s.stops, f.num)
+		}
+		nums[f.num] = true
+	}
+	sort.Ints(s.stops)
+	if !nums[0] {
+		s.fields = append(s.fields, snippetLoc{0, s.end, s.end})
+	}
+	s.stops = append(s.stops, 0)
+
+	if len(s.stops) > 1 {
+		b.snippet = s
+	}
+	b.selectSnippetStop(s)
+}
+
+// InSnippet returns whether the cursor is visiting the tab stops of a
+// snippet
+func (b *Buffer) InSnippet() bool {
+	return b.snippet != nil
+}
+
+// SnippetNext selects the next tab stop of the snippet being visited. The
+// snippet is left at the last one.
+func (b *Buffer) SnippetNext() bool {
+	return b.moveSnippetStop(1)
+}
+
+// SnippetPrevious selects the previous tab stop of the snippet being
+// visited
+func (b *Buffer) SnippetPrevious() bool {
+	return b.moveSnippetStop(-1)
+}
+
+func (b *Buffer) moveSnippetStop(dir int) bool {
+	s := b.snippet
+	if s == nil || s.cur+dir < 0 {
+		return false
+	}
+	s.cur += dir
+	if s.cur >= len(s.stops)-1 {
+		s.cur = len(s.stops) - 1
+		b.snippet = nil
+	}
+	b.selectSnippetStop(s)
+	return true
+}
+
+// EndSnippet stops visiting the tab stops of the snippet
+func (b *Buffer) EndSnippet() {
+	b.snippet = nil
+}
+
+// selectSnippetStop selects the first occurrence of the current tab stop
+// of the snippet
+func (b *Buffer) selectSnippetStop(s *snippetSession) {
+	c := b.GetActiveCursor()
+	for _, f := range s.fields {
+		if f.num != s.stops[s.cur] {
+			continue
+		}
+		c.ResetSelection()
+		if f.start != f.end {
+			c.SetSelectionStart(f.start)
+			c.SetSelectionEnd(f.end)
+		}
+		c.GotoLoc(f.end)
+		c.StoreVisualX()
+		return
+	}
+}
+
+// UpdateSnippet copies the text of the first occurrence of the current tab
+// stop of the snippet to its other occurrences, its mirrors, or stops
+// visiting the snippet if the cursor left it
+func (b *Buffer) UpdateSnippet() {
+	s := b.snippet
+	if s == nil {
+		return
+	}
+	c := b.GetActiveCursor()
+	if b.NumCursors() > 1 || c.Loc.LessThan(s.start) || c.Loc.GreaterThan(s.end) {
+		b.snippet = nil
+		return
+	}
+
+	num := s.stops[s.cur]
+	first := -1
+	for i, f := range s.fields {
+		if f.num != num {
+			continue
+		}
+		if first < 0 {
+			first = i
+			continue
+		}
+		text := string(b.Substr(s.fields[first].start, s.fields[first].end))
+		if string(b.Substr(f.start, f.end)) != text {
+			b.Replace(f.start, f.end, text)
+			// the mirror spans the text inserted at its start
+			s.fields[i].start = f.start
+			s.fields[i].end = f.start.Move(util.CharacterCountInString(text), b)
+		}
+	}
+}
+
+// moveSnippet moves the locations of the snippet being visited after a
+// text event changed the text between start and end, move being the change
+// of a location. The occurrences of the current tab stop, and the snippet,
+// grow with the text inserted at their start, and the locations inside a
+// removed text move to its start.
+func (b *SharedBuffer) moveSnippet(eventType int, start, end Loc, move func(Loc) Loc) {
+	s := b.snippet
+	if s == nil {
+		return
+	}
+	adjust := func(loc Loc, sticky bool) Loc {
+		switch {
+		case eventType == TextEventInsert && sticky && loc == start:
+			return loc
+		case eventType != TextEventInsert && loc.GreaterThan(start) && loc.LessThan(end):
+			return start
+		}
+		return move(loc)
+	}
+
+	s.start = adjust(s.start, true)
+	s.end = adjust(s.end, false)
+	num := s.stops[s.cur]
+	for i, f := range s.fields {
+		s.fields[i].start = adjust(f.start, f.num == num)
+		s.fields[i].end = adjust(f.end, false)
+	}
+}
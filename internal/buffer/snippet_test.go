@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+func TestParseSnippets(t *testing.T) {
+	data := []byte("# comment\nsnippet if\n\tif ${1:cond} {\n\t\t$0\n\t}\n\nsnippet fn\n\tfunc $1()\n")
+	assert.Equal(t, []Snippet{
+		{Prefix: "if", Body: "if ${1:cond} {\n\t$0\n}"},
+		{Prefix: "fn", Body: "func $1()"},
+	}, parseSnippets(data))
+}
+
+func TestParseJSONSnippets(t *testing.T) {
+	data := []byte(`{
+		// comments are allowed
+		"if": {"prefix": "if", "body": ["if ${1:cond} {", "\t$0", "}"], "description": "If statement"},
+		"func": {"prefix": ["fn", "func"], "body": "func $1()"},
+		"invalid": {"body": "x"},
+	}`)
+	assert.Equal(t, []Snippet{
+		{Prefix: "fn", Body: "func $1()"},
+		{Prefix: "func", Body: "func $1()"},
+		{Prefix: "if", Body: "if ${1:cond} {\n\t$0\n}", Description: "If statement"},
+	}, parseJSONSnippets(data))
+	assert.Nil(t, parseJSONSnippets([]byte("{")))
+}
+
+func TestExpandSnippet(t *testing.T) {
+	vars := func(name string) (string, bool) {
+		if name == "TM_FILENAME" {
+			return "main.go", true
+		}
+		return "", false
+	}
+	expand := func(body string) (string, []snippetField) {
+		return expandSnippet(parseSnippetBody(body), vars)
+	}
+
+	text, fields := expand("if ${1:cond} {\n\t$0\n}")
+	assert.Equal(t, "if cond {\n\t\n}", text)
+	assert.Equal(t, []snippetField{{1, 3, 7}, {0, 11, 11}}, fields)
+
+	text, fields = expand("for $1 := ${1:i}; ${1}++")
+	assert.Equal(t, "for i := i; i++", text)
+	assert.Equal(t, []snippetField{{1, 4, 5}, {1, 9, 10}, {1, 12, 13}}, fields)
+
+	text, fields = expand("é(${1:a, ${2:b}}) ${3|x,y|}")
+	assert.Equal(t, "é(a, b) x", text)
+	assert.Equal(t, []snippetField{{1, 2, 6}, {2, 5, 6}, {3, 8, 9}}, fields)
+
+	text, fields = expand("$TM_FILENAME ${NOPE:none} $NOPE \\$x \\} $ ${")
+	assert.Equal(t, "main.go none  $x } $ ${", text)
+	assert.Empty(t, fields)
+}
+
+func TestInsertSnippet(t *testing.T) {
+	b := NewBufferFromString("  fori\n", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("tabstospaces", true)
+	b.SetOptionNative("tabsize", float64(2))
+	c := b.GetActiveCursor()
+
+	b.InsertSnippet(Loc{X: 2, Y: 0}, Loc{X: 6, Y: 0}, "for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}")
+	assert.Equal(t, "  for i := 0; i < n; i++ {\n    \n  }\n", string(b.Bytes()))
+	assert.True(t, b.InSnippet())
+	assert.Equal(t, "i", string(c.GetSelection()))
+
+	// typing in the tab stop updates its mirrors
+	b.Replace(c.CurSelection[0], c.CurSelection[1], "idx")
+	b.UpdateSnippet()
+	assert.Equal(t, "  for idx := 0; idx < n; idx++ {\n    \n  }\n", string(b.Bytes()))
+
+	assert.True(t, b.SnippetNext())
+	assert.Equal(t, "n", string(c.GetSelection()))
+	assert.True(t, b.SnippetPrevious())
+	assert.Equal(t, "idx", string(c.GetSelection()))
+	assert.True(t, b.SnippetNext())
+	assert.True(t, b.SnippetNext())
+	assert.False(t, b.InSnippet())
+	assert.Equal(t, Loc{X: 4, Y: 1}, c.Loc)
+	assert.False(t, b.SnippetNext())
+
+	// the insertion is undone in one step, apart from the typing
+	b.Undo()
+	b.Undo()
+	assert.Equal(t, "  fori\n", string(b.Bytes()))
+}
+
+func TestUpdateSnippetLeave(t *testing.T) {
+	b := NewBufferFromString("x\n", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	b.InsertSnippet(Loc{X: 0, Y: 0}, Loc{X: 0, Y: 0}, "f($1, $2)")
+	assert.True(t, b.InSnippet())
+	assert.Equal(t, Loc{X: 2, Y: 0}, c.Loc)
+	c.GotoLoc(Loc{X: 0, Y: 1})
+	b.UpdateSnippet()
+	assert.False(t, b.InSnippet())
+
+	// a snippet without tab stops is not visited
+	b.InsertSnippet(Loc{X: 0, Y: 1}, Loc{X: 0, Y: 1}, "end")
+	assert.False(t, b.InSnippet())
+	assert.Equal(t, Loc{X: 3, Y: 1}, c.Loc)
+}
+
+func TestSnippetBefore(t *testing.T) {
+	config.PluginAddRuntimeFileFromMemory(config.RTSnippet, "snippettest", `{
+		"a": {"prefix": "if", "body": "if $1"},
+		"b": {"prefix": "ife", "body": "if $1 else $2"},
+		"c": {"prefix": "->", "body": "=> $0"}
+	}`)
+	b := NewBufferFromString("ife xife x->", "", BTDefault)
+	defer b.Close()
+	b.SetOptionNative("filetype", "snippettest")
+
+	s, start, ok := b.SnippetBefore(Loc{X: 3, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, "ife", s.Prefix)
+	assert.Equal(t, Loc{X: 0, Y: 0}, start)
+
+	_, _, ok = b.SnippetBefore(Loc{X: 8, Y: 0})
+	assert.False(t, ok)
+
+	s, start, ok = b.SnippetBefore(Loc{X: 12, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, "->", s.Prefix)
+	assert.Equal(t, Loc{X: 10, Y: 0}, start)
+}
@@ -0,0 +1,163 @@
+package buffer
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// SortOptions tell how SortLines compares the lines
+type SortOptions struct {
+	// Reverse sorts the lines in descending order
+	Reverse bool
+	// Numeric compares the numbers the keys start with. The keys without a
+	// number come first.
+	Numeric    bool
+	IgnoreCase bool
+	// Unique keeps only the first of the lines with equal keys
+	Unique bool
+	// Column, when positive, is the field of the line compared instead of
+	// the whole line, the fields being separated by whitespace and numbered
+	// from 1
+	Column int
+	// Key, when set, compares the text it matches in the line, or in the
+	// field of Column, or the text of its first group if it has one. The
+	// lines it doesn't match have an empty key.
+	Key util.Regexp
+}
+
+var numberRegex = regexp.MustCompile(`^\s*[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+
+// A sortLine is a line along with the key it is compared by
+type sortLine struct {
+	line   []byte
+	key    string
+	num    float64
+	hasNum bool
+}
+
+func (opts SortOptions) sortLine(line []byte) sortLine {
+	key := line
+	if opts.Column > 0 {
+		key = nil
+		if fields := bytes.Fields(line); opts.Column <= len(fields) {
+			key = fields[opts.Column-1]
+		}
+	}
+	if opts.Key != nil {
+		m := opts.Key.FindAllSubmatchIndex(key, 1)
+		if len(m) == 0 {
+			key = nil
+		} else if len(m[0]) > 2 && m[0][2] >= 0 {
+			key = key[m[0][2]:m[0][3]]
+		} else {
+			key = key[m[0][0]:m[0][1]]
+		}
+	}
+	l := sortLine{line: line, key: string(key)}
+	if opts.IgnoreCase {
+		l.key = strings.ToLower(l.key)
+	}
+	if opts.Numeric {
+		if n := numberRegex.Find(key); n != nil {
+			l.num, _ = strconv.ParseFloat(strings.TrimSpace(string(n)), 64)
+			l.hasNum = true
+		}
+	}
+	return l
+}
+
+// compare returns whether the key of a comes before (-1), is equal to (0)
+// or comes after (1) the key of b
+func (opts SortOptions) compare(a, b sortLine) int {
+	if opts.Numeric {
+		switch {
+		case a.hasNum != b.hasNum:
+			if b.hasNum {
+				return -1
+			}
+			return 1
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+		if a.hasNum {
+			return 0
+		}
+	}
+	return strings.Compare(a.key, b.key)
+}
+
+// SortLines sorts the lines from start to end, keeping the order of the
+// lines with equal keys
+func (b *Buffer) SortLines(start, end int, opts SortOptions) {
+	lines := make([]sortLine, 0, end-start+1)
+	for y := start; y <= end; y++ {
+		lines = append(lines, opts.sortLine(b.LineBytes(y)))
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		c := opts.compare(lines[i], lines[j])
+		if opts.Reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	sorted := make([][]byte, 0, len(lines))
+	for i, l := range lines {
+		if opts.Unique && i > 0 && opts.compare(lines[i-1], l) == 0 {
+			continue
+		}
+		sorted = append(sorted, l.line)
+	}
+	b.replaceLines(start, end, sorted)
+}
+
+// UniqueLines removes the lines from start to end which are equal to a line
+// before them, keeping the order of the other lines
+func (b *Buffer) UniqueLines(start, end int, ignoreCase bool) {
+	seen := make(map[string]bool)
+	var lines [][]byte
+	for y := start; y <= end; y++ {
+		l := b.LineBytes(y)
+		key := string(l)
+		if ignoreCase {
+			key = strings.ToLower(key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			lines = append(lines, l)
+		}
+	}
+	b.replaceLines(start, end, lines)
+}
+
+// ReverseLines reverses the order of the lines from start to end
+func (b *Buffer) ReverseLines(start, end int) {
+	lines := make([][]byte, 0, end-start+1)
+	for y := end; y >= start; y-- {
+		lines = append(lines, b.LineBytes(y))
+	}
+	b.replaceLines(start, end, lines)
+}
+
+// replaceLines replaces the lines from start to end by lines, as a single
+// undo step, unless they are the same
+func (b *Buffer) replaceLines(start, end int, lines [][]byte) {
+	text := bytes.Join(lines, []byte{'\n'})
+	var old [][]byte
+	for y := start; y <= end; y++ {
+		old = append(old, b.LineBytes(y))
+	}
+	if bytes.Equal(text, bytes.Join(old, []byte{'\n'})) {
+		return
+	}
+	b.UndoGroup(func() {
+		b.Replace(Loc{X: 0, Y: start}, Loc{X: util.CharacterCount(b.LineBytes(end)), Y: end}, string(text))
+	})
+}
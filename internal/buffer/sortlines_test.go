@@ -0,0 +1,53 @@
+package buffer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sortedLines(text string, end int, opts SortOptions) string {
+	b := NewBufferFromString(text, "", BTDefault)
+	defer b.Close()
+	b.SortLines(0, end, opts)
+	return string(b.Bytes())
+}
+
+func TestSortLines(t *testing.T) {
+	text := "b 10\nA 2\nc 1\na 2\nx"
+	sorted := func(opts SortOptions) string {
+		return sortedLines(text, 3, opts)
+	}
+
+	assert.Equal(t, "A 2\na 2\nb 10\nc 1\nx", sorted(SortOptions{}))
+	assert.Equal(t, "c 1\nb 10\na 2\nA 2\nx", sorted(SortOptions{Reverse: true}))
+	// equal keys keep their order
+	assert.Equal(t, "A 2\na 2\nb 10\nc 1\nx", sorted(SortOptions{IgnoreCase: true}))
+	assert.Equal(t, "A 2\nb 10\nc 1\nx", sorted(SortOptions{IgnoreCase: true, Unique: true}))
+	assert.Equal(t, "c 1\nA 2\na 2\nb 10\nx", sorted(SortOptions{Numeric: true, Column: 2}))
+	assert.Equal(t, "c 1\nA 2\nb 10\nx", sorted(SortOptions{Numeric: true, Column: 2, Unique: true}))
+	assert.Equal(t, "b 10\nc 1\nA 2\na 2\nx", sorted(SortOptions{Key: regexp.MustCompile(` (\d)`)}))
+
+	// lines without a number come first, and are compared as text
+	assert.Equal(t, "x\nc 1\nb 10", sortedLines("c 1\nx\nb 10", 2, SortOptions{Numeric: true, Column: 2}))
+	assert.Equal(t, "A 2\na 2\nb 10\nc 1\nx", sorted(SortOptions{Numeric: true}))
+}
+
+func TestUniqueReverseLines(t *testing.T) {
+	b := NewBufferFromString("a\nb\nA\na\nb\nc", "", BTDefault)
+	defer b.Close()
+
+	b.UniqueLines(0, 4, false)
+	assert.Equal(t, "a\nb\nA\nc", string(b.Bytes()))
+	b.UniqueLines(0, 3, true)
+	assert.Equal(t, "a\nb\nc", string(b.Bytes()))
+	b.ReverseLines(0, 2)
+	assert.Equal(t, "c\nb\na", string(b.Bytes()))
+
+	// each change is a single undo step
+	b.Undo()
+	assert.Equal(t, "a\nb\nc", string(b.Bytes()))
+	b.Undo()
+	assert.Equal(t, "a\nb\nA\nc", string(b.Bytes()))
+}
@@ -0,0 +1,89 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/spell"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// proseFiletypes are the filetypes whose whole text is spell checked. For
+// other filetypes only the comments and strings are.
+var proseFiletypes = map[string]bool{
+	"asciidoc":   true,
+	"git-commit": true,
+	"mail":       true,
+	"markdown":   true,
+	"tex":        true,
+	"unknown":    true,
+}
+
+// Dictionary returns the dictionary of the buffer's spelllang
+func (b *Buffer) Dictionary() (*spell.Dictionary, error) {
+	return spell.Load(b.Settings["spelllang"].(string))
+}
+
+// spellChecked returns whether text highlighted with the given group is
+// spell checked in a filetype which isn't prose
+func spellChecked(group string) bool {
+	return strings.HasPrefix(group, "comment") || strings.HasPrefix(group, "constant.string")
+}
+
+// Words returns the words of line y which are spell checked: all words for
+// prose filetypes and the words of comments and strings otherwise
+func (b *Buffer) Words(y int) []spell.Word {
+	words := spell.Words(b.LineBytes(y))
+	if proseFiletypes[b.FileType()] || len(words) == 0 {
+		return words
+	}
+
+	// the highlighting groups starting on the line, by position
+	match := b.Match(y)
+	starts := make([]int, 0, len(match))
+	for x := range match {
+		starts = append(starts, x)
+	}
+	sort.Ints(starts)
+	names := make(map[highlight.Group]bool)
+
+	checked := words[:0]
+	i := -1
+	for _, w := range words {
+		for i+1 < len(starts) && starts[i+1] <= w.Start {
+			i++
+		}
+		if i < 0 {
+			continue
+		}
+		g := match[starts[i]]
+		ok, found := names[g]
+		if !found {
+			ok = spellChecked(g.String())
+			names[g] = ok
+		}
+		if ok {
+			checked = append(checked, w)
+		}
+	}
+	return checked
+}
+
+// Misspellings returns the misspelled words of line y, if spell checking is
+// enabled for the buffer and its dictionary can be loaded
+func (b *Buffer) Misspellings(y int) []spell.Word {
+	if !b.Settings["spell"].(bool) {
+		return nil
+	}
+	d, err := b.Dictionary()
+	if err != nil {
+		return nil
+	}
+	var bad []spell.Word
+	for _, w := range b.Words(y) {
+		if !d.Check(w.Text) {
+			bad = append(bad, w)
+		}
+	}
+	return bad
+}
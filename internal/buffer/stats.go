@@ -0,0 +1,96 @@
+package buffer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Stats are the counts of a text shown by the stats command. Chars and
+// Bytes include the line endings, and a word is a run of characters
+// which aren't spaces.
+type Stats struct {
+	Lines, Words, Chars, Bytes int
+}
+
+// lineStats are the counts of the lines of a buffer without their line
+// endings, which are computed once and then updated with the lines changed
+// by each edit
+type lineStats struct {
+	words, chars, bytes int
+}
+
+func countLine(l []byte) lineStats {
+	s := lineStats{bytes: len(l)}
+	inWord := false
+	for len(l) > 0 {
+		r, size := utf8.DecodeRune(l)
+		l = l[size:]
+		s.chars++
+		space := unicode.IsSpace(r)
+		if !space && !inWord {
+			s.words++
+		}
+		inWord = !space
+	}
+	return s
+}
+
+func (s *lineStats) add(o lineStats, sign int) {
+	s.words += sign * o.words
+	s.chars += sign * o.chars
+	s.bytes += sign * o.bytes
+}
+
+// countLines adds the counts of the lines start to end (inclusive) to the
+// totals if they are known, or subtracts them if sign is -1
+func (b *SharedBuffer) countLines(start, end, sign int) {
+	if b.stats == nil {
+		return
+	}
+	for i := start; i <= end && i < len(b.lines); i++ {
+		b.stats.add(countLine(b.lines[i].data), sign)
+	}
+}
+
+// Stats returns the counts of the whole buffer. They are computed the first
+// time and then updated as the buffer is edited.
+func (b *Buffer) Stats() Stats {
+	if b.stats == nil {
+		b.stats = new(lineStats)
+		b.countLines(0, len(b.lines)-1, 1)
+	}
+	n := len(b.lines)
+	eol := 1
+	if b.Endings == FFDos {
+		eol = 2
+	}
+	return Stats{
+		Lines: n,
+		Words: b.stats.words,
+		Chars: b.stats.chars + n - 1,
+		Bytes: b.stats.bytes + (n-1)*eol,
+	}
+}
+
+// TextStats returns the counts of the given text, such as a selection
+func TextStats(text []byte) Stats {
+	s := Stats{Lines: 1}
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' {
+			continue
+		}
+		l := countLine(text[start:i])
+		s.Words += l.words
+		s.Chars += l.chars
+		if i < len(text) {
+			s.Chars++
+			if i+1 < len(text) {
+				s.Lines++
+			}
+		}
+		start = i + 1
+	}
+	s.Bytes = len(text)
+	return s
+}
@@ -0,0 +1,31 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	b := NewBufferFromString("hello wörld\n\n  two  words \nend", "", BTDefault)
+	assert.Equal(t, Stats{Lines: 4, Words: 5, Chars: 30, Bytes: 31}, b.Stats())
+
+	// the counts are updated by the edits and stay the same as counting
+	// again from the start
+	b.Insert(Loc{X: 5, Y: 0}, " new\nlines and")
+	b.Remove(Loc{X: 0, Y: 3}, Loc{X: 3, Y: 4})
+	b.Insert(b.End(), "\n")
+	b.Undo()
+	b.Replace(Loc{X: 0, Y: 2}, Loc{X: 1, Y: 2}, "x y")
+	want := TextStats(b.Bytes())
+	assert.Equal(t, want, b.Stats())
+	b.stats = nil
+	assert.Equal(t, want, b.Stats())
+}
+
+func TestTextStats(t *testing.T) {
+	assert.Equal(t, Stats{Lines: 1, Words: 3, Chars: 6, Bytes: 7}, TextStats([]byte("ab ç\td")))
+	assert.Equal(t, Stats{Lines: 1, Words: 1, Chars: 2, Bytes: 2}, TextStats([]byte("a\n")))
+	assert.Equal(t, Stats{Lines: 3, Words: 2, Chars: 5, Bytes: 5}, TextStats([]byte("a\n\nb\n")))
+	assert.Equal(t, Stats{Lines: 1}, TextStats(nil))
+}
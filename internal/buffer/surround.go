@@ -0,0 +1,242 @@
+package buffer
+
+import (
+	"regexp"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// bracketPairs are the brackets which can surround text besides the pairs
+// of the autoclosepairs option
+const bracketPairs = "()[]{}<>"
+
+// tagRegex matches an opening, closing or self-closing tag such as <div>
+var tagRegex = regexp.MustCompile(`<(/?)([A-Za-z][\w:.-]*)[^<>]*?(/?)>`)
+
+// SurroundPair returns the text inserted before and after the text
+// surrounded by r: the brackets or quotes the character belongs to, given
+// by its opening or closing character, or the character itself on both
+// sides for other characters such as *
+func (b *Buffer) SurroundPair(r rune) (string, string) {
+	pairs := []rune(bracketPairs)
+	if p, ok := b.Settings["autoclosepairs"].(string); ok {
+		pairs = append(pairs, []rune(p)...)
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] == r || pairs[i+1] == r {
+			return string(pairs[i]), string(pairs[i+1])
+		}
+	}
+	return string(r), string(r)
+}
+
+// FindSurround returns the ranges of the opening and closing texts of the
+// innermost pair surrounding loc, given by r as in SurroundPair, and t for
+// an HTML or XML tag. Quotes and other characters which are the same on
+// both sides are only searched on the line of loc.
+func (b *Buffer) FindSurround(loc Loc, r rune) (open, close [2]Loc, ok bool) {
+	if r == 't' {
+		return b.findTag(loc)
+	}
+	o, c := b.SurroundPair(r)
+	ro, rc := []rune(o)[0], []rune(c)[0]
+	if ro == rc {
+		return b.findQuotes(loc, ro)
+	}
+
+	var start, end Loc
+	if b.RuneAt(loc) == ro {
+		start, ok = loc, true
+	} else {
+		depth := 0
+		start, ok = b.scanRunes(loc.Move(-1, b), false, func(r rune) bool {
+			switch r {
+			case rc:
+				depth++
+			case ro:
+				if depth == 0 {
+					return true
+				}
+				depth--
+			}
+			return false
+		})
+	}
+	if !ok {
+		return open, close, false
+	}
+
+	depth := 0
+	end, ok = b.scanRunes(start.Move(1, b), true, func(r rune) bool {
+		switch r {
+		case ro:
+			depth++
+		case rc:
+			if depth == 0 {
+				return true
+			}
+			depth--
+		}
+		return false
+	})
+	if !ok {
+		return open, close, false
+	}
+	open = [2]Loc{start, start.Move(1, b)}
+	close = [2]Loc{end, end.Move(1, b)}
+	return open, close, true
+}
+
+// scanRunes goes through the characters of the buffer from loc, forward or
+// backward, until f returns true, and returns the location of the character
+// it stopped on
+func (b *Buffer) scanRunes(loc Loc, forward bool, f func(r rune) bool) (Loc, bool) {
+	if forward {
+		for y := loc.Y; y < b.LinesNum(); y++ {
+			runes := []rune(string(b.LineBytes(y)))
+			x := 0
+			if y == loc.Y {
+				x = loc.X
+			}
+			for ; x < len(runes); x++ {
+				if f(runes[x]) {
+					return Loc{X: x, Y: y}, true
+				}
+			}
+		}
+		return loc, false
+	}
+	for y := loc.Y; y >= 0; y-- {
+		runes := []rune(string(b.LineBytes(y)))
+		x := len(runes) - 1
+		if y == loc.Y {
+			x = util.Min(loc.X, x)
+		}
+		for ; x >= 0; x-- {
+			if f(runes[x]) {
+				return Loc{X: x, Y: y}, true
+			}
+		}
+	}
+	return loc, false
+}
+
+// findQuotes returns the quotes q around loc on its line, the quotes of a
+// line being paired from its start. Escaped quotes are ignored.
+func (b *Buffer) findQuotes(loc Loc, q rune) (open, close [2]Loc, ok bool) {
+	runes := []rune(string(b.LineBytes(loc.Y)))
+	start := -1
+	for x := 0; x < len(runes); x++ {
+		if runes[x] != q || x > 0 && runes[x-1] == '\\' {
+			continue
+		}
+		if start < 0 {
+			start = x
+			continue
+		}
+		if start <= loc.X && loc.X <= x {
+			open = [2]Loc{{X: start, Y: loc.Y}, {X: start + 1, Y: loc.Y}}
+			close = [2]Loc{{X: x, Y: loc.Y}, {X: x + 1, Y: loc.Y}}
+			return open, close, true
+		}
+		start = -1
+	}
+	return open, close, false
+}
+
+// findTag returns the innermost pair of opening and closing tags around
+// loc, loc being either between them or on one of them
+func (b *Buffer) findTag(loc Loc) (open, close [2]Loc, ok bool) {
+	type tag struct {
+		name  string
+		start Loc
+		end   Loc
+	}
+	var stack []tag
+	for y := 0; y < b.LinesNum(); y++ {
+		line := b.LineBytes(y)
+		for _, m := range tagRegex.FindAllSubmatchIndex(line, -1) {
+			if m[7] > m[6] {
+				// self-closing tags don't surround anything
+				continue
+			}
+			t := tag{
+				name:  string(line[m[4]:m[5]]),
+				start: Loc{X: util.CharacterCount(line[:m[0]]), Y: y},
+				end:   Loc{X: util.CharacterCount(line[:m[1]]), Y: y},
+			}
+			if m[3] == m[2] {
+				stack = append(stack, t)
+				continue
+			}
+			// the closing tag ends the innermost tag with the same name
+			i := len(stack) - 1
+			for i >= 0 && stack[i].name != t.name {
+				i--
+			}
+			if i < 0 {
+				continue
+			}
+			o := stack[i]
+			stack = stack[:i]
+			if o.start.LessEqual(loc) && loc.LessThan(t.end) {
+				// the first pair closed around loc is the innermost one
+				return [2]Loc{o.start, o.end}, [2]Loc{t.start, t.end}, true
+			}
+		}
+	}
+	return open, close, false
+}
+
+// Surround inserts the texts given by r, as in SurroundPair, around the
+// selection of the cursor c, or the word under it if it has none, and
+// returns whether there was any text to surround. The surrounded text is
+// selected.
+func (b *Buffer) Surround(c *Cursor, r rune) bool {
+	if !c.HasSelection() {
+		start, end := c.X, c.X
+		runes := []rune(string(b.LineBytes(c.Y)))
+		for start > 0 && util.IsWordChar(runes[start-1]) {
+			start--
+		}
+		for end < len(runes) && util.IsWordChar(runes[end]) {
+			end++
+		}
+		if start == end {
+			return false
+		}
+		c.SetSelectionStart(Loc{X: start, Y: c.Y})
+		c.SetSelectionEnd(Loc{X: end, Y: c.Y})
+	}
+	o, cl := b.SurroundPair(r)
+	b.wrapSelection(c, o, cl)
+	return true
+}
+
+// ChangeSurround replaces the innermost pair given by from around the
+// cursor c, as in FindSurround, by the one given by to, as in
+// SurroundPair, and returns whether there was such a pair
+func (b *Buffer) ChangeSurround(c *Cursor, from, to rune) bool {
+	open, close, ok := b.FindSurround(c.Loc, from)
+	if !ok {
+		return false
+	}
+	o, cl := b.SurroundPair(to)
+	// the closing text is replaced first so that the range of the opening
+	// text stays valid
+	b.Replace(close[0], close[1], cl)
+	b.Replace(open[0], open[1], o)
+	return true
+}
+
+// DeleteSurround removes the innermost pair given by r around the cursor
+// c, as in FindSurround, and returns whether there was such a pair
+func (b *Buffer) DeleteSurround(c *Cursor, r rune) bool {
+	open, close, ok := b.FindSurround(c.Loc, r)
+	if !ok {
+		return false
+	}
+	b.Remove(close[0], close[1])
+	b.Remove(open[0], open[1])
+	return true
+}
@@ -0,0 +1,91 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSurround(t *testing.T) {
+	b := NewBufferFromString("f(a, [b], (c\n  d)) \"e\" x \"f\"", "", BTDefault)
+	defer b.Close()
+
+	open, close, ok := b.FindSurround(Loc{X: 6, Y: 0}, '(')
+	assert.True(t, ok)
+	assert.Equal(t, [2]Loc{{X: 1, Y: 0}, {X: 2, Y: 0}}, open)
+	assert.Equal(t, [2]Loc{{X: 4, Y: 1}, {X: 5, Y: 1}}, close)
+
+	// the cursor may be on one of the brackets, and either one names them
+	open, close, ok = b.FindSurround(Loc{X: 10, Y: 0}, ')')
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 10, Y: 0}, open[0])
+	assert.Equal(t, Loc{X: 3, Y: 1}, close[0])
+	open, _, ok = b.FindSurround(Loc{X: 3, Y: 1}, '(')
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 10, Y: 0}, open[0])
+
+	_, _, ok = b.FindSurround(Loc{X: 3, Y: 0}, '[')
+	assert.False(t, ok)
+
+	open, close, ok = b.FindSurround(Loc{X: 8, Y: 1}, '"')
+	assert.True(t, ok)
+	assert.Equal(t, Loc{X: 6, Y: 1}, open[0])
+	assert.Equal(t, Loc{X: 8, Y: 1}, close[0])
+	_, _, ok = b.FindSurround(Loc{X: 10, Y: 1}, '"')
+	assert.False(t, ok)
+}
+
+func TestFindSurroundTag(t *testing.T) {
+	b := NewBufferFromString("<div class=\"a\">\n  <p>x<br/>y</p>\n</div>", "", BTDefault)
+	defer b.Close()
+
+	open, close, ok := b.FindSurround(Loc{X: 6, Y: 1}, 't')
+	assert.True(t, ok)
+	assert.Equal(t, [2]Loc{{X: 2, Y: 1}, {X: 5, Y: 1}}, open)
+	assert.Equal(t, [2]Loc{{X: 12, Y: 1}, {X: 16, Y: 1}}, close)
+
+	open, close, ok = b.FindSurround(Loc{X: 1, Y: 1}, 't')
+	assert.True(t, ok)
+	assert.Equal(t, [2]Loc{{X: 0, Y: 0}, {X: 15, Y: 0}}, open)
+	assert.Equal(t, [2]Loc{{X: 0, Y: 2}, {X: 6, Y: 2}}, close)
+}
+
+func TestSurround(t *testing.T) {
+	b := NewBufferFromString("say hello world", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	c.GotoLoc(Loc{X: 6, Y: 0})
+	assert.True(t, b.Surround(c, ')'))
+	assert.Equal(t, "say (hello) world", string(b.Bytes()))
+	assert.Equal(t, "hello", string(c.GetSelection()))
+
+	c.SetSelectionStart(Loc{X: 4, Y: 0})
+	c.SetSelectionEnd(Loc{X: 17, Y: 0})
+	assert.True(t, b.Surround(c, '*'))
+	assert.Equal(t, "say *(hello) world*", string(b.Bytes()))
+
+	c.ResetSelection()
+	c.GotoLoc(Loc{X: 4, Y: 0})
+	assert.False(t, b.Surround(c, '"'))
+	c.GotoLoc(Loc{X: 3, Y: 0})
+	assert.True(t, b.Surround(c, '"'))
+	assert.Equal(t, "\"say\" *(hello) world*", string(b.Bytes()))
+}
+
+func TestChangeDeleteSurround(t *testing.T) {
+	b := NewBufferFromString("x = f(\"a\", <b>c</b>)", "", BTDefault)
+	defer b.Close()
+	c := b.GetActiveCursor()
+
+	c.GotoLoc(Loc{X: 8, Y: 0})
+	assert.True(t, b.ChangeSurround(c, '"', '\''))
+	assert.Equal(t, "x = f('a', <b>c</b>)", string(b.Bytes()))
+	assert.True(t, b.ChangeSurround(c, '(', '['))
+	assert.Equal(t, "x = f['a', <b>c</b>]", string(b.Bytes()))
+
+	c.GotoLoc(Loc{X: 14, Y: 0})
+	assert.True(t, b.DeleteSurround(c, 't'))
+	assert.Equal(t, "x = f['a', c]", string(b.Bytes()))
+	assert.False(t, b.ChangeSurround(c, 't', '<'))
+}
@@ -0,0 +1,321 @@
+package buffer
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// A syntaxFile is a syntax file known by its header. Only the header is
+// read when the syntax files are listed: the rules are parsed the first time
+// a buffer of the filetype is opened, and kept for the next ones.
+type syntaxFile struct {
+	file   config.RuntimeFile
+	header *highlight.Header
+	// data is the content of a user's syntax file, which is read to find
+	// its header
+	data []byte
+
+	fileOnce sync.Once
+	parsed   *highlight.File
+	fileErr  error
+
+	defOnce sync.Once
+	def     *highlight.Def
+	defErrs []error
+}
+
+// parse returns the parsed syntax file, without its rules compiled
+func (sf *syntaxFile) parse() (*highlight.File, error) {
+	sf.fileOnce.Do(func() {
+		data := sf.data
+		if data == nil {
+			if data, sf.fileErr = sf.file.Data(); sf.fileErr != nil {
+				return
+			}
+		}
+		sf.parsed, sf.fileErr = highlight.ParseFile(data)
+	})
+	return sf.parsed, sf.fileErr
+}
+
+// A syntaxCatalog lists the syntax files by their headers, the user's
+// files first as they take precedence over the default ones
+type syntaxCatalog struct {
+	files  []*syntaxFile
+	byType map[string]*syntaxFile
+	// others are the syntax files without a header file, whose filetype is
+	// only read if an include isn't found among the files
+	others []config.RuntimeFile
+
+	// the number of runtime files the catalog was made from, and the
+	// content of the user's syntax files
+	numSyntax, numHeaders int
+	userData              []string
+}
+
+var (
+	syntaxes     *syntaxCatalog
+	syntaxesLock sync.Mutex
+)
+
+// parallel calls f with 0 to n-1 on as many goroutines as there are
+// processors, and returns when all the calls returned
+func parallel(n int, f func(i int)) {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0) && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// loadSyntaxCatalog returns the catalog of the syntax files, which is made
+// again when syntax files were added or when one of the user's files
+// changed
+func loadSyntaxCatalog() *syntaxCatalog {
+	syntaxesLock.Lock()
+	defer syntaxesLock.Unlock()
+
+	real := config.ListRealRuntimeFiles(config.RTSyntax)
+	datas := make([][]byte, len(real))
+	for i, f := range real {
+		data, err := f.Data()
+		if err != nil {
+			screen.TermMessage("Error loading syntax file " + f.Name() + ": " + err.Error())
+			continue
+		}
+		datas[i] = data
+	}
+	if c := syntaxes; c != nil && !c.stale(datas) {
+		return c
+	}
+
+	c := &syntaxCatalog{
+		byType:     make(map[string]*syntaxFile),
+		numSyntax:  len(config.ListRuntimeFiles(config.RTSyntax)),
+		numHeaders: len(config.ListRuntimeFiles(config.RTSyntaxHeader)),
+	}
+	var users []*syntaxFile
+	for i, f := range real {
+		c.userData = append(c.userData, string(datas[i]))
+		if datas[i] == nil {
+			continue
+		}
+		if errs := validateSyntaxFile(f.Name(), datas[i]); len(errs) > 0 {
+			continue
+		}
+		users = append(users, &syntaxFile{file: f, data: datas[i]})
+	}
+	hdrs := config.ListRuntimeFiles(config.RTSyntaxHeader)
+	defaults := make([]*syntaxFile, len(hdrs))
+
+	// compiling the detection regexes of the headers is most of the time
+	// taken by the catalog
+	errs := make([]string, len(users)+len(hdrs))
+	parallel(len(users)+len(hdrs), func(i int) {
+		if i < len(users) {
+			sf := users[i]
+			var err error
+			if sf.header, err = highlight.MakeHeaderYaml(sf.data); err != nil {
+				errs[i] = "Error parsing header for syntax file " + sf.file.Name() + ": " + err.Error()
+			}
+			return
+		}
+		f := hdrs[i-len(users)]
+		data, err := f.Data()
+		if err != nil {
+			errs[i] = "Error loading syntax header file " + f.Name() + ": " + err.Error()
+			return
+		}
+		header, err := highlight.MakeHeader(data)
+		if err != nil {
+			errs[i] = "Error reading syntax header file " + f.Name() + ": " + err.Error()
+			return
+		}
+		defaults[i-len(users)] = &syntaxFile{header: header}
+	})
+	for _, err := range errs {
+		if err != "" {
+			screen.TermMessage(err)
+		}
+	}
+
+	named := make(map[string]bool)
+	for _, sf := range users {
+		if sf.header != nil {
+			c.add(sf)
+			named[sf.file.Name()] = true
+		}
+	}
+	for i, sf := range defaults {
+		if sf == nil {
+			continue
+		}
+		name := hdrs[i].Name()
+		for _, f := range config.ListRuntimeFiles(config.RTSyntax) {
+			if f.Name() == name {
+				sf.file = f
+				named[name] = true
+				c.add(sf)
+				break
+			}
+		}
+	}
+	for _, f := range config.ListRuntimeFiles(config.RTSyntax) {
+		if !named[f.Name()] {
+			c.others = append(c.others, f)
+		}
+	}
+	syntaxes = c
+	return c
+}
+
+// add adds a syntax file to the catalog
+func (c *syntaxCatalog) add(sf *syntaxFile) {
+	c.files = append(c.files, sf)
+	if _, ok := c.byType[sf.header.FileType]; !ok {
+		c.byType[sf.header.FileType] = sf
+	}
+}
+
+// stale returns whether runtime files were added since the catalog was
+// made, or the user's syntax files changed
+func (c *syntaxCatalog) stale(datas [][]byte) bool {
+	if c.numSyntax != len(config.ListRuntimeFiles(config.RTSyntax)) ||
+		c.numHeaders != len(config.ListRuntimeFiles(config.RTSyntaxHeader)) ||
+		len(c.userData) != len(datas) {
+		return true
+	}
+	for i, data := range datas {
+		if string(data) != c.userData[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the syntax file of the filetype ft, or nil
+func (c *syntaxCatalog) find(ft string) *syntaxFile {
+	if sf, ok := c.byType[ft]; ok {
+		return sf
+	}
+	for len(c.others) > 0 {
+		f := c.others[0]
+		c.others = c.others[1:]
+		data, err := f.Data()
+		if err != nil || invalidSyntaxFile(f.Name(), data) {
+			continue
+		}
+		header, err := highlight.MakeHeaderYaml(data)
+		if err != nil {
+			continue
+		}
+		sf := &syntaxFile{file: f, header: header}
+		c.byType[header.FileType] = sf
+		if header.FileType == ft {
+			return sf
+		}
+	}
+	return nil
+}
+
+// detect returns the syntax file of the filetype ft, or the one detected
+// from the path and the first line of a buffer if ft is unknown
+func (c *syntaxCatalog) detect(ft, path string, first []byte) *syntaxFile {
+	for _, sf := range c.files {
+		if ft == "unknown" || ft == "" {
+			if highlight.MatchFiletype(sf.header.FtDetect, path, first) {
+				return sf
+			}
+		} else if sf.header.FileType == ft {
+			return sf
+		}
+	}
+	return nil
+}
+
+// loadDef returns the syntax definition of sf with its includes resolved,
+// which is parsed the first time and shared by the buffers of the filetype.
+// The included files are parsed in parallel.
+func (c *syntaxCatalog) loadDef(sf *syntaxFile) (*highlight.Def, []error) {
+	sf.defOnce.Do(func() {
+		file, err := sf.parse()
+		if err != nil {
+			sf.defErrs = append(sf.defErrs, err)
+			return
+		}
+		def, err := highlight.ParseDef(file, sf.header)
+		if err != nil {
+			sf.defErrs = append(sf.defErrs, err)
+			return
+		}
+		sf.def = def
+		if !highlight.HasIncludes(def) {
+			return
+		}
+
+		// the included filetypes may include other filetypes in turn, so
+		// the included files are parsed until all includes are found
+		seen := map[string]bool{def.FileType: true}
+		var files []*highlight.File
+		next := highlight.GetIncludes(def)
+		for len(next) > 0 {
+			var round []*syntaxFile
+			for _, ft := range next {
+				if !seen[ft] {
+					seen[ft] = true
+					if inc := c.find(ft); inc != nil {
+						round = append(round, inc)
+					}
+				}
+			}
+			parsed := make([]*highlight.File, len(round))
+			includes := make([][]string, len(round))
+			errs := make([]error, len(round))
+			parallel(len(round), func(i int) {
+				f, err := round[i].parse()
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				parsed[i] = f
+				if d, err := highlight.ParseDef(f, nil); err == nil {
+					includes[i] = highlight.GetIncludes(d)
+				}
+			})
+			next = nil
+			for i := range round {
+				if errs[i] != nil {
+					sf.defErrs = append(sf.defErrs, errs[i])
+					continue
+				}
+				files = append(files, parsed[i])
+				next = append(next, includes[i]...)
+			}
+		}
+		highlight.ResolveIncludes(def, files)
+	})
+	return sf.def, sf.defErrs
+}
+
+// invalidateSyntaxCatalog makes the catalog of the syntax files again the
+// next time it is used
+func invalidateSyntaxCatalog() {
+	syntaxesLock.Lock()
+	syntaxes = nil
+	syntaxesLock.Unlock()
+}
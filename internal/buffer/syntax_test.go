@@ -0,0 +1,64 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+const catalogOuter = `filetype: catalogouter
+
+detect:
+    filename: "\\.outer$"
+
+rules:
+    - statement: "\\bouter\\b"
+    - default:
+        start: "<<"
+        end: ">>"
+        rules:
+            - include: "cataloginner"
+`
+
+const catalogInner = `filetype: cataloginner
+
+detect:
+    filename: "\\.inner$"
+
+rules:
+    - constant: "\\binner\\b"
+`
+
+func TestSyntaxCatalog(t *testing.T) {
+	config.PluginAddRuntimeFileFromMemory(config.RTSyntax, "catalogouter", catalogOuter)
+	config.PluginAddRuntimeFileFromMemory(config.RTSyntax, "cataloginner", catalogInner)
+
+	c := loadSyntaxCatalog()
+	assert.True(t, c == loadSyntaxCatalog())
+
+	outer := c.detect("unknown", "file.outer", nil)
+	inner := c.detect("", "file.inner", nil)
+	if assert.NotNil(t, outer) && assert.NotNil(t, inner) {
+		assert.Equal(t, "catalogouter", outer.header.FileType)
+		assert.Equal(t, inner, c.detect("cataloginner", "", nil))
+		assert.Nil(t, outer.parsed)
+		assert.Nil(t, inner.parsed)
+
+		def, errs := c.loadDef(outer)
+		assert.Empty(t, errs)
+		if assert.NotNil(t, def) {
+			assert.Equal(t, "catalogouter", def.FileType)
+		}
+		// the included file is parsed along with the outer one, and the
+		// definition is shared by the next buffers
+		assert.NotNil(t, inner.parsed)
+		again, _ := c.loadDef(outer)
+		assert.True(t, def == again)
+	}
+	assert.Nil(t, c.detect("unknown", "file.unknownext", nil))
+
+	config.PluginAddRuntimeFileFromMemory(config.RTSyntax, "catalogother", "filetype: catalogother\nrules: []\n")
+	assert.False(t, c == loadSyntaxCatalog())
+	invalidateSyntaxCatalog()
+}
@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/pkg/highlight"
+)
+
+// syntaxTestHeader marks the first line of a syntax test, which reads
+// "<comment> SYNTAX TEST <filetype>"
+const syntaxTestHeader = "SYNTAX TEST"
+
+// A syntaxAssertion is a line of a syntax test such as "// ^^^ statement",
+// checking that the carets' columns of the last line of code have the group
+type syntaxAssertion struct {
+	// line is the line of the assertion in the test, and code the line of
+	// code it checks
+	line, code int
+	// start and end are the columns of the carets
+	start, end int
+	group      string
+	not        bool
+}
+
+// IsSyntaxTest returns whether the text starts as a syntax test
+func IsSyntaxTest(first []byte) bool {
+	return strings.Contains(string(first), syntaxTestHeader)
+}
+
+// RunSyntaxTest highlights the code of a syntax test with the syntax of its
+// filetype and returns the messages of the assertions which fail, at their
+// lines of the test. The lines starting with the comment marker of the
+// header followed by carets are the assertions, which check that the
+// columns of the carets in the last line of code before them are
+// highlighted with the group following the carets, or one of its
+// subgroups, or not if the group starts with '-'. The other lines are the
+// code.
+func RunSyntaxTest(text string) (string, []*Message, error) {
+	lines := strings.Split(text, "\n")
+	i := strings.Index(lines[0], syntaxTestHeader)
+	comment := ""
+	if i >= 0 {
+		comment = strings.TrimSpace(lines[0][:i])
+	}
+	if comment == "" {
+		return "", nil, errors.New("The first line must be a comment with " + syntaxTestHeader + " and the filetype")
+	}
+	ft := strings.Trim(strings.TrimSpace(lines[0][i+len(syntaxTestHeader):]), "\"")
+
+	c := loadSyntaxCatalog()
+	sf := c.find(ft)
+	if sf == nil {
+		return ft, nil, errors.New("No syntax file for the filetype " + ft)
+	}
+	def, errs := c.loadDef(sf)
+	if def == nil {
+		if len(errs) > 0 {
+			return ft, nil, errs[0]
+		}
+		return ft, nil, errors.New("Could not load the syntax of " + ft)
+	}
+
+	var code []string
+	var asserts []syntaxAssertion
+	var msgs []*Message
+	for n, l := range lines[1:] {
+		n++
+		rest := strings.TrimLeft(l, " \t")
+		if !strings.HasPrefix(rest, comment) || !strings.HasPrefix(strings.TrimLeft(rest[len(comment):], " \t"), "^") {
+			code = append(code, l)
+			continue
+		}
+		runes := []rune(l)
+		start := strings.IndexRune(l, '^')
+		start = len([]rune(l[:start]))
+		end := start
+		for end < len(runes) && runes[end] == '^' {
+			end++
+		}
+		group := strings.TrimSpace(string(runes[end:]))
+		a := syntaxAssertion{line: n, code: len(code) - 1, start: start, end: end}
+		if strings.HasPrefix(group, "-") {
+			a.not = true
+			group = strings.TrimSpace(group[1:])
+		}
+		a.group = group
+		if a.code < 0 || group == "" {
+			msgs = append(msgs, NewMessage("syntaxtest", "The assertion needs a line of code above it and a group", Loc{start, n}, Loc{end, n}, MTError))
+			continue
+		}
+		asserts = append(asserts, a)
+	}
+
+	b := NewBufferFromString(strings.Join(code, "\n"), "", BTScratch)
+	defer b.Close()
+	h := highlight.NewHighlighter(def)
+	h.HighlightStates(b)
+	h.HighlightMatches(b, 0, b.End().Y)
+
+	for _, a := range asserts {
+		match := b.LineArray.Match(a.code)
+		for x := a.start; x < a.end; x++ {
+			got := groupAt(match, x)
+			ok := got == a.group || strings.HasPrefix(got, a.group+".")
+			if ok == a.not {
+				msg := fmt.Sprintf("Column %d is %s, expected %s", x+1, got, a.group)
+				if a.not {
+					msg = fmt.Sprintf("Column %d is %s, expected another group", x+1, got)
+				}
+				msgs = append(msgs, NewMessage("syntaxtest", msg, Loc{x, a.line}, Loc{a.end, a.line}, MTError))
+				break
+			}
+		}
+	}
+	return ft, msgs, nil
+}
+
+// groupAt returns the name of the group highlighting the column x of a
+// line with the given matches, or "default"
+func groupAt(match highlight.LineMatch, x int) string {
+	var g highlight.Group
+	last := -1
+	for col, group := range match {
+		if col <= x && col > last {
+			g, last = group, col
+		}
+	}
+	if name := g.String(); name != "" {
+		return name
+	}
+	return "default"
+}
@@ -0,0 +1,46 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+const syntaxTest = `# SYNTAX TEST "catalogouter"
+x outer << outer >>
+# ^^^^^ statement
+#  ^^^ -statement
+#               ^ default
+#        ^^^^^ statement
+`
+
+func TestRunSyntaxTest(t *testing.T) {
+	config.PluginAddRuntimeFileFromMemory(config.RTSyntax, "catalogouter", catalogOuter)
+	config.PluginAddRuntimeFileFromMemory(config.RTSyntax, "cataloginner", catalogInner)
+	defer invalidateSyntaxCatalog()
+
+	assert.True(t, IsSyntaxTest([]byte("// SYNTAX TEST go")))
+	assert.False(t, IsSyntaxTest([]byte("package main")))
+
+	ft, msgs, err := RunSyntaxTest(syntaxTest)
+	assert.NoError(t, err)
+	assert.Equal(t, "catalogouter", ft)
+	if assert.Len(t, msgs, 2) {
+		// the carets are not all in a statement, and the region does not
+		// highlight outer
+		assert.Equal(t, Loc{3, 3}, msgs[0].Start)
+		assert.Equal(t, "Column 4 is statement, expected another group", msgs[0].Msg)
+		assert.Equal(t, Loc{9, 5}, msgs[1].Start)
+		assert.Equal(t, "Column 10 is default, expected statement", msgs[1].Msg)
+	}
+
+	_, msgs, err = RunSyntaxTest("# SYNTAX TEST catalogouter\n# ^ statement\n")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+
+	_, _, err = RunSyntaxTest("x outer\n")
+	assert.Error(t, err)
+	_, _, err = RunSyntaxTest("# SYNTAX TEST catalogunknown\n")
+	assert.Error(t, err)
+}
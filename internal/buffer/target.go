@@ -0,0 +1,222 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A TargetKind tells what a target under the cursor refers to
+type TargetKind int
+
+const (
+	// TargetURL is a URL, opened in the browser
+	TargetURL TargetKind = iota
+	// TargetFile is the path of a file
+	TargetFile
+	// TargetImport is the name of a file or module included or imported by
+	// the line, found according to the language
+	TargetImport
+)
+
+// String returns the name of the kind: url, file or import
+func (k TargetKind) String() string {
+	return [...]string{"url", "file", "import"}[k]
+}
+
+// A Target is a URL, a path or an imported module under the cursor. Line
+// and Col are given after a path as "path:line:col", and are 0 otherwise.
+type Target struct {
+	Kind      TargetKind
+	Text      string
+	Line, Col int
+}
+
+var (
+	urlRegex = regexp.MustCompile("(?:\\b(?:https?|ftp|file)://|\\bwww\\.)[^\\s<>\"'`]+")
+	// pathRegex matches the runs of characters which may be part of a path
+	pathRegex = regexp.MustCompile("[^\\s\"'`<>()\\[\\]{},;|=]+")
+	// lineSuffixRegex matches the line and column after a path
+	lineSuffixRegex = regexp.MustCompile(`^(.+?)(?::(\d+))?(?::(\d+))?:?$`)
+)
+
+// importRegexes match the lines including or importing a file or a module
+// in the languages, and capture its name
+var importRegexes = map[string]*regexp.Regexp{
+	"c":          regexp.MustCompile(`^\s*#\s*(?:include|import)\s*[<"]([^>"]+)[>"]`),
+	"css":        regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)`),
+	"javascript": regexp.MustCompile(`\b(?:from|import|require)\s*\(?\s*['"]([^'"]+)['"]`),
+	"lua":        regexp.MustCompile(`\b(?:require|dofile|loadfile)\s*\(?\s*['"]([^'"]+)['"]`),
+	"python":     regexp.MustCompile(`^\s*(?:from\s+(\.*[\w.]*)\s+import\b|import\s+([\w.]+))`),
+	"ruby":       regexp.MustCompile(`\brequire(?:_relative)?\s*\(?\s*['"]([^'"]+)['"]`),
+	"shell":      regexp.MustCompile(`^\s*(?:source|\.)\s+['"]?([^\s'";]+)`),
+}
+
+// importLanguages give the language of the filetypes sharing its imports
+var importLanguages = map[string]string{
+	"c++":        "c",
+	"objc":       "c",
+	"python2":    "python",
+	"less":       "css",
+	"scss":       "css",
+	"sass":       "css",
+	"typescript": "javascript",
+	"jsx":        "javascript",
+	"tsx":        "javascript",
+	"svelte":     "javascript",
+	"vue":        "javascript",
+}
+
+// language returns the language of the buffer's filetype for its imports
+func (b *Buffer) language() string {
+	if l, ok := importLanguages[b.FileType()]; ok {
+		return l
+	}
+	return b.FileType()
+}
+
+// TargetAt returns what is under loc: a URL, else the file or module
+// imported by the line, else a path possibly followed by ":line:col".
+func (b *Buffer) TargetAt(loc Loc) (Target, bool) {
+	line := b.LineBytes(loc.Y)
+	x := len(util.SliceStart(line, loc.X))
+	under := func(r *regexp.Regexp) (int, int, bool) {
+		for _, m := range r.FindAllIndex(line, -1) {
+			if x >= m[0] && x <= m[1] {
+				return m[0], m[1], true
+			}
+		}
+		return 0, 0, false
+	}
+
+	if start, end, ok := under(urlRegex); ok {
+		return Target{Kind: TargetURL, Text: trimURL(string(line[start:end]))}, true
+	}
+	if r, ok := importRegexes[b.language()]; ok {
+		if m := r.FindSubmatch(line); m != nil {
+			for _, name := range m[1:] {
+				if len(name) > 0 {
+					return Target{Kind: TargetImport, Text: string(name)}, true
+				}
+			}
+		}
+	}
+	if start, end, ok := under(pathRegex); ok {
+		m := lineSuffixRegex.FindStringSubmatch(string(line[start:end]))
+		t := Target{Kind: TargetFile, Text: m[1]}
+		t.Line, _ = strconv.Atoi(m[2])
+		t.Col, _ = strconv.Atoi(m[3])
+		return t, true
+	}
+	return Target{}, false
+}
+
+// trimURL removes the punctuation ending a sentence after a URL, and the
+// closing brackets which don't belong to it
+func trimURL(url string) string {
+	for len(url) > 0 {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"):
+		case last == ']' && strings.Count(url, "[") < strings.Count(url, "]"):
+		default:
+			return url
+		}
+		url = url[:len(url)-1]
+	}
+	return url
+}
+
+// ResolveTarget returns the path of the file of a file or import target,
+// or an empty string if it isn't found. Paths are relative to the
+// directory of the buffer's file, the working directory or the root of
+// the project. The imports are searched for according to the language,
+// also in the directories of the includepath option.
+func (b *Buffer) ResolveTarget(t Target) string {
+	wd, _ := os.Getwd()
+	dir := wd
+	if b.AbsPath != "" {
+		dir = filepath.Dir(b.AbsPath)
+	}
+	dirs := []string{dir, wd, util.ProjectRoot(dir)}
+
+	if t.Kind == TargetFile {
+		path, err := util.ReplaceHome(t.Text)
+		if err != nil {
+			return ""
+		}
+		return findFile(dirs, []string{path})
+	}
+
+	for _, d := range strings.Split(b.Settings["includepath"].(string), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			if d, err := util.ReplaceHome(d); err == nil {
+				dirs = append(dirs, d)
+			}
+		}
+	}
+	name := t.Text
+	switch b.language() {
+	case "c":
+		return findFile(append(dirs, "/usr/local/include", "/usr/include"), []string{name})
+	case "python":
+		// each leading dot is a parent of the file's directory
+		rel := strings.TrimLeft(name, ".")
+		if up := len(name) - len(rel); up > 0 {
+			d := dir
+			for i := 1; i < up; i++ {
+				d = filepath.Dir(d)
+			}
+			dirs = []string{d}
+		}
+		p := filepath.FromSlash(strings.ReplaceAll(rel, ".", "/"))
+		return findFile(dirs, []string{p + ".py", filepath.Join(p, "__init__.py")})
+	case "javascript":
+		exts := []string{"", ".js", ".ts", ".jsx", ".tsx", ".mjs", ".cjs", ".json", "/index.js", "/index.ts"}
+		if !strings.HasPrefix(name, ".") && !filepath.IsAbs(name) {
+			// a package of the project
+			dirs = []string{filepath.Join(util.ProjectRoot(dir), "node_modules")}
+		} else {
+			dirs = []string{dir}
+		}
+		var names []string
+		for _, e := range exts {
+			names = append(names, filepath.FromSlash(name+e))
+		}
+		return findFile(dirs, names)
+	case "lua":
+		p := filepath.FromSlash(strings.ReplaceAll(name, ".", "/"))
+		return findFile(dirs, []string{name, p + ".lua", filepath.Join(p, "init.lua")})
+	case "ruby":
+		return findFile(dirs, []string{name, name + ".rb"})
+	case "css":
+		base := filepath.Join(filepath.Dir(name), "_"+filepath.Base(name))
+		return findFile(dirs, []string{name, name + ".css", name + ".scss", base + ".scss", name + ".less"})
+	}
+	return findFile(dirs, []string{name})
+}
+
+// findFile returns the first of the names which is a file, as an absolute
+// path or relative to one of the directories
+func findFile(dirs, names []string) string {
+	for _, n := range names {
+		if filepath.IsAbs(n) {
+			if info, err := os.Stat(n); err == nil && !info.IsDir() {
+				return n
+			}
+			continue
+		}
+		for _, d := range dirs {
+			p := filepath.Join(d, n)
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				return p
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetAt(t *testing.T) {
+	b := NewBufferFromString("see (https://x.org/a_(b)). or www.y.com\nopen ~/dir/é.go:12:3: error\n#include <stdio.h>\n  |  ", "", BTDefault)
+
+	tests := []struct {
+		loc  Loc
+		want Target
+	}{
+		{Loc{X: 10, Y: 0}, Target{Kind: TargetURL, Text: "https://x.org/a_(b)"}},
+		{Loc{X: 32, Y: 0}, Target{Kind: TargetURL, Text: "www.y.com"}},
+		{Loc{X: 0, Y: 0}, Target{Kind: TargetFile, Text: "see"}},
+		{Loc{X: 8, Y: 1}, Target{Kind: TargetFile, Text: "~/dir/é.go", Line: 12, Col: 3}},
+		// includes are files in the other filetypes
+		{Loc{X: 12, Y: 2}, Target{Kind: TargetFile, Text: "stdio.h"}},
+	}
+	for _, tt := range tests {
+		got, ok := b.TargetAt(tt.loc)
+		assert.True(t, ok)
+		assert.Equal(t, tt.want, got, "%v", tt.loc)
+	}
+	_, ok := b.TargetAt(Loc{X: 1, Y: 3})
+	assert.False(t, ok)
+
+	b.Settings["filetype"] = "c++"
+	got, _ := b.TargetAt(Loc{X: 0, Y: 2})
+	assert.Equal(t, Target{Kind: TargetImport, Text: "stdio.h"}, got)
+
+	b = NewBufferFromString("from ..pkg.mod import x\nimport os.path", "", BTDefault)
+	b.Settings["filetype"] = "python"
+	got, _ = b.TargetAt(Loc{X: 0, Y: 0})
+	assert.Equal(t, Target{Kind: TargetImport, Text: "..pkg.mod"}, got)
+	got, _ = b.TargetAt(Loc{X: 0, Y: 1})
+	assert.Equal(t, Target{Kind: TargetImport, Text: "os.path"}, got)
+}
+
+func TestResolveTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-target")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	files := []string{"src/main.py", "src/util/__init__.py", "pkg/mod.py", "lib/a.js", "node_modules/dep/index.js", "inc/h.h", "notes.txt"}
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.Nil(t, ioutil.WriteFile(p, nil, 0644))
+	}
+	file := func(name, ft string) *Buffer {
+		b := NewBufferFromString("", "", BTDefault)
+		b.AbsPath = filepath.Join(dir, filepath.FromSlash(name))
+		b.Settings["filetype"] = ft
+		return b
+	}
+	path := func(f string) string {
+		return filepath.Join(dir, filepath.FromSlash(f))
+	}
+
+	py := file("src/main.py", "python")
+	assert.Equal(t, path("src/util/__init__.py"), py.ResolveTarget(Target{Kind: TargetImport, Text: "util"}))
+	assert.Equal(t, path("pkg/mod.py"), py.ResolveTarget(Target{Kind: TargetImport, Text: "..pkg.mod"}))
+	assert.Equal(t, "", py.ResolveTarget(Target{Kind: TargetImport, Text: "missing"}))
+	assert.Equal(t, path("notes.txt"), py.ResolveTarget(Target{Kind: TargetFile, Text: "../notes.txt"}))
+
+	js := file("lib/b.js", "javascript")
+	assert.Equal(t, path("lib/a.js"), js.ResolveTarget(Target{Kind: TargetImport, Text: "./a"}))
+
+	// the project root is the directory with node_modules or .git
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+	assert.Equal(t, path("node_modules/dep/index.js"), js.ResolveTarget(Target{Kind: TargetImport, Text: "dep"}))
+
+	c := file("src/main.c", "c")
+	assert.Equal(t, "", c.ResolveTarget(Target{Kind: TargetImport, Text: "h.h"}))
+	c.Settings["includepath"] = path("inc")
+	assert.Equal(t, path("inc/h.h"), c.ResolveTarget(Target{Kind: TargetImport, Text: "h.h"}))
+}
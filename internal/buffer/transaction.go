@@ -0,0 +1,82 @@
+package buffer
+
+// A transaction groups the edits of a buffer from BeginTransaction until
+// CommitTransaction into one undo step, or undoes them all on
+// RollbackTransaction. The lines it changes are highlighted again and the
+// cursors relocated only when it ends, once for all its edits.
+type transaction struct {
+	// undo is the length of the undo stack when the transaction began, and
+	// redo the redo stack, which a rollback restores
+	undo int
+	redo *TEStack
+	// group is set when the transaction started the undo group of its
+	// events, rather than joining a group started before it
+	group bool
+	// start and end are the lines to highlight again, or start is -1
+	start, end int
+}
+
+// BeginTransaction starts a transaction on the buffer, whose edits are
+// undone as a single step. It returns false if one is started already.
+func (b *Buffer) BeginTransaction() bool {
+	if b.txn != nil {
+		return false
+	}
+	t := &transaction{undo: b.UndoStack.Len(), redo: b.RedoStack, start: -1}
+	if b.group == 0 {
+		b.groups++
+		b.group = b.groups
+		t.group = true
+	}
+	b.txn = t
+	return true
+}
+
+// CommitTransaction ends the transaction of the buffer, keeping its edits,
+// and returns false if there is none
+func (b *Buffer) CommitTransaction() bool {
+	return b.endTransaction() != nil
+}
+
+// RollbackTransaction ends the transaction of the buffer, undoing its
+// edits, and returns false if there is none. The redo stack is as it was
+// before the transaction.
+func (b *Buffer) RollbackTransaction() bool {
+	t := b.txn
+	if t == nil {
+		return false
+	}
+	for b.UndoStack.Len() > t.undo {
+		b.UndoOneEvent()
+	}
+	b.RedoStack = t.redo
+	b.endTransaction()
+	return true
+}
+
+// InTransaction returns whether a transaction is started on the buffer
+func (b *Buffer) InTransaction() bool {
+	return b.txn != nil
+}
+
+// endTransaction ends the transaction of the buffer, highlighting the lines
+// it changed and relocating the cursors, and returns it
+func (b *Buffer) endTransaction() *transaction {
+	t := b.txn
+	if t == nil {
+		return nil
+	}
+	b.txn = nil
+	if t.group {
+		b.group = 0
+		b.broken = true
+	}
+	if t.start >= 0 {
+		b.MarkModified(t.start, t.end)
+	}
+	for _, c := range b.cursors {
+		c.Relocate()
+		c.LastVisualX = c.GetVisualX()
+	}
+	return t
+}
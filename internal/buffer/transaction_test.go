@@ -0,0 +1,62 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+	b := NewBufferFromString("one\ntwo", "", BTDefault)
+	defer b.Close()
+	b.Settings["undotimeout"] = float64(0)
+
+	assert.True(t, b.BeginTransaction())
+	assert.False(t, b.BeginTransaction())
+	assert.True(t, b.InTransaction())
+	b.Insert(Loc{0, 0}, "a ")
+	b.Remove(Loc{0, 1}, Loc{3, 1})
+	b.Insert(Loc{0, 1}, "b")
+	assert.True(t, b.CommitTransaction())
+	assert.False(t, b.CommitTransaction())
+	assert.Equal(t, "a one\nb", string(b.Bytes()))
+
+	// the edits are undone as one step
+	b.Undo()
+	assert.Equal(t, "one\ntwo", string(b.Bytes()))
+	b.Redo()
+	assert.Equal(t, "a one\nb", string(b.Bytes()))
+
+	// a rollback undoes the edits and keeps the redo stack
+	b.Undo()
+	assert.True(t, b.BeginTransaction())
+	b.Insert(Loc{3, 1}, "\nthree")
+	b.GetActiveCursor().GotoLoc(Loc{5, 2})
+	assert.True(t, b.RollbackTransaction())
+	assert.False(t, b.RollbackTransaction())
+	assert.Equal(t, "one\ntwo", string(b.Bytes()))
+	assert.Equal(t, 3, b.RedoStack.Len())
+	b.Redo()
+	assert.Equal(t, "a one\nb", string(b.Bytes()))
+}
+
+func TestTransactionHighlighting(t *testing.T) {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = "if x else y"
+	}
+	b := highlightedBuffer(t, strings.Join(lines, "\n"))
+
+	// the lines changed during the transaction are highlighted at its end
+	b.BeginTransaction()
+	b.Insert(Loc{0, 40}, "/*")
+	b.Insert(Loc{0, 10}, "a\nb\nc\n")
+	b.Insert(Loc{0, 30}, "*/")
+	assert.False(t, b.Rehighlight(30))
+	assert.False(t, b.Rehighlight(43))
+	b.CommitTransaction()
+	assert.True(t, b.Rehighlight(30))
+	assert.True(t, b.Rehighlight(43))
+	checkHighlighting(t, b)
+}
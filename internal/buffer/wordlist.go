@@ -0,0 +1,105 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// projectWordsFile is the name of the file listing the words of a project
+// for the completion, at the root of the project
+const projectWordsFile = ".micro.words"
+
+// wordListBias is the score taken from the words of the word lists for
+// each rank below the words of the buffers: for an equal match, the words
+// of the buffers come first, then those of the project, of the wordlists
+// option and the keywords of the filetype
+const wordListBias = 8
+
+// A wordFile is a word list as it was read last
+type wordFile struct {
+	modTime time.Time
+	size    int64
+	words   []string
+}
+
+var (
+	wordFilesLock sync.Mutex
+	wordFiles     = make(map[string]*wordFile)
+)
+
+// readWords returns the words of the file at path, separated by
+// whitespace, leaving out the lines starting with #. The file is read again
+// only when it changed, and a missing file has no words.
+func readWords(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	wordFilesLock.Lock()
+	defer wordFilesLock.Unlock()
+	if f, ok := wordFiles[path]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return f.words
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	wordFiles[path] = &wordFile{info.ModTime(), info.Size(), words}
+	return words
+}
+
+// wordLists returns the lists of words completing the words of the buffer
+// besides those of the open buffers, from the first to the last ranked: the
+// words file of its project, the files of the wordlists option, relative to
+// the root of the project, and the keywords of its syntax
+func (b *Buffer) wordLists() [][]string {
+	dir, _ := os.Getwd()
+	if b.AbsPath != "" {
+		dir = filepath.Dir(b.AbsPath)
+	}
+	root := util.ProjectRoot(dir)
+
+	lists := [][]string{readWords(filepath.Join(root, projectWordsFile))}
+	var user []string
+	for _, path := range strings.Split(b.Settings["wordlists"].(string), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		path, _ = util.ReplaceHome(path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		user = append(user, readWords(path)...)
+	}
+	lists = append(lists, user)
+	if b.SyntaxDef != nil {
+		lists = append(lists, b.SyntaxDef.Keywords())
+	}
+	return lists
+}
+
+// sameStart returns whether the words start with the same letter, ignoring
+// the case. The words of the lists are only matched against the word being
+// completed if they do, the lists being much longer than the buffers.
+func sameStart(a, b string) bool {
+	ra, _ := utf8.DecodeRuneInString(a)
+	rb, _ := utf8.DecodeRuneInString(b)
+	return unicode.ToLower(ra) == unicode.ToLower(rb)
+}
@@ -0,0 +1,38 @@
+package clipboard
+
+import (
+	"strings"
+)
+
+// For storing the lines of a rectangular selection, which are written to the
+// clipboard one per line
+type blockClipboard map[Register][]string
+
+var block = make(blockClipboard)
+
+// getLines returns the lines of the rectangular selection stored for the
+// register, if they are the text of the clipboard (provided as an argument)
+func (c blockClipboard) getLines(r Register, clipboard string) []string {
+	lines := c[r]
+	if lines == nil || clipboard != strings.Join(lines, "\n") {
+		return nil
+	}
+	return lines
+}
+
+// WriteBlock writes the lines of a rectangular selection to a clipboard
+// register
+func WriteBlock(lines []string, r Register) error {
+	block[r] = lines
+	return write(strings.Join(lines, "\n"), r, CurrentMethod)
+}
+
+// ReadBlock reads the lines of a rectangular selection from a clipboard
+// register, and returns nil if its text is not one
+func ReadBlock(r Register) ([]string, error) {
+	clip, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	return block.getLines(r, clip), nil
+}
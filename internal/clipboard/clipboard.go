@@ -2,6 +2,7 @@ package clipboard
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/zyedidia/clipboard"
 )
@@ -35,11 +36,18 @@ const (
 	PrimaryReg = -2
 )
 
-// Initialize attempts to initialize the clipboard using the given method
+// Initialize attempts to initialize the clipboard using the given method.
+// In an SSH session, the external method is replaced by the terminal one if
+// the terminal is known to support it, since the external tools would only
+// reach the clipboard of the remote machine.
 func Initialize(m Method) error {
 	var err error
 	switch m {
 	case External:
+		if RemoteSession() && TerminalSupported() {
+			CurrentMethod = Terminal
+			return nil
+		}
 		err = clipboard.Initialize()
 	}
 	if err != nil {
@@ -55,7 +63,7 @@ func SetMethod(m string) Method {
 		CurrentMethod = Internal
 	case "external":
 		CurrentMethod = External
-	case "terminal":
+	case "terminal", "osc52":
 		CurrentMethod = Terminal
 	}
 	return CurrentMethod
@@ -80,6 +88,17 @@ func ReadMulti(r Register, num, ncursors int) (string, error) {
 	if ValidMulti(r, clip, ncursors) {
 		return multi.getText(r, num), nil
 	}
+	// the lines of a rectangular selection are pasted one per cursor
+	if lines := block.getLines(r, clip); len(lines) == ncursors && num < ncursors {
+		return lines[num], nil
+	}
+	// text with one line per cursor, such as a column of values copied
+	// with as many cursors, is spread over the cursors
+	if ncursors > 1 && num < ncursors {
+		if lines := strings.Split(strings.TrimSuffix(clip, "\n"), "\n"); len(lines) == ncursors {
+			return lines[num], nil
+		}
+	}
 	return clip, nil
 }
 
@@ -103,7 +122,7 @@ func read(r Register, m Method) (string, error) {
 	switch m {
 	case External:
 		switch r {
-		case ClipboardReg:
+		case SyncedReg:
 			return clipboard.ReadAll("clipboard")
 		case PrimaryReg:
 			return clipboard.ReadAll("primary")
@@ -114,12 +133,20 @@ func read(r Register, m Method) (string, error) {
 		return internal.read(r), nil
 	case Terminal:
 		switch r {
-		case ClipboardReg:
+		case SyncedReg, PrimaryReg:
 			// terminal paste works by sending an esc sequence to the
 			// terminal to trigger a paste event
-			return terminal.read("clipboard")
-		case PrimaryReg:
-			return terminal.read("primary")
+			reg := "clipboard"
+			if r == PrimaryReg {
+				reg = "primary"
+			}
+			clip, err := terminal.read(reg)
+			if err != nil && internal.has(r) {
+				// many terminals only allow writing to the clipboard, so
+				// the last text copied in micro is pasted instead
+				return internal.read(r), nil
+			}
+			return clip, err
 		default:
 			return internal.read(r), nil
 		}
@@ -131,7 +158,7 @@ func write(text string, r Register, m Method) error {
 	switch m {
 	case External:
 		switch r {
-		case ClipboardReg:
+		case SyncedReg:
 			return clipboard.WriteAll(text, "clipboard")
 		case PrimaryReg:
 			return clipboard.WriteAll(text, "primary")
@@ -142,10 +169,15 @@ func write(text string, r Register, m Method) error {
 		internal.write(text, r)
 	case Terminal:
 		switch r {
-		case ClipboardReg:
+		case SyncedReg, PrimaryReg:
+			internal.write(text, r)
+			if len(text) > MaxTerminalSize {
+				return ErrTooLarge{len(text)}
+			}
+			if r == PrimaryReg {
+				return terminal.write(text, "p")
+			}
 			return terminal.write(text, "c")
-		case PrimaryReg:
-			return terminal.write(text, "p")
 		default:
 			internal.write(text, r)
 		}
@@ -12,6 +12,11 @@ func (c internalClipboard) read(r Register) string {
 	return c[r]
 }
 
+func (c internalClipboard) has(r Register) bool {
+	_, ok := c[r]
+	return ok
+}
+
 func (c internalClipboard) write(text string, r Register) {
 	c[r] = text
 }
@@ -2,6 +2,7 @@ package clipboard
 
 import (
 	"bytes"
+	"strings"
 )
 
 // For storing multi cursor clipboard contents
@@ -15,8 +16,13 @@ func (c multiClipboard) getAllText(r Register) string {
 		return ""
 	}
 
+	// the texts are put on separate lines, so that pasting them with a
+	// single cursor gives one line per cursor
 	buf := &bytes.Buffer{}
-	for _, s := range content {
+	for i, s := range content {
+		if i > 0 && !strings.HasSuffix(content[i-1], "\n") {
+			buf.WriteByte('\n')
+		}
 		buf.WriteString(s)
 	}
 	return buf.String()
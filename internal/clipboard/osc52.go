@@ -0,0 +1,66 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxOSC52 is the size of the largest text tcell sends with OSC 52
+const maxOSC52 = 74993
+
+// MaxTerminalSize is the largest text in bytes which is written to the
+// terminal clipboard, given by the osc52maxsize option. Terminals ignore or
+// truncate OSC 52 sequences which are too long.
+var MaxTerminalSize = 65536
+
+// SetMaxTerminalSize sets the largest text written to the terminal
+// clipboard, which can't be larger than what tcell sends
+func SetMaxTerminalSize(n int) {
+	MaxTerminalSize = n
+	if n > maxOSC52 {
+		MaxTerminalSize = maxOSC52
+	}
+}
+
+// ErrTooLarge is returned when a text is too large to be written to the
+// terminal clipboard. The text is still stored in micro's clipboard.
+type ErrTooLarge struct {
+	Size int
+}
+
+func (e ErrTooLarge) Error() string {
+	return fmt.Sprintf("Text of %d bytes is too large for the terminal clipboard (osc52maxsize is %d), copied for micro only", e.Size, MaxTerminalSize)
+}
+
+// RemoteSession returns whether micro runs in an SSH session without
+// access to a graphical display, where the external tools cannot reach the
+// clipboard of the user
+func RemoteSession() bool {
+	if os.Getenv("SSH_TTY") == "" && os.Getenv("SSH_CONNECTION") == "" && os.Getenv("SSH_CLIENT") == "" {
+		return false
+	}
+	return os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
+
+// TerminalSupported returns whether the terminal is known to support
+// writing to the clipboard with OSC 52, from the TERM and TERM_PROGRAM
+// environment variables. tmux and screen forward the sequence to the
+// terminal they run in.
+func TerminalSupported() bool {
+	if os.Getenv("VTE_VERSION") != "" {
+		// gnome-terminal and the other VTE based terminals ignore OSC 52
+		return false
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "tmux":
+		return true
+	}
+	term := os.Getenv("TERM")
+	for _, t := range []string{"xterm-kitty", "alacritty", "foot", "st", "wezterm", "tmux", "screen", "contour"} {
+		if term == t || strings.HasPrefix(term, t+"-") {
+			return true
+		}
+	}
+	return false
+}
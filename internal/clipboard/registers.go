@@ -0,0 +1,63 @@
+package clipboard
+
+import (
+	"sort"
+)
+
+// SyncedReg is the register which is the system clipboard. It is the
+// clipboard register by default, in which case the named registers are only
+// stored in micro.
+var SyncedReg = ClipboardReg
+
+// NamedRegister returns the register named by the character r: a named
+// register for letters and digits, and the clipboard register for "
+func NamedRegister(r rune) (Register, bool) {
+	switch {
+	case r == '"':
+		return ClipboardReg, true
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return Register(r), true
+	}
+	return 0, false
+}
+
+// RegisterName returns the character naming the register r
+func RegisterName(r Register) string {
+	switch r {
+	case ClipboardReg:
+		return "\""
+	case PrimaryReg:
+		return "primary"
+	}
+	return string(rune(r))
+}
+
+// SetSyncedRegister makes the register named name the system clipboard,
+// the clipboard register being an internal register unless it is named
+func SetSyncedRegister(name string) {
+	SyncedReg = ClipboardReg
+	for _, r := range name {
+		if reg, ok := NamedRegister(r); ok {
+			SyncedReg = reg
+		}
+		break
+	}
+}
+
+// Registers returns the clipboard register followed by the named registers
+// which hold text or are synced with the system clipboard, in order
+func Registers() []Register {
+	var named []Register
+	for r, text := range internal {
+		if r != ClipboardReg && r != PrimaryReg && r != SyncedReg && text != "" {
+			named = append(named, r)
+		}
+	}
+	if SyncedReg != ClipboardReg {
+		named = append(named, SyncedReg)
+	}
+	sort.Slice(named, func(i, j int) bool {
+		return named[i] < named[j]
+	})
+	return append([]Register{ClipboardReg}, named...)
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/tcell/v2"
 )
 
@@ -15,6 +16,11 @@ var DefStyle tcell.Style = tcell.StyleDefault
 // The current colorscheme
 var Colorscheme map[string]tcell.Style
 
+// TermColors is the number of colors the terminal displays, or 0 if it is
+// unknown. The true colors of the colorschemes are approximated with the 256
+// colors palette when the terminal has it but doesn't display true colors.
+var TermColors int
+
 // GetColor takes in a syntax group and returns the colorscheme's style for that group
 func GetColor(color string) tcell.Style {
 	st := DefStyle
@@ -44,7 +50,7 @@ func GetColor(color string) tcell.Style {
 
 // ColorschemeExists checks if a given colorscheme exists
 func ColorschemeExists(colorschemeName string) bool {
-	return FindRuntimeFile(RTColorscheme, colorschemeName) != nil
+	return FindRuntimeFile(RTColorscheme, RemoteName(colorschemeName)) != nil
 }
 
 // InitColorscheme picks and initializes the colorscheme when micro starts
@@ -55,15 +61,25 @@ func InitColorscheme() error {
 	return LoadDefaultColorscheme()
 }
 
+// PreviewColorscheme displays the given colorscheme without changing the
+// colorscheme option, InitColorscheme goes back to the option's one
+func PreviewColorscheme(colorschemeName string) error {
+	DefStyle = tcell.StyleDefault
+	return LoadColorscheme(colorschemeName)
+}
+
 // LoadDefaultColorscheme loads the default colorscheme from $(ConfigDir)/colorschemes
 func LoadDefaultColorscheme() error {
 	return LoadColorscheme(GlobalSettings["colorscheme"].(string))
 }
 
-// LoadColorscheme loads the given colorscheme from a directory
+// LoadColorscheme loads the given colorscheme from a directory, or from the
+// cache of the runtime files if it is a URL
 func LoadColorscheme(colorschemeName string) error {
-	file := FindRuntimeFile(RTColorscheme, colorschemeName)
-	if file == nil {
+	file := FindRuntimeFile(RTColorscheme, RemoteName(colorschemeName))
+	if file == nil && IsRemote(colorschemeName) {
+		return errors.New(colorschemeName + " is not fetched, see 'runtime update'")
+	} else if file == nil {
 		return errors.New(colorschemeName + " is not a valid colorscheme")
 	}
 	if data, err := file.Data(); err != nil {
@@ -202,8 +218,46 @@ func StringToColor(str string) tcell.Color {
 			return GetColor256(num)
 		}
 		// Probably a truecolor hex value
-		return tcell.GetColor(str)
+		c := tcell.GetColor(str)
+		if c.IsRGB() && TermColors >= 256 && TermColors < 1<<24 {
+			return Approx256(c)
+		}
+		return c
+	}
+}
+
+// cubeLevels are the intensities of the red, green and blue components of
+// the 6x6x6 color cube of the 256 colors palette
+var cubeLevels = [6]int32{0, 95, 135, 175, 215, 255}
+
+// Approx256 returns the color of the 256 colors palette closest to the true
+// color c. The 16 first colors are left out as they are configured in the
+// terminal, so that the approximation looks the same everywhere.
+func Approx256(c tcell.Color) tcell.Color {
+	r, g, b := c.RGB()
+	dist := func(r2, g2, b2 int32) int32 {
+		return (r-r2)*(r-r2) + (g-g2)*(g-g2) + (b-b2)*(b-b2)
+	}
+	nearest := func(v int32) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if util.Abs(int(v-l)) < util.Abs(int(v-cubeLevels[best])) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	ri, gi, bi := nearest(r), nearest(g), nearest(b)
+	cube := dist(cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	// the grays 232 to 255 go from 8 to 238 by steps of 10
+	n := util.Clamp((int(r+g+b)/3-3)/10, 0, 23)
+	level := int32(8 + 10*n)
+	if dist(level, level, level) < cube {
+		return tcell.PaletteColor(232 + n)
 	}
+	return tcell.PaletteColor(16 + 36*ri + 6*gi + bi)
 }
 
 // GetColor256 returns the tcell color for a number between 0 and 255
@@ -72,3 +72,25 @@ color-link constant.string.char "#BDE6AD,#282828"`
 	assert.Equal(t, tcell.NewRGBColor(117, 113, 94), fg)
 	assert.Equal(t, tcell.NewRGBColor(40, 40, 40), bg)
 }
+
+func TestApprox256(t *testing.T) {
+	assert.Equal(t, tcell.PaletteColor(196), Approx256(tcell.NewRGBColor(255, 0, 0)))
+	assert.Equal(t, tcell.PaletteColor(231), Approx256(tcell.NewRGBColor(255, 255, 255)))
+	assert.Equal(t, tcell.PaletteColor(102), Approx256(tcell.NewRGBColor(135, 135, 135)))
+	// the grays are closer than the color cube for dark grays
+	assert.Equal(t, tcell.PaletteColor(235), Approx256(tcell.NewRGBColor(40, 40, 40)))
+	assert.Equal(t, tcell.PaletteColor(16), Approx256(tcell.NewRGBColor(0, 0, 0)))
+}
+
+func TestColorHex256(t *testing.T) {
+	TermColors = 256
+	defer func() { TermColors = 0 }()
+
+	fg, bg, _ := StringToStyle("#ef1234,#282828").Decompose()
+	assert.Equal(t, tcell.PaletteColor(197), fg)
+	assert.Equal(t, tcell.PaletteColor(235), bg)
+
+	TermColors = 1 << 24
+	fg, _, _ = StringToStyle("#ef1234").Decompose()
+	assert.Equal(t, tcell.NewRGBColor(239, 18, 52), fg)
+}
@@ -10,6 +10,24 @@ import (
 
 var ConfigDir string
 
+// StateDir stores what micro writes as it runs, such as the cursor positions,
+// the histories, the backups and the sessions, and DataDir what the user
+// creates with micro, such as macros and the personal dictionary. They are
+// kept out of ConfigDir so that the configuration can be versioned on its
+// own, unless the configuration directory is given with -config-dir or
+// $MICRO_CONFIG_HOME, in which case everything is stored there.
+var (
+	StateDir string
+	DataDir  string
+)
+
+// stateEntries and dataEntries are the files of StateDir and DataDir,
+// which older versions wrote to ConfigDir
+var (
+	stateEntries = []string{"buffers", "backups", "locks", "sessions", "recovery", "projects"}
+	dataEntries  = []string{"macros", "dictionaries"}
+)
+
 // InitConfigDir finds the configuration directory for micro according to the XDG spec.
 // If no directory is found, it creates one.
 func InitConfigDir(flagConfigDir string) error {
@@ -31,12 +49,14 @@ func InitConfigDir(flagConfigDir string) error {
 		microHome = filepath.Join(xdgHome, "micro")
 	}
 	ConfigDir = microHome
+	StateDir, DataDir = ConfigDir, ConfigDir
 
 	if len(flagConfigDir) > 0 {
 		if _, err := os.Stat(flagConfigDir); os.IsNotExist(err) {
 			e = errors.New("Error: " + flagConfigDir + " does not exist. Defaulting to " + ConfigDir + ".")
 		} else {
 			ConfigDir = flagConfigDir
+			StateDir, DataDir = ConfigDir, ConfigDir
 			return nil
 		}
 	}
@@ -48,5 +68,62 @@ func InitConfigDir(flagConfigDir string) error {
 		return errors.New("Error creating configuration directory: " + err.Error())
 	}
 
+	if os.Getenv("MICRO_CONFIG_HOME") == "" {
+		if StateDir, err = xdgDir("XDG_STATE_HOME", ".local", "state"); err != nil {
+			return err
+		}
+		if DataDir, err = xdgDir("XDG_DATA_HOME", ".local", "share"); err != nil {
+			return err
+		}
+		if err := migrate(StateDir, stateEntries); err != nil && e == nil {
+			e = err
+		}
+		if err := migrate(DataDir, dataEntries); err != nil && e == nil {
+			e = err
+		}
+	}
+
 	return e
 }
+
+// xdgDir returns the micro directory of the XDG base directory given by the
+// environment variable env, or the given directory of the home directory
+// if it isn't set, and creates it
+func xdgDir(env string, home ...string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" {
+		h, err := homedir.Dir()
+		if err != nil {
+			return "", errors.New("Error finding your home directory: " + err.Error())
+		}
+		base = filepath.Join(append([]string{h}, home...)...)
+	}
+	dir := filepath.Join(base, "micro")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", errors.New("Error creating directory " + dir + ": " + err.Error())
+	}
+	return dir, nil
+}
+
+// LogFile returns the path of the file the log is written to
+func LogFile() string {
+	return filepath.Join(StateDir, "log.txt")
+}
+
+// migrate moves the given files of ConfigDir, written there by older
+// versions, to dir if it doesn't have them yet
+func migrate(dir string, names []string) error {
+	for _, name := range names {
+		old, dst := filepath.Join(ConfigDir, name), filepath.Join(dir, name)
+		if _, err := os.Stat(old); err != nil {
+			continue
+		}
+		if _, err := os.Stat(dst); err == nil {
+			continue
+		}
+		if err := os.Rename(old, dst); err != nil {
+			return errors.New("Error moving " + old + " to " + dir + ": " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setenv(t *testing.T, env map[string]string) func() {
+	old := make(map[string]string)
+	for k, v := range env {
+		old[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range old {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func TestInitConfigDirXDG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-xdg")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer setenv(t, map[string]string{
+		"MICRO_CONFIG_HOME": "",
+		"XDG_CONFIG_HOME":   filepath.Join(dir, "config"),
+		"XDG_STATE_HOME":    filepath.Join(dir, "state"),
+		"XDG_DATA_HOME":     filepath.Join(dir, "data"),
+	})()
+	defer func(c, s, d string) { ConfigDir, StateDir, DataDir = c, s, d }(ConfigDir, StateDir, DataDir)
+
+	// files written by older versions in the config directory
+	old := filepath.Join(dir, "config", "micro")
+	assert.NoError(t, os.MkdirAll(filepath.Join(old, "buffers"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(old, "buffers", "history"), []byte("h"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(old, "macros"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(old, "settings.json"), []byte("{}"), 0644))
+	// a directory already in the state directory isn't replaced
+	assert.NoError(t, os.MkdirAll(filepath.Join(old, "sessions"), os.ModePerm))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "state", "micro", "sessions", "auto"), os.ModePerm))
+
+	assert.NoError(t, InitConfigDir(""))
+	assert.Equal(t, old, ConfigDir)
+	assert.Equal(t, filepath.Join(dir, "state", "micro"), StateDir)
+	assert.Equal(t, filepath.Join(dir, "data", "micro"), DataDir)
+
+	data, err := ioutil.ReadFile(filepath.Join(StateDir, "buffers", "history"))
+	assert.NoError(t, err)
+	assert.Equal(t, "h", string(data))
+	assert.DirExists(t, filepath.Join(DataDir, "macros"))
+	assert.DirExists(t, filepath.Join(StateDir, "sessions", "auto"))
+	assert.DirExists(t, filepath.Join(ConfigDir, "sessions"))
+	assert.FileExists(t, filepath.Join(ConfigDir, "settings.json"))
+	_, err = os.Stat(filepath.Join(ConfigDir, "buffers"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInitConfigDirGiven(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-xdg")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer setenv(t, map[string]string{"XDG_STATE_HOME": filepath.Join(dir, "state")})()
+	defer func(c, s, d string) { ConfigDir, StateDir, DataDir = c, s, d }(ConfigDir, StateDir, DataDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "buffers"), os.ModePerm))
+	assert.NoError(t, InitConfigDir(dir))
+	assert.Equal(t, dir, ConfigDir)
+	assert.Equal(t, dir, StateDir)
+	assert.Equal(t, dir, DataDir)
+	assert.DirExists(t, filepath.Join(dir, "buffers"))
+
+	defer setenv(t, map[string]string{"MICRO_CONFIG_HOME": dir})()
+	assert.NoError(t, InitConfigDir(""))
+	assert.Equal(t, dir, StateDir)
+	assert.Equal(t, dir, DataDir)
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// LocalHistory receives a value every localhistory minutes, when the main
+// loop adds the changed buffers to the local history
+var LocalHistory = make(chan bool)
+
+var historyTimer struct {
+	sync.Mutex
+	// gen counts the timers started, the older ones stop
+	gen int
+}
+
+// StartLocalHistory sends to LocalHistory every given number of minutes
+// from now on, or stops if it is not positive
+func StartLocalHistory(minutes float64) {
+	historyTimer.Lock()
+	historyTimer.gen++
+	gen := historyTimer.gen
+	historyTimer.Unlock()
+	if minutes <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(time.Duration(minutes * float64(time.Minute)))
+			historyTimer.Lock()
+			stopped := gen != historyTimer.gen
+			historyTimer.Unlock()
+			if stopped {
+				return
+			}
+			LocalHistory <- true
+		}
+	}()
+}
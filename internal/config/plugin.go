@@ -2,10 +2,11 @@ package config
 
 import (
 	"errors"
-	"log"
+	"time"
 
 	lua "github.com/yuin/gopher-lua"
 	ulua "github.com/zyedidia/micro/v2/internal/lua"
+	"github.com/zyedidia/micro/v2/internal/util"
 )
 
 // ErrNoSuchFunction is returned when Call is executed on a function that does not exist
@@ -15,7 +16,9 @@ var ErrNoSuchFunction = errors.New("No such function exists")
 func LoadAllPlugins() error {
 	var reterr error
 	for _, p := range Plugins {
+		start := time.Now()
 		err := p.Load()
+		util.RecordTiming("plugin "+p.Name+" load", start)
 		if err != nil {
 			reterr = err
 		}
@@ -31,7 +34,11 @@ func RunPluginFn(fn string, args ...lua.LValue) error {
 		if !p.IsEnabled() {
 			continue
 		}
+		start := time.Now()
 		_, err := p.Call(fn, args...)
+		if err != ErrNoSuchFunction {
+			util.RecordTiming("plugin "+p.Name+" "+fn, start)
+		}
 		if err != nil && err != ErrNoSuchFunction {
 			reterr = errors.New("Plugin " + p.Name + ": " + err.Error())
 		}
@@ -49,10 +56,12 @@ func RunPluginFnBool(fn string, args ...lua.LValue) (bool, error) {
 		if !p.IsEnabled() {
 			continue
 		}
+		start := time.Now()
 		val, err := p.Call(fn, args...)
 		if err == ErrNoSuchFunction {
 			continue
 		}
+		util.RecordTiming("plugin "+p.Name+" "+fn, start)
 		if err != nil {
 			reterr = errors.New("Plugin " + p.Name + ": " + err.Error())
 			continue
@@ -109,7 +118,7 @@ func (p *Plugin) Load() error {
 func (p *Plugin) Call(fn string, args ...lua.LValue) (lua.LValue, error) {
 	plug := ulua.L.GetGlobal(p.Name)
 	if plug == lua.LNil {
-		log.Println("Plugin does not exist:", p.Name, "at", p.DirName, ":", p)
+		util.Log(util.LogWarn, "config", "Plugin does not exist:", p.Name, "at", p.DirName, ":", p)
 		return nil, nil
 	}
 	luafn := ulua.L.GetField(plug, fn)
@@ -0,0 +1,540 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zyedidia/json5"
+)
+
+// A remoteType is a directory of runtime files which may be fetched from a
+// URL, with the files it holds
+type remoteType struct {
+	dir      string
+	fileType RTFiletype
+	pattern  string
+}
+
+var remoteTypes = []remoteType{
+	{"colorschemes", RTColorscheme, "*.micro"},
+	{"syntax", RTSyntax, "*.yaml"},
+	{"syntax", RTSyntaxHeader, "*.hdr"},
+	{"help", RTHelp, "*.md"},
+	{"snippets", RTSnippet, "*.snippets"},
+	{"snippets", RTSnippet, "*.json"},
+	{"abbreviations", RTAbbrev, "*.json"},
+	{"layouts", RTLayout, "*.json"},
+}
+
+// remoteExts are the directories of the files given by URL in the
+// runtimeurls option, by extension. The other files are given by bundles.
+var remoteExts = map[string]string{
+	".micro":    "colorschemes",
+	".yaml":     "syntax",
+	".hdr":      "syntax",
+	".md":       "help",
+	".snippets": "snippets",
+}
+
+// remoteClient fetches the runtime files, giving up quickly so that micro
+// starts offline
+var remoteClient = &http.Client{Timeout: 15 * time.Second}
+
+// A remoteFile is a file of the cache of the runtime files fetched from
+// URLs, at Path in RemoteDir. Bundle is the URL of the bundle listing it,
+// if it was not given directly.
+type remoteFile struct {
+	URL     string    `json:"url"`
+	Path    string    `json:"path"`
+	Sum     string    `json:"sha256"`
+	Bundle  string    `json:"bundle,omitempty"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// A remoteBundle lists runtime files to fetch along with the directory of
+// each, such as "colorschemes/team.micro" or "plug/team/team.lua"
+type remoteBundle struct {
+	Files []struct {
+		URL  string `json:"url"`
+		Path string `json:"path"`
+		Sum  string `json:"sha256"`
+	} `json:"files"`
+}
+
+// RemoteDir is the cache of the runtime files fetched from URLs, which
+// micro uses when the URLs can't be reached
+func RemoteDir() string {
+	if DataDir == "" {
+		return ""
+	}
+	return filepath.Join(DataDir, "remote")
+}
+
+// IsRemote returns whether a runtime file is given as a URL rather than a
+// name
+func IsRemote(name string) bool {
+	return strings.HasPrefix(name, "https://") || strings.HasPrefix(name, "http://")
+}
+
+func isHTTPS(u string) bool {
+	return strings.HasPrefix(u, "https://")
+}
+
+// splitSum splits the checksum pinned with "#sha256=" from a URL
+func splitSum(ref string) (string, string) {
+	if i := strings.Index(ref, "#sha256="); i >= 0 {
+		return ref[:i], strings.ToLower(ref[i+len("#sha256="):])
+	}
+	return ref, ""
+}
+
+// RemoteURLs returns the URLs of the runtime files which micro fetches: the
+// ones of the runtimeurls option and the colorscheme if it is a URL
+func RemoteURLs() []string {
+	var urls []string
+	if v, ok := GlobalSettings["runtimeurls"]; ok {
+		urls = stringList(v)
+	}
+	if v, ok := GlobalSettings["colorscheme"].(string); ok && IsRemote(v) {
+		urls = append(urls, v)
+	}
+	return urls
+}
+
+// stringList returns the strings of an option which is a list
+func stringList(v interface{}) []string {
+	switch l := v.(type) {
+	case []string:
+		return l
+	case []interface{}:
+		var res []string
+		for _, s := range l {
+			if s, ok := s.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+func validateRuntimeURLs(option string, value interface{}) error {
+	for _, ref := range stringList(value) {
+		u, sum := splitSum(ref)
+		if !IsRemote(u) {
+			return errors.New(ref + " is not an http or https URL")
+		}
+		if sum != "" && !validSum(sum) {
+			return errors.New(ref + " has an invalid sha256 checksum")
+		}
+		if ext := remoteExt(u); ext != ".json" && remoteExts[ext] == "" {
+			return errors.New(ref + " is not a colorscheme, syntax, help or snippets file or a bundle")
+		}
+	}
+	return nil
+}
+
+func validSum(sum string) bool {
+	b, err := hex.DecodeString(sum)
+	return err == nil && len(b) == sha256.Size
+}
+
+// remoteExt returns the extension of the file of a URL, ignoring its query
+func remoteExt(u string) string {
+	if p, err := url.Parse(u); err == nil {
+		return path.Ext(p.Path)
+	}
+	return ""
+}
+
+// readRemoteIndex reads the files of the cache, by URL
+func readRemoteIndex() map[string]*remoteFile {
+	index := make(map[string]*remoteFile)
+	data, err := ioutil.ReadFile(filepath.Join(RemoteDir(), "index.json"))
+	if err != nil {
+		return index
+	}
+	var files []*remoteFile
+	if json.Unmarshal(data, &files) == nil {
+		for _, f := range files {
+			index[f.URL] = f
+		}
+	}
+	return index
+}
+
+func writeRemoteIndex(index map[string]*remoteFile) error {
+	files := make([]*remoteFile, 0, len(index))
+	for _, f := range index {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	data, _ := json.MarshalIndent(files, "", "    ")
+	// the index is read while it is written by a fetch in the background
+	name := filepath.Join(RemoteDir(), "index.json")
+	if err := ioutil.WriteFile(name+".part", append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(name+".part", name)
+}
+
+// cached returns whether the file of the cache is there with the checksum
+// it was fetched with
+func (f *remoteFile) cached() bool {
+	data, err := ioutil.ReadFile(filepath.Join(RemoteDir(), filepath.FromSlash(f.Path)))
+	return err == nil && sum(data) == f.Sum
+}
+
+func sum(data []byte) string {
+	s := sha256.Sum256(data)
+	return hex.EncodeToString(s[:])
+}
+
+// A remoteFetch fetches the runtime files of the cache which are missing,
+// or all of them for an update
+type remoteFetch struct {
+	out    io.Writer
+	update bool
+	index  map[string]*remoteFile
+	// used are the URLs of the files still referenced, and changed the
+	// directories of the files fetched
+	used    map[string]bool
+	changed map[string]bool
+	errs    []string
+}
+
+// A RemoteResult is the result of a fetch of the runtime files in the
+// background, which the main loop applies with Apply
+type RemoteResult struct {
+	// Update is whether all the files were fetched again, Log what was
+	// fetched and Err the files which could not be
+	Update bool
+	Log    string
+	Err    error
+
+	changed map[string]bool
+}
+
+// RemoteResults receives the results of the fetches started by FetchRuntime
+var RemoteResults = make(chan *RemoteResult, 4)
+
+// remoteLock keeps the fetches from writing the cache at the same time
+var remoteLock sync.Mutex
+
+// FetchRuntime fetches in the background the runtime files given as URLs
+// which are not in the cache, or all of them if update is set, checking
+// them against their pinned checksums, and removes the files no longer
+// given from the cache. A file which can't be fetched is kept from the
+// cache if it is there. The result is sent to RemoteResults once the files
+// are fetched, and it returns false if there is nothing to fetch.
+func FetchRuntime(update bool) bool {
+	root := RemoteDir()
+	urls := RemoteURLs()
+	if root == "" || len(urls) == 0 && !exists(filepath.Join(root, "index.json")) {
+		return false
+	}
+	go func() {
+		remoteLock.Lock()
+		defer remoteLock.Unlock()
+
+		var out strings.Builder
+		changed, err := fetchRuntime(urls, &out, update)
+		RemoteResults <- &RemoteResult{
+			Update:  update,
+			Log:     out.String(),
+			Err:     err,
+			changed: changed,
+		}
+	}()
+	return true
+}
+
+// Changed returns whether any runtime files of the cache changed
+func (r *RemoteResult) Changed() bool {
+	return len(r.changed) > 0
+}
+
+// Apply registers the runtime files of the cache again if any changed, and
+// returns whether plugins did, which are only loaded when micro starts
+func (r *RemoteResult) Apply() bool {
+	if r.Changed() {
+		reloadRemoteFiles()
+	}
+	return r.changed["plug"]
+}
+
+// fetchRuntime fetches the runtime files of the URLs to the cache, and
+// returns the directories of the files which changed
+func fetchRuntime(urls []string, out io.Writer, update bool) (map[string]bool, error) {
+	f := &remoteFetch{
+		out:     out,
+		update:  update,
+		index:   readRemoteIndex(),
+		used:    make(map[string]bool),
+		changed: make(map[string]bool),
+	}
+	for _, ref := range urls {
+		u, want := splitSum(ref)
+		if remoteExt(u) == ".json" {
+			f.bundle(u, want)
+		} else if dir := remoteExts[remoteExt(u)]; dir != "" {
+			name := path.Base(strings.SplitN(u, "?", 2)[0])
+			f.file(u, path.Join(dir, name), want, "")
+		} else {
+			f.errs = append(f.errs, u+" is not a runtime file")
+		}
+	}
+	for u, file := range f.index {
+		if !f.used[u] {
+			os.Remove(filepath.Join(RemoteDir(), filepath.FromSlash(file.Path)))
+			delete(f.index, u)
+			f.changed[strings.SplitN(file.Path, "/", 2)[0]] = true
+			fmt.Fprintln(out, "Removed", file.Path)
+		}
+	}
+	if len(f.changed) > 0 {
+		if err := writeRemoteIndex(f.index); err != nil {
+			f.errs = append(f.errs, err.Error())
+		}
+	}
+	var err error
+	if len(f.errs) > 0 {
+		err = errors.New("Error fetching the runtime files: " + strings.Join(f.errs, "; "))
+	}
+	return f.changed, err
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// bundle fetches the bundle at u and the files it lists, using the cached
+// bundle if it can't be fetched
+func (f *remoteFetch) bundle(u, want string) {
+	f.file(u, path.Join("bundles", sum([]byte(u))[:16]+".json"), want, "")
+	m := f.index[u]
+	if m == nil || !m.cached() {
+		return
+	}
+	data, err := ioutil.ReadFile(filepath.Join(RemoteDir(), filepath.FromSlash(m.Path)))
+	var b remoteBundle
+	if err == nil {
+		err = json5.Unmarshal(data, &b)
+	}
+	if err != nil {
+		f.errs = append(f.errs, u+": "+err.Error())
+		return
+	}
+	base, _ := url.Parse(u)
+	for _, file := range b.Files {
+		p := path.Clean(file.Path)
+		parts := strings.SplitN(p, "/", 2)
+		if !remoteBundlePath(p) {
+			f.errs = append(f.errs, u+": the path "+file.Path+" is not a runtime file")
+			continue
+		}
+		// the URLs are relative to the bundle's
+		if ref, err := base.Parse(file.URL); err == nil {
+			file.URL = ref.String()
+		}
+		want := strings.ToLower(file.Sum)
+		// plugins run code, so they must be pinned by a bundle which can't
+		// be tampered with
+		if parts[0] == "plug" && !(isHTTPS(u) && isHTTPS(file.URL) && validSum(want)) {
+			f.errs = append(f.errs, u+": the plugin file "+file.Path+" must be fetched over https with a sha256 checksum")
+			continue
+		}
+		f.file(file.URL, p, want, u)
+	}
+}
+
+// remoteBundlePath returns whether a bundle may fetch a file to the path,
+// in one of the directories of the runtime files or in the directory of a
+// plugin. Backslashes are rejected since they separate the paths on Windows.
+func remoteBundlePath(p string) bool {
+	parts := strings.Split(p, "/")
+	if path.IsAbs(p) || len(parts) < 2 || strings.Contains(p, "\\") {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	if parts[0] == "plug" {
+		return len(parts) == 3
+	}
+	if len(parts) != 2 {
+		return false
+	}
+	for _, t := range remoteTypes {
+		if ok, _ := path.Match(t.pattern, parts[1]); ok && t.dir == parts[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// file fetches the file at u to p in the cache unless it is cached already,
+// and returns whether it was fetched
+func (f *remoteFetch) file(u, p, want, bundle string) bool {
+	f.used[u] = true
+	old := f.index[u]
+	if old != nil && !f.update && old.cached() && (want == "" || want == old.Sum) {
+		return false
+	}
+	// the names are kept unique among the files of the cache
+	for _, other := range f.index {
+		if other.URL != u && other.Path == p {
+			ext := path.Ext(p)
+			p = strings.TrimSuffix(p, ext) + "-" + sum([]byte(u))[:8] + ext
+			break
+		}
+	}
+
+	fail := func(err string) bool {
+		if old != nil && old.cached() && (want == "" || want == old.Sum) {
+			fmt.Fprintf(f.out, "%s: %s, using the cached copy\n", u, err)
+		} else {
+			f.errs = append(f.errs, u+": "+err)
+		}
+		return false
+	}
+	data, err := remoteGet(u)
+	if err != nil {
+		return fail(err.Error())
+	}
+	got := sum(data)
+	if want != "" && got != want {
+		return fail("the sha256 checksum is " + got + " instead of " + want)
+	}
+	if old != nil && old.Sum == got && old.Path == p && old.cached() {
+		old.Fetched = time.Now()
+		old.Bundle = bundle
+		f.changed["index"] = true
+		return true
+	}
+
+	name := filepath.Join(RemoteDir(), filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		return fail(err.Error())
+	}
+	if err := ioutil.WriteFile(name+".part", data, 0644); err != nil {
+		return fail(err.Error())
+	}
+	if err := os.Rename(name+".part", name); err != nil {
+		return fail(err.Error())
+	}
+	if old != nil && old.Path != p {
+		os.Remove(filepath.Join(RemoteDir(), filepath.FromSlash(old.Path)))
+	}
+	f.index[u] = &remoteFile{URL: u, Path: p, Sum: got, Bundle: bundle, Fetched: time.Now()}
+	f.changed[strings.SplitN(p, "/", 2)[0]] = true
+	fmt.Fprintln(f.out, "Fetched", u)
+	return true
+}
+
+func remoteGet(u string) ([]byte, error) {
+	resp, err := remoteClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// RemoteName returns the name of the runtime file fetched from a URL, or
+// the name itself if it is not a URL
+func RemoteName(name string) string {
+	if !IsRemote(name) {
+		return name
+	}
+	u, _ := splitSum(name)
+	if f, ok := readRemoteIndex()[u]; ok {
+		base := path.Base(f.Path)
+		return strings.TrimSuffix(base, path.Ext(base))
+	}
+	return name
+}
+
+// ListRemoteFiles writes the files of the cache of the runtime files along
+// with their state
+func ListRemoteFiles(out io.Writer) {
+	index := readRemoteIndex()
+	if len(index) == 0 {
+		fmt.Fprintln(out, "No runtime files are fetched from URLs")
+		return
+	}
+	files := make([]*remoteFile, 0, len(index))
+	for _, f := range index {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	for _, f := range files {
+		short := f.Sum
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		state := "ok"
+		if !f.cached() {
+			state = "missing or changed"
+		}
+		line := fmt.Sprintf("%s  %s  sha256:%s  fetched %s  %s", f.Path, f.URL, short, f.Fetched.Format("2006-01-02 15:04"), state)
+		if f.Bundle != "" {
+			line += "  (bundle " + f.Bundle + ")"
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+// reloadRemoteFiles registers the runtime files of the cache again, after
+// the user's files and before the default ones
+func reloadRemoteFiles() {
+	root := RemoteDir()
+	for _, t := range remoteTypes {
+		dir := filepath.Join(root, t.dir)
+		userDir := filepath.Join(ConfigDir, t.dir)
+		reload := func(files []RuntimeFile, fresh []RuntimeFile) []RuntimeFile {
+			var user, rest []RuntimeFile
+			for _, f := range files {
+				rf, ok := f.(realFile)
+				match, _ := filepath.Match(t.pattern, filepath.Base(string(rf)))
+				switch {
+				case ok && filepath.Dir(string(rf)) == dir && match:
+				case ok && filepath.Dir(string(rf)) == userDir:
+					user = append(user, f)
+				default:
+					rest = append(rest, f)
+				}
+			}
+			return append(append(user, fresh...), rest...)
+		}
+		var fresh []RuntimeFile
+		entries, _ := ioutil.ReadDir(dir)
+		for _, e := range entries {
+			if ok, _ := filepath.Match(t.pattern, e.Name()); ok && !e.IsDir() {
+				fresh = append(fresh, realFile(filepath.Join(dir, e.Name())))
+			}
+		}
+		allFiles[t.fileType] = reload(allFiles[t.fileType], fresh)
+		realFiles[t.fileType] = reload(realFiles[t.fileType], fresh)
+	}
+}
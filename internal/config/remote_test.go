@@ -0,0 +1,123 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-remote")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	oldData, oldGlobal := DataDir, GlobalSettings
+	defer func() { DataDir, GlobalSettings = oldData, oldGlobal }()
+	DataDir = dir
+
+	plugin := "VERSION = \"1.0.0\"\n"
+	files := map[string]string{
+		"/team.micro":    "color-link default \"red\"\n",
+		"/bundle.json":   `{"files": [{"url": "lang.yaml", "path": "syntax/lang.yaml"}, {"url": "/p.lua", "path": "plug/p/p.lua", "sha256": "` + sum([]byte(plugin)) + `"}]}`,
+		"/lang.yaml":     "filetype: lang\ndetect:\n    filename: \"\\\\.lang$\"\nrules: []\n",
+		"/p.lua":         plugin,
+		"/unpinned.json": `{"files": [{"url": "/p.lua", "path": "plug/q/q.lua"}]}`,
+	}
+	online := true
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !online || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+	oldClient := remoteClient
+	defer func() { remoteClient = oldClient }()
+	remoteClient = srv.Client()
+
+	fetch := func(update bool) (bool, error) {
+		if !FetchRuntime(update) {
+			return false, nil
+		}
+		r := <-RemoteResults
+		return r.Apply(), r.Err
+	}
+
+	scheme := srv.URL + "/team.micro"
+	GlobalSettings = map[string]interface{}{
+		"colorscheme": scheme,
+		"runtimeurls": []interface{}{srv.URL + "/bundle.json"},
+	}
+	plugins, err := fetch(false)
+	assert.True(t, plugins)
+	assert.NoError(t, err)
+	assert.Equal(t, "team", RemoteName(scheme))
+	assert.True(t, ColorschemeExists(scheme))
+	assert.NotNil(t, FindRuntimeFile(RTSyntax, "lang"))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "remote", "plug", "p", "p.lua"))
+	assert.NoError(t, err)
+	assert.Equal(t, files["/p.lua"], string(data))
+
+	// the plugins must be pinned
+	GlobalSettings["runtimeurls"] = []interface{}{srv.URL + "/bundle.json", srv.URL + "/unpinned.json"}
+	plugins, err = fetch(false)
+	assert.False(t, plugins)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "remote", "plug", "q", "q.lua"))
+	assert.True(t, os.IsNotExist(err))
+	GlobalSettings["runtimeurls"] = []interface{}{srv.URL + "/bundle.json"}
+
+	// offline, the cached copies are used
+	delete(files, "/bundle.json")
+	online = false
+	_, err = fetch(true)
+	assert.NoError(t, err)
+	assert.NotNil(t, FindRuntimeFile(RTSyntax, "lang"))
+
+	// a file which does not have its pinned checksum is not used
+	online = true
+	GlobalSettings["colorscheme"] = "default"
+	GlobalSettings["runtimeurls"] = []interface{}{scheme + "#sha256=" + sum([]byte("other"))}
+	_, err = fetch(false)
+	assert.Error(t, err)
+	assert.Nil(t, FindRuntimeFile(RTSyntax, "lang"))
+	GlobalSettings["runtimeurls"] = []interface{}{scheme + "#sha256=" + sum([]byte(files["/team.micro"]))}
+	_, err = fetch(false)
+	assert.NoError(t, err)
+	assert.NotNil(t, FindRuntimeFile(RTColorscheme, "team"))
+
+	// the files no longer given are removed
+	GlobalSettings["runtimeurls"] = []interface{}{}
+	_, err = fetch(false)
+	assert.NoError(t, err)
+	assert.Nil(t, FindRuntimeFile(RTColorscheme, "team"))
+	_, err = os.Stat(filepath.Join(dir, "remote", "colorschemes", "team.micro"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestValidateRuntimeURLs(t *testing.T) {
+	assert.NoError(t, validateRuntimeURLs("runtimeurls", []interface{}{"https://example.com/a.micro", "https://example.com/b.json#sha256=" + sum(nil)}))
+	assert.Error(t, validateRuntimeURLs("runtimeurls", []interface{}{"/tmp/a.micro"}))
+	assert.Error(t, validateRuntimeURLs("runtimeurls", []interface{}{"https://example.com/a.txt"}))
+	assert.Error(t, validateRuntimeURLs("runtimeurls", []interface{}{"https://example.com/a.micro#sha256=12"}))
+	assert.NoError(t, validateColorscheme("colorscheme", "https://example.com/a.micro"))
+	assert.Error(t, validateColorscheme("colorscheme", "https://example.com/a.yaml"))
+
+	assert.True(t, remoteBundlePath("syntax/a.yaml"))
+	assert.True(t, remoteBundlePath("plug/p/p.lua"))
+	assert.False(t, remoteBundlePath("syntax/a.micro"))
+	assert.False(t, remoteBundlePath("../a.micro"))
+	assert.False(t, remoteBundlePath("plug/p.lua"))
+	assert.False(t, remoteBundlePath("plug/../index.json"))
+	assert.False(t, remoteBundlePath("plug/p/.."))
+	assert.False(t, remoteBundlePath(`colorschemes/..\..\x.micro`))
+	assert.False(t, remoteBundlePath(`plug/p\..\..\x.lua`))
+}
@@ -3,12 +3,13 @@ package config
 import (
 	"errors"
 	"io/ioutil"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
 )
 
 const (
@@ -17,10 +18,13 @@ const (
 	RTHelp         = 2
 	RTPlugin       = 3
 	RTSyntaxHeader = 4
+	RTSnippet      = 5
+	RTAbbrev       = 6
+	RTLayout       = 7
 )
 
 var (
-	NumTypes = 5 // How many filetypes are there
+	NumTypes = 8 // How many filetypes are there
 )
 
 type RTFiletype int
@@ -160,6 +164,9 @@ func ListRealRuntimeFiles(fileType RTFiletype) []RuntimeFile {
 func InitRuntimeFiles() {
 	add := func(fileType RTFiletype, dir, pattern string) {
 		AddRuntimeFilesFromDirectory(fileType, filepath.Join(ConfigDir, dir), pattern)
+		if remote := RemoteDir(); remote != "" {
+			AddRuntimeFilesFromDirectory(fileType, filepath.Join(remote, dir), pattern)
+		}
 		AddRuntimeFilesFromAssets(fileType, path.Join("runtime", dir), pattern)
 	}
 
@@ -167,6 +174,10 @@ func InitRuntimeFiles() {
 	add(RTSyntax, "syntax", "*.yaml")
 	add(RTSyntaxHeader, "syntax", "*.hdr")
 	add(RTHelp, "help", "*.md")
+	add(RTSnippet, "snippets", "*.snippets")
+	add(RTSnippet, "snippets", "*.json")
+	add(RTAbbrev, "abbreviations", "*.json")
+	add(RTLayout, "layouts", "*.json")
 
 	initlua := filepath.Join(ConfigDir, "init.lua")
 	if _, err := os.Stat(initlua); !os.IsNotExist(err) {
@@ -177,12 +188,52 @@ func InitRuntimeFiles() {
 		Plugins = append(Plugins, p)
 	}
 
-	// Search ConfigDir for plugin-scripts
-	plugdir := filepath.Join(ConfigDir, "plug")
-	files, _ := ioutil.ReadDir(plugdir)
+	// Search ConfigDir for plugin-scripts, then the plugins fetched from URLs
+	Plugins = append(Plugins, findPlugins(filepath.Join(ConfigDir, "plug"))...)
+	if dir := RemoteDir(); dir != "" {
+		Plugins = append(Plugins, findPlugins(filepath.Join(dir, "plug"))...)
+	}
+
+	plugdir := filepath.Join("runtime", "plugins")
+	if files, err := AssetDir(plugdir); err == nil {
+		for _, d := range files {
+			if srcs, err := AssetDir(filepath.Join(plugdir, d)); err == nil {
+				p := new(Plugin)
+				p.Name = d
+				p.DirName = d
+				p.Default = true
+				for _, f := range srcs {
+					if strings.HasSuffix(f, ".lua") {
+						p.Srcs = append(p.Srcs, assetFile(filepath.Join(plugdir, d, f)))
+					} else if strings.HasSuffix(f, ".json") {
+						data, err := Asset(filepath.Join(plugdir, d, f))
+						if err != nil {
+							continue
+						}
+						p.Info, err = NewPluginInfo(data)
+						if err != nil {
+							continue
+						}
+						p.Name = p.Info.Name
+					}
+				}
+				if !isID(p.Name) || len(p.Srcs) <= 0 {
+					util.Log(util.LogWarn, "config", p.Name, "is not a plugin")
+					continue
+				}
+				Plugins = append(Plugins, p)
+			}
+		}
+	}
+}
 
-	isID := regexp.MustCompile(`^[_A-Za-z0-9]+$`).MatchString
+// isID returns whether a plugin name is a valid Lua identifier
+var isID = regexp.MustCompile(`^[_A-Za-z0-9]+$`).MatchString
 
+// findPlugins returns the plugins of the plugin directories in plugdir
+func findPlugins(plugdir string) []*Plugin {
+	var plugins []*Plugin
+	files, _ := ioutil.ReadDir(plugdir)
 	for _, d := range files {
 		if d.IsDir() {
 			srcs, _ := ioutil.ReadDir(filepath.Join(plugdir, d.Name()))
@@ -206,44 +257,39 @@ func InitRuntimeFiles() {
 			}
 
 			if !isID(p.Name) || len(p.Srcs) <= 0 {
-				log.Println(p.Name, "is not a plugin")
+				util.Log(util.LogWarn, "config", p.Name, "is not a plugin")
 				continue
 			}
-			Plugins = append(Plugins, p)
+			plugins = append(plugins, p)
 		}
 	}
+	return plugins
+}
 
-	plugdir = filepath.Join("runtime", "plugins")
-	if files, err := AssetDir(plugdir); err == nil {
-		for _, d := range files {
-			if srcs, err := AssetDir(filepath.Join(plugdir, d)); err == nil {
-				p := new(Plugin)
-				p.Name = d
-				p.DirName = d
-				p.Default = true
-				for _, f := range srcs {
-					if strings.HasSuffix(f, ".lua") {
-						p.Srcs = append(p.Srcs, assetFile(filepath.Join(plugdir, d, f)))
-					} else if strings.HasSuffix(f, ".json") {
-						data, err := Asset(filepath.Join(plugdir, d, f))
-						if err != nil {
-							continue
-						}
-						p.Info, err = NewPluginInfo(data)
-						if err != nil {
-							continue
-						}
-						p.Name = p.Info.Name
-					}
-				}
-				if !isID(p.Name) || len(p.Srcs) <= 0 {
-					log.Println(p.Name, "is not a plugin")
-					continue
-				}
-				Plugins = append(Plugins, p)
+// ReloadSyntaxFiles rereads the list of syntax files of the config
+// directory, so that the files added or removed since micro started are
+// taken into account. The user's files still come before the default ones.
+func ReloadSyntaxFiles() {
+	dir := filepath.Join(ConfigDir, "syntax")
+	// others returns the files which aren't in the syntax directory
+	others := func(files []RuntimeFile) []RuntimeFile {
+		var res []RuntimeFile
+		for _, f := range files {
+			if rf, ok := f.(realFile); !ok || filepath.Dir(string(rf)) != dir {
+				res = append(res, f)
 			}
 		}
+		return res
+	}
+	reload := func(fileType RTFiletype, pattern string) {
+		all, real := others(allFiles[fileType]), others(realFiles[fileType])
+		allFiles[fileType], realFiles[fileType] = nil, nil
+		AddRuntimeFilesFromDirectory(fileType, dir, pattern)
+		allFiles[fileType] = append(allFiles[fileType], all...)
+		realFiles[fileType] = append(realFiles[fileType], real...)
 	}
+	reload(RTSyntax, "*.yaml")
+	reload(RTSyntaxHeader, "*.hdr")
 }
 
 // PluginReadRuntimeFile allows plugin scripts to read the content of a runtime file
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/pkg/highlight"
 )
 
 func init() {
@@ -40,3 +44,90 @@ func TestFindFile(t *testing.T) {
 	e := FindRuntimeFile(RTSyntax, "foobar")
 	assert.Nil(t, e)
 }
+
+func TestReloadSyntaxFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	oldConfigDir := ConfigDir
+	ConfigDir = dir
+	defer func() { ConfigDir = oldConfigDir }()
+
+	syntaxDir := filepath.Join(dir, "syntax")
+	assert.Nil(t, os.Mkdir(syntaxDir, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(syntaxDir, "go.yaml"), []byte("filetype: go\n"), 0644))
+	plugin := memoryFile{"plugin", []byte("filetype: plugin\n")}
+	AddRealRuntimeFile(RTSyntax, plugin)
+
+	ReloadSyntaxFiles()
+	files := ListRuntimeFiles(RTSyntax)
+	assert.Equal(t, realFile(filepath.Join(syntaxDir, "go.yaml")), files[0])
+	f := FindRuntimeFile(RTSyntax, "go")
+	data, err := f.Data()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("filetype: go\n"), data)
+	assert.NotNil(t, FindRuntimeFile(RTSyntax, "plugin"))
+	assert.Equal(t, 2, len(ListRealRuntimeFiles(RTSyntax)))
+
+	// removed files are forgotten and the builtin file is used again
+	assert.Nil(t, os.Remove(filepath.Join(syntaxDir, "go.yaml")))
+	ReloadSyntaxFiles()
+	f = FindRuntimeFile(RTSyntax, "go")
+	data, err = f.Data()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("filetype: go"), data[:12])
+	assert.NotEqual(t, []byte("filetype: go\n"), data)
+	assert.Equal(t, 1, len(ListRealRuntimeFiles(RTSyntax)))
+}
+
+func TestEmbeddedRuntime(t *testing.T) {
+	// runtime.go must be regenerated with make runtime whenever a file of
+	// the runtime directory changes
+	root := filepath.Join("..", "..")
+	err := filepath.Walk(filepath.Join(root, "runtime"), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) == ".hdr" {
+			return err
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		embedded, err := Asset(filepath.ToSlash(name))
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, string(data), string(embedded), name)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+// assertDetects asserts that the builtin header of the filetype ft detects
+// the file name
+func assertDetects(t *testing.T, ft, name string) {
+	f := FindRuntimeFile(RTSyntaxHeader, ft)
+	if !assert.NotNil(t, f, ft) {
+		return
+	}
+	data, err := f.Data()
+	assert.Nil(t, err)
+	header, err := highlight.MakeHeader(data)
+	if assert.Nil(t, err) {
+		assert.Equal(t, ft, header.FileType)
+		assert.True(t, header.FtDetect[0].MatchString(name), name)
+	}
+}
+
+func TestDetectHTTP(t *testing.T) {
+	assertDetects(t, "http", "api.http")
+	assertDetects(t, "http", "api.rest")
+}
+
+func TestDetectGoTemplates(t *testing.T) {
+	assertDetects(t, "gohtml", "index.gohtml")
+	assertDetects(t, "gohtml", "page.tmpl")
+	assert.NotNil(t, FindRuntimeFile(RTSyntax, "html"))
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+)
+
+// The secrets are the tokens and the passwords used by the integrations and
+// the plugins. They are kept in the secrets file encrypted with a key
+// derived from a master passphrase, which is asked once per session, or
+// given by the command of the secretcmd option, such as a password manager.
+
+// ErrSecretsLocked is returned when a secret of the secrets file is needed
+// before the master passphrase is given
+var ErrSecretsLocked = errors.New("The secrets are locked, unlock them with 'secret unlock'")
+
+// secretsCheck is encrypted in the secrets file to check the passphrase
+const secretsCheck = "micro secrets"
+
+// keyIterations is the number of iterations of PBKDF2 deriving the key
+const keyIterations = 200000
+
+// secretsFile is the content of the secrets file. The names of the secrets
+// are not encrypted, their values are, with AES-GCM.
+type secretsFile struct {
+	Salt    []byte            `json:"salt"`
+	Check   []byte            `json:"check"`
+	Secrets map[string][]byte `json:"secrets"`
+}
+
+var (
+	// secretKey is the key of the secrets file while it is unlocked
+	secretKey []byte
+	// commandSecrets caches the secrets given by secretcmd
+	commandSecrets = make(map[string]string)
+)
+
+// SecretsPath returns the path of the secrets file
+func SecretsPath() string {
+	return filepath.Join(DataDir, "secrets.json")
+}
+
+// SecretsExist returns whether there is a secrets file, and so a master
+// passphrase
+func SecretsExist() bool {
+	_, err := os.Stat(SecretsPath())
+	return err == nil
+}
+
+// SecretsUnlocked returns whether the master passphrase was given
+func SecretsUnlocked() bool {
+	return secretKey != nil
+}
+
+func readSecrets() (*secretsFile, error) {
+	data, err := ioutil.ReadFile(SecretsPath())
+	if err != nil {
+		return nil, err
+	}
+	f := new(secretsFile)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, errors.New("Error reading " + SecretsPath() + ": " + err.Error())
+	}
+	if f.Secrets == nil {
+		f.Secrets = make(map[string][]byte)
+	}
+	return f, nil
+}
+
+func writeSecrets(f *secretsFile) error {
+	data, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(DataDir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(SecretsPath(), append(data, '\n'), 0600)
+}
+
+// UnlockSecrets derives the key of the secrets file from the master
+// passphrase and checks it. The secrets file is created with the passphrase
+// if there is none.
+func UnlockSecrets(passphrase string) error {
+	f, err := readSecrets()
+	if os.IsNotExist(err) {
+		f = &secretsFile{Salt: make([]byte, 16), Secrets: make(map[string][]byte)}
+		if _, err := rand.Read(f.Salt); err != nil {
+			return err
+		}
+		key := deriveKey(passphrase, f.Salt)
+		if f.Check, err = encryptSecret(key, "", secretsCheck); err != nil {
+			return err
+		}
+		if err := writeSecrets(f); err != nil {
+			return err
+		}
+		secretKey = key
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	key := deriveKey(passphrase, f.Salt)
+	if check, err := decryptSecret(key, "", f.Check); err != nil || check != secretsCheck {
+		return errors.New("Wrong passphrase")
+	}
+	secretKey = key
+	return nil
+}
+
+// LockSecrets forgets the key of the secrets file, and the secrets given by
+// secretcmd
+func LockSecrets() {
+	secretKey = nil
+	commandSecrets = make(map[string]string)
+}
+
+// SetSecret encrypts the value of the secret name in the secrets file
+func SetSecret(name, value string) error {
+	if !SecretsUnlocked() {
+		return ErrSecretsLocked
+	}
+	f, err := readSecrets()
+	if err != nil {
+		return err
+	}
+	if f.Secrets[name], err = encryptSecret(secretKey, name, value); err != nil {
+		return err
+	}
+	return writeSecrets(f)
+}
+
+// RemoveSecret removes the secret name from the secrets file
+func RemoveSecret(name string) error {
+	f, err := readSecrets()
+	if os.IsNotExist(err) {
+		return errors.New("No secret named " + name)
+	} else if err != nil {
+		return err
+	}
+	if _, ok := f.Secrets[name]; !ok {
+		return errors.New("No secret named " + name)
+	}
+	delete(f.Secrets, name)
+	return writeSecrets(f)
+}
+
+// SecretNames returns the names of the secrets of the secrets file, sorted
+func SecretNames() []string {
+	f, err := readSecrets()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for name := range f.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetSecret returns the value of the secret name, from the secrets file if
+// it has it, or else from the command of the secretcmd option in which %s
+// is replaced by the name
+func GetSecret(name string) (string, error) {
+	if f, err := readSecrets(); err == nil {
+		if data, ok := f.Secrets[name]; ok {
+			if !SecretsUnlocked() {
+				return "", ErrSecretsLocked
+			}
+			return decryptSecret(secretKey, name, data)
+		}
+	}
+	if v, ok := commandSecrets[name]; ok {
+		return v, nil
+	}
+	secretcmd, _ := GlobalSettings["secretcmd"].(string)
+	if secretcmd == "" {
+		return "", errors.New("No secret named " + name)
+	}
+	args, err := shellquote.Split(secretcmd)
+	if err != nil {
+		return "", err
+	}
+	for i := range args {
+		args[i] = strings.Replace(args[i], "%s", name, -1)
+	}
+	var stderr bytes.Buffer
+	c := exec.Command(args[0], args[1:]...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New("Error getting the secret " + name + ": " + msg)
+		}
+		return "", errors.New("Error getting the secret " + name + ": " + err.Error())
+	}
+	// the first line, as pass prints the password first
+	v := strings.TrimRight(strings.SplitN(string(out), "\n", 2)[0], "\r")
+	commandSecrets[name] = v
+	return v, nil
+}
+
+var secretRefRegex = regexp.MustCompile(`\{\{\s*\$secret\s+([^\s}]+)\s*\}\}`)
+
+// ExpandSecrets replaces the references to the secrets in s, written as
+// {{$secret name}}, by their values
+func ExpandSecrets(s string) (string, error) {
+	var err error
+	s = secretRefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		v, e := GetSecret(secretRefRegex.FindStringSubmatch(ref)[1])
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			return ref
+		}
+		return v
+	})
+	return s, err
+}
+
+// PluginGetSecret returns the value of the secret plugin.name. The
+// micro/config package imported by a plugin has it bound to the name of the
+// plugin, so that it reads its own secrets. The plugins share the Lua state,
+// so this doesn't keep a malicious plugin from reaching the others' secrets.
+func PluginGetSecret(plugin, name string) (string, error) {
+	if plugin == "" || strings.Contains(plugin, ".") {
+		return "", errors.New("Invalid plugin name " + plugin)
+	}
+	return GetSecret(plugin + "." + name)
+}
+
+// deriveKey derives the key of the secrets file from the passphrase with
+// PBKDF2-HMAC-SHA256
+func deriveKey(passphrase string, salt []byte) []byte {
+	return pbkdf2([]byte(passphrase), salt, keyIterations, 32)
+}
+
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	n := prf.Size()
+	key := make([]byte, 0, (keyLen+n-1)/n*n)
+	u := make([]byte, n)
+	var index [4]byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(index[:], block)
+		prf.Write(index[:])
+		key = prf.Sum(key)
+		t := key[len(key)-n:]
+		copy(u, t)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return key[:keyLen]
+}
+
+// encryptSecret encrypts the value of the secret name, which is
+// authenticated with it so that the values can't be swapped
+func encryptSecret(key []byte, name, value string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(value), []byte(name)), nil
+}
+
+func decryptSecret(key []byte, name string, data []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("The secret " + name + " is corrupted")
+	}
+	value, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", errors.New("The secret " + name + " can't be decrypted")
+	}
+	return string(value), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
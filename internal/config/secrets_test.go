@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// the test vectors of RFC 7914
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64)))
+	assert.Equal(t, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b",
+		hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), 1, 32)))
+	assert.Equal(t, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43",
+		hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), 2, 32)))
+}
+
+func TestSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-secrets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func(d string) { DataDir = d }(DataDir)
+	DataDir = dir
+	defer LockSecrets()
+	if GlobalSettings == nil {
+		GlobalSettings = make(map[string]interface{})
+	}
+	defer func(v interface{}) { GlobalSettings["secretcmd"] = v }(GlobalSettings["secretcmd"])
+	GlobalSettings["secretcmd"] = ""
+
+	assert.False(t, SecretsExist())
+	assert.Equal(t, ErrSecretsLocked, SetSecret("token", "x"))
+	assert.NoError(t, UnlockSecrets("master"))
+	assert.True(t, SecretsExist())
+	assert.NoError(t, SetSecret("token", "s3cret"))
+	assert.NoError(t, SetSecret("gist.token", "abc"))
+	assert.Equal(t, []string{"gist.token", "token"}, SecretNames())
+
+	data, err := ioutil.ReadFile(SecretsPath())
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(data), "s3cret"))
+	info, err := os.Stat(SecretsPath())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	v, err := GetSecret("token")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", v)
+	v, err = ExpandSecrets("Bearer {{$secret token}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer s3cret", v)
+	v, err = PluginGetSecret("gist", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", v)
+	_, err = PluginGetSecret("gist.token", "")
+	assert.Error(t, err)
+
+	LockSecrets()
+	_, err = GetSecret("token")
+	assert.Equal(t, ErrSecretsLocked, err)
+	assert.EqualError(t, UnlockSecrets("wrong"), "Wrong passphrase")
+	assert.NoError(t, UnlockSecrets("master"))
+	assert.NoError(t, RemoveSecret("token"))
+	_, err = GetSecret("token")
+	assert.EqualError(t, err, "No secret named token")
+
+	GlobalSettings["secretcmd"] = "echo 'from %s'"
+	v, err = GetSecret("db")
+	assert.NoError(t, err)
+	assert.Equal(t, "from db", v)
+}
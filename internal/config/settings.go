@@ -8,11 +8,13 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/zyedidia/glob"
 	"github.com/zyedidia/json5"
+	"github.com/zyedidia/micro/v2/internal/quickfix"
 	"github.com/zyedidia/micro/v2/internal/util"
 	"golang.org/x/text/encoding/htmlindex"
 )
@@ -42,15 +44,58 @@ func init() {
 
 // Options with validators
 var optionValidators = map[string]optionValidator{
-	"autosave":     validateNonNegativeValue,
-	"clipboard":    validateClipboard,
-	"tabsize":      validatePositiveValue,
-	"scrollmargin": validateNonNegativeValue,
-	"scrollspeed":  validateNonNegativeValue,
-	"colorscheme":  validateColorscheme,
-	"colorcolumn":  validateNonNegativeValue,
-	"fileformat":   validateLineEnding,
-	"encoding":     validateEncoding,
+	"ambiguouswidth":    validateAmbiguousWidth,
+	"autoclosepairs":    validatePairs,
+	"autocompletechars": validatePositiveValue,
+	"autosave":          validateNonNegativeValue,
+	"hugefile":          validateNonNegativeValue,
+	"clipboard":         validateClipboard,
+	"clipboardsync":     validateRegisterName,
+	"osc52maxsize":      validatePositiveValue,
+	"tabsize":           validatePositiveValue,
+	"modelines":         validateNonNegativeValue,
+	"textwidth":         validatePositiveValue,
+	"tabmaxwidth":       validateNonNegativeValue,
+	"tabpath":           validateTabPath,
+	"termdir":           validateTermDir,
+	"taskoutput":        validateTaskOutput,
+	"dbformat":          validateDBFormat,
+	"imageprotocol":     validateImageProtocol,
+	"fuzzymatcher":      validateFuzzyMatcher,
+	"confirm":           validateConfirm,
+	"confirmfiles":      validatePositiveValue,
+	"fuzzycase":         validateFuzzyCase,
+	"fuzzyrecency":      validateNonNegativeValue,
+	"fuzzydepth":        validateNonNegativeValue,
+	"scrollback":        validateNonNegativeValue,
+	"scrolloff":         validateNonNegativeValue,
+	"sidescrolloff":     validateNonNegativeValue,
+	"smoothscroll":      validateNonNegativeValue,
+	"scrollspeed":       validateNonNegativeValue,
+	"colorscheme":       validateColorscheme,
+	"runtimeurls":       validateRuntimeURLs,
+	"colorcolumn":       validateColorColumn,
+	"colorswatch":       validateColorSwatch,
+	"dedentpattern":     validateRegexp,
+	"fileformat":        validateLineEnding,
+	"historylength":     validatePositiveValue,
+	"incrementstep":     validatePositiveValue,
+	"encoding":          validateEncoding,
+	"errorformat":       validateErrorFormat,
+	"divchars":          validateDivChars,
+	"indentchar":        validateIndentChar,
+	"indentguidechar":   validateIndentChar,
+	"indentpattern":     validateRegexp,
+	"keytimeout":        validateNonNegativeValue,
+	"regexengine":       validateRegexEngine,
+	"undogroup":         validateUndoGroup,
+	"diffbase":          validateDiffBase,
+	"keyprofile":        validateKeyProfile,
+	"foldmethod":        validateFoldMethod,
+	"undotimeout":       validateNonNegativeValue,
+	"localhistory":      validateNonNegativeValue,
+	"loglevel":          validateLogLevel,
+	"logfilter":         validateLogFilter,
 }
 
 func ReadSettings() error {
@@ -80,77 +125,235 @@ func ReadSettings() error {
 					}
 				}
 			}
+
+			// scrollmargin was renamed to scrolloff
+			renameSetting(parsedSettings, "scrollmargin", "scrolloff")
+			for _, v := range parsedSettings {
+				if m, ok := v.(map[string]interface{}); ok {
+					renameSetting(m, "scrollmargin", "scrolloff")
+				}
+			}
 		}
 	}
 	return nil
 }
 
+// renameSetting moves the value of a renamed option to its new name, unless
+// the new name is also set
+func renameSetting(settings map[string]interface{}, old, new string) {
+	if v, ok := settings[old]; ok {
+		if _, ok := settings[new]; !ok {
+			settings[new] = v
+		}
+		delete(settings, old)
+	}
+}
+
 func verifySetting(option string, value reflect.Type, def reflect.Type) bool {
 	var interfaceArr []interface{}
 	switch option {
-	case "pluginrepos", "pluginchannels":
+	case "pluginrepos", "pluginchannels", "runtimeurls":
 		return value.AssignableTo(reflect.TypeOf(interfaceArr))
+	case "colorcolumn":
+		// a single column may also be given as a number
+		return value.Kind() == reflect.String || value.Kind() == reflect.Float64
 	default:
 		return def.AssignableTo(value)
 	}
 }
 
+// ValidateSetting checks that a value read from settings.json has the same
+// type as the option's default value and passes the option's validator.
+// The returned error describes the problem and the default that will be
+// used instead
+func ValidateSetting(option string, value interface{}, def interface{}) error {
+	if value == nil || !verifySetting(option, reflect.TypeOf(value), reflect.TypeOf(def)) {
+		return fmt.Errorf("setting '%s' has incorrect type (%s), using default value: %v (%s)", option, typeName(value), def, typeName(def))
+	}
+	if err := OptionIsValid(option, value); err != nil {
+		return fmt.Errorf("setting '%s' has invalid value %v (%s), using default value: %v", option, value, err, def)
+	}
+	return nil
+}
+
+// typeName returns a user friendly name for the type of a setting value
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}, []string:
+		return "list"
+	case nil:
+		return "null"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// joinErrors combines a list of error messages into a single error
+// so that every problem with settings.json is reported at once
+func joinErrors(prefix string, errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(prefix + strings.Join(errs, "\n"+prefix))
+}
+
 // InitGlobalSettings initializes the options map and sets all options to their default values
 // Must be called after ReadSettings
 func InitGlobalSettings() error {
-	var err error
+	var errs []string
 	GlobalSettings = DefaultGlobalSettings()
 
 	for k, v := range parsedSettings {
-		if !strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
-			if _, ok := GlobalSettings[k]; ok && !verifySetting(k, reflect.TypeOf(v), reflect.TypeOf(GlobalSettings[k])) {
-				err = fmt.Errorf("Global Error: setting '%s' has incorrect type (%s), using default value: %v (%s)", k, reflect.TypeOf(v), GlobalSettings[k], reflect.TypeOf(GlobalSettings[k]))
-				continue
+		if v == nil || !strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
+			if def, ok := GlobalSettings[k]; ok {
+				if err := ValidateSetting(k, v, def); err != nil {
+					errs = append(errs, err.Error())
+					continue
+				}
 			}
 
 			GlobalSettings[k] = v
 		}
 	}
-	return err
+	return joinErrors("Global Error: ", errs)
+}
+
+// applyLocalSettings copies the options in local into settings, skipping
+// (and reporting) any value that fails validation
+func applyLocalSettings(settings map[string]interface{}, local map[string]interface{}, errs *[]string) {
+	for k, v := range local {
+		if def, ok := settings[k]; ok {
+			if err := ValidateSetting(k, v, def); err != nil {
+				*errs = append(*errs, err.Error())
+				continue
+			}
+		}
+		settings[k] = v
+	}
 }
 
 // InitLocalSettings scans the json in settings.json and sets the options locally based
 // on whether the filetype or path matches ft or glob local settings
 // Must be called after ReadSettings
 func InitLocalSettings(settings map[string]interface{}, path string) error {
-	var parseError error
+	var errs []string
 	for k, v := range parsedSettings {
-		if strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
+		if v != nil && strings.HasPrefix(reflect.TypeOf(v).String(), "map") {
 			if strings.HasPrefix(k, "ft:") {
 				if settings["filetype"].(string) == k[3:] {
-					for k1, v1 := range v.(map[string]interface{}) {
-						if _, ok := settings[k1]; ok && !verifySetting(k1, reflect.TypeOf(v1), reflect.TypeOf(settings[k1])) {
-							parseError = fmt.Errorf("Error: setting '%s' has incorrect type (%s), using default value: %v (%s)", k, reflect.TypeOf(v1), settings[k1], reflect.TypeOf(settings[k1]))
-							continue
-						}
-						settings[k1] = v1
-					}
+					applyLocalSettings(settings, v.(map[string]interface{}), &errs)
 				}
 			} else {
 				g, err := glob.Compile(k)
 				if err != nil {
-					parseError = errors.New("Error with glob setting " + k + ": " + err.Error())
+					errs = append(errs, "Error with glob setting "+k+": "+err.Error())
 					continue
 				}
 
 				if g.MatchString(path) {
-					for k1, v1 := range v.(map[string]interface{}) {
-						if _, ok := settings[k1]; ok && !verifySetting(k1, reflect.TypeOf(v1), reflect.TypeOf(settings[k1])) {
-							parseError = fmt.Errorf("Error: setting '%s' has incorrect type (%s), using default value: %v (%s)", k, reflect.TypeOf(v1), settings[k1], reflect.TypeOf(settings[k1]))
-							continue
-						}
-						settings[k1] = v1
-					}
+					applyLocalSettings(settings, v.(map[string]interface{}), &errs)
 				}
 			}
 		}
 	}
-	return parseError
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			if root, ok := util.FindProjectRoot(filepath.Dir(abs)); ok {
+				applyProjectSettings(settings, root, abs, &errs)
+			}
+		}
+	}
+	return joinErrors("Error: ", errs)
+}
+
+// projectOptions are the options which the settings file of a project may
+// set, which change how the text is shown and indented, and the directory
+// terminals start in. The others are ignored so that opening a file of a
+// cloned repository cannot run commands or write files elsewhere.
+var projectOptions = map[string]bool{
+	"autoindent":      true,
+	"breakindent":     true,
+	"colorcolumn":     true,
+	"commenttype":     true,
+	"dedentpattern":   true,
+	"detectindent":    true,
+	"eofnewline":      true,
+	"filetype":        true,
+	"indentchar":      true,
+	"indentguidechar": true,
+	"indentguides":    true,
+	"indentpattern":   true,
+	"keepautoindent":  true,
+	"rmtrailingws":    true,
+	"showbreak":       true,
+	"softwrap":        true,
+	"spell":           true,
+	"spelllang":       true,
+	"syntax":          true,
+	"tabsize":         true,
+	"tabstospaces":    true,
+	"termdir":         true,
+	"textwidth":       true,
+	"wordwrap":        true,
+}
+
+// applyProjectSettings sets the options of the project settings file found
+// at the root of the project of the file at path. The file has the same
+// format as settings.json: the options at its top level apply to all files
+// of the project, and the ft: and glob sections to the files of a filetype
+// or whose path relative to the root matches the glob. Only the options of
+// projectOptions are used.
+func applyProjectSettings(settings map[string]interface{}, root, path string, errs *[]string) {
+	input, err := ioutil.ReadFile(filepath.Join(root, util.ProjectSettingsFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			*errs = append(*errs, "Error reading project settings: "+err.Error())
+		}
+		return
+	}
+	var parsed map[string]interface{}
+	if err := json5.Unmarshal(input, &parsed); err != nil {
+		*errs = append(*errs, "Error reading "+filepath.Join(root, util.ProjectSettingsFile)+": "+err.Error())
+		return
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	local := make(map[string]interface{})
+	for k, v := range parsed {
+		if m, ok := v.(map[string]interface{}); ok {
+			if strings.HasPrefix(k, "ft:") {
+				if settings["filetype"].(string) != k[3:] {
+					continue
+				}
+			} else if g, err := glob.Compile(k); err != nil {
+				*errs = append(*errs, "Error with glob setting "+k+": "+err.Error())
+				continue
+			} else if !g.MatchString(rel) {
+				continue
+			}
+			for mk, mv := range m {
+				local[mk] = mv
+			}
+		} else if _, ok := local[k]; !ok {
+			local[k] = v
+		}
+	}
+	for k := range local {
+		if !projectOptions[k] {
+			delete(local, k)
+		}
+	}
+	applyLocalSettings(settings, local, errs)
 }
 
 // WriteSettings writes the settings to the specified filename as JSON
@@ -256,48 +459,104 @@ func GetGlobalOption(name string) interface{} {
 }
 
 var defaultCommonSettings = map[string]interface{}{
-	"autoindent":     true,
-	"autosu":         false,
-	"backup":         true,
-	"backupdir":      "",
-	"basename":       false,
-	"colorcolumn":    float64(0),
-	"cursorline":     true,
-	"diffgutter":     false,
-	"encoding":       "utf-8",
-	"eofnewline":     true,
-	"fastdirty":      false,
-	"fileformat":     "unix",
-	"filetype":       "unknown",
-	"incsearch":      true,
-	"ignorecase":     true,
-	"indentchar":     " ",
-	"keepautoindent": false,
-	"matchbrace":     true,
-	"mkparents":      false,
-	"permbackup":     false,
-	"readonly":       false,
-	"rmtrailingws":   false,
-	"ruler":          true,
-	"relativeruler":  false,
-	"savecursor":     false,
-	"saveundo":       false,
-	"scrollbar":      false,
-	"scrollmargin":   float64(3),
-	"scrollspeed":    float64(2),
-	"smartpaste":     true,
-	"softwrap":       false,
-	"splitbottom":    true,
-	"splitright":     true,
-	"statusformatl":  "$(filename) $(modified)($(line),$(col)) $(status.paste)| ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)",
-	"statusformatr":  "$(bind:ToggleKeyMenu): bindings, $(bind:ToggleHelp): help",
-	"statusline":     true,
-	"syntax":         true,
-	"tabmovement":    false,
-	"tabsize":        float64(4),
-	"tabstospaces":   false,
-	"useprimary":     true,
-	"wordwrap":       false,
+	"abbreviations":     true,
+	"autoclose":         true,
+	"autoclosepairs":    "()[]{}\"\"''``",
+	"autocomplete":      false,
+	"autocompletechars": float64(3),
+	"autoindent":        true,
+	"autosu":            false,
+	"backup":            true,
+	"backupdir":         "",
+	"basename":          false,
+	"breakindent":       false,
+	"buildcmd":          "make",
+	"colorcolumn":       float64(0),
+	"colorswatch":       "auto",
+	"commenttype":       "",
+	"cursorcolumn":      false,
+	"cursorline":        true,
+	"dbcmd":             "",
+	"dbformat":          "csv",
+	"dedentpattern":     "",
+	"detectindent":      true,
+	"diffbase":          "auto",
+	"diffgutter":        false,
+	"drafts":            false,
+	"encoding":          "utf-8",
+	"eofnewline":        true,
+	"errorformat":       `%f:%l:%c: %m,%f:%l: %m,%f(%l\,%c): %m,%f(%l): %m`,
+	"fastdirty":         false,
+	"fileformat":        "unix",
+	"filelock":          false,
+	"filetype":          "unknown",
+	"foldmethod":        "auto",
+	"follow":            false,
+	"hex":               false,
+	"imageview":         true,
+	"hlsearch":          true,
+	"incsearch":         true,
+	"ignorecase":        true,
+	"incrementstep":     float64(1),
+	"indentchar":        " ",
+	"indentguides":      false,
+	"indentguidechar":   "│",
+	"indentpattern":     "",
+	"includepath":       "",
+	"keepautoindent":    false,
+	"matchbrace":        true,
+	"mkparents":         false,
+	"modeline":          true,
+	"modelines":         float64(5),
+	"pathcomplete":      true,
+	"permbackup":        false,
+	"readonly":          false,
+	"regexengine":       "go",
+	"rmtrailingws":      false,
+	"ruler":             true,
+	"relativeruler":     false,
+	"replcmd":           "",
+	"runcmd":            "",
+	"savecheck":         "",
+	"savecursor":        false,
+	"saveundo":          false,
+	"scrollbar":         false,
+	"scrollbind":        false,
+	"scrollbarmarks":    true,
+	"scrolloff":         float64(3),
+	"sidescrolloff":     float64(0),
+	"smoothscroll":      float64(0),
+	"scrollspeed":       float64(2),
+	"showbreak":         "",
+	"smartend":          false,
+	"smarthome":         true,
+	"smartpaste":        true,
+	"softwrap":          false,
+	"spell":             false,
+	"spelllang":         "en_US",
+	"splitbottom":       true,
+	"splitright":        true,
+	"statusformatl":     "$(mode)$(filename) $(modified)($(line),$(col)) $(status.paste)| ft:$(opt:filetype) | $(opt:fileformat) | $(opt:encoding)",
+	"statusformatr":     "$(search)$(keys)$(bind:ToggleKeyMenu): bindings, $(bind:ToggleHelp): help",
+	"statusline":        true,
+	"syntax":            true,
+	"tabmovement":       false,
+	"tabsize":           float64(4),
+	"tabstospaces":      false,
+	"tagsonsave":        false,
+	"taskoutput":        "pane",
+	"termdir":           "",
+	"termenv":           "",
+	"termshell":         "",
+	"testcmd":           "",
+	"textwidth":         float64(80),
+	"undogroup":         "time",
+	"undotimeout":       float64(1000),
+	"useprimary":        true,
+	"viewmode":          false,
+	"virtualedit":       false,
+	"wordlists":         "",
+	"wordwrap":          false,
 }
 
 func GetInfoBarOffset() int {
@@ -324,23 +583,74 @@ func DefaultCommonSettings() map[string]interface{} {
 // a list of settings that should only be globally modified and their
 // default values
 var DefaultGlobalOnlySettings = map[string]interface{}{
+	"ambiguouswidth": "auto",
 	"autosave":       float64(0),
+	"autosession":    false,
 	"clipboard":      "external",
+	"clipboardsync":  "\"",
 	"colorscheme":    "default",
+	"confirm":        "quit,overwrite,replace,closeterm,delete",
+	"confirmfiles":   float64(1),
 	"divchars":       "|-",
 	"divreverse":     true,
+	"fuzzycase":      "ignore",
+	"fuzzydepth":     float64(0),
+	"fuzzymatcher":   "subsequence",
+	"fuzzyrecency":   float64(1),
+	"historylength":  float64(100),
+	"hugefile":       float64(100),
+	"imageprotocol":  "auto",
 	"infobar":        true,
 	"keymenu":        false,
+	"kittykeys":      true,
+	"keyprofile":     "default",
+	"keytimeout":     float64(1000),
+	"leader":         "\\",
+	"localhistory":   float64(0),
+	"logfilter":      "",
+	"loglevel":       "warn",
+	"modal":          false,
 	"mouse":          true,
+	"osc52maxsize":   float64(65536),
+	"osc7":           false,
 	"parsecursor":    false,
 	"paste":          false,
+	"remotecontrol":  false,
+	"replacesave":    false,
 	"savehistory":    true,
+	"sessionreplay":  false,
+	"scrollback":     float64(1000),
+	"screenreader":   false,
+	"secretcmd":      "",
+	"speechcmd":      "",
 	"sucmd":          "sudo",
+	"tabclose":       false,
+	"tabformat":      "$(filename)$(modified)",
+	"tabmaxwidth":    float64(0),
+	"tabpath":        "full",
+	"tagscommand":    "ctags -R",
+	"termtitle":      false,
+	"titleformat":    "$(filename)$(modified) - $(project)",
 	"pluginchannels": []string{"https://raw.githubusercontent.com/micro-editor/plugin-channel/master/channel.json"},
 	"pluginrepos":    []string{},
+	"runtimeurls":    []string{},
 	"xterm":          false,
 }
 
+// PaneSettings are the display options which can be set for a pane only,
+// with the setpane command, overriding the ones of its buffer
+var PaneSettings = []string{
+	"colorcolumn",
+	"cursorline",
+	"diffgutter",
+	"relativeruler",
+	"ruler",
+	"scrollbar",
+	"scrollbind",
+	"softwrap",
+	"wordwrap",
+}
+
 // a list of settings that should never be globally modified
 var LocalSettings = []string{
 	"filetype",
@@ -377,10 +687,14 @@ func DefaultAllSettings() map[string]interface{} {
 func GetNativeValue(option string, realValue interface{}, value string) (interface{}, error) {
 	var native interface{}
 	kind := reflect.TypeOf(realValue).Kind()
+	if option == "colorcolumn" {
+		// the value may be a number if it was set in settings.json
+		kind = reflect.String
+	}
 	if kind == reflect.Bool {
 		b, err := util.ParseBool(value)
 		if err != nil {
-			return nil, ErrInvalidValue
+			return nil, invalidValueError(option, value, "a boolean (on/off, true/false)")
 		}
 		native = b
 	} else if kind == reflect.String {
@@ -388,7 +702,7 @@ func GetNativeValue(option string, realValue interface{}, value string) (interfa
 	} else if kind == reflect.Float64 {
 		i, err := strconv.Atoi(value)
 		if err != nil {
-			return nil, ErrInvalidValue
+			return nil, invalidValueError(option, value, "an integer")
 		}
 		native = float64(i)
 	} else {
@@ -401,6 +715,12 @@ func GetNativeValue(option string, realValue interface{}, value string) (interfa
 	return native, nil
 }
 
+// invalidValueError returns a descriptive error for a value that could not
+// be converted to the type of the given option
+func invalidValueError(option, value, expected string) error {
+	return fmt.Errorf("%w: %s must be %s, got '%s'", ErrInvalidValue, option, expected, value)
+}
+
 // OptionIsValid checks if a value is valid for a certain option
 func OptionIsValid(option string, value interface{}) error {
 	if validator, ok := optionValidators[option]; ok {
@@ -440,6 +760,44 @@ func validateNonNegativeValue(option string, value interface{}) error {
 	return nil
 }
 
+func validateColorColumn(option string, value interface{}) error {
+	_, err := ColorColumns(value)
+	return err
+}
+
+// ColorColumns returns the columns of the colorcolumn option, which is a
+// comma separated list of columns like "80,120", or a single column given
+// as a number. The column 0 is ignored.
+func ColorColumns(value interface{}) ([]int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return nil, errors.New("colorcolumn must be non-negative")
+		}
+		if v == 0 {
+			return nil, nil
+		}
+		return []int{int(v)}, nil
+	case string:
+		var cols []int
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			c, err := strconv.Atoi(f)
+			if err != nil || c < 0 {
+				return nil, errors.New("colorcolumn must be a comma separated list of columns, got '" + f + "'")
+			}
+			if c > 0 {
+				cols = append(cols, c)
+			}
+		}
+		return cols, nil
+	}
+	return nil, errors.New("Expected string or numeric type for colorcolumn")
+}
+
 func validateColorscheme(option string, value interface{}) error {
 	colorscheme, ok := value.(string)
 
@@ -447,6 +805,14 @@ func validateColorscheme(option string, value interface{}) error {
 		return errors.New("Expected string type for colorscheme")
 	}
 
+	// a colorscheme given by URL is fetched once the settings are read
+	if IsRemote(colorscheme) {
+		if u, sum := splitSum(colorscheme); remoteExt(u) != ".micro" || sum != "" && !validSum(sum) {
+			return errors.New(colorscheme + " is not the URL of a colorscheme")
+		}
+		return nil
+	}
+
 	if !ColorschemeExists(colorscheme) {
 		return errors.New(colorscheme + " is not a valid colorscheme")
 	}
@@ -462,9 +828,23 @@ func validateClipboard(option string, value interface{}) error {
 	}
 
 	switch val {
-	case "internal", "external", "terminal":
+	case "internal", "external", "terminal", "osc52":
 	default:
-		return errors.New(option + " must be 'internal', 'external', or 'terminal'")
+		return errors.New(option + " must be 'internal', 'external', 'terminal', or 'osc52'")
+	}
+
+	return nil
+}
+
+func validateRegisterName(option string, value interface{}) error {
+	name, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for " + option)
+	}
+
+	if !regexp.MustCompile(`^["a-zA-Z0-9]$`).MatchString(name) {
+		return errors.New(option + " must be \" or a letter or digit naming a register")
 	}
 
 	return nil
@@ -484,7 +864,338 @@ func validateLineEnding(option string, value interface{}) error {
 	return nil
 }
 
-func validateEncoding(option string, value interface{}) error {
-	_, err := htmlindex.Get(value.(string))
+func validateAmbiguousWidth(option string, value interface{}) error {
+	width, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for ambiguouswidth")
+	}
+
+	switch width {
+	case "auto", "narrow", "wide":
+	default:
+		return errors.New("ambiguouswidth must be 'auto', 'narrow' or 'wide'")
+	}
+
+	return nil
+}
+
+func validateRegexEngine(option string, value interface{}) error {
+	engine, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for regexengine")
+	}
+
+	if engine != "go" && engine != "pcre" {
+		return errors.New("Regex engine must be either 'go' or 'pcre'")
+	}
+
+	return nil
+}
+
+func validatePairs(option string, value interface{}) error {
+	pairs, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for " + option)
+	}
+
+	if util.CharacterCountInString(pairs)%2 != 0 {
+		return errors.New(option + " must contain pairs of characters")
+	}
+
+	return nil
+}
+
+func validateRegexp(option string, value interface{}) error {
+	pattern, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for " + option)
+	}
+
+	if _, err := regexp.Compile(pattern); err != nil {
+		return errors.New(option + " is not a valid regular expression: " + err.Error())
+	}
+
+	return nil
+}
+
+func validateTermDir(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for termdir")
+	}
+
+	switch val {
+	case "", "buffer", "project":
+	default:
+		return errors.New(option + " must be '', 'buffer', or 'project'")
+	}
+
+	return nil
+}
+
+func validateColorSwatch(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for colorswatch")
+	}
+
+	if val != "auto" && val != "on" && val != "off" {
+		return errors.New(option + " must be 'auto', 'on' or 'off'")
+	}
+
+	return nil
+}
+
+func validateUndoGroup(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for undogroup")
+	}
+
+	if val != "time" && val != "word" && val != "action" {
+		return errors.New(option + " must be 'time', 'word' or 'action'")
+	}
+
+	return nil
+}
+
+func validateDiffBase(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for diffbase")
+	}
+
+	switch val {
+	case "auto", "git", "hg", "svn", "fossil", "disk":
+	default:
+		return errors.New(option + " must be 'auto', 'git', 'hg', 'svn', 'fossil' or 'disk'")
+	}
+
+	return nil
+}
+
+func validateKeyProfile(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for keyprofile")
+	}
+
+	switch val {
+	case "default", "emacs", "nano", "vscode":
+		return nil
+	}
+	return errors.New(option + " must be 'default', 'emacs', 'nano' or 'vscode'")
+}
+
+func validateLogLevel(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for loglevel")
+	}
+
+	if _, err := util.ParseLogLevel(val); err != nil {
+		return errors.New(option + " must be 'debug', 'info', 'warn' or 'error'")
+	}
+	return nil
+}
+
+func validateLogFilter(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for logfilter")
+	}
+
+	known := strings.Join(util.LogSubsystems, ",")
+	for _, s := range strings.Split(val, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" && !strings.Contains(","+known+",", ","+s+",") {
+			return errors.New(option + " must list subsystems among " + strings.Join(util.LogSubsystems, ", "))
+		}
+	}
+	return nil
+}
+
+func validateFoldMethod(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for foldmethod")
+	}
+
+	switch val {
+	case "auto", "bracket", "indent":
+		return nil
+	}
+	return errors.New(option + " must be 'auto', 'bracket' or 'indent'")
+}
+
+func validateTaskOutput(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for taskoutput")
+	}
+
+	if val != "pane" && val != "terminal" {
+		return errors.New(option + " must be 'pane' or 'terminal'")
+	}
+
+	return nil
+}
+
+func validateDBFormat(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for dbformat")
+	}
+
+	switch val {
+	case "csv", "text", "tsv":
+		return nil
+	}
+	return errors.New(option + " must be 'csv', 'text' or 'tsv'")
+}
+
+func validateFuzzyMatcher(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for fuzzymatcher")
+	}
+
+	switch val {
+	case "subsequence", "fzf", "exact":
+		return nil
+	}
+	return errors.New(option + " must be 'subsequence', 'fzf' or 'exact'")
+}
+
+func validateFuzzyCase(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for fuzzycase")
+	}
+
+	switch val {
+	case "ignore", "smart", "respect":
+		return nil
+	}
+	return errors.New(option + " must be 'ignore', 'smart' or 'respect'")
+}
+
+func validateImageProtocol(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for imageprotocol")
+	}
+
+	switch val {
+	case "auto", "kitty", "iterm", "sixel", "blocks":
+		return nil
+	}
+	return errors.New(option + " must be 'auto', 'kitty', 'iterm', 'sixel' or 'blocks'")
+}
+
+// ConfirmedActions are the dangerous actions which the confirm option may
+// list, to be confirmed before they are done
+var ConfirmedActions = []string{"quit", "overwrite", "replace", "closeterm", "delete"}
+
+func validateConfirm(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for " + option)
+	}
+
+	known := strings.Join(ConfirmedActions, ",")
+	for _, a := range strings.Split(val, ",") {
+		a = strings.TrimSpace(a)
+		if a != "" && !strings.Contains(","+known+",", ","+a+",") {
+			return errors.New(option + " must list actions among " + strings.Join(ConfirmedActions, ", "))
+		}
+	}
+	return nil
+}
+
+func validateTabPath(option string, value interface{}) error {
+	val, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for tabpath")
+	}
+
+	switch val {
+	case "full", "base", "short":
+	default:
+		return errors.New(option + " must be 'full', 'base', or 'short'")
+	}
+
+	return nil
+}
+
+func validateErrorFormat(option string, value interface{}) error {
+	format, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for errorformat")
+	}
+
+	_, err := quickfix.ParseFormat(format)
 	return err
 }
+
+func validateEncoding(option string, value interface{}) error {
+	enc, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for encoding")
+	}
+
+	if _, err := htmlindex.Get(enc); err != nil {
+		return errors.New(enc + " is not a valid encoding")
+	}
+
+	return nil
+}
+
+func validateDivChars(option string, value interface{}) error {
+	divchars, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for divchars")
+	}
+
+	if util.CharacterCountInString(divchars) != 2 {
+		return errors.New(option + " must contain exactly two characters")
+	}
+
+	return nil
+}
+
+func validateIndentChar(option string, value interface{}) error {
+	indentchar, ok := value.(string)
+
+	if !ok {
+		return errors.New("Expected string type for indentchar")
+	}
+
+	if util.CharacterCountInString(indentchar) > 1 {
+		return errors.New(option + " must be a single character")
+	}
+
+	return nil
+}
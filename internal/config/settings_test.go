@@ -0,0 +1,208 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSetting(t *testing.T) {
+	assert.Nil(t, ValidateSetting("tabsize", float64(8), float64(4)))
+	assert.Nil(t, ValidateSetting("fileformat", "dos", "unix"))
+
+	err := ValidateSetting("tabsize", "4", float64(4))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "incorrect type (string)")
+
+	err = ValidateSetting("tabsize", float64(0), float64(4))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "must be greater than 0")
+
+	err = ValidateSetting("fileformat", "mac", "unix")
+	assert.NotNil(t, err)
+
+	err = ValidateSetting("ruler", nil, true)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "incorrect type (null)")
+
+	assert.Nil(t, ValidateSetting("indentpattern", "{$", ""))
+	err = ValidateSetting("dedentpattern", "^(}", "")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "dedentpattern is not a valid regular expression")
+
+	assert.Nil(t, ValidateSetting("autoclosepairs", "()«»", ""))
+	assert.NotNil(t, ValidateSetting("autoclosepairs", "()[", ""))
+
+	assert.Nil(t, ValidateSetting("clipboardsync", "a", ""))
+	assert.NotNil(t, ValidateSetting("clipboardsync", "ab", ""))
+
+	assert.Nil(t, ValidateSetting("termdir", "project", ""))
+	assert.NotNil(t, ValidateSetting("termdir", "home", ""))
+
+	assert.Nil(t, ValidateSetting("taskoutput", "terminal", "pane"))
+	assert.NotNil(t, ValidateSetting("taskoutput", "tab", "pane"))
+	assert.Nil(t, ValidateSetting("dbformat", "tsv", "csv"))
+	assert.NotNil(t, ValidateSetting("dbformat", "json", "csv"))
+	assert.Nil(t, ValidateSetting("colorswatch", "on", "auto"))
+	assert.NotNil(t, ValidateSetting("colorswatch", true, "auto"))
+	assert.Nil(t, ValidateSetting("textwidth", float64(72), float64(80)))
+	assert.NotNil(t, ValidateSetting("textwidth", float64(0), float64(80)))
+	assert.Nil(t, ValidateSetting("foldmethod", "indent", "auto"))
+	assert.NotNil(t, ValidateSetting("foldmethod", "syntax", "auto"))
+	assert.Nil(t, ValidateSetting("undogroup", "word", "time"))
+	assert.NotNil(t, ValidateSetting("undogroup", "line", "time"))
+	assert.Nil(t, ValidateSetting("diffbase", "hg", "auto"))
+	assert.NotNil(t, ValidateSetting("diffbase", "cvs", "auto"))
+	assert.Nil(t, ValidateSetting("keyprofile", "emacs", "default"))
+	assert.NotNil(t, ValidateSetting("keyprofile", "vim", "default"))
+	assert.Nil(t, ValidateSetting("confirm", "quit, closeterm", ""))
+	assert.Nil(t, ValidateSetting("confirm", "", ""))
+	assert.Nil(t, ValidateSetting("confirm", "quit,delete", ""))
+	assert.NotNil(t, ValidateSetting("confirm", "quit,exit", ""))
+	assert.Nil(t, ValidateSetting("loglevel", "debug", "warn"))
+	assert.NotNil(t, ValidateSetting("loglevel", "verbose", "warn"))
+	assert.Nil(t, ValidateSetting("logfilter", "lua, rpc", ""))
+	assert.NotNil(t, ValidateSetting("logfilter", "lua,plugins", ""))
+}
+
+func TestColorColumns(t *testing.T) {
+	cols, err := ColorColumns("80, 120,0")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{80, 120}, cols)
+
+	cols, err = ColorColumns(float64(72))
+	assert.Nil(t, err)
+	assert.Equal(t, []int{72}, cols)
+
+	cols, err = ColorColumns("")
+	assert.Nil(t, err)
+	assert.Empty(t, cols)
+
+	_, err = ColorColumns("80,abc")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ValidateSetting("colorcolumn", float64(80), ""))
+	assert.NotNil(t, ValidateSetting("colorcolumn", "-1", ""))
+
+	v, err := GetNativeValue("colorcolumn", float64(80), "80,100")
+	assert.Nil(t, err)
+	assert.Equal(t, "80,100", v)
+}
+
+func TestInitGlobalSettingsKeepsDefaults(t *testing.T) {
+	parsedSettings = map[string]interface{}{
+		"tabsize":    "4",
+		"ruler":      false,
+		"fileformat": "mac",
+	}
+	oldSettings := GlobalSettings
+	defer func() {
+		parsedSettings = make(map[string]interface{})
+		GlobalSettings = oldSettings
+	}()
+
+	err := InitGlobalSettings()
+	assert.NotNil(t, err)
+	assert.Equal(t, float64(4), GlobalSettings["tabsize"])
+	assert.Equal(t, "unix", GlobalSettings["fileformat"])
+	assert.Equal(t, false, GlobalSettings["ruler"])
+}
+
+func TestPaneSettings(t *testing.T) {
+	// the options of a pane override the ones of its buffer
+	for _, option := range PaneSettings {
+		_, ok := defaultCommonSettings[option]
+		assert.True(t, ok, option)
+	}
+}
+
+func TestGetNativeValue(t *testing.T) {
+	v, err := GetNativeValue("tabsize", float64(4), "2")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(2), v)
+
+	_, err = GetNativeValue("tabsize", float64(4), "two")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "tabsize must be an integer")
+	assert.True(t, errors.Is(err, ErrInvalidValue))
+
+	_, err = GetNativeValue("ruler", true, "maybe")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "boolean")
+
+	_, err = GetNativeValue("divchars", "|-", "|")
+	assert.NotNil(t, err)
+}
+
+func TestProjectSettings(t *testing.T) {
+	root, err := ioutil.TempDir("", "micro-project")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "docs"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(root, ".micro.json"), []byte(`{
+		// comments are allowed like in settings.json
+		"tabsize": 2,
+		"colorscheme": "monokai",
+		"ft:go": {"tabstospaces": false, "buildcmd": "touch pwned"},
+		"termenv": "LD_PRELOAD=./pwned.so",
+		"termdir": "project",
+		"savecheck": "touch pwned",
+		"autosu": true,
+		"backupdir": "/tmp/elsewhere",
+		"docs/*.md": {"tabsize": 4, "softwrap": true}
+	}`), 0644))
+
+	settings := func(path, ft string) map[string]interface{} {
+		s := DefaultCommonSettings()
+		s["tabstospaces"] = true
+		s["filetype"] = ft
+		assert.Nil(t, InitLocalSettings(s, filepath.Join(root, path)))
+		return s
+	}
+
+	s := settings("main.go", "go")
+	assert.Equal(t, float64(2), s["tabsize"])
+	assert.Equal(t, false, s["tabstospaces"])
+	assert.NotContains(t, s, "colorscheme")
+	assert.Equal(t, "project", s["termdir"])
+	// only the options showing and indenting the text are used
+	assert.Equal(t, "", s["savecheck"])
+	assert.Equal(t, "", s["termenv"])
+	assert.Equal(t, "make", s["buildcmd"])
+	assert.Equal(t, false, s["autosu"])
+	assert.Equal(t, "", s["backupdir"])
+
+	s = settings("docs/a.md", "markdown")
+	assert.Equal(t, float64(4), s["tabsize"])
+	assert.Equal(t, true, s["softwrap"])
+	assert.Equal(t, true, s["tabstospaces"])
+
+	s = DefaultCommonSettings()
+	assert.Nil(t, InitLocalSettings(s, filepath.Join(os.TempDir(), "elsewhere.txt")))
+	assert.Equal(t, float64(4), s["tabsize"])
+}
+
+func TestReadSettingsRenamed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	old := ConfigDir
+	ConfigDir = dir
+	defer func() {
+		ConfigDir = old
+		parsedSettings = make(map[string]interface{})
+	}()
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "settings.json"), []byte(`{
+		"scrollmargin": 5,
+		"*.md": {"scrollmargin": 1, "scrolloff": 2}
+	}`), 0644))
+	assert.Nil(t, ReadSettings())
+	assert.Equal(t, float64(5), parsedSettings["scrolloff"])
+	assert.NotContains(t, parsedSettings, "scrollmargin")
+	assert.Equal(t, map[string]interface{}{"scrolloff": float64(2)}, parsedSettings["*.md"])
+}
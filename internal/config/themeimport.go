@@ -0,0 +1,430 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/json5"
+	"gopkg.in/yaml.v2"
+)
+
+// A themeStyle is a style of an imported theme, with the colors written
+// #rrggbb, or empty to use the ones of the default style
+type themeStyle struct {
+	fg, bg string
+	attrs  []string
+}
+
+// String returns the style as written in a color-link statement
+func (s themeStyle) String() string {
+	colors := s.fg
+	if s.bg != "" {
+		colors += "," + s.bg
+	}
+	return strings.TrimSpace(strings.Join(s.attrs, " ") + " " + colors)
+}
+
+// A themeLink is a group of a colorscheme with its style
+type themeLink struct {
+	group string
+	style themeStyle
+}
+
+// writeColorscheme returns the text of a colorscheme made of the links,
+// skipping the ones with an empty style
+func writeColorscheme(source string, links []themeLink) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Imported from %s\n", source)
+	for _, l := range links {
+		if s := l.style.String(); s != "" {
+			fmt.Fprintf(&sb, "color-link %s \"%s\"\n", l.group, s)
+		}
+	}
+	return sb.String()
+}
+
+// hexColor returns the color written as #rgb, #rrggbb, the same with an
+// alpha component, or without the #, as #rrggbb. A color which is partially
+// transparent is blended with the background bg if it is given.
+func hexColor(s, bg string) (string, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 || len(s) == 4 {
+		var long []byte
+		for i := 0; i < len(s); i++ {
+			long = append(long, s[i], s[i])
+		}
+		s = string(long)
+	}
+	if len(s) != 6 && len(s) != 8 {
+		return "", false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return "", false
+	}
+	if len(s) == 6 {
+		return "#" + strings.ToLower(s), true
+	}
+
+	r, g, b, a := v>>24, v>>16&0xff, v>>8&0xff, v&0xff
+	if bg, ok := hexColor(bg, ""); ok {
+		bv, _ := strconv.ParseUint(bg[1:], 16, 32)
+		blend := func(c, under uint64) uint64 {
+			return (c*a + under*(255-a)) / 255
+		}
+		r, g, b = blend(r, bv>>16), blend(g, bv>>8&0xff), blend(b, bv&0xff)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b), true
+}
+
+// ImportTheme converts a base16 scheme, in YAML, or a VSCode theme, in JSON,
+// to a micro colorscheme. It returns the name of the theme, which is empty
+// if it has none, and the text of the colorscheme. The source is mentioned
+// at the top of the colorscheme.
+func ImportTheme(data []byte, source string) (string, string, error) {
+	var vs vscodeTheme
+	if err := json5.Unmarshal(data, &vs); err == nil && (len(vs.Colors) > 0 || len(vs.TokenColors) > 0) {
+		return vs.Name, writeColorscheme(source, vs.links()), nil
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return "", "", errors.New("The theme is neither a base16 scheme nor a VSCode theme")
+	}
+	b16, err := parseBase16(raw)
+	if err != nil {
+		return "", "", err
+	}
+	return b16.name, writeColorscheme(source, b16.links()), nil
+}
+
+// A base16Scheme is a palette of 16 colors, base00 to base0F, in the
+// format of https://github.com/chriskempson/base16 or of its newer version
+// which puts the colors under palette
+type base16Scheme struct {
+	name   string
+	colors [16]string
+}
+
+func parseBase16(raw map[string]interface{}) (*base16Scheme, error) {
+	b := new(base16Scheme)
+	for _, key := range []string{"scheme", "name"} {
+		if name, ok := raw[key].(string); ok && b.name == "" {
+			b.name = name
+		}
+	}
+	palette := make(map[string]string)
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			palette[strings.ToLower(k)] = s
+		}
+	}
+	if p, ok := raw["palette"].(map[interface{}]interface{}); ok {
+		for k, v := range p {
+			if s, ok := v.(string); ok {
+				palette[strings.ToLower(fmt.Sprint(k))] = s
+			}
+		}
+	}
+	for i := range b.colors {
+		key := fmt.Sprintf("base%02x", i)
+		c, ok := hexColor(palette[key], "")
+		if !ok {
+			if _, found := palette[key]; found {
+				return nil, fmt.Errorf("The color %s of the base16 scheme is invalid: %s", key, palette[key])
+			}
+			return nil, errors.New("The theme is neither a base16 scheme nor a VSCode theme")
+		}
+		b.colors[i] = c
+	}
+	return b, nil
+}
+
+// links maps the colors of the scheme to micro's groups, following the
+// base16 styling guidelines
+func (b *base16Scheme) links() []themeLink {
+	c := func(i int) string {
+		return b.colors[i]
+	}
+	fg := func(i int, attrs ...string) themeStyle {
+		return themeStyle{fg: c(i), attrs: attrs}
+	}
+	style := func(f, bg int) themeStyle {
+		return themeStyle{fg: c(f), bg: c(bg)}
+	}
+	back := func(i int) themeStyle {
+		return themeStyle{bg: c(i)}
+	}
+	return []themeLink{
+		{"default", style(0x05, 0x00)},
+		{"comment", fg(0x03)},
+		{"identifier", fg(0x0D)},
+		{"identifier.class", fg(0x0A)},
+		{"identifier.var", fg(0x08)},
+		{"constant", fg(0x09)},
+		{"constant.string", fg(0x0B)},
+		{"constant.string.char", fg(0x0B)},
+		{"constant.specialChar", fg(0x0C)},
+		{"constant.string.url", fg(0x0D, "underline")},
+		{"statement", fg(0x0E)},
+		{"symbol", fg(0x05)},
+		{"symbol.tag", fg(0x08)},
+		{"preproc", fg(0x0A)},
+		{"type", fg(0x0A)},
+		{"type.keyword", fg(0x0E)},
+		{"special", fg(0x0C)},
+		{"underlined", fg(0x08, "underline")},
+		{"error", fg(0x08, "bold")},
+		{"todo", style(0x0A, 0x01)},
+		{"selection", back(0x02)},
+		{"hlsearch", style(0x01, 0x0A)},
+		{"statusline", style(0x04, 0x02)},
+		{"tabbar", style(0x04, 0x01)},
+		{"tabbar.active", style(0x05, 0x02)},
+		{"popup", style(0x05, 0x01)},
+		{"indent-char", fg(0x02)},
+		{"indent-guide", fg(0x02)},
+		{"indent-guide-active", fg(0x03)},
+		{"line-number", style(0x03, 0x01)},
+		{"current-line-number", style(0x04, 0x01)},
+		// the background of the lines and columns is the foreground of
+		// their group
+		{"cursor-line", fg(0x01)},
+		{"cursor-column", fg(0x01)},
+		{"color-column", fg(0x01)},
+		{"gutter-error", fg(0x08)},
+		{"gutter-warning", fg(0x0A)},
+		{"gutter-info", fg(0x0D)},
+		{"bookmark", style(0x0D, 0x01)},
+		{"spell-error", fg(0x08)},
+		{"diff-added", fg(0x0B)},
+		{"diff-modified", fg(0x0E)},
+		{"diff-deleted", fg(0x08)},
+		{"diff-text", back(0x02)},
+		{"scrollbar", fg(0x03)},
+		{"divider", style(0x02, 0x00)},
+		{"message", fg(0x05)},
+		{"error-message", fg(0x08, "bold")},
+	}
+}
+
+// A vscodeTheme is a color theme of Visual Studio Code, with the colors of
+// the interface and the colors of the TextMate scopes
+type vscodeTheme struct {
+	Name        string            `json:"name"`
+	Colors      map[string]string `json:"colors"`
+	TokenColors []vscodeRule      `json:"tokenColors"`
+}
+
+type vscodeRule struct {
+	Scope    interface{} `json:"scope"`
+	Settings struct {
+		Foreground string `json:"foreground"`
+		Background string `json:"background"`
+		FontStyle  string `json:"fontStyle"`
+	} `json:"settings"`
+}
+
+// selectors returns the scope selectors of the rule, which are a string
+// separated by commas or a list
+func (r vscodeRule) selectors() []string {
+	var sels []string
+	switch s := r.Scope.(type) {
+	case string:
+		sels = strings.Split(s, ",")
+	case []interface{}:
+		for _, v := range s {
+			if str, ok := v.(string); ok {
+				sels = append(sels, strings.Split(str, ",")...)
+			}
+		}
+	}
+	for i := range sels {
+		sels[i] = strings.TrimSpace(sels[i])
+	}
+	return sels
+}
+
+// scopeRule returns the rule of the most specific selector matching scope,
+// the later rules taking precedence, or nil. A selector matches the scopes
+// it is a prefix of, so that keyword matches keyword.control. The selectors
+// of nested scopes are left out.
+func (t *vscodeTheme) scopeRule(scope string) *vscodeRule {
+	var best *vscodeRule
+	bestLen := 0
+	for i := range t.TokenColors {
+		r := &t.TokenColors[i]
+		for _, sel := range r.selectors() {
+			if sel == "" || strings.Contains(sel, " ") || (sel != scope && !strings.HasPrefix(scope, sel+".")) {
+				continue
+			}
+			if len(sel) >= bestLen {
+				best, bestLen = r, len(sel)
+			}
+		}
+	}
+	return best
+}
+
+// color returns the first of the colors of the interface the theme has,
+// blended with the background of the editor if it is transparent
+func (t *vscodeTheme) color(keys ...string) string {
+	for _, k := range keys {
+		if c, ok := hexColor(t.Colors[k], t.background()); ok {
+			return c
+		}
+	}
+	return ""
+}
+
+// background returns the background of the editor, which the older themes
+// give as the settings of a rule without a scope
+func (t *vscodeTheme) background() string {
+	if c, ok := hexColor(t.Colors["editor.background"], ""); ok {
+		return c
+	}
+	for _, r := range t.TokenColors {
+		if r.Scope == nil {
+			if c, ok := hexColor(r.Settings.Background, ""); ok {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+// foreground returns the foreground of the editor
+func (t *vscodeTheme) foreground() string {
+	if c := t.color("editor.foreground", "foreground"); c != "" {
+		return c
+	}
+	for _, r := range t.TokenColors {
+		if r.Scope == nil {
+			if c, ok := hexColor(r.Settings.Foreground, ""); ok {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+// scopeStyle returns the style of the first scope the theme has a rule for
+func (t *vscodeTheme) scopeStyle(scopes ...string) themeStyle {
+	for _, scope := range scopes {
+		r := t.scopeRule(scope)
+		if r == nil {
+			continue
+		}
+		var s themeStyle
+		s.fg, _ = hexColor(r.Settings.Foreground, t.background())
+		s.bg, _ = hexColor(r.Settings.Background, t.background())
+		for _, a := range strings.Fields(r.Settings.FontStyle) {
+			if a == "bold" || a == "italic" || a == "underline" {
+				s.attrs = append(s.attrs, a)
+			}
+		}
+		return s
+	}
+	return themeStyle{}
+}
+
+// links maps the TextMate scopes and the colors of the interface of the
+// theme to micro's groups
+func (t *vscodeTheme) links() []themeLink {
+	bg := t.background()
+	ui := func(fg, bg string) themeStyle {
+		return themeStyle{fg: fg, bg: bg}
+	}
+	return []themeLink{
+		{"default", ui(t.foreground(), bg)},
+		{"comment", t.scopeStyle("comment")},
+		{"identifier", t.scopeStyle("entity.name.function", "variable")},
+		{"identifier.class", t.scopeStyle("entity.name.type.class", "entity.name.type")},
+		{"identifier.var", t.scopeStyle("variable.other", "variable")},
+		{"identifier.macro", t.scopeStyle("entity.name.function.preprocessor")},
+		{"constant", t.scopeStyle("constant")},
+		{"constant.number", t.scopeStyle("constant.numeric", "constant")},
+		{"constant.bool", t.scopeStyle("constant.language", "constant")},
+		{"constant.string", t.scopeStyle("string")},
+		{"constant.string.char", t.scopeStyle("constant.character", "string")},
+		{"constant.specialChar", t.scopeStyle("constant.character.escape", "constant.character")},
+		{"constant.string.url", t.scopeStyle("markup.underline.link")},
+		{"statement", t.scopeStyle("keyword.control", "keyword")},
+		{"symbol", t.scopeStyle("keyword.operator", "punctuation")},
+		{"symbol.operator", t.scopeStyle("keyword.operator")},
+		{"symbol.brackets", t.scopeStyle("punctuation.section.brackets", "meta.brace", "punctuation")},
+		{"symbol.tag", t.scopeStyle("entity.name.tag")},
+		{"preproc", t.scopeStyle("meta.preprocessor", "keyword.control.directive", "keyword.control.import")},
+		{"type", t.scopeStyle("storage.type", "support.type", "entity.name.type")},
+		{"type.keyword", t.scopeStyle("storage.modifier", "storage")},
+		{"special", t.scopeStyle("support.function", "constant.character.escape")},
+		{"underlined", t.scopeStyle("markup.underline")},
+		{"error", t.scopeStyle("invalid")},
+		{"diff-added", ui(t.color("editorGutter.addedBackground", "gitDecoration.addedResourceForeground"), "")},
+		{"diff-modified", ui(t.color("editorGutter.modifiedBackground", "gitDecoration.modifiedResourceForeground"), "")},
+		{"diff-deleted", ui(t.color("editorGutter.deletedBackground", "gitDecoration.deletedResourceForeground"), "")},
+		{"diff-text", ui("", t.color("diffEditor.insertedTextBackground"))},
+		{"selection", ui(t.color("editor.selectionForeground"), t.color("editor.selectionBackground"))},
+		{"hlsearch", ui("", t.color("editor.findMatchHighlightBackground", "editor.findMatchBackground"))},
+		{"statusline", ui(t.color("statusBar.foreground"), t.color("statusBar.background"))},
+		{"tabbar", ui(t.color("tab.inactiveForeground"), t.color("editorGroupHeader.tabsBackground", "tab.inactiveBackground"))},
+		{"tabbar.active", ui(t.color("tab.activeForeground"), t.color("tab.activeBackground"))},
+		{"popup", ui(t.color("editorSuggestWidget.foreground"), t.color("editorSuggestWidget.background"))},
+		{"indent-char", ui(t.color("editorWhitespace.foreground"), "")},
+		{"indent-guide", ui(t.color("editorIndentGuide.background"), "")},
+		{"indent-guide-active", ui(t.color("editorIndentGuide.activeBackground"), "")},
+		{"line-number", ui(t.color("editorLineNumber.foreground"), t.color("editorGutter.background"))},
+		{"current-line-number", ui(t.color("editorLineNumber.activeForeground"), t.color("editorGutter.background"))},
+		// the background of the lines and columns is the foreground of
+		// their group
+		{"cursor-line", ui(t.color("editor.lineHighlightBackground"), "")},
+		{"color-column", ui(t.color("editorRuler.foreground"), "")},
+		{"gutter-error", ui(t.color("editorError.foreground"), "")},
+		{"gutter-warning", ui(t.color("editorWarning.foreground"), "")},
+		{"gutter-info", ui(t.color("editorInfo.foreground"), "")},
+		{"spell-error", ui(t.color("editorError.foreground"), "")},
+		{"scrollbar", ui(t.color("scrollbarSlider.activeBackground", "scrollbarSlider.background"), "")},
+		{"divider", ui(t.color("editorGroup.border", "panel.border"), bg)},
+		{"error-message", ui(t.color("errorForeground", "editorError.foreground"), "")},
+	}
+}
+
+// ThemeName returns the name of the colorscheme of an imported theme, made
+// of its name, or of the name of its file if it has none
+func ThemeName(name, filename string) string {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		name = strings.TrimSuffix(name, "-color-theme")
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9_]+`).ReplaceAllString(name, "-")
+	return strings.Trim(name, "-")
+}
+
+// SaveColorscheme writes a colorscheme to the colorschemes directory of the
+// config directory and returns its path. The colorscheme can then be
+// loaded by its name without restarting.
+func SaveColorscheme(name, text string) (string, error) {
+	if name == "" {
+		return "", errors.New("The colorscheme has no name")
+	}
+	if ColorschemeExists(name) {
+		return "", errors.New("A colorscheme named " + name + " already exists")
+	}
+	dir := filepath.Join(ConfigDir, "colorschemes")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".micro")
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		return "", err
+	}
+	AddRealRuntimeFile(RTColorscheme, realFile(path))
+	return path, nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/tcell/v2"
+)
+
+const testBase16 = `scheme: "Test Ocean"
+author: "someone"
+base00: "2b303b"
+base01: "343d46"
+base02: "4f5b66"
+base03: "65737e"
+base04: "a7adba"
+base05: "c0c5ce"
+base06: "dfe1e8"
+base07: "eff1f5"
+base08: "bf616a"
+base09: "d08770"
+base0A: "ebcb8b"
+base0B: "a3be8c"
+base0C: "96b5b4"
+base0D: "8fa1b3"
+base0E: "b48ead"
+base0F: "ab7967"
+`
+
+const testVSCode = `{
+	// comments and trailing commas are allowed
+	"name": "Test Dark",
+	"colors": {
+		"editor.background": "#1e1e1e",
+		"editor.foreground": "#d4d4d4",
+		"editor.lineHighlightBackground": "#ffffff20",
+		"statusBar.background": "#007acc",
+	},
+	"tokenColors": [
+		{"scope": "comment", "settings": {"foreground": "#6a9955", "fontStyle": "italic"}},
+		{"scope": ["keyword", "storage.type"], "settings": {"foreground": "#569cd6"}},
+		{"scope": "keyword.control", "settings": {"foreground": "#c586c0"}},
+		{"scope": "string, constant.character", "settings": {"foreground": "#ce9178"}},
+		{"scope": "source.go keyword.operator", "settings": {"foreground": "#ff0000"}},
+	],
+}`
+
+func TestImportBase16(t *testing.T) {
+	name, text, err := ImportTheme([]byte(testBase16), "ocean.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "Test Ocean", name)
+	assert.True(t, strings.HasPrefix(text, "# Imported from ocean.yaml\n"))
+
+	c, err := ParseColorscheme(text)
+	assert.Nil(t, err)
+	fg, bg, _ := c["default"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xc0, 0xc5, 0xce), fg)
+	assert.Equal(t, tcell.NewRGBColor(0x2b, 0x30, 0x3b), bg)
+	fg, _, _ = c["constant.string"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xa3, 0xbe, 0x8c), fg)
+	_, bg, _ = c["selection"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0x4f, 0x5b, 0x66), bg)
+
+	// the newer format puts the colors under palette
+	palette := "system: base16\nname: Palette\npalette:\n" + strings.Replace(testBase16[strings.Index(testBase16, "base00"):], "base", "  base", -1)
+	name, text2, err := ImportTheme([]byte(palette), "palette.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "Palette", name)
+	assert.Equal(t, strings.SplitN(text, "\n", 2)[1], strings.SplitN(text2, "\n", 2)[1])
+
+	_, _, err = ImportTheme([]byte("base00: \"2b303b\"\n"), "partial.yaml")
+	assert.NotNil(t, err)
+	_, _, err = ImportTheme([]byte("base00: \"zzzzzz\"\n"), "bad.yaml")
+	assert.NotNil(t, err)
+}
+
+func TestImportVSCode(t *testing.T) {
+	name, text, err := ImportTheme([]byte(testVSCode), "dark.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "Test Dark", name)
+
+	c, err := ParseColorscheme(text)
+	assert.Nil(t, err)
+	fg, bg, _ := c["default"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xd4, 0xd4, 0xd4), fg)
+	assert.Equal(t, tcell.NewRGBColor(0x1e, 0x1e, 0x1e), bg)
+
+	// the most specific selector wins
+	fg, _, _ = c["statement"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xc5, 0x86, 0xc0), fg)
+	fg, _, _ = c["type"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0x56, 0x9c, 0xd6), fg)
+	fg, _, attrs := c["comment"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0x6a, 0x99, 0x55), fg)
+	assert.NotZero(t, attrs&tcell.AttrItalic)
+	fg, _, _ = c["constant.string.char"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xce, 0x91, 0x78), fg)
+
+	// the nested selectors are left out, and the transparent colors are
+	// blended with the background
+	assert.NotContains(t, text, "#ff0000")
+	fg, _, _ = c["cursor-line"].Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0x3a, 0x3a, 0x3a), fg)
+}
+
+func TestHexColor(t *testing.T) {
+	for _, c := range []struct{ in, bg, out string }{
+		{"#abc", "", "#aabbcc"},
+		{"AABBCC", "", "#aabbcc"},
+		{"#11223380", "", "#112233"},
+		{"#ffffff80", "#000000", "#808080"},
+	} {
+		out, ok := hexColor(c.in, c.bg)
+		assert.True(t, ok, c.in)
+		assert.Equal(t, c.out, out, c.in)
+	}
+	_, ok := hexColor("red", "")
+	assert.False(t, ok)
+}
+
+func TestThemeName(t *testing.T) {
+	assert.Equal(t, "test-ocean", ThemeName("Test Ocean", "ocean.yaml"))
+	assert.Equal(t, "one-dark", ThemeName("", "/tmp/One Dark-color-theme.json"))
+}
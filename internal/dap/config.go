@@ -0,0 +1,122 @@
+package dap
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/zyedidia/json5"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A Configuration tells how to start a debugging session: the adapter to
+// start or connect to, and the arguments of its launch or attach request
+type Configuration struct {
+	Name string
+	// Adapter is the command starting the adapter, split as by a shell
+	Adapter string
+	// Address is the host:port of an adapter listening on TCP, which
+	// Adapter starts if it is set
+	Address string
+	// Request is launch or attach
+	Request string
+	// Arguments are the arguments of the request, such as the program to
+	// launch, which depend on the adapter
+	Arguments map[string]interface{}
+}
+
+// NewConfiguration returns the configuration given by the JSON object v,
+// in which the fields other than name, adapter, address and request are
+// the arguments of the request
+func NewConfiguration(v map[string]interface{}) (Configuration, error) {
+	c := Configuration{Request: "launch", Arguments: make(map[string]interface{})}
+	for k, val := range v {
+		s, isString := val.(string)
+		switch k {
+		case "name", "adapter", "address", "request":
+			if !isString {
+				return c, errors.New("Expected string type for " + k)
+			}
+		}
+		switch k {
+		case "name":
+			c.Name = s
+		case "adapter":
+			c.Adapter = s
+		case "address":
+			c.Address = s
+		case "request":
+			c.Request = s
+		default:
+			c.Arguments[k] = val
+		}
+	}
+	if c.Request != "launch" && c.Request != "attach" {
+		return c, errors.New("request must be 'launch' or 'attach'")
+	}
+	if c.Adapter == "" && c.Address == "" {
+		return c, errors.New("A debug configuration needs an adapter or an address")
+	}
+	return c, nil
+}
+
+// LoadConfigurations returns the configurations of the debug array of the
+// project settings file at root, if there is one
+func LoadConfigurations(root string) ([]Configuration, error) {
+	path := filepath.Join(root, util.ProjectSettingsFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var settings struct {
+		Debug []map[string]interface{} `json:"debug"`
+	}
+	if err := json5.Unmarshal(data, &settings); err != nil {
+		return nil, errors.New("Error reading " + path + ": " + err.Error())
+	}
+	var configs []Configuration
+	for i, v := range settings.Debug {
+		c, err := NewConfiguration(v)
+		if err != nil {
+			return nil, errors.New("Error in debug configuration " + strconv.Itoa(i+1) + " of " + path + ": " + err.Error())
+		}
+		if c.Name == "" {
+			c.Name = c.Request + strconv.Itoa(i+1)
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+// Expand returns the configuration with replace applied to its adapter and
+// address and to the strings of its arguments
+func (c Configuration) Expand(replace func(string) string) Configuration {
+	c.Adapter = replace(c.Adapter)
+	c.Address = replace(c.Address)
+	c.Arguments = expandValue(c.Arguments, replace).(map[string]interface{})
+	return c
+}
+
+func expandValue(v interface{}, replace func(string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return replace(v)
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = expandValue(e, replace)
+		}
+		return l
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = expandValue(e, replace)
+		}
+		return m
+	}
+	return v
+}
@@ -0,0 +1,334 @@
+// Package dap is a client of the Debug Adapter Protocol, which debuggers
+// such as delve, debugpy or lldb-dap speak. A debug adapter is started as a
+// process talking on its standard input and output, or reached on a TCP
+// address, and the client sends it requests and receives its responses and
+// events, see https://microsoft.github.io/debug-adapter-protocol.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Message is a request, a response or an event. Requests come from the
+// client, or from the adapter for the reverse requests.
+type Message struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+
+	// Command is set for the requests and responses
+	Command   string      `json:"command,omitempty"`
+	Arguments interface{} `json:"arguments,omitempty"`
+
+	// RequestSeq, Success and Message are set for the responses
+	RequestSeq int    `json:"request_seq,omitempty"`
+	Success    bool   `json:"success,omitempty"`
+	Message    string `json:"message,omitempty"`
+
+	// Event is set for the events
+	Event string `json:"event,omitempty"`
+
+	// Body is the body of the responses and events
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Err returns the error of a failed response, or nil
+func (m *Message) Err() error {
+	if m.Success {
+		return nil
+	}
+	if m.Message != "" && m.Command != "" {
+		return errors.New(m.Command + ": " + m.Message)
+	} else if m.Message != "" {
+		return errors.New(m.Message)
+	}
+	// the details are in the error in the body
+	var body struct {
+		Error struct {
+			Format string `json:"format"`
+		} `json:"error"`
+	}
+	json.Unmarshal(m.Body, &body)
+	if body.Error.Format != "" {
+		return errors.New(m.Command + ": " + body.Error.Format)
+	}
+	return errors.New(m.Command + " failed")
+}
+
+// Decode decodes the body of the message into v
+func (m *Message) Decode(v interface{}) error {
+	if len(m.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Body, v)
+}
+
+// ReadMessage reads a message, made of a Content-Length header giving the
+// length of the JSON content which follows it
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if length < 0 {
+				// the blank lines before the header are skipped
+				continue
+			}
+			break
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 && strings.EqualFold(line[:i], "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+			if err != nil || length < 0 {
+				return nil, errors.New("Invalid header " + line)
+			}
+		}
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	m := new(Message)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteMessage writes the message m with its Content-Length header
+func WriteMessage(w io.Writer, m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "Content-Length: "+strconv.Itoa(len(data))+"\r\n\r\n"+string(data))
+	return err
+}
+
+// A Client talks to a debug adapter. The callbacks of the requests and the
+// handlers are run by Dispatch, one at a time in the order the messages are
+// received.
+type Client struct {
+	// Dispatch runs the callbacks, it calls them by default. It is used to
+	// run them in the goroutine of the interface.
+	Dispatch func(f func())
+	// OnEvent is called with the events of the adapter
+	OnEvent func(e *Message)
+	// OnRequest is called with the reverse requests of the adapter and
+	// returns the body of the response or an error. Without it, they fail.
+	OnRequest func(r *Message) (interface{}, error)
+	// OnClose is called when the connection to the adapter is closed, with
+	// the error which closed it or nil if Close was called
+	OnClose func(err error)
+
+	conn io.ReadWriteCloser
+	cmd  *exec.Cmd
+
+	lock    sync.Mutex
+	seq     int
+	pending map[int]func(*Message)
+	closed  bool
+}
+
+// NewClient returns a client talking to an adapter on conn. Its handlers
+// should be set before Run is called.
+func NewClient(conn io.ReadWriteCloser) *Client {
+	return &Client{
+		Dispatch: func(f func()) { f() },
+		conn:     conn,
+		pending:  make(map[int]func(*Message)),
+	}
+}
+
+// cmdConn is the standard input and output of an adapter process
+type cmdConn struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (c cmdConn) Close() error {
+	c.WriteCloser.Close()
+	return c.ReadCloser.Close()
+}
+
+// Start starts the adapter command in the directory dir and returns a
+// client talking on its standard input and output, or connects to the
+// adapter at address if it is not empty, which command is then a server
+// listening on it. Its standard error is written to stderr if it is not nil.
+func Start(command []string, dir, address string, stderr io.Writer) (*Client, error) {
+	if len(command) == 0 && address == "" {
+		return nil, errors.New("No debug adapter given")
+	}
+	var cmd *exec.Cmd
+	var conn io.ReadWriteCloser
+	if len(command) > 0 {
+		cmd = exec.Command(command[0], command[1:]...)
+		cmd.Dir = dir
+		cmd.Stderr = stderr
+		if address == "" {
+			in, err := cmd.StdinPipe()
+			if err != nil {
+				return nil, err
+			}
+			out, err := cmd.StdoutPipe()
+			if err != nil {
+				return nil, err
+			}
+			conn = cmdConn{out, in}
+		} else {
+			cmd.Stdout = stderr
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+	}
+	if address != "" {
+		var err error
+		if conn, err = dial(address, cmd != nil); err != nil {
+			if cmd != nil {
+				cmd.Process.Kill()
+				cmd.Wait()
+			}
+			return nil, err
+		}
+	}
+	c := NewClient(conn)
+	c.cmd = cmd
+	return c, nil
+}
+
+// dial connects to the adapter at address, retrying for a few seconds if
+// it has just been started
+func dial(address string, retry bool) (net.Conn, error) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil || !retry || time.Now().After(deadline) {
+			return conn, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Run reads the messages of the adapter until the connection is closed
+func (c *Client) Run() {
+	r := bufio.NewReader(c.conn)
+	var err error
+	for {
+		var m *Message
+		if m, err = ReadMessage(r); err != nil {
+			break
+		}
+		c.handle(m)
+	}
+
+	c.lock.Lock()
+	closed := c.closed
+	c.closed = true
+	pending := c.pending
+	c.pending = make(map[int]func(*Message))
+	c.lock.Unlock()
+
+	if closed || err == io.EOF {
+		err = nil
+	}
+	c.conn.Close()
+	if c.cmd != nil {
+		c.cmd.Wait()
+	}
+	c.Dispatch(func() {
+		for _, cb := range pending {
+			if cb != nil {
+				cb(&Message{Type: "response", Message: "The debug adapter exited"})
+			}
+		}
+		if c.OnClose != nil {
+			c.OnClose(err)
+		}
+	})
+}
+
+func (c *Client) handle(m *Message) {
+	switch m.Type {
+	case "response":
+		c.lock.Lock()
+		cb := c.pending[m.RequestSeq]
+		delete(c.pending, m.RequestSeq)
+		c.lock.Unlock()
+		if cb != nil {
+			c.Dispatch(func() { cb(m) })
+		}
+	case "event":
+		if c.OnEvent != nil {
+			c.Dispatch(func() { c.OnEvent(m) })
+		}
+	case "request":
+		c.Dispatch(func() {
+			var body interface{}
+			err := errors.New("Unsupported request " + m.Command)
+			if c.OnRequest != nil {
+				body, err = c.OnRequest(m)
+			}
+			resp := &Message{Type: "response", RequestSeq: m.Seq, Command: m.Command, Success: err == nil}
+			if err != nil {
+				resp.Message = err.Error()
+			} else if body != nil {
+				resp.Body, _ = json.Marshal(body)
+			}
+			c.send(resp)
+		})
+	}
+}
+
+// send sends m, after giving it the next sequence number, which it returns
+func (c *Client) send(m *Message) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return 0, errors.New("The debug adapter is not running")
+	}
+	c.seq++
+	m.Seq = c.seq
+	return m.Seq, WriteMessage(c.conn, m)
+}
+
+// Request sends the request command with the given arguments, and calls cb
+// with its response if it is not nil
+func (c *Client) Request(command string, args interface{}, cb func(resp *Message)) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return errors.New("The debug adapter is not running")
+	}
+	c.seq++
+	m := &Message{Seq: c.seq, Type: "request", Command: command, Arguments: args}
+	c.pending[m.Seq] = cb
+	if err := WriteMessage(c.conn, m); err != nil {
+		delete(c.pending, m.Seq)
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the adapter and kills it if it was
+// started by the client
+func (c *Client) Close() {
+	c.lock.Lock()
+	c.closed = true
+	c.lock.Unlock()
+	c.conn.Close()
+	if c.cmd != nil && c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,133 @@
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessages(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteMessage(&buf, &Message{Seq: 1, Type: "request", Command: "next", Arguments: ThreadArguments{3}}))
+	assert.Equal(t, `Content-Length: 70`+"\r\n\r\n"+`{"seq":1,"type":"request","command":"next","arguments":{"threadId":3}}`, buf.String())
+
+	buf.WriteString("Content-Length: 42\r\nContent-Type: application/json\r\n\r\n")
+	buf.WriteString(`{"seq":2,"type":"event","event":"stopped"}`)
+	r := bufio.NewReader(&buf)
+	m, err := ReadMessage(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "next", m.Command)
+	m, err = ReadMessage(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "stopped", m.Event)
+	_, err = ReadMessage(r)
+	assert.NotNil(t, err)
+
+	resp := &Message{Type: "response", Command: "launch", Body: json.RawMessage(`{"error":{"format":"no program"}}`)}
+	assert.Equal(t, "launch: no program", resp.Err().Error())
+	resp.Success = true
+	assert.Nil(t, resp.Err())
+}
+
+// fakeAdapter answers the requests read from conn with handle, until it
+// returns false
+func fakeAdapter(conn net.Conn, handle func(m *Message, w func(*Message)) bool) {
+	r := bufio.NewReader(conn)
+	w := func(m *Message) { WriteMessage(conn, m) }
+	for {
+		m, err := ReadMessage(r)
+		if err != nil || !handle(m, w) {
+			conn.Close()
+			return
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	server, conn := net.Pipe()
+	go fakeAdapter(server, func(m *Message, w func(*Message)) bool {
+		switch m.Command {
+		case "initialize":
+			w(&Message{Type: "response", RequestSeq: m.Seq, Command: m.Command, Success: true, Body: json.RawMessage(`{"supportsConfigurationDoneRequest":true}`)})
+			w(&Message{Type: "event", Event: "initialized"})
+			// a reverse request
+			w(&Message{Seq: 5, Type: "request", Command: "runInTerminal"})
+		case "runInTerminal":
+			assert.False(t, m.Success)
+			assert.Equal(t, 5, m.RequestSeq)
+		case "disconnect":
+			return false
+		}
+		return true
+	})
+
+	c := NewClient(conn)
+	events := make(chan string, 10)
+	c.OnEvent = func(e *Message) { events <- e.Event }
+	c.OnClose = func(err error) { events <- "closed" }
+	go c.Run()
+
+	var caps Capabilities
+	assert.Nil(t, c.Request("initialize", InitializeArguments{ClientID: "micro"}, func(resp *Message) {
+		assert.Nil(t, resp.Err())
+		assert.Nil(t, resp.Decode(&caps))
+		events <- "initialize"
+	}))
+	assert.Equal(t, "initialize", <-events)
+	assert.True(t, caps.SupportsConfigurationDoneRequest)
+	assert.Equal(t, "initialized", <-events)
+
+	// the pending requests fail when the adapter exits
+	assert.Nil(t, c.Request("disconnect", nil, func(resp *Message) {
+		assert.NotNil(t, resp.Err())
+		events <- "disconnect"
+	}))
+	assert.Equal(t, "disconnect", <-events)
+	assert.Equal(t, "closed", <-events)
+	assert.NotNil(t, c.Request("next", nil, nil))
+}
+
+func TestLoadConfigurations(t *testing.T) {
+	root, err := ioutil.TempDir("", "micro-dap")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	configs, err := LoadConfigurations(root)
+	assert.Nil(t, err)
+	assert.Empty(t, configs)
+
+	settings := `{
+		"tabsize": 4,
+		"debug": [
+			{"name": "tests", "adapter": "dlv dap -l 127.0.0.1:4711", "address": "127.0.0.1:4711", "mode": "test", "program": "%d"},
+			{"adapter": "python3 -m debugpy.adapter", "request": "attach", "connect": {"port": 5678}},
+		],
+	}`
+	path := filepath.Join(root, ".micro.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(settings), 0644))
+	configs, err = LoadConfigurations(root)
+	assert.Nil(t, err)
+	assert.Equal(t, []Configuration{
+		{"tests", "dlv dap -l 127.0.0.1:4711", "127.0.0.1:4711", "launch", map[string]interface{}{"mode": "test", "program": "%d"}},
+		{"attach2", "python3 -m debugpy.adapter", "", "attach", map[string]interface{}{"connect": map[string]interface{}{"port": 5678.0}}},
+	}, configs)
+
+	c := configs[0].Expand(func(s string) string { return strings.Replace(s, "%d", "/src", -1) })
+	assert.Equal(t, "/src", c.Arguments["program"])
+	assert.Equal(t, "%d", configs[0].Arguments["program"])
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"debug": [{"name": "x", "request": "run", "adapter": "a"}]}`), 0644))
+	_, err = LoadConfigurations(root)
+	assert.NotNil(t, err)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"debug": [{"name": "x"}]}`), 0644))
+	_, err = LoadConfigurations(root)
+	assert.NotNil(t, err)
+}
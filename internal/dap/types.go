@@ -0,0 +1,131 @@
+package dap
+
+// The types of the bodies of the messages used by the client. The lines
+// and columns are numbered from 1, which is asked in the initialize
+// request.
+
+// InitializeArguments are the arguments of the initialize request
+type InitializeArguments struct {
+	ClientID        string `json:"clientID"`
+	ClientName      string `json:"clientName"`
+	AdapterID       string `json:"adapterID"`
+	LinesStartAt1   bool   `json:"linesStartAt1"`
+	ColumnsStartAt1 bool   `json:"columnsStartAt1"`
+	PathFormat      string `json:"pathFormat"`
+}
+
+// Capabilities are the features of the adapter, given in the response to
+// the initialize request
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+// A Source is a file of the program being debugged
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// A SourceBreakpoint is a breakpoint set on a line of a source
+type SourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+// SetBreakpointsArguments are the arguments of the setBreakpoints request,
+// which replaces all the breakpoints of a source
+type SetBreakpointsArguments struct {
+	Source      Source             `json:"source"`
+	Breakpoints []SourceBreakpoint `json:"breakpoints"`
+}
+
+// A Breakpoint is a breakpoint as the adapter set it, which may be on
+// another line than the one asked for
+type Breakpoint struct {
+	Verified bool   `json:"verified"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+}
+
+// A Thread is a thread of the program
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// StoppedEvent is the body of the stopped event
+type StoppedEvent struct {
+	Reason            string `json:"reason"`
+	Description       string `json:"description"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+	Text              string `json:"text"`
+}
+
+// OutputEvent is the body of the output event, which category is console,
+// stdout, stderr or telemetry
+type OutputEvent struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+// ExitedEvent is the body of the exited event
+type ExitedEvent struct {
+	ExitCode int `json:"exitCode"`
+}
+
+// ThreadArguments are the arguments of the requests acting on a thread,
+// such as continue, next, stepIn, stepOut and pause
+type ThreadArguments struct {
+	ThreadID int `json:"threadId"`
+}
+
+// StackTraceArguments are the arguments of the stackTrace request
+type StackTraceArguments struct {
+	ThreadID int `json:"threadId"`
+	Levels   int `json:"levels,omitempty"`
+}
+
+// A StackFrame is a frame of the stack of a thread
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+// A Scope is a group of variables of a frame, such as the locals
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+// A Variable is a variable of a scope, or a field of another one if its
+// parent has a variables reference
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// EvaluateArguments are the arguments of the evaluate request
+type EvaluateArguments struct {
+	Expression string `json:"expression"`
+	FrameID    int    `json:"frameId,omitempty"`
+	Context    string `json:"context"`
+}
+
+// EvaluateResponse is the body of the response to the evaluate request
+type EvaluateResponse struct {
+	Result             string `json:"result"`
+	Type               string `json:"type"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// DisconnectArguments are the arguments of the disconnect request
+type DisconnectArguments struct {
+	TerminateDebuggee bool `json:"terminateDebuggee"`
+}
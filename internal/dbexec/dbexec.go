@@ -0,0 +1,178 @@
+// Package dbexec runs SQL statements with the command line client of a
+// database and formats their results as tables, for the dbexec command
+package dbexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// Formats are the formats of the output of the clients which are shown as
+// tables, and text the output shown as it is
+var Formats = []string{"csv", "text", "tsv"}
+
+// Statement returns the start and the end offsets of the statement of the
+// SQL text containing the offset, without its semicolon and the blanks
+// around it. The semicolons of strings, quoted names and comments don't end
+// statements. An offset after the last statement is in it.
+func Statement(text string, offset int) (int, int) {
+	start := 0
+	for _, end := range ends(text) {
+		if offset <= end {
+			return trim(text, start, end)
+		}
+		start = end + 1
+	}
+	s, e := trim(text, start, len(text))
+	if s == e && start > 0 {
+		// after the last statement
+		return Statement(text, start-1)
+	}
+	return s, e
+}
+
+// ends returns the offsets of the semicolons ending the statements of text
+func ends(text string) []int {
+	var semicolons []int
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			if j := strings.IndexByte(text[i+1:], c); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(text)
+			}
+		case strings.HasPrefix(text[i:], "--"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			if j := strings.Index(text[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(text)
+			}
+		case c == ';':
+			semicolons = append(semicolons, i)
+		}
+	}
+	return semicolons
+}
+
+func trim(text string, start, end int) (int, int) {
+	for start < end && strings.ContainsRune(" \t\r\n", rune(text[start])) {
+		start++
+	}
+	for end > start && strings.ContainsRune(" \t\r\n", rune(text[end-1])) {
+		end--
+	}
+	return start, end
+}
+
+// Run runs the client command in dir with the statement on its standard
+// input, and returns its output. The client is killed when ctx is done.
+func Run(ctx context.Context, command []string, dir, statement string) (string, error) {
+	if len(command) == 0 {
+		return "", errors.New("No database command")
+	}
+	c := exec.CommandContext(ctx, command[0], command[1:]...)
+	c.Dir = dir
+	c.Stdin = strings.NewReader(statement + ";\n")
+	var stdout, stderr bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, &stderr
+	err := c.Run()
+	if ctx.Err() != nil {
+		return stdout.String(), ctx.Err()
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), errors.New(msg)
+		}
+		return stdout.String(), err
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" && stdout.Len() == 0 {
+		// the clients which print the errors of the statements and exit
+		// successfully
+		return "", errors.New(msg)
+	}
+	return stdout.String(), nil
+}
+
+// Parse returns the rows of the output of a client in the given format,
+// the first one being the names of the columns, or nil for the text format
+func Parse(out, format string) ([][]string, error) {
+	switch format {
+	case "csv":
+		r := csv.NewReader(strings.NewReader(out))
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		return r.ReadAll()
+	case "tsv":
+		var rows [][]string
+		for _, l := range strings.Split(strings.TrimRight(out, "\r\n"), "\n") {
+			if l != "" {
+				rows = append(rows, strings.Split(strings.TrimRight(l, "\r"), "\t"))
+			}
+		}
+		return rows, nil
+	}
+	return nil, nil
+}
+
+// Table returns the rows aligned in columns, separated by | and with a line
+// under the first row, which names the columns
+func Table(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = util.Max(widths[i], width(cell))
+		}
+	}
+
+	var b strings.Builder
+	for y, row := range rows {
+		var line strings.Builder
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = strings.Replace(row[i], "\n", " ", -1)
+			}
+			if i > 0 {
+				line.WriteString(" | ")
+			}
+			line.WriteString(cell + strings.Repeat(" ", w-width(cell)))
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+		if y == 0 {
+			for i, w := range widths {
+				if i > 0 {
+					b.WriteString("-+-")
+				}
+				b.WriteString(strings.Repeat("-", w))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// width returns the width of the cell on the screen
+func width(cell string) int {
+	w := 0
+	for _, r := range cell {
+		if r == '\n' {
+			r = ' '
+		}
+		w += util.CharacterWidth(r)
+	}
+	return w
+}
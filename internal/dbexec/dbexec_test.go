@@ -0,0 +1,66 @@
+package dbexec
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatement(t *testing.T) {
+	text := "select 1;\n\n-- a comment; here\nselect ';' as \"a;b\"\n  from t /* ; */ ;\nselect 3;\n"
+	stmt := func(offset int) string {
+		s, e := Statement(text, offset)
+		return text[s:e]
+	}
+	assert.Equal(t, "select 1", stmt(0))
+	assert.Equal(t, "select 1", stmt(8))
+	second := "-- a comment; here\nselect ';' as \"a;b\"\n  from t /* ; */"
+	assert.Equal(t, second, stmt(9))
+	assert.Equal(t, second, stmt(strings.Index(text, "from")))
+	assert.Equal(t, "select 3", stmt(strings.Index(text, "3")))
+	// after the last statement
+	assert.Equal(t, "select 3", stmt(len(text)))
+	assert.Equal(t, "select 4", func() string {
+		s, e := Statement("select 4", 3)
+		return "select 4"[s:e]
+	}())
+}
+
+func TestParse(t *testing.T) {
+	rows, err := Parse("id,name\n1,\"Smith, J\"\n2,\n", "csv")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"id", "name"}, {"1", "Smith, J"}, {"2", ""}}, rows)
+
+	rows, err = Parse("id\tname\r\n1\tab\r\n", "tsv")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"id", "name"}, {"1", "ab"}}, rows)
+
+	rows, err = Parse("anything", "text")
+	assert.Nil(t, err)
+	assert.Nil(t, rows)
+}
+
+func TestTable(t *testing.T) {
+	assert.Equal(t, ""+
+		"id | name     | city\n"+
+		"---+----------+-----\n"+
+		"1  | Smith, J | 東京\n"+
+		"22 | a b      |\n", Table([][]string{{"id", "name", "city"}, {"1", "Smith, J", "東京"}, {"22", "a\nb"}}))
+}
+
+func TestRun(t *testing.T) {
+	out, err := Run(context.Background(), []string{"cat"}, "", "select 1")
+	assert.Nil(t, err)
+	assert.Equal(t, "select 1;\n", out)
+
+	_, err = Run(context.Background(), []string{"sh", "-c", "echo 'no such table' >&2; exit 1"}, "", "select 1")
+	assert.EqualError(t, err, "no such table")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = Run(ctx, []string{"sleep", "10"}, "", "")
+	assert.Equal(t, context.Canceled, err)
+}
@@ -2,11 +2,13 @@ package display
 
 import (
 	"strconv"
+	"strings"
 
 	runewidth "github.com/mattn/go-runewidth"
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/config"
 	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/spell"
 	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/tcell/v2"
 )
@@ -29,6 +31,15 @@ type BufWindow struct {
 	hasMessage       bool
 	maxLineNumLength int
 	drawDivider      bool
+
+	// lines are the lines drawn in the last frame, which are not redrawn
+	// if they did not change
+	lines lineCache
+	image imageState
+
+	// Settings are the options set for this window only, which override
+	// the ones of its buffer (see config.PaneSettings)
+	Settings map[string]interface{}
 }
 
 // NewBufWindow creates a new window at a location in the screen with a width and height
@@ -47,17 +58,8 @@ func NewBufWindow(x, y, width, height int, buf *buffer.Buffer) *BufWindow {
 func (w *BufWindow) SetBuffer(b *buffer.Buffer) {
 	w.Buf = b
 	b.OptionCallback = func(option string, nativeValue interface{}) {
-		if option == "softwrap" {
-			if nativeValue.(bool) {
-				w.StartCol = 0
-			} else {
-				w.StartLine.Row = 0
-			}
-			w.Relocate()
-
-			for _, c := range w.Buf.GetCursors() {
-				c.LastVisualX = c.GetVisualX()
-			}
+		if _, ok := w.Settings[option]; option == "softwrap" && !ok {
+			w.softwrapChanged()
 		}
 	}
 	b.GetVisualX = func(loc buffer.Loc) int {
@@ -65,6 +67,50 @@ func (w *BufWindow) SetBuffer(b *buffer.Buffer) {
 	}
 }
 
+// softwrapChanged scrolls the window again after the softwrap option
+// changed
+func (w *BufWindow) softwrapChanged() {
+	if w.Setting("softwrap").(bool) {
+		w.StartCol = 0
+	} else {
+		w.StartLine.Row = 0
+	}
+	w.Relocate()
+
+	for _, c := range w.Buf.GetCursors() {
+		c.LastVisualX = c.GetVisualX()
+	}
+}
+
+// Setting returns the value of the option in the window: the value set for
+// the window if there is one, or else the value of its buffer
+func (w *BufWindow) Setting(option string) interface{} {
+	if v, ok := w.Settings[option]; ok {
+		return v
+	}
+	return w.Buf.Settings[option]
+}
+
+// SetOption sets the option for the window only, or back to the value of
+// its buffer if value is nil
+func (w *BufWindow) SetOption(option string, value interface{}) {
+	wrap := w.Setting("softwrap")
+	if value == nil {
+		delete(w.Settings, option)
+	} else {
+		if w.Settings == nil {
+			w.Settings = make(map[string]interface{})
+		}
+		w.Settings[option] = value
+	}
+	if option == "diffgutter" && value == true {
+		w.Buf.UpdateDiffBase()
+	}
+	if w.Setting("softwrap") != wrap {
+		w.softwrapChanged()
+	}
+}
+
 func (w *BufWindow) GetView() *View {
 	return w.View
 }
@@ -79,7 +125,7 @@ func (w *BufWindow) Resize(width, height int) {
 
 	w.Relocate()
 
-	if w.Buf.Settings["softwrap"].(bool) {
+	if w.Setting("softwrap").(bool) {
 		for _, c := range w.Buf.GetCursors() {
 			c.LastVisualX = c.GetVisualX()
 		}
@@ -128,25 +174,26 @@ func (w *BufWindow) updateDisplayInfo() {
 		w.bufHeight--
 	}
 
-	w.hasMessage = len(b.Messages) > 0
+	_, stopped := b.StoppedLine()
+	w.hasMessage = len(b.Messages) > 0 || b.HasBookmarks() || b.HasBreakpoints() || stopped
 
 	// We need to know the string length of the largest line number
 	// so we can pad appropriately when displaying line numbers
-	w.maxLineNumLength = len(strconv.Itoa(b.LinesNum()))
+	w.maxLineNumLength = len(strconv.Itoa(b.FileLine(b.LinesNum()-1) + 1))
 
 	w.gutterOffset = 0
 	if w.hasMessage {
 		w.gutterOffset += 2
 	}
-	if b.Settings["diffgutter"].(bool) {
+	if w.Setting("diffgutter").(bool) {
 		w.gutterOffset++
 	}
-	if b.Settings["ruler"].(bool) {
+	if w.Setting("ruler").(bool) {
 		w.gutterOffset += w.maxLineNumLength + 1
 	}
 
 	w.bufWidth = w.Width - w.gutterOffset
-	if w.Buf.Settings["scrollbar"].(bool) && w.Buf.LinesNum() > w.Height {
+	if w.hasScrollBar() {
 		w.bufWidth--
 	}
 }
@@ -172,7 +219,7 @@ func (w *BufWindow) getStartInfo(n, lineN int) ([]byte, int, int, *tcell.Style)
 			ts := tabsize - (width % tabsize)
 			w = ts
 		default:
-			w = runewidth.RuneWidth(r)
+			w = util.CharacterWidth(r)
 		}
 		if width+w > n {
 			return b, n - width, bloc.X, s
@@ -199,9 +246,11 @@ func (w *BufWindow) Clear() {
 func (w *BufWindow) Relocate() bool {
 	b := w.Buf
 	height := w.bufHeight
-	ret := false
+	// the cursors don't stay in folded lines
+	ret := b.UnfoldCursors()
 	activeC := w.Buf.GetActiveCursor()
-	scrollmargin := int(b.Settings["scrollmargin"].(float64))
+	// the cursor can't be kept further than the middle of the window
+	scrollmargin := util.Min(util.IntOpt(b.Settings["scrolloff"]), (height-1)/2)
 
 	c := w.SLocFromLoc(activeC.Loc)
 	bStart := SLoc{0, 0}
@@ -223,19 +272,23 @@ func (w *BufWindow) Relocate() bool {
 	}
 
 	// horizontal relocation (scrolling)
-	if !b.Settings["softwrap"].(bool) {
-		cx := activeC.GetVisualX()
-		rw := runewidth.RuneWidth(activeC.RuneUnder(activeC.X))
-		if rw == 0 {
-			rw = 1 // tab or newline
+	if !w.Setting("softwrap").(bool) {
+		cx := activeC.GetVisualX() + activeC.Virtual
+		// a tab or a line end takes one cell
+		rw := util.CharacterWidth(activeC.RuneUnder(activeC.X))
+
+		width := w.Width - w.gutterOffset
+		sidescrolloff := util.Min(util.IntOpt(b.Settings["sidescrolloff"]), (width-1)/2)
+		if sidescrolloff < 0 {
+			sidescrolloff = 0
 		}
 
-		if cx < w.StartCol {
-			w.StartCol = cx
+		if cx-sidescrolloff < w.StartCol && w.StartCol > 0 {
+			w.StartCol = util.Max(cx-sidescrolloff, 0)
 			ret = true
 		}
-		if cx+w.gutterOffset+rw > w.StartCol+w.Width {
-			w.StartCol = cx - w.Width + w.gutterOffset + rw
+		if cx+rw+sidescrolloff > w.StartCol+width {
+			w.StartCol = cx + rw + sidescrolloff - width
 			ret = true
 		}
 	}
@@ -258,19 +311,59 @@ func (w *BufWindow) LocFromVisual(svloc buffer.Loc) buffer.Loc {
 	return w.LocFromVLoc(vloc)
 }
 
+// ScreenLoc returns the position on the screen of a location in the buffer,
+// and false if the location is scrolled out of the window
+func (w *BufWindow) ScreenLoc(loc buffer.Loc) (int, int, bool) {
+	vloc := w.VLocFromLoc(loc)
+	x := w.X + w.gutterOffset + vloc.VisualX - w.StartCol
+	y := w.Y + w.Diff(w.StartLine, vloc.SLoc)
+	if x < w.X+w.gutterOffset || x >= w.X+w.gutterOffset+w.bufWidth || y < w.Y || y >= w.Y+w.bufHeight {
+		return x, y, false
+	}
+	return x, y, true
+}
+
 func (w *BufWindow) drawGutter(vloc *buffer.Loc, bloc *buffer.Loc) {
 	char := ' '
 	s := config.DefStyle
-	for _, m := range w.Buf.Messages {
-		if m.Start.Y == bloc.Y || m.End.Y == bloc.Y {
-			s = m.Style()
-			char = '>'
-			break
+	second := char
+	y, stopped := w.Buf.StoppedLine()
+	stopped = stopped && y == bloc.Y
+	if w.Buf.IsBreakpoint(bloc.Y) || stopped {
+		// a breakpoint, and an arrow on the line where the debugger is
+		// stopped
+		if style, ok := config.Colorscheme["breakpoint"]; ok {
+			s = style
+		} else if style, ok := config.Colorscheme["gutter-error"]; ok {
+			s = style
+		}
+		if w.Buf.IsBreakpoint(bloc.Y) {
+			char = '*'
+		} else {
+			char = '='
+		}
+		if stopped {
+			second = '>'
+		}
+	} else if m := w.Buf.LineMessage(bloc.Y); m != nil {
+		s = m.Style()
+		char, second = '>', '>'
+	} else if names := w.Buf.BookmarksAt(bloc.Y); len(names) > 0 {
+		// the start of the name of a bookmark
+		if style, ok := config.Colorscheme["bookmark"]; ok {
+			s = style
+		} else if style, ok := config.Colorscheme["line-number"]; ok {
+			s = style
+		}
+		name := []rune(names[0])
+		char = name[0]
+		if len(name) > 1 {
+			second = name[1]
 		}
 	}
 	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, char, nil, s)
 	vloc.X++
-	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, char, nil, s)
+	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, second, nil, s)
 	vloc.X++
 }
 
@@ -305,8 +398,8 @@ func (w *BufWindow) drawDiffGutter(backgroundStyle tcell.Style, softwrapped bool
 func (w *BufWindow) drawLineNum(lineNumStyle tcell.Style, softwrapped bool, vloc *buffer.Loc, bloc *buffer.Loc) {
 	cursorLine := w.Buf.GetActiveCursor().Loc.Y
 	var lineInt int
-	if w.Buf.Settings["relativeruler"] == false || cursorLine == bloc.Y {
-		lineInt = bloc.Y + 1
+	if w.Setting("relativeruler") == false || cursorLine == bloc.Y {
+		lineInt = w.Buf.FileLine(bloc.Y) + 1
 	} else {
 		lineInt = bloc.Y - cursorLine
 	}
@@ -327,11 +420,54 @@ func (w *BufWindow) drawLineNum(lineNumStyle tcell.Style, softwrapped bool, vloc
 		vloc.X++
 	}
 
-	// Write the extra space
-	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, ' ', nil, lineNumStyle)
+	// Write the extra space, which marks the folded lines
+	extra := ' '
+	if _, ok := w.Buf.IsFolded(bloc.Y); ok && !softwrapped {
+		extra = '+'
+	}
+	screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, extra, nil, lineNumStyle)
 	vloc.X++
 }
 
+// drawFoldSummary draws after the end of a folded line, from the column of
+// vloc, the number of lines it hides
+func (w *BufWindow) drawFoldSummary(vloc buffer.Loc, maxWidth, n int) {
+	style := config.DefStyle
+	if s, ok := config.Colorscheme["fold"]; ok {
+		style = s
+	} else if s, ok := config.Colorscheme["comment"]; ok {
+		style = s
+	}
+	summary := " ... " + strconv.Itoa(n) + " lines "
+	if n == 1 {
+		summary = " ... 1 line "
+	}
+	for _, r := range summary {
+		if vloc.X >= maxWidth || vloc.Y < 0 {
+			break
+		}
+		screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, r, nil, style)
+		vloc.X++
+	}
+}
+
+// foldEndStyle returns the highlighting style at the end of the lines
+// hidden by the fold of the line start, up to the line end, which carries
+// over to the line after them
+func (w *BufWindow) foldEndStyle(style tcell.Style, start, end int) tcell.Style {
+	for y := end; y > start; y-- {
+		last := -1
+		for x := range w.Buf.Match(y) {
+			last = util.Max(last, x)
+		}
+		if last >= 0 {
+			style, _ = w.getStyle(style, buffer.Loc{X: last, Y: y})
+			return style
+		}
+	}
+	return style
+}
+
 // getStyle returns the highlight style for the given character position
 // If there is no change to the current highlight style it just returns that
 func (w *BufWindow) getStyle(style tcell.Style, bloc buffer.Loc) (tcell.Style, bool) {
@@ -363,7 +499,7 @@ func (w *BufWindow) displayBuffer() {
 	maxWidth := w.gutterOffset + w.bufWidth
 
 	if b.ModifiedThisFrame {
-		if b.Settings["diffgutter"].(bool) {
+		if w.Setting("diffgutter").(bool) {
 			b.UpdateDiff(func(synchronous bool) {
 				// If the diff was updated asynchronously, the outer call to
 				// displayBuffer might already be completed and we need to
@@ -377,32 +513,27 @@ func (w *BufWindow) displayBuffer() {
 				}
 			})
 		}
+		// the buffer it is compared with is diffed against its new text
+		if peer := b.DiffPeer(); peer != nil {
+			peer.SetDiffBase(b.Bytes())
+		}
 		b.ModifiedThisFrame = false
 	}
 
 	var matchingBraces []buffer.Loc
-	// bracePairs is defined in buffer.go
 	if b.Settings["matchbrace"].(bool) {
-		for _, bp := range buffer.BracePairs {
-			for _, c := range b.GetCursors() {
-				if c.HasSelection() {
-					continue
-				}
-				curX := c.X
-				curLoc := c.Loc
-
-				r := c.RuneUnder(curX)
-				rl := c.RuneUnder(curX - 1)
-				if r == bp[0] || r == bp[1] || rl == bp[0] || rl == bp[1] {
-					mb, left, found := b.FindMatchingBrace(bp, curLoc)
-					if found {
-						matchingBraces = append(matchingBraces, mb)
-						if !left {
-							matchingBraces = append(matchingBraces, curLoc)
-						} else {
-							matchingBraces = append(matchingBraces, curLoc.Move(-1, b))
-						}
-					}
+		for _, c := range b.GetCursors() {
+			if c.HasSelection() {
+				continue
+			}
+			from, to, found := b.MatchBrace(c.Loc)
+			if !found {
+				continue
+			}
+			// a keyword is underlined along with the one matching it
+			for _, r := range [][2]buffer.Loc{from, to} {
+				for l := r[0]; l.LessThan(r[1]); l.X++ {
+					matchingBraces = append(matchingBraces, l)
 				}
 			}
 		}
@@ -414,18 +545,26 @@ func (w *BufWindow) displayBuffer() {
 	}
 	curNumStyle := config.DefStyle
 	if style, ok := config.Colorscheme["current-line-number"]; ok {
-		if !b.Settings["cursorline"].(bool) {
+		if !w.Setting("cursorline").(bool) {
 			curNumStyle = lineNumStyle
 		} else {
 			curNumStyle = style
 		}
 	}
 
-	softwrap := b.Settings["softwrap"].(bool)
-	wordwrap := softwrap && b.Settings["wordwrap"].(bool)
+	softwrap := w.Setting("softwrap").(bool)
+	wordwrap := softwrap && w.Setting("wordwrap").(bool)
 
 	tabsize := util.IntOpt(b.Settings["tabsize"])
-	colorcolumn := util.IntOpt(b.Settings["colorcolumn"])
+	colorcolumns, _ := config.ColorColumns(w.Setting("colorcolumn"))
+	isColorColumn := func(col int) bool {
+		for _, c := range colorcolumns {
+			if c == col {
+				return true
+			}
+		}
+		return false
+	}
 
 	// this represents the current draw position
 	// within the current window
@@ -440,15 +579,139 @@ func (w *BufWindow) displayBuffer() {
 
 	cursors := b.GetCursors()
 
+	// matches of the last search on the line being drawn, if they are
+	// highlighted
+	searchRegex := b.SearchHighlightRegex()
+	searchLine := -1
+	var searchMatches [][2]int
+	searchStyle := config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorOlive)
+	if s, ok := config.Colorscheme["hlsearch"]; ok {
+		searchStyle = s
+	}
+
+	// messages on the line being drawn, which are underlined
+	msgLine := -1
+	var lineMsgs []*buffer.Message
+
+	// misspelled words on the line being drawn
+	spellLine := -1
+	var misspellings []spell.Word
+	spellStyle, hasSpellStyle := config.Colorscheme["spell-error"]
+
+	// colors written on the line being drawn, which are drawn on their
+	// color
+	swatchLine := -1
+	var swatches []buffer.ColorLiteral
+
+	// changes within the line being drawn, in a diff view
+	diffLine := -1
+	var diffChanges []buffer.DiffChange
+	diffStyle := config.DefStyle.Reverse(true)
+	if s, ok := config.Colorscheme["diff-text"]; ok {
+		diffStyle = s
+	}
+
+	// the visual columns of the cursors in the window, if they are
+	// highlighted
+	var cursorCols []int
+	var cursorColumnBg tcell.Color
+	if b.Settings["cursorcolumn"].(bool) && w.active {
+		s, ok := config.Colorscheme["cursor-column"]
+		if !ok {
+			s, ok = config.Colorscheme["cursor-line"]
+		}
+		if ok {
+			cursorColumnBg, _, _ = s.Decompose()
+			for _, c := range cursors {
+				if !c.HasSelection() {
+					cursorCols = append(cursorCols, w.VLocFromLoc(c.Loc).VisualX-w.StartCol)
+				}
+			}
+		}
+	}
+	isCursorColumn := func(x int) bool {
+		for _, c := range cursorCols {
+			if c == x {
+				return true
+			}
+		}
+		return false
+	}
+
+	showbreakStyle := config.DefStyle
+	if s, ok := config.Colorscheme["indent-char"]; ok {
+		fg, _, _ := s.Decompose()
+		showbreakStyle = showbreakStyle.Foreground(fg)
+	}
+
+	var guides *indentGuides
+	var guideChar rune
+	var guideStyle, activeGuideStyle tcell.Style
+	if b.Settings["indentguides"].(bool) && !config.GetGlobalOption("screenreader").(bool) {
+		guides = newIndentGuides(b, tabsize)
+		guideChar = '│'
+		if runes := []rune(b.Settings["indentguidechar"].(string)); len(runes) > 0 {
+			guideChar = runes[0]
+		}
+		guideStyle = config.DefStyle
+		if s, ok := config.Colorscheme["indent-guide"]; ok {
+			guideStyle = s
+		} else if s, ok := config.Colorscheme["indent-char"]; ok {
+			guideStyle = s
+		}
+		activeGuideStyle = config.DefStyle
+		if s, ok := config.Colorscheme["indent-guide-active"]; ok {
+			activeGuideStyle = s
+		}
+	}
+	// guideAt returns the style of the indent guide drawn at the visual
+	// column x of the line being drawn, if there is one
+	guideAt := func(x int) (tcell.Style, bool) {
+		g, active := guides.at(bloc.Y, x-w.gutterOffset+w.StartCol)
+		if active {
+			return activeGuideStyle, true
+		}
+		return guideStyle, g
+	}
+
+	w.lines.start(w.windowKey(matchingBraces, cursorCols, guides))
+	defer w.lines.finish()
+
 	curStyle := config.DefStyle
 	for ; vloc.Y < w.bufHeight; vloc.Y++ {
 		vloc.X = 0
 
 		currentLine := false
+		hasCursor := false
 		for _, c := range cursors {
-			if bloc.Y == c.Y && w.active {
-				currentLine = true
-				break
+			if bloc.Y == c.Y {
+				hasCursor = true
+				if w.active {
+					currentLine = true
+				}
+			}
+		}
+
+		// the lines with a cursor are always drawn, so that it is shown
+		lineStart, lineStyle := vloc.Y, curStyle
+		var sig uint64
+		if !hasCursor {
+			sig = w.lineSignature(bloc.Y, matchingBraces, guides)
+			if l, ok := w.lines.get(bloc.Y, vloc.Y, sig, curStyle); ok {
+				w.lines.put(bloc.Y, l)
+				vloc.Y += l.rows - 1
+				curStyle = l.endStyle
+
+				bloc.X = w.StartCol
+				if end, ok := b.IsFolded(bloc.Y); ok {
+					curStyle = w.foldEndStyle(curStyle, bloc.Y, end)
+					bloc.Y = end
+				}
+				bloc.Y++
+				if bloc.Y >= b.LinesNum() {
+					break
+				}
+				continue
 			}
 		}
 
@@ -462,11 +725,11 @@ func (w *BufWindow) displayBuffer() {
 				w.drawGutter(&vloc, &bloc)
 			}
 
-			if b.Settings["diffgutter"].(bool) {
+			if w.Setting("diffgutter").(bool) {
 				w.drawDiffGutter(s, false, &vloc, &bloc)
 			}
 
-			if b.Settings["ruler"].(bool) {
+			if w.Setting("ruler").(bool) {
 				w.drawLineNum(s, false, &vloc, &bloc)
 			}
 		} else {
@@ -479,15 +742,69 @@ func (w *BufWindow) displayBuffer() {
 		}
 		bloc.X = bslice
 
+		// guides are only drawn on the first row of wrapped lines
+		wrapped := false
+
+		// continuation rows start at rowStart, after wrapPrefix
+		rowStart := w.gutterOffset
+		wrapIndent := 0
+		var wrapPrefix []rune
+		if softwrap {
+			wrapIndent = w.wrapIndent(b.LineBytes(bloc.Y), tabsize)
+			showbreak := b.Settings["showbreak"].(string)
+			spaces := util.Max(wrapIndent-runewidth.StringWidth(showbreak), 0)
+			wrapPrefix = []rune(strings.Repeat(" ", spaces) + showbreak)
+		}
+
 		draw := func(r rune, combc []rune, style tcell.Style, highlight bool, showcursor bool) {
 			if nColsBeforeStart <= 0 && vloc.Y >= 0 {
 				if highlight {
+					if swatchLine != bloc.Y {
+						swatchLine = bloc.Y
+						swatches = b.ColorLiterals(bloc.Y)
+					}
+					for _, c := range swatches {
+						if bloc.X >= c.Start && bloc.X < c.End {
+							style = buffer.SwatchStyle(c.Color)
+							break
+						}
+					}
+
 					_, origBg, _ := style.Decompose()
 					_, defBg, _ := config.DefStyle.Decompose()
 
 					// syntax highlighting with non-default background takes precedence
 					// over cursor-line and color-column
 					dontOverrideBackground := origBg != defBg
+					// search matches and selections take precedence over the
+					// cursor-line and cursor-column
+					highlighted := false
+
+					if diffLine != bloc.Y {
+						diffLine = bloc.Y
+						diffChanges = b.DiffChanges(bloc.Y)
+					}
+					for _, c := range diffChanges {
+						if bloc.X >= c.Start && bloc.X < c.End {
+							style = diffStyle
+							highlighted = true
+							break
+						}
+					}
+
+					if searchRegex != nil {
+						if searchLine != bloc.Y {
+							searchLine = bloc.Y
+							searchMatches = b.LineMatches(searchRegex, bloc.Y)
+						}
+						for _, m := range searchMatches {
+							if bloc.X >= m[0] && bloc.X < m[1] {
+								style = searchStyle
+								highlighted = true
+								break
+							}
+						}
+					}
 
 					for _, c := range cursors {
 						if c.HasSelection() &&
@@ -499,26 +816,67 @@ func (w *BufWindow) displayBuffer() {
 							if s, ok := config.Colorscheme["selection"]; ok {
 								style = s
 							}
+							highlighted = true
 						}
+					}
 
-						if b.Settings["cursorline"].(bool) && w.active && !dontOverrideBackground &&
-							!c.HasSelection() && c.Y == bloc.Y {
-							if s, ok := config.Colorscheme["cursor-line"]; ok {
-								fg, _, _ := s.Decompose()
-								style = style.Background(fg)
+					if !dontOverrideBackground && !highlighted {
+						for _, c := range cursors {
+							if w.Setting("cursorline").(bool) && w.active &&
+								!c.HasSelection() && c.Y == bloc.Y {
+								if s, ok := config.Colorscheme["cursor-line"]; ok {
+									fg, _, _ := s.Decompose()
+									style = style.Background(fg)
+								}
 							}
 						}
+						if isCursorColumn(vloc.X - w.gutterOffset) {
+							style = style.Background(cursorColumnBg)
+						}
 					}
 
-					for _, m := range b.Messages {
-						if bloc.GreaterEqual(m.Start) && bloc.LessThan(m.End) ||
-							bloc.LessThan(m.End) && bloc.GreaterEqual(m.Start) {
+					if msgLine != bloc.Y {
+						msgLine = bloc.Y
+						lineMsgs = lineMsgs[:0]
+						for _, m := range b.Messages {
+							if m.Start.Y <= bloc.Y && m.End.Y >= bloc.Y {
+								lineMsgs = append(lineMsgs, m)
+							}
+						}
+					}
+					for _, m := range lineMsgs {
+						if bloc.GreaterEqual(m.Start) && bloc.LessThan(m.End) {
 							style = style.Underline(true)
 							break
 						}
 					}
 
-					if r == '\t' {
+					if spellLine != bloc.Y {
+						spellLine = bloc.Y
+						misspellings = b.Misspellings(bloc.Y)
+					}
+					for _, m := range misspellings {
+						if bloc.X >= m.Start && bloc.X < m.End {
+							style = style.Underline(true)
+							if hasSpellStyle {
+								fg, _, _ := spellStyle.Decompose()
+								style = style.Foreground(fg)
+							}
+							break
+						}
+					}
+
+					guide := false
+					if guides != nil && !wrapped && (r == ' ' || r == '\t') {
+						var gs tcell.Style
+						if gs, guide = guideAt(vloc.X); guide {
+							fg, _, _ := gs.Decompose()
+							style = style.Foreground(fg)
+							r = guideChar
+						}
+					}
+
+					if r == '\t' && !guide {
 						indentrunes := []rune(b.Settings["indentchar"].(string))
 						// if empty indentchar settings, use space
 						if len(indentrunes) == 0 {
@@ -533,7 +891,7 @@ func (w *BufWindow) displayBuffer() {
 					}
 
 					if s, ok := config.Colorscheme["color-column"]; ok {
-						if colorcolumn != 0 && vloc.X-w.gutterOffset+w.StartCol == colorcolumn && !dontOverrideBackground {
+						if isColorColumn(vloc.X-w.gutterOffset+w.StartCol) && !dontOverrideBackground {
 							fg, _, _ := s.Decompose()
 							style = style.Background(fg)
 						}
@@ -550,7 +908,7 @@ func (w *BufWindow) displayBuffer() {
 
 				if showcursor {
 					for _, c := range cursors {
-						if c.X == bloc.X && c.Y == bloc.Y && !c.HasSelection() {
+						if c.X == bloc.X && c.Y == bloc.Y && !c.HasSelection() && (c.Virtual == 0 || softwrap) {
 							w.showCursor(w.X+vloc.X, w.Y+vloc.Y, c.Num == 0)
 						}
 					}
@@ -564,17 +922,33 @@ func (w *BufWindow) displayBuffer() {
 
 		wrap := func() {
 			vloc.X = 0
+			wrapped = true
 			if w.hasMessage {
 				w.drawGutter(&vloc, &bloc)
 			}
-			if b.Settings["diffgutter"].(bool) {
+			if w.Setting("diffgutter").(bool) {
 				w.drawDiffGutter(lineNumStyle, true, &vloc, &bloc)
 			}
 
 			// This will draw an empty line number because the current line is wrapped
-			if b.Settings["ruler"].(bool) {
+			if w.Setting("ruler").(bool) {
 				w.drawLineNum(lineNumStyle, true, &vloc, &bloc)
 			}
+
+			// indent the continuation row and draw showbreak at its start
+			rowStart = w.gutterOffset + wrapIndent
+			for _, r := range wrapPrefix {
+				if vloc.X >= rowStart {
+					break
+				}
+				draw(r, nil, showbreakStyle, false, false)
+				for i := 1; i < runewidth.RuneWidth(r) && vloc.X < rowStart; i++ {
+					draw(' ', nil, showbreakStyle, false, false)
+				}
+			}
+			for vloc.X < rowStart {
+				draw(' ', nil, config.DefStyle, false, false)
+			}
 		}
 
 		type glyph struct {
@@ -608,7 +982,7 @@ func (w *BufWindow) displayBuffer() {
 				width = util.Min(ts, maxWidth-vloc.X)
 				totalwidth += ts
 			default:
-				width = runewidth.RuneWidth(r)
+				width = util.CharacterWidth(r)
 				totalwidth += width
 			}
 
@@ -618,13 +992,13 @@ func (w *BufWindow) displayBuffer() {
 			// Collect a complete word to know its width.
 			// If wordwrap is off, every single character is a complete "word".
 			if wordwrap {
-				if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth {
+				if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth-wrapIndent {
 					continue
 				}
 			}
 
 			// If a word (or just a wide rune) does not fit in the window
-			if vloc.X+wordwidth > maxWidth && vloc.X > w.gutterOffset {
+			if vloc.X+wordwidth > maxWidth && vloc.X > rowStart {
 				for vloc.X < maxWidth {
 					draw(' ', nil, config.DefStyle, false, false)
 				}
@@ -677,7 +1051,7 @@ func (w *BufWindow) displayBuffer() {
 
 		style := config.DefStyle
 		for _, c := range cursors {
-			if b.Settings["cursorline"].(bool) && w.active &&
+			if w.Setting("cursorline").(bool) && w.active &&
 				!c.HasSelection() && c.Y == bloc.Y {
 				if s, ok := config.Colorscheme["cursor-line"]; ok {
 					fg, _, _ := s.Decompose()
@@ -687,13 +1061,25 @@ func (w *BufWindow) displayBuffer() {
 		}
 		for i := vloc.X; i < maxWidth; i++ {
 			curStyle := style
+			if isCursorColumn(i - w.gutterOffset) {
+				curStyle = curStyle.Background(cursorColumnBg)
+			}
 			if s, ok := config.Colorscheme["color-column"]; ok {
-				if colorcolumn != 0 && i-w.gutterOffset+w.StartCol == colorcolumn {
+				if isColorColumn(i - w.gutterOffset + w.StartCol) {
 					fg, _, _ := s.Decompose()
 					curStyle = style.Background(fg)
 				}
 			}
-			screen.SetContent(i+w.X, vloc.Y+w.Y, ' ', nil, curStyle)
+			r := ' '
+			if guides != nil && !wrapped {
+				// the guides of blank lines go past their end
+				if gs, ok := guideAt(i); ok {
+					fg, _, _ := gs.Decompose()
+					curStyle = curStyle.Foreground(fg)
+					r = guideChar
+				}
+			}
+			screen.SetContent(i+w.X, vloc.Y+w.Y, r, nil, curStyle)
 		}
 
 		if vloc.X != maxWidth {
@@ -701,12 +1087,42 @@ func (w *BufWindow) displayBuffer() {
 			draw(' ', nil, config.DefStyle, true, true)
 		}
 
+		// the cursors past the end of the line, with virtualedit
+		for _, c := range cursors {
+			if c.Virtual > 0 && c.Y == bloc.Y && !c.HasSelection() && !softwrap {
+				x := w.gutterOffset + c.GetVisualX() + c.Virtual - w.StartCol
+				if x >= w.gutterOffset && x < maxWidth {
+					w.showCursor(w.X+x, w.Y+vloc.Y, c.Num == 0)
+				}
+			}
+		}
+
+		end, folded := b.IsFolded(bloc.Y)
+		if folded {
+			w.drawFoldSummary(vloc, maxWidth, end-bloc.Y)
+			curStyle = w.foldEndStyle(curStyle, bloc.Y, end)
+		}
+
+		if !hasCursor {
+			w.lines.put(bloc.Y, drawnLine{lineStart, vloc.Y - lineStart + 1, sig, lineStyle, curStyle})
+		}
+
 		bloc.X = w.StartCol
+		if folded {
+			bloc.Y = end
+		}
 		bloc.Y++
 		if bloc.Y >= b.LinesNum() {
 			break
 		}
 	}
+
+	// clear the rows after the end of the buffer
+	for y := vloc.Y + 1; y < w.bufHeight; y++ {
+		for x := 0; x < maxWidth; x++ {
+			screen.SetContent(w.X+x, w.Y+y, ' ', nil, config.DefStyle)
+		}
+	}
 }
 
 func (w *BufWindow) displayStatusLine() {
@@ -737,22 +1153,112 @@ func (w *BufWindow) displayStatusLine() {
 	}
 }
 
+// maxScrollBarSearch is the number of lines above which the search matches
+// are not marked on the scrollbar
+const maxScrollBarSearch = 20000
+
+// hasScrollBar returns whether the scrollbar is shown, which it isn't for
+// screen readers
+func (w *BufWindow) hasScrollBar() bool {
+	return w.Setting("scrollbar").(bool) && w.Buf.LinesNum() > w.Height &&
+		!config.GetGlobalOption("screenreader").(bool)
+}
+
+// scrollBarThumb returns the first row and the number of rows of the part
+// of the scrollbar showing the visible lines
+func (w *BufWindow) scrollBarThumb() (int, int) {
+	n := w.Buf.LinesNum()
+	size := util.Clamp(w.bufHeight*w.bufHeight/n, 1, w.bufHeight)
+	start := util.Clamp(w.StartLine.Line*w.bufHeight/n, 0, w.bufHeight-size)
+	return start, size
+}
+
+// ScrollBarLine returns the line at the row of the scrollbar at the screen
+// location x, y, and false if the scrollbar is not there
+func (w *BufWindow) ScrollBarLine(x, y int) (int, bool) {
+	if !w.hasScrollBar() || x != w.X+w.Width-1 || y < w.Y || y >= w.Y+w.bufHeight {
+		return 0, false
+	}
+	return (y - w.Y) * w.Buf.LinesNum() / w.bufHeight, true
+}
+
+// GutterLine returns the line shown at the screen location x, y if it is
+// in the gutter or the line numbers, and false otherwise
+func (w *BufWindow) GutterLine(x, y int) (int, bool) {
+	if x < w.X || x >= w.X+w.gutterOffset || y < w.Y || y >= w.Y+w.bufHeight {
+		return 0, false
+	}
+	loc := w.LocFromVisual(buffer.Loc{X: w.X + w.gutterOffset, Y: y})
+	if w.Diff(w.StartLine, w.SLocFromLoc(w.Buf.End())) < y-w.Y {
+		// below the end of the buffer
+		return 0, false
+	}
+	return loc.Y, true
+}
+
+// ScrollBarDrag returns the start line of the view which puts the middle
+// of the scrollbar thumb at the row y of the screen
+func (w *BufWindow) ScrollBarDrag(y int) int {
+	_, size := w.scrollBarThumb()
+	row := util.Clamp(y-w.Y-size/2, 0, w.bufHeight-size)
+	return util.Clamp(row*w.Buf.LinesNum()/w.bufHeight, 0, w.Buf.LinesNum()-1)
+}
+
 func (w *BufWindow) displayScrollBar() {
-	if w.Buf.Settings["scrollbar"].(bool) && w.Buf.LinesNum() > w.Height {
-		scrollX := w.X + w.Width - 1
-		barsize := int(float64(w.Height) / float64(w.Buf.LinesNum()) * float64(w.Height))
-		if barsize < 1 {
-			barsize = 1
-		}
-		barstart := w.Y + int(float64(w.StartLine.Line)/float64(w.Buf.LinesNum())*float64(w.Height))
+	if !w.hasScrollBar() {
+		return
+	}
+	b := w.Buf
+	scrollX := w.X + w.Width - 1
+	barstart, barsize := w.scrollBarThumb()
+
+	scrollBarStyle := config.DefStyle.Reverse(true)
+	if style, ok := config.Colorscheme["scrollbar"]; ok {
+		scrollBarStyle = style
+	}
 
-		scrollBarStyle := config.DefStyle.Reverse(true)
-		if style, ok := config.Colorscheme["scrollbar"]; ok {
-			scrollBarStyle = style
+	// marks of the search matches and the messages, by row
+	marks := make(map[int]tcell.Style)
+	runes := make(map[int]rune)
+	row := func(line int) int {
+		return util.Clamp(line*w.bufHeight/b.LinesNum(), 0, w.bufHeight-1)
+	}
+	if b.Settings["scrollbarmarks"].(bool) {
+		if r := b.SearchHighlightRegex(); r != nil && b.LinesNum() <= maxScrollBarSearch {
+			searchStyle := config.DefStyle.Foreground(tcell.ColorOlive)
+			if s, ok := config.Colorscheme["hlsearch"]; ok {
+				_, bg, _ := s.Decompose()
+				searchStyle = config.DefStyle.Foreground(bg)
+			}
+			for y := 0; y < b.LinesNum(); y++ {
+				if r.Match(b.LineBytes(y)) {
+					marks[row(y)], runes[row(y)] = searchStyle, '-'
+				}
+			}
+		}
+		// the most severe message of a row is shown
+		kinds := make(map[int]buffer.MsgType)
+		for _, m := range b.Messages {
+			y := row(m.Start.Y)
+			if k, ok := kinds[y]; !ok || m.Kind > k {
+				kinds[y] = m.Kind
+				fg, _, _ := m.Style().Decompose()
+				marks[y], runes[y] = config.DefStyle.Foreground(fg), '!'
+			}
 		}
+	}
 
-		for y := barstart; y < util.Min(barstart+barsize, w.Y+w.bufHeight); y++ {
-			screen.SetContent(scrollX, y, '|', nil, scrollBarStyle)
+	for y := 0; y < w.bufHeight; y++ {
+		thumb := y >= barstart && y < barstart+barsize
+		if mark, ok := marks[y]; ok {
+			style := mark
+			if thumb {
+				fg, _, _ := mark.Decompose()
+				style = scrollBarStyle.Foreground(fg)
+			}
+			screen.SetContent(scrollX, w.Y+y, runes[y], nil, style)
+		} else if thumb {
+			screen.SetContent(scrollX, w.Y+y, '|', nil, scrollBarStyle)
 		}
 	}
 }
@@ -762,6 +1268,12 @@ func (w *BufWindow) Display() {
 	w.updateDisplayInfo()
 
 	w.displayStatusLine()
+	if w.showsImage() {
+		w.lines.lines = nil
+		w.displayImage()
+		return
+	}
+	w.image.key = ""
 	w.displayScrollBar()
 	w.displayBuffer()
 }
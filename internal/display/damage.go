@@ -0,0 +1,197 @@
+package display
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// Only the parts of the screen that changed are redrawn: each BufWindow
+// remembers a signature of every line it drew and skips the lines whose
+// signature is the same in the next frame. The whole screen is cleared and
+// redrawn when what is laid out on it changes.
+
+var (
+	// frame counts the full redraws. The lines a window drew in an
+	// earlier one are not on the screen anymore.
+	frame uint64
+	// lastLayout describes what was laid out on the screen by the last
+	// full redraw
+	lastLayout string
+)
+
+// StartFrame starts drawing the screen, given a description of the panes
+// laid out on it, and returns whether it must be cleared and fully
+// redrawn. It is the case when the layout changed, and also when the size
+// of the screen, the colorscheme or the popups changed or the screen was
+// restarted.
+func StartFrame(layout string) bool {
+	w, h := screen.Screen.Size()
+	layout = fmt.Sprintf("%s|%d %d|%p %v|%s", layout, w, h, config.Colorscheme, config.DefStyle, popupLayout())
+	if screen.TakeRedrawAll() || layout != lastLayout {
+		lastLayout = layout
+		frame++
+		return true
+	}
+	return false
+}
+
+// popupLayout describes the area covered by the visible popups
+func popupLayout() string {
+	s := ""
+	for _, p := range popups {
+		x, y, w, h := p.bounds()
+		s += fmt.Sprintf("%d %d %d %d,", x, y, w, h)
+	}
+	return s
+}
+
+// A drawnLine is a buffer line as it was drawn on the screen
+type drawnLine struct {
+	// row is the first row of the line in the window and rows the number
+	// of rows it takes
+	row, rows int
+	sig       uint64
+	// startStyle and endStyle are the syntax highlighting style carried
+	// over from the previous line and to the next one
+	startStyle, endStyle tcell.Style
+}
+
+// lineCache holds the lines drawn by a window in the last frame
+type lineCache struct {
+	frame uint64
+	// key describes everything drawn in the window that is not specific to
+	// a line. The lines are all redrawn when it changes.
+	key   string
+	lines map[int]drawnLine
+	next  map[int]drawnLine
+}
+
+// start prepares the cache for drawing a frame with the given window key.
+// The lines of the last frame are dropped if the key changed.
+func (c *lineCache) start(key string) {
+	valid := c.frame == frame && c.key == key && c.lines != nil
+	c.frame, c.key = frame, key
+	if !valid {
+		c.lines = nil
+	}
+	c.next = make(map[int]drawnLine, len(c.lines))
+}
+
+// get returns the line n if it was drawn at the same row with the same
+// signature and the same carried style in the last frame
+func (c *lineCache) get(n, row int, sig uint64, style tcell.Style) (drawnLine, bool) {
+	l, ok := c.lines[n]
+	if !ok || l.row != row || l.sig != sig || l.startStyle != style {
+		return drawnLine{}, false
+	}
+	return l, true
+}
+
+// put records the line n as drawn in this frame
+func (c *lineCache) put(n int, l drawnLine) {
+	c.next[n] = l
+}
+
+// finish ends the frame, the lines drawn in it replacing the old ones
+func (c *lineCache) finish() {
+	c.lines, c.next = c.next, nil
+}
+
+// windowKey describes everything that changes how the lines of the window
+// are drawn, other than the lines themselves
+func (w *BufWindow) windowKey(matchingBraces []buffer.Loc, cursorCols []int, guides *indentGuides) string {
+	b := w.Buf
+	c := b.GetActiveCursor()
+	cursorY := -1
+	if w.Setting("relativeruler").(bool) {
+		cursorY = c.Y
+	}
+	search := ""
+	if r := b.SearchHighlightRegex(); r != nil {
+		search = r.String()
+	}
+	guide := ""
+	if guides != nil {
+		guide = fmt.Sprint(guides.activeCol, guides.activeStart, guides.activeEnd)
+	}
+	return fmt.Sprint(fmt.Sprintf("%p", b), w.X, w.Y, w.Width, w.Height, w.bufWidth, w.bufHeight,
+		w.gutterOffset, w.maxLineNumLength, w.hasMessage, w.StartCol, w.StartLine.Row,
+		w.active, cursorY, search, cursorCols, guide, len(matchingBraces), b.Settings, w.Settings)
+}
+
+// lineSignature returns a hash of everything drawn on the line n: its text
+// and highlighting, the search matches, selections, messages, bookmarks,
+// misspellings and matching braces on it, its indentation guides and its
+// diff status.
+// Anything that is drawn on a line must be part of its signature, or the
+// line is not redrawn when it changes.
+func (w *BufWindow) lineSignature(n int, matchingBraces []buffer.Loc, guides *indentGuides) uint64 {
+	b := w.Buf
+	h := fnv.New64a()
+	h.Write(b.LineBytes(n))
+
+	// the highlighting of the line, in any order
+	var match uint64
+	for x, g := range b.Match(n) {
+		match += uint64(x)*2654435761 ^ uint64(g)<<56
+	}
+
+	parts := []interface{}{n, match, len(b.Match(n)), b.DiffStatus(n), b.DiffChanges(n)}
+	if r := b.SearchHighlightRegex(); r != nil {
+		parts = append(parts, b.LineMatches(r, n))
+	}
+	for _, c := range b.GetCursors() {
+		if !c.HasSelection() {
+			continue
+		}
+		start, end := c.CurSelection[0], c.CurSelection[1]
+		if end.LessThan(start) {
+			start, end = end, start
+		}
+		if start.Y > n || end.Y < n {
+			continue
+		}
+		sx, ex := -1, math.MaxInt32
+		if start.Y == n {
+			sx = start.X
+		}
+		if end.Y == n {
+			ex = end.X
+		}
+		parts = append(parts, "sel", sx, ex)
+	}
+	for _, m := range b.Messages {
+		if m.Start.Y <= n && m.End.Y >= n {
+			parts = append(parts, "msg", m.Start, m.End, m.Kind)
+		}
+	}
+	if names := b.BookmarksAt(n); len(names) > 0 {
+		parts = append(parts, "bookmark", names[0])
+	}
+	for _, mb := range matchingBraces {
+		if mb.Y == n {
+			parts = append(parts, "brace", mb.X)
+		}
+	}
+	if guides != nil {
+		parts = append(parts, "guide", guides.depth(n))
+	}
+	if y, ok := b.StoppedLine(); ok && y == n {
+		parts = append(parts, "stopped")
+	}
+	if b.IsBreakpoint(n) {
+		parts = append(parts, "breakpoint")
+	}
+	if end, ok := b.IsFolded(n); ok {
+		parts = append(parts, "fold", end)
+	}
+	parts = append(parts, b.Misspellings(n))
+	fmt.Fprint(h, parts...)
+	return h.Sum64()
+}
@@ -0,0 +1,62 @@
+package display
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/git"
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// gitStatusInterval is how long the git status of a directory is shown
+// before it is asked again
+const gitStatusInterval = 2 * time.Second
+
+// gitStatusEntry is the last known git status of a directory
+type gitStatusEntry struct {
+	status  git.Status
+	ok      bool
+	checked time.Time
+	running bool
+}
+
+var (
+	gitStatuses     = make(map[string]*gitStatusEntry)
+	gitStatusesLock sync.Mutex
+)
+
+// gitStatus returns the git status of the directory of the buffer's file.
+// Running git would slow down drawing, so the status is updated in the
+// background when it is old and the screen is redrawn when it is known.
+func gitStatus(b *buffer.Buffer) (git.Status, bool) {
+	if b.Path == "" || b.Type != buffer.BTDefault {
+		return git.Status{}, false
+	}
+	dir := filepath.Dir(b.AbsPath)
+
+	gitStatusesLock.Lock()
+	defer gitStatusesLock.Unlock()
+	e, ok := gitStatuses[dir]
+	if !ok {
+		e = new(gitStatusEntry)
+		gitStatuses[dir] = e
+	}
+	if !e.running && time.Since(e.checked) > gitStatusInterval {
+		e.running = true
+		go func() {
+			s, err := git.GetStatus(dir)
+			gitStatusesLock.Lock()
+			changed := e.ok != (err == nil) || e.status != s
+			e.status, e.ok = s, err == nil
+			e.checked = time.Now()
+			e.running = false
+			gitStatusesLock.Unlock()
+			if changed {
+				screen.Redraw()
+			}
+		}()
+	}
+	return e.status, e.ok
+}
@@ -0,0 +1,105 @@
+package display
+
+import (
+	"fmt"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/graphics"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// The images drawn with a graphics protocol are not cells of the screen:
+// their escape sequences are written after the screen is shown, again
+// only when the image moved or the screen was fully redrawn, and the cells
+// they cover are left blank.
+
+var (
+	// pendingImages are the escape sequences of the images to write after
+	// the screen is shown
+	pendingImages []string
+	// kittyShown and kittyDrawn are the ids of the images drawn with the
+	// kitty protocol in the last frame and in this one. Those which aren't
+	// drawn anymore are deleted, since they stay over the cells.
+	kittyShown = map[int]bool{}
+	kittyDrawn = map[int]bool{}
+	// lastImageID is the id given to the last window which drew an image
+	lastImageID int
+)
+
+// imageState is the image a window last wrote to the terminal
+type imageState struct {
+	id    int
+	key   string
+	frame uint64
+}
+
+// showsImage returns whether the window shows the picture of its buffer
+// rather than its text
+func (w *BufWindow) showsImage() bool {
+	return w.Buf.Image != nil && w.Buf.Settings["imageview"].(bool)
+}
+
+// displayImage draws the picture of the buffer centered in the window
+func (w *BufWindow) displayImage() {
+	if w.Width <= 0 || w.bufHeight <= 0 {
+		return
+	}
+	for y := 0; y < w.bufHeight; y++ {
+		for x := 0; x < w.Width; x++ {
+			screen.SetContent(w.X+x, w.Y+y, ' ', nil, config.DefStyle)
+		}
+	}
+
+	img := w.Buf.Image
+	protocol := graphics.Protocol(config.GetGlobalOption("imageprotocol").(string))
+	cols, rows := graphics.Size(img, protocol, w.Width, w.bufHeight)
+	x0, y0 := w.X+(w.Width-cols)/2, w.Y+(w.bufHeight-rows)/2
+
+	if protocol == graphics.Blocks {
+		for y, line := range graphics.DrawBlocks(img, cols, rows) {
+			for x, bl := range line {
+				r, fg, both := bl.Rune()
+				style := config.DefStyle.Foreground(tcell.NewRGBColor(int32(fg.R), int32(fg.G), int32(fg.B)))
+				if both {
+					style = style.Background(tcell.NewRGBColor(int32(bl.Bottom.R), int32(bl.Bottom.G), int32(bl.Bottom.B)))
+				}
+				screen.SetContent(x0+x, y0+y, r, nil, style)
+			}
+		}
+		return
+	}
+
+	if w.image.id == 0 {
+		lastImageID++
+		w.image.id = lastImageID
+	}
+	if protocol == graphics.Kitty {
+		kittyDrawn[w.image.id] = true
+	}
+	key := fmt.Sprintf("%p %s %d %d %d %d", img, protocol, x0, y0, cols, rows)
+	if key == w.image.key && w.image.frame == frame {
+		return
+	}
+	w.image.key, w.image.frame = key, frame
+	// the cursor is saved and restored around the image, which the screen
+	// doesn't know of
+	seq := graphics.Sequence(img, protocol, w.image.id, cols, rows)
+	pendingImages = append(pendingImages, fmt.Sprintf("\x1b7\x1b[%d;%dH%s\x1b8", y0+1, x0+1, seq))
+}
+
+// FlushImages writes the images drawn with a graphics protocol in this
+// frame to the terminal, once the screen was shown, and deletes those of
+// the kitty protocol which are not drawn anymore
+func FlushImages() {
+	for id := range kittyShown {
+		if !kittyDrawn[id] {
+			screen.PutSequence(graphics.DeleteKitty(id))
+		}
+	}
+	for _, seq := range pendingImages {
+		screen.PutSequence(seq)
+	}
+	pendingImages = nil
+	kittyShown, kittyDrawn = kittyDrawn, map[int]bool{}
+}
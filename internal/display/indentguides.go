@@ -0,0 +1,96 @@
+package display
+
+import (
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// maxGuideScan limits how far the indentation of the surrounding lines is
+// searched for blank lines and for the block of the cursor
+const maxGuideScan = 200
+
+// indentGuides holds the indentation guides of the lines of a window. A
+// guide is drawn at every indentation level of the leading whitespace,
+// blank lines taking the indentation of the lines around them, and the
+// guide of the block containing the cursor is drawn in its own style.
+type indentGuides struct {
+	b       *buffer.Buffer
+	tabsize int
+	depths  map[int]int
+
+	// activeCol is the column of the guide of the cursor's block, or -1,
+	// and activeStart and activeEnd the lines of the block
+	activeCol              int
+	activeStart, activeEnd int
+}
+
+func newIndentGuides(b *buffer.Buffer, tabsize int) *indentGuides {
+	g := &indentGuides{
+		b:         b,
+		tabsize:   tabsize,
+		depths:    make(map[int]int),
+		activeCol: -1,
+	}
+
+	c := b.GetActiveCursor()
+	if d := g.depth(c.Y); d > 0 {
+		g.activeCol = (d - 1) / tabsize * tabsize
+		g.activeStart, g.activeEnd = c.Y, c.Y
+		for y := c.Y - 1; y >= 0 && c.Y-y < maxGuideScan && g.depth(y) > g.activeCol; y-- {
+			g.activeStart = y
+		}
+		for y := c.Y + 1; y < b.LinesNum() && y-c.Y < maxGuideScan && g.depth(y) > g.activeCol; y++ {
+			g.activeEnd = y
+		}
+	}
+	return g
+}
+
+// indent returns the visual width of the leading whitespace of a line,
+// and false if it is blank
+func (g *indentGuides) indent(y int) (int, bool) {
+	l := g.b.LineBytes(y)
+	ws := util.GetLeadingWhitespace(l)
+	if len(ws) == len(l) {
+		return 0, false
+	}
+	return util.StringWidth(ws, util.CharacterCount(ws), g.tabsize), true
+}
+
+// depth returns the width of the indentation covered by guides on a line.
+// A blank line is as indented as the least indented of the closest non
+// blank lines above and below it.
+func (g *indentGuides) depth(y int) int {
+	if d, ok := g.depths[y]; ok {
+		return d
+	}
+	d, ok := g.indent(y)
+	if !ok {
+		above, below := 0, 0
+		for i := y - 1; i >= 0 && y-i < maxGuideScan; i-- {
+			if ind, ok := g.indent(i); ok {
+				above = ind
+				break
+			}
+		}
+		for i := y + 1; i < g.b.LinesNum() && i-y < maxGuideScan; i++ {
+			if ind, ok := g.indent(i); ok {
+				below = ind
+				break
+			}
+		}
+		d = util.Min(above, below)
+	}
+	g.depths[y] = d
+	return d
+}
+
+// at returns whether a guide is drawn on the line y at the visual column
+// col, and whether it is the guide of the cursor's block
+func (g *indentGuides) at(y, col int) (bool, bool) {
+	if col%g.tabsize != 0 || col >= g.depth(y) {
+		return false, false
+	}
+	active := col == g.activeCol && y >= g.activeStart && y <= g.activeEnd
+	return true, active
+}
@@ -14,7 +14,12 @@ type InfoWindow struct {
 	*info.InfoBuf
 	*View
 
+	// Picker is displayed above the prompt while it is set
+	Picker *Picker
+
 	hscroll int
+	// promptScroll is the first column of the response shown in the prompt
+	promptScroll int
 }
 
 func (i *InfoWindow) errStyle() tcell.Style {
@@ -66,10 +71,14 @@ func (i *InfoWindow) SetActive(b bool) {}
 func (i *InfoWindow) IsActive() bool   { return true }
 
 func (i *InfoWindow) LocFromVisual(vloc buffer.Loc) buffer.Loc {
-	c := i.Buffer.GetActiveCursor()
-	l := i.Buffer.LineBytes(0)
-	n := util.CharacterCountInString(i.Msg)
-	return buffer.Loc{c.GetCharPosInLine(l, vloc.X-n), 0}
+	vx := vloc.X - i.promptStart() + i.promptScroll
+	for _, c := range i.promptCells() {
+		if vx < c.width {
+			return c.loc
+		}
+		vx -= c.width
+	}
+	return i.Buffer.End()
 }
 
 func (i *InfoWindow) BufView() View {
@@ -95,94 +104,139 @@ func (i *InfoWindow) Clear() {
 	}
 }
 
-func (i *InfoWindow) displayBuffer() {
-	b := i.Buffer
-	line := b.LineBytes(0)
-	activeC := b.GetActiveCursor()
-
-	blocX := 0
-	vlocX := util.CharacterCountInString(i.Msg)
+// A promptCell is a character of the response of the prompt, at loc in the
+// buffer, or the end of a line, shown as a newline marker
+type promptCell struct {
+	loc     buffer.Loc
+	r       rune
+	combc   []rune
+	width   int
+	newline bool
+}
 
+// promptCells returns the cells of the response of the prompt, whose lines
+// are shown one after the other, separated by newline markers
+func (i *InfoWindow) promptCells() []promptCell {
+	b := i.Buffer
 	tabsize := 4
-	line, nColsBeforeStart, bslice := util.SliceVisualEnd(line, blocX, tabsize)
-	blocX = bslice
-
-	draw := func(r rune, combc []rune, style tcell.Style) {
-		if nColsBeforeStart <= 0 {
-			bloc := buffer.Loc{X: blocX, Y: 0}
-			if activeC.HasSelection() &&
-				(bloc.GreaterEqual(activeC.CurSelection[0]) && bloc.LessThan(activeC.CurSelection[1]) ||
-					bloc.LessThan(activeC.CurSelection[0]) && bloc.GreaterEqual(activeC.CurSelection[1])) {
-				// The current character is selected
-				style = i.defStyle().Reverse(true)
-
-				if s, ok := config.Colorscheme["selection"]; ok {
-					style = s
-				}
-
+	var cells []promptCell
+	vx := 0
+	for y := 0; y < b.LinesNum(); y++ {
+		line := b.LineBytes(y)
+		x := 0
+		for len(line) > 0 {
+			r, combc, size := util.DecodeCharacter(line)
+			width := util.CharacterWidth(r)
+			if r == '\t' {
+				r, width = ' ', tabsize-vx%tabsize
 			}
-
-			rw := runewidth.RuneWidth(r)
-			for j := 0; j < rw; j++ {
-				c := r
-				if j > 0 {
-					c = ' '
-					combc = nil
-				}
-				screen.SetContent(vlocX, i.Y, c, combc, style)
+			if i.Masked {
+				r, combc, width = '*', nil, 1
 			}
-			vlocX++
+			cells = append(cells, promptCell{loc: buffer.Loc{X: x, Y: y}, r: r, combc: combc, width: width})
+			vx += width
+			x++
+			line = line[size:]
+		}
+		if y < b.LinesNum()-1 {
+			cells = append(cells, promptCell{loc: buffer.Loc{X: x, Y: y}, r: '↵', width: 1, newline: true})
+			vx++
 		}
-		nColsBeforeStart--
 	}
+	return cells
+}
 
-	totalwidth := blocX - nColsBeforeStart
-	for len(line) > 0 {
-		curVX := vlocX
-		curBX := blocX
-		r, combc, size := util.DecodeCharacter(line)
-
-		draw(r, combc, i.defStyle())
+// promptStart returns the column where the response of the prompt starts,
+// after its message
+func (i *InfoWindow) promptStart() int {
+	return runewidth.StringWidth(i.Msg)
+}
 
-		width := 0
+func (i *InfoWindow) displayBuffer() {
+	activeC := i.Buffer.GetActiveCursor()
+	cells := i.promptCells()
+	start := i.promptStart()
+	width := i.Width - start
+	if width <= 0 {
+		return
+	}
 
-		char := ' '
-		switch r {
-		case '\t':
-			ts := tabsize - (totalwidth % tabsize)
-			width = ts
-		default:
-			width = runewidth.RuneWidth(r)
-			char = '@'
+	// the response scrolls horizontally to keep the cursor shown
+	total, cx := 0, -1
+	for _, c := range cells {
+		if cx < 0 && c.loc == activeC.Loc {
+			cx = total
 		}
+		total += c.width
+	}
+	if cx < 0 {
+		cx = total
+	}
+	i.promptScroll = util.Clamp(i.promptScroll, 0, util.Max(total+1-width, 0))
+	if cx < i.promptScroll {
+		i.promptScroll = cx
+	} else if cx >= i.promptScroll+width {
+		i.promptScroll = cx - width + 1
+	}
 
-		blocX++
-		line = line[size:]
-
-		// Draw any extra characters either spaces for tabs or @ for incomplete wide runes
-		if width > 1 {
-			for j := 1; j < width; j++ {
-				draw(char, nil, i.defStyle())
-			}
-		}
-		if activeC.X == curBX {
-			screen.ShowCursor(curVX, i.Y)
+	markerStyle := i.defStyle()
+	if s, ok := config.Colorscheme["indent-char"]; ok {
+		markerStyle = s
+	}
+	vx := 0
+	for _, c := range cells {
+		x := start + vx - i.promptScroll
+		vx += c.width
+		if x < start {
+			continue
 		}
-		totalwidth += width
-		if vlocX >= i.Width {
+		if x+c.width > i.Width {
+			screen.SetContent(i.Width-1, i.Y, '>', nil, markerStyle)
 			break
 		}
+		style := i.defStyle()
+		if c.newline {
+			style = markerStyle
+		} else if activeC.HasSelection() &&
+			(c.loc.GreaterEqual(activeC.CurSelection[0]) && c.loc.LessThan(activeC.CurSelection[1]) ||
+				c.loc.LessThan(activeC.CurSelection[0]) && c.loc.GreaterEqual(activeC.CurSelection[1])) {
+			// The current character is selected
+			style = i.defStyle().Reverse(true)
+
+			if s, ok := config.Colorscheme["selection"]; ok {
+				style = s
+			}
+		}
+		screen.SetContent(x, i.Y, c.r, c.combc, style)
+		for j := 1; j < c.width && c.r == ' '; j++ {
+			screen.SetContent(x+j, i.Y, ' ', nil, style)
+		}
 	}
-	if activeC.X == blocX {
-		screen.ShowCursor(vlocX, i.Y)
+	if i.promptScroll > 0 {
+		screen.SetContent(start, i.Y, '<', nil, markerStyle)
 	}
+	screen.ShowCursor(start+cx-i.promptScroll, i.Y)
 }
 
-var keydisplay = []string{"^Q Quit, ^S Save, ^O Open, ^G Help, ^E Command Bar, ^K Cut Line", "^F Find, ^Z Undo, ^Y Redo, ^A Select All, ^D Duplicate Line, ^T New Tab"}
+// keyMenus are the lines of the key menu for each value of the keyprofile
+// option
+var keyMenus = map[string][]string{
+	"default": {"^Q Quit, ^S Save, ^O Open, ^G Help, ^E Command Bar, ^K Cut Line", "^F Find, ^Z Undo, ^Y Redo, ^A Select All, ^D Duplicate Line, ^T New Tab"},
+	"emacs":   {"^X^C Quit, ^X^S Save, ^X^F Open, F1 Help, M-x Command Bar, ^K Kill Line", "^S Find, ^_ Undo, ^W Cut, M-w Copy, ^Y Paste, ^X2 ^X3 Split"},
+	"nano":    {"^X Exit, ^O Write Out, ^R Read File, ^G Help, M-x Command Bar, ^K Cut", "^W Where Is, ^\\ Replace, ^U Paste, M-u Undo, M-e Redo, ^_ Go To Line"},
+	"vscode":  {"^Q Quit, ^S Save, ^P Open, M-h Help, F1 Palette, ^G Go To Line", "^F Find, ^Z Undo, ^Y Redo, ^D Add Cursor, ^_ Comment, ^\\ Split"},
+}
 
-func (i *InfoWindow) displayKeyMenu() {
-	// TODO: maybe make this based on the actual keybindings
+// keyMenu returns the lines of the key menu of the keyprofile option
+func keyMenu() []string {
+	if m, ok := keyMenus[config.GetGlobalOption("keyprofile").(string)]; ok {
+		return m
+	}
+	return keyMenus["default"]
+}
 
+func (i *InfoWindow) displayKeyMenu() {
+	keydisplay := keyMenu()
 	for y := 0; y < len(keydisplay); y++ {
 		for x := 0; x < i.Width; x++ {
 			if x < len(keydisplay[y]) {
@@ -253,6 +307,15 @@ func (i *InfoWindow) Display() {
 		}
 	}
 
+	if i.HasPrompt && i.Picker != nil {
+		keymenuOffset := 0
+		if config.GetGlobalOption("keymenu").(bool) {
+			keymenuOffset = len(keyMenu())
+		}
+		i.Picker.Display(i.Y-keymenuOffset, i.Width)
+		return
+	}
+
 	if i.HasSuggestions && len(i.Suggestions) > 1 {
 		i.scrollToSuggestion()
 
@@ -265,7 +328,7 @@ func (i *InfoWindow) Display() {
 		}
 		keymenuOffset := 0
 		if config.GetGlobalOption("keymenu").(bool) {
-			keymenuOffset = len(keydisplay)
+			keymenuOffset = len(keyMenu())
 		}
 
 		draw := func(r rune, s tcell.Style) {
@@ -0,0 +1,160 @@
+package display
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// A PickerItem is one entry that can be chosen in a Picker
+type PickerItem struct {
+	// Text is what the query is matched against
+	Text string
+	// Detail is extra information displayed on the right
+	Detail string
+	// Data can be used by the owner of the picker to identify the item
+	Data interface{}
+	// Recent is the rank of the item among those used recently, 1 for the
+	// most recent, or 0 if it wasn't used
+	Recent int
+	// Path is whether Text is a path, whose depth lowers the score
+	Path bool
+}
+
+// rankBonus returns the score added to the match of an item for how
+// recently it was used, less a penalty for the depth of its path, weighted
+// by the fuzzyrecency and fuzzydepth options
+func rankBonus(it PickerItem) int {
+	bonus := 0.0
+	if it.Recent > 0 {
+		bonus += config.GetGlobalOption("fuzzyrecency").(float64) * 128 / float64(it.Recent)
+	}
+	if it.Path {
+		depth := strings.Count(filepath.ToSlash(it.Text), "/")
+		bonus -= config.GetGlobalOption("fuzzydepth").(float64) * 16 * float64(depth)
+	}
+	return int(bonus)
+}
+
+// A Picker is a list of items filtered by fuzzy matching against a query.
+// It is displayed as a popup above the infobar, with the best match at the
+// bottom closest to the prompt.
+type Picker struct {
+	Items []PickerItem
+	// Matches holds the indices of the items matching the query, best first
+	Matches []int
+	// Selected is the index into Matches of the selected item
+	Selected int
+
+	// MaxHeight is the maximum number of lines the picker may use
+	MaxHeight int
+
+	query  string
+	scroll int
+}
+
+// NewPicker returns a new picker for the given items, with all of them
+// matching
+func NewPicker(items []PickerItem) *Picker {
+	p := new(Picker)
+	p.Items = items
+	p.MaxHeight = 10
+	p.query = "\x00"
+	p.Filter("")
+	return p
+}
+
+// Filter updates the list of matches for the given query. The selection is
+// reset to the best match unless the query didn't change.
+func (p *Picker) Filter(query string) {
+	if query == p.query {
+		return
+	}
+	p.query = query
+
+	scores := make(map[int]int)
+	p.Matches = p.Matches[:0]
+	for i, it := range p.Items {
+		if s, ok := util.FuzzyMatch(query, it.Text); ok {
+			scores[i] = s + rankBonus(it)
+			p.Matches = append(p.Matches, i)
+		}
+	}
+	if query != "" {
+		sort.SliceStable(p.Matches, func(a, b int) bool {
+			return scores[p.Matches[a]] > scores[p.Matches[b]]
+		})
+	}
+	p.Selected = 0
+	p.scroll = 0
+}
+
+// Next selects the next (worse) match
+func (p *Picker) Next() {
+	if p.Selected < len(p.Matches)-1 {
+		p.Selected++
+	}
+}
+
+// Prev selects the previous (better) match
+func (p *Picker) Prev() {
+	if p.Selected > 0 {
+		p.Selected--
+	}
+}
+
+// Current returns the selected item, or nil if nothing matches
+func (p *Picker) Current() *PickerItem {
+	if p.Selected < 0 || p.Selected >= len(p.Matches) {
+		return nil
+	}
+	return &p.Items[p.Matches[p.Selected]]
+}
+
+// Display draws the picker in the lines directly above line y
+func (p *Picker) Display(y, width int) {
+	height := util.Min(util.Min(p.MaxHeight, y), util.Max(len(p.Items), 1))
+	if height <= 0 {
+		return
+	}
+
+	if p.Selected < p.scroll {
+		p.scroll = p.Selected
+	} else if p.Selected >= p.scroll+height {
+		p.scroll = p.Selected - height + 1
+	}
+
+	// the best match is on the bottom line, next to the prompt
+	lines := make([]PopupLine, height)
+	selected := -1
+	for line := 0; line < height; line++ {
+		n := p.scroll + line
+		l := &lines[height-1-line]
+		if n >= len(p.Matches) {
+			if line == 0 {
+				l.Text = "  (no matches)"
+			}
+			// otherwise keep the popup a constant size while typing
+			continue
+		}
+
+		it := p.Items[p.Matches[n]]
+		prefix := "  "
+		if n == p.Selected {
+			prefix = "> "
+			selected = height - 1 - line
+		}
+		l.Text, l.Detail = prefix+it.Text, it.Detail
+	}
+
+	popup := Popup{
+		X: 0, Y: y - height,
+		Width: width, Height: height,
+		Lines:    lines,
+		Selected: selected,
+	}
+	popup.Display()
+}
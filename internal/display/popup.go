@@ -0,0 +1,265 @@
+package display
+
+import (
+	"sort"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A PopupLine is a line of a popup, with optional text aligned to the right
+type PopupLine struct {
+	Text   string
+	Detail string
+}
+
+// A Popup is a floating window drawn over the splits and the infobar,
+// for transient content such as completion menus, documentation or
+// pickers. Popups are drawn in the order of their Z, the popups with the
+// highest Z on top.
+type Popup struct {
+	// X, Y is the position of the top left corner, including the border
+	X, Y int
+	// Width and Height are the size of the content, excluding the border.
+	// A width of 0 fits the longest line and a height of 0 fits all lines.
+	Width, Height int
+	Z             int
+
+	Border bool
+	// Title is shown in the top border
+	Title string
+
+	Lines []PopupLine
+	// Selected is the index of the highlighted line, or -1. The popup
+	// scrolls to keep it visible.
+	Selected int
+
+	// Style is the style of the content, by default the popup color of the
+	// colorscheme, or the statusline color if there is none
+	Style *tcell.Style
+
+	scroll int
+}
+
+// popups are the visible popups, in the order they were shown
+var popups []*Popup
+
+// NewPopup returns a new popup at the given position with the given
+// lines. It is not visible until Show is called.
+func NewPopup(x, y int, lines []string) *Popup {
+	p := &Popup{X: x, Y: y, Selected: -1}
+	p.SetLines(lines)
+	return p
+}
+
+// SetLines replaces the lines of the popup
+func (p *Popup) SetLines(lines []string) {
+	p.Lines = make([]PopupLine, len(lines))
+	for i, l := range lines {
+		p.Lines[i].Text = l
+	}
+}
+
+// Show makes the popup visible
+func (p *Popup) Show() {
+	if !p.Visible() {
+		popups = append(popups, p)
+	}
+}
+
+// Hide removes the popup from the screen
+func (p *Popup) Hide() {
+	for i, o := range popups {
+		if o == p {
+			popups = append(popups[:i], popups[i+1:]...)
+			return
+		}
+	}
+}
+
+// Visible returns whether the popup is shown
+func (p *Popup) Visible() bool {
+	for _, o := range popups {
+		if o == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Size returns the outer size of the popup on the screen, including the
+// border
+func (p *Popup) Size() (int, int) {
+	w, h := p.Width, p.Height
+	if w <= 0 {
+		w = runewidth.StringWidth(p.Title)
+		for _, l := range p.Lines {
+			lw := runewidth.StringWidth(l.Text)
+			if l.Detail != "" {
+				lw += 1 + runewidth.StringWidth(l.Detail)
+			}
+			w = util.Max(w, lw)
+		}
+		w = util.Max(w, 1)
+	}
+	if h <= 0 {
+		h = util.Max(len(p.Lines), 1)
+	}
+	if p.Border {
+		w, h = w+2, h+2
+	}
+	return w, h
+}
+
+// Contains returns whether the screen location x, y is inside the popup
+func (p *Popup) Contains(x, y int) bool {
+	px, py, w, h := p.bounds()
+	return x >= px && x < px+w && y >= py && y < py+h
+}
+
+// bounds returns the position and outer size of the popup, moved and
+// shrunk to fit the screen
+func (p *Popup) bounds() (int, int, int, int) {
+	sw, sh := screen.Screen.Size()
+	w, h := p.Size()
+	w, h = util.Min(w, sw), util.Min(h, sh)
+	x := util.Clamp(p.X, 0, sw-w)
+	y := util.Clamp(p.Y, 0, sh-h)
+	return x, y, w, h
+}
+
+func (p *Popup) style() tcell.Style {
+	if p.Style != nil {
+		return *p.Style
+	}
+	if s, ok := config.Colorscheme["popup"]; ok {
+		return s
+	}
+	if s, ok := config.Colorscheme["statusline"]; ok {
+		return s
+	}
+	return config.DefStyle.Reverse(true)
+}
+
+// Display draws the popup on the screen
+func (p *Popup) Display() {
+	x, y, w, h := p.bounds()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	style := p.style()
+
+	if p.Border {
+		p.drawBorder(x, y, w, h, style)
+		x, y, w, h = x+1, y+1, w-2, h-2
+	}
+
+	if p.Selected >= 0 {
+		if p.Selected < p.scroll {
+			p.scroll = p.Selected
+		} else if p.Selected >= p.scroll+h {
+			p.scroll = p.Selected - h + 1
+		}
+	}
+	p.scroll = util.Clamp(p.scroll, 0, util.Max(len(p.Lines)-h, 0))
+
+	for i := 0; i < h; i++ {
+		n := p.scroll + i
+		if n >= len(p.Lines) {
+			drawPopupLine(x, y+i, w, "", "", style)
+			continue
+		}
+		s := style
+		if n == p.Selected {
+			s = style.Reverse(true)
+		}
+		drawPopupLine(x, y+i, w, p.Lines[n].Text, p.Lines[n].Detail, s)
+	}
+}
+
+func (p *Popup) drawBorder(x, y, w, h int, style tcell.Style) {
+	for i := 1; i < w-1; i++ {
+		screen.SetContent(x+i, y, tcell.RuneHLine, nil, style)
+		screen.SetContent(x+i, y+h-1, tcell.RuneHLine, nil, style)
+	}
+	for i := 1; i < h-1; i++ {
+		screen.SetContent(x, y+i, tcell.RuneVLine, nil, style)
+		screen.SetContent(x+w-1, y+i, tcell.RuneVLine, nil, style)
+	}
+	screen.SetContent(x, y, tcell.RuneULCorner, nil, style)
+	screen.SetContent(x+w-1, y, tcell.RuneURCorner, nil, style)
+	screen.SetContent(x, y+h-1, tcell.RuneLLCorner, nil, style)
+	screen.SetContent(x+w-1, y+h-1, tcell.RuneLRCorner, nil, style)
+
+	tx := x + 2
+	for _, r := range p.Title {
+		rw := runewidth.RuneWidth(r)
+		if tx+rw > x+w-2 {
+			break
+		}
+		screen.SetContent(tx, y, r, nil, style)
+		tx += rw
+	}
+}
+
+// drawPopupLine draws text on the left and detail on the right of a line of
+// the given width, truncating the text if they don't fit
+func drawPopupLine(x, y, width int, text, detail string, style tcell.Style) {
+	end := x + width
+	dw := runewidth.StringWidth(detail)
+	if dw >= width {
+		dw = 0
+		detail = ""
+	}
+	tend := end
+	if dw > 0 {
+		tend = end - dw - 1
+	}
+
+	cx := x
+	for _, r := range text {
+		rw := runewidth.RuneWidth(r)
+		if cx+rw > tend {
+			break
+		}
+		screen.SetContent(cx, y, r, nil, style)
+		for j := 1; j < rw; j++ {
+			screen.SetContent(cx+j, y, ' ', nil, style)
+		}
+		cx += rw
+	}
+	for ; cx < end-dw; cx++ {
+		screen.SetContent(cx, y, ' ', nil, style)
+	}
+	for _, r := range detail {
+		screen.SetContent(cx, y, r, nil, style)
+		cx += runewidth.RuneWidth(r)
+	}
+}
+
+// DisplayPopups draws the visible popups, the ones with the highest Z last
+func DisplayPopups() {
+	sorted := make([]*Popup, len(popups))
+	copy(sorted, popups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Z < sorted[j].Z
+	})
+	for _, p := range sorted {
+		p.Display()
+	}
+}
+
+// PopupAt returns the topmost visible popup containing the screen location
+// x, y, or nil if there is none
+func PopupAt(x, y int) *Popup {
+	var top *Popup
+	for _, p := range popups {
+		if p.Contains(x, y) && (top == nil || p.Z >= top.Z) {
+			top = p
+		}
+	}
+	return top
+}
@@ -56,13 +56,16 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 		return vloc
 	}
 
-	wordwrap := w.Buf.Settings["wordwrap"].(bool)
+	wordwrap := w.Setting("wordwrap").(bool)
 	tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 	line := w.Buf.LineBytes(loc.Y)
 	x := 0
 	totalwidth := 0
 
+	indent := w.wrapIndent(line, tabsize)
+	rowStart := 0
+
 	wordwidth := 0
 	wordoffset := 0
 
@@ -77,7 +80,7 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 			width = util.Min(ts, w.bufWidth-vloc.VisualX)
 			totalwidth += ts
 		default:
-			width = runewidth.RuneWidth(r)
+			width = util.CharacterWidth(r)
 			totalwidth += width
 		}
 
@@ -86,7 +89,7 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 		// Collect a complete word to know its width.
 		// If wordwrap is off, every single character is a complete "word".
 		if wordwrap {
-			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth {
+			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth-indent {
 				if x < loc.X {
 					wordoffset += width
 					x++
@@ -96,9 +99,9 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 		}
 
 		// If a word (or just a wide rune) does not fit in the window
-		if vloc.VisualX+wordwidth > w.bufWidth && vloc.VisualX > 0 {
+		if vloc.VisualX+wordwidth > w.bufWidth && vloc.VisualX > rowStart {
 			vloc.Row++
-			vloc.VisualX = 0
+			vloc.VisualX, rowStart = indent, indent
 		}
 
 		if x == loc.X {
@@ -114,7 +117,7 @@ func (w *BufWindow) getVLocFromLoc(loc buffer.Loc) VLoc {
 
 		if vloc.VisualX >= w.bufWidth {
 			vloc.Row++
-			vloc.VisualX = 0
+			vloc.VisualX, rowStart = indent, indent
 		}
 	}
 	return vloc
@@ -127,12 +130,15 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 		return loc
 	}
 
-	wordwrap := w.Buf.Settings["wordwrap"].(bool)
+	wordwrap := w.Setting("wordwrap").(bool)
 	tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 	line := w.Buf.LineBytes(svloc.Line)
 	vloc := VLoc{SLoc: SLoc{svloc.Line, 0}, VisualX: 0}
 
+	indent := w.wrapIndent(line, tabsize)
+	rowStart := 0
+
 	totalwidth := 0
 
 	var widths []int
@@ -154,7 +160,7 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 			width = util.Min(ts, w.bufWidth-vloc.VisualX)
 			totalwidth += ts
 		default:
-			width = runewidth.RuneWidth(r)
+			width = util.CharacterWidth(r)
 			totalwidth += width
 		}
 
@@ -164,13 +170,13 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 		// Collect a complete word to know its width.
 		// If wordwrap is off, every single character is a complete "word".
 		if wordwrap {
-			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth {
+			if !util.IsWhitespace(r) && len(line) > 0 && wordwidth < w.bufWidth-indent {
 				continue
 			}
 		}
 
 		// If a word (or just a wide rune) does not fit in the window
-		if vloc.VisualX+wordwidth > w.bufWidth && vloc.VisualX > 0 {
+		if vloc.VisualX+wordwidth > w.bufWidth && vloc.VisualX > rowStart {
 			if vloc.Row == svloc.Row {
 				if wordwrap {
 					// it's a word, not a wide rune
@@ -179,7 +185,7 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 				return loc
 			}
 			vloc.Row++
-			vloc.VisualX = 0
+			vloc.VisualX, rowStart = indent, indent
 		}
 
 		for i := range widths {
@@ -195,12 +201,26 @@ func (w *BufWindow) getLocFromVLoc(svloc VLoc) buffer.Loc {
 
 		if vloc.VisualX >= w.bufWidth {
 			vloc.Row++
-			vloc.VisualX = 0
+			vloc.VisualX, rowStart = indent, indent
 		}
 	}
 	return loc
 }
 
+// wrapIndent returns the column at which the continuation rows of a soft
+// wrapped line start: the width of the indentation of the line if
+// breakindent is on, plus the width of showbreak. At least half of the
+// window is kept for the text.
+func (w *BufWindow) wrapIndent(line []byte, tabsize int) int {
+	indent := 0
+	if w.Buf.Settings["breakindent"].(bool) {
+		ws := util.GetLeadingWhitespace(line)
+		indent = util.StringWidth(ws, util.CharacterCount(ws), tabsize)
+	}
+	indent += runewidth.StringWidth(w.Buf.Settings["showbreak"].(string))
+	return util.Min(indent, w.bufWidth/2)
+}
+
 func (w *BufWindow) getRowCount(line int) int {
 	eol := buffer.Loc{X: util.CharacterCount(w.Buf.LineBytes(line)), Y: line}
 	return w.getVLocFromLoc(eol).Row + 1
@@ -212,7 +232,7 @@ func (w *BufWindow) scrollUp(s SLoc, n int) SLoc {
 			s.Row -= n
 			n = 0
 		} else if s.Line > 0 {
-			s.Line--
+			s.Line = w.Buf.VisibleLine(s.Line - 1)
 			n -= s.Row + 1
 			s.Row = w.getRowCount(s.Line) - 1
 		} else {
@@ -229,8 +249,8 @@ func (w *BufWindow) scrollDown(s SLoc, n int) SLoc {
 		if n < rc-s.Row {
 			s.Row += n
 			n = 0
-		} else if s.Line < w.Buf.LinesNum()-1 {
-			s.Line++
+		} else if next := w.nextLine(s.Line); next < w.Buf.LinesNum() {
+			s.Line = next
 			n -= rc - s.Row
 			s.Row = 0
 		} else {
@@ -241,6 +261,15 @@ func (w *BufWindow) scrollDown(s SLoc, n int) SLoc {
 	return s
 }
 
+// nextLine returns the line shown after the line y, which is after the
+// lines it hides if it is folded
+func (w *BufWindow) nextLine(y int) int {
+	if end, ok := w.Buf.IsFolded(y); ok {
+		return end + 1
+	}
+	return y + 1
+}
+
 func (w *BufWindow) scroll(s SLoc, n int) SLoc {
 	if n < 0 {
 		return w.scrollUp(s, -n)
@@ -253,7 +282,7 @@ func (w *BufWindow) diff(s1, s2 SLoc) int {
 	for s1.LessThan(s2) {
 		if s1.Line < s2.Line {
 			n += w.getRowCount(s1.Line) - s1.Row
-			s1.Line++
+			s1.Line = w.nextLine(s1.Line)
 			s1.Row = 0
 		} else {
 			n += s2.Row - s1.Row
@@ -268,7 +297,11 @@ func (w *BufWindow) diff(s1, s2 SLoc) int {
 // which means scrolling up. The returned location is guaranteed to be
 // within the buffer boundaries.
 func (w *BufWindow) Scroll(s SLoc, n int) SLoc {
-	if !w.Buf.Settings["softwrap"].(bool) {
+	if !w.Setting("softwrap").(bool) {
+		if w.Buf.HasFolds() {
+			s.Line = w.Buf.MoveVisibleLines(s.Line, n)
+			return s
+		}
 		s.Line += n
 		if s.Line < 0 {
 			s.Line = 0
@@ -283,7 +316,11 @@ func (w *BufWindow) Scroll(s SLoc, n int) SLoc {
 
 // Diff returns the difference (the vertical distance) between two SLocs.
 func (w *BufWindow) Diff(s1, s2 SLoc) int {
-	if !w.Buf.Settings["softwrap"].(bool) {
+	s1.Line, s2.Line = w.Buf.VisibleLine(s1.Line), w.Buf.VisibleLine(s2.Line)
+	if !w.Setting("softwrap").(bool) {
+		if w.Buf.HasFolds() {
+			return w.diffLines(s1.Line, s2.Line)
+		}
 		return s2.Line - s1.Line
 	}
 	if s1.GreaterThan(s2) {
@@ -292,10 +329,26 @@ func (w *BufWindow) Diff(s1, s2 SLoc) int {
 	return w.diff(s1, s2)
 }
 
+// diffLines returns the number of lines shown from the line y1 to the line
+// y2, which is negative if y2 is before y1
+func (w *BufWindow) diffLines(y1, y2 int) int {
+	if y2 < y1 {
+		return -w.diffLines(y2, y1)
+	}
+	n := 0
+	for y := y1; y < y2; n++ {
+		y = w.nextLine(y)
+	}
+	return n
+}
+
 // SLocFromLoc takes a position in the buffer and returns the location
 // of the visual line containing this position.
 func (w *BufWindow) SLocFromLoc(loc buffer.Loc) SLoc {
-	if !w.Buf.Settings["softwrap"].(bool) {
+	if w.Buf.LineHidden(loc.Y) {
+		return SLoc{w.Buf.VisibleLine(loc.Y), 0}
+	}
+	if !w.Setting("softwrap").(bool) {
 		return SLoc{loc.Y, 0}
 	}
 	return w.getVLocFromLoc(loc).SLoc
@@ -304,7 +357,10 @@ func (w *BufWindow) SLocFromLoc(loc buffer.Loc) SLoc {
 // VLocFromLoc takes a position in the buffer and returns the corresponding
 // visual location in the linewrapped buffer.
 func (w *BufWindow) VLocFromLoc(loc buffer.Loc) VLoc {
-	if !w.Buf.Settings["softwrap"].(bool) {
+	if w.Buf.LineHidden(loc.Y) {
+		return VLoc{SLoc{w.Buf.VisibleLine(loc.Y), 0}, 0}
+	}
+	if !w.Setting("softwrap").(bool) {
 		tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 		visualx := util.StringWidth(w.Buf.LineBytes(loc.Y), loc.X, tabsize)
@@ -316,7 +372,7 @@ func (w *BufWindow) VLocFromLoc(loc buffer.Loc) VLoc {
 // LocFromVLoc takes a visual location in the linewrapped buffer and returns
 // the position in the buffer corresponding to this visual location.
 func (w *BufWindow) LocFromVLoc(vloc VLoc) buffer.Loc {
-	if !w.Buf.Settings["softwrap"].(bool) {
+	if !w.Setting("softwrap").(bool) {
 		tabsize := util.IntOpt(w.Buf.Settings["tabsize"])
 
 		x := util.GetCharPosInLine(w.Buf.LineBytes(vloc.Line), vloc.VisualX, tabsize)
@@ -33,10 +33,11 @@ var statusInfo = map[string]func(*buffer.Buffer) string{
 		return b.GetName()
 	},
 	"line": func(b *buffer.Buffer) string {
-		return strconv.Itoa(b.GetActiveCursor().Y + 1)
+		return strconv.Itoa(b.FileLine(b.GetActiveCursor().Y) + 1)
 	},
 	"col": func(b *buffer.Buffer) string {
-		return strconv.Itoa(b.GetActiveCursor().X + 1)
+		c := b.GetActiveCursor()
+		return strconv.Itoa(c.X + c.Virtual + 1)
 	},
 	"modified": func(b *buffer.Buffer) string {
 		if b.Modified() {
@@ -47,6 +48,83 @@ var statusInfo = map[string]func(*buffer.Buffer) string{
 		}
 		return ""
 	},
+	"search": func(b *buffer.Buffer) string {
+		r := b.SearchHighlightRegex()
+		if r == nil {
+			return ""
+		}
+		c := b.GetActiveCursor()
+		loc := c.Loc
+		if c.HasSelection() {
+			loc = c.CurSelection[0]
+		}
+		cur, total := b.SearchMatchCount(r, loc, maxSearchCount)
+		if total >= maxSearchCount {
+			return fmt.Sprintf("%d/%d+ ", cur, total)
+		}
+		return fmt.Sprintf("%d/%d ", cur, total)
+	},
+	"git": func(b *buffer.Buffer) string {
+		s, ok := gitStatus(b)
+		if !ok || s.Branch == "" {
+			return ""
+		}
+		if s.Dirty {
+			return s.Branch + "* "
+		}
+		return s.Branch + " "
+	},
+	"diagnostics": func(b *buffer.Buffer) string {
+		errors, warnings := 0, 0
+		for _, m := range b.Messages {
+			switch m.Kind {
+			case buffer.MTError:
+				errors++
+			case buffer.MTWarning:
+				warnings++
+			}
+		}
+		s := ""
+		if errors > 0 {
+			s += fmt.Sprintf("E:%d ", errors)
+		}
+		if warnings > 0 {
+			s += fmt.Sprintf("W:%d ", warnings)
+		}
+		return s
+	},
+	"scope": func(b *buffer.Buffer) string {
+		if scope := b.Scope(b.GetActiveCursor().Y); scope != "" {
+			return scope + " "
+		}
+		return ""
+	},
+	"encoding": func(b *buffer.Buffer) string {
+		return strings.ToUpper(b.Settings["encoding"].(string))
+	},
+	"stats": func(b *buffer.Buffer) string {
+		c := b.GetActiveCursor()
+		if c.HasSelection() {
+			s := buffer.TextStats(c.GetSelection())
+			return fmt.Sprintf("%d/%d words ", s.Words, b.Stats().Words)
+		}
+		return fmt.Sprintf("%d words ", b.Stats().Words)
+	},
+	"eol": func(b *buffer.Buffer) string {
+		if b.Endings == buffer.FFDos {
+			return "CRLF"
+		}
+		return "LF"
+	},
+}
+
+// maxSearchCount limits how many matches are counted for $(search)
+const maxSearchCount = 1000
+
+// SetStatusInfoFn registers a function that provides the text for
+// `$(name)` in the statusline format options
+func SetStatusInfoFn(name string, fn func(*buffer.Buffer) string) {
+	statusInfo[name] = fn
 }
 
 func SetStatusInfoFnLua(fn string) {
@@ -1,6 +1,11 @@
 package display
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
 	runewidth "github.com/mattn/go-runewidth"
 	"github.com/zyedidia/micro/v2/internal/buffer"
 	"github.com/zyedidia/micro/v2/internal/config"
@@ -8,6 +13,117 @@ import (
 	"github.com/zyedidia/micro/v2/internal/util"
 )
 
+// tabCloseButton is drawn after the name of each tab when the tabclose
+// option is on
+const tabCloseButton = '×'
+
+// filetypeIcons are the icons shown by $(icon) in the tabformat option, from
+// the Nerd Fonts
+var filetypeIcons = map[string]string{
+	"c":          "\ue61e",
+	"c++":        "\ue61d",
+	"css":        "\ue749",
+	"go":         "\ue626",
+	"html":       "\ue736",
+	"java":       "\ue738",
+	"javascript": "\ue74e",
+	"json":       "\ue60b",
+	"lua":        "\ue620",
+	"markdown":   "\ue609",
+	"php":        "\ue73d",
+	"python":     "\ue606",
+	"ruby":       "\ue739",
+	"rust":       "\ue7a8",
+	"shell":      "\ue795",
+	"typescript": "\ue628",
+	"vim":        "\ue62b",
+}
+
+// defaultFiletypeIcon is the icon of the filetypes without their own
+const defaultFiletypeIcon = "\uf15b"
+
+// SetFiletypeIcon sets the icon shown by $(icon) in the tabformat option
+// for the given filetype. An empty icon shows none.
+func SetFiletypeIcon(ft, icon string) {
+	filetypeIcons[ft] = icon
+}
+
+var tabInfo = map[string]func(int, *buffer.Buffer) string{
+	"index": func(i int, b *buffer.Buffer) string {
+		return strconv.Itoa(i + 1)
+	},
+	"filename": func(i int, b *buffer.Buffer) string {
+		name := b.GetName()
+		if b.Path == "" {
+			return name
+		}
+		switch config.GetGlobalOption("tabpath").(string) {
+		case "base":
+			return filepath.Base(name)
+		case "short":
+			return util.ShortenPath(name)
+		}
+		return name
+	},
+	"modified": func(i int, b *buffer.Buffer) string {
+		if b.Modified() {
+			return " +"
+		}
+		return ""
+	},
+	"icon": func(i int, b *buffer.Buffer) string {
+		if icon, ok := filetypeIcons[b.FileType()]; ok {
+			return icon
+		}
+		return defaultFiletypeIcon
+	},
+	"filetype": func(i int, b *buffer.Buffer) string {
+		return b.FileType()
+	},
+	"project": func(i int, b *buffer.Buffer) string {
+		return filepath.Base(util.ProjectRoot(bufferDir(b)))
+	},
+}
+
+// bufferDir returns the directory of the file of b, or the working
+// directory if it has none
+func bufferDir(b *buffer.Buffer) string {
+	if b.Path != "" {
+		return filepath.Dir(b.AbsPath)
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// FormatTabName returns the name of the tab at index i in the tab bar, by
+// expanding the tabformat option for the buffer b of its active pane. Panes
+// without a buffer, such as terminals, are shown by their name.
+func FormatTabName(i int, b *buffer.Buffer, name string) string {
+	if b == nil {
+		return name
+	}
+	return expandTabFormat(config.GetGlobalOption("tabformat").(string), i, b)
+}
+
+// expandTabFormat expands the fields of tabformat in the format f for the
+// buffer b of the tab at index i
+func expandTabFormat(f string, i int, b *buffer.Buffer) string {
+	format := formatParser.ReplaceAllFunc([]byte(f), func(match []byte) []byte {
+		name := string(match[2 : len(match)-1])
+		if len(name) > 4 && name[:4] == "opt:" {
+			if val, ok := b.Settings[name[4:]]; ok {
+				return []byte(fmt.Sprint(val))
+			}
+			return []byte("null")
+		}
+		if fn, ok := tabInfo[name]; ok {
+			return []byte(fn(i, b))
+		}
+		return []byte{}
+	})
+	return string(format)
+}
+
 type TabWindow struct {
 	Names   []string
 	active  int
@@ -27,17 +143,62 @@ func (w *TabWindow) Resize(width, height int) {
 	w.Width = width
 }
 
+// label returns the name of the tab i as it is drawn, truncated to the
+// tabmaxwidth option
+func (w *TabWindow) label(i int) string {
+	n := w.Names[i]
+	max := util.IntOpt(config.GetGlobalOption("tabmaxwidth"))
+	if max <= 0 || runewidth.StringWidth(n) <= max {
+		return n
+	}
+	return runewidth.Truncate(n, max, "…")
+}
+
+// closeWidth returns the width taken by the close button of a tab
+func closeWidth() int {
+	if config.GetGlobalOption("tabclose").(bool) {
+		return 2
+	}
+	return 0
+}
+
+// tabWidth returns the width of the tab i on the tab bar: the label and its
+// close button between a bracket or a space on the left and three columns
+// on the right
+func (w *TabWindow) tabWidth(i int) int {
+	return runewidth.StringWidth(w.label(i)) + closeWidth() + 4
+}
+
+// LocFromVisual returns the index of the tab at the given screen location,
+// or -1 if there is none
 func (w *TabWindow) LocFromVisual(vloc buffer.Loc) int {
 	x := -w.hscroll
 
-	for i, n := range w.Names {
-		x++
-		s := util.CharacterCountInString(n)
-		if vloc.Y == w.Y && vloc.X < x+s {
+	for i := range w.Names {
+		if vloc.Y == w.Y && vloc.X < x+w.tabWidth(i)-2 {
 			return i
 		}
-		x += s
-		x += 3
+		x += w.tabWidth(i)
+		if x >= w.Width {
+			break
+		}
+	}
+	return -1
+}
+
+// CloseFromVisual returns the index of the tab whose close button is at the
+// given screen location, or -1 if there is none
+func (w *TabWindow) CloseFromVisual(vloc buffer.Loc) int {
+	if closeWidth() == 0 || vloc.Y != w.Y {
+		return -1
+	}
+	x := -w.hscroll
+
+	for i := range w.Names {
+		if vloc.X == x+w.tabWidth(i)-4 {
+			return i
+		}
+		x += w.tabWidth(i)
 		if x >= w.Width {
 			break
 		}
@@ -57,8 +218,8 @@ func (w *TabWindow) Scroll(amt int) {
 
 func (w *TabWindow) TotalSize() int {
 	sum := 2
-	for _, n := range w.Names {
-		sum += runewidth.StringWidth(n) + 4
+	for i := range w.Names {
+		sum += w.tabWidth(i)
 	}
 	return sum - 4
 }
@@ -72,8 +233,8 @@ func (w *TabWindow) SetActive(a int) {
 	x := 2
 	s := w.TotalSize()
 
-	for i, n := range w.Names {
-		c := util.CharacterCountInString(n)
+	for i := range w.Names {
+		c := w.tabWidth(i) - 4
 		if i == a {
 			if x+c >= w.hscroll+w.Width {
 				w.hscroll = util.Clamp(x+c+1-w.Width, 0, s-w.Width)
@@ -129,19 +290,25 @@ func (w *TabWindow) Display() {
 		}
 	}
 
-	for i, n := range w.Names {
-		if i == w.active {
+	closeBtn := closeWidth() > 0
+	for i := range w.Names {
+		active := i == w.active
+		if active {
 			draw('[', 1, true)
 		} else {
 			draw(' ', 1, false)
 		}
-		for _, c := range n {
-			draw(c, 1, i == w.active)
+		for _, c := range w.label(i) {
+			draw(c, 1, active)
+		}
+		if closeBtn {
+			draw(' ', 1, active)
+			draw(tabCloseButton, 1, active)
 		}
 		if i == len(w.Names)-1 {
 			done = true
 		}
-		if i == w.active {
+		if active {
 			draw(']', 1, true)
 			draw(' ', 2, true)
 		} else {
@@ -0,0 +1,36 @@
+package display
+
+import (
+	"os"
+
+	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/screen"
+)
+
+// UpdateTerminal sets the title of the terminal to the titleformat option
+// expanded for the buffer b of the active pane of the tab at index i, or to
+// the name of the pane if it has no buffer, when the termtitle option is
+// on, and tells the terminal the directory of the buffer when the osc7
+// option is on
+func UpdateTerminal(i int, b *buffer.Buffer, name string) {
+	if config.GetGlobalOption("termtitle").(bool) {
+		title := name
+		if b != nil {
+			title = expandTabFormat(config.GetGlobalOption("titleformat").(string), i, b)
+		}
+		screen.SetTitle(title)
+	} else {
+		screen.RestoreTitle()
+	}
+
+	if config.GetGlobalOption("osc7").(bool) {
+		dir := ""
+		if b != nil {
+			dir = bufferDir(b)
+		} else {
+			dir, _ = os.Getwd()
+		}
+		screen.SetDirectory(dir)
+	}
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A BlameLine tells which commit last changed a line
+type BlameLine struct {
+	// Commit is the abbreviated hash, made of zeros for lines which are
+	// not committed yet
+	Commit  string
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// Blame annotates each line of text, the current contents of the file at
+// path, with the commit which last changed it
+func Blame(path string, text []byte) ([]BlameLine, error) {
+	root, name, err := repoPath(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(root, text, "blame", "--porcelain", "--contents", "-", "--", name)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out)
+}
+
+// parseBlame parses the output of git blame --porcelain. The details of a
+// commit are only given for its first line.
+func parseBlame(out []byte) ([]BlameLine, error) {
+	commits := make(map[string]*BlameLine)
+	var lines []BlameLine
+	var cur *BlameLine
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		l := scanner.Text()
+		if strings.HasPrefix(l, "\t") {
+			if cur == nil {
+				return nil, errors.New("Malformed blame output")
+			}
+			lines = append(lines, *cur)
+			continue
+		}
+
+		fields := strings.SplitN(l, " ", 2)
+		key := fields[0]
+		value := ""
+		if len(fields) > 1 {
+			value = fields[1]
+		}
+
+		if len(key) == 40 && strings.Trim(key, "0123456789abcdef") == "" {
+			// header of a line: hash, original and final line numbers
+			if c, ok := commits[key]; ok {
+				cur = c
+			} else {
+				cur = &BlameLine{Commit: key[:8]}
+				commits[key] = cur
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch key {
+		case "author":
+			cur.Author = value
+		case "author-time":
+			if t, err := strconv.ParseInt(value, 10, 64); err == nil {
+				cur.Time = time.Unix(t, 0)
+			}
+		case "summary":
+			cur.Summary = value
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,120 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Hunks returns the hunks of changes between base and text
+func Hunks(base, text []byte) []Hunk {
+	baseLines := splitLines(base)
+	textLines := splitLines(text)
+
+	differ := dmp.New()
+	baseRunes, textRunes, _ := differ.DiffLinesToRunes(string(base), string(text))
+	diffs := differ.DiffMainRunes(baseRunes, textRunes, false)
+
+	var hunks []Hunk
+	var cur *Hunk
+	i, j := 0, 0
+	for _, d := range diffs {
+		n := len([]rune(d.Text))
+		if d.Type == dmp.DiffEqual {
+			if cur != nil {
+				hunks = append(hunks, *cur)
+				cur = nil
+			}
+			i += n
+			j += n
+			continue
+		}
+
+		if cur == nil {
+			cur = &Hunk{BaseStart: i, Start: j}
+		}
+		if d.Type == dmp.DiffDelete {
+			cur.Base = append(cur.Base, baseLines[i:i+n]...)
+			i += n
+		} else {
+			cur.Lines = append(cur.Lines, textLines[j:j+n]...)
+			j += n
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+	return hunks
+}
+
+// FindHunk returns the hunk between base and text which contains the line y
+// of text
+func FindHunk(base, text []byte, y int) (Hunk, bool) {
+	for _, h := range Hunks(base, text) {
+		if h.Contains(y) {
+			return h, true
+		}
+	}
+	return Hunk{}, false
+}
+
+// MapLine returns the line of base corresponding to the line y of text. A
+// line which was added maps to the first base line of its hunk.
+func MapLine(base, text []byte, y int) int {
+	offset := 0
+	for _, h := range Hunks(base, text) {
+		if y < h.Start {
+			break
+		}
+		if h.Contains(y) && len(h.Lines) > 0 {
+			return h.BaseStart
+		}
+		offset += len(h.Base) - len(h.Lines)
+	}
+	return y + offset
+}
+
+// Unified returns the unified diff between base and text, named baseName and
+// name in its header, with the given number of lines of context around the
+// changes. Changes closer than twice the context share a hunk. It is empty if
+// the texts are the same.
+func Unified(base, text []byte, baseName, name string, context int) []byte {
+	hunks := Hunks(base, text)
+	if len(hunks) == 0 {
+		return nil
+	}
+	baseLines := splitLines(base)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", baseName, name)
+	for i := 0; i < len(hunks); {
+		j := i + 1
+		for j < len(hunks) && hunks[j].BaseStart-(hunks[j-1].BaseStart+len(hunks[j-1].Base)) <= 2*context {
+			j++
+		}
+		first, last := hunks[i], hunks[j-1]
+		start := first.BaseStart - context
+		if start < 0 {
+			start = 0
+		}
+		end := last.BaseStart + len(last.Base) + context
+		if end > len(baseLines) {
+			end = len(baseLines)
+		}
+		textStart := first.Start - (first.BaseStart - start)
+		textEnd := last.Start + len(last.Lines) + end - (last.BaseStart + len(last.Base))
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(start, end-start), hunkRange(textStart, textEnd-textStart))
+
+		pos := start
+		for _, h := range hunks[i:j] {
+			writeLines(&b, ' ', baseLines[pos:h.BaseStart])
+			writeLines(&b, '-', h.Base)
+			writeLines(&b, '+', h.Lines)
+			pos = h.BaseStart + len(h.Base)
+		}
+		writeLines(&b, ' ', baseLines[pos:end])
+		i = j
+	}
+	return b.Bytes()
+}
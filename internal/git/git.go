@@ -0,0 +1,191 @@
+// Package git runs the git command line tool to compare files with the
+// repository, stage changes and annotate files
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// run runs git in dir with the given input and returns its output. The error
+// contains what git printed on stderr.
+func run(dir string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// repoPath returns the root of the repository containing the file and the
+// path of the file relative to it, with '/' separators
+func repoPath(path string) (string, string, error) {
+	dir, name := filepath.Split(path)
+	out, err := run(dir, nil, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	prefix := ""
+	if len(lines) > 1 {
+		prefix = lines[1]
+	}
+	return lines[0], prefix + name, nil
+}
+
+// Show returns the contents of the file at the given path in the revision
+// rev, such as "HEAD". An empty rev refers to the index.
+func Show(path, rev string) ([]byte, error) {
+	dir, name := filepath.Split(path)
+	return run(dir, nil, "show", rev+":./"+name)
+}
+
+// Apply applies a hunk of changes of the file at path to the index, or
+// removes it from the index if reverse is set
+func Apply(path string, h Hunk, reverse bool) error {
+	root, name, err := repoPath(path)
+	if err != nil {
+		return err
+	}
+	patch := h.Patch(name)
+	args := []string{"apply", "--cached", "--unidiff-zero"}
+	if reverse {
+		args = append(args, "-R")
+	}
+	_, err = run(root, patch, append(args, "-")...)
+	return err
+}
+
+// A Status is the state of the working tree of a repository
+type Status struct {
+	// Branch is the checked out branch, or the abbreviated commit when the
+	// HEAD is detached
+	Branch string
+	// Dirty is set when files have changes which aren't committed
+	Dirty bool
+}
+
+// GetStatus returns the status of the repository containing the directory
+// dir. Untracked files don't make it dirty.
+func GetStatus(dir string) (Status, error) {
+	out, err := run(dir, nil, "status", "--porcelain", "--branch", "--untracked-files=no")
+	if err != nil {
+		return Status{}, err
+	}
+	s := parseStatus(out)
+	if s.Branch == "" {
+		if out, err := run(dir, nil, "rev-parse", "--short", "HEAD"); err == nil {
+			s.Branch = strings.TrimSpace(string(out))
+		}
+	}
+	return s, nil
+}
+
+// parseStatus parses the output of git status --porcelain --branch, whose
+// first line is "## branch...upstream [ahead 1]", "## No commits yet on
+// branch" or "## HEAD (no branch)" when the HEAD is detached
+func parseStatus(out []byte) Status {
+	var s Status
+	for i, l := range strings.Split(string(out), "\n") {
+		if i > 0 {
+			s.Dirty = s.Dirty || l != ""
+			continue
+		}
+		l = strings.TrimPrefix(l, "## ")
+		switch {
+		case strings.HasPrefix(l, "HEAD (no branch)"):
+		case strings.HasPrefix(l, "No commits yet on "):
+			s.Branch = strings.TrimPrefix(l, "No commits yet on ")
+		case strings.HasPrefix(l, "Initial commit on "):
+			s.Branch = strings.TrimPrefix(l, "Initial commit on ")
+		default:
+			if j := strings.Index(l, "..."); j >= 0 {
+				l = l[:j]
+			}
+			if f := strings.Fields(l); len(f) > 0 {
+				s.Branch = f[0]
+			}
+		}
+	}
+	return s
+}
+
+// A Hunk is a group of consecutive changed lines between a base text and a
+// new text. The lines include their line ending.
+type Hunk struct {
+	// BaseStart and Start are the 0-based line numbers of the hunk in the
+	// base and in the new text. For a hunk that has no lines on one side it
+	// is the line before which lines were added or removed.
+	BaseStart, Start int
+	Base, Lines      []string
+}
+
+// Contains returns whether the new text's line y is part of the hunk. A hunk
+// which only removes lines contains the line following the removal.
+func (h Hunk) Contains(y int) bool {
+	n := len(h.Lines)
+	if n == 0 {
+		n = 1
+	}
+	return y >= h.Start && y < h.Start+n
+}
+
+// Patch returns a unified diff with no context lines which applies the hunk
+// to the file at path, given relative to the root of the repository
+func (h Hunk) Patch(path string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(h.BaseStart, len(h.Base)), hunkRange(h.Start, len(h.Lines)))
+	writeLines(&b, '-', h.Base)
+	writeLines(&b, '+', h.Lines)
+	return b.Bytes()
+}
+
+// hunkRange formats the range of a hunk for a unified diff header, where an
+// empty range refers to the line before it
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func writeLines(b *bytes.Buffer, prefix byte, lines []string) {
+	for _, l := range lines {
+		b.WriteByte(prefix)
+		b.WriteString(l)
+		if !strings.HasSuffix(l, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits text after each newline. The last line has no newline
+// if the text doesn't end with one.
+func splitLines(text []byte) []string {
+	var lines []string
+	s := string(text)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHunks(t *testing.T) {
+	base := []byte("a\nb\nc\nd\ne")
+	text := []byte("a\nB\nc\nx\ny\nd\n")
+
+	hunks := Hunks(base, text)
+	assert.Equal(t, []Hunk{
+		{BaseStart: 1, Start: 1, Base: []string{"b\n"}, Lines: []string{"B\n"}},
+		{BaseStart: 3, Start: 3, Lines: []string{"x\n", "y\n"}},
+		{BaseStart: 4, Start: 6, Base: []string{"e"}},
+	}, hunks)
+
+	assert.Equal(t, "--- a/f\n+++ b/f\n@@ -2,1 +2,1 @@\n-b\n+B\n", string(hunks[0].Patch("f")))
+	assert.Equal(t, "--- a/f\n+++ b/f\n@@ -3,0 +4,2 @@\n+x\n+y\n", string(hunks[1].Patch("f")))
+	assert.Equal(t, "--- a/f\n+++ b/f\n@@ -5,1 +6,0 @@\n-e\n\\ No newline at end of file\n", string(hunks[2].Patch("f")))
+
+	_, ok := FindHunk(base, text, 2)
+	assert.False(t, ok)
+	h, ok := FindHunk(base, text, 4)
+	assert.True(t, ok)
+	assert.Equal(t, 3, h.Start)
+
+	assert.Equal(t, 2, MapLine(base, text, 2))
+	assert.Equal(t, 3, MapLine(base, text, 4))
+	assert.Equal(t, 3, MapLine(base, text, 5))
+}
+
+func TestUnified(t *testing.T) {
+	base := []byte("1\n2\n3\n4\n5\n6\n7\n8\n")
+	text := []byte("1\nb\n3\n4\n5\n6\n8\n")
+
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n 1\n-2\n+b\n 3\n@@ -6,3 +6,2 @@\n 6\n-7\n 8\n",
+		string(Unified(base, text, "a", "b", 1)))
+	// the changes are close enough to share a hunk
+	assert.Equal(t, "--- a\n+++ b\n@@ -1,8 +1,7 @@\n 1\n-2\n+b\n 3\n 4\n 5\n 6\n-7\n 8\n",
+		string(Unified(base, text, "a", "b", 2)))
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+x\n", string(Unified(nil, []byte("x\n"), "a", "b", 3)))
+	assert.Empty(t, Unified(base, base, "a", "b", 3))
+}
+
+func TestParseBlame(t *testing.T) {
+	out := "1111111111111111111111111111111111111111 1 1 2\n" +
+		"author Jane Doe\n" +
+		"author-time 1600000000\n" +
+		"summary First commit\n" +
+		"filename f\n" +
+		"\tone\n" +
+		"1111111111111111111111111111111111111111 2 2\n" +
+		"\ttwo\n" +
+		"0000000000000000000000000000000000000000 3 3 1\n" +
+		"author Not Committed Yet\n" +
+		"\tthree\n"
+
+	lines, err := parseBlame([]byte(out))
+	assert.Nil(t, err)
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "11111111", lines[1].Commit)
+	assert.Equal(t, "Jane Doe", lines[1].Author)
+	assert.Equal(t, "First commit", lines[0].Summary)
+	assert.Equal(t, int64(1600000000), lines[0].Time.Unix())
+	assert.Equal(t, "00000000", lines[2].Commit)
+}
+
+func TestParseStatus(t *testing.T) {
+	assert.Equal(t, Status{Branch: "main"}, parseStatus([]byte("## main...origin/main [ahead 1]\n")))
+	assert.Equal(t, Status{Branch: "dev", Dirty: true}, parseStatus([]byte("## dev\n M f.go\n")))
+	assert.Equal(t, Status{Branch: "master"}, parseStatus([]byte("## No commits yet on master\n")))
+	assert.Equal(t, Status{}, parseStatus([]byte("## HEAD (no branch)\n")))
+}
+
+func TestApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "micro-git")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sub", "f.txt")
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(path, []byte("a\nb\nc\n"), 0644)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "-m", "init"},
+	} {
+		_, err := run(dir, nil, args...)
+		assert.Nil(t, err)
+	}
+
+	text := []byte("a\nB\nc\nd\n")
+	hunks := Hunks([]byte("a\nb\nc\n"), text)
+	assert.Len(t, hunks, 2)
+	assert.Nil(t, Apply(path, hunks[1], false))
+
+	index, err := Show(path, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb\nc\nd\n", string(index))
+
+	assert.Nil(t, Apply(path, hunks[1], true))
+	index, _ = Show(path, "")
+	assert.Equal(t, "a\nb\nc\n", string(index))
+
+	s, err := GetStatus(filepath.Join(dir, "sub"))
+	assert.Nil(t, err)
+	assert.False(t, s.Dirty)
+	assert.NotEmpty(t, s.Branch)
+	ioutil.WriteFile(path, text, 0644)
+	s, _ = GetStatus(dir)
+	assert.True(t, s.Dirty)
+
+	lines, err := Blame(path, text)
+	assert.Nil(t, err)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "t", lines[0].Author)
+	assert.Equal(t, "00000000", lines[1].Commit)
+}
@@ -0,0 +1,48 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+)
+
+// A Block is a cell of an image drawn with half blocks: its upper half is
+// the color Top and its lower half the color Bottom. The transparent
+// halves are those of the pixels whose alpha is under half.
+type Block struct {
+	Top, Bottom color.NRGBA
+}
+
+// Rune returns the character drawn in the cell, whose foreground is the
+// color of the half it fills, and whether the background is the other
+// color or the default one. Rune is a space if both halves are transparent.
+func (bl Block) Rune() (rune, color.NRGBA, bool) {
+	top, bottom := bl.Top.A >= 0x80, bl.Bottom.A >= 0x80
+	switch {
+	case top && bottom:
+		return '▀', bl.Top, true
+	case top:
+		return '▀', bl.Top, false
+	case bottom:
+		return '▄', bl.Bottom, false
+	}
+	return ' ', color.NRGBA{}, false
+}
+
+// DrawBlocks returns the image shrunk to fit in cols by rows cells as rows
+// of half blocks, each cell showing two pixels one above the other
+func DrawBlocks(img image.Image, cols, rows int) [][]Block {
+	b := img.Bounds()
+	c, r := Fit(b.Dx(), b.Dy(), cols, rows, 1, 2)
+	if c == 0 {
+		return nil
+	}
+	px := Resize(img, c, 2*r)
+	blocks := make([][]Block, r)
+	for y := range blocks {
+		blocks[y] = make([]Block, c)
+		for x := range blocks[y] {
+			blocks[y][x] = Block{px.NRGBAAt(x, 2*y), px.NRGBAAt(x, 2*y+1)}
+		}
+	}
+	return blocks
+}
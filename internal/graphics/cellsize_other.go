@@ -0,0 +1,9 @@
+// +build plan9 nacl windows solaris
+
+package graphics
+
+// CellSize returns the size in pixels of the cells of the terminal, which
+// isn't known on this system, so a common size
+func CellSize() (int, int) {
+	return defaultCellWidth, defaultCellHeight
+}
@@ -0,0 +1,27 @@
+// +build linux darwin dragonfly openbsd netbsd freebsd
+
+package graphics
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// CellSize returns the size in pixels of the cells of the terminal, as it
+// tells the size of its window, or a common size if it doesn't
+func CellSize() (int, int) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return defaultCellWidth, defaultCellHeight
+	}
+	defer tty.Close()
+	var ws struct {
+		Row, Col, XPixel, YPixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.Row == 0 || ws.Col == 0 || ws.XPixel == 0 || ws.YPixel == 0 {
+		return defaultCellWidth, defaultCellHeight
+	}
+	return int(ws.XPixel / ws.Col), int(ws.YPixel / ws.Row)
+}
@@ -0,0 +1,147 @@
+// Package graphics draws images in the terminal: with the graphics
+// protocols of kitty, iTerm2 or sixel in the terminals which understand
+// them, and otherwise as an approximation with unicode half blocks whose
+// two colors are two pixels of the image
+package graphics
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+
+	// the formats of the images which can be decoded
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// The protocols with which images are drawn
+const (
+	Kitty  = "kitty"
+	ITerm  = "iterm"
+	Sixel  = "sixel"
+	Blocks = "blocks"
+)
+
+// defaultCellWidth and defaultCellHeight are the size in pixels assumed for
+// the cells of the terminal when it isn't known
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// Decode returns the image in data, a PNG, JPEG or GIF file
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Protocol returns the protocol with which images are drawn, given the
+// imageprotocol option: the protocol it names, or for auto the one which
+// the terminal is known to support from its environment variables
+func Protocol(option string) string {
+	if option != "auto" {
+		return option
+	}
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		// the multiplexers don't pass the images through
+		return Blocks
+	}
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty":
+		return Kitty
+	case program == "iTerm.app" || program == "WezTerm" || program == "mintty":
+		return ITerm
+	}
+	for _, t := range []string{"foot", "mlterm", "yaft", "contour"} {
+		if strings.HasPrefix(term, t) {
+			return Sixel
+		}
+	}
+	return Blocks
+}
+
+// Fit returns the number of columns and rows taken by an image of w by h
+// pixels, shrunk to fit in cols by rows cells of cw by ch pixels if it is
+// larger, with its aspect ratio kept
+func Fit(w, h, cols, rows, cw, ch int) (int, int) {
+	if w <= 0 || h <= 0 || cols <= 0 || rows <= 0 {
+		return 0, 0
+	}
+	scale := 1.0
+	if s := float64(cols*cw) / float64(w); s < scale {
+		scale = s
+	}
+	if s := float64(rows*ch) / float64(h); s < scale {
+		scale = s
+	}
+	c := ceil(float64(w) * scale / float64(cw))
+	r := ceil(float64(h) * scale / float64(ch))
+	return clamp(c, 1, cols), clamp(r, 1, rows)
+}
+
+func ceil(f float64) int {
+	n := int(f)
+	if float64(n) < f-1e-9 {
+		n++
+	}
+	return n
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// Resize returns the image scaled to w by h pixels, each pixel the average
+// of those of the image it covers
+func Resize(img image.Image, w, h int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	b := img.Bounds()
+	if b.Empty() || w <= 0 || h <= 0 {
+		return out
+	}
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := b.Min.Y + (y+1)*b.Dy()/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := b.Min.X + (x+1)*b.Dx()/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			// the colors are averaged premultiplied by their alpha, so
+			// that the transparent pixels don't darken the others
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			c := color.NRGBA{}
+			if a > 0 {
+				c = color.NRGBA{
+					R: uint8(r * 0xff / a),
+					G: uint8(g * 0xff / a),
+					B: uint8(bl * 0xff / a),
+					A: uint8(a / n >> 8),
+				}
+			}
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	return out
+}
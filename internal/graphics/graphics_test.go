@@ -0,0 +1,115 @@
+package graphics
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if y < h/2 {
+				img.SetNRGBA(x, y, color.NRGBA{0xff, 0, 0, 0xff})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{0, 0, 0xff, 0xff})
+			}
+		}
+	}
+	return img
+}
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(4, 2)))
+	img, err := Decode(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 2), img.Bounds())
+
+	_, err = Decode([]byte("\x00\x01 not an image"))
+	assert.Error(t, err)
+}
+
+func TestFit(t *testing.T) {
+	// small images aren't scaled up
+	c, r := Fit(20, 40, 80, 24, 1, 2)
+	assert.Equal(t, 20, c)
+	assert.Equal(t, 20, r)
+	// large ones are shrunk keeping their aspect ratio
+	c, r = Fit(1000, 500, 80, 24, 10, 20)
+	assert.Equal(t, 80, c)
+	assert.Equal(t, 20, r)
+	c, r = Fit(500, 1000, 80, 24, 10, 20)
+	assert.Equal(t, 24, c)
+	assert.Equal(t, 24, r)
+	c, r = Fit(0, 10, 80, 24, 10, 20)
+	assert.Equal(t, 0, c)
+	assert.Equal(t, 0, r)
+}
+
+func TestDrawBlocks(t *testing.T) {
+	blocks := DrawBlocks(testImage(4, 8), 2, 2)
+	if assert.Len(t, blocks, 2) && assert.Len(t, blocks[0], 2) {
+		r, fg, both := blocks[0][0].Rune()
+		assert.Equal(t, '▀', r)
+		assert.Equal(t, color.NRGBA{0xff, 0, 0, 0xff}, fg)
+		assert.True(t, both)
+		assert.Equal(t, color.NRGBA{0, 0, 0xff, 0xff}, blocks[1][1].Bottom)
+	}
+
+	// transparent halves show the background
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 2))
+	img.SetNRGBA(0, 1, color.NRGBA{0, 0xff, 0, 0xff})
+	r, fg, both := DrawBlocks(img, 10, 10)[0][0].Rune()
+	assert.Equal(t, '▄', r)
+	assert.Equal(t, color.NRGBA{0, 0xff, 0, 0xff}, fg)
+	assert.False(t, both)
+	r, _, _ = Block{}.Rune()
+	assert.Equal(t, ' ', r)
+}
+
+func TestSequence(t *testing.T) {
+	img := testImage(300, 300)
+
+	seq := Sequence(img, Kitty, 7, 4, 3)
+	assert.True(t, strings.HasPrefix(seq, DeleteKitty(7)+"\x1b_Ga=T,f=100,i=7,c=4,r=3,"))
+	assert.True(t, strings.HasSuffix(seq, "\x1b\\"))
+	// the last chunk ends the transmission
+	assert.Contains(t, seq, "m=0;")
+
+	seq = Sequence(img, ITerm, 0, 4, 3)
+	assert.True(t, strings.HasPrefix(seq, "\x1b]1337;File=inline=1;width=4;height=3;"))
+	assert.True(t, strings.HasSuffix(seq, "\a"))
+
+	seq = Sequence(testImage(2, 12), Sixel, 0, 1, 1)
+	// red and blue, two bands of six rows
+	assert.Equal(t, "\x1bP0;1q\"1;1;2;12#180;2;100;0;0#180~~-#5;2;0;0;100#5~~-\x1b\\", seq)
+
+	assert.Equal(t, 5, level(0xff))
+	assert.Equal(t, 0, level(0x19))
+}
+
+func TestProtocol(t *testing.T) {
+	for _, v := range []string{"TMUX", "TERM", "TERM_PROGRAM", "KITTY_WINDOW_ID"} {
+		defer os.Setenv(v, os.Getenv(v))
+		os.Unsetenv(v)
+	}
+	assert.Equal(t, Sixel, Protocol(Sixel))
+	os.Setenv("TERM", "xterm-256color")
+	assert.Equal(t, Blocks, Protocol("auto"))
+	os.Setenv("TERM", "xterm-kitty")
+	assert.Equal(t, Kitty, Protocol("auto"))
+	os.Setenv("TERM", "foot")
+	assert.Equal(t, Sixel, Protocol("auto"))
+	os.Setenv("TERM_PROGRAM", "iTerm.app")
+	assert.Equal(t, ITerm, Protocol("auto"))
+	os.Setenv("TMUX", "/tmp/tmux-0/default,1,0")
+	assert.Equal(t, Blocks, Protocol("auto"))
+}
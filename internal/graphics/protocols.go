@@ -0,0 +1,174 @@
+package graphics
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+)
+
+// kittyChunk is the size of the chunks of base64 in which the kitty
+// protocol transmits an image
+const kittyChunk = 4096
+
+// Size returns the number of columns and rows which the image takes when
+// drawn with the protocol in at most cols by rows cells
+func Size(img image.Image, protocol string, cols, rows int) (int, int) {
+	b := img.Bounds()
+	if protocol == Blocks {
+		return Fit(b.Dx(), b.Dy(), cols, rows, 1, 2)
+	}
+	cw, ch := CellSize()
+	return Fit(b.Dx(), b.Dy(), cols, rows, cw, ch)
+}
+
+// Sequence returns the escape sequence which draws the image with the
+// protocol in cols by rows cells from the cursor. The kitty protocol keeps
+// the image as id, which replaces the image of the same id.
+func Sequence(img image.Image, protocol string, id, cols, rows int) string {
+	// the image is sent shrunk to the pixels of its cells, or as it is if
+	// it is smaller
+	cw, ch := CellSize()
+	b := img.Bounds()
+	scale := 1.0
+	if s := float64(cols*cw) / float64(b.Dx()); s < scale {
+		scale = s
+	}
+	if s := float64(rows*ch) / float64(b.Dy()); s < scale {
+		scale = s
+	}
+	px := Resize(img, clamp(int(float64(b.Dx())*scale), 1, cols*cw), clamp(int(float64(b.Dy())*scale), 1, rows*ch))
+	switch protocol {
+	case Kitty:
+		return kittySequence(px, id, cols, rows)
+	case ITerm:
+		return itermSequence(px, cols, rows)
+	case Sixel:
+		return sixelSequence(px)
+	}
+	return ""
+}
+
+// DeleteKitty returns the escape sequence which deletes the image id drawn
+// with the kitty protocol
+func DeleteKitty(id int) string {
+	return fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id)
+}
+
+func encodePNG(img image.Image) string {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// kittySequence transmits the image as PNG in chunks and places it under
+// the text without moving the cursor, the terminal not answering
+func kittySequence(img image.Image, id, cols, rows int) string {
+	data := encodePNG(img)
+	var sb strings.Builder
+	sb.WriteString(DeleteKitty(id))
+	for first := true; first || data != ""; first = false {
+		chunk := data
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		data = data[len(chunk):]
+		more := 0
+		if data != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,i=%d,c=%d,r=%d,C=1,z=-1,q=2,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return sb.String()
+}
+
+func itermSequence(img image.Image, cols, rows int) string {
+	return fmt.Sprintf("\x1b]1337;File=inline=1;width=%d;height=%d;preserveAspectRatio=1:%s\a", cols, rows, encodePNG(img))
+}
+
+// sixelSequence draws the image with the colors of a 6x6x6 cube, leaving
+// its transparent pixels as they are
+func sixelSequence(img *image.NRGBA) string {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	index := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+			if c.A < 0x80 {
+				index[y*w+x] = -1
+				continue
+			}
+			index[y*w+x] = level(c.R)*36 + level(c.G)*6 + level(c.B)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\x1bP0;1q\"1;1;%d;%d", w, h)
+	defined := make([]bool, 216)
+	for band := 0; band < h; band += 6 {
+		var colors []int
+		used := make([]bool, 216)
+		for i := band * w; i < (band+6)*w && i < len(index); i++ {
+			if c := index[i]; c >= 0 && !used[c] {
+				used[c] = true
+				colors = append(colors, c)
+			}
+		}
+		for n, c := range colors {
+			if !defined[c] {
+				defined[c] = true
+				fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", c, c/36*20, c/6%6*20, c%6*20)
+			}
+			fmt.Fprintf(&sb, "#%d", c)
+			var last byte
+			run := 0
+			for x := 0; x <= w; x++ {
+				var ch byte
+				if x < w {
+					bits := 0
+					for row := 0; row < 6 && band+row < h; row++ {
+						if index[(band+row)*w+x] == c {
+							bits |= 1 << uint(row)
+						}
+					}
+					ch = byte(63 + bits)
+				}
+				if ch == last && x < w {
+					run++
+					continue
+				}
+				writeRun(&sb, last, run)
+				last, run = ch, 1
+			}
+			if n < len(colors)-1 {
+				sb.WriteByte('$')
+			}
+		}
+		sb.WriteByte('-')
+	}
+	sb.WriteString("\x1b\\")
+	return sb.String()
+}
+
+// level returns the level of a color component in the cube, from 0 to 5
+func level(c uint8) int {
+	return (int(c)*5 + 127) / 255
+}
+
+// writeRun writes n times the sixel character ch
+func writeRun(sb *strings.Builder, ch byte, n int) {
+	switch {
+	case n <= 0:
+	case n > 3:
+		fmt.Fprintf(sb, "!%d%c", n, ch)
+	default:
+		sb.WriteString(strings.Repeat(string(ch), n))
+	}
+}
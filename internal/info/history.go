@@ -4,16 +4,17 @@ import (
 	"encoding/gob"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/zyedidia/micro/v2/internal/config"
 )
 
-// LoadHistory attempts to load user history from configDir/buffers/history
+// LoadHistory attempts to load user history from stateDir/buffers/history
 // into the history map
 // The savehistory option must be on
 func (i *InfoBuf) LoadHistory() {
 	if config.GetGlobalOption("savehistory").(bool) {
-		file, err := os.Open(filepath.Join(config.ConfigDir, "buffers", "history"))
+		file, err := os.Open(filepath.Join(config.StateDir, "buffers", "history"))
 		var decodedMap map[string][]string
 		if err == nil {
 			defer file.Close()
@@ -27,6 +28,9 @@ func (i *InfoBuf) LoadHistory() {
 		}
 
 		if decodedMap != nil {
+			for k, v := range decodedMap {
+				decodedMap[k] = cleanHistory(v)
+			}
 			i.History = decodedMap
 		} else {
 			i.History = make(map[string][]string)
@@ -36,23 +40,36 @@ func (i *InfoBuf) LoadHistory() {
 	}
 }
 
-// SaveHistory saves the user's command history to configDir/buffers/history
-// only if the savehistory option is on
+// SaveHistory saves the user's command history to stateDir/buffers/history
+// only if the savehistory option is on. The history is shared by the
+// instances of micro: the entries saved by the others since this one
+// started are kept, older than the entries of this one.
 func (i *InfoBuf) SaveHistory() {
 	if config.GetGlobalOption("savehistory").(bool) {
-		// Don't save history past 100
+		path := filepath.Join(config.StateDir, "buffers", "history")
+		var disk map[string][]string
+		if file, err := os.Open(path); err == nil {
+			gob.NewDecoder(file).Decode(&disk)
+			file.Close()
+		}
+
+		saved := make(map[string][]string)
+		for k, v := range disk {
+			saved[k] = v
+		}
 		for k, v := range i.History {
-			if len(v) > 100 {
-				i.History[k] = v[len(i.History[k])-100:]
-			}
+			saved[k] = append(saved[k], v...)
+		}
+		for k, v := range saved {
+			saved[k] = cleanHistory(v)
 		}
 
-		file, err := os.Create(filepath.Join(config.ConfigDir, "buffers", "history"))
+		file, err := os.Create(path)
 		if err == nil {
 			defer file.Close()
 			encoder := gob.NewEncoder(file)
 
-			err = encoder.Encode(i.History)
+			err = encoder.Encode(saved)
 			if err != nil {
 				i.Error("Error saving history:", err)
 				return
@@ -61,6 +78,30 @@ func (i *InfoBuf) SaveHistory() {
 	}
 }
 
+// cleanHistory removes empty and duplicate entries from a history, keeping
+// the most recent occurrence of each, and drops the oldest entries beyond
+// the historylength option
+func cleanHistory(h []string) []string {
+	seen := make(map[string]bool)
+	var clean []string
+	for j := len(h) - 1; j >= 0; j-- {
+		if h[j] == "" || seen[h[j]] {
+			continue
+		}
+		seen[h[j]] = true
+		clean = append(clean, h[j])
+	}
+
+	max := int(config.GetGlobalOption("historylength").(float64))
+	if len(clean) > max {
+		clean = clean[:max]
+	}
+	for a, b := 0, len(clean)-1; a < b; a, b = a+1, b-1 {
+		clean[a], clean[b] = clean[b], clean[a]
+	}
+	return clean
+}
+
 // AddToHistory adds a new item to the history for the prompt type `ptype`.
 // This function is not used by micro itself. It is useful for plugins
 // which add their own items to the history, bypassing the infobar command line.
@@ -82,6 +123,16 @@ func (i *InfoBuf) AddToHistory(ptype string, item string) {
 				break
 			}
 		}
+		i.trimHistory(ptype)
+	}
+}
+
+// trimHistory drops the oldest entries of the history for the prompt type
+// beyond the historylength option
+func (i *InfoBuf) trimHistory(ptype string) {
+	h := i.History[ptype]
+	if max := int(config.GetGlobalOption("historylength").(float64)); len(h) > max {
+		i.History[ptype] = append([]string(nil), h[len(h)-max:]...)
 	}
 }
 
@@ -102,3 +153,38 @@ func (i *InfoBuf) DownHistory(history []string) {
 		i.Buffer.GetActiveCursor().GotoLoc(i.End())
 	}
 }
+
+// SearchHistory searches backwards through the history for an older entry
+// containing the text of the prompt, like Ctrl-r in a shell. Repeating the
+// search finds the next older entry containing the same text. The search
+// ends with EndHistorySearch.
+func (i *InfoBuf) SearchHistory(history []string) {
+	if !i.HasPrompt || i.HasYN {
+		return
+	}
+	if !i.searching {
+		i.searching = true
+		i.searchQuery = i.Response()
+		i.searchMsg = i.Msg
+	}
+
+	for j := i.HistoryNum - 1; j >= 0; j-- {
+		if history[j] != history[i.HistoryNum] && strings.Contains(history[j], i.searchQuery) {
+			i.HistoryNum = j
+			i.Replace(i.Start(), i.End(), history[j])
+			i.Buffer.GetActiveCursor().GotoLoc(i.End())
+			i.Msg = "(reverse-search '" + i.searchQuery + "') " + i.searchMsg
+			return
+		}
+	}
+	i.Msg = "(failed reverse-search '" + i.searchQuery + "') " + i.searchMsg
+}
+
+// EndHistorySearch ends a search started with SearchHistory, keeping the
+// entry that was found in the prompt
+func (i *InfoBuf) EndHistorySearch() {
+	if i.searching {
+		i.searching = false
+		i.Msg = i.searchMsg
+	}
+}
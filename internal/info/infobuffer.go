@@ -2,6 +2,7 @@ package info
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/zyedidia/micro/v2/internal/buffer"
 )
@@ -17,15 +18,29 @@ type InfoBuf struct {
 	HasYN      bool
 
 	PromptType string
+	// Masked hides the response of a secret prompt, which is not kept in
+	// the history
+	Masked bool
 
 	Msg    string
 	YNResp bool
+	// the keys answering a choice prompt, and the one typed
+	Choices    string
+	ChoiceResp rune
 
 	// This map stores the history for all the different kinds of uses Prompt has
 	// It's a map of history type -> history array
 	History    map[string][]string
 	HistoryNum int
 
+	// state of a reverse search through the history
+	searching   bool
+	searchQuery string
+	searchMsg   string
+
+	// the text removed by the last kills, which Yank inserts back
+	killed string
+
 	// Is the current message a message from the gutter
 	HasGutter bool
 
@@ -105,12 +120,71 @@ func (i *InfoBuf) Prompt(prompt string, msg string, ptype string, eventcb func(s
 
 	i.PromptType = ptype
 	i.Msg = prompt
+	i.searching = false
 	i.HasPrompt = true
 	i.HasMessage, i.HasError, i.HasYN = false, false, false
 	i.HasGutter = false
 	i.PromptCallback = donecb
 	i.EventCallback = eventcb
 	i.Buffer.Insert(i.Buffer.Start(), msg)
+	// the edits of the previous prompts are not undone in this one
+	i.Buffer.UndoStack = new(buffer.TEStack)
+	i.Buffer.RedoStack = new(buffer.TEStack)
+}
+
+// Response returns the text typed in the prompt, whose lines are joined
+// with newlines
+func (i *InfoBuf) Response() string {
+	lines := make([]string, i.LinesNum())
+	for y := range lines {
+		lines[y] = string(i.LineBytes(y))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Kill removes the text between start and end from the prompt and keeps it
+// for Yank. If more is set, the text is added to the text of the previous
+// kill, before it if the text ends at the cursor, as when killing
+// backwards, and after it otherwise.
+func (i *InfoBuf) Kill(start, end buffer.Loc, more bool) {
+	if start.GreaterThan(end) {
+		start, end = end, start
+	}
+	if start == end {
+		return
+	}
+	text := string(i.Substr(start, end))
+	c := i.Buffer.GetActiveCursor()
+	switch {
+	case !more:
+		i.killed = text
+	case end == c.Loc:
+		i.killed = text + i.killed
+	default:
+		i.killed += text
+	}
+	i.Remove(start, end)
+	c.GotoLoc(start)
+}
+
+// Yank inserts the text of the last kills at the cursor of the prompt
+func (i *InfoBuf) Yank() {
+	if i.killed == "" {
+		return
+	}
+	c := i.Buffer.GetActiveCursor()
+	if c.HasSelection() {
+		c.DeleteSelection()
+		c.ResetSelection()
+	}
+	i.Insert(c.Loc, i.killed)
+}
+
+// SecretPrompt starts a prompt for a passphrase or a secret, whose response
+// is shown masked and is not added to the history
+func (i *InfoBuf) SecretPrompt(prompt string, donecb func(string, bool)) {
+	i.Prompt(prompt, "", "Secret", nil, donecb)
+	i.Masked = true
 }
 
 // YNPrompt creates a yes or no prompt, and the callback returns the yes/no result and whether
@@ -123,38 +197,58 @@ func (i *InfoBuf) YNPrompt(prompt string, donecb func(bool, bool)) {
 	i.Msg = prompt
 	i.HasPrompt = true
 	i.HasYN = true
+	i.Choices = "yn"
 	i.HasMessage, i.HasError = false, false
 	i.HasGutter = false
 	i.YNCallback = donecb
 }
 
+// ChoicePrompt creates a prompt answered by typing one of the keys of
+// choices, and the callback returns the key typed and whether the prompt
+// was canceled
+func (i *InfoBuf) ChoicePrompt(prompt string, choices string, donecb func(rune, bool)) {
+	i.YNPrompt(prompt, func(yes, canceled bool) {
+		donecb(i.ChoiceResp, canceled)
+	})
+	i.Choices = choices
+}
+
 // DonePrompt finishes the current prompt and indicates whether or not it was canceled
 func (i *InfoBuf) DonePrompt(canceled bool) {
 	hadYN := i.HasYN
+	// the callback may start a new prompt, so remember which history to update
+	ptype := i.PromptType
+	masked := i.Masked
+	i.Masked = false
 	i.HasPrompt = false
 	i.HasYN = false
 	i.HasGutter = false
+	i.searching = false
 	if !hadYN {
 		if i.PromptCallback != nil {
-			if canceled {
-				i.Replace(i.Start(), i.End(), "")
-				i.PromptCallback("", true)
-				h := i.History[i.PromptType]
-				i.History[i.PromptType] = h[:len(h)-1]
+			resp := i.Response()
+			i.Replace(i.Start(), i.End(), "")
+			h := i.History[ptype]
+			if masked || canceled && resp == "" {
+				i.History[ptype] = h[:len(h)-1]
 			} else {
-				resp := string(i.LineBytes(0))
-				i.Replace(i.Start(), i.End(), "")
-				i.PromptCallback(resp, false)
-				h := i.History[i.PromptType]
+				// the response of a canceled prompt is kept as well, so
+				// that it is not lost
 				h[len(h)-1] = resp
 
 				// avoid duplicates
 				for j := len(h) - 2; j >= 0; j-- {
 					if h[j] == h[len(h)-1] {
-						i.History[i.PromptType] = append(h[:j], h[j+1:]...)
+						i.History[ptype] = append(h[:j], h[j+1:]...)
 						break
 					}
 				}
+				i.trimHistory(ptype)
+			}
+			if canceled {
+				i.PromptCallback("", true)
+			} else {
+				i.PromptCallback(resp, false)
 			}
 			// i.PromptCallback = nil
 		}
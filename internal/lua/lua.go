@@ -29,15 +29,25 @@ import (
 var L *lua.LState
 var Lock sync.Mutex
 
+// PluginImport returns the import function of the plugin module, if it is
+// set. It is a local of each file of the plugin, hiding the global import,
+// so that the functions it binds to the plugin aren't used by others by
+// mistake. This is not a sandbox: the plugins share the Lua state.
+var PluginImport func(module string) lua.LValue
+
 // LoadFile loads a lua file
 func LoadFile(module string, file string, data []byte) error {
-	pluginDef := []byte("module(\"" + module + "\", package.seeall)")
+	pluginDef := []byte("local import = ... or import module(\"" + module + "\", package.seeall)")
 
 	if fn, err := L.Load(bytes.NewReader(append(pluginDef, data...)), file); err != nil {
 		return err
 	} else {
 		L.Push(fn)
-		return L.PCall(0, lua.MultRet, nil)
+		if PluginImport == nil {
+			return L.PCall(0, lua.MultRet, nil)
+		}
+		L.Push(PluginImport(module))
+		return L.PCall(1, lua.MultRet, nil)
 	}
 }
 
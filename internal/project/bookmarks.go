@@ -0,0 +1,97 @@
+package project
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// A Bookmark is a named line of a file. The names are unique across all
+// files, and the bookmarks set without a name are numbered.
+type Bookmark struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// bookmarksPath returns the file storing the bookmarks of all files
+func bookmarksPath() string {
+	return filepath.Join(dir(), "bookmarks.json")
+}
+
+// Bookmarks returns the stored bookmarks of all files, sorted as by
+// SortBookmarks
+func Bookmarks() ([]Bookmark, error) {
+	var bms []Bookmark
+	if err := readJSON(bookmarksPath(), &bms); err != nil {
+		return nil, err
+	}
+	SortBookmarks(bms)
+	return bms, nil
+}
+
+// SortBookmarks sorts the numbered bookmarks first in the order of their
+// numbers, and then the named ones in alphabetical order
+func SortBookmarks(bms []Bookmark) {
+	sort.SliceStable(bms, func(i, j int) bool {
+		ni, erri := strconv.Atoi(bms[i].Name)
+		nj, errj := strconv.Atoi(bms[j].Name)
+		if erri == nil && errj == nil {
+			return ni < nj
+		} else if erri == nil || errj == nil {
+			return erri == nil
+		}
+		return bms[i].Name < bms[j].Name
+	})
+}
+
+// FileBookmarks returns the bookmarks of the file at the absolute path
+func FileBookmarks(path string) ([]Bookmark, error) {
+	bms, err := Bookmarks()
+	if err != nil {
+		return nil, err
+	}
+	var file []Bookmark
+	for _, bm := range bms {
+		if bm.Path == path {
+			file = append(file, bm)
+		}
+	}
+	return file, nil
+}
+
+// SetFileBookmarks replaces the bookmarks of the file at the absolute path
+// by bms. The bookmarks of other files with the same names are removed.
+func SetFileBookmarks(path string, bms []Bookmark) error {
+	all, err := Bookmarks()
+	if err != nil {
+		// start over if the file is corrupted
+		all = nil
+	}
+	names := make(map[string]bool)
+	for i := range bms {
+		bms[i].Path = path
+		names[bms[i].Name] = true
+	}
+	kept := bms
+	for _, bm := range all {
+		if bm.Path != path && !names[bm.Name] {
+			kept = append(kept, bm)
+		}
+	}
+	return writeJSON(bookmarksPath(), kept)
+}
+
+// NextNumber returns the smallest positive number which is not the name of
+// a bookmark of bms
+func NextNumber(bms []Bookmark) string {
+	used := make(map[string]bool)
+	for _, bm := range bms {
+		used[bm.Name] = true
+	}
+	n := 1
+	for used[strconv.Itoa(n)] {
+		n++
+	}
+	return strconv.Itoa(n)
+}
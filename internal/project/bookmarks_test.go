@@ -0,0 +1,31 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookmarks(t *testing.T) {
+	defer tempConfigDir(t)()
+
+	assert.Nil(t, SetFileBookmarks("/a", []Bookmark{{Name: "10", Line: 1}, {Name: "x", Line: 2}}))
+	assert.Nil(t, SetFileBookmarks("/b", []Bookmark{{Name: "2", Line: 3}, {Name: "b", Line: 0}}))
+	bms, err := Bookmarks()
+	assert.Nil(t, err)
+	var names []string
+	for _, bm := range bms {
+		names = append(names, bm.Name)
+	}
+	assert.Equal(t, []string{"2", "10", "b", "x"}, names)
+	assert.Equal(t, "1", NextNumber(bms))
+
+	// the names are unique across the files
+	assert.Nil(t, SetFileBookmarks("/b", []Bookmark{{Name: "x", Line: 5}}))
+	a, err := FileBookmarks("/a")
+	assert.Nil(t, err)
+	assert.Equal(t, []Bookmark{{Name: "10", Path: "/a", Line: 1}}, a)
+	b, err := FileBookmarks("/b")
+	assert.Nil(t, err)
+	assert.Equal(t, []Bookmark{{Name: "x", Path: "/b", Line: 5}}, b)
+}
@@ -0,0 +1,198 @@
+// Package project stores the state micro keeps for each project: the list
+// of known projects and the recently opened files, along with the bookmarks
+// of all files. A project is the tree below a directory containing a git
+// repository or a .micro.json file with the project's settings, see
+// util.FindProjectRoot.
+package project
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+)
+
+// maxRecent is the number of recent files kept for each project and for
+// all files
+const maxRecent = 100
+
+// A Project is a known project along with the last time it was used
+type Project struct {
+	Root     string    `json:"root"`
+	LastUsed time.Time `json:"lastused"`
+}
+
+// Name returns the name of the project, which is the name of its root
+func (p Project) Name() string {
+	return filepath.Base(p.Root)
+}
+
+// dir returns the directory storing the state of the projects
+func dir() string {
+	return filepath.Join(config.StateDir, "projects")
+}
+
+// StateDir returns the directory storing the state of the project with the
+// given root
+func StateDir(root string) string {
+	return filepath.Join(dir(), util.EscapePath(root))
+}
+
+// readJSON reads the json file at path into v. A missing file is not an
+// error and leaves v unchanged.
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// List returns the known projects, the most recently used first. The
+// projects whose root doesn't exist anymore are left out.
+func List() ([]Project, error) {
+	var projects []Project
+	if err := readJSON(filepath.Join(dir(), "projects.json"), &projects); err != nil {
+		return nil, err
+	}
+	existing := projects[:0]
+	for _, p := range projects {
+		if _, err := os.Stat(p.Root); err == nil {
+			existing = append(existing, p)
+		}
+	}
+	sort.SliceStable(existing, func(i, j int) bool {
+		return existing[i].LastUsed.After(existing[j].LastUsed)
+	})
+	return existing, nil
+}
+
+// Touch adds the project with the given root to the known projects, or
+// marks it as used now if it is already known
+func Touch(root string) error {
+	var projects []Project
+	path := filepath.Join(dir(), "projects.json")
+	if err := readJSON(path, &projects); err != nil {
+		return err
+	}
+	now := time.Now()
+	found := false
+	for i := range projects {
+		if projects[i].Root == root {
+			projects[i].LastUsed = now
+			found = true
+		}
+	}
+	if !found {
+		projects = append(projects, Project{root, now})
+	}
+	return writeJSON(path, projects)
+}
+
+// A RecentFile is a recently opened file along with the cursor position
+// when it was last closed
+type RecentFile struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+// recentPath returns the file storing the recent files of the project with
+// the given root, or of all files if root is empty
+func recentPath(root string) string {
+	if root == "" {
+		return filepath.Join(dir(), "recent.json")
+	}
+	return filepath.Join(StateDir(root), "recent.json")
+}
+
+// Recent returns the files recently opened in the project with the given
+// root, or all recent files if root is empty, the most recent first. The
+// paths are absolute.
+func Recent(root string) ([]RecentFile, error) {
+	var files []RecentFile
+	err := readJSON(recentPath(root), &files)
+	return files, err
+}
+
+// updateRecent calls update on the list of recent files of the project with
+// the given root, or of all files, and saves the result
+func updateRecent(root string, update func([]RecentFile) []RecentFile) error {
+	files, err := Recent(root)
+	if err != nil {
+		// start over if the file is corrupted
+		files = nil
+	}
+	return writeJSON(recentPath(root), update(files))
+}
+
+// addRecent moves path to the front of the recent files, keeping its
+// cursor position if it was already in the list
+func addRecent(root, path string) error {
+	return updateRecent(root, func(files []RecentFile) []RecentFile {
+		recent := []RecentFile{{Path: path}}
+		for _, f := range files {
+			if f.Path == path {
+				recent[0] = f
+			} else if len(recent) < maxRecent {
+				recent = append(recent, f)
+			}
+		}
+		return recent
+	})
+}
+
+// Open records that the file at the absolute path was opened: it becomes
+// the most recent file, and its project, if it is in one, the most recently
+// used project
+func Open(path string) error {
+	if err := addRecent("", path); err != nil {
+		return err
+	}
+	root, ok := util.FindProjectRoot(filepath.Dir(path))
+	if !ok {
+		return nil
+	}
+	if err := addRecent(root, path); err != nil {
+		return err
+	}
+	return Touch(root)
+}
+
+// SaveCursor records the cursor position of the recent file at the absolute
+// path, to restore it when the file is reopened from the recent files
+func SaveCursor(path string, line, col int) error {
+	set := func(files []RecentFile) []RecentFile {
+		for i := range files {
+			if files[i].Path == path {
+				files[i].Line, files[i].Col = line, col
+			}
+		}
+		return files
+	}
+	if err := updateRecent("", set); err != nil {
+		return err
+	}
+	if root, ok := util.FindProjectRoot(filepath.Dir(path)); ok {
+		return updateRecent(root, set)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+func tempConfigDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "micro-config")
+	assert.Nil(t, err)
+	old := config.StateDir
+	config.StateDir = dir
+	return func() {
+		config.StateDir = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	defer tempConfigDir(t)()
+	root, err := ioutil.TempDir("", "micro-project")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "p", ".git"), 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "q"), 0755))
+
+	a := filepath.Join(root, "p", "a.go")
+	b := filepath.Join(root, "p", "b.go")
+	c := filepath.Join(root, "q", "c.txt")
+	for _, f := range []string{a, b, c, a} {
+		assert.Nil(t, Open(f))
+	}
+
+	paths := func(root string) []string {
+		recent, err := Recent(root)
+		assert.Nil(t, err)
+		var paths []string
+		for _, f := range recent {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+	assert.Equal(t, []string{a, c, b}, paths(""))
+	assert.Equal(t, []string{a, b}, paths(filepath.Join(root, "p")))
+
+	projects, err := List()
+	assert.Nil(t, err)
+	assert.Len(t, projects, 1)
+	assert.Equal(t, filepath.Join(root, "p"), projects[0].Root)
+	assert.Equal(t, "p", projects[0].Name())
+}
+
+func TestSaveCursor(t *testing.T) {
+	defer tempConfigDir(t)()
+	root, err := ioutil.TempDir("", "micro-project")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	assert.Nil(t, os.Mkdir(filepath.Join(root, ".git"), 0755))
+
+	a := filepath.Join(root, "a.go")
+	b := filepath.Join(root, "b.go")
+	assert.Nil(t, Open(a))
+	assert.Nil(t, SaveCursor(a, 10, 4))
+	assert.Nil(t, Open(b))
+	assert.Nil(t, Open(a))
+
+	for _, r := range []string{"", root} {
+		recent, err := Recent(r)
+		assert.Nil(t, err)
+		assert.Equal(t, []RecentFile{{a, 10, 4}, {b, 0, 0}}, recent)
+	}
+}
+
+func TestList(t *testing.T) {
+	defer tempConfigDir(t)()
+	root, err := ioutil.TempDir("", "micro-project")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	dirs := []string{filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "gone")}
+	for _, d := range dirs {
+		assert.Nil(t, os.Mkdir(d, 0755))
+		assert.Nil(t, Touch(d))
+	}
+	assert.Nil(t, Touch(dirs[0]))
+	assert.Nil(t, os.Remove(dirs[2]))
+
+	projects, err := List()
+	assert.Nil(t, err)
+	assert.Len(t, projects, 2)
+	assert.Equal(t, dirs[0], projects[0].Root)
+	assert.Equal(t, dirs[1], projects[1].Root)
+}
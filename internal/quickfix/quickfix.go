@@ -0,0 +1,197 @@
+// Package quickfix runs build and lint commands and finds the locations of
+// the messages in their output, for the list of errors
+package quickfix
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+)
+
+// An Entry is a message of the output of a command located in a file
+type Entry struct {
+	// Path is the file of the message, as written in the output
+	Path string
+	// Line and Col are 0-based. Col is 0 if the message has no column.
+	Line, Col int
+	// Kind is the type of the message, such as "error" or "warning", if
+	// the format gives it
+	Kind string
+	Text string
+}
+
+// kinds are the types of messages given by %t
+var kinds = map[byte]string{
+	'e': "error",
+	'w': "warning",
+	'i': "info",
+	'n': "note",
+}
+
+// A Format recognizes the lines of the output which locate a message
+type Format struct {
+	patterns []*regexp.Regexp
+}
+
+// ParseFormat compiles an errorformat: a comma separated list of patterns
+// tried in order on each line of the output, in which %f matches the file,
+// %l the line, %c the column, %m the message and %t the first letter of its
+// type. %% matches a percent sign and \, a comma. A pattern matches a whole
+// line.
+func ParseFormat(format string) (*Format, error) {
+	f := new(Format)
+	for _, p := range splitFormat(format) {
+		if p == "" {
+			continue
+		}
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	if len(f.patterns) == 0 {
+		return nil, errors.New("Empty errorformat")
+	}
+	return f, nil
+}
+
+// splitFormat splits the patterns of an errorformat on the commas which are
+// not escaped
+func splitFormat(format string) []string {
+	var parts []string
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		switch {
+		case format[i] == '\\' && i+1 < len(format) && format[i+1] == ',':
+			sb.WriteByte(',')
+			i++
+		case format[i] == ',':
+			parts = append(parts, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(format[i])
+		}
+	}
+	return append(parts, sb.String())
+}
+
+func compilePattern(p string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' {
+			sb.WriteString(regexp.QuoteMeta(p[i : i+1]))
+			continue
+		}
+		if i+1 >= len(p) {
+			return nil, errors.New("Incomplete errorformat item at the end of " + p)
+		}
+		i++
+		switch p[i] {
+		case 'f':
+			sb.WriteString(`(?P<f>.+?)`)
+		case 'l':
+			sb.WriteString(`(?P<l>\d+)`)
+		case 'c':
+			sb.WriteString(`(?P<c>\d+)`)
+		case 'm':
+			sb.WriteString(`(?P<m>.*)`)
+		case 't':
+			sb.WriteString(`(?P<t>[A-Za-z])`)
+		case '%':
+			sb.WriteString("%")
+		default:
+			return nil, errors.New("Unknown errorformat item %" + p[i:i+1])
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, err
+	}
+	if subexpIndex(re, "f") < 0 || subexpIndex(re, "l") < 0 {
+		return nil, errors.New("The errorformat pattern " + p + " needs %f and %l")
+	}
+	return re, nil
+}
+
+// Parse returns the messages located by the format in the output of a
+// command. The lines which match none of the patterns are ignored.
+func (f *Format) Parse(output string) []Entry {
+	var entries []Entry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		for _, re := range f.patterns {
+			if e, ok := match(re, line); ok {
+				entries = append(entries, e)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// subexpIndex returns the index of the named group of re, or -1
+func subexpIndex(re *regexp.Regexp, name string) int {
+	for i, n := range re.SubexpNames() {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func match(re *regexp.Regexp, line string) (Entry, bool) {
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		return Entry{}, false
+	}
+	group := func(name string) string {
+		if i := subexpIndex(re, name); i >= 0 {
+			return m[i]
+		}
+		return ""
+	}
+
+	var e Entry
+	e.Path = group("f")
+	l, err := strconv.Atoi(group("l"))
+	if err != nil || l < 1 {
+		return Entry{}, false
+	}
+	e.Line = l - 1
+	if c, err := strconv.Atoi(group("c")); err == nil && c > 0 {
+		e.Col = c - 1
+	}
+	if t := group("t"); t != "" {
+		e.Kind = kinds[strings.ToLower(t)[0]]
+	}
+	e.Text = strings.TrimSpace(group("m"))
+	return e, true
+}
+
+// Run runs the command in the directory dir and returns its output and error
+// output. A command which fails is not an error, since builds report errors
+// by failing, but a command which can't be run is.
+func Run(dir, command string) (string, error) {
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("No build command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); ok {
+		err = nil
+	}
+	return string(out), err
+}
@@ -0,0 +1,39 @@
+package quickfix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	f, err := ParseFormat(`%f:%l:%c: %m,%f(%l\,%c): %t%*: %m,%f:%l: %m`)
+	assert.Error(t, err)
+
+	f, err = ParseFormat(`%f:%l:%c: %m,%f(%l\,%c): %t: %m,%f:%l: %m`)
+	assert.NoError(t, err)
+
+	out := "# example\n" +
+		"./main.go:10:2: undefined: x\r\n" +
+		"src/a.c(3,7): w: unused variable\n" +
+		"lib/b.py:42: expected an indented block\n" +
+		"make: *** [all] Error 1\n"
+	assert.Equal(t, []Entry{
+		{Path: "./main.go", Line: 9, Col: 1, Text: "undefined: x"},
+		{Path: "src/a.c", Line: 2, Col: 6, Kind: "warning", Text: "unused variable"},
+		{Path: "lib/b.py", Line: 41, Text: "expected an indented block"},
+	}, f.Parse(out))
+}
+
+func TestParseFormatErrors(t *testing.T) {
+	_, err := ParseFormat("")
+	assert.Error(t, err)
+	_, err = ParseFormat("%f: %m")
+	assert.Error(t, err)
+	_, err = ParseFormat("%f:%l%")
+	assert.Error(t, err)
+
+	f, err := ParseFormat("100%%: %f:%l")
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{{Path: "x.go", Line: 1}}, f.Parse("100%: x.go:2"))
+}
@@ -0,0 +1,260 @@
+// Package rest parses the requests of .http and .rest files and sends them,
+// for the HTTP request runner
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Request is a block of a request file: a request line with a method
+// and a URL, the header lines below it, and a body after an empty line.
+// Blocks are separated by lines starting with ###.
+type Request struct {
+	Method, URL string
+	// Header are the header lines, in the order of the file
+	Header [][2]string
+	Body   string
+	// Start and End are the first and the last line of the block
+	Start, End int
+}
+
+// A File is the requests of a request file and the variables defined in it
+// by lines such as `@host = example.com`, which `{{host}}` is replaced
+// with in the requests
+type File struct {
+	Requests  []Request
+	Variables map[string]string
+}
+
+var (
+	methodRegex   = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|TRACE|CONNECT)\s+((?:\{\{[^}]*\}\}|\S)+)(\s+HTTP/[0-9.]+)?\s*$`)
+	urlRegex      = regexp.MustCompile(`^(https?://|\{\{)(\{\{[^}]*\}\}|\S)*$`)
+	headerRegex   = regexp.MustCompile(`^([!#$%&'*+.^_|~0-9A-Za-z-]+)\s*:\s*(.*)$`)
+	variableRegex = regexp.MustCompile(`^@([A-Za-z_][A-Za-z0-9_.-]*)\s*=\s*(.*?)\s*$`)
+	// refRegex matches the references to the variables, where
+	// {{$processEnv NAME}} is the environment variable NAME
+	refRegex = regexp.MustCompile(`\{\{\s*(\$processEnv\s+)?([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+)
+
+// isComment returns whether the line is a comment, which is ignored out of
+// the bodies
+func isComment(l string) bool {
+	return strings.HasPrefix(l, "#") || strings.HasPrefix(l, "//")
+}
+
+// Parse parses the lines of a request file
+func Parse(lines []string) *File {
+	f := &File{Variables: make(map[string]string)}
+	start := 0
+	for y := 0; y <= len(lines); y++ {
+		if y == len(lines) || strings.HasPrefix(lines[y], "###") {
+			if r, ok := f.parseBlock(lines[start:y], start); ok {
+				f.Requests = append(f.Requests, r)
+			}
+			start = y + 1
+		}
+	}
+	return f
+}
+
+// parseBlock parses the block of lines starting at the line start, and
+// returns false if it has no request line
+func (f *File) parseBlock(lines []string, start int) (Request, bool) {
+	r := Request{Start: start, End: start + len(lines) - 1}
+	y := 0
+	for ; y < len(lines); y++ {
+		l := strings.TrimSpace(lines[y])
+		if m := variableRegex.FindStringSubmatch(l); m != nil {
+			f.Variables[m[1]] = m[2]
+			continue
+		}
+		if l == "" || isComment(l) {
+			continue
+		}
+		if m := methodRegex.FindStringSubmatch(l); m != nil {
+			r.Method, r.URL = m[1], m[2]
+		} else if urlRegex.MatchString(l) {
+			r.Method, r.URL = "GET", l
+		} else {
+			continue
+		}
+		break
+	}
+	if r.URL == "" {
+		return r, false
+	}
+
+	// the query can continue on the lines starting with ? or &
+	for y++; y < len(lines); y++ {
+		l := strings.TrimSpace(lines[y])
+		if !strings.HasPrefix(l, "?") && !strings.HasPrefix(l, "&") {
+			break
+		}
+		r.URL += l
+	}
+	for ; y < len(lines); y++ {
+		l := strings.TrimSpace(lines[y])
+		if l == "" {
+			y++
+			break
+		}
+		if isComment(l) {
+			continue
+		}
+		if m := headerRegex.FindStringSubmatch(l); m != nil {
+			r.Header = append(r.Header, [2]string{m[1], m[2]})
+		}
+	}
+	if y < len(lines) {
+		r.Body = strings.TrimRight(strings.Join(lines[y:], "\n"), "\n \t")
+	}
+	return r, true
+}
+
+// RequestAt returns the request of the block containing the line y
+func (f *File) RequestAt(y int) (Request, bool) {
+	for _, r := range f.Requests {
+		if y >= r.Start && y <= r.End {
+			return r, true
+		}
+	}
+	return Request{}, false
+}
+
+// expand replaces the references to the variables in s. The values of the
+// variables are expanded as well, and the variables which are not defined
+// in the file are looked up in the environment.
+func (f *File) expand(s string, depth int) (string, error) {
+	var err error
+	s = refRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		m := refRegex.FindStringSubmatch(ref)
+		name := m[2]
+		if v, ok := f.Variables[name]; ok && m[1] == "" {
+			if depth > 10 {
+				err = errors.New("The variable " + name + " refers to itself")
+				return ref
+			}
+			v, e := f.expand(v, depth+1)
+			if e != nil {
+				err = e
+			}
+			return v
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		err = errors.New("Undefined variable " + name)
+		return ref
+	})
+	return s, err
+}
+
+// Expand returns the request r with the references to the variables
+// replaced in its URL, headers and body
+func (f *File) Expand(r Request) (Request, error) {
+	var err error
+	expand := func(s string) string {
+		s, e := f.expand(s, 0)
+		if e != nil && err == nil {
+			err = e
+		}
+		return s
+	}
+	r.URL = expand(r.URL)
+	header := make([][2]string, len(r.Header))
+	for i, h := range r.Header {
+		header[i] = [2]string{h[0], expand(h[1])}
+	}
+	r.Header = header
+	r.Body = expand(r.Body)
+	return r, err
+}
+
+// A Response is the response to a request
+type Response struct {
+	Proto, Status string
+	Header        http.Header
+	Body          []byte
+	// Duration is the time from the request to the end of the body
+	Duration time.Duration
+}
+
+// Send sends the request with the client and reads the response
+func (r Request) Send(client *http.Client) (*Response, error) {
+	req, err := http.NewRequest(r.Method, r.URL, strings.NewReader(r.Body))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range r.Header {
+		if strings.EqualFold(h[0], "Host") {
+			req.Host = h[1]
+		} else {
+			req.Header.Add(h[0], h[1])
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Proto:    resp.Proto,
+		Status:   resp.Status,
+		Header:   resp.Header,
+		Body:     data,
+		Duration: time.Since(start),
+	}, nil
+}
+
+// IsJSON returns whether the body of the response is JSON, according to
+// its content type
+func (r *Response) IsJSON() bool {
+	t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return t == "application/json" || strings.HasSuffix(t, "+json")
+}
+
+// Format returns the status line, the headers and the body of the
+// response as they are shown, the headers sorted and a JSON body indented
+func (r *Response) Format() string {
+	var b strings.Builder
+	b.WriteString(r.Proto + " " + r.Status + "\n")
+	var header bytes.Buffer
+	r.Header.Write(&header)
+	b.WriteString(strings.Replace(header.String(), "\r\n", "\n", -1))
+	b.WriteString("\n")
+
+	body := r.Body
+	if r.IsJSON() {
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "", "    ") == nil {
+			body = indented.Bytes()
+		}
+	}
+	b.Write(body)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Summary returns the status of the response, its size and how long it
+// took
+func (r *Response) Summary() string {
+	return r.Status + " (" + strconv.Itoa(len(r.Body)) + " bytes in " + r.Duration.Round(time.Millisecond).String() + ")"
+}
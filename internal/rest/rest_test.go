@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const requests = `@host = {{base}}/api
+@base = http://localhost:8080
+
+# list the users
+GET {{host}}/users
+    ?page=2
+    &limit={{$processEnv REST_TEST_LIMIT}}
+Accept: application/json
+
+###
+
+POST {{host}}/users HTTP/1.1
+Content-Type: application/json
+// a comment
+
+{
+    "name": "{{name}}"
+}
+
+###
+# no request here
+
+### last
+https://example.com/{{$processEnv REST_TEST_PATH}}`
+
+func TestParse(t *testing.T) {
+	f := Parse(strings.Split(requests, "\n"))
+	assert.Equal(t, map[string]string{"host": "{{base}}/api", "base": "http://localhost:8080"}, f.Variables)
+	assert.Equal(t, []Request{
+		{Method: "GET", URL: "{{host}}/users?page=2&limit={{$processEnv REST_TEST_LIMIT}}", Header: [][2]string{{"Accept", "application/json"}}, Start: 0, End: 8},
+		{Method: "POST", URL: "{{host}}/users", Header: [][2]string{{"Content-Type", "application/json"}}, Body: "{\n    \"name\": \"{{name}}\"\n}", Start: 10, End: 18},
+		{Method: "GET", URL: "https://example.com/{{$processEnv REST_TEST_PATH}}", Start: 23, End: 23},
+	}, f.Requests)
+
+	r, ok := f.RequestAt(14)
+	assert.True(t, ok)
+	assert.Equal(t, "POST", r.Method)
+	_, ok = f.RequestAt(20)
+	assert.False(t, ok)
+}
+
+func TestExpand(t *testing.T) {
+	f := Parse(strings.Split(requests, "\n"))
+	os.Setenv("REST_TEST_LIMIT", "10")
+	defer os.Unsetenv("REST_TEST_LIMIT")
+
+	r, err := f.Expand(f.Requests[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/api/users?page=2&limit=10", r.URL)
+	assert.Equal(t, "{{host}}/users?page=2&limit={{$processEnv REST_TEST_LIMIT}}", f.Requests[0].URL)
+
+	_, err = f.Expand(f.Requests[1])
+	assert.EqualError(t, err, "Undefined variable name")
+
+	f.Variables["name"] = "{{name}}"
+	_, err = f.Expand(f.Requests[1])
+	assert.EqualError(t, err, "The variable name refers to itself")
+}
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Method", req.Method)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"got":` + string(body) + `,"auth":"` + req.Header.Get("Authorization") + `"}`))
+	}))
+	defer server.Close()
+
+	r := Request{Method: "POST", URL: server.URL, Header: [][2]string{{"Authorization", "token"}}, Body: `[1,2]`}
+	resp, err := r.Send(server.Client())
+	assert.Nil(t, err)
+	assert.Equal(t, "201 Created", resp.Status)
+	assert.True(t, resp.IsJSON())
+
+	out := resp.Format()
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 201 Created\n"), out)
+	assert.Contains(t, out, "\nX-Method: POST\n")
+	assert.True(t, strings.HasSuffix(out, "\n\n{\n    \"got\": [\n        1,\n        2\n    ],\n    \"auth\": \"token\"\n}\n"), out)
+}
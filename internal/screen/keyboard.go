@@ -0,0 +1,222 @@
+package screen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zyedidia/micro/v2/internal/config"
+	"github.com/zyedidia/micro/v2/internal/util"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// The kitty keyboard protocol makes the terminal send the keys which have
+// no escape sequence of their own, such as Ctrl-Shift-letter, Ctrl-, or
+// Shift-Enter, as CSI code;modifiers u. It is only enabled when the
+// terminal answers the query of its flags, and the terminal keeps the flags
+// of the alternate screen apart, which are dropped when micro leaves it.
+const (
+	kittyQuery = "\x1b[?u"
+	// kittyPush enables disambiguating the escape codes, the first flag of
+	// the protocol
+	kittyPush = "\x1b[>1u"
+	kittyPop  = "\x1b[<u"
+)
+
+// kittyModifiers are the bits of the modifiers of the protocol, which are
+// sent plus one
+const (
+	kittyShift = 1 << iota
+	kittyAlt
+	kittyCtrl
+	kittySuper
+	kittyHyper
+	kittyMeta
+	kittyCapsLock
+	kittyNumLock
+)
+
+// KittyKeys is whether the terminal sends the keys with the kitty keyboard
+// protocol
+var KittyKeys bool
+
+// kittyCodes are the keys which the terminal sends as CSI u when they are
+// pressed with modifiers: Tab, Enter, Esc, Backspace and the keys of ASCII
+// characters
+func kittyCodes() []int {
+	codes := []int{9, 13, 27, 127}
+	for c := ' '; c < 127; c++ {
+		if c < 'A' || c > 'Z' {
+			codes = append(codes, int(c))
+		}
+	}
+	return codes
+}
+
+// PutSequence writes an escape sequence to the terminal, which the
+// simulation screen ignores
+func PutSequence(seq string) {
+	if t, ok := Screen.(interface{ TPuts(string) }); ok {
+		t.TPuts(seq)
+	}
+}
+
+// QueryKeyboard asks the terminal whether it supports the kitty keyboard
+// protocol, unless the kittykeys option is off. Its answer is a raw event,
+// which EnableKittyKeys is called for.
+func QueryKeyboard() {
+	KittyKeys = false
+	if !config.GetGlobalOption("kittykeys").(bool) {
+		return
+	}
+	for flags := 0; flags < 32; flags++ {
+		Screen.RegisterRawSeq(fmt.Sprintf("\x1b[?%du", flags))
+	}
+	PutSequence(kittyQuery)
+}
+
+// IsKeyboardReply returns whether the escape sequence is the answer of the
+// terminal to the query of the flags of the kitty keyboard protocol
+func IsKeyboardReply(seq string) bool {
+	if !strings.HasPrefix(seq, "\x1b[?") || !strings.HasSuffix(seq, "u") {
+		return false
+	}
+	_, err := strconv.Atoi(seq[3 : len(seq)-1])
+	return err == nil
+}
+
+// EnableKittyKeys enables the kitty keyboard protocol in the terminal,
+// which supports it, and registers the sequences of the keys it sends
+func EnableKittyKeys() {
+	if KittyKeys || !config.GetGlobalOption("kittykeys").(bool) {
+		return
+	}
+	KittyKeys = true
+	util.Log(util.LogDebug, "display", "The kitty keyboard protocol is enabled")
+	for _, c := range kittyCodes() {
+		Screen.RegisterRawSeq(fmt.Sprintf("\x1b[%du", c))
+		for m := 1; m <= kittyShift|kittyAlt|kittyCtrl|kittySuper; m++ {
+			for _, lock := range []int{0, kittyCapsLock, kittyNumLock, kittyCapsLock | kittyNumLock} {
+				Screen.RegisterRawSeq(fmt.Sprintf("\x1b[%d;%du", c, m+lock+1))
+			}
+		}
+	}
+	PutSequence(kittyPush)
+}
+
+// DisableKittyKeys makes the terminal send the keys as before the kitty
+// keyboard protocol was enabled
+func DisableKittyKeys() {
+	if KittyKeys {
+		PutSequence(kittyPop)
+		KittyKeys = false
+	}
+}
+
+// ParseKittyKey returns the key event of a key sent with the kitty keyboard
+// protocol, and the event which a terminal without it would send for the
+// key if it differs, or nil. The keys pressed with Super or Hyper aren't
+// key events.
+func ParseKittyKey(seq string) (*tcell.EventKey, *tcell.EventKey, bool) {
+	if !strings.HasPrefix(seq, "\x1b[") || !strings.HasSuffix(seq, "u") {
+		return nil, nil, false
+	}
+	fields := strings.Split(seq[2:len(seq)-1], ";")
+	if len(fields) > 2 {
+		return nil, nil, false
+	}
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, nil, false
+	}
+	bits := 0
+	if len(fields) == 2 {
+		m, err := strconv.Atoi(fields[1])
+		if err != nil || m < 1 {
+			return nil, nil, false
+		}
+		bits = (m - 1) &^ (kittyCapsLock | kittyNumLock)
+	}
+	if bits&^(kittyShift|kittyAlt|kittyCtrl|kittyMeta) != 0 {
+		return nil, nil, false
+	}
+
+	mod := tcell.ModNone
+	if bits&kittyShift != 0 {
+		mod |= tcell.ModShift
+	}
+	if bits&(kittyAlt|kittyMeta) != 0 {
+		mod |= tcell.ModAlt
+	}
+	if bits&kittyCtrl != 0 {
+		mod |= tcell.ModCtrl
+	}
+	alt := mod & tcell.ModAlt
+	key := func(k tcell.Key, r rune, mod tcell.ModMask) *tcell.EventKey {
+		return tcell.NewEventKey(k, r, mod, seq)
+	}
+
+	switch code {
+	case 27:
+		return key(tcell.KeyEsc, 0, mod), key(tcell.KeyEsc, 0, alt), true
+	case 9:
+		if mod == tcell.ModShift {
+			return key(tcell.KeyBacktab, 0, tcell.ModNone), nil, true
+		}
+		return key(tcell.KeyTab, 9, mod), key(tcell.KeyTab, 9, alt), true
+	case 13:
+		return key(tcell.KeyEnter, 13, mod), key(tcell.KeyEnter, 13, alt), true
+	case 127:
+		if mod&tcell.ModCtrl != 0 {
+			return key(tcell.KeyBackspace2, 127, mod), key(tcell.KeyBackspace, 8, alt), true
+		}
+		return key(tcell.KeyBackspace2, 127, mod), key(tcell.KeyBackspace2, 127, alt), true
+	}
+	if code < ' ' || code >= 127 {
+		return nil, nil, false
+	}
+
+	r := rune(code)
+	if mod&tcell.ModCtrl == 0 {
+		// Alt with a letter, which Shift makes upper case, as sent without
+		// the protocol
+		if mod&tcell.ModShift != 0 && r >= 'a' && r <= 'z' {
+			return key(tcell.KeyRune, r-'a'+'A', alt), nil, true
+		}
+		return key(tcell.KeyRune, r, mod), key(tcell.KeyRune, r, alt), true
+	}
+
+	// the control characters which Ctrl makes of some keys without the
+	// protocol, Tab for Ctrl-i and Enter for Ctrl-m
+	var ctrl tcell.Key = -1
+	switch {
+	case r >= 'a' && r <= 'z':
+		ctrl = tcell.KeyCtrlA + tcell.Key(r-'a')
+	case r == ' ' || r == '@':
+		ctrl = tcell.KeyCtrlSpace
+	case r == '[':
+		ctrl = tcell.KeyEsc
+	case r == '\\':
+		ctrl = tcell.KeyCtrlBackslash
+	case r == ']':
+		ctrl = tcell.KeyCtrlRightSq
+	case r == '^':
+		ctrl = tcell.KeyCtrlCarat
+	case r == '_' || r == '/':
+		ctrl = tcell.KeyCtrlUnderscore
+	}
+	if ctrl < 0 {
+		return key(tcell.KeyRune, r, mod), key(tcell.KeyRune, r, alt), true
+	}
+	legacy := key(ctrl, rune(ctrl), alt|tcell.ModCtrl)
+	switch ctrl {
+	case tcell.KeyTab, tcell.KeyEnter, tcell.KeyBackspace:
+		legacy = key(ctrl, rune(ctrl), alt)
+	case tcell.KeyEsc:
+		legacy = key(ctrl, 0, alt)
+	}
+	if r >= 'a' && r <= 'z' || r == ' ' {
+		return key(ctrl, rune(ctrl), mod), legacy, true
+	}
+	return key(tcell.KeyRune, r, mod), legacy, true
+}
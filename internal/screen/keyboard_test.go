@@ -0,0 +1,86 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zyedidia/tcell/v2"
+)
+
+// A testKey is what a test compares of a key event
+type testKey struct {
+	key tcell.Key
+	r   rune
+	mod tcell.ModMask
+}
+
+func describeKey(e *tcell.EventKey) *testKey {
+	if e == nil {
+		return nil
+	}
+	return &testKey{e.Key(), e.Rune(), e.Modifiers()}
+}
+
+func TestParseKittyKey(t *testing.T) {
+	ctrl, shift, alt := tcell.ModCtrl, tcell.ModShift, tcell.ModAlt
+	tests := []struct {
+		seq         string
+		key, legacy *testKey
+	}{
+		// the modifiers are sent plus one, the bits being Shift, Alt, Ctrl
+		// and Super, and Meta is Alt
+		{"\x1b[97u", &testKey{tcell.KeyRune, 'a', 0}, &testKey{tcell.KeyRune, 'a', 0}},
+		{"\x1b[97;3u", &testKey{tcell.KeyRune, 'a', alt}, &testKey{tcell.KeyRune, 'a', alt}},
+		{"\x1b[97;33u", &testKey{tcell.KeyRune, 'a', alt}, &testKey{tcell.KeyRune, 'a', alt}},
+		{"\x1b[97;5u", &testKey{tcell.KeyCtrlA, 1, ctrl}, &testKey{tcell.KeyCtrlA, 1, ctrl}},
+		{"\x1b[97;6u", &testKey{tcell.KeyCtrlA, 1, ctrl | shift}, &testKey{tcell.KeyCtrlA, 1, ctrl}},
+		{"\x1b[97;7u", &testKey{tcell.KeyCtrlA, 1, ctrl | alt}, &testKey{tcell.KeyCtrlA, 1, ctrl | alt}},
+		{"\x1b[97;4u", &testKey{tcell.KeyRune, 'A', alt}, nil},
+		{"\x1b[44;5u", &testKey{tcell.KeyRune, ',', ctrl}, &testKey{tcell.KeyRune, ',', 0}},
+		{"\x1b[32;5u", &testKey{tcell.KeyCtrlSpace, 0, ctrl}, &testKey{tcell.KeyCtrlSpace, 0, ctrl}},
+
+		// Caps Lock and Num Lock are ignored
+		{"\x1b[97;69u", &testKey{tcell.KeyCtrlA, 1, ctrl}, &testKey{tcell.KeyCtrlA, 1, ctrl}},
+		{"\x1b[97;133u", &testKey{tcell.KeyCtrlA, 1, ctrl}, &testKey{tcell.KeyCtrlA, 1, ctrl}},
+		{"\x1b[97;197u", &testKey{tcell.KeyCtrlA, 1, ctrl}, &testKey{tcell.KeyCtrlA, 1, ctrl}},
+
+		// the control characters sent for them without the protocol
+		{"\x1b[105;5u", &testKey{tcell.KeyTab, 9, ctrl}, &testKey{tcell.KeyTab, 9, 0}},
+		{"\x1b[109;5u", &testKey{tcell.KeyEnter, 13, ctrl}, &testKey{tcell.KeyEnter, 13, 0}},
+		{"\x1b[91;5u", &testKey{tcell.KeyRune, '[', ctrl}, &testKey{tcell.KeyEsc, 0, 0}},
+		{"\x1b[47;5u", &testKey{tcell.KeyRune, '/', ctrl}, &testKey{tcell.KeyCtrlUnderscore, 31, ctrl}},
+		{"\x1b[95;7u", &testKey{tcell.KeyRune, '_', ctrl | alt}, &testKey{tcell.KeyCtrlUnderscore, 31, ctrl | alt}},
+		{"\x1b[92;5u", &testKey{tcell.KeyRune, '\\', ctrl}, &testKey{tcell.KeyCtrlBackslash, 28, ctrl}},
+
+		// the keys with escape sequences of their own
+		{"\x1b[9;2u", &testKey{tcell.KeyBacktab, 0, 0}, nil},
+		{"\x1b[9;5u", &testKey{tcell.KeyTab, 9, ctrl}, &testKey{tcell.KeyTab, 9, 0}},
+		{"\x1b[13;2u", &testKey{tcell.KeyEnter, 13, shift}, &testKey{tcell.KeyEnter, 13, 0}},
+		{"\x1b[13;3u", &testKey{tcell.KeyEnter, 13, alt}, &testKey{tcell.KeyEnter, 13, alt}},
+		{"\x1b[27u", &testKey{tcell.KeyEsc, 0, 0}, &testKey{tcell.KeyEsc, 0, 0}},
+		{"\x1b[127;5u", &testKey{tcell.KeyBackspace2, 127, ctrl}, &testKey{tcell.KeyBackspace, 8, 0}},
+		{"\x1b[127;2u", &testKey{tcell.KeyBackspace2, 127, shift}, &testKey{tcell.KeyBackspace2, 127, 0}},
+	}
+	for _, test := range tests {
+		key, legacy, ok := ParseKittyKey(test.seq)
+		if assert.True(t, ok, "%q", test.seq) {
+			assert.Equal(t, test.key, describeKey(key), "%q", test.seq)
+			assert.Equal(t, test.legacy, describeKey(legacy), "%q", test.seq)
+		}
+	}
+
+	// Super and Hyper, and the sequences which are not keys
+	for _, seq := range []string{"\x1b[97;9u", "\x1b[97;17u", "\x1b[97;0u", "\x1b[97;5;1u", "\x1b[3u", "\x1b[200u", "\x1b[xu", "\x1b[97~", "\x1b[?1u"} {
+		_, _, ok := ParseKittyKey(seq)
+		assert.False(t, ok, "%q", seq)
+	}
+}
+
+func TestIsKeyboardReply(t *testing.T) {
+	assert.True(t, IsKeyboardReply("\x1b[?0u"))
+	assert.True(t, IsKeyboardReply("\x1b[?31u"))
+	assert.False(t, IsKeyboardReply("\x1b[?u"))
+	assert.False(t, IsKeyboardReply("\x1b[?1;2u"))
+	assert.False(t, IsKeyboardReply("\x1b[97u"))
+	assert.False(t, IsKeyboardReply("\x1b[?1c"))
+}
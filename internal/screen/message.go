@@ -3,11 +3,28 @@ package screen
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+
+	isatty "github.com/mattn/go-isatty"
 )
 
+// termIO returns the input and output to talk with the user, which are those
+// of the controlling terminal when the standard ones are redirected, as in
+// `cmd | micro -filter | cmd`, and the function closing them
+func termIO() (io.Reader, io.Writer, func()) {
+	if isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd()) {
+		return os.Stdin, os.Stdout, func() {}
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return os.Stdin, os.Stdout, func() {}
+	}
+	return tty, tty, func() { tty.Close() }
+}
+
 // TermMessage sends a message to the user in the terminal. This usually occurs before
 // micro has been fully initialized -- ie if there is an error in the syntax highlighting
 // regular expressions
@@ -17,11 +34,13 @@ import (
 func TermMessage(msg ...interface{}) {
 	screenb := TempFini()
 
-	fmt.Println(msg...)
-	fmt.Print("\nPress enter to continue")
+	in, out, done := termIO()
+	fmt.Fprintln(out, msg...)
+	fmt.Fprint(out, "\nPress enter to continue")
 
-	reader := bufio.NewReader(os.Stdin)
+	reader := bufio.NewReader(in)
 	reader.ReadString('\n')
+	done()
 
 	TempStart(screenb)
 }
@@ -34,11 +53,14 @@ func TermMessage(msg ...interface{}) {
 func TermPrompt(prompt string, options []string, wait bool) int {
 	screenb := TempFini()
 
+	in, out, done := termIO()
+	defer done()
+
 	idx := -1
 	// same behavior as do { ... } while (wait && idx == -1)
 	for ok := true; ok; ok = wait && idx == -1 {
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print(prompt)
+		reader := bufio.NewReader(in)
+		fmt.Fprint(out, prompt)
 		resp, _ := reader.ReadString('\n')
 		resp = strings.TrimSpace(resp)
 
@@ -49,7 +71,7 @@ func TermPrompt(prompt string, options []string, wait bool) int {
 		}
 
 		if wait && idx == -1 {
-			fmt.Println("\nInvalid choice.")
+			fmt.Fprintln(out, "\nInvalid choice.")
 		}
 	}
 
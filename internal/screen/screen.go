@@ -2,7 +2,6 @@ package screen
 
 import (
 	"errors"
-	"log"
 	"os"
 	"sync"
 
@@ -48,6 +47,25 @@ func Redraw() {
 	}
 }
 
+// redrawAll is set when the content of the screen is lost, for example
+// when it is restarted, and the next frame must draw every cell
+var redrawAll bool
+
+// RedrawAll schedules a redraw of the whole screen, instead of only the
+// parts that changed
+func RedrawAll() {
+	redrawAll = true
+	Redraw()
+}
+
+// TakeRedrawAll returns whether the whole screen must be redrawn, and
+// resets it
+func TakeRedrawAll() bool {
+	r := redrawAll
+	redrawAll = false
+	return r
+}
+
 // DrawChan returns the draw channel
 func DrawChan() chan bool {
 	return drawChan
@@ -113,12 +131,22 @@ func SetContent(x, y int, mainc rune, combc []rune, style tcell.Style) {
 	}
 }
 
+// Fini shuts the screen down, giving the terminal back its title and the
+// keys it sent before micro
+func Fini() {
+	DisableKittyKeys()
+	RestoreTitle()
+	Screen.Fini()
+}
+
 // TempFini shuts the screen down temporarily
 func TempFini() bool {
 	screenWasNil := Screen == nil
 
 	if !screenWasNil {
-		Screen.Fini()
+		// the commands run meanwhile may tell another directory
+		directory = ""
+		Fini()
 		Lock()
 		Screen = nil
 	}
@@ -136,12 +164,17 @@ func TempStart(screenWasNil bool) {
 // Init creates and initializes the tcell screen
 func Init() error {
 	drawChan = make(chan bool, 8)
+	redrawAll = true
 
-	// Should we enable true color?
-	truecolor := os.Getenv("MICRO_TRUECOLOR") == "1"
-
-	if !truecolor {
+	// True color is used when the terminal tells it supports it with
+	// COLORTERM, unless MICRO_TRUECOLOR is 0. MICRO_TRUECOLOR=1 enables it
+	// for the terminals which support it without telling.
+	modifiedColorterm := false
+	if os.Getenv("MICRO_TRUECOLOR") == "0" {
 		os.Setenv("TCELL_TRUECOLOR", "disable")
+	} else if os.Getenv("MICRO_TRUECOLOR") == "1" && os.Getenv("COLORTERM") == "" {
+		os.Setenv("COLORTERM", "truecolor")
+		modifiedColorterm = true
 	}
 
 	var oldTerm string
@@ -160,8 +193,8 @@ func Init() error {
 	var err error
 	Screen, err = tcell.NewScreen()
 	if err != nil {
-		log.Println("Warning: during screen initialization:", err)
-		log.Println("Falling back to TERM=xterm-256color")
+		util.Log(util.LogWarn, "display", "During screen initialization:", err)
+		util.Log(util.LogWarn, "display", "Falling back to TERM=xterm-256color")
 		setXterm()
 		Screen, err = tcell.NewScreen()
 		if err != nil {
@@ -171,17 +204,24 @@ func Init() error {
 	if err = Screen.Init(); err != nil {
 		return err
 	}
+	config.TermColors = Screen.Colors()
+	util.Log(util.LogDebug, "display", "TERM="+os.Getenv("TERM"), "COLORTERM="+os.Getenv("COLORTERM"), "colors:", Screen.Colors())
 
 	Screen.SetPaste(config.GetGlobalOption("paste").(bool))
+	util.SetAmbiguousWidth(config.GetGlobalOption("ambiguouswidth").(string))
 
-	// restore TERM
+	// restore TERM and COLORTERM
 	if modifiedTerm {
 		os.Setenv("TERM", oldTerm)
 	}
+	if modifiedColorterm {
+		os.Unsetenv("COLORTERM")
+	}
 
 	if config.GetGlobalOption("mouse").(bool) {
 		Screen.EnableMouse()
 	}
+	QueryKeyboard()
 
 	return nil
 }
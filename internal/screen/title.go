@@ -0,0 +1,68 @@
+package screen
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// The title of the terminal is set with OSC 2, after the title it had is
+// saved on the stack of titles of xterm, which is restored when micro
+// leaves the screen. OSC 7 tells the terminal the directory micro works
+// in, which terminals and tmux open their new windows in.
+const (
+	titlePush = "\x1b[22;2t"
+	titlePop  = "\x1b[23;2t"
+)
+
+var (
+	// title is the title set last, and titleSaved whether the title of the
+	// terminal was saved
+	title      string
+	titleSaved bool
+	// directory is the directory told to the terminal last
+	directory string
+)
+
+// SetTitle sets the title of the terminal, unless it is the title set
+// already. The control characters of the title are dropped.
+func SetTitle(t string) {
+	t = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, t)
+	if titleSaved && t == title {
+		return
+	}
+	if !titleSaved {
+		PutSequence(titlePush)
+		titleSaved = true
+	}
+	title = t
+	PutSequence("\x1b]2;" + t + "\x07")
+}
+
+// RestoreTitle gives the terminal back the title it had before SetTitle
+func RestoreTitle() {
+	if titleSaved {
+		PutSequence(titlePop)
+		titleSaved = false
+		title = ""
+	}
+}
+
+// SetDirectory tells the terminal the directory micro works in with OSC 7,
+// unless it is the directory told already
+func SetDirectory(dir string) {
+	if dir == directory {
+		return
+	}
+	directory = dir
+	host, _ := os.Hostname()
+	u := url.URL{Scheme: "file", Host: host, Path: filepath.ToSlash(dir)}
+	PutSequence("\x1b]7;" + u.String() + "\x1b\\")
+}
@@ -31,6 +31,24 @@ func ExecCommand(name string, arg ...string) (string, error) {
 	return outstring, err
 }
 
+// Filter runs a command with input as its standard input and returns its
+// standard output. If the command fails, the error includes its standard
+// error.
+func Filter(name string, args []string, input []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
 // RunCommand executes a shell command and returns the output/error
 func RunCommand(input string) (string, error) {
 	args, err := shellquote.Split(input)
@@ -2,11 +2,17 @@ package shell
 
 import (
 	"bytes"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/zyedidia/micro/v2/internal/buffer"
+	"github.com/zyedidia/micro/v2/internal/config"
 	"github.com/zyedidia/micro/v2/internal/screen"
+	"github.com/zyedidia/micro/v2/internal/util"
 	"github.com/zyedidia/terminal"
 )
 
@@ -36,6 +42,25 @@ type Terminal struct {
 	getOutput bool
 	output    *bytes.Buffer
 	callback  CallbackFunc
+
+	// Dir is the directory the command is started in, the working directory
+	// if it is empty, and Env holds the variables added to its environment
+	Dir string
+	Env []string
+	// LastCommand is the last line entered in the terminal, as far as it can
+	// be followed from the keys sent to it
+	LastCommand string
+
+	cmd *exec.Cmd
+	// input is the line being entered, or nil when it was edited otherwise
+	// than by typing and erasing characters
+	input []rune
+
+	// width and height are the size of the screen of the emulator, and
+	// scrollback holds the lines which scrolled off its top, both guarded
+	// by the lock of the State
+	width, height int
+	scrollback    []string
 }
 
 // HasSelection returns whether this terminal has a valid selection
@@ -75,6 +100,10 @@ func (t *Terminal) Start(execCmd []string, getOutput bool, wait bool, callback f
 	}
 
 	cmd := exec.Command(execCmd[0], execCmd[1:]...)
+	cmd.Dir = t.Dir
+	if len(t.Env) > 0 {
+		cmd.Env = append(os.Environ(), t.Env...)
+	}
 	t.output = nil
 	if getOutput {
 		t.output = bytes.NewBuffer([]byte{})
@@ -84,6 +113,8 @@ func (t *Terminal) Start(execCmd []string, getOutput bool, wait bool, callback f
 		return err
 	}
 	t.Term = Term
+	t.cmd = cmd
+	t.input = []rune{}
 	t.getOutput = getOutput
 	t.Status = TTRunning
 	t.title = execCmd[0] + ":" + strconv.Itoa(cmd.Process.Pid)
@@ -92,14 +123,20 @@ func (t *Terminal) Start(execCmd []string, getOutput bool, wait bool, callback f
 		callback(out, userargs)
 	}
 
+	t.width, t.height = 80, 24
+	t.scrollback = nil
+
 	go func() {
+		buf := make([]byte, 4096)
+		var pending []byte
 		for {
-			err := Term.Parse()
+			n, err := Term.File().Read(buf)
 			if err != nil {
 				Term.Write([]byte("Press enter to close"))
 				screen.Redraw()
 				break
 			}
+			pending = t.parse(append(pending, buf[:n]...))
 			screen.Redraw()
 		}
 		t.Stop()
@@ -108,6 +145,129 @@ func (t *Terminal) Start(execCmd []string, getOutput bool, wait bool, callback f
 	return nil
 }
 
+// parse feeds the output p of the command to the emulator line by line, so
+// that the lines scrolling off the screen can be kept, and returns what is
+// left of an incomplete character at its end
+func (t *Terminal) parse(p []byte) []byte {
+	end := len(p)
+	for i := 1; i <= utf8.UTFMax && i <= len(p); i++ {
+		if utf8.RuneStart(p[len(p)-i]) {
+			if !utf8.FullRune(p[len(p)-i:]) {
+				end -= i
+			}
+			break
+		}
+	}
+	for q := p[:end]; len(q) > 0; {
+		n := bytes.IndexByte(q, '\n') + 1
+		if n == 0 {
+			n = len(q)
+		}
+		t.write(q[:n])
+		q = q[n:]
+	}
+	return append([]byte(nil), p[end:]...)
+}
+
+// write feeds p, which holds at most one line break at its end, to the
+// emulator and adds the lines it scrolls off the screen to the scrollback.
+// They are found by comparing the screen with how it was before, the line
+// being written to aside.
+func (t *Terminal) write(p []byte) {
+	t.State.Lock()
+	_, y := t.State.Cursor()
+	track := y == t.height-1 && t.height > 2 && !t.State.Mode(terminal.ModeAltScreen) &&
+		config.GetGlobalOption("scrollback").(float64) > 0
+	var before []string
+	if track {
+		before = t.screenLines()
+	}
+	t.State.Unlock()
+
+	t.Term.Write(p)
+	if !track {
+		return
+	}
+
+	t.State.Lock()
+	defer t.State.Unlock()
+	after := t.screenLines()
+	if len(after) != len(before) {
+		return
+	}
+	rows := len(before)
+	n := 0
+	if _, y := t.State.Cursor(); p[len(p)-1] == '\n' && y == rows-1 {
+		// the line break at the bottom scrolled the screen
+		n = 1
+	}
+	for ; n < rows-1; n++ {
+		if equalLines(before[n:rows-1], after[:rows-1-n]) {
+			break
+		}
+	}
+	if n == 0 || n == rows-1 {
+		return
+	}
+	t.scrollback = append(t.scrollback, before[:n]...)
+	if max := int(config.GetGlobalOption("scrollback").(float64)); len(t.scrollback) > max {
+		t.scrollback = t.scrollback[len(t.scrollback)-max:]
+	}
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// screenLines returns the text of the lines of the screen, without trailing
+// whitespace. The State must be locked.
+func (t *Terminal) screenLines() []string {
+	lines := make([]string, t.height)
+	var sb strings.Builder
+	for y := range lines {
+		sb.Reset()
+		for x := 0; x < t.width; x++ {
+			c, _, _ := t.State.Cell(x, y)
+			if c == 0 {
+				c = ' '
+			}
+			sb.WriteRune(c)
+		}
+		lines[y] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+// History returns the text of the scrollback followed by the screen, without
+// the blank lines at the bottom of the screen
+func (t *Terminal) History() string {
+	t.State.Lock()
+	defer t.State.Unlock()
+	lines := append(append([]string(nil), t.scrollback...), t.screenLines()...)
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetSize resizes the screen of the emulator
+func (t *Terminal) SetSize(width, height int) {
+	// the size is never larger than that of the screen of the State, which
+	// is resized in between
+	t.State.Lock()
+	t.width, t.height = util.Min(width, t.width), util.Min(height, t.height)
+	t.State.Unlock()
+	t.Term.Resize(width, height)
+	t.State.Lock()
+	t.width, t.height = width, height
+	t.State.Unlock()
+}
+
 // Stop stops execution of the terminal and sets the Status
 // to TTDone
 func (t *Terminal) Stop() {
@@ -136,4 +296,51 @@ func (t *Terminal) Close() {
 // WriteString writes a given string to this terminal's pty
 func (t *Terminal) WriteString(str string) {
 	t.Term.File().WriteString(str)
+	t.trackInput(str)
+}
+
+// trackInput follows the line entered in the terminal to set LastCommand.
+// The line becomes unknown when it is edited with other keys than typing
+// and erasing characters, such as arrows and tab completion.
+func (t *Terminal) trackInput(str string) {
+	for _, r := range str {
+		switch {
+		case r == '\r':
+			if cmd := strings.TrimSpace(string(t.input)); t.input != nil && cmd != "" {
+				t.LastCommand = cmd
+			}
+			t.input = []rune{}
+		case r == 0x7f || r == '\b':
+			if len(t.input) > 0 {
+				t.input = t.input[:len(t.input)-1]
+			}
+		case r < 0x20:
+			t.input = nil
+		case t.input != nil:
+			t.input = append(t.input, r)
+		}
+	}
+}
+
+// Command returns the command the terminal was started with
+func (t *Terminal) Command() []string {
+	if t.cmd == nil {
+		return nil
+	}
+	return t.cmd.Args
+}
+
+// WorkingDir returns the working directory of the command, as far as the
+// system tells it, or else the directory it was started in
+func (t *Terminal) WorkingDir() string {
+	if t.cmd != nil && t.cmd.Process != nil {
+		if dir, err := os.Readlink("/proc/" + strconv.Itoa(t.cmd.Process.Pid) + "/cwd"); err == nil {
+			return dir
+		}
+	}
+	dir, err := filepath.Abs(t.Dir)
+	if err != nil {
+		return t.Dir
+	}
+	return dir
 }
@@ -0,0 +1,328 @@
+// Package spell implements a spell checker using dictionaries in the
+// hunspell format, which are made of a word list (.dic) and affix rules
+// (.aff) deriving the other forms of the words
+package spell
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// A Dictionary is a set of correctly spelled words
+type Dictionary struct {
+	words map[string]bool
+	// try holds the characters used to build suggestions, the most
+	// common first
+	try string
+}
+
+// NewDictionary returns an empty dictionary
+func NewDictionary() *Dictionary {
+	return &Dictionary{
+		words: make(map[string]bool),
+		try:   "esianrtolcdugmphbyfvkwzxjq'",
+	}
+}
+
+// Add adds a word to the dictionary
+func (d *Dictionary) Add(word string) {
+	d.words[word] = true
+}
+
+// Len returns the number of words of the dictionary, including the forms
+// derived by affixes
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Check returns whether the word is spelled correctly. A capitalized or
+// all uppercase word is also correct if its lowercase form is, and an all
+// uppercase word if its capitalized form is.
+func (d *Dictionary) Check(word string) bool {
+	if d.words[word] {
+		return true
+	}
+	lower := strings.ToLower(word)
+	if lower == word {
+		return false
+	}
+	if d.words[lower] {
+		return true
+	}
+	if strings.ToUpper(word) == word {
+		return d.words[capitalize(lower)]
+	}
+	return false
+}
+
+// capitalize returns s with its first letter in uppercase
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// affix is a rule which adds a prefix or a suffix to a word
+type affix struct {
+	prefix bool
+	// cross is set if the rule can be combined with an affix of the other
+	// kind
+	cross bool
+	strip string
+	add   string
+	cond  *regexp.Regexp
+}
+
+// apply returns the word with the affix, or false if the rule doesn't
+// apply to the word
+func (a *affix) apply(word string) (string, bool) {
+	if a.prefix {
+		if !strings.HasPrefix(word, a.strip) || (a.cond != nil && !a.cond.MatchString(word)) {
+			return "", false
+		}
+		return a.add + word[len(a.strip):], true
+	}
+	if !strings.HasSuffix(word, a.strip) || (a.cond != nil && !a.cond.MatchString(word)) {
+		return "", false
+	}
+	return word[:len(word)-len(a.strip)] + a.add, true
+}
+
+// affixFile holds the rules of an .aff file which are used by the checker
+type affixFile struct {
+	// flagType is "long" for two character flags, "num" for numbers
+	// separated by commas and "" for single characters
+	flagType  string
+	affixes   map[string][]*affix
+	needAffix string
+	forbidden string
+	try       string
+}
+
+// parseFlags splits the flags of a word according to the flag type
+func (af *affixFile) parseFlags(s string) []string {
+	var flags []string
+	switch af.flagType {
+	case "long":
+		rs := []rune(s)
+		for i := 0; i+1 < len(rs); i += 2 {
+			flags = append(flags, string(rs[i:i+2]))
+		}
+	case "num":
+		for _, f := range strings.Split(s, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				flags = append(flags, f)
+			}
+		}
+	default:
+		for _, r := range s {
+			flags = append(flags, string(r))
+		}
+	}
+	return flags
+}
+
+// decoder returns a reader decoding r from the encoding named by SET in an
+// affix file
+func decoder(r io.Reader, set string) io.Reader {
+	if set == "" || strings.EqualFold(set, "UTF-8") {
+		return r
+	}
+	enc, err := htmlindex.Get(set)
+	if err != nil {
+		return r
+	}
+	return transform.NewReader(r, enc.NewDecoder())
+}
+
+// parseAffixes reads the affix rules from an .aff file. Only the
+// parts used for checking words are supported: prefixes, suffixes and
+// the flags marking words which are forbidden or need an affix.
+func parseAffixes(r io.Reader) (*affixFile, string, error) {
+	af := &affixFile{affixes: make(map[string][]*affix)}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the encoding applies to the whole file, including the lines before
+	// SET which are ASCII
+	set := ""
+	for _, l := range strings.Split(string(data), "\n") {
+		if f := strings.Fields(l); len(f) >= 2 && f[0] == "SET" {
+			set = f[1]
+			break
+		}
+	}
+	if decoded, err := ioutil.ReadAll(decoder(bytes.NewReader(data), set)); err == nil {
+		data = decoded
+	}
+	lines := strings.Split(string(data), "\n")
+
+	cross := make(map[string]bool)
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) < 2 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		switch f[0] {
+		case "FLAG":
+			af.flagType = strings.ToLower(f[1])
+			if af.flagType == "utf-8" {
+				af.flagType = ""
+			}
+		case "TRY":
+			af.try = f[1]
+		case "NEEDAFFIX":
+			af.needAffix = f[1]
+		case "FORBIDDENWORD":
+			af.forbidden = f[1]
+		case "PFX", "SFX":
+			if len(f) == 4 {
+				// header: flag, cross product, number of rules
+				if _, err := strconv.Atoi(f[3]); err == nil {
+					cross[f[0]+f[1]] = f[2] == "Y"
+					continue
+				}
+			}
+			if len(f) < 4 {
+				continue
+			}
+			a := &affix{prefix: f[0] == "PFX", cross: cross[f[0]+f[1]]}
+			if f[2] != "0" {
+				a.strip = f[2]
+			}
+			add := f[3]
+			if i := strings.IndexByte(add, '/'); i >= 0 {
+				// continuation classes aren't supported
+				add = add[:i]
+			}
+			if add != "0" {
+				a.add = add
+			}
+			cond := "."
+			if len(f) > 4 {
+				cond = f[4]
+			}
+			if cond != "." {
+				expr := cond + "$"
+				if a.prefix {
+					expr = "^" + cond
+				}
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					continue
+				}
+				a.cond = re
+			}
+			af.affixes[f[1]] = append(af.affixes[f[1]], a)
+		}
+	}
+	return af, set, nil
+}
+
+// Parse reads a dictionary in the hunspell format from its word list and
+// its affix file. The affix file may be nil, in which case the word list
+// is read as plain words, one per line.
+func Parse(dic, aff io.Reader) (*Dictionary, error) {
+	d := NewDictionary()
+	af := &affixFile{}
+	if aff != nil {
+		var set string
+		var err error
+		af, set, err = parseAffixes(aff)
+		if err != nil {
+			return nil, err
+		}
+		dic = decoder(dic, set)
+		if af.try != "" {
+			d.try = strings.ToLower(af.try)
+		}
+	}
+
+	scanner := bufio.NewScanner(dic)
+	first := true
+	for scanner.Scan() {
+		l := strings.TrimSpace(scanner.Text())
+		if first {
+			first = false
+			// the first line is the approximate number of words
+			if _, err := strconv.Atoi(l); err == nil {
+				continue
+			}
+		}
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		// morphological fields follow a tab or a space
+		if i := strings.IndexAny(l, "\t "); i >= 0 {
+			l = l[:i]
+		}
+		word, flags := l, ""
+		if i := strings.Index(l, "/"); i > 0 {
+			word, flags = l[:i], l[i+1:]
+		}
+		d.addForms(af, word, af.parseFlags(flags))
+	}
+	return d, scanner.Err()
+}
+
+// addForms adds a word and the forms derived from it by the affixes given
+// by its flags
+func (d *Dictionary) addForms(af *affixFile, word string, flags []string) {
+	bare := true
+	for _, f := range flags {
+		if f == af.forbidden {
+			return
+		}
+		if f == af.needAffix {
+			bare = false
+		}
+	}
+	if bare {
+		d.Add(word)
+	}
+
+	var prefixes []*affix
+	for _, f := range flags {
+		for _, a := range af.affixes[f] {
+			if a.prefix {
+				prefixes = append(prefixes, a)
+				if w, ok := a.apply(word); ok {
+					d.Add(w)
+				}
+			}
+		}
+	}
+	for _, f := range flags {
+		for _, a := range af.affixes[f] {
+			if a.prefix {
+				continue
+			}
+			w, ok := a.apply(word)
+			if !ok {
+				continue
+			}
+			d.Add(w)
+			if !a.cross {
+				continue
+			}
+			for _, p := range prefixes {
+				if p.cross {
+					if pw, ok := p.apply(w); ok {
+						d.Add(pw)
+					}
+				}
+			}
+		}
+	}
+}
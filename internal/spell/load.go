@@ -0,0 +1,123 @@
+package spell
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/zyedidia/micro/v2/internal/config"
+)
+
+// dictionaryDirs returns the directories searched for the .dic and .aff
+// files of a language: the dictionaries directories of micro's data and
+// configuration, the directories in $DICPATH and the usual system directories
+func dictionaryDirs() []string {
+	dirs := []string{filepath.Join(config.DataDir, "dictionaries")}
+	if config.ConfigDir != config.DataDir {
+		dirs = append(dirs, filepath.Join(config.ConfigDir, "dictionaries"))
+	}
+	if p := os.Getenv("DICPATH"); p != "" {
+		dirs = append(dirs, filepath.SplitList(p)...)
+	}
+	dirs = append(dirs,
+		"/usr/share/hunspell",
+		"/usr/share/myspell",
+		"/usr/share/myspell/dicts",
+		"/usr/local/share/hunspell",
+		"/Library/Spelling",
+	)
+	if home, err := homedir.Dir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Library", "Spelling"))
+	}
+	return dirs
+}
+
+// PersonalPath returns the path of the personal dictionary, which holds
+// the words added by the user, one per line
+func PersonalPath() string {
+	return filepath.Join(config.DataDir, "dictionaries", "personal.txt")
+}
+
+type loaded struct {
+	dict *Dictionary
+	err  error
+}
+
+var (
+	dictsLock sync.Mutex
+	dicts     = make(map[string]loaded)
+)
+
+// Load returns the dictionary of the given language, such as "en_US",
+// including the words of the personal dictionary. A dictionary is only
+// read once; the failure to find it is remembered as well.
+func Load(lang string) (*Dictionary, error) {
+	dictsLock.Lock()
+	defer dictsLock.Unlock()
+
+	if l, ok := dicts[lang]; ok {
+		return l.dict, l.err
+	}
+	d, err := load(lang)
+	dicts[lang] = loaded{d, err}
+	return d, err
+}
+
+func load(lang string) (*Dictionary, error) {
+	for _, dir := range dictionaryDirs() {
+		dic, err := os.Open(filepath.Join(dir, lang+".dic"))
+		if err != nil {
+			continue
+		}
+		defer dic.Close()
+
+		var aff io.Reader
+		if f, err := os.Open(filepath.Join(dir, lang+".aff")); err == nil {
+			defer f.Close()
+			aff = f
+		}
+		d, err := Parse(dic, aff)
+		if err != nil {
+			return nil, err
+		}
+		if f, err := os.Open(PersonalPath()); err == nil {
+			defer f.Close()
+			if p, err := Parse(f, nil); err == nil {
+				for w := range p.words {
+					d.Add(w)
+				}
+			}
+		}
+		return d, nil
+	}
+	return nil, errors.New("No dictionary found for " + lang)
+}
+
+// AddPersonal adds a word to the personal dictionary and to the
+// dictionaries already loaded
+func AddPersonal(word string) error {
+	path := PersonalPath()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(word + "\n"); err != nil {
+		return err
+	}
+
+	dictsLock.Lock()
+	defer dictsLock.Unlock()
+	for _, l := range dicts {
+		if l.dict != nil {
+			l.dict.Add(word)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package spell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAff = `SET UTF-8
+TRY esianrtolcdugmphbyfvkwz
+NEEDAFFIX X
+
+PFX A Y 1
+PFX A   0     re         .
+
+SFX D Y 3
+SFX D   y     ied        [^aeiou]y
+SFX D   0     ed         [^ey]
+SFX D   0     d          e
+
+SFX S N 1
+SFX S   0     s          .
+`
+
+const testDic = `6
+try/D
+work/ADS
+bake/D
+Paris
+hello
+bound/X	po:noun
+`
+
+func testDictionary(t *testing.T) *Dictionary {
+	d, err := Parse(strings.NewReader(testDic), strings.NewReader(testAff))
+	assert.Nil(t, err)
+	return d
+}
+
+func TestCheck(t *testing.T) {
+	d := testDictionary(t)
+
+	for _, w := range []string{"try", "tried", "work", "worked", "rework", "reworked", "works", "baked", "Paris", "PARIS", "Hello", "HELLO"} {
+		assert.True(t, d.Check(w), w)
+	}
+	// reworks needs a cross product of S, which has none
+	for _, w := range []string{"tryed", "bakeed", "reworks", "paris", "helo", "bound"} {
+		assert.False(t, d.Check(w), w)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	d := testDictionary(t)
+
+	assert.Equal(t, []string{"worked"}, d.Suggest("wroked", 3)[:1])
+	assert.Equal(t, "Hello", d.Suggest("Helo", 3)[0])
+	assert.Equal(t, "HELLO", d.Suggest("HELO", 3)[0])
+	assert.Equal(t, "Paris", d.Suggest("pari", 3)[0])
+	assert.Empty(t, d.Suggest("zzzzzzzz", 3))
+}
+
+func TestPlainWordList(t *testing.T) {
+	d, err := Parse(strings.NewReader("foo\nbar\n"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, d.Len())
+	assert.True(t, d.Check("Foo"))
+}
+
+func TestLatin1(t *testing.T) {
+	aff := "SET ISO8859-1\n"
+	dic := "1\ncaf\xe9\n"
+	d, err := Parse(strings.NewReader(dic), strings.NewReader(aff))
+	assert.Nil(t, err)
+	assert.True(t, d.Check("café"))
+}
+
+func TestWords(t *testing.T) {
+	words := Words([]byte("// It's a fooBar x_y 'quoted' naïve go1 don’t"))
+	var texts []string
+	for _, w := range words {
+		texts = append(texts, w.Text)
+	}
+	assert.Equal(t, []string{"It's", "quoted", "naïve", "don't"}, texts)
+	assert.Equal(t, Word{"quoted", 22, 28}, words[1])
+	assert.Equal(t, Word{"naïve", 30, 35}, words[2])
+}
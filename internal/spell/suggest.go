@@ -0,0 +1,132 @@
+package spell
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEdits2Len is the length above which words only get suggestions one
+// edit away, since the number of candidates grows quickly with the length
+const maxEdits2Len = 16
+
+// edits returns the words one deletion, transposition, replacement or
+// insertion of a character of try away from word
+func edits(word []rune, try []rune) []string {
+	var res []string
+	for i := 0; i <= len(word); i++ {
+		if i < len(word) {
+			res = append(res, string(word[:i])+string(word[i+1:]))
+		}
+		if i+1 < len(word) {
+			t := make([]rune, len(word))
+			copy(t, word)
+			t[i], t[i+1] = t[i+1], t[i]
+			res = append(res, string(t))
+		}
+		for _, c := range try {
+			if i < len(word) && c != word[i] {
+				res = append(res, string(word[:i])+string(c)+string(word[i+1:]))
+			}
+			res = append(res, string(word[:i])+string(c)+string(word[i:]))
+		}
+	}
+	return res
+}
+
+// Suggest returns up to n correctly spelled words close to word, the
+// closest first. The suggestions follow the capitalization of word.
+func (d *Dictionary) Suggest(word string, n int) []string {
+	if word == "" {
+		return nil
+	}
+	lower := strings.ToLower(word)
+	try := []rune(d.try)
+	for _, r := range lower {
+		if !strings.ContainsRune(d.try, r) {
+			try = append(try, r)
+		}
+	}
+
+	dist := make(map[string]int)
+	var found []string
+	add := func(w string, n int) {
+		if !d.words[w] {
+			// a proper noun
+			w = capitalize(w)
+			if !d.words[w] {
+				return
+			}
+		}
+		if _, ok := dist[w]; !ok && w != lower {
+			dist[w] = n
+			found = append(found, w)
+		}
+	}
+
+	edits1 := edits([]rune(lower), try)
+	for _, w := range edits1 {
+		add(w, 1)
+	}
+	if len(found) < n && utf8.RuneCountInString(lower) <= maxEdits2Len {
+		seen := make(map[string]bool)
+		for _, e := range edits1 {
+			if seen[e] {
+				continue
+			}
+			seen[e] = true
+			for _, w := range edits([]rune(e), try) {
+				add(w, 2)
+			}
+		}
+	}
+
+	wordLen := utf8.RuneCountInString(lower)
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if dist[a] != dist[b] {
+			return dist[a] < dist[b]
+		}
+		// prefer words of the same length, then the same first letter
+		la := abs(utf8.RuneCountInString(a) - wordLen)
+		lb := abs(utf8.RuneCountInString(b) - wordLen)
+		if la != lb {
+			return la < lb
+		}
+		fa := strings.ToLower(a)[0] == lower[0]
+		fb := strings.ToLower(b)[0] == lower[0]
+		if fa != fb {
+			return fa
+		}
+		return a < b
+	})
+	if len(found) > n {
+		found = found[:n]
+	}
+
+	for i, w := range found {
+		found[i] = matchCase(w, word)
+	}
+	return found
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// matchCase returns w capitalized or in uppercase like the word it was
+// suggested for
+func matchCase(w, word string) string {
+	r, _ := utf8.DecodeRuneInString(word)
+	switch {
+	case utf8.RuneCountInString(word) > 1 && strings.ToUpper(word) == word:
+		return strings.ToUpper(w)
+	case unicode.IsUpper(r):
+		return capitalize(w)
+	}
+	return w
+}
@@ -0,0 +1,94 @@
+package spell
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Word is a word of a text, which starts and ends at the given character
+// offsets
+type Word struct {
+	Text       string
+	Start, End int
+}
+
+// isWordRune returns whether r can be part of a word in the sense of
+// Words, in which words are separated by everything else
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\'' || r == '’'
+}
+
+// Words returns the words of text which should be spell checked. Words
+// which look like identifiers, because they contain digits or underscores
+// or have uppercase letters after lowercase ones, are skipped, as are
+// single letters.
+func Words(text []byte) []Word {
+	var words []Word
+	s := string(text)
+	x := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if !isWordRune(r) {
+			s = s[size:]
+			x++
+			continue
+		}
+
+		// a sequence of word characters
+		start := x
+		end := 0
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if !isWordRune(r) {
+				break
+			}
+			end += size
+			x++
+		}
+		if w, ok := checkable(s[:end], start); ok {
+			words = append(words, w)
+		}
+		s = s[end:]
+	}
+	return words
+}
+
+// checkable trims the apostrophes around a sequence of word characters
+// starting at the character offset x and returns the word if it should be
+// spell checked
+func checkable(s string, x int) (Word, bool) {
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r != '\'' && r != '’' {
+			break
+		}
+		s = s[size:]
+		x++
+	}
+	for len(s) > 0 {
+		r, size := utf8.DecodeLastRuneInString(s)
+		if r != '\'' && r != '’' {
+			break
+		}
+		s = s[:len(s)-size]
+	}
+
+	n := 0
+	lower := false
+	for _, r := range s {
+		n++
+		if unicode.IsDigit(r) || r == '_' {
+			return Word{}, false
+		}
+		if unicode.IsLower(r) {
+			lower = true
+		} else if unicode.IsUpper(r) && lower {
+			return Word{}, false
+		}
+	}
+	if n < 2 {
+		return Word{}, false
+	}
+	return Word{Text: strings.Replace(s, "’", "'", -1), Start: x, End: x + n}, true
+}
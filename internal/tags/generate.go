@@ -0,0 +1,31 @@
+package tags
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+)
+
+// Generate runs the command which generates the tags file in the directory
+// dir, such as "ctags -R". The error contains the output of the command.
+func Generate(dir, command string) error {
+	args, err := shellquote.Split(command)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("No tags command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(args[0] + ": " + msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Package tags reads the tags files generated by ctags and similar tools,
+// which index where the symbols of a project are defined
+package tags
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the tags files which are looked for
+const FileName = "tags"
+
+// A Tag is the definition of a symbol
+type Tag struct {
+	Name string
+	// Path is the absolute path of the file containing the definition
+	Path string
+	// Line is the 0-based line of the definition, or -1 if the tag only
+	// gives a pattern
+	Line int
+	// Pattern is the text of the line of the definition, without the
+	// anchors and escapes of the search pattern in the tags file
+	Pattern string
+	// Kind is the kind of symbol, such as "f" or "function", if known
+	Kind string
+}
+
+// MatchLine returns whether line is the definition of the tag according to
+// its pattern
+func (t Tag) MatchLine(line string) bool {
+	return t.Pattern != "" && strings.TrimRight(line, "\r") == t.Pattern
+}
+
+// FindLine returns the line of the definition of the tag in the given lines
+// of its file, or -1 if it can't be found
+func (t Tag) FindLine(lines []string) int {
+	if t.Line >= 0 {
+		if t.Line < len(lines) {
+			return t.Line
+		}
+		return -1
+	}
+	for i, l := range lines {
+		if t.MatchLine(l) {
+			return i
+		}
+	}
+	// the indentation may have changed since the tags were generated
+	pattern := strings.TrimSpace(t.Pattern)
+	for i, l := range lines {
+		if pattern != "" && strings.TrimSpace(l) == pattern {
+			return i
+		}
+	}
+	return -1
+}
+
+// Locate finds the tags file which applies to a file of the directory dir,
+// which is the closest one in dir or in its parents
+func Locate(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+type index struct {
+	modTime time.Time
+	size    int64
+	tags    map[string][]Tag
+}
+
+var (
+	cacheLock sync.Mutex
+	cache     = make(map[string]*index)
+)
+
+// Lookup returns the tags of the given name in the tags file at path. The
+// file is parsed once and parsed again when it changes.
+func Lookup(path, name string) ([]Tag, error) {
+	idx, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	return idx.tags[name], nil
+}
+
+// InFile returns the tags of the tags file at path which are defined in the
+// given file, sorted by line when they have one
+func InFile(path, file string) ([]Tag, error) {
+	idx, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	var ts []Tag
+	for _, tags := range idx.tags {
+		for _, t := range tags {
+			if t.Path == file {
+				ts = append(ts, t)
+			}
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Line != ts[j].Line {
+			return ts[i].Line < ts[j].Line
+		}
+		return ts[i].Name < ts[j].Name
+	})
+	return ts, nil
+}
+
+// load returns the parsed tags file at path from the cache, or parses it if
+// it isn't cached or changed
+func load(path string) (*index, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	idx, ok := cache[path]
+	if !ok || !idx.modTime.Equal(info.ModTime()) || idx.size != info.Size() {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		tags, err := Parse(f, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		idx = &index{info.ModTime(), info.Size(), tags}
+		cache[path] = idx
+	}
+	return idx, nil
+}
+
+// Parse reads a tags file in the format of ctags and returns its tags by
+// name. Relative paths are relative to dir.
+func Parse(r io.Reader, dir string) (map[string][]Tag, error) {
+	tags := make(map[string][]Tag)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if t, ok := parseLine(scanner.Text(), dir); ok {
+			tags[t.Name] = append(tags[t.Name], t)
+		}
+	}
+	return tags, scanner.Err()
+}
+
+// parseLine parses a line made of the name, the file and the address of a
+// tag separated by tabs, where the address is a line number or a search
+// pattern, optionally followed by ;" and extension fields such as the kind
+func parseLine(l, dir string) (Tag, bool) {
+	if strings.HasPrefix(l, "!_TAG_") {
+		return Tag{}, false
+	}
+	fields := strings.SplitN(l, "\t", 3)
+	if len(fields) < 3 || fields[0] == "" || fields[1] == "" {
+		return Tag{}, false
+	}
+	t := Tag{Name: fields[0], Path: filepath.FromSlash(fields[1]), Line: -1}
+	if !filepath.IsAbs(t.Path) {
+		t.Path = filepath.Join(dir, t.Path)
+	}
+
+	addr, rest := fields[2], ""
+	if addr != "" && (addr[0] == '/' || addr[0] == '?') {
+		pattern, n, ok := parsePattern(addr)
+		if !ok {
+			return Tag{}, false
+		}
+		t.Pattern = pattern
+		addr, rest = addr[:n], addr[n:]
+	} else {
+		i := strings.IndexAny(addr, ";\t")
+		if i >= 0 {
+			addr, rest = addr[:i], addr[i:]
+		}
+		n, err := strconv.Atoi(addr)
+		if err != nil || n < 1 {
+			return Tag{}, false
+		}
+		t.Line = n - 1
+	}
+
+	if strings.HasPrefix(rest, ";\"") {
+		for _, f := range strings.Split(rest[2:], "\t") {
+			if f == "" {
+				continue
+			}
+			if !strings.Contains(f, ":") {
+				t.Kind = f
+			} else if strings.HasPrefix(f, "kind:") {
+				t.Kind = f[len("kind:"):]
+			}
+		}
+	}
+	return t, true
+}
+
+// parsePattern parses the search pattern at the start of addr, delimited
+// by '/' or '?', and returns the line it matches and the length of the
+// pattern in addr. The pattern is meant to match a whole line, as generated
+// by ctags, so it is returned without its anchors.
+func parsePattern(addr string) (string, int, bool) {
+	delim := addr[0]
+	var sb strings.Builder
+	i := 1
+	for ; i < len(addr) && addr[i] != delim; i++ {
+		if addr[i] == '\\' && i+1 < len(addr) {
+			i++
+		}
+		sb.WriteByte(addr[i])
+	}
+	if i >= len(addr) {
+		return "", 0, false
+	}
+	pattern := strings.TrimPrefix(sb.String(), "^")
+	if strings.HasSuffix(pattern, "$") {
+		pattern = pattern[:len(pattern)-1]
+	}
+	return pattern, i + 1, true
+}
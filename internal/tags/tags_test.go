@@ -0,0 +1,79 @@
+package tags
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tagsFile = `!_TAG_FILE_FORMAT	2	/extended format/
+!_TAG_FILE_SORTED	1	/0=unsorted, 1=sorted/
+Foo	a.go	/^func Foo() {$/;"	f
+Foo	sub/b.go	/^type Foo struct {$/;"	kind:type	line:3
+Bar	/abs/c.c	12;"	kind:function
+Slash	a.go	/^var x = a \/ b$/;"	v
+`
+
+func TestParse(t *testing.T) {
+	tags, err := Parse(strings.NewReader(tagsFile), "/root")
+	assert.Nil(t, err)
+	assert.Len(t, tags, 3)
+
+	assert.Equal(t, []Tag{
+		{Name: "Foo", Path: filepath.FromSlash("/root/a.go"), Line: -1, Pattern: "func Foo() {", Kind: "f"},
+		{Name: "Foo", Path: filepath.FromSlash("/root/sub/b.go"), Line: -1, Pattern: "type Foo struct {", Kind: "type"},
+	}, tags["Foo"])
+	assert.Equal(t, []Tag{{Name: "Bar", Path: filepath.FromSlash("/abs/c.c"), Line: 11, Kind: "function"}}, tags["Bar"])
+	assert.Equal(t, "var x = a / b", tags["Slash"][0].Pattern)
+}
+
+func TestFindLine(t *testing.T) {
+	lines := []string{"package a", "", "\tfunc Foo() {\r", "func Foo() {", "}"}
+	tag := Tag{Name: "Foo", Line: -1, Pattern: "func Foo() {"}
+	assert.Equal(t, 3, tag.FindLine(lines))
+
+	tag.Pattern = "  func Foo() {"
+	assert.Equal(t, 2, tag.FindLine(lines))
+
+	tag.Pattern = "func Bar() {"
+	assert.Equal(t, -1, tag.FindLine(lines))
+
+	assert.Equal(t, 1, Tag{Line: 1}.FindLine(lines))
+	assert.Equal(t, -1, Tag{Line: 10}.FindLine(lines))
+}
+
+func TestLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "micro-tags")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	path := filepath.Join(dir, FileName)
+	ioutil.WriteFile(path, []byte(tagsFile), 0644)
+
+	found, ok := Locate(sub)
+	assert.True(t, ok)
+	assert.Equal(t, path, found)
+
+	tags, err := Lookup(path, "Foo")
+	assert.Nil(t, err)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, filepath.Join(sub, "b.go"), tags[1].Path)
+
+	tags, err = InFile(path, filepath.Join(dir, "a.go"))
+	assert.Nil(t, err)
+	if assert.Len(t, tags, 2) {
+		assert.Equal(t, "Foo", tags[0].Name)
+		assert.Equal(t, "Slash", tags[1].Name)
+	}
+
+	ioutil.WriteFile(path, []byte("Foo\ta.go\t1\n"), 0644)
+	tags, err = Lookup(path, "Foo")
+	assert.Nil(t, err)
+	assert.Len(t, tags, 1)
+}
@@ -0,0 +1,253 @@
+package util
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// calcConstants are the constants of Calculate, by name
+var calcConstants = map[string]float64{
+	"pi":  math.Pi,
+	"tau": 2 * math.Pi,
+	"e":   math.E,
+}
+
+// calcFunctions are the functions of Calculate, by name
+var calcFunctions = map[string]func(args []float64) (float64, error){
+	"abs":   unary(math.Abs),
+	"sqrt":  unary(math.Sqrt),
+	"cbrt":  unary(math.Cbrt),
+	"exp":   unary(math.Exp),
+	"ln":    unary(math.Log),
+	"log":   unary(math.Log10),
+	"log2":  unary(math.Log2),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"asin":  unary(math.Asin),
+	"acos":  unary(math.Acos),
+	"atan":  unary(math.Atan),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"trunc": unary(math.Trunc),
+	"min":   variadic(math.Min),
+	"max":   variadic(math.Max),
+}
+
+func unary(f func(float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, errors.New("Expected 1 argument")
+		}
+		return f(args[0]), nil
+	}
+}
+
+func variadic(f func(float64, float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, errors.New("Expected at least 1 argument")
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			v = f(v, a)
+		}
+		return v, nil
+	}
+}
+
+// Calculate evaluates an arithmetic expression: numbers, which may be
+// written in hexadecimal, octal or binary with the 0x, 0o and 0b prefixes,
+// the operators + - * / % and ^ (or **) for powers, parentheses, the
+// constants pi, tau and e, and functions such as sqrt(x), ln(x) and
+// max(x, y).
+func Calculate(expr string) (float64, error) {
+	c := &calculator{s: expr}
+	v, err := c.sum()
+	if err != nil {
+		return 0, err
+	}
+	c.space()
+	if c.i < len(c.s) {
+		return 0, errors.New("Unexpected " + strconv.Quote(c.s[c.i:]))
+	}
+	return v, nil
+}
+
+// FormatNumber formats a result of Calculate with at most 12 significant
+// digits, which hides the rounding errors of floating point numbers, and
+// without an exponent unless the number is very large or small
+func FormatNumber(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 12, 64), 64)
+	if a := math.Abs(f); f != 0 && (a < 1e-6 || a >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// calculator is a recursive descent parser evaluating the expression s
+// from the index i
+type calculator struct {
+	s string
+	i int
+}
+
+func (c *calculator) space() {
+	for c.i < len(c.s) && unicode.IsSpace(rune(c.s[c.i])) {
+		c.i++
+	}
+}
+
+// accept skips op, after whitespace, if it comes next
+func (c *calculator) accept(op string) bool {
+	c.space()
+	if strings.HasPrefix(c.s[c.i:], op) {
+		c.i += len(op)
+		return true
+	}
+	return false
+}
+
+// sum parses terms separated by + and -
+func (c *calculator) sum() (float64, error) {
+	v, err := c.product()
+	for err == nil {
+		var w float64
+		if c.accept("+") {
+			w, err = c.product()
+			v += w
+		} else if c.accept("-") {
+			w, err = c.product()
+			v -= w
+		} else {
+			break
+		}
+	}
+	return v, err
+}
+
+// product parses factors separated by *, / and %
+func (c *calculator) product() (float64, error) {
+	v, err := c.unary()
+	for err == nil {
+		var w float64
+		if c.accept("*") {
+			w, err = c.unary()
+			v *= w
+		} else if c.accept("/") {
+			if w, err = c.unary(); err == nil && w == 0 {
+				err = errors.New("Division by zero")
+			}
+			v /= w
+		} else if c.accept("%") {
+			if w, err = c.unary(); err == nil && w == 0 {
+				err = errors.New("Division by zero")
+			}
+			v = math.Mod(v, w)
+		} else {
+			break
+		}
+	}
+	return v, err
+}
+
+// unary parses a power preceded by signs, -2^2 being -4
+func (c *calculator) unary() (float64, error) {
+	if c.accept("-") {
+		v, err := c.unary()
+		return -v, err
+	} else if c.accept("+") {
+		return c.unary()
+	}
+	return c.power()
+}
+
+// power parses a right associative power, whose exponent may have a sign
+func (c *calculator) power() (float64, error) {
+	v, err := c.atom()
+	if err != nil {
+		return 0, err
+	}
+	if c.accept("^") || c.accept("**") {
+		w, err := c.unary()
+		return math.Pow(v, w), err
+	}
+	return v, nil
+}
+
+// atom parses a number, a constant, a function call or an expression in
+// parentheses
+func (c *calculator) atom() (float64, error) {
+	c.space()
+	if c.i == len(c.s) {
+		return 0, errors.New("Unexpected end of expression")
+	}
+	if c.accept("(") {
+		v, err := c.sum()
+		if err == nil && !c.accept(")") {
+			err = errors.New("Missing )")
+		}
+		return v, err
+	}
+
+	start := c.i
+	r := rune(c.s[c.i])
+	if unicode.IsDigit(r) || r == '.' {
+		for c.i < len(c.s) && (isNumberChar(c.s[c.i]) ||
+			(c.s[c.i] == '+' || c.s[c.i] == '-') && (c.s[c.i-1] == 'e' || c.s[c.i-1] == 'E') && !strings.HasPrefix(c.s[start:], "0x")) {
+			c.i++
+		}
+		num := strings.Replace(c.s[start:c.i], "_", "", -1)
+		if v, err := strconv.ParseFloat(num, 64); err == nil && !strings.HasPrefix(num, "0x") {
+			return v, nil
+		}
+		if v, err := strconv.ParseInt(num, 0, 64); err == nil {
+			return float64(v), nil
+		}
+		return 0, errors.New("Invalid number " + num)
+	}
+	if !unicode.IsLetter(r) {
+		return 0, errors.New("Unexpected " + strconv.Quote(c.s[c.i:]))
+	}
+
+	for c.i < len(c.s) && (unicode.IsLetter(rune(c.s[c.i])) || unicode.IsDigit(rune(c.s[c.i]))) {
+		c.i++
+	}
+	name := strings.ToLower(c.s[start:c.i])
+	if f, ok := calcFunctions[name]; ok {
+		if !c.accept("(") {
+			return 0, errors.New("Missing ( after " + name)
+		}
+		var args []float64
+		for !c.accept(")") {
+			if len(args) > 0 && !c.accept(",") {
+				return 0, errors.New("Missing ) after the arguments of " + name)
+			}
+			v, err := c.sum()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, v)
+		}
+		v, err := f(args)
+		if err != nil {
+			return 0, errors.New(name + ": " + err.Error())
+		}
+		return v, nil
+	}
+	if v, ok := calcConstants[name]; ok {
+		return v, nil
+	}
+	return 0, errors.New("Unknown name " + name)
+}
+
+func isNumberChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || c == '_'
+}
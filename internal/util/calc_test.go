@@ -0,0 +1,39 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		expr string
+		out  string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(1 + 2) * 3", "9"},
+		{"10 / 4", "2.5"},
+		{"7 % 3", "1"},
+		{"2 ^ 3 ^ 2", "512"},
+		{"2**10", "1024"},
+		{"-2^2", "-4"},
+		{"2^-1", "0.5"},
+		{"0.1 + 0.2", "0.3"},
+		{"0xff + 0b11 + 0o10", "266"},
+		{"1_000 * 1e3", "1000000"},
+		{"sqrt(16) + max(1, 5, 3)", "9"},
+		{"round(pi * 100) / 100", "3.14"},
+		{"1e30", "1e+30"},
+	}
+	for _, tt := range tests {
+		v, err := Calculate(tt.expr)
+		assert.Nil(t, err, tt.expr)
+		assert.Equal(t, tt.out, FormatNumber(v), tt.expr)
+	}
+
+	for _, expr := range []string{"", "1 +", "(1", "1 / 0", "foo", "sqrt 4", "sqrt(1, 2)", "1 2", "0xzz"} {
+		_, err := Calculate(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
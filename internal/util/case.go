@@ -0,0 +1,112 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CaseStyles are the styles ConvertCase converts text to
+var CaseStyles = []string{"upper", "lower", "title", "snake", "camel", "pascal", "kebab"}
+
+// ConvertCase converts s to the style, which is one of CaseStyles. The
+// upper, lower and title styles change the case of the letters, capitalizing
+// the words for title. The other styles rewrite each identifier of s, a run
+// of letters, digits, underscores and hyphens, whose words are separated by
+// underscores, hyphens or case changes as in HTTPServer or fooBar. It
+// returns false if the style is unknown.
+func ConvertCase(s, style string) (string, bool) {
+	switch style {
+	case "upper":
+		return strings.ToUpper(s), true
+	case "lower":
+		return strings.ToLower(s), true
+	case "title":
+		runes := []rune(s)
+		for i, r := range runes {
+			if i == 0 || !unicode.IsLetter(runes[i-1]) && runes[i-1] != '\'' {
+				runes[i] = unicode.ToTitle(r)
+			} else {
+				runes[i] = unicode.ToLower(r)
+			}
+		}
+		return string(runes), true
+	case "snake", "camel", "pascal", "kebab":
+	default:
+		return s, false
+	}
+
+	var sb strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if !isIdentRune(runes[i]) {
+			sb.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isIdentRune(runes[j]) {
+			j++
+		}
+		words := identWords(runes[i:j])
+		if len(words) == 0 {
+			// only underscores and hyphens
+			sb.WriteString(string(runes[i:j]))
+		} else {
+			sb.WriteString(joinWords(words, style))
+		}
+		i = j
+	}
+	return sb.String(), true
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+// identWords returns the lowercase words of an identifier
+func identWords(ident []rune) []string {
+	var words []string
+	var word []rune
+	end := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = nil
+		}
+	}
+	for i, r := range ident {
+		if r == '_' || r == '-' {
+			end()
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := ident[i-1]
+			// a lowercase letter or digit followed by an uppercase one, or
+			// the last uppercase letter of an acronym followed by a
+			// lowercase one, starts a word
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(ident) && unicode.IsLower(ident[i+1]) {
+				end()
+			}
+		}
+		word = append(word, r)
+	}
+	end()
+	return words
+}
+
+func joinWords(words []string, style string) string {
+	switch style {
+	case "snake":
+		return strings.Join(words, "_")
+	case "kebab":
+		return strings.Join(words, "-")
+	}
+	for i, w := range words {
+		if i > 0 || style == "pascal" {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+	}
+	return strings.Join(words, "")
+}
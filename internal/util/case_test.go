@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertCase(t *testing.T) {
+	tests := []struct {
+		in, style, out string
+	}{
+		{"Hello World", "upper", "HELLO WORLD"},
+		{"Hello World", "lower", "hello world"},
+		{"hello wORLD, it's me", "title", "Hello World, It's Me"},
+		{"fooBarBaz", "snake", "foo_bar_baz"},
+		{"HTTPServer", "snake", "http_server"},
+		{"parse_url2Path", "camel", "parseUrl2Path"},
+		{"some-kebab-name", "pascal", "SomeKebabName"},
+		{"SomeName", "kebab", "some-name"},
+		{"foo.barBaz(qux_quux)", "camel", "foo.barBaz(quxQuux)"},
+		{"a_b - c", "pascal", "AB - C"},
+		{"__init__", "camel", "init"},
+	}
+	for _, tt := range tests {
+		out, ok := ConvertCase(tt.in, tt.style)
+		assert.True(t, ok)
+		assert.Equal(t, tt.out, out, tt.in+" to "+tt.style)
+	}
+
+	_, ok := ConvertCase("x", "unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/runenames"
+)
+
+// categories are the two letter general categories of Unicode, without
+// LC which groups the cased letters
+var categories []string
+
+func init() {
+	for c := range unicode.Categories {
+		if len(c) == 2 && c != "LC" {
+			categories = append(categories, c)
+		}
+	}
+	sort.Strings(categories)
+}
+
+// RuneCategory returns the two letter general category of r, such as Lu
+// for an upper case letter, or Cn if r isn't assigned
+func RuneCategory(r rune) string {
+	for _, c := range categories {
+		if unicode.Is(unicode.Categories[c], r) {
+			return c
+		}
+	}
+	return "Cn"
+}
+
+// RuneName returns the Unicode name of r. The runes of the ranges whose
+// names aren't listed one by one, such as the control characters, get the
+// name of their range between angle brackets, except for the CJK
+// ideographs which are named after their code point.
+func RuneName(r rune) string {
+	name := runenames.Name(r)
+	switch {
+	case name == "":
+		return "<unassigned>"
+	case strings.HasPrefix(name, "<CJK Ideograph"):
+		return fmt.Sprintf("CJK UNIFIED IDEOGRAPH-%04X", r)
+	}
+	return name
+}
+
+// DescribeCharacter returns the code points of the character made of r and
+// its combining runes, with their names and categories, its UTF-8 bytes,
+// and the digraph inserting it if there is one
+func DescribeCharacter(r rune, combc []rune) string {
+	runes := append([]rune{r}, combc...)
+	var points, bytes []string
+	for _, c := range runes {
+		points = append(points, fmt.Sprintf("U+%04X %s (%s)", c, RuneName(c), RuneCategory(c)))
+		buf := make([]byte, utf8.RuneLen(c))
+		utf8.EncodeRune(buf, c)
+		for _, b := range buf {
+			bytes = append(bytes, fmt.Sprintf("%02x", b))
+		}
+	}
+
+	desc := strings.Join(points, " + ") + ", UTF-8 " + strings.Join(bytes, " ")
+	if unicode.IsGraphic(r) {
+		desc = string(runes) + ": " + desc
+	}
+	if len(combc) == 0 {
+		if d, ok := RuneDigraph(r); ok {
+			desc += ", digraph " + d
+		}
+	}
+	return desc
+}
+
+// ParseCodePoint parses a code point written in hexadecimal as U+XXXX,
+// 0xXXXX, \uXXXX or just XXXX
+func ParseCodePoint(s string) (rune, error) {
+	hex := s
+	for _, p := range []string{"U+", "u+", "0x", "0X", "\\u", "\\U"} {
+		hex = strings.TrimPrefix(hex, p)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || hex == "" || !utf8.ValidRune(rune(n)) {
+		return 0, errors.New("Invalid code point: " + s)
+	}
+	return rune(n), nil
+}
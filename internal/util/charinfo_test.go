@@ -0,0 +1,27 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeCharacter(t *testing.T) {
+	assert.Equal(t, "é: U+00E9 LATIN SMALL LETTER E WITH ACUTE (Ll), UTF-8 c3 a9, digraph e'", DescribeCharacter('é', nil))
+	assert.Equal(t, "e\u0301: U+0065 LATIN SMALL LETTER E (Ll) + U+0301 COMBINING ACUTE ACCENT (Mn), UTF-8 65 cc 81", DescribeCharacter('e', []rune{'\u0301'}))
+	assert.Equal(t, "U+0009 <control> (Cc), UTF-8 09", DescribeCharacter('\t', nil))
+	assert.Equal(t, "一: U+4E00 CJK UNIFIED IDEOGRAPH-4E00 (Lo), UTF-8 e4 b8 80", DescribeCharacter('一', nil))
+	assert.Equal(t, "Cn", RuneCategory(0x378))
+}
+
+func TestParseCodePoint(t *testing.T) {
+	for _, s := range []string{"U+00E9", "u+e9", "0xE9", "\\u00e9", "e9"} {
+		r, err := ParseCodePoint(s)
+		assert.NoError(t, err)
+		assert.Equal(t, 'é', r)
+	}
+	for _, s := range []string{"", "U+", "xyz", "110000", "D800"} {
+		_, err := ParseCodePoint(s)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,88 @@
+package util
+
+// digraphs are the two character mnemonics of RFC 1345 for the characters
+// of the Latin-1 supplement, the common ones of Latin Extended-A, the Greek
+// letters, and some punctuation, currency, arrow and mathematical symbols
+var digraphs = map[string]rune{
+	"NS": '\u00a0', "!I": '¡', "Ct": '¢', "Pd": '£', "Cu": '¤', "Ye": '¥',
+	"BB": '¦', "SE": '§', "':": '¨', "Co": '©', "-a": 'ª', "<<": '«',
+	"NO": '¬', "--": '\u00ad', "Rg": '®', "'m": '¯', "DG": '°', "+-": '±',
+	"2S": '²', "3S": '³', "''": '´', "My": 'µ', "PI": '¶', ".M": '·',
+	"',": '¸', "1S": '¹', "-o": 'º', ">>": '»', "14": '¼', "12": '½',
+	"34": '¾', "?I": '¿',
+
+	"A!": 'À', "A'": 'Á', "A>": 'Â', "A?": 'Ã', "A:": 'Ä', "AA": 'Å',
+	"AE": 'Æ', "C,": 'Ç', "E!": 'È', "E'": 'É', "E>": 'Ê', "E:": 'Ë',
+	"I!": 'Ì', "I'": 'Í', "I>": 'Î', "I:": 'Ï', "D-": 'Ð', "N?": 'Ñ',
+	"O!": 'Ò', "O'": 'Ó', "O>": 'Ô', "O?": 'Õ', "O:": 'Ö', "*X": '×',
+	"O/": 'Ø', "U!": 'Ù', "U'": 'Ú', "U>": 'Û', "U:": 'Ü', "Y'": 'Ý',
+	"TH": 'Þ', "ss": 'ß', "a!": 'à', "a'": 'á', "a>": 'â', "a?": 'ã',
+	"a:": 'ä', "aa": 'å', "ae": 'æ', "c,": 'ç', "e!": 'è', "e'": 'é',
+	"e>": 'ê', "e:": 'ë', "i!": 'ì', "i'": 'í', "i>": 'î', "i:": 'ï',
+	"d-": 'ð', "n?": 'ñ', "o!": 'ò', "o'": 'ó', "o>": 'ô', "o?": 'õ',
+	"o:": 'ö', "-:": '÷', "o/": 'ø', "u!": 'ù', "u'": 'ú', "u>": 'û',
+	"u:": 'ü', "y'": 'ý', "th": 'þ', "y:": 'ÿ',
+
+	"A-": 'Ā', "a-": 'ā', "A(": 'Ă', "a(": 'ă', "A;": 'Ą', "a;": 'ą',
+	"C'": 'Ć', "c'": 'ć', "C<": 'Č', "c<": 'č', "D<": 'Ď', "d<": 'ď',
+	"D/": 'Đ', "d/": 'đ', "E-": 'Ē', "e-": 'ē', "E;": 'Ę', "e;": 'ę',
+	"E<": 'Ě', "e<": 'ě', "G(": 'Ğ', "g(": 'ğ', "I.": 'İ', "i.": 'ı',
+	"L/": 'Ł', "l/": 'ł', "L<": 'Ľ', "l<": 'ľ', "N'": 'Ń', "n'": 'ń',
+	"N<": 'Ň', "n<": 'ň', "O\"": 'Ő', "o\"": 'ő', "OE": 'Œ', "oe": 'œ',
+	"R<": 'Ř', "r<": 'ř', "S'": 'Ś', "s'": 'ś', "S,": 'Ş', "s,": 'ş',
+	"S<": 'Š', "s<": 'š', "T<": 'Ť', "t<": 'ť', "U0": 'Ů', "u0": 'ů',
+	"U\"": 'Ű', "u\"": 'ű', "Y:": 'Ÿ', "Z'": 'Ź', "z'": 'ź', "Z.": 'Ż',
+	"z.": 'ż', "Z<": 'Ž', "z<": 'ž',
+
+	"A*": 'Α', "B*": 'Β', "G*": 'Γ', "D*": 'Δ', "E*": 'Ε', "Z*": 'Ζ',
+	"Y*": 'Η', "H*": 'Θ', "I*": 'Ι', "K*": 'Κ', "L*": 'Λ', "M*": 'Μ',
+	"N*": 'Ν', "C*": 'Ξ', "O*": 'Ο', "P*": 'Π', "R*": 'Ρ', "S*": 'Σ',
+	"T*": 'Τ', "U*": 'Υ', "F*": 'Φ', "X*": 'Χ', "Q*": 'Ψ', "W*": 'Ω',
+	"a*": 'α', "b*": 'β', "g*": 'γ', "d*": 'δ', "e*": 'ε', "z*": 'ζ',
+	"y*": 'η', "h*": 'θ', "i*": 'ι', "k*": 'κ', "l*": 'λ', "m*": 'μ',
+	"n*": 'ν', "c*": 'ξ', "o*": 'ο', "p*": 'π', "r*": 'ρ', "*s": 'ς',
+	"s*": 'σ', "t*": 'τ', "u*": 'υ', "f*": 'φ', "x*": 'χ', "q*": 'ψ',
+	"w*": 'ω',
+
+	"-N": '–', "-M": '—', "'6": '‘', "'9": '’', ".9": '‚', "\"6": '“',
+	"\"9": '”', ":9": '„', "/-": '†', "/=": '‡', "..": '‥', ",.": '…',
+	"%0": '‰', "1'": '′', "2'": '″', "<1": '‹', ">1": '›', "Eu": '€',
+	"oC": '℃', "TM": '™', "OK": '✓', "XX": '✗', "*1": '☆', "*2": '★',
+
+	"<-": '←', "-!": '↑', "->": '→', "-v": '↓', "<>": '↔', "UD": '↕',
+	"<=": '⇐', "=>": '⇒', "==": '⇔',
+
+	"FA": '∀', "dP": '∂', "TE": '∃', "/0": '∅', "DE": '∆', "NB": '∇',
+	"(-": '∈', "-)": '∋', "*P": '∏', "+Z": '∑', "-2": '−', "-+": '∓',
+	"*-": '∗', "Ob": '∘', "Sb": '∙', "RT": '√', "0(": '∝', "00": '∞',
+	"-L": '∟', "-V": '∠', "PP": '∥', "AN": '∧', "OR": '∨', "(U": '∩',
+	")U": '∪', "In": '∫', "DI": '∬', "Io": '∮', ".:": '∴', ":.": '∵',
+	"?1": '∼', "?-": '≃', "?=": '≅', "?2": '≈', "!=": '≠', "=3": '≡',
+	"=<": '≤', ">=": '≥', "<*": '≪', "*>": '≫', "(C": '⊂', ")C": '⊃',
+	"(_": '⊆', ")_": '⊇', "-T": '⊥', ".P": '⋅', ":3": '⋮', ".3": '⋯',
+}
+
+// Digraph returns the character of the digraph d, a two character
+// mnemonic of RFC 1345 such as e' for é, which may also be typed the other
+// way around if that isn't a digraph itself
+func Digraph(d string) (rune, bool) {
+	runes := []rune(d)
+	if len(runes) != 2 {
+		return 0, false
+	}
+	if r, ok := digraphs[d]; ok {
+		return r, true
+	}
+	r, ok := digraphs[string([]rune{runes[1], runes[0]})]
+	return r, ok
+}
+
+// RuneDigraph returns the digraph of the character r, if it has one
+func RuneDigraph(r rune) (string, bool) {
+	for d, c := range digraphs {
+		if c == r {
+			return d, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigraph(t *testing.T) {
+	r, ok := Digraph("e'")
+	assert.True(t, ok)
+	assert.Equal(t, 'é', r)
+
+	// either order works, unless the reversed one is a digraph itself
+	r, _ = Digraph("'e")
+	assert.Equal(t, 'é', r)
+	r, _ = Digraph("->")
+	assert.Equal(t, '→', r)
+	r, _ = Digraph(">-")
+	assert.Equal(t, '→', r)
+	r, _ = Digraph("=<")
+	assert.Equal(t, '≤', r)
+	r, _ = Digraph("<=")
+	assert.Equal(t, '⇐', r)
+
+	_, ok = Digraph("qq")
+	assert.False(t, ok)
+	_, ok = Digraph("e")
+	assert.False(t, ok)
+
+	d, ok := RuneDigraph('ß')
+	assert.True(t, ok)
+	assert.Equal(t, "ss", d)
+}
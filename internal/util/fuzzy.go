@@ -0,0 +1,263 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The fuzzy matching of the pickers and of the completion is set by the
+// fuzzymatcher and fuzzycase options, or replaced by a plugin's scorer
+var (
+	fuzzyAlgorithm = "subsequence"
+	fuzzyCase      = "ignore"
+
+	// FuzzyScorer, if not nil, scores the matches instead of the algorithm
+	// of the fuzzymatcher option. It returns false if str doesn't match.
+	FuzzyScorer func(pattern, str string) (int, bool)
+)
+
+// The scores of the fzf algorithm for a matched character, the start and
+// the extension of a gap, and the bonuses of matching at a word boundary,
+// at a camel case hump and right after the previous character
+const (
+	fzfMatch       = 16
+	fzfGapStart    = -3
+	fzfGapExt      = -1
+	fzfBoundary    = 8
+	fzfCamel       = 7
+	fzfConsecutive = 4
+)
+
+// SetFuzzyMatcher sets the algorithm of FuzzyMatch, subsequence, fzf or
+// exact, and whether it ignores the case: ignore, respect, or smart which
+// ignores it unless the pattern has upper case letters
+func SetFuzzyMatcher(algorithm, caseRule string) {
+	fuzzyAlgorithm, fuzzyCase = algorithm, caseRule
+}
+
+// FuzzyMatch reports whether pattern matches str, and returns a score for
+// the match, higher for better matches, given by FuzzyScorer if it is set.
+// The empty pattern matches anything.
+func FuzzyMatch(pattern, str string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	if FuzzyScorer != nil {
+		return FuzzyScorer(pattern, str)
+	}
+	return BuiltinFuzzyMatch(pattern, str)
+}
+
+// BuiltinFuzzyMatch is FuzzyMatch with the algorithm of the fuzzymatcher
+// option, whether FuzzyScorer is set or not
+func BuiltinFuzzyMatch(pattern, str string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	fold := fuzzyCase == "ignore" || fuzzyCase == "smart" && strings.ToLower(pattern) == pattern
+	runes := []rune(str)
+	switch fuzzyAlgorithm {
+	case "fzf":
+		return fuzzyFzf(pattern, runes, fold)
+	case "exact":
+		score, ok := exactMatch(foldRunes(pattern, fold), runes, fold, false, false)
+		return score*8 - Min(len(runes), 127), ok
+	}
+	return fuzzySubsequence(foldRunes(pattern, fold), runes, fold)
+}
+
+func foldRunes(s string, fold bool) []rune {
+	if fold {
+		s = strings.ToLower(s)
+	}
+	return []rune(s)
+}
+
+// runeEqual returns whether the rune of the string matches the rune of the
+// pattern, which is lower case if the case is ignored
+func runeEqual(r, p rune, fold bool) bool {
+	if fold {
+		return unicode.ToLower(r) == p
+	}
+	return r == p
+}
+
+// fuzzySubsequence matches the characters of the pattern in order in the
+// string. Matches of consecutive characters and matches at the start of
+// words score higher, so the best score goes to the most natural
+// abbreviation of str.
+func fuzzySubsequence(pat, runes []rune, fold bool) (int, bool) {
+	score := 0
+	pi := 0
+	last := -2
+	for i, r := range runes {
+		if pi == len(pat) {
+			break
+		}
+		if !runeEqual(r, pat[pi], fold) {
+			continue
+		}
+
+		score++
+		if i == last+1 {
+			score += 5
+		}
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsNumber(runes[i-1]) || (unicode.IsUpper(r) && unicode.IsLower(runes[i-1])) {
+			score += 8
+		}
+		if pi == 0 {
+			// penalize matches that start far into the string
+			score -= Min(i, 10)
+		}
+		last = i
+		pi++
+	}
+	if pi < len(pat) {
+		return 0, false
+	}
+
+	// prefer shorter strings when the match is otherwise equal
+	return score*16 - Min(len(runes)-len(pat), 15), true
+}
+
+// fuzzyFzf matches the pattern as fzf does: each of its words must match,
+// in any order, the string. A word is matched fuzzily, or exactly when it
+// starts with ', or at the start for ^ and the end for $, and the words
+// starting with ! must not be in the string.
+func fuzzyFzf(pattern string, runes []rune, fold bool) (int, bool) {
+	total := 0
+	for _, term := range strings.Fields(pattern) {
+		inverse := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+		exact := inverse || strings.HasPrefix(term, "'")
+		term = strings.TrimPrefix(term, "'")
+		prefix := strings.HasPrefix(term, "^")
+		term = strings.TrimPrefix(term, "^")
+		suffix := len(term) > 1 && strings.HasSuffix(term, "$")
+		if suffix {
+			term = term[:len(term)-1]
+		}
+		if term == "" {
+			continue
+		}
+
+		pat := foldRunes(term, fold)
+		var score int
+		var ok bool
+		if exact || prefix || suffix {
+			score, ok = exactMatch(pat, runes, fold, prefix, suffix)
+		} else {
+			score, ok = fzfFuzzy(pat, runes, fold)
+		}
+		if inverse {
+			ok, score = !ok, 0
+		}
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	// the shorter strings win the ties
+	return total*8 - Min(len(runes), 127), true
+}
+
+// boundaryBonus returns the bonus of matching the character i of the string
+func boundaryBonus(runes []rune, i int) int {
+	if i == 0 {
+		return fzfBoundary
+	}
+	prev, r := runes[i-1], runes[i]
+	switch {
+	case prev == '/' || prev == '\\':
+		return fzfBoundary + 1
+	case !unicode.IsLetter(prev) && !unicode.IsNumber(prev):
+		return fzfBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(r), !unicode.IsNumber(prev) && unicode.IsNumber(r):
+		return fzfCamel
+	}
+	return 0
+}
+
+// fzfFuzzy finds the shortest part of the string ending the first match of
+// the characters of the pattern, and scores the match in it: the matched
+// characters with their bonuses less the gaps between them
+func fzfFuzzy(pat, runes []rune, fold bool) (int, bool) {
+	end := -1
+	for i, pi := 0, 0; i < len(runes); i++ {
+		if runeEqual(runes[i], pat[pi], fold) {
+			if pi++; pi == len(pat) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+	start := end
+	for i, pi := end, len(pat)-1; i >= 0; i-- {
+		if runeEqual(runes[i], pat[pi], fold) {
+			if pi--; pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	score, pi := 0, 0
+	inGap := false
+	consecutive, firstBonus := 0, 0
+	for i := start; i <= end && pi < len(pat); i++ {
+		if !runeEqual(runes[i], pat[pi], fold) {
+			if inGap {
+				score += fzfGapExt
+			} else {
+				score += fzfGapStart
+			}
+			inGap, consecutive = true, 0
+			continue
+		}
+		bonus := boundaryBonus(runes, i)
+		if consecutive == 0 {
+			firstBonus = bonus
+		} else {
+			// a run of characters keeps the bonus of its first one
+			bonus = Max(Max(bonus, firstBonus), fzfConsecutive)
+		}
+		if pi == 0 {
+			bonus *= 2
+		}
+		score += fzfMatch + bonus
+		inGap = false
+		consecutive++
+		pi++
+	}
+	return score, true
+}
+
+// exactMatch matches the pattern as a substring of the string, at its
+// start or its end if prefix or suffix, and scores the occurrence at the
+// best boundary
+func exactMatch(pat, runes []rune, fold, prefix, suffix bool) (int, bool) {
+	best := -1
+	for i := 0; i+len(pat) <= len(runes); i++ {
+		if prefix && i > 0 || suffix && i+len(pat) != len(runes) {
+			continue
+		}
+		j := 0
+		for j < len(pat) && runeEqual(runes[i+j], pat[j], fold) {
+			j++
+		}
+		if j < len(pat) {
+			continue
+		}
+		if b := boundaryBonus(runes, i); b > best {
+			best = b
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return fzfMatch*len(pat) + 2*best + fzfConsecutive*(len(pat)-1), true
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	_, ok := FuzzyMatch("", "anything")
+	assert.True(t, ok)
+
+	_, ok = FuzzyMatch("sv", "Save")
+	assert.True(t, ok)
+	_, ok = FuzzyMatch("vs", "Save")
+	assert.False(t, ok)
+
+	// word starts and consecutive characters beat scattered matches
+	s1, _ := FuzzyMatch("ff", "find_file")
+	s2, _ := FuzzyMatch("ff", "offset")
+	assert.Greater(t, s1, s2)
+
+	s1, _ = FuzzyMatch("sa", "SelectAll")
+	s2, _ = FuzzyMatch("sa", "Session")
+	assert.Greater(t, s1, s2)
+
+	s1, _ = FuzzyMatch("save", "Save")
+	s2, _ = FuzzyMatch("save", "SaveAll")
+	assert.Greater(t, s1, s2)
+}
+
+func TestFuzzyMatchers(t *testing.T) {
+	defer SetFuzzyMatcher("subsequence", "ignore")
+
+	SetFuzzyMatcher("subsequence", "smart")
+	_, ok := FuzzyMatch("sv", "Save")
+	assert.True(t, ok)
+	_, ok = FuzzyMatch("Sv", "save")
+	assert.False(t, ok)
+	SetFuzzyMatcher("subsequence", "respect")
+	_, ok = FuzzyMatch("sv", "Save")
+	assert.False(t, ok)
+
+	SetFuzzyMatcher("exact", "ignore")
+	_, ok = FuzzyMatch("ave", "Save")
+	assert.True(t, ok)
+	_, ok = FuzzyMatch("sv", "Save")
+	assert.False(t, ok)
+	// a match at the start of a word is better
+	s1, _ := FuzzyMatch("file", "find_file")
+	s2, _ := FuzzyMatch("file", "profiles")
+	assert.Greater(t, s1, s2)
+
+	SetFuzzyMatcher("fzf", "ignore")
+	_, ok = FuzzyMatch("act go", "internal/action/tab.go")
+	assert.True(t, ok)
+	_, ok = FuzzyMatch("go act", "internal/action/tab.go")
+	assert.True(t, ok)
+	_, ok = FuzzyMatch("act !tab", "internal/action/tab.go")
+	assert.False(t, ok)
+	_, ok = FuzzyMatch("^int .go$", "internal/action/tab.go")
+	assert.True(t, ok)
+	_, ok = FuzzyMatch("^act", "internal/action/tab.go")
+	assert.False(t, ok)
+	_, ok = FuzzyMatch("'tba", "internal/action/tab.go")
+	assert.False(t, ok)
+	_, ok = FuzzyMatch("tba", "internal/action/tab.go")
+	assert.False(t, ok)
+	// the shortest string and the match at word starts win
+	s1, _ = FuzzyMatch("tab", "internal/action/tab.go")
+	s2, _ = FuzzyMatch("tab", "internal/action/table_test.go")
+	assert.Greater(t, s1, s2)
+	s1, _ = FuzzyMatch("bp", "bufpane.go")
+	s2, _ = FuzzyMatch("bp", "subpath.go")
+	assert.Greater(t, s1, s2)
+
+	FuzzyScorer = func(pattern, str string) (int, bool) {
+		return len(str), str != pattern
+	}
+	defer func() { FuzzyScorer = nil }()
+	s1, ok = FuzzyMatch("a", "abc")
+	assert.True(t, ok)
+	assert.Equal(t, 3, s1)
+	_, ok = FuzzyMatch("a", "a")
+	assert.False(t, ok)
+	_, ok = BuiltinFuzzyMatch("a", "a")
+	assert.True(t, ok)
+}
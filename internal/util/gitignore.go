@@ -0,0 +1,214 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrTooManyFiles is returned by ProjectFiles when the file limit is hit
+var ErrTooManyFiles = errors.New("Too many files, the list is incomplete")
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	base    string
+	negate  bool
+	dirOnly bool
+}
+
+// A Gitignore holds the patterns of the .gitignore files of a directory tree
+type Gitignore struct {
+	rules []ignoreRule
+}
+
+// AddPatterns adds the patterns of a .gitignore file found in the directory
+// base, given relative to the root of the tree with '/' separators
+func (g *Gitignore) AddPatterns(base string, data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// a pattern containing a slash is relative to the .gitignore,
+		// otherwise it matches at any depth
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		r.re = re
+		g.rules = append(g.rules, r)
+	}
+}
+
+// globToRegexp converts a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.Replace(class, "\\", "\\\\", -1) + "]")
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// Ignored returns whether the given path, relative to the root of the tree
+// with '/' separators, is ignored. As in git, the last matching pattern
+// decides.
+func (g *Gitignore) Ignored(path string, isDir bool) bool {
+	ignored := false
+	for _, r := range g.rules {
+		rel := path
+		if r.base != "" {
+			if !strings.HasPrefix(path, r.base+"/") {
+				continue
+			}
+			rel = path[len(r.base)+1:]
+		}
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// ProjectSettingsFile is the file holding the settings of a project, at its
+// root
+const ProjectSettingsFile = ".micro.json"
+
+// ProjectMarkers are the files or directories marking the root of a
+// project: a git repository or a project settings file
+var ProjectMarkers = []string{".git", ProjectSettingsFile}
+
+// FindProjectRoot returns the closest parent of dir (or dir itself) which
+// contains one of the ProjectMarkers, or false if there is none
+func FindProjectRoot(dir string) (string, bool) {
+	d := dir
+	for {
+		for _, m := range ProjectMarkers {
+			if _, err := os.Stat(filepath.Join(d, m)); err == nil {
+				return d, true
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", false
+		}
+		d = parent
+	}
+}
+
+// ProjectRoot returns the root of the project containing dir, or dir if it
+// isn't in a project
+func ProjectRoot(dir string) string {
+	if root, ok := FindProjectRoot(dir); ok {
+		return root
+	}
+	return dir
+}
+
+// ProjectFiles lists the files below root, relative to it, skipping the .git
+// directory and anything ignored by the .gitignore files of the tree. At most
+// limit files are returned, in which case the error is ErrTooManyFiles.
+func ProjectFiles(root string, limit int) ([]string, error) {
+	var files []string
+	ignore := new(Gitignore)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// skip unreadable files and directories
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if rel == "." {
+				rel = ""
+			} else if info.Name() == ".git" || ignore.Ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if data, err := ioutil.ReadFile(filepath.Join(path, ".gitignore")); err == nil {
+				ignore.AddPatterns(rel, data)
+			}
+			return nil
+		}
+
+		if ignore.Ignored(rel, false) {
+			return nil
+		}
+		if len(files) >= limit {
+			return ErrTooManyFiles
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
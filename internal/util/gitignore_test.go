@@ -0,0 +1,75 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitignore(t *testing.T) {
+	g := new(Gitignore)
+	g.AddPatterns("", []byte("# comment\n*.o\n/build\nvendor/\n!keep.o\ndocs/**/*.tmp\n"))
+	g.AddPatterns("sub", []byte("local.txt\n"))
+
+	assert.True(t, g.Ignored("main.o", false))
+	assert.True(t, g.Ignored("a/b/main.o", false))
+	assert.False(t, g.Ignored("keep.o", false))
+	assert.True(t, g.Ignored("build", true))
+	assert.False(t, g.Ignored("src/build", true))
+	assert.True(t, g.Ignored("src/vendor", true))
+	assert.False(t, g.Ignored("vendor", false))
+	assert.True(t, g.Ignored("docs/x.tmp", false))
+	assert.True(t, g.Ignored("docs/a/b/x.tmp", false))
+	assert.True(t, g.Ignored("sub/local.txt", false))
+	assert.False(t, g.Ignored("local.txt", false))
+	assert.False(t, g.Ignored("main.go", false))
+}
+
+func TestProjectFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "micro-project")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	write := func(name, data string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(data), 0644))
+	}
+	write(".gitignore", "*.log\nout/\n")
+	write(".git/HEAD", "ref")
+	write("main.go", "")
+	write("debug.log", "")
+	write("out/bin", "")
+	write("pkg/a.go", "")
+	write("pkg/.gitignore", "gen.go\n")
+	write("pkg/gen.go", "")
+
+	files, err := ProjectFiles(root, 100)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{".gitignore", "main.go", "pkg/.gitignore", "pkg/a.go"}, files)
+	assert.Equal(t, root, ProjectRoot(filepath.Join(root, "pkg")))
+
+	files, err = ProjectFiles(root, 2)
+	assert.Equal(t, ErrTooManyFiles, err)
+	assert.Len(t, files, 2)
+}
+
+func TestFindProjectRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "micro-project")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "a", "b")
+	assert.Nil(t, os.MkdirAll(sub, 0755))
+	_, ok := FindProjectRoot(sub)
+	assert.False(t, ok)
+	assert.Equal(t, sub, ProjectRoot(sub))
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(root, "a", ".micro.json"), []byte("{}"), 0644))
+	found, ok := FindProjectRoot(sub)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(root, "a"), found)
+}
@@ -0,0 +1,116 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// maxGrepFileSize is the size above which files are not searched
+const maxGrepFileSize = 16 * 1024 * 1024
+
+// A GrepLine is a line shown in the results of a search, either a matching
+// line or a context line around one
+type GrepLine struct {
+	// Num is the line number, starting at 0
+	Num   int
+	Text  string
+	Match bool
+	// Col is the character offset of the first match on a matching line
+	Col int
+}
+
+// A GrepResult holds the matching lines of one file along with their context
+type GrepResult struct {
+	// Path of the file relative to the search root, with '/' separators
+	Path    string
+	Lines   []GrepLine
+	Matches int
+}
+
+// Grep searches the given files, which are relative to root, for the regular
+// expression using one goroutine per CPU. A result is sent for each file with
+// at least one match, including up to context lines before and after each
+// match. The results channel is closed once all files have been searched or
+// the search is stopped by closing stop.
+func Grep(root string, files []string, re Regexp, context int, results chan<- GrepResult, stop <-chan struct{}) {
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				r, ok := grepFile(filepath.Join(root, filepath.FromSlash(p)), re, context)
+				if !ok {
+					continue
+				}
+				r.Path = p
+				select {
+				case results <- r:
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+	loop:
+		for _, f := range files {
+			select {
+			case paths <- f:
+			case <-stop:
+				break loop
+			}
+		}
+		close(paths)
+		wg.Wait()
+		close(results)
+	}()
+}
+
+// grepFile searches a single file. Binary and very large files are skipped.
+func grepFile(path string, re Regexp, context int) (GrepResult, bool) {
+	var r GrepResult
+	data, err := ioutil.ReadFile(path)
+	if err != nil || len(data) > maxGrepFileSize {
+		return r, false
+	}
+	if bytes.IndexByte(data[:Min(len(data), 8000)], 0) >= 0 {
+		return r, false
+	}
+
+	lines := bytes.Split(data, []byte{'\n'})
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		lines[i] = bytes.TrimSuffix(l, []byte{'\r'})
+	}
+
+	// mark the matching lines and the context lines around them
+	match := make([]bool, len(lines))
+	show := make([]bool, len(lines))
+	cols := make(map[int]int)
+	for i, l := range lines {
+		loc := re.FindIndex(l)
+		if loc == nil {
+			continue
+		}
+		r.Matches++
+		match[i] = true
+		cols[i] = RunePos(l, loc[0])
+		for j := Max(0, i-context); j <= Min(len(lines)-1, i+context); j++ {
+			show[j] = true
+		}
+	}
+	for i, l := range lines {
+		if show[i] {
+			r.Lines = append(r.Lines, GrepLine{i, string(l), match[i], cols[i]})
+		}
+	}
+	return r, r.Matches > 0
+}
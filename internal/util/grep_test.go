@@ -0,0 +1,42 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrep(t *testing.T) {
+	root, err := ioutil.TempDir("", "micro-grep")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("one\ntwo\nfoo\nthree\nfour\nfive\nxfoo\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "b.txt"), []byte("nothing here\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "c.bin"), []byte("foo\x00"), 0644)
+
+	results := make(chan GrepResult)
+	Grep(root, []string{"a.txt", "b.txt", "c.bin", "missing"}, regexp.MustCompile("foo"), 1, results, nil)
+
+	var all []GrepResult
+	for r := range results {
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Path < all[j].Path })
+
+	assert.Len(t, all, 1)
+	assert.Equal(t, "a.txt", all[0].Path)
+	assert.Equal(t, 2, all[0].Matches)
+	assert.Equal(t, []GrepLine{
+		{1, "two", false, 0},
+		{2, "foo", true, 0},
+		{3, "three", false, 0},
+		{5, "five", false, 0},
+		{6, "xfoo", true, 1},
+	}, all[0].Lines)
+}
@@ -0,0 +1,163 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A LogLevel is the severity of a message of the log
+type LogLevel int
+
+// The levels of the messages of the log, from the least severe. The
+// messages below the loglevel option are dropped.
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// LogLevels are the names of the levels, by level
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// LogSubsystems are the parts of micro which log messages, which the
+// logfilter option selects
+var LogSubsystems = []string{"buffer", "config", "display", "editor", "lua", "rpc"}
+
+func (l LogLevel) String() string {
+	if l >= 0 && int(l) < len(LogLevels) {
+		return LogLevels[l]
+	}
+	return "unknown"
+}
+
+// ParseLogLevel returns the level with the given name
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, n := range LogLevels {
+		if n == name {
+			return LogLevel(i), nil
+		}
+	}
+	return LogWarn, errors.New("Unknown log level " + name)
+}
+
+// A LogEntry is a message of the log
+type LogEntry struct {
+	Time      time.Time
+	Level     LogLevel
+	Subsystem string
+	Message   string
+}
+
+func (e LogEntry) String() string {
+	return fmt.Sprintf("%s %-5s %s: %s", e.Time.Format("15:04:05"), e.Level, e.Subsystem, e.Message)
+}
+
+// maxLogFileSize is the size above which the log file is started over
+// when it is opened
+const maxLogFileSize = 1 << 20
+
+// LogUpdates receives the messages as they are logged, which the main loop
+// writes to the log pane. The messages are dropped when it is full.
+var LogUpdates = make(chan LogEntry, 100)
+
+var logState = struct {
+	sync.Mutex
+	level  LogLevel
+	filter map[string]bool
+	file   *os.File
+}{level: LogWarn}
+
+// SetLogLevel drops the messages below the level from now on
+func SetLogLevel(level LogLevel) {
+	logState.Lock()
+	logState.level = level
+	logState.Unlock()
+}
+
+// SetLogFilter only keeps the messages of the subsystems of the comma
+// separated list from now on, or those of all subsystems if it is empty
+func SetLogFilter(list string) {
+	var filter map[string]bool
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			if filter == nil {
+				filter = make(map[string]bool)
+			}
+			filter[s] = true
+		}
+	}
+	logState.Lock()
+	logState.filter = filter
+	logState.Unlock()
+}
+
+// OpenLogFile writes the messages to the file at path from now on, after
+// the messages of the previous sessions unless they grew too large
+func OpenLogFile(path string) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if info, err := os.Stat(path); err == nil && info.Size() > maxLogFileSize {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return err
+	}
+	logState.Lock()
+	if logState.file != nil {
+		logState.file.Close()
+	}
+	logState.file = f
+	logState.Unlock()
+	fmt.Fprintf(f, "%s micro %s started\n", time.Now().Format("2006-01-02 15:04:05"), Version)
+	return nil
+}
+
+// Log logs a message of a subsystem, made of args separated by spaces, if
+// the level and the subsystem are not filtered out
+func Log(level LogLevel, subsystem string, args ...interface{}) {
+	e := LogEntry{
+		Time:      time.Now(),
+		Level:     level,
+		Subsystem: subsystem,
+		Message:   strings.TrimSuffix(fmt.Sprintln(args...), "\n"),
+	}
+	logState.Lock()
+	if level < logState.level || logState.filter != nil && !logState.filter[subsystem] {
+		logState.Unlock()
+		return
+	}
+	if logState.file != nil {
+		fmt.Fprintln(logState.file, e.Time.Format("2006-01-02"), e)
+	}
+	logState.Unlock()
+
+	select {
+	case LogUpdates <- e:
+	default:
+	}
+}
+
+// logWriter logs the lines written to it
+type logWriter struct {
+	level     LogLevel
+	subsystem string
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	for _, l := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		Log(w.level, w.subsystem, l)
+	}
+	return len(p), nil
+}
+
+// LogWriter returns a writer logging each line written to it as a message
+// of the subsystem at the level
+func LogWriter(level LogLevel, subsystem string) io.Writer {
+	return logWriter{level, subsystem}
+}
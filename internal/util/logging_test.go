@@ -0,0 +1,68 @@
+package util
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drainLog returns the messages of LogUpdates
+func drainLog() []string {
+	var msgs []string
+	for {
+		select {
+		case e := <-LogUpdates:
+			msgs = append(msgs, e.Level.String()+" "+e.Subsystem+": "+e.Message)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	defer SetLogLevel(LogWarn)
+	defer SetLogFilter("")
+	drainLog()
+
+	path := filepath.Join(dir, "log.txt")
+	assert.NoError(t, OpenLogFile(path))
+	SetLogLevel(LogInfo)
+	Log(LogDebug, "buffer", "dropped")
+	Log(LogInfo, "buffer", "Saved", 3, "files")
+	Log(LogError, "lua", "failed")
+	assert.Equal(t, []string{"info buffer: Saved 3 files", "error lua: failed"}, drainLog())
+
+	SetLogFilter("lua, rpc")
+	Log(LogWarn, "buffer", "dropped")
+	Log(LogWarn, "rpc", "kept")
+	assert.Equal(t, []string{"warn rpc: kept"}, drainLog())
+
+	SetLogFilter("")
+	l := log.New(LogWriter(LogWarn, "editor"), "", 0)
+	l.Println("first\nsecond")
+	assert.Equal(t, []string{"warn editor: first", "warn editor: second"}, drainLog())
+
+	data, _ := ioutil.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 6) {
+		assert.Contains(t, lines[0], "micro "+Version+" started")
+		assert.True(t, strings.HasSuffix(lines[1], " info  buffer: Saved 3 files"))
+		assert.True(t, strings.HasSuffix(lines[3], " warn  rpc: kept"))
+	}
+
+	level, err := ParseLogLevel("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, LogDebug, level)
+	_, err = ParseLogLevel("verbose")
+	assert.Error(t, err)
+}
@@ -2,8 +2,9 @@ package util
 
 import (
 	"fmt"
-	"log"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	humanize "github.com/dustin/go-humanize"
@@ -17,11 +18,93 @@ func GetMemStats() string {
 }
 
 func Tic(s string) time.Time {
-	log.Println("START:", s)
+	Log(LogDebug, "editor", "START:", s)
 	return time.Now()
 }
 
 func Toc(start time.Time) {
 	end := time.Now()
-	log.Println("END: ElapsedTime in seconds:", end.Sub(start))
+	Log(LogDebug, "editor", "END: ElapsedTime in seconds:", end.Sub(start))
+}
+
+// StartTime is when micro started, which the startup timings are measured
+// from
+var StartTime = time.Now()
+
+// A Timing is the time taken by a step of the startup, such as loading a
+// plugin, with the number of times it ran
+type Timing struct {
+	Name     string
+	Duration time.Duration
+	Count    int
+}
+
+var (
+	timingsLock sync.Mutex
+	timings     []Timing
+	startupDone int32
+)
+
+// RecordTiming adds the time since start to the step name of the startup,
+// until FinishStartup is called
+func RecordTiming(name string, start time.Time) {
+	if atomic.LoadInt32(&startupDone) != 0 {
+		return
+	}
+	d := time.Since(start)
+	timingsLock.Lock()
+	defer timingsLock.Unlock()
+	for i := range timings {
+		if timings[i].Name == name {
+			timings[i].Duration += d
+			timings[i].Count++
+			return
+		}
+	}
+	timings = append(timings, Timing{name, d, 1})
+}
+
+// FinishStartup records the time of the whole startup, when the screen is
+// first drawn, and stops recording timings
+func FinishStartup() {
+	RecordTiming("first draw", StartTime)
+	atomic.StoreInt32(&startupDone, 1)
+}
+
+// Timings returns the timings of the startup, in the order of their steps
+func Timings() []Timing {
+	timingsLock.Lock()
+	defer timingsLock.Unlock()
+	return append([]Timing(nil), timings...)
+}
+
+// LatencyStats sums up the times from input events to the screen drawn
+// after them
+type LatencyStats struct {
+	Count            int
+	Total, Max, Last time.Duration
+}
+
+// Mean returns the average latency
+func (s LatencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// MeasureLatency is whether the latency of the input events is recorded
+var MeasureLatency bool
+
+// Latency is the latency of the input events since MeasureLatency was set
+var Latency LatencyStats
+
+// RecordLatency adds the latency of an input event
+func RecordLatency(d time.Duration) {
+	Latency.Count++
+	Latency.Total += d
+	Latency.Last = d
+	if d > Latency.Max {
+		Latency.Max = d
+	}
 }
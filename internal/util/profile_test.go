@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordTiming(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	RecordTiming("test step", start)
+	RecordTiming("test step", start)
+	var step Timing
+	for _, tm := range Timings() {
+		if tm.Name == "test step" {
+			step = tm
+		}
+	}
+	assert.Equal(t, 2, step.Count)
+	assert.True(t, step.Duration >= 2*time.Second)
+}
+
+func TestLatency(t *testing.T) {
+	Latency = LatencyStats{}
+	assert.Equal(t, time.Duration(0), Latency.Mean())
+	RecordLatency(2 * time.Millisecond)
+	RecordLatency(4 * time.Millisecond)
+	assert.Equal(t, LatencyStats{Count: 2, Total: 6 * time.Millisecond, Max: 4 * time.Millisecond, Last: 4 * time.Millisecond}, Latency)
+	assert.Equal(t, 3*time.Millisecond, Latency.Mean())
+}
@@ -0,0 +1,378 @@
+package util
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+// RegexpMatchTimeout limits the time a single match of the pcre engine may
+// take, since backtracking can be exponential for some patterns
+const RegexpMatchTimeout = time.Second
+
+// A Regexp is a compiled regular expression. It is implemented by
+// *regexp.Regexp and by the pcre engine returned by CompileRegexp, which
+// supports lookaround and backreferences.
+type Regexp interface {
+	Match(b []byte) bool
+	FindIndex(b []byte) []int
+	FindAllIndex(b []byte, n int) [][]int
+	FindAllSubmatchIndex(b []byte, n int) [][]int
+	Expand(dst []byte, template []byte, src []byte, match []int) []byte
+	String() string
+}
+
+// CompileRegexp compiles a regular expression with the given engine, either
+// "go" for the standard library or "pcre" for a Perl compatible backtracking
+// engine
+func CompileRegexp(expr string, engine string) (Regexp, error) {
+	if engine != "pcre" {
+		return regexp.Compile(expr)
+	}
+	re, err := regexp2.Compile(expr, regexp2.RE2)
+	if err != nil {
+		return nil, err
+	}
+	re.MatchTimeout = RegexpMatchTimeout
+	return &pcreRegexp{re}, nil
+}
+
+// FindAllIndexRange returns the byte indices in src of the first n matches
+// of re in src[start:end], or of all of them if n is negative. The matches
+// end at end as if src did, but the text of src before start is seen by ^,
+// \b and \B at the start of the range, and by the lookbehind of the pcre
+// engine, so that both engines match a range within a line alike.
+func FindAllIndexRange(re Regexp, src []byte, start, end, n int) [][]int {
+	switch r := re.(type) {
+	case *pcreRegexp:
+		return r.matchesRange(src, start, end, n, false)
+	case *regexp.Regexp:
+		return goMatchesRange(r, src, start, end, n, false)
+	}
+	return offsetMatches(re.FindAllIndex(src[start:end], n), start)
+}
+
+// FindAllSubmatchIndexRange is like FindAllIndexRange, but also returns the
+// indices of the submatches
+func FindAllSubmatchIndexRange(re Regexp, src []byte, start, end, n int) [][]int {
+	switch r := re.(type) {
+	case *pcreRegexp:
+		return r.matchesRange(src, start, end, n, true)
+	case *regexp.Regexp:
+		return goMatchesRange(r, src, start, end, n, true)
+	}
+	return offsetMatches(re.FindAllSubmatchIndex(src[start:end], n), start)
+}
+
+// goMatchesRange is like FindAllSubmatchIndexRange for the go engine, which
+// cannot start a match within a text. The rune before each position a
+// match is looked for from is matched too by a regexp skipping it, since
+// the assertions of the regexp package look no further back. Empty matches
+// are ignored after a match, as by the regexp package.
+func goMatchesRange(re *regexp.Regexp, src []byte, start, end, n int, sub bool) [][]int {
+	if start == 0 {
+		if sub {
+			return re.FindAllSubmatchIndex(src[:end], n)
+		}
+		return re.FindAllIndex(src[:end], n)
+	}
+	after := afterRegexp(re)
+	if after == nil {
+		return nil
+	}
+	var all [][]int
+	prev := -1
+	for pos := start; pos <= end && (n < 0 || len(all) < n); {
+		_, size := utf8.DecodeLastRune(src[:pos])
+		m := after.FindSubmatchIndex(src[pos-size : end])
+		if m == nil {
+			break
+		}
+		m = offsetMatches([][]int{m[2:]}, pos-size)[0]
+		accept := true
+		if m[1] == pos {
+			accept = m[0] != prev
+			if pos == end {
+				pos++
+			} else {
+				_, size = utf8.DecodeRune(src[pos:end])
+				pos += size
+			}
+		} else {
+			pos = m[1]
+		}
+		prev = m[1]
+		if !accept {
+			continue
+		}
+		if !sub {
+			m = m[:2]
+		}
+		all = append(all, m)
+	}
+	return all
+}
+
+// maxAfterRegexps bounds the number of regexps cached by afterRegexp
+const maxAfterRegexps = 16
+
+var afterRegexps = struct {
+	sync.Mutex
+	m map[*regexp.Regexp]*regexp.Regexp
+}{m: make(map[*regexp.Regexp]*regexp.Regexp)}
+
+// afterRegexp returns the regexp of goMatchesRange for re, which matches a
+// rune and then re, or nil if it doesn't compile. It is cached, since a
+// search looks for the same regexp from many positions.
+func afterRegexp(re *regexp.Regexp) *regexp.Regexp {
+	afterRegexps.Lock()
+	defer afterRegexps.Unlock()
+	if after, ok := afterRegexps.m[re]; ok {
+		return after
+	}
+	if len(afterRegexps.m) >= maxAfterRegexps {
+		afterRegexps.m = make(map[*regexp.Regexp]*regexp.Regexp)
+	}
+	// the regexp is nil if it doesn't compile, which is cached as well
+	after, _ := regexp.Compile(`(?s:.)(` + re.String() + `)`)
+	afterRegexps.m[re] = after
+	return after
+}
+
+// offsetMatches adds offset to the indices of the matches, but to the -1 of
+// the submatches which did not match
+func offsetMatches(matches [][]int, offset int) [][]int {
+	for _, m := range matches {
+		for i := range m {
+			if m[i] >= 0 {
+				m[i] += offset
+			}
+		}
+	}
+	return matches
+}
+
+// ReplaceRange replaces the matches of re in src[start:end], found as by
+// FindAllSubmatchIndexRange, with template, expanded as by ExpandTemplate.
+// It returns the new text for src[start:end] and the number of replacements
+// made.
+func ReplaceRange(re Regexp, src, template []byte, start, end int) ([]byte, int) {
+	return ReplaceRangeFunc(re, src, start, end, func(m []int) []byte {
+		return ExpandTemplate(re, nil, template, src, m)
+	})
+}
+
+// ReplaceRangeFunc is like ReplaceRange, but the replacement of each match
+// is returned by repl, given the indices of the match and its submatches
+func ReplaceRangeFunc(re Regexp, src []byte, start, end int, repl func(match []int) []byte) ([]byte, int) {
+	result := []byte{}
+	last := start
+	n := 0
+	for _, m := range FindAllSubmatchIndexRange(re, src, start, end, -1) {
+		result = append(result, src[last:m[0]]...)
+		result = append(result, repl(m)...)
+		last = m[1]
+		n++
+	}
+	result = append(result, src[last:end]...)
+	return result, n
+}
+
+// ExpandTemplate appends template to dst with the submatches of src
+// substituted as by Expand, and also referred to as \0 to \9. \U and \L
+// turn the text following them, up to \E, to upper or lower case, and \u
+// and \l the next character only. \\ is a backslash, and the other
+// backslashes are kept as they are.
+func ExpandTemplate(re Regexp, dst, template, src []byte, match []int) []byte {
+	var mode, next byte
+	var lit []byte
+	flush := func() {
+		text := re.Expand(nil, lit, src, match)
+		lit = lit[:0]
+		switch mode {
+		case 'U':
+			text = bytes.ToUpper(text)
+		case 'L':
+			text = bytes.ToLower(text)
+		}
+		if next != 0 && len(text) > 0 {
+			r, size := utf8.DecodeRune(text)
+			if next == 'u' {
+				r = unicode.ToUpper(r)
+			} else {
+				r = unicode.ToLower(r)
+			}
+			dst = append(dst, string(r)...)
+			text = text[size:]
+			next = 0
+		}
+		dst = append(dst, text...)
+	}
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '\\' || i+1 == len(template) {
+			lit = append(lit, c)
+			continue
+		}
+		switch e := template[i+1]; {
+		case e == 'U' || e == 'L' || e == 'E':
+			flush()
+			mode = e
+		case e == 'u' || e == 'l':
+			flush()
+			next = e
+		case e >= '0' && e <= '9':
+			lit = append(lit, '$', '{', e, '}')
+		case e == '\\':
+			lit = append(lit, '\\')
+		default:
+			lit = append(lit, c, e)
+		}
+		i++
+	}
+	flush()
+	return dst
+}
+
+type pcreRegexp struct {
+	re *regexp2.Regexp
+}
+
+func (p *pcreRegexp) String() string {
+	return p.re.String()
+}
+
+// runes decodes b and returns the byte offset of each rune, with len(b)
+// added at the end
+func runes(b []byte) ([]rune, []int) {
+	rs := make([]rune, 0, len(b))
+	offsets := make([]int, 0, len(b)+1)
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		rs = append(rs, r)
+		offsets = append(offsets, i)
+		i += size
+	}
+	offsets = append(offsets, len(b))
+	return rs, offsets
+}
+
+// matches returns the byte indices of the first n matches, including the
+// submatches if sub is set. A match that times out ends the search.
+func (p *pcreRegexp) matches(b []byte, n int, sub bool) [][]int {
+	return p.matchesRange(b, 0, len(b), n, sub)
+}
+
+// matchesRange is like matches for the matches in b[start:end], which may
+// look behind start
+func (p *pcreRegexp) matchesRange(b []byte, start, end, n int, sub bool) [][]int {
+	rs, offsets := runes(b[:end])
+	var all [][]int
+	m, err := p.re.FindRunesMatchStartingAt(rs, utf8.RuneCount(b[:start]))
+	for m != nil && err == nil && (n < 0 || len(all) < n) {
+		groups := []regexp2.Group{m.Group}
+		if sub {
+			groups = m.Groups()
+		}
+		idx := make([]int, 0, 2*len(groups))
+		for _, g := range groups {
+			if len(g.Captures) == 0 {
+				idx = append(idx, -1, -1)
+				continue
+			}
+			idx = append(idx, offsets[g.Index], offsets[g.Index+g.Length])
+		}
+		all = append(all, idx)
+		m, err = p.re.FindNextMatch(m)
+	}
+	return all
+}
+
+func (p *pcreRegexp) Match(b []byte) bool {
+	return p.FindIndex(b) != nil
+}
+
+func (p *pcreRegexp) FindIndex(b []byte) []int {
+	if m := p.matches(b, 1, false); len(m) > 0 {
+		return m[0]
+	}
+	return nil
+}
+
+func (p *pcreRegexp) FindAllIndex(b []byte, n int) [][]int {
+	return p.matches(b, n, false)
+}
+
+func (p *pcreRegexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	return p.matches(b, n, true)
+}
+
+// Expand appends template to dst with $1, ${1}, $name and ${name} replaced
+// by the corresponding submatch of src, as in the regexp package
+func (p *pcreRegexp) Expand(dst []byte, template []byte, src []byte, match []int) []byte {
+	for len(template) > 0 {
+		i := 0
+		for i < len(template) && template[i] != '$' {
+			i++
+		}
+		dst = append(dst, template[:i]...)
+		template = template[i:]
+		if len(template) == 0 {
+			break
+		}
+
+		// template starts with '$'
+		if len(template) > 1 && template[1] == '$' {
+			dst = append(dst, '$')
+			template = template[2:]
+			continue
+		}
+		name, rest, ok := expandName(template[1:])
+		if !ok {
+			// malformed, keep the '$' as is
+			dst = append(dst, '$')
+			template = template[1:]
+			continue
+		}
+		template = rest
+
+		num, err := strconv.Atoi(name)
+		if err != nil {
+			num = p.re.GroupNumberFromName(name)
+		}
+		if num >= 0 && 2*num+1 < len(match) && match[2*num] >= 0 {
+			dst = append(dst, src[match[2*num]:match[2*num+1]]...)
+		}
+	}
+	return dst
+}
+
+// expandName extracts the group name after a '$' in a template, either
+// braced or the longest sequence of letters, digits and underscores
+func expandName(t []byte) (string, []byte, bool) {
+	brace := len(t) > 0 && t[0] == '{'
+	if brace {
+		t = t[1:]
+	}
+	i := 0
+	for i < len(t) && (t[i] == '_' || t[i] >= '0' && t[i] <= '9' || t[i] >= 'a' && t[i] <= 'z' || t[i] >= 'A' && t[i] <= 'Z') {
+		i++
+	}
+	if i == 0 {
+		return "", nil, false
+	}
+	name := t[:i]
+	if brace {
+		if i >= len(t) || t[i] != '}' {
+			return "", nil, false
+		}
+		i++
+	}
+	return string(name), t[i:], true
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileRegexp(t *testing.T) {
+	_, err := CompileRegexp("(?<=foo)bar", "go")
+	assert.NotNil(t, err)
+
+	re, err := CompileRegexp("(?<=foo)bar", "pcre")
+	assert.Nil(t, err)
+	assert.True(t, re.Match([]byte("foobar")))
+	assert.False(t, re.Match([]byte("bazbar")))
+
+	// indices are in bytes, as with the regexp package
+	re, _ = CompileRegexp("b(?!x)", "pcre")
+	assert.Equal(t, [][]int{{6, 7}, {8, 9}}, re.FindAllIndex([]byte("ébx ab b"), -1))
+	assert.Equal(t, []int{6, 7}, re.FindIndex([]byte("ébx ab b")))
+}
+
+func TestReplaceRange(t *testing.T) {
+	src := []byte("foo1 foo2 foo3")
+	for _, engine := range []string{"go", "pcre"} {
+		re, _ := CompileRegexp(`foo(?P<n>\d)`, engine)
+		out, n := ReplaceRange(re, src, []byte("$n${1}$$"), 0, len(src))
+		assert.Equal(t, "11$ 22$ 33$", string(out))
+		assert.Equal(t, 3, n)
+
+		out, n = ReplaceRange(re, src, []byte("x"), 5, 14)
+		assert.Equal(t, "x x", string(out))
+		assert.Equal(t, 2, n)
+	}
+
+	re, _ := CompileRegexp(`(?<=o)\d`, "pcre")
+	out, n := ReplaceRange(re, src, []byte("_"), 3, 4)
+	assert.Equal(t, "_", string(out))
+	assert.Equal(t, 1, n)
+}
+
+func TestFindAllIndexRange(t *testing.T) {
+	src := []byte("abba aaa")
+	for _, engine := range []string{"go", "pcre"} {
+		find := func(expr string, start, end int) [][]int {
+			re, err := CompileRegexp(expr, engine)
+			assert.Nil(t, err)
+			return FindAllIndexRange(re, src, start, end, -1)
+		}
+
+		// the start of a range within a word is not a line start or a word
+		// boundary
+		assert.Nil(t, find(`^b`, 2, 4), engine)
+		assert.Nil(t, find(`\bb`, 2, 4), engine)
+		assert.Equal(t, [][]int{{2, 3}}, find(`\Bb`, 2, 4), engine)
+		assert.Equal(t, [][]int{{0, 1}}, find(`^a`, 0, 4), engine)
+		assert.Equal(t, [][]int{{5, 6}}, find(`\ba`, 4, 8), engine)
+
+		// the end of the range is the end of the text
+		assert.Equal(t, [][]int{{2, 3}}, find(`b$`, 1, 3), engine)
+		assert.Equal(t, [][]int{{6, 8}}, find(`a+`, 6, 8), engine)
+		assert.Equal(t, [][]int{{6, 8}}, find(`aa`, 6, 8), engine)
+
+		assert.Equal(t, [][]int{{2, 2}, {3, 3}, {4, 4}}, find(`x*`, 2, 4), engine)
+	}
+
+	// as with the regexp package, an empty match right after a match is
+	// ignored
+	re, _ := CompileRegexp(`b?`, "go")
+	assert.Equal(t, [][]int{{2, 3}, {4, 4}}, FindAllIndexRange(re, src, 2, 4, -1))
+
+	// the regexp matching from within a text is compiled once
+	after := afterRegexp(re.(*regexp.Regexp))
+	assert.Equal(t, `(?s:.)(b?)`, after.String())
+	assert.True(t, after == afterRegexp(re.(*regexp.Regexp)))
+}
+
+func TestExpandTemplate(t *testing.T) {
+	src := []byte("foo_bar")
+	for _, engine := range []string{"go", "pcre"} {
+		re, _ := CompileRegexp(`(\w+)_(?P<a>\w+)`, engine)
+		m := re.FindAllSubmatchIndex(src, -1)[0]
+		expand := func(template string) string {
+			return string(ExpandTemplate(re, nil, []byte(template), src, m))
+		}
+		assert.Equal(t, "bar-foo", expand(`\2-\1`))
+		assert.Equal(t, "BAR_foo", expand(`\U$a\E_\1`))
+		assert.Equal(t, "Foo Bar", expand(`\u\1 \u${2}`))
+		assert.Equal(t, "fOO", expand(`\U\l\1`))
+		assert.Equal(t, `\1 \n`, expand(`\\1 \n`))
+		assert.Equal(t, "foo_bar\\", expand(`\0\`))
+	}
+}
@@ -0,0 +1,147 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// ErrNoTrash is returned by MoveToTrash on the systems without a trash
+var ErrNoTrash = errors.New("The system has no trash")
+
+// trashDir returns the trash of the user, which is ~/.Trash on macOS and
+// the home trash of the freedesktop.org specification on the other Unix
+// systems, or "" if there is none
+func trashDir() string {
+	home, err := homedir.Dir()
+	switch {
+	case runtime.GOOS == "windows" || runtime.GOOS == "plan9":
+		return ""
+	case runtime.GOOS == "darwin":
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(home, ".Trash")
+	}
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		if err != nil {
+			return ""
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(data, "Trash")
+}
+
+// HasTrash returns whether MoveToTrash can move files to a trash on this
+// system
+func HasTrash() bool {
+	return trashDir() != ""
+}
+
+// MoveToTrash moves the file at path to the trash of the user, under a
+// name which no other file of the trash has. In the freedesktop.org trash,
+// the original path and the time of the deletion are written along with it
+// so that it can be restored. A file on another file system than the home
+// trash goes to the trash at the top of its file system, or is copied to
+// the home trash if there is none.
+func MoveToTrash(path string) error {
+	dir := trashDir()
+	if dir == "" {
+		return ErrNoTrash
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if top := topTrashDir(abs, dir); top != "" {
+		if err := moveToTrash(abs, top); err == nil {
+			return nil
+		}
+	}
+	return moveToTrash(abs, dir)
+}
+
+// moveToTrash moves the file at abs to the trash dir
+func moveToTrash(abs, dir string) error {
+	files, info := dir, ""
+	if runtime.GOOS != "darwin" {
+		files, info = filepath.Join(dir, "files"), filepath.Join(dir, "info")
+		if err := os.MkdirAll(info, 0700); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(files, 0700); err != nil {
+		return err
+	}
+
+	base := filepath.Base(abs)
+	name := base
+	for n := 2; ; n++ {
+		_, errFile := os.Lstat(filepath.Join(files, name))
+		_, errInfo := os.Lstat(filepath.Join(info, name+".trashinfo"))
+		if os.IsNotExist(errFile) && (info == "" || os.IsNotExist(errInfo)) {
+			break
+		}
+		name = base + "." + strconv.Itoa(n)
+	}
+
+	infoFile := ""
+	if info != "" {
+		infoFile = filepath.Join(info, name+".trashinfo")
+		u := url.URL{Path: abs}
+		data := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", u.EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		if err := ioutil.WriteFile(infoFile, []byte(data), 0600); err != nil {
+			return err
+		}
+	}
+	err := os.Rename(abs, filepath.Join(files, name))
+	if err != nil && crossDevice(err) {
+		err = copyAndRemove(abs, filepath.Join(files, name))
+	}
+	if err != nil && infoFile != "" {
+		os.Remove(infoFile)
+	}
+	return err
+}
+
+// copyAndRemove moves the regular file src to dst by copying it, for the
+// files which can't be renamed across file systems
+func copyAndRemove(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return errors.New(src + " is on another file system than the trash")
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Remove(src)
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
@@ -0,0 +1,15 @@
+// +build plan9 nacl windows
+
+package util
+
+// topTrashDir returns the trash of the file system of the file at path,
+// which these systems have none of
+func topTrashDir(path, home string) string {
+	return ""
+}
+
+// crossDevice returns whether a rename failed because the files are on
+// different file systems, which isn't known on this system
+func crossDevice(err error) bool {
+	return false
+}
@@ -0,0 +1,64 @@
+// +build linux darwin dragonfly solaris openbsd netbsd freebsd
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// device returns the device of the file system holding the file at path,
+// or of its closest parent which exists
+func device(path string) (uint64, bool) {
+	for {
+		var st syscall.Stat_t
+		if err := syscall.Lstat(path, &st); err == nil {
+			return uint64(st.Dev), true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, false
+		}
+		path = parent
+	}
+}
+
+// topTrashDir returns the trash of the file system of the file at path,
+// $topdir/.Trash-$uid in the freedesktop.org specification, if it is not
+// the file system of the home trash, or "" if the home trash should be
+// used. The trash must be a directory of the user if it exists.
+func topTrashDir(path, home string) string {
+	if runtime.GOOS == "darwin" {
+		return ""
+	}
+	dev, ok := device(path)
+	homeDev, homeOk := device(home)
+	if !ok || !homeOk || dev == homeDev {
+		return ""
+	}
+	// the top directory is the mount point, the last parent on the device
+	top := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(top)
+		if d, ok := device(parent); parent == top || !ok || d != dev {
+			break
+		}
+		top = parent
+	}
+	dir := filepath.Join(top, ".Trash-"+strconv.Itoa(os.Getuid()))
+	var st syscall.Stat_t
+	if err := syscall.Lstat(dir, &st); err == nil && (st.Mode&syscall.S_IFMT != syscall.S_IFDIR || int(st.Uid) != os.Getuid()) {
+		return ""
+	}
+	return dir
+}
+
+// crossDevice returns whether a rename failed because the files are on
+// different file systems
+func crossDevice(err error) bool {
+	le, ok := err.(*os.LinkError)
+	return ok && le.Err == syscall.EXDEV
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveToTrash(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" || runtime.GOOS == "plan9" {
+		t.Skip("the test uses the freedesktop.org trash")
+	}
+	dir, err := ioutil.TempDir("", "trash")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	assert.True(t, HasTrash())
+
+	path := filepath.Join(dir, "my file.txt")
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, ioutil.WriteFile(path, []byte("text"), 0644))
+		assert.NoError(t, MoveToTrash(path))
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	trash := filepath.Join(dir, "data", "Trash")
+	data, err := ioutil.ReadFile(filepath.Join(trash, "files", "my file.txt.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "text", string(data))
+	info, err := ioutil.ReadFile(filepath.Join(trash, "info", "my file.txt.trashinfo"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(info), "[Trash Info]\nPath="+filepath.ToSlash(dir)+"/my%20file.txt\nDeletionDate="))
+
+	assert.Error(t, MoveToTrash(path))
+	_, err = os.Stat(filepath.Join(trash, "info", "my file.txt.3.trashinfo"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCopyToTrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trash")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// the files of the same file system are moved to the home trash
+	path := filepath.Join(dir, "a.txt")
+	assert.Equal(t, "", topTrashDir(path, filepath.Join(dir, "data", "Trash")))
+
+	// the files which can't be renamed are copied
+	assert.NoError(t, ioutil.WriteFile(path, []byte("text"), 0600))
+	dst := filepath.Join(dir, "b.txt")
+	assert.NoError(t, copyAndRemove(path, dst))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	data, err := ioutil.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "text", string(data))
+	info, err := os.Stat(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Error(t, copyAndRemove(dir, filepath.Join(dir, "c")))
+	assert.False(t, crossDevice(os.ErrNotExist))
+}
@@ -3,21 +3,29 @@ package util
 import (
 	"unicode"
 	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
 )
 
 // Unicode is annoying. A "code point" (rune in Go-speak) may need up to
 // 4 bytes to represent it. In general, a code point will represent a
 // complete character, but this is not always the case. A character with
 // accents may be made up of multiple code points (the code point for the
-// original character, and additional code points for each accent/marking).
+// original character, and additional code points for each accent/marking),
+// and so are emoji made of several pictographs joined by zero width joiners,
+// emoji with a skin tone modifier and flags (two regional indicators).
 // The functions below are meant to help deal with these additional "combining"
-// code points. In underlying operations (search, replace, etc...), micro will
-// treat a character with combining code points as just the original code point.
-// For rendering, micro will display the combining characters. It's not perfect
-// but it's pretty good.
+// code points, following the grapheme clusters of Unicode closely enough
+// for the cursor to never land inside a character. In underlying operations
+// (search, replace, etc...), micro will treat a character with combining code
+// points as just the original code point. For rendering, micro will display
+// the combining characters. It's not perfect but it's pretty good.
 
 var minMark = rune(unicode.Mark.R16[0].Lo)
 
+// zwj is the zero width joiner
+const zwj = '\u200d'
+
 func isMark(r rune) bool {
 	// Fast path
 	if r < minMark {
@@ -26,20 +34,53 @@ func isMark(r rune) bool {
 	return unicode.In(r, unicode.Mark)
 }
 
+// isPictographic returns whether r is in the blocks of the pictographs
+// which zero width joiners join into a single emoji
+func isPictographic(r rune) bool {
+	return r >= 0x1f000 && r <= 0x1faff || r >= 0x2600 && r <= 0x27bf ||
+		r >= 0x2300 && r <= 0x23ff || r >= 0x2b00 && r <= 0x2bff ||
+		r >= 0x2190 && r <= 0x21ff || r == 0xa9 || r == 0xae
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// joins returns whether c belongs to the character made of the n runes
+// before it, whose first rune is base and last rune is prev
+func joins(base, prev, c rune, n int) bool {
+	switch {
+	case c < minMark:
+		return false
+	case isMark(c) || c == zwj:
+		return true
+	case c >= 0x1f3fb && c <= 0x1f3ff, c >= 0xe0020 && c <= 0xe007f:
+		// emoji modifiers and tags
+		return true
+	case prev == zwj:
+		return isPictographic(base) && isPictographic(c)
+	case isRegionalIndicator(c):
+		return n == 1 && isRegionalIndicator(base)
+	}
+	return false
+}
+
 // DecodeCharacter returns the next character from an array of bytes
 // A character is a rune along with any accompanying combining runes
 func DecodeCharacter(b []byte) (rune, []rune, int) {
 	r, size := utf8.DecodeRune(b)
-	b = b[size:]
-	c, s := utf8.DecodeRune(b)
+	prev, n := r, 1
 
 	var combc []rune
-	for isMark(c) {
+	for size < len(b) {
+		c, s := utf8.DecodeRune(b[size:])
+		if !joins(r, prev, c, n) {
+			break
+		}
 		combc = append(combc, c)
 		size += s
-
-		b = b[s:]
-		c, s = utf8.DecodeRune(b)
+		prev = c
+		n++
 	}
 
 	return r, combc, size
@@ -49,16 +90,18 @@ func DecodeCharacter(b []byte) (rune, []rune, int) {
 // A character is a rune along with any accompanying combining runes
 func DecodeCharacterInString(str string) (rune, []rune, int) {
 	r, size := utf8.DecodeRuneInString(str)
-	str = str[size:]
-	c, s := utf8.DecodeRuneInString(str)
+	prev, n := r, 1
 
 	var combc []rune
-	for isMark(c) {
+	for size < len(str) {
+		c, s := utf8.DecodeRuneInString(str[size:])
+		if !joins(r, prev, c, n) {
+			break
+		}
 		combc = append(combc, c)
 		size += s
-
-		str = str[s:]
-		c, s = utf8.DecodeRuneInString(str)
+		prev = c
+		n++
 	}
 
 	return r, combc, size
@@ -70,12 +113,26 @@ func CharacterCount(b []byte) int {
 	s := 0
 
 	for len(b) > 0 {
-		r, size := utf8.DecodeRune(b)
-		if !isMark(r) {
+		// Fast path
+		if b[0] < utf8.RuneSelf && (len(b) == 1 || b[1] < utf8.RuneSelf) {
+			b = b[1:]
 			s++
+			continue
 		}
 
+		r, size := utf8.DecodeRune(b)
+		prev, n := r, 1
+		for size < len(b) {
+			c, cs := utf8.DecodeRune(b[size:])
+			if !joins(r, prev, c, n) {
+				break
+			}
+			size += cs
+			prev = c
+			n++
+		}
 		b = b[size:]
+		s++
 	}
 
 	return s
@@ -86,11 +143,53 @@ func CharacterCount(b []byte) int {
 func CharacterCountInString(str string) int {
 	s := 0
 
-	for _, r := range str {
-		if !isMark(r) {
+	for len(str) > 0 {
+		// Fast path
+		if str[0] < utf8.RuneSelf && (len(str) == 1 || str[1] < utf8.RuneSelf) {
+			str = str[1:]
 			s++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(str)
+		prev, n := r, 1
+		for size < len(str) {
+			c, cs := utf8.DecodeRuneInString(str[size:])
+			if !joins(r, prev, c, n) {
+				break
+			}
+			size += cs
+			prev = c
+			n++
 		}
+		str = str[size:]
+		s++
 	}
 
 	return s
 }
+
+// CharacterWidth returns the number of cells taken on the screen by a
+// character starting with r, other than a tab. Characters of no width,
+// such as a combining mark without a character before it, take a cell as
+// they do in the terminal.
+func CharacterWidth(r rune) int {
+	if w := runewidth.RuneWidth(r); w > 0 {
+		return w
+	}
+	return 1
+}
+
+// SetAmbiguousWidth sets the width of the characters of ambiguous East Asian
+// width, which the terminal shows in one cell or in two with CJK fonts: wide
+// makes it two cells, narrow one cell and auto chooses from the locale
+func SetAmbiguousWidth(width string) {
+	switch width {
+	case "wide":
+		runewidth.DefaultCondition.EastAsianWidth = true
+	case "narrow":
+		runewidth.DefaultCondition.EastAsianWidth = false
+	default:
+		runewidth.DefaultCondition.EastAsianWidth = runewidth.EastAsianWidth
+	}
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCharacters(t *testing.T) {
+	// a combining mark, an emoji made of pictographs and zero width joiners,
+	// a skin tone modifier, a flag and another flag after it
+	text := "é👨‍👩‍👧👋🏽🇫🇷🇩🇪á"
+	var chars []string
+	for s := text; len(s) > 0; {
+		_, _, size := DecodeCharacterInString(s)
+		chars = append(chars, s[:size])
+		s = s[size:]
+	}
+	assert.Equal(t, []string{"é", "👨‍👩‍👧", "👋🏽", "🇫🇷", "🇩🇪", "á"}, chars)
+	assert.Equal(t, 6, CharacterCountInString(text))
+	assert.Equal(t, 6, CharacterCount([]byte(text)))
+
+	r, combc, size := DecodeCharacter([]byte("👋🏽x"))
+	assert.Equal(t, '👋', r)
+	assert.Equal(t, []rune{'🏽'}, combc)
+	assert.Equal(t, len("👋🏽"), size)
+
+	// a joiner only joins pictographs to the character after it, and a lone
+	// mark is a character
+	assert.Equal(t, 2, CharacterCountInString("a‍b́"))
+	assert.Equal(t, 2, CharacterCountInString("́x"))
+}
+
+func TestCharacterWidth(t *testing.T) {
+	assert.Equal(t, 1, CharacterWidth('a'))
+	assert.Equal(t, 2, CharacterWidth('世'))
+	assert.Equal(t, 2, CharacterWidth('👨'))
+	// shown in a cell of its own
+	assert.Equal(t, 1, CharacterWidth('́'))
+
+	SetAmbiguousWidth("wide")
+	assert.Equal(t, 2, CharacterWidth('±'))
+	SetAmbiguousWidth("narrow")
+	assert.Equal(t, 1, CharacterWidth('±'))
+	SetAmbiguousWidth("auto")
+
+	assert.Equal(t, 5, StringWidth([]byte("é世👋🏽x"), 3, 4))
+}
@@ -17,7 +17,6 @@ import (
 	"unicode"
 
 	"github.com/blang/semver"
-	runewidth "github.com/mattn/go-runewidth"
 )
 
 var (
@@ -145,7 +144,7 @@ func SliceVisualEnd(b []byte, n, tabsize int) ([]byte, int, int) {
 			ts := tabsize - (width % tabsize)
 			w = ts
 		default:
-			w = runewidth.RuneWidth(r)
+			w = CharacterWidth(r)
 		}
 		if width+w > n {
 			return b, n - width, i
@@ -182,7 +181,7 @@ func StringWidth(b []byte, n, tabsize int) int {
 			ts := tabsize - (width % tabsize)
 			width += ts
 		default:
-			width += runewidth.RuneWidth(r)
+			width += CharacterWidth(r)
 		}
 
 		i++
@@ -310,6 +309,23 @@ func ReplaceHome(path string) (string, error) {
 	return strings.Replace(path, homeString, home, 1), nil
 }
 
+// ShortenPath shortens every directory of a path to its first character,
+// or its first two if it starts with a dot, keeping the last element whole,
+// such as "i/a/tab.go" for "internal/action/tab.go"
+func ShortenPath(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, p := range parts[:len(parts)-1] {
+		n := 1
+		if strings.HasPrefix(p, ".") {
+			n = 2
+		}
+		if CharacterCountInString(p) > n {
+			parts[i] = SliceStartStr(p, n)
+		}
+	}
+	return filepath.FromSlash(strings.Join(parts, "/"))
+}
+
 // GetPathAndCursorPosition returns a filename without everything following a `:`
 // This is used for opening files like util.go:10:5 to specify a line and column
 // Special cases like Windows Absolute path (C:\myfile.txt:10:5) are handled correctly.
@@ -384,7 +400,7 @@ func GetCharPosInLine(b []byte, visualPos int, tabsize int) int {
 			ts := tabsize - (width % tabsize)
 			width += ts
 		default:
-			width += runewidth.RuneWidth(r)
+			width += CharacterWidth(r)
 		}
 
 		if width >= visualPos {
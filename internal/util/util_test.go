@@ -31,3 +31,10 @@ func TestSliceVisualEnd(t *testing.T) {
 	assert.Equal(t, []byte("ello"), slc)
 	assert.Equal(t, 0, n)
 }
+
+func TestShortenPath(t *testing.T) {
+	assert.Equal(t, "tab.go", ShortenPath("tab.go"))
+	assert.Equal(t, "i/a/tab.go", ShortenPath("internal/action/tab.go"))
+	assert.Equal(t, "/h/u/.c/m/settings.json", ShortenPath("/home/user/.config/micro/settings.json"))
+	assert.Equal(t, "~/../ü//x", ShortenPath("~/../über//x"))
+}
@@ -0,0 +1,78 @@
+// Package vcs finds the version control system of a working copy and the
+// version of a file its changes are made from
+package vcs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/zyedidia/micro/v2/internal/git"
+)
+
+// Systems are the version control systems supported by Base
+var Systems = []string{"git", "hg", "svn", "fossil"}
+
+// markers are the files or directories at the root of the working copies
+// of each version control system
+var markers = map[string][]string{
+	"git":    {".git"},
+	"hg":     {".hg"},
+	"svn":    {".svn"},
+	"fossil": {".fslckout", "_FOSSIL_"},
+}
+
+// Detect returns the version control system of the innermost working copy
+// containing dir, or an empty string if it isn't in any
+func Detect(dir string) string {
+	for {
+		for _, vcs := range Systems {
+			for _, m := range markers[vcs] {
+				if _, err := os.Stat(filepath.Join(dir, m)); err == nil {
+					return vcs
+				}
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Base returns the contents of the file at path in the revision its
+// working copy of the version control system is at: the HEAD of git, the
+// parent of the working directory of hg, and the checked out version of
+// svn and fossil
+func Base(path, vcs string) ([]byte, error) {
+	var args []string
+	name := filepath.Base(path)
+	switch vcs {
+	case "git":
+		return git.Show(path, "HEAD")
+	case "hg":
+		args = []string{"hg", "cat", "-r", ".", name}
+	case "svn":
+		args = []string{"svn", "cat", "-r", "BASE", name}
+	case "fossil":
+		args = []string{"fossil", "cat", name}
+	default:
+		return nil, errors.New("Unknown version control system: " + vcs)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, errors.New(string(msg))
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,33 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	root, err := ioutil.TempDir("", "micro-vcs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	sub := filepath.Join(root, "a", "b")
+	assert.Nil(t, os.MkdirAll(sub, 0755))
+
+	assert.Equal(t, "", Detect(sub))
+
+	assert.Nil(t, os.Mkdir(filepath.Join(root, ".hg"), 0755))
+	assert.Equal(t, "hg", Detect(sub))
+
+	// the innermost working copy wins
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(root, "a", "_FOSSIL_"), nil, 0644))
+	assert.Equal(t, "fossil", Detect(sub))
+	assert.Equal(t, "hg", Detect(root))
+}
+
+func TestBaseUnknown(t *testing.T) {
+	_, err := Base("file.txt", "cvs")
+	assert.NotNil(t, err)
+}
@@ -201,6 +201,18 @@ func (n *Node) ResizeSplit(size int) bool {
 	return n.parent.hResizeSplit(ind, size)
 }
 
+// ResizeChild sets the size of the child at index i along the direction of
+// the split. The following child takes up the difference.
+func (n *Node) ResizeChild(i int, size int) bool {
+	if i < 0 || i+1 >= len(n.children) {
+		return false
+	}
+	if n.Kind == STVert {
+		return n.vResizeSplit(i, size)
+	}
+	return n.hResizeSplit(i, size)
+}
+
 // Resize sets this node's size and resizes all children accordlingly
 func (n *Node) Resize(w, h int) {
 	n.W, n.H = w, h
@@ -478,6 +490,62 @@ func (n *Node) Unsplit() bool {
 	return true
 }
 
+// Leaves returns the leaves of the tree in the order they are displayed,
+// from left to right and from top to bottom
+func (n *Node) Leaves() []*Node {
+	if n.IsLeaf() {
+		return []*Node{n}
+	}
+	var leaves []*Node
+	for _, c := range n.children {
+		leaves = append(leaves, c.Leaves()...)
+	}
+	return leaves
+}
+
+// Rotate turns the split containing this leaf from splits side by side
+// to splits on top of each other or the other way around, along with the
+// splits it contains
+func (n *Node) Rotate() bool {
+	if !n.IsLeaf() || n.parent == nil {
+		return false
+	}
+	n.parent.flip()
+	n.parent.Resize(n.parent.W, n.parent.H)
+	return true
+}
+
+func (n *Node) flip() {
+	switch n.Kind {
+	case STVert:
+		n.Kind = STHoriz
+	case STHoriz:
+		n.Kind = STVert
+	}
+	for _, c := range n.children {
+		c.propW, c.propH = c.propH, c.propW
+		c.flip()
+	}
+}
+
+// Equalize gives the same size to all the children of each split in the
+// tree
+func (n *Node) Equalize() {
+	n.equalize()
+	n.Resize(n.W, n.H)
+}
+
+func (n *Node) equalize() {
+	for _, c := range n.children {
+		if n.Kind == STHoriz {
+			c.propW = 1 / float64(len(n.children))
+		} else {
+			c.propH = 1 / float64(len(n.children))
+		}
+		c.equalize()
+	}
+}
+
 // String returns the string form of the node and all children (used for debugging)
 func (n *Node) String() string {
 	var strf func(n *Node, ident int) string
@@ -14,3 +14,77 @@ func TestHSplit(t *testing.T) {
 
 	fmt.Println(root.String())
 }
+
+func TestResizeChild(t *testing.T) {
+	root := NewRoot(0, 0, 90, 40)
+	n1 := root.VSplit(true)
+	root.GetNode(n1).VSplit(true)
+	children := root.Children()
+	if len(children) != 3 {
+		t.Fatalf("expected 3 splits, got %d", len(children))
+	}
+
+	if !root.ResizeChild(0, 20) || !root.ResizeChild(1, 50) {
+		t.Fatal("could not resize the splits")
+	}
+	if root.ResizeChild(2, 10) {
+		t.Error("the last split can't be resized")
+	}
+	for i, w := range []int{20, 50, 20} {
+		if children[i].W != w {
+			t.Errorf("split %d: expected width %d, got %d", i, w, children[i].W)
+		}
+	}
+	if children[1].X != 20 || children[2].X != 70 {
+		t.Errorf("unexpected positions %d and %d", children[1].X, children[2].X)
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	root := NewRoot(0, 0, 90, 40)
+	n2 := root.VSplit(true)
+	n3 := root.GetNode(root.id).HSplit(true)
+	leaves := root.Leaves()
+	if len(leaves) != 3 {
+		t.Fatalf("expected 3 leaves, got %d", len(leaves))
+	}
+	for i, id := range []uint64{root.id, n3, n2} {
+		if leaves[i].ID() != id {
+			t.Errorf("leaf %d: expected id %d, got %d", i, id, leaves[i].ID())
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	root := NewRoot(0, 0, 90, 40)
+	n2 := root.VSplit(true)
+	n3 := root.GetNode(n2).HSplit(true)
+	if !root.GetNode(root.id).Rotate() {
+		t.Fatal("could not rotate the splits")
+	}
+	// the splits side by side are on top of each other, and the other way
+	// around in the right split
+	left, right := root.GetNode(root.id), root.GetNode(n2)
+	if left.W != 90 || left.H != 20 || right.Y != 20 || right.W != 45 {
+		t.Errorf("unexpected sizes %v and %v", left.View, right.View)
+	}
+	if bottom := root.GetNode(n3); bottom.X != 45 || bottom.Y != 20 || bottom.H != 20 {
+		t.Errorf("unexpected size %v", bottom.View)
+	}
+	if NewRoot(0, 0, 10, 10).Rotate() {
+		t.Error("a lone split can't be rotated")
+	}
+}
+
+func TestEqualize(t *testing.T) {
+	root := NewRoot(0, 0, 90, 40)
+	n1 := root.VSplit(true)
+	root.GetNode(n1).VSplit(true)
+	root.ResizeChild(0, 60)
+	root.Equalize()
+	for i, c := range root.Children() {
+		if c.W != 30 || c.X != 30*i {
+			t.Errorf("split %d: unexpected size %v", i, c.View)
+		}
+	}
+}
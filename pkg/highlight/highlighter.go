@@ -67,7 +67,7 @@ func combineLineMatch(src, dst LineMatch) LineMatch {
 // A State represents the region at the end of a line
 type State *region
 
-var EmptyDef = Def{nil, &rules{}}
+var EmptyDef = Def{rules: &rules{}}
 
 // LineStates is an interface for a buffer-like object which can also store the states and matches for every line
 type LineStates interface {
@@ -362,8 +362,14 @@ func (h *Highlighter) HighlightMatches(input LineStates, startline, endline int)
 // for each line until it comes across a line whose state does not change
 // returns the number of the final line
 func (h *Highlighter) ReHighlightStates(input LineStates, startline int) int {
-	// lines := input.LineData()
+	return h.ReHighlightStatesRange(input, startline, startline)
+}
 
+// ReHighlightStatesRange sets the end of line states of the lines from
+// startline to endline, which were modified, and continues down until it
+// comes across a line whose state does not change
+// returns the number of the final line
+func (h *Highlighter) ReHighlightStatesRange(input LineStates, startline, endline int) int {
 	h.lastRegion = nil
 	if startline > 0 {
 		h.lastRegion = input.State(startline - 1)
@@ -383,7 +389,7 @@ func (h *Highlighter) ReHighlightStates(input LineStates, startline int) int {
 
 		input.SetState(i, curState)
 
-		if curState == lastState {
+		if curState == lastState && i >= endline {
 			return i
 		}
 	}
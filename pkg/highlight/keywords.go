@@ -0,0 +1,123 @@
+package highlight
+
+import (
+	"regexp/syntax"
+	"sort"
+	"unicode"
+)
+
+// maxExpansion is the number of strings a regular expression may match
+// for them to be listed as keywords
+const maxExpansion = 1000
+
+// Keywords returns the words highlighted by the patterns of the syntax
+// outside of its regions, such as the keywords and the builtin types and
+// functions of the language, sorted. The patterns matching a finite number
+// of strings, or their parts that do, give the words they match.
+func (d *Def) Keywords() []string {
+	d.keywordsOnce.Do(func() {
+		if d.rules == nil {
+			return
+		}
+		seen := make(map[string]bool)
+		for _, p := range d.rules.patterns {
+			re, err := syntax.Parse(p.regex.String(), syntax.Perl)
+			if err != nil {
+				continue
+			}
+			for _, w := range patternWords(re) {
+				if !seen[w] && isKeyword(w) {
+					seen[w] = true
+					d.keywords = append(d.keywords, w)
+				}
+			}
+		}
+		sort.Strings(d.keywords)
+	})
+	return d.keywords
+}
+
+// isKeyword returns whether s is a word of at least two characters
+func isKeyword(s string) bool {
+	n := 0
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_' {
+			return false
+		}
+		n++
+	}
+	return n >= 2
+}
+
+// patternWords returns the strings matched by the regular expression if
+// they are few, or else those matched by its largest parts which match few
+func patternWords(re *syntax.Regexp) []string {
+	if words, ok := expand(re); ok {
+		return words
+	}
+	var words []string
+	for _, sub := range re.Sub {
+		words = append(words, patternWords(sub)...)
+	}
+	return words
+}
+
+// expand returns the strings matched by the regular expression, and false
+// if there are more than maxExpansion of them. Anchors and word boundaries
+// match the empty string.
+func expand(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText,
+		syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return []string{""}, true
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
+	case syntax.OpCharClass:
+		var strs []string
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if len(strs) == 4 {
+					// a class of more characters is not part of a word
+					return nil, false
+				}
+				strs = append(strs, string(r))
+			}
+		}
+		return strs, true
+	case syntax.OpCapture:
+		return expand(re.Sub[0])
+	case syntax.OpQuest:
+		strs, ok := expand(re.Sub[0])
+		return append([]string{""}, strs...), ok && len(strs) < maxExpansion
+	case syntax.OpAlternate:
+		var strs []string
+		for _, sub := range re.Sub {
+			s, ok := expand(sub)
+			if !ok || len(strs)+len(s) > maxExpansion {
+				return nil, false
+			}
+			strs = append(strs, s...)
+		}
+		return strs, true
+	case syntax.OpConcat:
+		strs := []string{""}
+		for _, sub := range re.Sub {
+			s, ok := expand(sub)
+			if !ok || len(strs)*len(s) > maxExpansion {
+				return nil, false
+			}
+			next := make([]string, 0, len(strs)*len(s))
+			for _, a := range strs {
+				for _, b := range s {
+					next = append(next, a+b)
+				}
+			}
+			strs = next
+		}
+		return strs, true
+	}
+	return nil, false
+}
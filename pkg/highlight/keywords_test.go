@@ -0,0 +1,35 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywords(t *testing.T) {
+	const syntax = `filetype: test
+
+detect:
+    filename: "\\.test$"
+
+rules:
+    - statement: "\\b(if|else|for|func)\\b"
+    - type: "\\b(u?int(8|16)?|bool)\\b"
+    - constant.number: "\\b[0-9]+\\b"
+    - identifier: "\\b(print)\\(.*\\)"
+    - constant.string:
+        start: "\""
+        end: "\""
+        rules:
+            - special: "\\b(inside)\\b"
+`
+	f, err := ParseFile([]byte(syntax))
+	assert.NoError(t, err)
+	header, err := MakeHeaderYaml([]byte(syntax))
+	assert.NoError(t, err)
+	d, err := ParseDef(f, header)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"bool", "else", "for", "func", "if", "int", "int16", "int8",
+		"print", "uint", "uint16", "uint8"}, d.Keywords())
+}
@@ -0,0 +1,102 @@
+package highlight
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lint returns the likely mistakes of a syntax file which Validate
+// accepts: unknown keys at the top of the file, repeated rules, rules which
+// never apply and regions which can start on an empty match. A pattern is
+// overridden by the same pattern later in its list, which highlights the
+// same text last, and a region never starts if an earlier region of its
+// list starts with the same pattern.
+func Lint(data []byte) []error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	v := new(validator)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "filetype", "detect", "indent", "comment", "pairs":
+		case "rules":
+			v.lintRules(val)
+		default:
+			v.errorf(key, "unknown key %q", key.Value)
+		}
+	}
+	return v.errs
+}
+
+func (v *validator) lintRules(n *yaml.Node) {
+	if n.Kind != yaml.SequenceNode {
+		return
+	}
+	// patterns are the group and the pattern of the last rule for each
+	// pattern
+	patterns := make(map[string][2]*yaml.Node)
+	starts := make(map[string]*yaml.Node)
+	for _, rule := range n.Content {
+		if rule.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(rule.Content); i += 2 {
+			key, val := rule.Content[i], rule.Content[i+1]
+			switch {
+			case key.Value == "include":
+			case val.Kind == yaml.ScalarNode:
+				if prev, ok := patterns[val.Value]; ok {
+					if prev[0].Value == key.Value {
+						v.errorf(val, "the rule repeats the rule at line %d", prev[1].Line)
+					} else {
+						v.errorf(prev[1], "the rule never applies, the same pattern at line %d overrides it", val.Line)
+					}
+				}
+				patterns[val.Value] = [2]*yaml.Node{key, val}
+			case val.Kind == yaml.MappingNode:
+				v.lintRegion(val, starts)
+			}
+		}
+	}
+}
+
+// lintRegion checks a region of a list of rules, where starts are the
+// start patterns of the regions before it
+func (v *validator) lintRegion(n *yaml.Node, starts map[string]*yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		switch key.Value {
+		case "start":
+			if prev, ok := starts[val.Value]; ok {
+				v.errorf(val, "the region never starts, the region at line %d starts with the same pattern", prev.Line)
+			} else {
+				starts[val.Value] = val
+			}
+			if r, err := regexp.Compile(val.Value); err == nil && emptyMatchInside(r) {
+				v.errorf(val, "the region can start on an empty match")
+			}
+		case "rules":
+			v.lintRules(val)
+		}
+	}
+}
+
+// emptyMatchInside returns whether r matches the empty string elsewhere
+// than at the start of a line, which "^" does to start a region covering a
+// whole line
+func emptyMatchInside(r *regexp.Regexp) bool {
+	for _, m := range r.FindAllStringIndex("\x00\x00", -1) {
+		if m[0] == m[1] && m[0] > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sync"
 
 	"gopkg.in/yaml.v2"
 )
@@ -17,8 +18,32 @@ type Group uint8
 var Groups map[string]Group
 var numGroups Group
 
+// groupsLock guards Groups, as syntax files may be parsed on several
+// goroutines
+var groupsLock sync.RWMutex
+
+// getGroup returns the group of the given name, which is added to Groups
+// if it is new
+func getGroup(name string) Group {
+	groupsLock.Lock()
+	defer groupsLock.Unlock()
+	if _, ok := Groups[name]; !ok {
+		numGroups++
+		Groups[name] = numGroups
+	}
+	return Groups[name]
+}
+
+// GetGroup returns the group of the given name, such as "constant.string",
+// for highlighting text which isn't parsed with a syntax definition
+func GetGroup(name string) Group {
+	return getGroup(name)
+}
+
 // String returns the group name attached to the specific group
 func (g Group) String() string {
+	groupsLock.RLock()
+	defer groupsLock.RUnlock()
 	for k, v := range Groups {
 		if v == g {
 			return k
@@ -35,6 +60,37 @@ type Def struct {
 	*Header
 
 	rules *rules
+
+	// Indent are the patterns of the lines changing the indentation
+	Indent IndentRules
+	// Comment are the markers of the comments of the language
+	Comment CommentMarkers
+	// Pairs are the keywords opening and closing blocks, such as if and end,
+	// which are matched as brackets are
+	Pairs [][2]string
+
+	keywordsOnce sync.Once
+	keywords     []string
+}
+
+// CommentMarkers are the markers of the comments of a language, given by
+// the comment key of a syntax file. Either may be empty.
+type CommentMarkers struct {
+	// Line starts a comment which runs until the end of the line
+	Line string
+	// BlockStart and BlockEnd delimit a comment which may span lines
+	BlockStart, BlockEnd string
+}
+
+// IndentRules recognize the lines which change the indentation of the
+// lines after them, given by the indent key of a syntax file
+type IndentRules struct {
+	// Increase matches the lines after which the indentation increases,
+	// such as a line ending with an opening brace
+	Increase *regexp.Regexp
+	// Decrease matches the lines which are indented one level less than
+	// the lines before them, such as a line starting with a closing brace
+	Decrease *regexp.Regexp
 }
 
 type Header struct {
@@ -206,12 +262,110 @@ func ParseDef(f *File, header *Header) (s *Def, err error) {
 			}
 
 			s.rules = rules
+		} else if k == "indent" {
+			indent, err := parseIndent(v)
+			if err != nil {
+				return nil, err
+			}
+			s.Indent = indent
+		} else if k == "comment" {
+			comment, err := parseComment(v)
+			if err != nil {
+				return nil, err
+			}
+			s.Comment = comment
+		} else if k == "pairs" {
+			pairs, err := parsePairs(v)
+			if err != nil {
+				return nil, err
+			}
+			s.Pairs = pairs
 		}
 	}
 
 	return s, err
 }
 
+func parseIndent(v interface{}) (IndentRules, error) {
+	var indent IndentRules
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return indent, errors.New("indent must be a mapping with the increase and decrease keys")
+	}
+	for k, v := range m {
+		str, ok := v.(string)
+		if !ok {
+			return indent, fmt.Errorf("indent %v must be a regular expression", k)
+		}
+		re, err := regexp.Compile(str)
+		if err != nil {
+			return indent, err
+		}
+		switch k {
+		case "increase":
+			indent.Increase = re
+		case "decrease":
+			indent.Decrease = re
+		default:
+			return indent, fmt.Errorf("unknown indent key %v", k)
+		}
+	}
+	return indent, nil
+}
+
+func parseComment(v interface{}) (CommentMarkers, error) {
+	var comment CommentMarkers
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return comment, errors.New("comment must be a mapping with the line and block keys")
+	}
+	for k, v := range m {
+		switch k {
+		case "line":
+			line, ok := v.(string)
+			if !ok {
+				return comment, errors.New("the line comment marker must be a string")
+			}
+			comment.Line = line
+		case "block":
+			block, ok := v.([]interface{})
+			if !ok || len(block) != 2 {
+				return comment, errors.New("the block comment markers must be a start and an end")
+			}
+			start, ok1 := block[0].(string)
+			end, ok2 := block[1].(string)
+			if !ok1 || !ok2 {
+				return comment, errors.New("the block comment markers must be strings")
+			}
+			comment.BlockStart, comment.BlockEnd = start, end
+		default:
+			return comment, fmt.Errorf("unknown comment key %v", k)
+		}
+	}
+	return comment, nil
+}
+
+func parsePairs(v interface{}) ([][2]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("pairs must be a list of keyword pairs")
+	}
+	var pairs [][2]string
+	for _, p := range list {
+		pair, ok := p.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, errors.New("a keyword pair must be an opening and a closing keyword")
+		}
+		open, ok1 := pair[0].(string)
+		close, ok2 := pair[1].(string)
+		if !ok1 || !ok2 {
+			return nil, errors.New("the keywords of a pair must be strings")
+		}
+		pairs = append(pairs, [2]string{open, close})
+	}
+	return pairs, nil
+}
+
 // HasIncludes returns whether this syntax def has any include statements
 func HasIncludes(d *Def) bool {
 	hasIncludes := len(d.rules.includes) > 0
@@ -248,42 +402,68 @@ func getIncludesInRegion(region *region) []string {
 
 // ResolveIncludes will sort out the rules for including other filetypes
 // You should call this after parsing all the Defs
+// The includes of the included filetypes are resolved as well, so that a
+// filetype embedded in another one is highlighted with its own embedded
+// languages. A filetype isn't included again inside itself.
 func ResolveIncludes(def *Def, files []*File) {
 	resolveIncludesInDef(files, def)
 }
 
 func resolveIncludesInDef(files []*File, d *Def) {
+	seen := make(map[string]bool)
+	if d.Header != nil {
+		seen[d.FileType] = true
+	}
 	for _, lang := range d.rules.includes {
-		for _, searchFile := range files {
-			if lang == searchFile.FileType {
-				searchDef, _ := ParseDef(searchFile, nil)
-				d.rules.patterns = append(d.rules.patterns, searchDef.rules.patterns...)
-				d.rules.regions = append(d.rules.regions, searchDef.rules.regions...)
-			}
-		}
+		includeRules(files, d.rules, lang, seen)
 	}
 	for _, r := range d.rules.regions {
-		resolveIncludesInRegion(files, r)
+		resolveIncludesInRegion(files, r, seen)
 		r.parent = nil
 	}
 }
 
-func resolveIncludesInRegion(files []*File, region *region) {
-	for _, lang := range region.rules.includes {
-		for _, searchFile := range files {
-			if lang == searchFile.FileType {
-				searchDef, _ := ParseDef(searchFile, nil)
-				region.rules.patterns = append(region.rules.patterns, searchDef.rules.patterns...)
-				region.rules.regions = append(region.rules.regions, searchDef.rules.regions...)
-			}
+func resolveIncludesInRegion(files []*File, region *region, seen map[string]bool) {
+	inner := seen
+	if len(region.rules.includes) > 0 {
+		inner = make(map[string]bool, len(seen)+len(region.rules.includes))
+		for lang := range seen {
+			inner[lang] = true
+		}
+		for _, lang := range region.rules.includes {
+			includeRules(files, region.rules, lang, inner)
 		}
 	}
 	for _, r := range region.rules.regions {
-		resolveIncludesInRegion(files, r)
+		resolveIncludesInRegion(files, r, inner)
 		r.parent = region
 	}
 }
 
+// includeRules appends the patterns and regions of the filetype lang to
+// ru, along with the ones of the filetypes lang includes at its top level.
+// The included filetypes are added to seen.
+func includeRules(files []*File, ru *rules, lang string, seen map[string]bool) {
+	if seen[lang] {
+		return
+	}
+	for _, searchFile := range files {
+		if lang == searchFile.FileType {
+			searchDef, err := ParseDef(searchFile, nil)
+			if err != nil {
+				return
+			}
+			seen[lang] = true
+			ru.patterns = append(ru.patterns, searchDef.rules.patterns...)
+			ru.regions = append(ru.regions, searchDef.rules.regions...)
+			for _, l := range searchDef.rules.includes {
+				includeRules(files, ru, l, seen)
+			}
+			return
+		}
+	}
+}
+
 func parseRules(input []interface{}, curRegion *region) (ru *rules, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -312,12 +492,7 @@ func parseRules(input []interface{}, curRegion *region) (ru *rules, err error) {
 						return nil, err
 					}
 
-					groupStr := group.(string)
-					if _, ok := Groups[groupStr]; !ok {
-						numGroups++
-						Groups[groupStr] = numGroups
-					}
-					groupNum := Groups[groupStr]
+					groupNum := getGroup(group.(string))
 					ru.patterns = append(ru.patterns, &pattern{groupNum, r})
 				}
 			case map[interface{}]interface{}:
@@ -348,12 +523,7 @@ func parseRegion(group string, regionInfo map[interface{}]interface{}, prevRegio
 	}()
 
 	r = new(region)
-	if _, ok := Groups[group]; !ok {
-		numGroups++
-		Groups[group] = numGroups
-	}
-	groupNum := Groups[group]
-	r.group = groupNum
+	r.group = getGroup(group)
 	r.parent = prevRegion
 
 	r.start, err = regexp.Compile(regionInfo["start"].(string))
@@ -379,17 +549,7 @@ func parseRegion(group string, regionInfo map[interface{}]interface{}, prevRegio
 
 	// limit-color is optional
 	if _, ok := regionInfo["limit-group"]; ok {
-		groupStr := regionInfo["limit-group"].(string)
-		if _, ok := Groups[groupStr]; !ok {
-			numGroups++
-			Groups[groupStr] = numGroups
-		}
-		groupNum := Groups[groupStr]
-		r.limitGroup = groupNum
-
-		if err != nil {
-			return nil, err
-		}
+		r.limitGroup = getGroup(regionInfo["limit-group"].(string))
 	} else {
 		r.limitGroup = r.group
 	}
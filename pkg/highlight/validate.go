@@ -0,0 +1,224 @@
+package highlight
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A SyntaxError is an error in a syntax file along with its position
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// validator collects the errors found in a syntax file
+type validator struct {
+	errs []error
+}
+
+func (v *validator) errorf(n *yaml.Node, format string, args ...interface{}) {
+	v.errs = append(v.errs, &SyntaxError{n.Line, n.Column, fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a yaml syntax file and returns the errors it contains.
+// Unlike ParseDef, which stops at the first error, it reports all the
+// errors with their line and column. The yaml syntax errors themselves
+// only have a line number.
+func Validate(data []byte) []error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []error{err}
+	}
+	if len(doc.Content) == 0 {
+		return []error{&SyntaxError{1, 1, "the syntax file is empty"}}
+	}
+
+	v := new(validator)
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		v.errorf(root, "expected a mapping with the filetype, detect and rules keys")
+		return v.errs
+	}
+
+	found := make(map[string]bool)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		found[key.Value] = true
+		switch key.Value {
+		case "filetype":
+			if val.Kind != yaml.ScalarNode || val.Value == "" {
+				v.errorf(val, "the filetype must be a name")
+			}
+		case "detect":
+			v.detect(val)
+		case "rules":
+			v.rules(val)
+		case "indent":
+			v.indent(val)
+		case "comment":
+			v.comment(val)
+		case "pairs":
+			v.pairs(val)
+		}
+	}
+	if !found["filetype"] {
+		v.errorf(root, "missing filetype")
+	}
+	if !found["rules"] {
+		v.errorf(root, "missing rules")
+	}
+	return v.errs
+}
+
+func (v *validator) detect(n *yaml.Node) {
+	if n.Tag == "!!null" {
+		// the filetype is only set explicitly
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		v.errorf(n, "detect must be a mapping with the filename and header keys")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		switch key.Value {
+		case "filename", "header":
+			v.regex(val)
+		default:
+			v.errorf(key, "unknown detect key %q", key.Value)
+		}
+	}
+}
+
+func (v *validator) indent(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		v.errorf(n, "indent must be a mapping with the increase and decrease keys")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		switch key.Value {
+		case "increase", "decrease":
+			v.regex(val)
+		default:
+			v.errorf(key, "unknown indent key %q", key.Value)
+		}
+	}
+}
+
+func (v *validator) comment(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		v.errorf(n, "comment must be a mapping with the line and block keys")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		switch key.Value {
+		case "line":
+			if val.Kind != yaml.ScalarNode || val.Value == "" {
+				v.errorf(val, "the line comment marker must be a string")
+			}
+		case "block":
+			if val.Kind != yaml.SequenceNode || len(val.Content) != 2 {
+				v.errorf(val, "the block comment markers must be a start and an end")
+				continue
+			}
+			for _, m := range val.Content {
+				if m.Kind != yaml.ScalarNode || m.Value == "" {
+					v.errorf(m, "a block comment marker must be a string")
+				}
+			}
+		default:
+			v.errorf(key, "unknown comment key %q", key.Value)
+		}
+	}
+}
+
+// regex checks that n is a valid regular expression
+func (v *validator) regex(n *yaml.Node) {
+	if n.Kind != yaml.ScalarNode {
+		v.errorf(n, "expected a regular expression")
+		return
+	}
+	if _, err := regexp.Compile(n.Value); err != nil {
+		v.errorf(n, "invalid regular expression: %v", err)
+	}
+}
+
+func (v *validator) rules(n *yaml.Node) {
+	if n.Kind != yaml.SequenceNode {
+		v.errorf(n, "expected a list of rules")
+		return
+	}
+	for _, rule := range n.Content {
+		if rule.Kind != yaml.MappingNode {
+			v.errorf(rule, "a rule must be a group followed by a pattern, a region or an include")
+			continue
+		}
+		for i := 0; i+1 < len(rule.Content); i += 2 {
+			key, val := rule.Content[i], rule.Content[i+1]
+			switch {
+			case val.Kind == yaml.ScalarNode && key.Value == "include":
+				if val.Value == "" {
+					v.errorf(val, "missing filetype to include")
+				}
+			case val.Kind == yaml.ScalarNode:
+				v.regex(val)
+			case val.Kind == yaml.MappingNode:
+				v.region(val)
+			default:
+				v.errorf(val, "expected a pattern or a region for %s", key.Value)
+			}
+		}
+	}
+}
+
+func (v *validator) region(n *yaml.Node) {
+	found := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		found[key.Value] = true
+		switch key.Value {
+		case "start", "end", "skip":
+			v.regex(val)
+		case "limit-group":
+			if val.Kind != yaml.ScalarNode || val.Value == "" {
+				v.errorf(val, "limit-group must be a group")
+			}
+		case "rules":
+			v.rules(val)
+		default:
+			v.errorf(key, "unknown region key %q", key.Value)
+		}
+	}
+	for _, k := range []string{"start", "end", "rules"} {
+		if !found[k] {
+			v.errorf(n, "the region is missing %s", k)
+		}
+	}
+}
+
+func (v *validator) pairs(n *yaml.Node) {
+	if n.Kind != yaml.SequenceNode {
+		v.errorf(n, "pairs must be a list of keyword pairs")
+		return
+	}
+	for _, p := range n.Content {
+		if p.Kind != yaml.SequenceNode || len(p.Content) != 2 {
+			v.errorf(p, "a keyword pair must be an opening and a closing keyword")
+			continue
+		}
+		for _, k := range p.Content {
+			if k.Kind != yaml.ScalarNode || k.Value == "" {
+				v.errorf(k, "the keywords of a pair must be strings")
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package highlight
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateRuntime(t *testing.T) {
+	files, err := filepath.Glob("../../runtime/syntax/*.yaml")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no syntax files found")
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, err := range Validate(data) {
+			t.Errorf("%s: %v", filepath.Base(f), err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	const syntax = `filetype: test
+
+detect:
+    filename: "\\.test($"
+
+rules:
+    - statement: "\\b(if|else)\\b"
+    - comment:
+        start: "/\\*"
+        limit-color: comment
+        rules:
+            - todo: "[TODO"
+    - "func"
+`
+	expected := []string{
+		"line 4, column 15: invalid regular expression: error parsing regexp: missing closing ): `\\.test($`",
+		"line 10, column 9: unknown region key \"limit-color\"",
+		"line 12, column 21: invalid regular expression: error parsing regexp: missing closing ]: `[TODO`",
+		"line 9, column 9: the region is missing end",
+		"line 13, column 7: a rule must be a group followed by a pattern, a region or an include",
+	}
+	errs := Validate([]byte(syntax))
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("error %d: expected %q, got %q", i, expected[i], err.Error())
+		}
+	}
+
+	indent := "filetype: test\nindent:\n    increase: \"{$\"\n    dedent: \"^}\"\nrules: []\n"
+	if errs := Validate([]byte(indent)); len(errs) != 1 || errs[0].Error() != "line 4, column 5: unknown indent key \"dedent\"" {
+		t.Errorf("expected an unknown indent key error, got %v", errs)
+	}
+	comment := "filetype: test\ncomment:\n    line: \"//\"\n    block: [\"/*\"]\nrules: []\n"
+	if errs := Validate([]byte(comment)); len(errs) != 1 || errs[0].Error() != "line 4, column 12: the block comment markers must be a start and an end" {
+		t.Errorf("expected a block comment error, got %v", errs)
+	}
+	pairs := "filetype: test\npairs:\n    - [\"if\", \"end\"]\n    - [\"do\"]\nrules: []\n"
+	if errs := Validate([]byte(pairs)); len(errs) != 1 || errs[0].Error() != "line 4, column 7: a keyword pair must be an opening and a closing keyword" {
+		t.Errorf("expected a keyword pair error, got %v", errs)
+	}
+	if errs := Validate([]byte("filetype: [")); len(errs) != 1 {
+		t.Errorf("expected a yaml error, got %v", errs)
+	}
+	if errs := Validate([]byte("rules: []\n")); len(errs) != 1 || errs[0].Error() != "line 1, column 1: missing filetype" {
+		t.Errorf("expected a missing filetype error, got %v", errs)
+	}
+}
+
+func TestLint(t *testing.T) {
+	const syntax = `filetype: test
+colors: true
+
+rules:
+    - statement: "\\bif\\b"
+    - statement: "\\bif\\b"
+    - constant: "\\bnil\\b"
+    - comment:
+        start: "#"
+        end: "$"
+        rules:
+            - todo: "TODO"
+            - special: "TODO"
+    - constant.string:
+        start: "#"
+        end: "!"
+        rules: []
+    - default:
+        start: "^"
+        end: "$"
+        rules: []
+    - default:
+        start: "x*"
+        end: "y"
+        rules: []
+    - identifier: "\\bnil\\b"
+`
+	expected := []string{
+		"line 2, column 1: unknown key \"colors\"",
+		"line 6, column 18: the rule repeats the rule at line 5",
+		"line 12, column 21: the rule never applies, the same pattern at line 13 overrides it",
+		"line 15, column 16: the region never starts, the region at line 9 starts with the same pattern",
+		"line 23, column 16: the region can start on an empty match",
+		"line 7, column 17: the rule never applies, the same pattern at line 26 overrides it",
+	}
+	errs := Lint([]byte(syntax))
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d warnings, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("warning %d: expected %q, got %q", i, expected[i], err.Error())
+		}
+	}
+}